@@ -39,6 +39,10 @@ var (
 	// This error is returned by WaitDeployed if contract creation leaves an
 	// empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
+
+	// ErrNotSponsored is returned by sponsored transact operations on a contract
+	// which is not a TRC21 token with enough fee capacity in the TRC21 issuer.
+	ErrNotSponsored = errors.New("contract fees not sponsored")
 )
 
 // ContractCaller defines the methods needed to allow operating with contract on a read
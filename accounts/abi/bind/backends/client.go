@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ChainCallAPI serves the contract calls and code of the head state of a
+// chain under the eth namespace.
+type ChainCallAPI struct {
+	backend *SimulatedBackend
+}
+
+// CallArgs are the arguments of an eth_call as sent by the ethclient.
+type CallArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     hexutil.Bytes   `json:"data"`
+}
+
+// Call executes a contract call on the head state.
+func (s *ChainCallAPI) Call(ctx context.Context, args CallArgs, number rpc.BlockNumber) (hexutil.Bytes, error) {
+	if number != rpc.LatestBlockNumber {
+		return nil, errBlockNumberUnsupported
+	}
+	msg := ethereum.CallMsg{From: args.From, To: args.To, Gas: uint64(args.Gas), Data: args.Data}
+	if args.GasPrice != nil {
+		msg.GasPrice = args.GasPrice.ToInt()
+	}
+	if args.Value != nil {
+		msg.Value = args.Value.ToInt()
+	}
+	return s.backend.CallContract(ctx, msg, nil)
+}
+
+// GetCode returns the code of a contract in the head state.
+func (s *ChainCallAPI) GetCode(ctx context.Context, addr common.Address, number rpc.BlockNumber) (hexutil.Bytes, error) {
+	if number != rpc.LatestBlockNumber {
+		return nil, errBlockNumberUnsupported
+	}
+	return s.backend.CodeAt(ctx, addr, nil)
+}
+
+// NewChainClient returns an in-process client executing the calls of contract
+// bindings on the head state of a chain, standing in for the IPC client of a
+// node for chains running without one.
+func NewChainClient(db ethdb.Database, chain *core.BlockChain) (*ethclient.Client, error) {
+	server := rpc.NewServer()
+	backend := &SimulatedBackend{database: db, blockchain: chain, config: chain.Config()}
+	if err := server.RegisterName("eth", &ChainCallAPI{backend}); err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpc.DialInProc(server)), nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	validatorContract "github.com/ethereum/go-ethereum/contracts/validator"
+	contractValidator "github.com/ethereum/go-ethereum/contracts/validator/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestChainClient(t *testing.T) {
+	deployer := crypto.PubkeyToAddress(sellerKey.PublicKey)
+	candidateCap := new(big.Int).Mul(big.NewInt(50000), big.NewInt(params.Ether))
+	sim := NewSimulatedBackend(core.GenesisAlloc{deployer: {Balance: new(big.Int).Mul(candidateCap, big.NewInt(2))}})
+	addr, _, err := validatorContract.DeployValidator(bind.NewKeyedTransactor(sellerKey), sim, []common.Address{buyer}, []*big.Int{candidateCap}, deployer)
+	if err != nil {
+		t.Fatalf("failed to deploy the validator contract: %v", err)
+	}
+	sim.Commit()
+
+	client, err := NewChainClient(sim.database, sim.blockchain)
+	if err != nil {
+		t.Fatalf("failed to create the chain client: %v", err)
+	}
+	validator, err := contractValidator.NewTomoValidator(addr, client)
+	if err != nil {
+		t.Fatalf("failed to bind the validator contract: %v", err)
+	}
+	// The bindings read the head state through the client
+	opts := new(bind.CallOpts)
+	if candidates, err := validator.GetCandidates(opts); err != nil || len(candidates) != 1 || candidates[0] != buyer {
+		t.Fatalf("candidates mismatch: have %x, %v, want [%x]", candidates, err, buyer)
+	}
+	if cap, err := validator.GetCandidateCap(opts, buyer); err != nil || cap.Cmp(candidateCap) != 0 {
+		t.Fatalf("candidate cap mismatch: have %v, %v, want %v", cap, err, candidateCap)
+	}
+	if code, err := client.CodeAt(context.Background(), addr, nil); err != nil || len(code) == 0 {
+		t.Fatalf("code mismatch: have %d bytes, %v", len(code), err)
+	}
+	if _, err := client.CodeAt(context.Background(), addr, big.NewInt(0)); err == nil {
+		t.Errorf("past state served")
+	}
+}
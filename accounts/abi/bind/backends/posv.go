@@ -0,0 +1,505 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	tomoxContract "github.com/ethereum/go-ethereum/contracts/tomox/contract"
+	trc21Contract "github.com/ethereum/go-ethereum/contracts/trc21issuer/contract"
+	validatorContract "github.com/ethereum/go-ethereum/contracts/validator/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+const (
+	posvExtraVanity     = 32       // Fixed number of extra-data prefix bytes reserved for signer vanity
+	posvExtraSeal       = 65       // Fixed number of extra-data suffix bytes reserved for signer seal
+	posvMatchGasLimit   = 40000000 // Gas limit of the transactions carrying matched orders, as set by the miner
+	posvDefaultEpoch    = 30
+	posvDefaultGap      = 5
+	posvMaxRelayers     = 100
+	posvMaxRelayerPairs = 100
+)
+
+var (
+	// PosvMasternodeCap is the stake of the single masternode sealing a PosvBackend.
+	PosvMasternodeCap = new(big.Int).Mul(big.NewInt(50000), big.NewInt(params.Ether))
+
+	// PosvRelayerDeposit is the minimum deposit accepted by the relayer
+	// registration contract of a PosvBackend.
+	PosvRelayerDeposit = new(big.Int).Mul(big.NewInt(25000), big.NewInt(params.Ether))
+
+	// posvDeployKey is a throwaway key used to deploy the system contracts on a
+	// SimulatedBackend before copying them into the genesis allocation.
+	posvDeployKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+
+	errPosvAdjustTime   = errors.New("PosvBackend cannot shift the clock of a POSV chain")
+	errOrderSender      = errors.New("order is not signed by its user address")
+	errPosvUnknownBlock = errors.New("unknown block")
+
+	// drainCheckpoints makes sure somebody listens to the checkpoint notifications
+	// the blockchain sends on every epoch, as cmd/tomo does for a real node.
+	drainCheckpoints sync.Once
+)
+
+// This nil assignment ensures compile time that PosvBackend implements bind.ContractBackend.
+var _ bind.ContractBackend = (*PosvBackend)(nil)
+
+// PosvBackend is a SimulatedBackend sealed by a single POSV masternode with the
+// TomoX matching engine attached. Besides contract transactions it accepts
+// OrderTransactions, which are matched when the pending block is committed the
+// same way a masternode does, so that relayer and exchange integrations can be
+// tested without spawning a real node.
+type PosvBackend struct {
+	*SimulatedBackend
+
+	engine   *posv.Posv
+	tomoX    *tomox.TomoX
+	tomoXDir string
+
+	masternodeKey *ecdsa.PrivateKey
+	masternode    common.Address
+
+	pendingTxs    types.Transactions                         // Transactions included in the pending block
+	pendingOrders map[common.Address]types.OrderTransactions // Orders matched in the pending block
+}
+
+// NewPosvBackend creates a new binding backend using a simulated POSV chain for
+// testing purposes. The genesis holds the validator contract with a single
+// masternode candidate and the relayer registration contract on top of the
+// given allocation. A nil config selects a short epoch suitable for tests.
+func NewPosvBackend(alloc core.GenesisAlloc, config *params.PosvConfig) *PosvBackend {
+	posvConfig := &params.PosvConfig{Epoch: posvDefaultEpoch, Gap: posvDefaultGap}
+	if config != nil {
+		*posvConfig = *config
+	}
+	if posvConfig.RewardCheckpoint == 0 {
+		posvConfig.RewardCheckpoint = posvConfig.Epoch
+	}
+	if err := posvConfig.Validate(); err != nil {
+		panic(err)
+	}
+	chainConfig := *params.AllEthashProtocolChanges
+	chainConfig.Ethash = nil
+	chainConfig.Posv = posvConfig
+
+	masternodeKey, _ := crypto.GenerateKey()
+	masternode := crypto.PubkeyToAddress(masternodeKey.PublicKey)
+
+	genesis := core.Genesis{
+		Config:    &chainConfig,
+		Alloc:     core.GenesisAlloc{},
+		GasLimit:  42000000,
+		ExtraData: make([]byte, posvExtraVanity+common.AddressLength+posvExtraSeal),
+	}
+	copy(genesis.ExtraData[posvExtraVanity:], masternode[:])
+	for addr, account := range alloc {
+		genesis.Alloc[addr] = account
+	}
+	if err := allocPosvContracts(genesis.Alloc, masternode); err != nil {
+		panic(fmt.Errorf("failed to deploy system contracts: %v", err))
+	}
+	database, _ := ethdb.NewMemDatabase()
+	genesis.MustCommit(database)
+
+	tomoXDir, err := ioutil.TempDir("", "tomox-simulated")
+	if err != nil {
+		panic(err)
+	}
+	tomoX := tomox.New(&tomox.Config{DataDir: tomoXDir})
+
+	engine := posv.New(posvConfig, database)
+	engine.Authorize(masternode, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, masternodeKey)
+	})
+	engine.GetTomoXService = func() *tomox.TomoX {
+		return tomoX
+	}
+	engine.HookValidator = func(header *types.Header, signers []common.Address) ([]byte, error) {
+		// Every masternode double validates its own blocks
+		var validators []byte
+		for i := range signers {
+			validators = append(validators, common.LeftPadBytes([]byte(strconv.Itoa(i)), posv.M2ByteLength)...)
+		}
+		return validators, nil
+	}
+	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, engine, vm.Config{})
+	// The masternodes of the next epoch are read from the validator contract
+	blockchain.Client, _ = NewChainClient(database, blockchain)
+	engine.HookGetSignersFromContract = func(blockHash common.Hash) ([]common.Address, error) {
+		header := blockchain.GetHeaderByHash(blockHash)
+		if header == nil {
+			return nil, errPosvUnknownBlock
+		}
+		statedb, err := blockchain.StateAt(header.Root)
+		if err != nil {
+			return nil, err
+		}
+		return state.GetCandidates(statedb), nil
+	}
+	drainCheckpoints.Do(func() {
+		go func() {
+			for range core.CheckpointCh {
+			}
+		}()
+	})
+
+	backend := &PosvBackend{
+		SimulatedBackend: &SimulatedBackend{
+			database:   database,
+			blockchain: blockchain,
+			config:     genesis.Config,
+			events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
+		},
+		engine:        engine,
+		tomoX:         tomoX,
+		tomoXDir:      tomoXDir,
+		masternodeKey: masternodeKey,
+		masternode:    masternode,
+	}
+	backend.rollback()
+	return backend
+}
+
+// allocPosvContracts deploys the validator and relayer registration contracts
+// on a SimulatedBackend and copies their code and storage to the well known
+// addresses of the genesis allocation.
+func allocPosvContracts(alloc core.GenesisAlloc, masternode common.Address) error {
+	deployer := crypto.PubkeyToAddress(posvDeployKey.PublicKey)
+	sim := NewSimulatedBackend(core.GenesisAlloc{deployer: {Balance: new(big.Int).Mul(PosvMasternodeCap, big.NewInt(2))}})
+	opts := bind.NewKeyedTransactor(posvDeployKey)
+
+	minVoterCap := new(big.Int).Mul(big.NewInt(10), big.NewInt(params.Ether))
+	validatorAddr, _, _, err := validatorContract.DeployTomoValidator(opts, sim, []common.Address{masternode}, []*big.Int{PosvMasternodeCap}, masternode, PosvMasternodeCap, minVoterCap, big.NewInt(common.MaxMasternodes), big.NewInt(1296000), big.NewInt(86400))
+	if err != nil {
+		return err
+	}
+	relayerAddr, _, _, err := tomoxContract.DeployRelayerRegistration(opts, sim, big.NewInt(posvMaxRelayers), big.NewInt(posvMaxRelayerPairs), PosvRelayerDeposit)
+	if err != nil {
+		return err
+	}
+	sim.Commit()
+
+	contracts := []struct {
+		deployed common.Address
+		target   string
+		balance  *big.Int
+	}{
+		{validatorAddr, common.MasternodeVotingSMC, PosvMasternodeCap},
+		{relayerAddr, common.RelayerRegistrationSMC, new(big.Int)},
+	}
+	for _, contract := range contracts {
+		account, err := sim.DumpContract(contract.deployed)
+		if err != nil {
+			return err
+		}
+		account.Balance = contract.balance
+		alloc[common.HexToAddress(contract.target)] = account
+	}
+	return nil
+}
+
+// Masternode returns the address sealing the simulated chain.
+func (b *PosvBackend) Masternode() common.Address {
+	return b.masternode
+}
+
+// Commit matches the pending orders, seals the pending block with the
+// masternode key and imports it, starting a fresh new state.
+func (b *PosvBackend) Commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block, err := b.engine.Seal(b.blockchain, b.pendingBlock, nil)
+	if err != nil {
+		panic(err) // This cannot happen unless the simulator is wrong, fail in that case
+	}
+	if _, err := b.blockchain.InsertChain([]*types.Block{block}); err != nil {
+		panic(err)
+	}
+	b.rollback()
+}
+
+// Rollback aborts all pending transactions and orders, reverting to the last
+// committed state.
+func (b *PosvBackend) Rollback() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollback()
+}
+
+func (b *PosvBackend) rollback() {
+	b.pendingTxs = nil
+	b.pendingOrders = make(map[common.Address]types.OrderTransactions)
+	if err := b.refresh(); err != nil {
+		panic(err)
+	}
+}
+
+// AdvanceEpoch commits blocks until the chain reaches the next checkpoint and
+// returns its number.
+func (b *PosvBackend) AdvanceEpoch() uint64 {
+	for {
+		b.Commit()
+		if number := b.blockchain.CurrentBlock().NumberU64(); number%b.config.Posv.Epoch == 0 {
+			return number
+		}
+	}
+}
+
+// SendTransaction updates the pending block to include the given transaction.
+// It panics if the transaction is invalid.
+func (b *PosvBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sender, err := types.Sender(types.MakeSigner(b.config, b.pendingBlock.Number()), tx)
+	if err != nil {
+		panic(fmt.Errorf("invalid transaction: %v", err))
+	}
+	nonce := b.pendingState.GetNonce(sender)
+	if tx.Nonce() != nonce {
+		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
+	}
+	b.pendingTxs = append(b.pendingTxs, tx)
+	if err := b.refresh(); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// SendOrderTransaction queues an order to be matched in the pending block. The
+// decimals of the traded tokens are read from their contracts at the latest
+// block, so the tokens must be deployed and committed beforehand.
+func (b *PosvBackend) SendOrderTransaction(ctx context.Context, tx *types.OrderTransaction) error {
+	sender, err := types.OrderSender(types.OrderTxSigner{}, tx)
+	if err != nil {
+		return err
+	}
+	if sender != tx.UserAddress() {
+		return errOrderSender
+	}
+	for _, token := range []common.Address{tx.BaseToken(), tx.QuoteToken()} {
+		if err := b.cacheTokenDecimal(ctx, token); err != nil {
+			return err
+		}
+	}
+	order, err := tomox.NewOrderItem(tx)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := order.VerifyOrder(b.pendingState); err != nil {
+		return err
+	}
+	b.pendingOrders[sender] = append(b.pendingOrders[sender], tx)
+	if err := b.refresh(); err != nil {
+		b.pendingOrders[sender] = b.pendingOrders[sender][:len(b.pendingOrders[sender])-1]
+		return err
+	}
+	return nil
+}
+
+// cacheTokenDecimal feeds the matching engine with the decimal of a TRC21
+// token, which a masternode would otherwise request over IPC.
+func (b *PosvBackend) cacheTokenDecimal(ctx context.Context, token common.Address) error {
+	if token.String() == common.TomoNativeAddress {
+		return nil
+	}
+	caller, err := trc21Contract.NewMyTRC21Caller(token, b)
+	if err != nil {
+		return err
+	}
+	decimals, err := caller.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to get decimals of token %s: %v", token.Hex(), err)
+	}
+	b.tomoX.SetTokenDecimal(token, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	return nil
+}
+
+// AdjustTime is not supported as POSV rejects blocks from the future.
+func (b *PosvBackend) AdjustTime(adjustment time.Duration) error {
+	return errPosvAdjustTime
+}
+
+// Trades returns the trades matched in the given block.
+func (b *PosvBackend) Trades(number uint64) ([]map[string]string, error) {
+	block := b.blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return nil, errPosvUnknownBlock
+	}
+	batches, err := core.ExtractMatchingTransactions(block.Transactions())
+	if err != nil {
+		return nil, err
+	}
+	var trades []map[string]string
+	for _, batch := range batches {
+		for _, match := range batch.Data {
+			trades = append(trades, match.Trades...)
+		}
+	}
+	return trades, nil
+}
+
+// TomoXState returns the order books at the latest block.
+func (b *PosvBackend) TomoXState() (*tomox_state.TomoXStateDB, error) {
+	return b.tomoX.GetTomoxState(b.blockchain.CurrentBlock())
+}
+
+// Close releases and removes the TomoX database.
+func (b *PosvBackend) Close() error {
+	b.tomoX.GetDB().Close()
+	return os.RemoveAll(b.tomoXDir)
+}
+
+// refresh rebuilds the pending block and state on top of the latest block from
+// the pending orders and transactions.
+func (b *PosvBackend) refresh() error {
+	parent := b.blockchain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Time:       parent.Time(),
+	}
+	if err := b.engine.Prepare(b.blockchain, header); err != nil {
+		return err
+	}
+	statedb, err := b.blockchain.StateAt(parent.Root())
+	if err != nil {
+		return err
+	}
+	tomoxState, err := b.tomoX.GetTomoxState(parent)
+	if err != nil {
+		return err
+	}
+	tomox_state.UpgradeMatchingVersion(b.config.TomoXVersion(header.Number), tomoxState)
+	if b.config.IsPriceOracle(header.Number) {
+		tomox_state.ActivatePriceOracle(tomoxState)
+	}
+	if header.Number.Uint64()%b.config.Posv.Epoch == 0 && b.config.IsRelayerFee(header.Number) {
+		tomox_state.RefreshRelayerFees(tomoxState, statedb)
+	}
+	// Match the orders first and record the outcome in the special
+	// transactions leading the block, as the miner does.
+	pending := make(map[common.Address]types.OrderTransactions, len(b.pendingOrders))
+	for addr, orders := range b.pendingOrders {
+		pending[addr] = orders
+	}
+	matches := b.tomoX.ProcessOrderPending(b.masternode, b.blockchain.IPCEndpoint, pending, statedb, tomoxState, header.Number.Uint64(), tomox.OrderBudget{})
+	if tomox.StateMatchingRules(tomoxState).IsExpiry {
+		tomox_state.PruneExpiredOrders(header.Number.Uint64(), tomoxState)
+	}
+	tomox_state.UpdatePriceOracle(header.Number.Uint64(), tomoxState, statedb)
+	specialTxs, err := b.matchingTransactions(statedb.GetNonce(b.masternode), matches, tomoxState.IntermediateRoot())
+	if err != nil {
+		return err
+	}
+	var (
+		txs            = append(specialTxs, b.pendingTxs...)
+		receipts       types.Receipts
+		usedGas        = new(uint64)
+		gp             = new(core.GasPool).AddGas(header.GasLimit)
+		feeCapacity    = state.GetTRC21FeeCapacityFromState(statedb)
+		balanceUpdated = map[common.Address]*big.Int{}
+		totalFeeUsed   = big.NewInt(0)
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		receipt, gas, err, tokenFeeUsed := core.ApplyTransaction(b.config, feeCapacity, b.blockchain, &b.masternode, gp, statedb, header, tx, usedGas, vm.Config{})
+		if err != nil {
+			return fmt.Errorf("invalid transaction %s: %v", tx.Hash().Hex(), err)
+		}
+		receipts = append(receipts, receipt)
+		if tokenFeeUsed {
+			fee := new(big.Int).SetUint64(gas)
+			if header.Number.Cmp(common.TIPTRC21Fee) > 0 {
+				fee = fee.Mul(fee, common.TRC21GasPrice)
+			}
+			feeCapacity[*tx.To()] = new(big.Int).Sub(feeCapacity[*tx.To()], fee)
+			balanceUpdated[*tx.To()] = feeCapacity[*tx.To()]
+			totalFeeUsed = totalFeeUsed.Add(totalFeeUsed, fee)
+		}
+	}
+	state.UpdateTRC21Fee(statedb, balanceUpdated, totalFeeUsed)
+	header.GasUsed = *usedGas
+
+	var trades types.Trades
+	if b.config.IsTradeRoot(header.Number) {
+		trades = tomox.MatchedTrades(matches)
+		header.TradeRoot = types.DeriveSha(trades)
+	}
+	block, err := b.engine.Finalize(b.blockchain, header, statedb, txs, nil, receipts)
+	if err != nil {
+		return err
+	}
+	if trades != nil {
+		block = block.WithTrades(trades)
+	}
+	b.pendingBlock = block
+	b.pendingState = statedb
+	return nil
+}
+
+// matchingTransactions builds the transactions carrying the matched orders and
+// the resulting TomoX state root, signed by the masternode.
+func (b *PosvBackend) matchingTransactions(nonce uint64, matches []tomox.TxDataMatch, root common.Hash) (types.Transactions, error) {
+	data, err := tomox.EncodeTxMatchesBatch(tomox.TxMatchBatch{
+		Data:      matches,
+		Timestamp: time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	signer := types.MakeSigner(b.config, b.blockchain.CurrentBlock().Number())
+	matchTx, err := types.SignTx(types.NewTransaction(nonce, common.HexToAddress(common.TomoXAddr), big.NewInt(0), posvMatchGasLimit, big.NewInt(0), data), signer, b.masternodeKey)
+	if err != nil {
+		return nil, err
+	}
+	rootTx, err := types.SignTx(types.NewTransaction(nonce, common.HexToAddress(common.TomoXStateAddr), big.NewInt(0), posvMatchGasLimit, big.NewInt(0), root.Bytes()), signer, b.masternodeKey)
+	if err != nil {
+		return nil, err
+	}
+	return types.Transactions{matchTx, rootTx}, nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	tomoxContract "github.com/ethereum/go-ethereum/contracts/tomox/contract"
+	trc21Contract "github.com/ethereum/go-ethereum/contracts/trc21issuer/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+var (
+	sellerKey, _       = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	buyerKey, _        = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7b")
+	relayerOwnerKey, _ = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
+
+	seller       = crypto.PubkeyToAddress(sellerKey.PublicKey)
+	buyer        = crypto.PubkeyToAddress(buyerKey.PublicKey)
+	relayerOwner = crypto.PubkeyToAddress(relayerOwnerKey.PublicKey)
+	relayer      = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+)
+
+func signOrder(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, side string, quantity, price *big.Int, token common.Address) *types.OrderTransaction {
+	tx := types.NewOrderTransaction(nonce, quantity, price, relayer, crypto.PubkeyToAddress(key.PublicKey), token, common.HexToAddress(common.TomoNativeAddress), "NEW", side, tomox_state.Limit, "TOKEN/TOMO", common.Hash{}, 0)
+	tx.SetOrderHash(types.OrderTxSigner{}.Hash(tx))
+	signed, err := types.OrderSignTx(tx, types.OrderTxSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign order: %v", err)
+	}
+	return signed
+}
+
+func TestPosvBackendMatchOrders(t *testing.T) {
+	funds := new(big.Int).Mul(big.NewInt(100000), big.NewInt(params.Ether))
+	sim := NewPosvBackend(core.GenesisAlloc{
+		seller:       {Balance: funds},
+		buyer:        {Balance: funds},
+		relayerOwner: {Balance: funds},
+	}, nil)
+	defer sim.Close()
+
+	// Issue a token and list it against TOMO on a fresh relayer
+	tokenCap := new(big.Int).Mul(big.NewInt(1000000), big.NewInt(params.Ether))
+	token, _, trc21, err := trc21Contract.DeployMyTRC21(bind.NewKeyedTransactor(sellerKey), sim, "Token", "TOKEN", 18, tokenCap, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("failed to deploy token: %v", err)
+	}
+	sim.Commit()
+
+	registration, err := tomoxContract.NewRelayerRegistration(common.HexToAddress(common.RelayerRegistrationSMC), sim)
+	if err != nil {
+		t.Fatalf("failed to bind relayer registration: %v", err)
+	}
+	opts := bind.NewKeyedTransactor(relayerOwnerKey)
+	opts.Value = PosvRelayerDeposit
+	if _, err := registration.Register(opts, relayer, 10, []common.Address{token}, []common.Address{common.HexToAddress(common.TomoNativeAddress)}); err != nil {
+		t.Fatalf("failed to register relayer: %v", err)
+	}
+	sim.Commit()
+
+	if number := sim.AdvanceEpoch(); number%sim.config.Posv.Epoch != 0 {
+		t.Fatalf("chain stopped at block %d, want a checkpoint", number)
+	}
+
+	// Cross a sell and a buy order, both should be filled in the next block
+	quantity := new(big.Int).Mul(big.NewInt(10), big.NewInt(params.Ether))
+	price := big.NewInt(params.Ether)
+	ctx := context.Background()
+	if err := sim.SendOrderTransaction(ctx, signOrder(t, sellerKey, 0, tomox_state.Ask, quantity, price, token)); err != nil {
+		t.Fatalf("failed to send sell order: %v", err)
+	}
+	if err := sim.SendOrderTransaction(ctx, signOrder(t, buyerKey, 0, tomox_state.Bid, quantity, price, token)); err != nil {
+		t.Fatalf("failed to send buy order: %v", err)
+	}
+	sim.Commit()
+
+	trades, err := sim.Trades(sim.blockchain.CurrentBlock().NumberU64())
+	if err != nil {
+		t.Fatalf("failed to get trades: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("trade count mismatch: have %d, want 1", len(trades))
+	}
+	balance, err := trc21.BalanceOf(nil, buyer)
+	if err != nil {
+		t.Fatalf("failed to get buyer balance: %v", err)
+	}
+	if balance.Cmp(quantity) != 0 {
+		t.Errorf("buyer balance mismatch: have %v, want %v", balance, quantity)
+	}
+}
+
+func TestPosvBackendRejectOrder(t *testing.T) {
+	sim := NewPosvBackend(core.GenesisAlloc{}, nil)
+	defer sim.Close()
+
+	// The relayer is not registered, so the order must be refused
+	tx := signOrder(t, sellerKey, 0, tomox_state.Ask, big.NewInt(1), big.NewInt(1), common.HexToAddress(common.TomoNativeAddress))
+	if err := sim.SendOrderTransaction(context.Background(), tx); err != tomox_state.ErrInvalidRelayer {
+		t.Errorf("error mismatch: have %v, want %v", err, tomox_state.ErrInvalidRelayer)
+	}
+	if err := sim.AdjustTime(0); err != errPosvAdjustTime {
+		t.Errorf("error mismatch: have %v, want %v", err, errPosvAdjustTime)
+	}
+}
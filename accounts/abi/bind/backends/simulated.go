@@ -17,6 +17,7 @@
 package backends
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -38,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -170,6 +172,29 @@ func (b *SimulatedBackend) ForEachStorageAt(ctx context.Context, contract common
 	return nil
 }
 
+// DumpContract extracts the code and the decoded storage of a contract, to
+// copy it into a genesis allocation.
+func (b *SimulatedBackend) DumpContract(addr common.Address) (core.GenesisAccount, error) {
+	code, err := b.CodeAt(context.Background(), addr, nil)
+	if err != nil {
+		return core.GenesisAccount{}, err
+	}
+	storage := make(map[common.Hash]common.Hash)
+	err = b.ForEachStorageAt(context.Background(), addr, nil, func(key, val common.Hash) bool {
+		var decoded []byte
+		if err = rlp.DecodeBytes(bytes.TrimLeft(val.Bytes(), "\x00"), &decoded); err != nil {
+			err = fmt.Errorf("invalid storage slot %x of %x: %v", key, addr, err)
+			return false
+		}
+		storage[key] = common.BytesToHash(decoded)
+		return true
+	})
+	if err != nil {
+		return core.GenesisAccount{}, err
+	}
+	return core.GenesisAccount{Code: code, Storage: storage}, nil
+}
+
 // TransactionReceipt returns the receipt of a transaction.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	receipt, _, _, _ := core.GetReceipt(b.database, txHash)
@@ -470,6 +495,12 @@ func (fb *filterBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Su
 		return nil
 	})
 }
+func (fb *filterBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
 func (fb *filterBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return fb.bc.SubscribeChainEvent(ch)
 }
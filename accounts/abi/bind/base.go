@@ -53,6 +53,11 @@ type TransactOpts struct {
 	GasPrice *big.Int // Gas price to use for the transaction execution (nil = gas price oracle)
 	GasLimit uint64   // Gas limit to set for the transaction execution (0 = estimate)
 
+	// Sponsored pays the fee of a call to a TRC21 token in the token instead of
+	// TOMO (TomoZ): the TRC21 issuer pays the gas at the TRC21 gas price from the
+	// fee capacity of the token, which charges its own fee to the sender.
+	Sponsored bool
+
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
 
@@ -199,8 +204,14 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 	} else {
 		nonce = opts.Nonce.Uint64()
 	}
+	if opts.Sponsored && contract == nil {
+		return nil, errors.New("contract creation can't be sponsored")
+	}
 	// Figure out the gas allowance and gas price values
 	gasPrice := opts.GasPrice
+	if gasPrice == nil && opts.Sponsored {
+		gasPrice = common.TRC21GasPrice
+	}
 	if gasPrice == nil {
 		gasPrice, err = c.transactor.SuggestGasPrice(ensureContext(opts.Context))
 		if err != nil {
@@ -224,6 +235,11 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
 		}
 	}
+	if opts.Sponsored {
+		if err := c.checkSponsorship(opts, gasLimit); err != nil {
+			return nil, err
+		}
+	}
 	// Create the transaction, sign it and schedule it for execution
 	var rawTx *types.Transaction
 	if contract == nil {
@@ -244,6 +260,30 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 	return signedTx, nil
 }
 
+// getTokenCapacitySig is the selector of the getTokenCapacity(address) method of
+// the TRC21 issuer, returning the fee capacity of a token.
+var getTokenCapacitySig = crypto.Keccak256([]byte("getTokenCapacity(address)"))[:4]
+
+// checkSponsorship ensures the fee capacity of the bound contract in the TRC21
+// issuer covers the gas of a sponsored transaction.
+func (c *BoundContract) checkSponsorship(opts *TransactOpts, gasLimit uint64) error {
+	issuer := common.TRC21IssuerSMC
+	msg := ethereum.CallMsg{
+		From: opts.From,
+		To:   &issuer,
+		Data: append(append([]byte{}, getTokenCapacitySig...), c.address.Hash().Bytes()...),
+	}
+	output, err := c.caller.CallContract(ensureContext(opts.Context), msg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve fee capacity: %v", err)
+	}
+	capacity := new(big.Int).SetBytes(output)
+	if capacity.Cmp(new(big.Int).Mul(common.TRC21GasPrice, new(big.Int).SetUint64(gasLimit))) < 0 {
+		return ErrNotSponsored
+	}
+	return nil
+}
+
 // FilterLogs filters contract logs for past blocks, returning the necessary
 // channels to construct a strongly typed bound iterator on top of them.
 func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
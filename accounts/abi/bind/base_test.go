@@ -0,0 +1,99 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// sponsorBackend is a contract backend with a TRC21 issuer returning a fixed fee
+// capacity, recording the transactions sent.
+type sponsorBackend struct {
+	capacity *big.Int
+	sent     []*types.Transaction
+}
+
+func (b *sponsorBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (b *sponsorBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if *call.To != common.TRC21IssuerSMC || !strings.HasPrefix(string(call.Data), string(crypto.Keccak256([]byte("getTokenCapacity(address)"))[:4])) {
+		return nil, nil
+	}
+	return common.BigToHash(b.capacity).Bytes(), nil
+}
+
+func (b *sponsorBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (b *sponsorBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (b *sponsorBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (b *sponsorBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return 50000, nil
+}
+
+func (b *sponsorBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func TestSponsoredTransact(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[{"name":"","type":"bool"}],"type":"function"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse abi: %v", err)
+	}
+	backend := &sponsorBackend{capacity: new(big.Int).Mul(common.TRC21GasPrice, big.NewInt(50000))}
+	token := bind.NewBoundContract(common.HexToAddress("0x0a"), parsed, backend, backend, nil)
+
+	opts := bind.NewKeyedTransactor(testKey)
+	opts.Sponsored = true
+	tx, err := token.Transact(opts, "transfer", common.HexToAddress("0x0b"), big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to send sponsored transaction: %v", err)
+	}
+	if tx.GasPrice().Cmp(common.TRC21GasPrice) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", tx.GasPrice(), common.TRC21GasPrice)
+	}
+	if len(backend.sent) != 1 {
+		t.Fatalf("sent transactions mismatch: have %d, want 1", len(backend.sent))
+	}
+	// The fee capacity must cover the gas of the transaction
+	backend.capacity.Sub(backend.capacity, big.NewInt(1))
+	if _, err := token.Transact(opts, "transfer", common.HexToAddress("0x0b"), big.NewInt(1)); err != bind.ErrNotSponsored {
+		t.Errorf("transaction over capacity: have %v, want %v", err, bind.ErrNotSponsored)
+	}
+	if len(backend.sent) != 1 {
+		t.Errorf("transaction over capacity sent")
+	}
+}
@@ -37,6 +37,10 @@ import (
 
 const (
 	version = 3
+
+	// versionArgon2 is the version of the key files encrypted with a key derived
+	// by argon2id, laid out like the ones of version 3.
+	versionArgon2 = 4
 )
 
 type Key struct {
@@ -25,6 +25,7 @@ import (
 	crand "crypto/rand"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -41,9 +42,10 @@ import (
 )
 
 var (
-	ErrLocked  = accounts.NewAuthNeededError("password or unlock")
-	ErrNoMatch = errors.New("no key for given address or file")
-	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+	ErrLocked      = accounts.NewAuthNeededError("password or unlock")
+	ErrNoMatch     = errors.New("no key for given address or file")
+	ErrDecrypt     = errors.New("could not decrypt key with given passphrase")
+	ErrUnencrypted = errors.New("key files not encrypted")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -78,7 +80,16 @@ type unlocked struct {
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP}}
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, nil}}
+	ks.init(keydir)
+	return ks
+}
+
+// NewKeyStoreArgon2 creates a keystore for the given directory, storing the new
+// and updated keys in version 4 key files derived with argon2id.
+func NewKeyStoreArgon2(keydir string, params Argon2Params) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, 0, 0, &params}}
 	ks.init(keydir)
 	return ks
 }
@@ -423,13 +434,10 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.encryptKey(key, newPassphrase)
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return EncryptKey(key, newPassphrase, StandardScryptN, StandardScryptP)
 }
 
 // Import stores the given encrypted JSON key into the key directory.
@@ -472,6 +480,30 @@ func (ks *KeyStore) Update(a accounts.Account, passphrase, newPassphrase string)
 	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
 }
 
+// Upgrade re-encrypts the key file of an account in place, in the version and
+// with the KDF parameters of the keystore, keeping its passphrase. It reports
+// whether the file needed it.
+func (ks *KeyStore) Upgrade(a accounts.Account, passphrase string) (bool, error) {
+	store, ok := ks.storage.(*keyStorePassphrase)
+	if !ok {
+		return false, ErrUnencrypted
+	}
+	a, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return false, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	keyjson, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		return false, err
+	}
+	if store.current(keyjson) {
+		return false, nil
+	}
+	return true, store.StoreKey(a.URL.Path, key, passphrase)
+}
+
 // ImportPreSaleKey decrypts the given Ethereum presale wallet and stores
 // a key file in the key directory. The key file is encrypted with the same passphrase.
 func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (accounts.Account, error) {
@@ -39,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/argon2"
 	"github.com/ethereum/go-ethereum/crypto/randentropy"
 	"github.com/pborman/uuid"
 	"golang.org/x/crypto/pbkdf2"
@@ -46,7 +47,8 @@ import (
 )
 
 const (
-	keyHeaderKDF = "scrypt"
+	keyHeaderKDF       = "scrypt"
+	keyHeaderKDFArgon2 = "argon2id"
 
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
@@ -68,10 +70,41 @@ const (
 	scryptDKLen = 32
 )
 
+// Argon2Params are the argon2id parameters of the version 4 key files.
+type Argon2Params struct {
+	Time    uint32 // Number of passes over the memory
+	Memory  uint32 // Memory used in KiB
+	Threads uint8  // Number of lanes computed in parallel
+}
+
+var (
+	// StandardArgon2 are the argon2id parameters using 256MB memory and taking
+	// approximately 1s CPU time on a modern processor.
+	StandardArgon2 = Argon2Params{Time: 3, Memory: 256 * 1024, Threads: 4}
+
+	// LightArgon2 are the argon2id parameters using 4MB memory and taking
+	// approximately 20ms CPU time on a modern processor.
+	LightArgon2 = Argon2Params{Time: 3, Memory: 4 * 1024, Threads: 4}
+)
+
+// maxArgon2Memory is the memory in KiB the key files may require to be
+// decrypted, 4GB.
+const maxArgon2Memory = 4 * 1024 * 1024
+
+// validate checks that the parameters are within the bounds of argon2id and
+// of the memory allowed.
+func (p Argon2Params) validate() error {
+	if p.Time < 1 || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+		return fmt.Errorf("Unsupported argon2id parameters: t=%d, m=%d, p=%d", p.Time, p.Memory, p.Threads)
+	}
+	return nil
+}
+
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
 	scryptP     int
+	argon2      *Argon2Params // Parameters of the version 4 key files, version 3 if nil
 }
 
 func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
@@ -93,18 +126,51 @@ func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string)
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (common.Address, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP}, crand.Reader, auth)
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP, nil}, crand.Reader, auth)
+	return a.Address, err
+}
+
+// StoreKeyArgon2 generates a key, encrypts with 'auth' in a version 4 key file
+// and stores in the given directory
+func StoreKeyArgon2(dir, auth string, params Argon2Params) (common.Address, error) {
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, 0, 0, &params}, crand.Reader, auth)
 	return a.Address, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := ks.encryptKey(key, auth)
 	if err != nil {
 		return err
 	}
 	return writeKeyFile(filename, keyjson)
 }
 
+// encryptKey encrypts a key in the version of the key files of the store.
+func (ks keyStorePassphrase) encryptKey(key *Key, auth string) ([]byte, error) {
+	if ks.argon2 != nil {
+		return EncryptKeyArgon2(key, auth, *ks.argon2)
+	}
+	return EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+}
+
+// current reports whether a key file is encrypted in the version and with the
+// KDF parameters of the key files of the store.
+func (ks keyStorePassphrase) current(keyjson []byte) bool {
+	k := new(encryptedKeyJSONV3)
+	if err := json.Unmarshal(keyjson, k); err != nil {
+		return false
+	}
+	params, kdf := k.Crypto.KDFParams, k.Crypto.KDF
+	if ks.argon2 != nil {
+		return k.Version == versionArgon2 && kdf == keyHeaderKDFArgon2 &&
+			paramEquals(params["t"], int(ks.argon2.Time)) &&
+			paramEquals(params["m"], int(ks.argon2.Memory)) &&
+			paramEquals(params["p"], int(ks.argon2.Threads))
+	}
+	return k.Version == version && kdf == keyHeaderKDF &&
+		paramEquals(params["n"], ks.scryptN) && paramEquals(params["p"], ks.scryptP)
+}
+
 func (ks keyStorePassphrase) JoinPath(filename string) string {
 	if filepath.IsAbs(filename) {
 		return filename
@@ -122,6 +188,37 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	scryptParamsJSON := make(map[string]interface{}, 5)
+	scryptParamsJSON["n"] = scryptN
+	scryptParamsJSON["r"] = scryptR
+	scryptParamsJSON["p"] = scryptP
+	scryptParamsJSON["dklen"] = scryptDKLen
+	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptKeyJSON(key, derivedKey, keyHeaderKDF, scryptParamsJSON, version)
+}
+
+// EncryptKeyArgon2 encrypts a key using the specified argon2id parameters into
+// a version 4 json blob that can be decrypted later on.
+func EncryptKeyArgon2(key *Key, auth string, params Argon2Params) ([]byte, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey := argon2.IDKey([]byte(auth), salt, params.Time, params.Memory, params.Threads, scryptDKLen)
+
+	argon2ParamsJSON := make(map[string]interface{}, 5)
+	argon2ParamsJSON["t"] = params.Time
+	argon2ParamsJSON["m"] = params.Memory
+	argon2ParamsJSON["p"] = params.Threads
+	argon2ParamsJSON["dklen"] = scryptDKLen
+	argon2ParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptKeyJSON(key, derivedKey, keyHeaderKDFArgon2, argon2ParamsJSON, versionArgon2)
+}
+
+// encryptKeyJSON encrypts a key with a derived key into a json blob.
+func encryptKeyJSON(key *Key, derivedKey []byte, kdf string, kdfParams map[string]interface{}, version int) ([]byte, error) {
 	encryptKey := derivedKey[:16]
 	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
 
@@ -132,13 +229,6 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
-	scryptParamsJSON := make(map[string]interface{}, 5)
-	scryptParamsJSON["n"] = scryptN
-	scryptParamsJSON["r"] = scryptR
-	scryptParamsJSON["p"] = scryptP
-	scryptParamsJSON["dklen"] = scryptDKLen
-	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
-
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
@@ -147,8 +237,8 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          keyHeaderKDF,
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf,
+		KDFParams:    kdfParams,
 		MAC:          hex.EncodeToString(mac),
 	}
 	encryptedKeyJSONV3 := encryptedKeyJSONV3{
@@ -199,9 +289,12 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 }
 
 func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byte, keyId []byte, err error) {
-	if keyProtected.Version != version {
+	if keyProtected.Version != version && keyProtected.Version != versionArgon2 {
 		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
 	}
+	if (keyProtected.Version == versionArgon2) != (keyProtected.Crypto.KDF == keyHeaderKDFArgon2) {
+		return nil, nil, fmt.Errorf("KDF %v not supported in version %v", keyProtected.Crypto.KDF, keyProtected.Version)
+	}
 
 	if keyProtected.Crypto.Cipher != "aes-128-ctr" {
 		return nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
@@ -296,6 +389,19 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 		}
 		key := pbkdf2.Key(authArray, salt, c, dkLen, sha256.New)
 		return key, nil
+
+	} else if cryptoJSON.KDF == keyHeaderKDFArgon2 {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t < 0 || m < 0 || m > maxArgon2Memory || p < 0 || p > 0xff {
+			return nil, fmt.Errorf("Unsupported argon2id parameters: t=%d, m=%d, p=%d", t, m, p)
+		}
+		params := Argon2Params{Time: uint32(t), Memory: uint32(m), Threads: uint8(p)}
+		if err := params.validate(); err != nil {
+			return nil, err
+		}
+		return argon2.IDKey(authArray, salt, params.Time, params.Memory, params.Threads, uint32(dkLen)), nil
 	}
 
 	return nil, fmt.Errorf("Unsupported KDF: %s", cryptoJSON.KDF)
@@ -311,3 +417,14 @@ func ensureInt(x interface{}) int {
 	}
 	return res
 }
+
+// paramEquals reports whether a KDF parameter decoded from json has a value.
+func paramEquals(x interface{}, value int) bool {
+	switch x := x.(type) {
+	case int:
+		return x == value
+	case float64:
+		return x == float64(value)
+	}
+	return false
+}
@@ -17,6 +17,7 @@
 package keystore
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"testing"
 
@@ -28,6 +29,8 @@ const (
 	veryLightScryptP = 1
 )
 
+var veryLightArgon2 = Argon2Params{Time: 1, Memory: 8, Threads: 1}
+
 // Tests that a json key file can be decrypted and encrypted in multiple rounds.
 func TestKeyEncryptDecrypt(t *testing.T) {
 	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
@@ -58,3 +61,44 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that keys encrypted with argon2id are stored in version 4 key files and
+// can be decrypted again.
+func TestKeyEncryptDecryptArgon2(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if keyjson, err = EncryptKeyArgon2(key, "foo", veryLightArgon2); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	var k encryptedKeyJSONV3
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		t.Fatal(err)
+	}
+	if k.Version != 4 || k.Crypto.KDF != "argon2id" {
+		t.Fatalf("key file mismatch: version %d, kdf %s", k.Version, k.Crypto.KDF)
+	}
+	if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+		t.Fatalf("key decrypted with bad password: %v", err)
+	}
+	decrypted, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("key failed to decrypt: %v", err)
+	}
+	if decrypted.Address != key.Address || decrypted.PrivateKey.D.Cmp(key.PrivateKey.D) != 0 {
+		t.Fatalf("key mismatch: have %x, want %x", decrypted.Address, key.Address)
+	}
+	// Parameters beyond the memory allowed are rejected before deriving
+	k.Crypto.KDFParams["m"] = maxArgon2Memory + 1
+	if keyjson, err = json.Marshal(k); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptKey(keyjson, "foo"); err == nil || err == ErrDecrypt {
+		t.Fatalf("key with excessive memory parameter not rejected: %v", err)
+	}
+}
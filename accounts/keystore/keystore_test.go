@@ -18,6 +18,7 @@ package keystore
 
 import (
 	"io/ioutil"
+	"math/big"
 	"math/rand"
 	"os"
 	"runtime"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 )
 
@@ -68,6 +70,42 @@ func TestKeyStore(t *testing.T) {
 	}
 }
 
+// Tests that key files are upgraded in place to the version and KDF parameters
+// of the keystore, once.
+func TestUpgrade(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded, err := ks.Upgrade(a, "foo"); err != nil || upgraded {
+		t.Fatalf("current key file upgraded: %v, %v", upgraded, err)
+	}
+	v4 := NewKeyStoreArgon2(dir, veryLightArgon2)
+	if _, err := v4.Upgrade(a, "bar"); err != ErrDecrypt {
+		t.Fatalf("key file upgraded with bad password: %v", err)
+	}
+	if upgraded, err := v4.Upgrade(a, "foo"); err != nil || !upgraded {
+		t.Fatalf("version 3 key file not upgraded: %v, %v", upgraded, err)
+	}
+	keyjson, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(keyjson), `"version":4`) {
+		t.Fatalf("key file not in version 4: %s", keyjson)
+	}
+	if upgraded, err := v4.Upgrade(a, "foo"); err != nil || upgraded {
+		t.Fatalf("upgraded key file upgraded again: %v, %v", upgraded, err)
+	}
+	// The upgraded key still unlocks with its passphrase in any keystore
+	if err := ks.Unlock(a, "foo"); err != nil {
+		t.Fatalf("upgraded key failed to unlock: %v", err)
+	}
+}
+
 func TestSign(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -113,6 +151,37 @@ func TestSignWithPassphrase(t *testing.T) {
 	}
 }
 
+// Tests that text signatures of an order hash are valid TomoX order signatures.
+func TestSignTextOrder(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	pass := "passwd"
+	acc, err := ks.NewAccount(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := ks.Wallets()[0]
+
+	signer := types.OrderTxSigner{}
+	order := types.NewOrderTransaction(1, big.NewInt(100), big.NewInt(2), common.Address{1}, acc.Address, common.Address{2}, common.Address{3}, types.OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+
+	if _, err := wallet.SignText(acc, signer.Hash(order).Bytes()); err != ErrLocked {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	sig, err := wallet.SignTextWithPassphrase(acc, pass, signer.Hash(order).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := order.WithSignature(signer, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := types.OrderSender(signer, signed); err != nil || from != acc.Address {
+		t.Fatalf("order sender mismatch: have %x (%v), want %x", from, err, acc.Address)
+	}
+}
+
 func TestTimedUnlock(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -159,6 +159,15 @@ func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transactio
 	return w.trezorSign(path, tx, chainID)
 }
 
+// SignText implements usbwallet.driver, sending the text to the Trezor and
+// waiting for the user to confirm or deny signing it.
+func (w *trezorDriver) SignText(path accounts.DerivationPath, text []byte) (common.Address, []byte, error) {
+	if w.device == nil {
+		return common.Address{}, nil, accounts.ErrWalletClosed
+	}
+	return w.trezorSignText(path, text)
+}
+
 // trezorDerive sends a derivation request to the Trezor device and returns the
 // Ethereum address located on that path.
 func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
@@ -235,6 +244,30 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 	return sender, signed, nil
 }
 
+// trezorSignText sends the text to the Trezor wallet, and waits for the user to
+// confirm or deny signing it as an Ethereum personal message.
+func (w *trezorDriver) trezorSignText(derivationPath []uint32, text []byte) (common.Address, []byte, error) {
+	response := new(trezor.EthereumMessageSignature)
+	if _, err := w.trezorExchange(&trezor.EthereumSignMessage{AddressN: derivationPath, Message: text}, response); err != nil {
+		return common.Address{}, nil, err
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	reply := response.GetSignature()
+	if len(reply) != 65 || reply[64] < 27 {
+		return common.Address{}, nil, errors.New("reply lacks signature")
+	}
+	signature := append(reply[:64:64], reply[64]-27)
+
+	signer, err := textSigner(text, signature)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	if signer != common.BytesToAddress(response.GetAddress()) {
+		return common.Address{}, nil, fmt.Errorf("signer mismatch: reported %x, recovered %s", response.GetAddress(), signer.Hex())
+	}
+	return signer, signature, nil
+}
+
 // trezorExchange performs a data exchange with the Trezor wallet, sending it a
 // message and retrieving the response. If multiple responses are possible, the
 // method will also return the index of the destination object used.
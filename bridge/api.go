@@ -0,0 +1,92 @@
+package bridge
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RPCAttestation is the attestation of an event returned over RPC. The
+// signatures are ordered as the signers, with a recovery id of 27 or 28 as
+// expected by ecrecover.
+type RPCAttestation struct {
+	ID          common.Hash      `json:"id"`
+	ChainID     *hexutil.Big     `json:"chainId"`
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	BlockHash   common.Hash      `json:"blockHash"`
+	TxHash      common.Hash      `json:"transactionHash"`
+	LogIndex    hexutil.Uint     `json:"logIndex"`
+	Address     common.Address   `json:"address"`
+	Topics      []common.Hash    `json:"topics"`
+	Data        hexutil.Bytes    `json:"data"`
+	Signers     []common.Address `json:"signers"`
+	Signatures  []hexutil.Bytes  `json:"signatures"`
+	Threshold   hexutil.Uint     `json:"threshold"`
+	Complete    bool             `json:"complete"`
+}
+
+func newRPCAttestation(a *Attestation) *RPCAttestation {
+	event := a.Event
+	result := &RPCAttestation{
+		ID:          event.ID(),
+		ChainID:     (*hexutil.Big)(event.ChainID),
+		BlockNumber: hexutil.Uint64(event.BlockNumber),
+		BlockHash:   event.BlockHash,
+		TxHash:      event.TxHash,
+		LogIndex:    hexutil.Uint(event.LogIndex),
+		Address:     event.Address,
+		Topics:      event.Topics,
+		Data:        event.Data,
+		Signers:     a.Signers(),
+		Threshold:   hexutil.Uint(a.Threshold()),
+		Complete:    a.Complete(),
+	}
+	for _, signer := range result.Signers {
+		sig := common.CopyBytes(a.Signatures[signer])
+		sig[64] += 27
+		result.Signatures = append(result.Signatures, sig)
+	}
+	return result
+}
+
+// PublicBridgeAPI provides the attestations of the bridge events.
+type PublicBridgeAPI struct {
+	b *Bridge
+}
+
+// NewPublicBridgeAPI creates a new bridge API.
+func NewPublicBridgeAPI(b *Bridge) *PublicBridgeAPI {
+	return &PublicBridgeAPI{b}
+}
+
+// GetAttestation returns the attestation of an event by its id, nil if the
+// event is unknown, not yet final or out of the retention.
+func (api *PublicBridgeAPI) GetAttestation(ctx context.Context, id common.Hash) *RPCAttestation {
+	if attestation := api.b.pool.attestation(id); attestation != nil {
+		return newRPCAttestation(attestation)
+	}
+	return nil
+}
+
+// GetAttestations returns the attestations of the events of a block, ordered
+// by log index. The latest block is the last final one.
+func (api *PublicBridgeAPI) GetAttestations(ctx context.Context, blockNr rpc.BlockNumber) []*RPCAttestation {
+	var number uint64
+	switch blockNr {
+	case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+		if head := api.b.eth.BlockChain().CurrentBlock().NumberU64(); head >= api.b.config.Confirmations {
+			number = head - api.b.config.Confirmations
+		}
+	default:
+		number = uint64(blockNr.Int64())
+	}
+	attestations := api.b.pool.block(number)
+
+	results := make([]*RPCAttestation, len(attestations))
+	for i, attestation := range attestations {
+		results[i] = newRPCAttestation(attestation)
+	}
+	return results
+}
@@ -0,0 +1,322 @@
+package bridge
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// maxOrphans is the maximum number of votes kept for the events not yet
+	// final locally, the peers ahead of the local chain signing them first.
+	maxOrphans = 4096
+
+	// orphanBlocks is the number of blocks the votes of an event not final
+	// locally are kept for.
+	orphanBlocks = 1024
+)
+
+var (
+	errNotMasternode = errors.New("signer is not a masternode of the event epoch")
+	errInvalidVote   = errors.New("invalid vote signature")
+)
+
+// Event is a log of a bridge contract, attested by the masternodes once final.
+type Event struct {
+	ChainID     *big.Int
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	LogIndex    uint
+	Address     common.Address
+	Topics      []common.Hash
+	Data        []byte
+}
+
+// ID returns the hash signed by the masternodes attesting the event, the
+// keccak256 hash of its RLP encoding.
+func (e *Event) ID() (id common.Hash) {
+	hw := sha3.NewKeccak256()
+	rlp.Encode(hw, e)
+	hw.Sum(id[:0])
+	return id
+}
+
+// findEvents returns the events of the contracts logged in a block.
+func findEvents(chainID *big.Int, block *types.Block, receipts types.Receipts, contracts []common.Address) []*Event {
+	var (
+		events []*Event
+		index  uint
+	)
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			for _, contract := range contracts {
+				if log.Address == contract && i < len(block.Transactions()) {
+					events = append(events, &Event{
+						ChainID:     chainID,
+						BlockNumber: block.NumberU64(),
+						BlockHash:   block.Hash(),
+						TxHash:      block.Transactions()[i].Hash(),
+						LogIndex:    index,
+						Address:     log.Address,
+						Topics:      log.Topics,
+						Data:        log.Data,
+					})
+					break
+				}
+			}
+			index++
+		}
+	}
+	return events
+}
+
+// Vote is the signature of an event by a masternode.
+type Vote struct {
+	ID        common.Hash
+	Signature []byte
+}
+
+// Hash returns the hash identifying the vote among the ones relayed.
+func (v *Vote) Hash() common.Hash {
+	return crypto.Keccak256Hash(v.ID[:], v.Signature)
+}
+
+// Signer recovers the address of the masternode which signed the event.
+func (v *Vote) Signer() (common.Address, error) {
+	if len(v.Signature) != 65 {
+		return common.Address{}, errInvalidVote
+	}
+	pub, err := crypto.SigToPub(v.ID[:], v.Signature)
+	if err != nil {
+		return common.Address{}, errInvalidVote
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Attestation is an event along with the signatures of the masternodes of its
+// epoch, complete once signed by more than two thirds of them.
+type Attestation struct {
+	Event       *Event
+	Masternodes []common.Address
+	Signatures  map[common.Address][]byte
+}
+
+// Threshold returns the number of signatures completing the attestation.
+func (a *Attestation) Threshold() int {
+	return len(a.Masternodes)*2/3 + 1
+}
+
+// Complete returns whether the attestation is signed by enough masternodes.
+func (a *Attestation) Complete() bool {
+	return len(a.Signatures) >= a.Threshold()
+}
+
+// Signers returns the masternodes which signed the event, in ascending order
+// as bridge contracts usually expect them.
+func (a *Attestation) Signers() []common.Address {
+	signers := make([]common.Address, 0, len(a.Signatures))
+	for signer := range a.Signatures {
+		signers = append(signers, signer)
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		return bytes.Compare(signers[i][:], signers[j][:]) < 0
+	})
+	return signers
+}
+
+// isMasternode returns whether an address is a masternode of the event epoch.
+func (a *Attestation) isMasternode(addr common.Address) bool {
+	for _, masternode := range a.Masternodes {
+		if masternode == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// copy returns a copy of the attestation, safe to use out of the pool lock.
+func (a *Attestation) copy() *Attestation {
+	cpy := &Attestation{
+		Event:       a.Event,
+		Masternodes: a.Masternodes,
+		Signatures:  make(map[common.Address][]byte, len(a.Signatures)),
+	}
+	for signer, sig := range a.Signatures {
+		cpy.Signatures[signer] = sig
+	}
+	return cpy
+}
+
+// orphanVotes are the votes received for an event not yet final locally.
+type orphanVotes struct {
+	votes  []*Vote
+	number uint64 // Last final block when the first vote was received
+}
+
+// pool aggregates the votes of the final events into attestations.
+type pool struct {
+	attestations map[common.Hash]*Attestation
+	numbers      map[uint64][]common.Hash // Events of each block, for lookups and pruning
+	orphans      map[common.Hash]*orphanVotes
+	orphanCount  int
+	final        uint64 // Last final block tracked
+	retention    uint64
+
+	mu sync.RWMutex
+}
+
+// newPool creates a pool keeping the attestations for a number of blocks.
+func newPool(retention uint64) *pool {
+	return &pool{
+		attestations: make(map[common.Hash]*Attestation),
+		numbers:      make(map[uint64][]common.Hash),
+		orphans:      make(map[common.Hash]*orphanVotes),
+		retention:    retention,
+	}
+}
+
+// addEvent tracks a final event signed by the masternodes of its epoch,
+// returning the votes received for it beforehand and accepted now.
+func (p *pool) addEvent(event *Event, masternodes []common.Address) []*Vote {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := event.ID()
+	if _, ok := p.attestations[id]; ok {
+		return nil
+	}
+	p.attestations[id] = &Attestation{
+		Event:       event,
+		Masternodes: masternodes,
+		Signatures:  make(map[common.Address][]byte),
+	}
+	p.numbers[event.BlockNumber] = append(p.numbers[event.BlockNumber], id)
+
+	orphans := p.orphans[id]
+	if orphans == nil {
+		return nil
+	}
+	delete(p.orphans, id)
+	p.orphanCount -= len(orphans.votes)
+
+	var accepted []*Vote
+	for _, vote := range orphans.votes {
+		if ok, err := p.addVoteLocked(vote); ok && err == nil {
+			accepted = append(accepted, vote)
+		}
+	}
+	return accepted
+}
+
+// addVote adds the vote of a masternode to the attestation of its event,
+// returning whether it is new and valid, so to be relayed. The votes of the
+// events not yet final locally are kept until they are.
+func (p *pool) addVote(vote *Vote) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.addVoteLocked(vote)
+}
+
+func (p *pool) addVoteLocked(vote *Vote) (bool, error) {
+	attestation := p.attestations[vote.ID]
+	if attestation == nil {
+		if p.orphanCount >= maxOrphans {
+			return false, nil
+		}
+		orphans := p.orphans[vote.ID]
+		if orphans == nil {
+			orphans = &orphanVotes{number: p.final}
+			p.orphans[vote.ID] = orphans
+		}
+		orphans.votes = append(orphans.votes, vote)
+		p.orphanCount++
+		return false, nil
+	}
+	signer, err := vote.Signer()
+	if err != nil {
+		return false, err
+	}
+	if !attestation.isMasternode(signer) {
+		return false, errNotMasternode
+	}
+	if _, ok := attestation.Signatures[signer]; ok {
+		return false, nil
+	}
+	attestation.Signatures[signer] = vote.Signature
+	return true, nil
+}
+
+// prune drops the attestations older than the retention and the orphan votes
+// whose events never became final.
+func (p *pool) prune(final uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.final = final
+	if final > p.retention {
+		for number, ids := range p.numbers {
+			if number+p.retention < final {
+				for _, id := range ids {
+					delete(p.attestations, id)
+				}
+				delete(p.numbers, number)
+			}
+		}
+	}
+	for id, orphans := range p.orphans {
+		if orphans.number+orphanBlocks < final {
+			delete(p.orphans, id)
+			p.orphanCount -= len(orphans.votes)
+		}
+	}
+}
+
+// attestation returns the attestation of an event, nil if unknown.
+func (p *pool) attestation(id common.Hash) *Attestation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if attestation := p.attestations[id]; attestation != nil {
+		return attestation.copy()
+	}
+	return nil
+}
+
+// block returns the attestations of the events of a block, by log index.
+func (p *pool) block(number uint64) []*Attestation {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	attestations := make([]*Attestation, 0, len(p.numbers[number]))
+	for _, id := range p.numbers[number] {
+		attestations = append(attestations, p.attestations[id].copy())
+	}
+	sort.Slice(attestations, func(i, j int) bool {
+		return attestations[i].Event.LogIndex < attestations[j].Event.LogIndex
+	})
+	return attestations
+}
+
+// votes returns all the votes of the attestations kept, to sync new peers.
+func (p *pool) votes() []*Vote {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var votes []*Vote
+	for id, attestation := range p.attestations {
+		for _, sig := range attestation.Signatures {
+			votes = append(votes, &Vote{ID: id, Signature: sig})
+		}
+	}
+	return votes
+}
@@ -0,0 +1,144 @@
+package bridge
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func testEvent(number uint64, index uint) *Event {
+	return &Event{
+		ChainID:     big.NewInt(88),
+		BlockNumber: number,
+		BlockHash:   common.Hash{byte(number)},
+		TxHash:      common.Hash{0x01},
+		LogIndex:    index,
+		Address:     common.Address{0xbb},
+		Topics:      []common.Hash{{0x02}},
+		Data:        []byte{0x03},
+	}
+}
+
+func signVote(t *testing.T, key *ecdsa.PrivateKey, id common.Hash) *Vote {
+	sig, err := crypto.Sign(id[:], key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return &Vote{ID: id, Signature: sig}
+}
+
+func TestFindEvents(t *testing.T) {
+	bridgeContract, otherContract := common.Address{0xbb}, common.Address{0xcc}
+
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil),
+		types.NewTransaction(1, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil),
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(10)}, txs, nil, nil)
+	receipts := types.Receipts{
+		{Logs: []*types.Log{{Address: otherContract}, {Address: bridgeContract, Data: []byte{0x01}}}},
+		{Logs: []*types.Log{{Address: bridgeContract, Data: []byte{0x02}}}},
+	}
+	events := findEvents(big.NewInt(88), block, receipts, []common.Address{bridgeContract})
+	if len(events) != 2 {
+		t.Fatalf("events mismatch: have %d, want 2", len(events))
+	}
+	for i, want := range []struct {
+		tx    common.Hash
+		index uint
+	}{{txs[0].Hash(), 1}, {txs[1].Hash(), 2}} {
+		if events[i].TxHash != want.tx || events[i].LogIndex != want.index || events[i].BlockHash != block.Hash() {
+			t.Errorf("event %d mismatch: have %x/%d, want %x/%d", i, events[i].TxHash, events[i].LogIndex, want.tx, want.index)
+		}
+	}
+	if events[0].ID() == events[1].ID() {
+		t.Errorf("distinct events share the id %x", events[0].ID())
+	}
+}
+
+func TestPoolAttestation(t *testing.T) {
+	var (
+		keys        = make([]*ecdsa.PrivateKey, 4)
+		masternodes = make([]common.Address, 4)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		masternodes[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	outsider, _ := crypto.GenerateKey()
+
+	pool := newPool(100)
+	event := testEvent(10, 0)
+	id := event.ID()
+
+	// Votes received before the event is final locally are kept until it is
+	if ok, err := pool.addVote(signVote(t, keys[0], id)); ok || err != nil {
+		t.Fatalf("orphan vote accepted: %v, %v", ok, err)
+	}
+	if accepted := pool.addEvent(event, masternodes); len(accepted) != 1 {
+		t.Fatalf("orphan votes mismatch: have %d, want 1", len(accepted))
+	}
+	if _, err := pool.addVote(signVote(t, outsider, id)); err != errNotMasternode {
+		t.Errorf("outsider vote error mismatch: have %v, want %v", err, errNotMasternode)
+	}
+	if ok, _ := pool.addVote(signVote(t, keys[0], id)); ok {
+		t.Errorf("duplicate vote accepted")
+	}
+	if _, err := pool.addVote(&Vote{ID: id, Signature: []byte{0x01}}); err != errInvalidVote {
+		t.Errorf("invalid vote error mismatch: have %v, want %v", err, errInvalidVote)
+	}
+	if attestation := pool.attestation(id); attestation.Threshold() != 3 || attestation.Complete() {
+		t.Fatalf("attestation completed early: threshold %d, signatures %d", attestation.Threshold(), len(attestation.Signatures))
+	}
+	for _, key := range keys[1:3] {
+		if ok, err := pool.addVote(signVote(t, key, id)); !ok || err != nil {
+			t.Fatalf("vote rejected: %v, %v", ok, err)
+		}
+	}
+	attestation := pool.attestation(id)
+	if !attestation.Complete() {
+		t.Fatalf("attestation incomplete with %d signatures", len(attestation.Signatures))
+	}
+	signers := attestation.Signers()
+	for i := 1; i < len(signers); i++ {
+		if bytes.Compare(signers[i-1][:], signers[i][:]) >= 0 {
+			t.Errorf("signers not sorted: %v", signers)
+		}
+	}
+	if votes := pool.votes(); len(votes) != 3 {
+		t.Errorf("votes mismatch: have %d, want 3", len(votes))
+	}
+	if attestations := pool.block(10); len(attestations) != 1 || attestations[0].Event.ID() != id {
+		t.Errorf("block attestations mismatch: %v", attestations)
+	}
+	// Attestations out of the retention are dropped
+	pool.prune(110)
+	if pool.attestation(id) == nil {
+		t.Fatalf("attestation pruned within the retention")
+	}
+	pool.prune(111)
+	if pool.attestation(id) != nil {
+		t.Fatalf("attestation kept out of the retention")
+	}
+}
+
+func TestPoolOrphanLimit(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pool := newPool(100)
+
+	for i := 0; i < maxOrphans+10; i++ {
+		pool.addVote(signVote(t, key, testEvent(uint64(i), 0).ID()))
+	}
+	if pool.orphanCount != maxOrphans {
+		t.Fatalf("orphan count mismatch: have %d, want %d", pool.orphanCount, maxOrphans)
+	}
+	pool.prune(orphanBlocks + 1)
+	if pool.orphanCount != 0 || len(pool.orphans) != 0 {
+		t.Fatalf("orphans not pruned: %d left", pool.orphanCount)
+	}
+}
@@ -0,0 +1,265 @@
+// Package bridge implements the attestation of the events of bridge contracts
+// by the masternodes, the building block of the bridges between TomoChain and
+// the other chains.
+//
+// Once final, each event of the configured contracts is signed by the
+// masternodes of its epoch with their etherbase keys. The votes are gossiped
+// over the bridge protocol and aggregated into attestations, complete when
+// signed by more than two thirds of the masternodes, which the relayers pick up
+// over RPC to submit to the other chain.
+package bridge
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBacklog is the maximum number of blocks scanned at once, when the head
+// jumps ahead.
+const maxBacklog = 1024
+
+// Bridge attests the events of the bridge contracts with the other masternodes.
+type Bridge struct {
+	config  Config
+	eth     *eth.Ethereum
+	posv    *posv.Posv
+	chainID *big.Int
+	pool    *pool
+
+	peers   map[*peer]struct{}
+	peersMu sync.RWMutex
+
+	last uint64 // Last final block scanned, only used by the loop
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates the bridge attestation service of a full node.
+func New(config *Config, ethereum *eth.Ethereum) (*Bridge, error) {
+	if ethereum == nil {
+		return nil, errors.New("bridge attestations require a full node")
+	}
+	engine, ok := ethereum.Engine().(*posv.Posv)
+	if !ok {
+		return nil, errors.New("bridge attestations require the PoSV consensus engine")
+	}
+	cfg := *config
+	if cfg.Confirmations == 0 {
+		cfg.Confirmations = DefaultConfig.Confirmations
+	}
+	if cfg.Retention == 0 {
+		cfg.Retention = DefaultConfig.Retention
+	}
+	return &Bridge{
+		config:  cfg,
+		eth:     ethereum,
+		posv:    engine,
+		chainID: ethereum.BlockChain().Config().ChainId,
+		pool:    newPool(cfg.Retention),
+		peers:   make(map[*peer]struct{}),
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// Protocols implements node.Service, returning the bridge protocol.
+func (b *Bridge) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    ProtocolName,
+		Version: ProtocolVersion,
+		Length:  protocolLength,
+		Run:     b.handle,
+	}}
+}
+
+// APIs implements node.Service, returning the bridge RPC API.
+func (b *Bridge) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "bridge",
+		Version:   "1.0",
+		Service:   NewPublicBridgeAPI(b),
+		Public:    true,
+	}}
+}
+
+// Start implements node.Service, attesting the events final from now on.
+func (b *Bridge) Start(server *p2p.Server) error {
+	if number := b.eth.BlockChain().CurrentBlock().NumberU64(); number >= b.config.Confirmations {
+		b.last = number - b.config.Confirmations
+	}
+	b.wg.Add(1)
+	go b.loop()
+
+	log.Info("Started bridge attestations", "contracts", len(b.config.Contracts), "confirmations", b.config.Confirmations)
+	return nil
+}
+
+// Stop implements node.Service, ending the attestations.
+func (b *Bridge) Stop() error {
+	close(b.quit)
+	b.wg.Wait()
+	return nil
+}
+
+// loop scans the blocks made final by each chain head.
+func (b *Bridge) loop() {
+	defer b.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := b.eth.BlockChain().SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			b.update(ev.Block.NumberU64())
+		case <-sub.Err():
+			return
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// update attests the events of the blocks buried deep enough under the head to
+// be final, and prunes the attestations out of the retention.
+func (b *Bridge) update(number uint64) {
+	if number < b.config.Confirmations {
+		return
+	}
+	final := number - b.config.Confirmations
+	if final > b.last+maxBacklog {
+		b.last = final - maxBacklog
+	}
+	for b.last < final {
+		block := b.eth.BlockChain().GetBlockByNumber(b.last + 1)
+		if block == nil {
+			log.Debug("Final block not found", "number", b.last+1)
+			break
+		}
+		b.attest(block)
+		b.last++
+	}
+	b.pool.prune(b.last)
+}
+
+// attest tracks the events of a final block, signs them if the etherbase is a
+// masternode of its epoch and relays the votes.
+func (b *Bridge) attest(block *types.Block) {
+	receipts := core.GetBlockReceipts(b.eth.ChainDb(), block.Hash(), block.NumberU64())
+	events := findEvents(b.chainID, block, receipts, b.config.Contracts)
+	if len(events) == 0 {
+		return
+	}
+	masternodes := b.posv.GetMasternodes(b.eth.BlockChain(), block.Header())
+	signFn := b.signer(masternodes)
+
+	var votes []*Vote
+	for _, event := range events {
+		votes = append(votes, b.pool.addEvent(event, masternodes)...)
+		if signFn == nil {
+			continue
+		}
+		id := event.ID()
+		sig, err := signFn(id)
+		if err != nil {
+			log.Warn("Failed to sign bridge event", "number", event.BlockNumber, "index", event.LogIndex, "err", err)
+			continue
+		}
+		vote := &Vote{ID: id, Signature: sig}
+		if ok, err := b.pool.addVote(vote); err != nil {
+			log.Warn("Failed to add bridge vote", "number", event.BlockNumber, "index", event.LogIndex, "err", err)
+		} else if ok {
+			votes = append(votes, vote)
+		}
+	}
+	b.relay(votes)
+}
+
+// signer returns the function signing the events with the etherbase key, nil if
+// the etherbase is not one of the masternodes.
+func (b *Bridge) signer(masternodes []common.Address) func(common.Hash) ([]byte, error) {
+	etherbase, err := b.eth.Etherbase()
+	if err != nil {
+		return nil
+	}
+	for _, masternode := range masternodes {
+		if masternode != etherbase {
+			continue
+		}
+		account := accounts.Account{Address: etherbase}
+		wallet, err := b.eth.AccountManager().Find(account)
+		if err != nil {
+			log.Warn("Masternode account unavailable to sign bridge events", "etherbase", etherbase, "err", err)
+			return nil
+		}
+		return func(id common.Hash) ([]byte, error) {
+			return wallet.SignHash(account, id[:])
+		}
+	}
+	return nil
+}
+
+// handle runs the bridge protocol with a peer, relaying the votes it doesn't
+// know of and aggregating the ones it sends.
+func (b *Bridge) handle(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	peer := newPeer(p, rw)
+
+	b.peersMu.Lock()
+	b.peers[peer] = struct{}{}
+	b.peersMu.Unlock()
+
+	closed := make(chan struct{})
+	defer func() {
+		b.peersMu.Lock()
+		delete(b.peers, peer)
+		b.peersMu.Unlock()
+		close(closed)
+	}()
+	go peer.broadcast(closed)
+
+	peer.relay(b.pool.votes())
+	for {
+		votes, err := peer.readVotes()
+		if err != nil {
+			return err
+		}
+		var accepted []*Vote
+		for _, vote := range votes {
+			peer.markVote(vote)
+			ok, err := b.pool.addVote(vote)
+			if err != nil {
+				log.Debug("Dropped invalid bridge vote", "peer", p.ID(), "id", vote.ID, "err", err)
+				continue
+			}
+			if ok {
+				accepted = append(accepted, vote)
+			}
+		}
+		b.relay(accepted)
+	}
+}
+
+// relay sends the votes to the peers which don't know of them.
+func (b *Bridge) relay(votes []*Vote) {
+	if len(votes) == 0 {
+		return
+	}
+	b.peersMu.RLock()
+	defer b.peersMu.RUnlock()
+
+	for peer := range b.peers {
+		peer.relay(votes)
+	}
+}
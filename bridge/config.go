@@ -0,0 +1,16 @@
+package bridge
+
+import "github.com/ethereum/go-ethereum/common"
+
+// Config are the configuration parameters of the bridge attestations.
+type Config struct {
+	Contracts     []common.Address `toml:",omitempty"` // Bridge contracts whose events are attested, disabled if empty
+	Confirmations uint64           // Number of blocks an event is buried under before being attested as final
+	Retention     uint64           // Number of blocks the attestations are kept in memory for
+}
+
+// DefaultConfig contains the default settings of the bridge attestations.
+var DefaultConfig = Config{
+	Confirmations: 50,
+	Retention:     86400,
+}
@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"gopkg.in/fatih/set.v0"
+)
+
+const (
+	ProtocolName    = "bridge" // Short name of the protocol used during capability negotiation
+	ProtocolVersion = 1        // Version of the protocol
+	protocolLength  = 1        // Number of messages of the protocol
+
+	VotesMsg = 0x00 // Votes of masternodes attesting events
+
+	maxMsgSize     = 1024 * 1024 // Maximum cap on the size of a protocol message
+	maxVotesPerMsg = 256         // Maximum number of votes sent in a message
+	maxKnownVotes  = 32768       // Maximum vote hashes to keep in the known list (prevent DOS)
+	peerQueueSize  = 128         // Number of vote batches queued to a peer before dropping them
+)
+
+// peer is a connection to a node running the bridge protocol.
+type peer struct {
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	known *set.Set     // Set of vote hashes known to be known by the peer
+	queue chan []*Vote // Votes to relay to the peer
+}
+
+func newPeer(p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
+	return &peer{
+		Peer:  p,
+		rw:    rw,
+		known: set.New(),
+		queue: make(chan []*Vote, peerQueueSize),
+	}
+}
+
+// markVote marks a vote as known by the peer, so it is never relayed back.
+func (p *peer) markVote(vote *Vote) {
+	for p.known.Size() >= maxKnownVotes {
+		p.known.Pop()
+	}
+	p.known.Add(vote.Hash())
+}
+
+// relay queues the votes not known by the peer, dropping them if the peer
+// falls behind.
+func (p *peer) relay(votes []*Vote) {
+	var unknown []*Vote
+	for _, vote := range votes {
+		if !p.known.Has(vote.Hash()) {
+			p.markVote(vote)
+			unknown = append(unknown, vote)
+		}
+	}
+	for len(unknown) > 0 {
+		n := len(unknown)
+		if n > maxVotesPerMsg {
+			n = maxVotesPerMsg
+		}
+		select {
+		case p.queue <- unknown[:n]:
+		default:
+			log.Debug("Bridge peer queue full, dropping votes", "peer", p.ID(), "votes", len(unknown))
+			return
+		}
+		unknown = unknown[n:]
+	}
+}
+
+// broadcast sends the queued votes to the peer until the connection ends.
+func (p *peer) broadcast(closed chan struct{}) error {
+	for {
+		select {
+		case votes := <-p.queue:
+			if err := p2p.Send(p.rw, VotesMsg, votes); err != nil {
+				return err
+			}
+		case <-closed:
+			return nil
+		}
+	}
+}
+
+// readVotes reads a message of votes from the peer.
+func (p *peer) readVotes() ([]*Vote, error) {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	defer msg.Discard()
+
+	if msg.Size > maxMsgSize {
+		return nil, fmt.Errorf("message too large: %v > %v", msg.Size, maxMsgSize)
+	}
+	if msg.Code != VotesMsg {
+		return nil, fmt.Errorf("invalid message code %d", msg.Code)
+	}
+	var votes []*Vote
+	if err := msg.Decode(&votes); err != nil {
+		return nil, fmt.Errorf("invalid votes: %v", err)
+	}
+	if len(votes) > maxVotesPerMsg {
+		return nil, fmt.Errorf("too many votes: %v > %v", len(votes), maxVotesPerMsg)
+	}
+	return votes, nil
+}
@@ -170,6 +170,10 @@ func (w *wizard) makeGenesis() {
 		fmt.Println("How many blocks before checkpoint need to prepare new set of masternodes? (default = 450)")
 		genesis.Config.Posv.Gap = uint64(w.readDefaultInt(450))
 
+		fmt.Println()
+		fmt.Printf("How many masternodes can seal blocks in an epoch? (default = %d)\n", common.MaxMasternodes)
+		genesis.Config.Posv.MaxMasternodes = w.readDefaultInt(common.MaxMasternodes)
+
 		fmt.Println()
 		fmt.Println("What is foundation wallet address? (default = 0x0000000000000000000000000000000000000068)")
 		genesis.Config.Posv.FoudationWalletAddr = w.readDefaultAddress(common.HexToAddress(common.FoudationAddr))
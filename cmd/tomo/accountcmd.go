@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
@@ -54,6 +55,11 @@ passwordfile as argument containing the wallet password in plaintext.`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
 				},
 				Description: `
 	tomo wallet [options] /path/to/my/presale.wallet
@@ -110,6 +116,11 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
 				},
 				Description: `
     tomo account new
@@ -135,6 +146,11 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
 				},
 				Description: `
     tomo account update <address>
@@ -153,6 +169,37 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:      "upgrade",
+				Usage:     "Upgrade the key files of existing accounts in place",
+				Action:    utils.MigrateFlags(accountUpgrade),
+				ArgsUsage: "[<address> ...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
+				},
+				Description: `
+    tomo account upgrade [options] [<address> ...]
+
+Upgrade the key files of the given accounts, or of all the accounts of the
+keystore, keeping their passwords.
+
+The key files are re-encrypted in place in the configured format: version 4
+files derived with argon2id with --keystore.argon2, as advised for the
+masternode keys, version 3 files derived with scrypt otherwise. Key files
+already in that format with the same KDF parameters are left untouched.
+
+For non-interactive use the passwords can be specified with the --password flag,
+one per line in the order of the accounts.
 `,
 			},
 			{
@@ -164,6 +211,11 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
@@ -307,7 +359,12 @@ func accountCreate(ctx *cli.Context) error {
 
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
+	var address common.Address
+	if params := cfg.Node.Argon2Config(); params != nil {
+		address, err = keystore.StoreKeyArgon2(keydir, password, *params)
+	} else {
+		address, err = keystore.StoreKey(keydir, password, scryptN, scryptP)
+	}
 
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
@@ -335,6 +392,37 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountUpgrade re-encrypts the key files of accounts in place in the format
+// configured, keeping their passwords.
+func accountUpgrade(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	addrs := []string(ctx.Args())
+	if len(addrs) == 0 {
+		for _, account := range ks.Accounts() {
+			addrs = append(addrs, account.Address.Hex())
+		}
+	}
+	if len(addrs) == 0 {
+		utils.Fatalf("No accounts to upgrade")
+	}
+	passwords := utils.MakePasswordList(ctx)
+	for i, addr := range addrs {
+		account, password := unlockAccount(ctx, ks, addr, i, passwords)
+		upgraded, err := ks.Upgrade(account, password)
+		if err != nil {
+			utils.Fatalf("Could not upgrade the account %s: %v", addr, err)
+		}
+		if upgraded {
+			fmt.Printf("Upgraded {%x}\n", account.Address)
+		} else {
+			fmt.Printf("Up to date {%x}\n", account.Address)
+		}
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
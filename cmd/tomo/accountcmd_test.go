@@ -106,6 +106,28 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountUpgrade(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	tomo := runTomo(t, "account", "upgrade",
+		"--datadir", datadir, "--lightkdf", "--keystore.argon2",
+		"f466859ead1932d743d622cb74fc058882e8648a")
+	tomo.Expect(`
+Unlocking account f466859ead1932d743d622cb74fc058882e8648a | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+Upgraded {f466859ead1932d743d622cb74fc058882e8648a}
+`)
+	tomo.ExpectExit()
+
+	keyjson, err := ioutil.ReadFile(filepath.Join(datadir, "keystore", "aaa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(keyjson), `"kdf":"argon2id"`) || !strings.Contains(string(keyjson), `"version":4`) {
+		t.Errorf("key file not upgraded: %s", keyjson)
+	}
+}
+
 func TestWalletImport(t *testing.T) {
 	tomo := runTomo(t, "wallet", "import", "--lightkdf", "testdata/guswallet.json")
 	defer tomo.ExpectExit()
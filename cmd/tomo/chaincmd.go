@@ -17,6 +17,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -27,7 +29,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/consensus/posv/reward"
 	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/contracts"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -35,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
@@ -49,6 +55,8 @@ var (
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
 			utils.LightModeFlag,
+			utils.DeveloperFlag,
+			utils.DeveloperPeriodFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -56,7 +64,8 @@ The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
 participating.
 
-It expects the genesis file as argument.`,
+It expects the genesis file as argument. With --dev, no file is needed and a
+persistent single node POSV developer chain is initialised in --datadir.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -70,11 +79,15 @@ It expects the genesis file as argument.`,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.TomoXDataDirFlag,
+			utils.TomoXCacheFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
-with several RLP-encoded blocks, or several files can be used.
+with several RLP-encoded blocks, or several files can be used. The TomoX snapshots
+of an export made with --tomox.snapshots are checked against their checkpoint block
+and written to the TomoX database.
 
 If only one file is used, import error will result in failure. If several files are used,
 processing will proceed even if an individual RLP-file import failure occurs.`,
@@ -88,13 +101,19 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.TomoXDataDirFlag,
+			utils.TomoXSnapshotsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing.
+
+With --tomox.snapshots, each epoch checkpoint block is followed
+by a snapshot of the TomoX and lending states after it, which
+the import command verifies and writes to the TomoX database.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -169,11 +188,63 @@ Remove blockchain and state databases`,
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.`,
 	}
+	verifyEpochCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifyEpoch),
+		Name:      "verify-epoch",
+		Usage:     "Verify the masternode shuffle of an epoch checkpoint",
+		ArgsUsage: "<epoch>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The verify-epoch command recomputes the validators of the masternodes of an epoch
+from the secrets and openings stored in the randomize contract before its
+checkpoint block, and compares them with the ones recorded in the checkpoint
+header. It fails if any of them differs.`,
+	}
+	dryRunRewardsCommand = cli.Command{
+		Action:    utils.MigrateFlags(dryRunRewards),
+		Name:      "dry-run-rewards",
+		Usage:     "Compute the rewards of a reward checkpoint without applying them",
+		ArgsUsage: "<number>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The dry-run-rewards command computes the rewards of a reward checkpoint block
+from the local chain, with the same engine as the consensus, and prints the
+signs of the masternodes and the transfers to their holders as JSON. Nothing is
+written to the database.`,
+	}
+	verifySnapshotCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifySnapshot),
+		Name:      "verify-snapshot",
+		Usage:     "Verify the flat state snapshot against the state trie",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The verify-snapshot command walks the state trie of the root the flat snapshot
+maintained with --snapshot was built for, and checks that the snapshot holds the
+very same accounts and storage slots. It fails at the first difference.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
 // the zero'd block (i.e. genesis) or will fail hard if it can't succeed.
 func initGenesis(ctx *cli.Context) error {
+	if ctx.GlobalBool(utils.DeveloperFlag.Name) {
+		return initDeveloperGenesis(ctx)
+	}
 	// Make sure we have a valid genesis JSON
 	genesisPath := ctx.Args().First()
 	if len(genesisPath) == 0 {
@@ -191,6 +262,26 @@ func initGenesis(ctx *cli.Context) error {
 	}
 	// Open an initialise both full and light databases
 	stack, _ := makeFullNode(ctx)
+	writeGenesis(stack, genesis)
+	return nil
+}
+
+// initDeveloperGenesis writes the developer chain genesis into the data directory
+// so 'tomo --dev --datadir' can be restarted without losing its state.
+func initDeveloperGenesis(ctx *cli.Context) error {
+	if !ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+		utils.Fatalf("Persistent developer chains require --%s", utils.DataDirFlag.Name)
+	}
+	stack, cfg := makeFullNode(ctx)
+	if cfg.Eth.Genesis == nil {
+		utils.Fatalf("Developer chain already initialised in %s", stack.DataDir())
+	}
+	writeGenesis(stack, cfg.Eth.Genesis)
+	return nil
+}
+
+// writeGenesis commits the genesis block into both the full and light databases.
+func writeGenesis(stack *node.Node, genesis *core.Genesis) {
 	for _, name := range []string{"chaindata", "lightchaindata"} {
 		chaindb, err := stack.OpenDatabase(name, 0, 0)
 		if err != nil {
@@ -202,7 +293,6 @@ func initGenesis(ctx *cli.Context) error {
 		}
 		log.Info("Successfully wrote genesis state", "database", name, "hash", hash)
 	}
-	return nil
 }
 
 func importChain(ctx *cli.Context) error {
@@ -310,7 +400,7 @@ func exportChain(ctx *cli.Context) error {
 	var err error
 	fp := ctx.Args().First()
 	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
+		err = utils.ExportChain(chain, fp, ctx.Bool(utils.TomoXSnapshotsFlag.Name))
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
@@ -321,7 +411,7 @@ func exportChain(ctx *cli.Context) error {
 		if first < 0 || last < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last), ctx.Bool(utils.TomoXSnapshotsFlag.Name))
 	}
 
 	if err != nil {
@@ -466,6 +556,153 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+// verifySnapshot checks the flat state snapshot against the state trie of its root.
+func verifySnapshot(ctx *cli.Context) error {
+	stack, _ := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	snap := state.NewSnapshot(chainDb)
+	root, ready := snap.Root()
+	if !ready {
+		utils.Fatalf("No complete state snapshot found")
+	}
+	start := time.Now()
+	accounts, slots, err := snap.Verify(state.NewDatabase(chainDb))
+	if err != nil {
+		utils.Fatalf("State snapshot of root %s is invalid after %d accounts and %d slots: %v", root.Hex(), accounts, slots, err)
+	}
+	fmt.Printf("State snapshot of root %s is valid: %d accounts, %d slots, %v\n", root.Hex(), accounts, slots, common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// verifyEpoch recomputes the validators of the masternodes recorded in the
+// checkpoint header of an epoch and reports the ones differing from the header.
+func verifyEpoch(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	epoch, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil || epoch == 0 {
+		utils.Fatalf("Invalid epoch number: %s", ctx.Args().First())
+	}
+	stack, _ := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	config := chain.Config().Posv
+	if config == nil {
+		utils.Fatalf("Chain is not running the PoSV consensus")
+	}
+	number := epoch * config.Epoch
+	header := chain.GetHeaderByNumber(number)
+	if header == nil {
+		utils.Fatalf("Checkpoint block %d not found", number)
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		utils.Fatalf("Parent of checkpoint block %d not found", number)
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		utils.Fatalf("Could not open state of block %d: %v", number-1, err)
+	}
+	masternodes := posv.GetMasternodesFromCheckpointHeader(header)
+	if len(masternodes) == 0 {
+		utils.Fatalf("No masternodes in checkpoint block %d", number)
+	}
+	randoms := make([]int64, len(masternodes))
+	for i, masternode := range masternodes {
+		if randoms[i], err = contracts.GetRandomizeFromState(statedb, masternode); err != nil {
+			utils.Fatalf("Could not decrypt randomize of %s: %v", masternode.Hex(), err)
+		}
+	}
+	m2, err := contracts.GenM2FromRandomize(randoms, int64(len(masternodes)))
+	if err != nil {
+		utils.Fatalf("Could not shuffle masternodes: %v", err)
+	}
+	expected := contracts.BuildValidatorFromM2(m2)
+	recorded := posv.ExtractValidatorsFromBytes(header.Validators)
+
+	fmt.Printf("Epoch %d, checkpoint block %d (%s), %d masternodes\n", epoch, number, header.Hash().Hex(), len(masternodes))
+	mismatches := 0
+	for i, masternode := range masternodes {
+		have := "missing"
+		if i < len(recorded) {
+			have = strconv.FormatInt(recorded[i], 10)
+		}
+		status := "ok"
+		if have != strconv.FormatInt(m2[i], 10) {
+			status = "MISMATCH"
+			mismatches++
+		}
+		fmt.Printf("%4d %s random=%d expected=%d recorded=%s %s\n", i, masternode.Hex(), randoms[i], m2[i], have, status)
+	}
+	if len(recorded) > len(masternodes) {
+		fmt.Printf("%d extra validators recorded in the checkpoint header\n", len(recorded)-len(masternodes))
+		mismatches++
+	}
+	if mismatches > 0 || !bytes.Equal(expected, header.Validators) {
+		utils.Fatalf("Validators of epoch %d do not match the randomize contract: %d mismatches", epoch, mismatches)
+	}
+	fmt.Printf("Validators of epoch %d match the randomize contract\n", epoch)
+	return nil
+}
+
+func dryRunRewards(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number: %s", ctx.Args().First())
+	}
+	stack, _ := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	config := chain.Config().Posv
+	if config == nil {
+		utils.Fatalf("Chain is not running the PoSV consensus")
+	}
+	if number%config.RewardCheckpoint != 0 {
+		utils.Fatalf("Block %d is not a reward checkpoint, rewards are credited every %d blocks", number, config.RewardCheckpoint)
+	}
+	header := chain.GetHeaderByNumber(number)
+	if header == nil {
+		utils.Fatalf("Checkpoint block %d not found", number)
+	}
+	if !reward.IsCheckpoint(chain, header) {
+		utils.Fatalf("No rewards are credited at block %d", number)
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		utils.Fatalf("Parent of checkpoint block %d not found", number)
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		utils.Fatalf("Could not open state of block %d: %v", number-1, err)
+	}
+	engine, ok := chain.Engine().(*posv.Posv)
+	if !ok {
+		utils.Fatalf("Chain is not running the PoSV consensus")
+	}
+	in, err := reward.NewInput(context.Background(), engine, chain, header, contracts.NewStorageValidatorCaller(statedb))
+	if err != nil {
+		utils.Fatalf("Could not collect the signers: %v", err)
+	}
+	result, err := reward.Compute(context.Background(), config, in)
+	if err != nil {
+		utils.Fatalf("Could not compute the rewards: %v", err)
+	}
+	out, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		utils.Fatalf("Could not encode the rewards: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
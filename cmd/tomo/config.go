@@ -28,6 +28,7 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/ethereum/go-ethereum/bridge"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/dashboard"
@@ -96,6 +97,7 @@ type tomoConfig struct {
 	Ethstats    ethstatsConfig
 	Dashboard   dashboard.Config
 	TomoX       tomox.Config
+	Bridge      bridge.Config
 	Account     account
 	StakeEnable bool
 	Bootnodes   Bootnodes
@@ -133,6 +135,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, tomoConfig) {
 		Eth:         eth.DefaultConfig,
 		Shh:         whisper.DefaultConfig,
 		TomoX:       tomox.DefaultConfig,
+		Bridge:      bridge.DefaultConfig,
 		Node:        defaultNodeConfig(),
 		Dashboard:   dashboard.DefaultConfig,
 		StakeEnable: true,
@@ -205,6 +208,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, tomoConfig) {
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 	utils.SetTomoXConfig(ctx, &cfg.TomoX)
+	utils.SetBridgeConfig(ctx, &cfg.Bridge)
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
 
 	return stack, cfg
@@ -258,6 +262,11 @@ func makeFullNode(ctx *cli.Context) (*node.Node, tomoConfig) {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+	// Masternodes attest the events of the bridge contracts if any
+	if len(cfg.Bridge.Contracts) > 0 {
+		utils.RegisterBridgeService(stack, &cfg.Bridge)
+	}
+
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
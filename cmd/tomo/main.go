@@ -54,11 +54,18 @@ var (
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
 		utils.PasswordFileFlag,
+		utils.OrderSignersFlag,
+		utils.TxSignersFlag,
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
+		utils.KeyStoreArgon2Flag,
+		utils.KeyStoreArgon2TimeFlag,
+		utils.KeyStoreArgon2MemoryFlag,
 		//utils.NoUSBFlag,
 		//utils.DashboardEnabledFlag,
 		//utils.DashboardAddrFlag,
@@ -76,6 +83,13 @@ var (
 		utils.TomoXDBConnectionUrlFlag,
 		utils.TomoXDBReplicaSetNameFlag,
 		utils.TomoXDBNameFlag,
+		utils.TomoXCacheFlag,
+		utils.TomoXDiscoveryFlag,
+		utils.TomoXKafkaBrokersFlag,
+		utils.TomoXKafkaTopicFlag,
+		utils.TomoXHistoryServeFlag,
+		utils.TomoXHistoryPeersFlag,
+		utils.TomoXHistoryBlocksFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
@@ -85,13 +99,23 @@ var (
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolPrioritySlotsFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolRejectUnprotectedFlag,
+		utils.OrderPoolRelayersFlag,
+		utils.OrderPoolRelayerSlotsFlag,
+		utils.OrderPoolRelayerRateFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.DownloaderRequestTTLFlag,
+		utils.DownloaderBlacklistFlag,
+		utils.DownloaderMinThroughputFlag,
 		utils.GCModeFlag,
-		//utils.LightServFlag,
-		//utils.LightPeersFlag,
+		utils.SnapshotFlag,
+		utils.LogIndexFlag,
+		utils.LightServFlag,
+		utils.LightPeersFlag,
 		//utils.LightKDFFlag,
 		//utils.CacheFlag,
 		//utils.CacheDatabaseFlag,
@@ -104,6 +128,20 @@ var (
 		utils.GasPriceFlag,
 		utils.StakerThreadsFlag,
 		utils.StakingEnabledFlag,
+		utils.MinorityForkGuardFlag,
+		utils.MinorityForkGuardTimeoutFlag,
+		utils.MaxOrdersFlag,
+		utils.OrderTimeShareFlag,
+		utils.SignerWalletsFlag,
+		utils.RemoteSignerFlag,
+		utils.RemoteSignerAuditFlag,
+		utils.PosvVerifyRewardsFlag,
+		utils.WatchdogTimeoutFlag,
+		utils.WatchdogRotatePeersFlag,
+		utils.PosvCandidateWebhookFlag,
+		utils.EventSinksFlag,
+		utils.BridgeContractsFlag,
+		utils.BridgeConfirmationsFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -111,8 +149,8 @@ var (
 		//utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
-		//utils.DeveloperFlag,
-		//utils.DeveloperPeriodFlag,
+		utils.DeveloperFlag,
+		utils.DeveloperPeriodFlag,
 		//utils.TestnetFlag,
 		//utils.RinkebyFlag,
 		//utils.VMEnableDebugFlag,
@@ -138,13 +176,26 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCMethodsFlag,
+		utils.WSMethodsFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCMethodRateLimitsFlag,
+		utils.RPCTimeoutFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCBatchResponseSizeFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCAuthApiFlag,
+		utils.RPCLogsRangeFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.IPCReadOnlyPathFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -166,6 +217,11 @@ func init() {
 		exportCommand,
 		removedbCommand,
 		dumpCommand,
+		verifyEpochCommand,
+		dryRunRewardsCommand,
+		verifySnapshotCommand,
+		// See tomoxcmd.go:
+		tomoxCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -0,0 +1,258 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	tomoxCommand = cli.Command{
+		Name:     "tomox",
+		Usage:    "Inspect the TomoX order books",
+		Category: "TOMOX COMMANDS",
+		Description: `
+Offline tools working on the TomoX states of the local chain.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "verify",
+				Usage:     "Verify the integrity of the order books",
+				ArgsUsage: "[<blockNum>|<blockHash>]",
+				Action:    utils.MigrateFlags(verifyTomoX),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TomoXDataDirFlag,
+					utils.TomoXCacheFlag,
+				},
+				Description: `
+The verify command walks the order books of the TomoX state at the head block, or
+at the given block, and checks that:
+
+  - the volume of each price level equals the sum of its resting orders,
+  - the resting orders match the order index in side, price and quantity, and
+    every order of the index rests at a price level,
+  - the order ids are below the nonce of their order book,
+  - the best bid is lower than the best ask.
+
+It prints a JSON report of every order book and fails if any check failed.`,
+			},
+			{
+				Name:      "replay",
+				Usage:     "Replay the matching of a block range",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(replayTomoX),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TomoXDataDirFlag,
+					utils.TomoXCacheFlag,
+					replayFromFlag,
+					replayToFlag,
+				},
+				Description: `
+The replay command applies again the matching transactions of the blocks from
+--from to --to, by default the head block, on top of the states of their parent
+blocks, and compares the computed TomoX roots with the ones the blocks carry.
+Nothing is written to the database, but the states of the parent blocks must be
+available, as on an archive node. Run it against a copy of the database of a
+stopped node, to check a change of the matching engine before deploying it.
+
+It prints a line for every block carrying a TomoX root and fails if any of them
+diverges.`,
+			},
+		},
+	}
+
+	replayFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to replay",
+	}
+	replayToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to replay (default = head block)",
+	}
+)
+
+// tomoxReport is the output of the verify command.
+type tomoxReport struct {
+	Number     uint64                  `json:"number"`
+	Hash       common.Hash             `json:"hash"`
+	Root       common.Hash             `json:"tomoxRoot"`
+	OrderBooks []*tomoxOrderBookReport `json:"orderBooks"`
+	Invalid    int                     `json:"invalid"`
+}
+
+// tomoxOrderBookReport names the pair of an order book report, when it is
+// listed by a relayer.
+type tomoxOrderBookReport struct {
+	BaseToken  *common.Address `json:"baseToken,omitempty"`
+	QuoteToken *common.Address `json:"quoteToken,omitempty"`
+	*tomox_state.OrderBookReport
+}
+
+// verifyTomoX checks the order books of the TomoX state of a block.
+func verifyTomoX(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command accepts at most one argument.")
+	}
+	stack, _ := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	engine, ok := chain.Engine().(*posv.Posv)
+	if !ok || engine.GetTomoXService == nil || engine.GetTomoXService() == nil {
+		utils.Fatalf("Chain is not running the PoSV consensus with TomoX")
+	}
+	tomoX := engine.GetTomoXService()
+
+	block := chain.CurrentBlock()
+	if arg := ctx.Args().First(); arg != "" {
+		if hashish(arg) {
+			block = chain.GetBlockByHash(common.HexToHash(arg))
+		} else if num, err := strconv.ParseUint(arg, 10, 64); err == nil {
+			block = chain.GetBlockByNumber(num)
+		} else {
+			utils.Fatalf("Invalid block number or hash: %s", arg)
+		}
+		if block == nil {
+			utils.Fatalf("Block %s not found", arg)
+		}
+	}
+	root, err := tomoX.GetTomoxStateRoot(block)
+	if err != nil {
+		utils.Fatalf("Could not read the TomoX root of block %d: %v", block.NumberU64(), err)
+	}
+	tomoxState, err := tomoX.GetTomoxState(block)
+	if err != nil {
+		utils.Fatalf("Could not open the TomoX state of block %d: %v", block.NumberU64(), err)
+	}
+	reports, err := tomoxState.VerifyOrderBooks()
+	if err != nil {
+		utils.Fatalf("Could not read the TomoX state of block %d: %v", block.NumberU64(), err)
+	}
+	// Name the order books of the pairs listed at the block
+	var pairs map[common.Hash][2]common.Address
+	if statedb, err := state.New(block.Root(), state.NewDatabase(chainDb)); err == nil {
+		pairs = listedPairs(statedb)
+	}
+	report := &tomoxReport{Number: block.NumberU64(), Hash: block.Hash(), Root: root}
+	for _, r := range reports {
+		item := &tomoxOrderBookReport{OrderBookReport: r}
+		if pair, ok := pairs[r.OrderBook]; ok {
+			item.BaseToken, item.QuoteToken = &pair[0], &pair[1]
+		}
+		if !r.Valid() {
+			report.Invalid++
+		}
+		report.OrderBooks = append(report.OrderBooks, item)
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	if report.Invalid > 0 {
+		return fmt.Errorf("%d of %d order books of block %d are inconsistent", report.Invalid, len(reports), block.NumberU64())
+	}
+	return nil
+}
+
+// listedPairs maps the order book hashes of the pairs listed by the relayers
+// to their base and quote tokens.
+func listedPairs(statedb *state.StateDB) map[common.Hash][2]common.Address {
+	pairs := make(map[common.Hash][2]common.Address)
+	for _, relayer := range tomox_state.GetCoinbaseList(statedb) {
+		bases, quotes := tomox_state.GetBaseTokenLength(relayer, statedb), tomox_state.GetQuoteTokenLength(relayer, statedb)
+		for i := uint64(0); i < bases && i < quotes; i++ {
+			base := tomox_state.GetBaseTokenAtIndex(relayer, statedb, i)
+			quote := tomox_state.GetQuoteTokenAtIndex(relayer, statedb, i)
+			pairs[tomox.GetOrderBookHash(base, quote)] = [2]common.Address{base, quote}
+		}
+	}
+	return pairs
+}
+
+// replayTomoX replays the matching of a range of blocks and compares the
+// computed TomoX roots with the recorded ones.
+func replayTomoX(ctx *cli.Context) error {
+	stack, _ := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	engine, ok := chain.Engine().(*posv.Posv)
+	if !ok || engine.GetTomoXService == nil || engine.GetTomoXService() == nil {
+		utils.Fatalf("Chain is not running the PoSV consensus with TomoX")
+	}
+	tomoX := engine.GetTomoXService()
+
+	from, to := ctx.Uint64(replayFromFlag.Name), chain.CurrentBlock().NumberU64()
+	if ctx.IsSet(replayToFlag.Name) {
+		to = ctx.Uint64(replayToFlag.Name)
+	}
+	if from == 0 || from > to {
+		utils.Fatalf("Invalid block range %d-%d", from, to)
+	}
+	var replayed, diverged int
+	for number := from; number <= to; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			utils.Fatalf("Block %d not found", number)
+		}
+		if !chain.Config().IsTIPTomoX(block.Number()) || !hasTomoXRoot(block) {
+			continue
+		}
+		want, _ := tomoX.GetTomoxStateRoot(block)
+		have, err := chain.ReplayTomoX(block)
+		replayed++
+		switch {
+		case err != nil:
+			diverged++
+			fmt.Printf("%d %s FAILED %v\n", number, block.Hash().Hex(), err)
+		case have != want:
+			diverged++
+			fmt.Printf("%d %s MISMATCH have=%s want=%s\n", number, block.Hash().Hex(), have.Hex(), want.Hex())
+		default:
+			fmt.Printf("%d %s ok %s\n", number, block.Hash().Hex(), have.Hex())
+		}
+	}
+	fmt.Printf("Replayed %d blocks of %d-%d, %d diverged\n", replayed, from, to, diverged)
+	if diverged > 0 {
+		return fmt.Errorf("%d of %d blocks diverged", diverged, replayed)
+	}
+	return nil
+}
+
+// hasTomoXRoot reports whether a block carries the root of its TomoX state.
+func hasTomoXRoot(block *types.Block) bool {
+	for _, tx := range block.Transactions() {
+		if tx.To() != nil && tx.To().Hex() == common.TomoXStateAddr && len(tx.Data()) > 0 {
+			return true
+		}
+	}
+	return false
+}
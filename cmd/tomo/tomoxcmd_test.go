@@ -0,0 +1,45 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestHasTomoXRoot(t *testing.T) {
+	stateAddr, matchAddr := common.HexToAddress(common.TomoXStateAddr), common.HexToAddress(common.TomoXAddr)
+	newBlock := func(txs ...*types.Transaction) *types.Block {
+		return types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, nil, nil)
+	}
+	tests := []struct {
+		block *types.Block
+		want  bool
+	}{
+		{newBlock(), false},
+		{newBlock(types.NewTransaction(0, matchAddr, common.Big0, 0, common.Big0, []byte{0x01})), false},
+		{newBlock(types.NewTransaction(0, stateAddr, common.Big0, 0, common.Big0, nil)), false},
+		{newBlock(types.NewContractCreation(0, common.Big0, 0, common.Big0, []byte{0x01}), types.NewTransaction(1, stateAddr, common.Big0, 0, common.Big0, common.Hash{1}.Bytes())), true},
+	}
+	for i, tt := range tests {
+		if have := hasTomoXRoot(tt.block); have != tt.want {
+			t.Errorf("test %d: TomoX root presence mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
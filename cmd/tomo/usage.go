@@ -70,25 +70,35 @@ var AppHelpFlagGroups = []flagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
+			utils.KeyStoreArgon2Flag,
+			utils.KeyStoreArgon2TimeFlag,
+			utils.KeyStoreArgon2MemoryFlag,
 			//utils.NoUSBFlag,
 			utils.NetworkIdFlag,
 			//utils.TestnetFlag,
 			//utils.RinkebyFlag,
 			utils.SyncModeFlag,
+			utils.DownloaderRequestTTLFlag,
+			utils.DownloaderBlacklistFlag,
+			utils.DownloaderMinThroughputFlag,
 			utils.GCModeFlag,
+			utils.SnapshotFlag,
+			utils.LogIndexFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
-			//utils.LightServFlag,
-			//utils.LightPeersFlag,
+			utils.LightServFlag,
+			utils.LightPeersFlag,
 			//utils.LightKDFFlag,
 		},
 	},
-	//{Name: "DEVELOPER CHAIN",
-	//	Flags: []cli.Flag{
-	//		utils.DeveloperFlag,
-	//		utils.DeveloperPeriodFlag,
-	//	},
-	//},
+	{Name: "DEVELOPER CHAIN",
+		Flags: []cli.Flag{
+			utils.DeveloperFlag,
+			utils.DeveloperPeriodFlag,
+		},
+	},
 	//{
 	//	Name: "ETHASH",
 	//	Flags: []cli.Flag{
@@ -122,7 +132,9 @@ var AppHelpFlagGroups = []flagGroup{
 	//		utils.TxPoolGlobalSlotsFlag,
 	//		utils.TxPoolAccountQueueFlag,
 	//		utils.TxPoolGlobalQueueFlag,
+	//		utils.TxPoolPrioritySlotsFlag,
 	//		utils.TxPoolLifetimeFlag,
+	//		utils.TxPoolRejectUnprotectedFlag,
 	//	},
 	//},
 	//{
@@ -139,6 +151,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.OrderSignersFlag,
+			utils.TxSignersFlag,
 		},
 	},
 	{
@@ -148,13 +162,26 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCMethodsFlag,
+			utils.WSMethodsFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCMethodRateLimitsFlag,
+			utils.RPCTimeoutFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCBatchResponseSizeFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCAuthApiFlag,
+			utils.RPCLogsRangeFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
+			utils.IPCReadOnlyPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
 			utils.JSpathFlag,
@@ -184,6 +211,13 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.StakingEnabledFlag,
 			utils.StakerThreadsFlag,
+			utils.MinorityForkGuardFlag,
+			utils.MinorityForkGuardTimeoutFlag,
+			utils.MaxOrdersFlag,
+			utils.OrderTimeShareFlag,
+			utils.SignerWalletsFlag,
+			utils.RemoteSignerFlag,
+			utils.RemoteSignerAuditFlag,
 			utils.EtherbaseFlag,
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
@@ -207,6 +241,13 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.PosvVerifyRewardsFlag,
+			utils.WatchdogTimeoutFlag,
+			utils.WatchdogRotatePeersFlag,
+			utils.PosvCandidateWebhookFlag,
+			utils.EventSinksFlag,
+			utils.BridgeContractsFlag,
+			utils.BridgeConfirmationsFlag,
 			//utils.FakePoWFlag,
 			//utils.NoCompactionFlag,
 		}, debug.Flags...),
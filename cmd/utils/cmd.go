@@ -128,18 +128,30 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 	blocks := make(types.Blocks, importBatchSize)
 	n := 0
 	for batch := 0; ; batch++ {
-		// Load a batch of RLP blocks.
+		// Load a batch of RLP blocks, up to the next TomoX snapshot.
 		if checkInterrupt() {
 			return fmt.Errorf("interrupted")
 		}
+		var snapshot *core.TomoXSnapshot
 		i := 0
 		for ; i < importBatchSize; i++ {
-			var b types.Block
-			if err := stream.Decode(&b); err == io.EOF {
+			var item rlp.RawValue
+			if err := stream.Decode(&item); err == io.EOF {
 				break
 			} else if err != nil {
 				return fmt.Errorf("at block %d: %v", n, err)
 			}
+			if core.IsTomoXSnapshot(item) {
+				snapshot = new(core.TomoXSnapshot)
+				if err := rlp.DecodeBytes(item, snapshot); err != nil {
+					return fmt.Errorf("at TomoX snapshot after block %d: %v", n, err)
+				}
+				break
+			}
+			var b types.Block
+			if err := rlp.DecodeBytes(item, &b); err != nil {
+				return fmt.Errorf("at block %d: %v", n, err)
+			}
 			// don't import first block
 			if b.NumberU64() == 0 {
 				i--
@@ -148,21 +160,26 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			blocks[i] = &b
 			n++
 		}
-		if i == 0 {
+		if i == 0 && snapshot == nil {
 			break
 		}
 		// Import the batch.
 		if checkInterrupt() {
 			return fmt.Errorf("interrupted")
 		}
-		missing := missingBlocks(chain, blocks[:i])
-		if len(missing) == 0 {
-			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
-			continue
-		}
-		if _, err := chain.InsertChain(missing); err != nil {
+		if missing := missingBlocks(chain, blocks[:i]); len(missing) == 0 {
+			if i > 0 {
+				log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[i-1].Hash())
+			}
+		} else if _, err := chain.InsertChain(missing); err != nil {
 			return fmt.Errorf("invalid block %d: %v", n, err)
 		}
+		// Write the TomoX states following the imported blocks
+		if snapshot != nil {
+			if err := chain.ImportTomoXSnapshot(snapshot); err != nil {
+				return fmt.Errorf("invalid TomoX snapshot of block %d: %v", snapshot.Number, err)
+			}
+		}
 	}
 	return nil
 }
@@ -186,8 +203,9 @@ func missingBlocks(chain *core.BlockChain, blocks []*types.Block) []*types.Block
 }
 
 // ExportChain exports a blockchain into the specified file, truncating any data
-// already present in the file.
-func ExportChain(blockchain *core.BlockChain, fn string) error {
+// already present in the file. With withTomoX set, the TomoX and lending states
+// are exported after each checkpoint block.
+func ExportChain(blockchain *core.BlockChain, fn string, withTomoX bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -203,7 +221,13 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := blockchain.Export(writer); err != nil {
+	export := blockchain.Export
+	if withTomoX {
+		export = func(w io.Writer) error {
+			return blockchain.ExportNWithTomoX(w, 0, blockchain.CurrentBlock().NumberU64())
+		}
+	}
+	if err := export(writer); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -212,8 +236,9 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 }
 
 // ExportAppendChain exports a blockchain into the specified file, appending to
-// the file if data already exists in it.
-func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64) error {
+// the file if data already exists in it. With withTomoX set, the TomoX and
+// lending states are exported after each checkpoint block.
+func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64, withTomoX bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -229,7 +254,11 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := blockchain.ExportN(writer, first, last); err != nil {
+	export := blockchain.ExportN
+	if withTomoX {
+		export = blockchain.ExportNWithTomoX
+	}
+	if err := export(writer, first, last); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
@@ -0,0 +1,134 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	blockSignerContract "github.com/ethereum/go-ethereum/contracts/blocksigner"
+	randomizeContract "github.com/ethereum/go-ethereum/contracts/randomize"
+	validatorContract "github.com/ethereum/go-ethereum/contracts/validator"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// developerBalance is the amount pre-funded to every developer account.
+	developerBalance = new(big.Int).Mul(big.NewInt(1000000000), big.NewInt(params.Ether))
+
+	// developerCap is the stake of the single developer masternode.
+	developerCap = new(big.Int).Mul(big.NewInt(50000), big.NewInt(params.Ether))
+
+	// developerDeployKey is a throwaway key used to deploy the system contracts
+	// on the simulated backend before copying them into the genesis allocation.
+	developerDeployKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+)
+
+// developerAccount returns the account sealing the developer chain, reusing the
+// first keystore account or creating a new one with an empty password.
+func developerAccount(ks *keystore.KeyStore) accounts.Account {
+	if accs := ks.Accounts(); len(accs) > 0 {
+		return accs[0]
+	}
+	developer, err := ks.NewAccount("")
+	if err != nil {
+		Fatalf("Failed to create developer account: %v", err)
+	}
+	return developer
+}
+
+// MakeDeveloperGenesis assembles the genesis of a single node POSV developer
+// chain sealed by the first keystore account. Every keystore account is
+// pre-funded and the validator, block signer and randomize contracts are
+// deployed so checkpoints, signing transactions and rewards work as on mainnet.
+func MakeDeveloperGenesis(ctx *cli.Context, ks *keystore.KeyStore) *core.Genesis {
+	developer := developerAccount(ks)
+	genesis := core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+
+	for _, account := range ks.Accounts() {
+		genesis.Alloc[account.Address] = core.GenesisAccount{Balance: developerBalance}
+	}
+	if err := allocDeveloperContracts(genesis, developer.Address); err != nil {
+		Fatalf("Failed to deploy developer system contracts: %v", err)
+	}
+	log.Info("Using developer account", "address", developer.Address, "funded", len(ks.Accounts()))
+	return genesis
+}
+
+// allocDeveloperContracts deploys the POSV system contracts on a simulated
+// backend and copies their code and storage to the well known addresses.
+func allocDeveloperContracts(genesis *core.Genesis, masternode common.Address) error {
+	deployer := crypto.PubkeyToAddress(developerDeployKey.PublicKey)
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{deployer: {Balance: new(big.Int).Mul(developerCap, big.NewInt(2))}})
+	opts := bind.NewKeyedTransactor(developerDeployKey)
+
+	validatorAddr, _, err := validatorContract.DeployValidator(opts, backend, []common.Address{masternode}, []*big.Int{developerCap}, masternode)
+	if err != nil {
+		return err
+	}
+	blockSignerAddr, _, err := blockSignerContract.DeployBlockSigner(opts, backend, new(big.Int).SetUint64(genesis.Config.Posv.Epoch))
+	if err != nil {
+		return err
+	}
+	randomizeAddr, _, err := randomizeContract.DeployRandomize(opts, backend)
+	if err != nil {
+		return err
+	}
+	backend.Commit()
+
+	contracts := []struct {
+		deployed common.Address
+		target   string
+		balance  *big.Int
+	}{
+		{validatorAddr, common.MasternodeVotingSMC, developerCap},
+		{blockSignerAddr, common.BlockSigners, new(big.Int)},
+		{randomizeAddr, common.RandomizeSMC, new(big.Int)},
+	}
+	for _, contract := range contracts {
+		account, err := backend.DumpContract(contract.deployed)
+		if err != nil {
+			return err
+		}
+		account.Balance = contract.balance
+		genesis.Alloc[common.HexToAddress(contract.target)] = account
+	}
+	return nil
+}
+
+// developerTomoXDir returns the directory holding the TomoX databases of a
+// developer chain: inside the data directory if one was given, otherwise a
+// temporary directory matching the ephemeral in-memory chain.
+func developerTomoXDir(ctx *cli.Context) string {
+	if ctx.GlobalIsSet(DataDirFlag.Name) {
+		return filepath.Join(ctx.GlobalString(DataDirFlag.Name), "tomox")
+	}
+	dir, err := ioutil.TempDir("", "tomox-dev")
+	if err != nil {
+		Fatalf("Failed to create developer TomoX directory: %v", err)
+	}
+	return dir
+}
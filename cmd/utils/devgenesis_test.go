@@ -0,0 +1,57 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestDeveloperContracts(t *testing.T) {
+	masternode := common.HexToAddress("0x0000000000000000000000000000000000000101")
+	genesis := core.DeveloperGenesisBlock(2, masternode)
+	if err := allocDeveloperContracts(genesis, masternode); err != nil {
+		t.Fatalf("failed to deploy the system contracts: %v", err)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	block := genesis.MustCommit(db)
+	statedb, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open the genesis state: %v", err)
+	}
+	for _, addr := range []string{common.MasternodeVotingSMC, common.BlockSigners, common.RandomizeSMC} {
+		if len(statedb.GetCode(common.HexToAddress(addr))) == 0 {
+			t.Errorf("system contract %s not deployed", addr)
+		}
+	}
+	// The developer account is the single masternode, staked and owning itself
+	if candidates := state.GetCandidates(statedb); len(candidates) != 1 || candidates[0] != masternode {
+		t.Fatalf("candidates mismatch: have %x, want [%x]", candidates, masternode)
+	}
+	if owner := state.GetCandidateOwner(statedb, masternode); owner != masternode {
+		t.Errorf("owner mismatch: have %x, want %x", owner, masternode)
+	}
+	if cap := state.GetCandidateCap(statedb, masternode); cap.Cmp(developerCap) != 0 {
+		t.Errorf("cap mismatch: have %v, want %v", cap, developerCap)
+	}
+	if balance := statedb.GetBalance(common.HexToAddress(common.MasternodeVotingSMC)); balance.Cmp(developerCap) != 0 {
+		t.Errorf("validator balance mismatch: have %v, want %v", balance, developerCap)
+	}
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/bridge"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/consensus"
@@ -158,7 +159,7 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral proof-of-stake-voting network with pre-funded developer accounts, staking and TomoX enabled",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
@@ -187,11 +188,31 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
 		Value: &defaultSyncMode,
 	}
+	DownloaderRequestTTLFlag = cli.DurationFlag{
+		Name:  "downloader.requestttl",
+		Usage: "Maximum time to wait for a sync request before penalising the peer (0 = built-in limit)",
+	}
+	DownloaderBlacklistFlag = cli.DurationFlag{
+		Name:  "downloader.blacklist",
+		Usage: "Time to refuse syncing from a peer dropped for stalling or bad data (0 = disabled)",
+	}
+	DownloaderMinThroughputFlag = cli.Float64Flag{
+		Name:  "downloader.minthroughput",
+		Usage: "Items per second under which a sync peer is rotated out (0 = disabled)",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat snapshot of the head state to speed up the state reads",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain a precise index of the log addresses and topics to speed up the log filtering",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -206,6 +227,26 @@ var (
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scryptn",
+		Usage: "Scrypt N parameter of the key files (default = 262144, or 4096 with --lightkdf)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scryptp",
+		Usage: "Scrypt P parameter of the key files (default = 1, or 6 with --lightkdf)",
+	}
+	KeyStoreArgon2Flag = cli.BoolFlag{
+		Name:  "keystore.argon2",
+		Usage: "Store new and updated keys in version 4 key files, derived with argon2id",
+	}
+	KeyStoreArgon2TimeFlag = cli.Uint64Flag{
+		Name:  "keystore.argon2time",
+		Usage: "Number of argon2id passes over the memory (default = 3)",
+	}
+	KeyStoreArgon2MemoryFlag = cli.Uint64Flag{
+		Name:  "keystore.argon2memory",
+		Usage: "Megabytes of memory used by argon2id (default = 256, or 4 with --lightkdf)",
+	}
 	// TomoX settings
 	TomoXEnabledFlag = cli.BoolFlag{
 		Name:  "tomox",
@@ -306,11 +347,34 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: eth.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolPrioritySlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.priorityslots",
+		Usage: "Slots reserved above the global ones for the governance transactions of the masternodes",
+		Value: eth.DefaultConfig.TxPool.PrioritySlots,
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolRejectUnprotectedFlag = cli.BoolFlag{
+		Name:  "txpool.rejectunprotected",
+		Usage: "Reject the transactions without replay protection (EIP-155), replayable on other chains",
+	}
+	OrderPoolRelayersFlag = cli.StringFlag{
+		Name:  "orderpool.relayers",
+		Usage: "Comma separated relayer addresses whose orders are accepted (default = all)",
+	}
+	OrderPoolRelayerSlotsFlag = cli.Uint64Flag{
+		Name:  "orderpool.relayerslots",
+		Usage: "Maximum number of pooled orders per relayer (0 = unlimited)",
+		Value: eth.DefaultConfig.OrderPool.RelayerSlots,
+	}
+	OrderPoolRelayerRateFlag = cli.Uint64Flag{
+		Name:  "orderpool.relayerrate",
+		Usage: "Maximum number of orders accepted per relayer per minute (0 = unlimited)",
+		Value: eth.DefaultConfig.OrderPool.RelayerRate,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -361,6 +425,64 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinorityForkGuardFlag = cli.BoolFlag{
+		Name:  "mine.forkguard",
+		Usage: "Stop staking while fewer than half of the masternodes seal the chain (minority fork protection)",
+	}
+	MinorityForkGuardTimeoutFlag = cli.DurationFlag{
+		Name:  "mine.forkguard.timeout",
+		Usage: "Longest time the minority fork protection stops staking before resuming it",
+		Value: eth.DefaultConfig.MinorityForkGuardTimeout,
+	}
+	MaxOrdersFlag = cli.IntFlag{
+		Name:  "mine.maxorders",
+		Usage: "Maximum number of order transactions matched per block (0 = unlimited)",
+	}
+	OrderTimeShareFlag = cli.IntFlag{
+		Name:  "mine.ordertimeshare",
+		Usage: "Percentage of the block period spent matching order transactions (0 = unlimited)",
+	}
+	SignerWalletsFlag = cli.StringFlag{
+		Name:  "mine.signers",
+		Usage: "Comma separated URLs of the local wallets holding the etherbase key, sealing fails over from the first to the next healthy one (single node only)",
+	}
+	RemoteSignerFlag = cli.StringFlag{
+		Name:  "mine.remotesigner",
+		Usage: "HTTP endpoint of an external signer sealing blocks for the etherbase (keeps the key off this host)",
+	}
+	RemoteSignerAuditFlag = cli.StringFlag{
+		Name:  "mine.remotesigner.audit",
+		Usage: "File to append an audit log of the remote signer requests to",
+	}
+	PosvVerifyRewardsFlag = cli.BoolFlag{
+		Name:  "posv.verifyrewards",
+		Usage: "Recompute the rewards at each checkpoint and report mismatches with the applied ones (auditing)",
+	}
+	WatchdogTimeoutFlag = cli.DurationFlag{
+		Name:  "watchdog.timeout",
+		Usage: "Alert when the chain head of a masternode does not advance for this long (0 = disabled)",
+	}
+	WatchdogRotatePeersFlag = cli.BoolFlag{
+		Name:  "watchdog.rotatepeers",
+		Usage: "Drop the peer with the lowest total difficulty when the chain head is stuck",
+	}
+	PosvCandidateWebhookFlag = cli.StringFlag{
+		Name:  "posv.candidatewebhook",
+		Usage: "URL to POST the changes of the masternode candidate status of the etherbase to",
+	}
+	EventSinksFlag = cli.StringFlag{
+		Name:  "eventsinks",
+		Usage: "Comma separated list of sink URLs to publish chain and DEX events to (http(s)://, kafka://<rest proxy>/<topic>, nats://<server>/<subject>)",
+	}
+	BridgeContractsFlag = cli.StringFlag{
+		Name:  "bridge.contracts",
+		Usage: "Comma separated list of bridge contracts whose events are attested by the masternodes",
+	}
+	BridgeConfirmationsFlag = cli.Uint64Flag{
+		Name:  "bridge.confirmations",
+		Usage: "Number of blocks a bridge event is buried under before being attested",
+		Value: bridge.DefaultConfig.Confirmations,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -372,6 +494,14 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	OrderSignersFlag = cli.StringFlag{
+		Name:  "ordersigners",
+		Usage: "Comma separated list of the only accounts signing orders through the APIs",
+	}
+	TxSignersFlag = cli.StringFlag{
+		Name:  "txsigners",
+		Usage: "Comma separated list of the only accounts signing transactions through the APIs",
+	}
 
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
@@ -424,6 +554,14 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpctlscert",
+		Usage: "PEM encoded certificate to serve the HTTP-RPC interface over TLS with",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpctlskey",
+		Usage: "PEM encoded private key of the HTTP-RPC TLS certificate",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -432,6 +570,10 @@ var (
 		Name:  "ipcpath",
 		Usage: "Filename for IPC socket/pipe within the datadir (explicit paths escape it)",
 	}
+	IPCReadOnlyPathFlag = DirectoryFlag{
+		Name:  "ipcreadonlypath",
+		Usage: "Filename for an additional read-only IPC socket/pipe serving the public APIs",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -456,6 +598,49 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCMethodsFlag = cli.StringFlag{
+		Name:  "rpcmethods",
+		Usage: "Comma separated list of methods served over the HTTP-RPC interface (namespace_method or namespace_*)",
+		Value: "",
+	}
+	WSMethodsFlag = cli.StringFlag{
+		Name:  "wsmethods",
+		Usage: "Comma separated list of methods served over the WS-RPC interface (namespace_method or namespace_*)",
+		Value: "",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Requests per second served to a client IP over the HTTP-RPC and WS-RPC interfaces (0 = unlimited)",
+	}
+	RPCMethodRateLimitsFlag = cli.StringFlag{
+		Name:  "rpcmethodratelimits",
+		Usage: "Comma separated requests per second served to a client IP per method (e.g. eth_call=5,eth_getLogs=1)",
+		Value: "",
+	}
+	RPCTimeoutFlag = cli.DurationFlag{
+		Name:  "rpctimeout",
+		Usage: "Execution time allowed to eth_call, eth_estimateGas and eth_getLogs over the HTTP-RPC and WS-RPC interfaces (0 = unlimited)",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpcbatchlimit",
+		Usage: "Maximum number of requests in a batch over the HTTP-RPC and WS-RPC interfaces (0 = unlimited)",
+	}
+	RPCBatchResponseSizeFlag = cli.IntFlag{
+		Name:  "rpcbatchresponsesize",
+		Usage: "Maximum size in bytes of the responses to a batch over the HTTP-RPC and WS-RPC interfaces (0 = unlimited)",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "Path of the hex encoded secret of the JWTs authenticating HTTP-RPC and WS-RPC clients (generated if missing)",
+	}
+	RPCAuthApiFlag = cli.StringFlag{
+		Name:  "rpcauthapi",
+		Usage: "API's offered to authenticated HTTP-RPC and WS-RPC clients only (default: admin,debug,miner,personal)",
+	}
+	RPCLogsRangeFlag = cli.Uint64Flag{
+		Name:  "rpclogsrange",
+		Usage: "Maximum number of blocks searched by an eth_getLogs request (0 = unlimited)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -559,6 +744,19 @@ var (
 		Usage: "Data directory for the TomoX databases",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	TomoXSnapshotsFlag = cli.BoolFlag{
+		Name:  "tomox.snapshots",
+		Usage: "Export the TomoX and lending states at each epoch checkpoint along with the blocks",
+	}
+	TomoXCacheFlag = cli.IntFlag{
+		Name:  "tomox.cache",
+		Usage: "Megabytes of memory allocated to the TomoX state tries",
+		Value: tomox.DefaultConfig.TrieCache,
+	}
+	TomoXDiscoveryFlag = cli.BoolFlag{
+		Name:  "tomox.discovery",
+		Usage: "Advertise and look for the other TomoX nodes over discovery v5, preferring them to exchange orders",
+	}
 	TomoXDBEngineFlag = cli.StringFlag{
 		Name:  "tomox.dbengine",
 		Usage: "Database engine for TomoX (leveldb, mongodb)",
@@ -578,6 +776,27 @@ var (
 		Name:  "tomox.dbReplicaSetName",
 		Usage: "ReplicaSetName if Master-Slave is setup",
 	}
+	TomoXKafkaBrokersFlag = cli.StringFlag{
+		Name:  "tomox.kafka.brokers",
+		Usage: "Comma separated list of Kafka brokers (host:port) to export the orders and trades to",
+	}
+	TomoXKafkaTopicFlag = cli.StringFlag{
+		Name:  "tomox.kafka.topic",
+		Usage: "Kafka topic the orders and trades are exported to",
+		Value: tomox.DefaultConfig.KafkaTopic,
+	}
+	TomoXHistoryServeFlag = cli.BoolFlag{
+		Name:  "tomox.history.serve",
+		Usage: "Serve the order history of the MongoDB to the nodes snapshotting it (mongodb engine only)",
+	}
+	TomoXHistoryPeersFlag = cli.StringFlag{
+		Name:  "tomox.history.peers",
+		Usage: "Comma separated enode URLs of the archive nodes to snapshot the order history from (mongodb engine only)",
+	}
+	TomoXHistoryBlocksFlag = cli.Uint64Flag{
+		Name:  "tomox.history.blocks",
+		Usage: "Number of recent blocks to snapshot the order history of (0 = all)",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -743,6 +962,12 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		cfg.HTTPTLSCert = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		cfg.HTTPTLSKey = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -766,6 +991,49 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCPolicy configures the restrictions of the requests served by the HTTP
+// and WebSocket RPC endpoints from the set command line flags.
+func setRPCPolicy(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCMethodsFlag.Name) {
+		cfg.HTTPMethods = splitAndTrim(ctx.GlobalString(RPCMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(WSMethodsFlag.Name) {
+		cfg.WSMethods = splitAndTrim(ctx.GlobalString(WSMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodRateLimitsFlag.Name) {
+		cfg.RPCMethodRateLimits = make(map[string]float64)
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodRateLimitsFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid method rate limit %q, expected method=rate", entry)
+			}
+			limit, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || limit < 0 {
+				Fatalf("Invalid rate limit of method %s: %s", parts[0], parts[1])
+			}
+			cfg.RPCMethodRateLimits[parts[0]] = limit
+		}
+	}
+	if ctx.GlobalIsSet(RPCTimeoutFlag.Name) {
+		cfg.RPCTimeout = ctx.GlobalDuration(RPCTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.RPCJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuthApiFlag.Name) {
+		cfg.RPCAuthModules = splitAndTrim(ctx.GlobalString(RPCAuthApiFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCBatchLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseSizeFlag.Name) {
+		cfg.RPCBatchResponseSize = ctx.GlobalInt(RPCBatchResponseSizeFlag.Name)
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -776,6 +1044,9 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 	case ctx.GlobalIsSet(IPCPathFlag.Name):
 		cfg.IPCPath = ctx.GlobalString(IPCPathFlag.Name)
 	}
+	if ctx.GlobalIsSet(IPCReadOnlyPathFlag.Name) {
+		cfg.Endpoints = append(cfg.Endpoints, node.Endpoint{IPCPath: ctx.GlobalString(IPCReadOnlyPathFlag.Name), ReadOnly: true})
+	}
 }
 
 // MakeDatabaseHandles raises out the number of allowed file handles per process
@@ -833,6 +1104,32 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
 	}
 }
 
+// setSigners retrieves the accounts allowed to sign orders and transactions
+// through the APIs either from the directly specified command line flags or
+// from the keystore if CLI indexed.
+func setSigners(ctx *cli.Context, ks *keystore.KeyStore, cfg *eth.Config) {
+	parse := func(flag cli.StringFlag) []common.Address {
+		var addrs []common.Address
+		for _, entry := range strings.Split(ctx.GlobalString(flag.Name), ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			account, err := MakeAddress(ks, entry)
+			if err != nil {
+				Fatalf("Option %q: %v", flag.Name, err)
+			}
+			addrs = append(addrs, account.Address)
+		}
+		return addrs
+	}
+	if ctx.GlobalIsSet(OrderSignersFlag.Name) {
+		cfg.OrderSigners = parse(OrderSignersFlag)
+	}
+	if ctx.GlobalIsSet(TxSignersFlag.Name) {
+		cfg.TxSigners = parse(TxSignersFlag)
+	}
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -894,7 +1191,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	// if we're running a light client or server, force enable the v5 peer discovery
 	// unless it is explicitly disabled with --nodiscover note that explicitly specifying
 	// --v5disc overrides --nodiscover, in which case the later only disables v4 discovery
-	forceV5Discovery := (lightClient || lightServer) && !ctx.GlobalBool(NoDiscoverFlag.Name)
+	forceV5Discovery := (lightClient || lightServer || ctx.GlobalBool(TomoXDiscoveryFlag.Name)) && !ctx.GlobalBool(NoDiscoverFlag.Name)
 	if ctx.GlobalIsSet(DiscoveryV5Flag.Name) {
 		cfg.DiscoveryV5 = ctx.GlobalBool(DiscoveryV5Flag.Name)
 	} else if forceV5Discovery {
@@ -924,6 +1221,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCPolicy(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -943,6 +1241,21 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.KeyStoreScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.KeyStoreScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreArgon2Flag.Name) {
+		cfg.KeyStoreArgon2 = ctx.GlobalBool(KeyStoreArgon2Flag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreArgon2TimeFlag.Name) {
+		cfg.KeyStoreArgon2Time = uint32(ctx.GlobalUint64(KeyStoreArgon2TimeFlag.Name))
+	}
+	if ctx.GlobalIsSet(KeyStoreArgon2MemoryFlag.Name) {
+		cfg.KeyStoreArgon2Memory = uint32(ctx.GlobalUint64(KeyStoreArgon2MemoryFlag.Name) * 1024)
+	}
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
@@ -988,9 +1301,33 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrioritySlotsFlag.Name) {
+		cfg.PrioritySlots = ctx.GlobalUint64(TxPoolPrioritySlotsFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRejectUnprotectedFlag.Name) {
+		cfg.RejectUnprotected = ctx.GlobalBool(TxPoolRejectUnprotectedFlag.Name)
+	}
+}
+
+func setOrderPool(ctx *cli.Context, cfg *core.OrderPoolConfig) {
+	if ctx.GlobalIsSet(OrderPoolRelayersFlag.Name) {
+		cfg.Relayers = nil
+		for _, relayer := range strings.Split(ctx.GlobalString(OrderPoolRelayersFlag.Name), ",") {
+			if relayer = strings.TrimSpace(relayer); !common.IsHexAddress(relayer) {
+				Fatalf("Invalid relayer address in --%s: %q", OrderPoolRelayersFlag.Name, relayer)
+			}
+			cfg.Relayers = append(cfg.Relayers, common.HexToAddress(relayer))
+		}
+	}
+	if ctx.GlobalIsSet(OrderPoolRelayerSlotsFlag.Name) {
+		cfg.RelayerSlots = ctx.GlobalUint64(OrderPoolRelayerSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(OrderPoolRelayerRateFlag.Name) {
+		cfg.RelayerRate = ctx.GlobalUint64(OrderPoolRelayerRateFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1066,6 +1403,10 @@ func SetTomoXConfig(ctx *cli.Context, cfg *tomox.Config) {
 	if len(cfg.DataDir) == 0 {
 		if ctx.GlobalIsSet(TomoXDataDirFlag.Name) {
 			cfg.DataDir = ctx.GlobalString(TomoXDataDirFlag.Name)
+		} else if ctx.GlobalBool(DeveloperFlag.Name) {
+			// Developer chains keep the order books next to the chain data
+			// (or in a throwaway directory if the chain is ephemeral).
+			cfg.DataDir = developerTomoXDir(ctx)
 		} else {
 			cfg.DataDir = TomoXDataDirFlag.Value.String()
 		}
@@ -1088,6 +1429,43 @@ func SetTomoXConfig(ctx *cli.Context, cfg *tomox.Config) {
 	if ctx.GlobalIsSet(TomoXDBReplicaSetNameFlag.Name) {
 		cfg.ReplicaSetName = ctx.GlobalString(TomoXDBReplicaSetNameFlag.Name)
 	}
+	if ctx.GlobalIsSet(TomoXCacheFlag.Name) {
+		cfg.TrieCache = ctx.GlobalInt(TomoXCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(TomoXDiscoveryFlag.Name) {
+		cfg.Discovery = ctx.GlobalBool(TomoXDiscoveryFlag.Name)
+	}
+	if ctx.GlobalIsSet(TomoXKafkaBrokersFlag.Name) {
+		cfg.KafkaBrokers = strings.Split(ctx.GlobalString(TomoXKafkaBrokersFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(TomoXKafkaTopicFlag.Name) {
+		cfg.KafkaTopic = ctx.GlobalString(TomoXKafkaTopicFlag.Name)
+	}
+	if ctx.GlobalIsSet(TomoXHistoryServeFlag.Name) {
+		cfg.HistoryServe = ctx.GlobalBool(TomoXHistoryServeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TomoXHistoryPeersFlag.Name) {
+		cfg.HistoryPeers = strings.Split(ctx.GlobalString(TomoXHistoryPeersFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(TomoXHistoryBlocksFlag.Name) {
+		cfg.HistoryBlocks = ctx.GlobalUint64(TomoXHistoryBlocksFlag.Name)
+	}
+}
+
+// SetBridgeConfig applies bridge-related command line flags to the config.
+func SetBridgeConfig(ctx *cli.Context, cfg *bridge.Config) {
+	if ctx.GlobalIsSet(BridgeContractsFlag.Name) {
+		cfg.Contracts = nil
+		for _, contract := range strings.Split(ctx.GlobalString(BridgeContractsFlag.Name), ",") {
+			if !common.IsHexAddress(contract) {
+				Fatalf("Invalid bridge contract address %q", contract)
+			}
+			cfg.Contracts = append(cfg.Contracts, common.HexToAddress(contract))
+		}
+	}
+	if ctx.GlobalIsSet(BridgeConfirmationsFlag.Name) {
+		cfg.Confirmations = ctx.GlobalUint64(BridgeConfirmationsFlag.Name)
+	}
 }
 
 // SetEthConfig applies eth-related command line flags to the config.
@@ -1100,8 +1478,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setEtherbase(ctx, ks, cfg)
+	setSigners(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setOrderPool(ctx, &cfg.OrderPool)
 	setEthash(ctx, cfg)
 
 	switch {
@@ -1131,6 +1511,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
@@ -1147,6 +1533,54 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinorityForkGuardFlag.Name) {
+		cfg.MinorityForkGuard = ctx.GlobalBool(MinorityForkGuardFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinorityForkGuardTimeoutFlag.Name) {
+		cfg.MinorityForkGuardTimeout = ctx.GlobalDuration(MinorityForkGuardTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxOrdersFlag.Name) {
+		cfg.OrderBudget.MaxOrders = ctx.GlobalInt(MaxOrdersFlag.Name)
+	}
+	if ctx.GlobalIsSet(OrderTimeShareFlag.Name) {
+		cfg.OrderBudget.TimeShare = ctx.GlobalInt(OrderTimeShareFlag.Name)
+	}
+	if ctx.GlobalIsSet(SignerWalletsFlag.Name) {
+		cfg.SignerWallets = strings.Split(ctx.GlobalString(SignerWalletsFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(RemoteSignerFlag.Name) {
+		cfg.RemoteSigner = ctx.GlobalString(RemoteSignerFlag.Name)
+	}
+	if ctx.GlobalIsSet(RemoteSignerAuditFlag.Name) {
+		cfg.RemoteSignerAudit = ctx.GlobalString(RemoteSignerAuditFlag.Name)
+	}
+	if ctx.GlobalIsSet(PosvVerifyRewardsFlag.Name) {
+		cfg.VerifyRewards = ctx.GlobalBool(PosvVerifyRewardsFlag.Name)
+	}
+	if ctx.GlobalIsSet(DownloaderRequestTTLFlag.Name) {
+		cfg.DownloaderRequestTTL = ctx.GlobalDuration(DownloaderRequestTTLFlag.Name)
+	}
+	if ctx.GlobalIsSet(DownloaderBlacklistFlag.Name) {
+		cfg.DownloaderBlacklist = ctx.GlobalDuration(DownloaderBlacklistFlag.Name)
+	}
+	if ctx.GlobalIsSet(DownloaderMinThroughputFlag.Name) {
+		cfg.DownloaderMinThroughput = ctx.GlobalFloat64(DownloaderMinThroughputFlag.Name)
+	}
+	if ctx.GlobalIsSet(WatchdogTimeoutFlag.Name) {
+		cfg.WatchdogTimeout = ctx.GlobalDuration(WatchdogTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(WatchdogRotatePeersFlag.Name) {
+		cfg.WatchdogRotatePeers = ctx.GlobalBool(WatchdogRotatePeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(PosvCandidateWebhookFlag.Name) {
+		cfg.CandidateWebhook = ctx.GlobalString(PosvCandidateWebhookFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogsRangeFlag.Name) {
+		cfg.LogsRangeLimit = ctx.GlobalUint64(RPCLogsRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(EventSinksFlag.Name) {
+		cfg.EventSinks = strings.Split(ctx.GlobalString(EventSinksFlag.Name), ",")
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
@@ -1171,24 +1605,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.Genesis = core.DefaultRinkebyGenesisBlock()
 	case ctx.GlobalBool(DeveloperFlag.Name):
 		// Create new developer account or reuse existing one
-		var (
-			developer accounts.Account
-			err       error
-		)
-		if accs := ks.Accounts(); len(accs) > 0 {
-			developer = ks.Accounts()[0]
-		} else {
-			developer, err = ks.NewAccount("")
-			if err != nil {
-				Fatalf("Failed to create developer account: %v", err)
-			}
-		}
+		developer := developerAccount(ks)
 		if err := ks.Unlock(developer, ""); err != nil {
 			Fatalf("Failed to unlock developer account: %v", err)
 		}
-		log.Info("Using developer account", "address", developer.Address)
-
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		// Reuse the stored genesis if the chain was initialised with 'tomo init --dev',
+		// deploying the system contracts only for a new chain
+		stored := false
+		if ctx.GlobalIsSet(DataDirFlag.Name) {
+			chainDb := MakeChainDatabase(ctx, stack)
+			stored = core.GetCanonicalHash(chainDb, 0) != (common.Hash{})
+			chainDb.Close()
+		}
+		if !stored {
+			cfg.Genesis = MakeDeveloperGenesis(ctx, ks)
+		}
 		if !ctx.GlobalIsSet(GasPriceFlag.Name) {
 			cfg.GasPrice = big.NewInt(1)
 		}
@@ -1251,9 +1682,22 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if err != nil {
 		Fatalf("%v", err)
 	}
-	var engine consensus.Engine
+	var (
+		engine consensus.Engine
+		tomoX  *tomox.TomoX
+	)
 	if config.Posv != nil {
-		engine = posv.New(config.Posv, chainDb)
+		// The TomoX service holds the order book and lending states the
+		// blocks are processed on
+		tomoXCfg := tomox.DefaultConfig
+		SetTomoXConfig(ctx, &tomoXCfg)
+		tomoX = tomox.New(&tomoXCfg)
+
+		c := posv.New(config.Posv, chainDb)
+		c.GetTomoXService = func() *tomox.TomoX {
+			return tomoX
+		}
+		engine = c
 	} else {
 		engine = ethash.NewFaker()
 		if !ctx.GlobalBool(FakePoWFlag.Name) {
@@ -1275,12 +1719,17 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: eth.DefaultConfig.TrieCache,
 		TrieTimeLimit: eth.DefaultConfig.TrieTimeout,
+		Snapshot:      ctx.GlobalBool(SnapshotFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
-	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
+	if tomoX != nil {
+		chain, err = core.NewBlockChainEx(chainDb, tomoX.GetDB(), cache, config, engine, vmcfg)
+	} else {
+		chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
+	}
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"github.com/ethereum/go-ethereum/bridge"
 	"github.com/ethereum/go-ethereum/dashboard"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -69,6 +70,18 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterBridgeService adds the bridge attestations to the stack.
+func RegisterBridgeService(stack *node.Node, cfg *bridge.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		ctx.Service(&ethServ)
+
+		return bridge.New(cfg, ethServ)
+	}); err != nil {
+		Fatalf("Failed to register the bridge service: %v", err)
+	}
+}
+
 func RegisterTomoXService(stack *node.Node, cfg *tomox.Config) {
 	if err := stack.Register(func(n *node.ServiceContext) (node.Service, error) {
 		return tomox.New(cfg), nil
@@ -23,6 +23,8 @@ const (
 	RangeReturnSigner          = 150
 	MinimunMinerBlockPerEpoch  = 1
 	TomoXSnapshotInterval      = 100 // 100 blocks
+	LendingLiquidationRate     = 110 // Collateral value in percent of the debt below which loans are liquidated
+	TomoXPriceWindow           = 100 // Number of blocks of trades averaged by the TomoX price oracle
 )
 
 var TIP2019Block = big.NewInt(1050000)
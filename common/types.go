@@ -37,6 +37,12 @@ const (
 	TeamAddr            = "0x0000000000000000000000000000000000000099"
 	TomoXAddr           = "0x0000000000000000000000000000000000000091"
 	TomoXStateAddr      = "0x0000000000000000000000000000000000000092"
+	TomoXLendingAddr    = "0x0000000000000000000000000000000000000093"
+	TomoXPriceOracle    = "0x0000000000000000000000000000000000000094"
+	MasternodeData      = "0x0000000000000000000000000000000000000095"
+	TomoXPairHalt       = "0x0000000000000000000000000000000000000096"
+	TomoXPairSize       = "0x0000000000000000000000000000000000000097"
+	BLSRegistry         = "0x0000000000000000000000000000000000000098"
 	TomoNativeAddress   = "0x0000000000000000000000000000000000000001"
 	VoteMethod          = "0x6dd7d8ea"
 	UnvoteMethod        = "0x02aa9be2"
@@ -87,6 +87,30 @@ func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
 	return snap.GetSigners(), nil
 }
 
+// GetExtraData decodes the extra-data of the header at the specified block.
+func (api *API) GetExtraData(number *rpc.BlockNumber) (*ExtraData, error) {
+	// Retrieve the requested block number (or current if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return DecodeHeaderExtra(header, api.posv.config.Epoch)
+}
+
+// GetExtraDataAtHash decodes the extra-data of the header with the given hash.
+func (api *API) GetExtraDataAtHash(hash common.Hash) (*ExtraData, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return DecodeHeaderExtra(header, api.posv.config.Epoch)
+}
+
 // Proposals returns the current proposals the node tries to uphold and vote on.
 func (api *API) Proposals() map[common.Address]bool {
 	api.posv.lock.RLock()
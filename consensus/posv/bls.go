@@ -0,0 +1,117 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posv
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+)
+
+// BuildCheckpointProof aggregates the BLS signatures of the gap block recorded
+// in the registry by the masternodes of an epoch. Masternodes without a valid
+// signature of this very gap block are left out of the signers.
+func BuildCheckpointProof(statedb state.StorageReader, masternodes []common.Address, gap uint64) *CheckpointProof {
+	var (
+		proof = emptyCheckpointProof(len(masternodes))
+		sigs  []*bls.Signature
+	)
+	for i, masternode := range masternodes {
+		number, sig := state.GetBLSCheckpointSignature(statedb, masternode)
+		if number != gap || sig == nil || state.GetBLSPublicKey(statedb, masternode) == nil {
+			continue
+		}
+		s, err := bls.UnmarshalSignature(sig)
+		if err != nil {
+			continue
+		}
+		sigs = append(sigs, s)
+		proof.Signers[i/8] |= 1 << uint(i%8)
+	}
+	if len(sigs) > 0 {
+		proof.Signature = bls.AggregateSignatures(sigs).Marshal()
+	}
+	return proof
+}
+
+// VerifyCheckpointProof checks that a checkpoint proof covers the masternodes
+// of an epoch, and that its aggregate signature verifies over the hash of the
+// gap block against the registered keys of the signers.
+func VerifyCheckpointProof(statedb state.StorageReader, masternodes []common.Address, gapHash common.Hash, proof *CheckpointProof) error {
+	if proof == nil || len(proof.Signers) != (len(masternodes)+7)/8 || len(proof.Signature) != bls.SignatureLength {
+		return errInvalidCheckpointProof
+	}
+	var pks []*bls.PublicKey
+	for i := 0; i < 8*len(proof.Signers); i++ {
+		if proof.Signers[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+		if i >= len(masternodes) {
+			return errInvalidCheckpointProof
+		}
+		pk, err := bls.UnmarshalPublicKey(state.GetBLSPublicKey(statedb, masternodes[i]))
+		if err != nil {
+			return errInvalidCheckpointProof
+		}
+		pks = append(pks, pk)
+	}
+	if len(pks) == 0 {
+		if !bytes.Equal(proof.Signature, make([]byte, bls.SignatureLength)) {
+			return errInvalidCheckpointProof
+		}
+		return nil
+	}
+	sig, err := bls.UnmarshalSignature(proof.Signature)
+	if err != nil || !sig.Verify(bls.AggregatePublicKeys(pks), gapHash.Bytes()) {
+		return errInvalidCheckpointProof
+	}
+	return nil
+}
+
+// CheckpointProofSigners returns the masternodes of an epoch flagged in the
+// signers of a checkpoint proof.
+func CheckpointProofSigners(masternodes []common.Address, proof *CheckpointProof) []common.Address {
+	var signers []common.Address
+	for i, masternode := range masternodes {
+		if i/8 < len(proof.Signers) && proof.Signers[i/8]&(1<<uint(i%8)) != 0 {
+			signers = append(signers, masternode)
+		}
+	}
+	return signers
+}
+
+// emptyCheckpointProof returns the proof of an epoch of n masternodes signed by
+// none of them.
+func emptyCheckpointProof(n int) *CheckpointProof {
+	return &CheckpointProof{
+		Signers:   make([]byte, (n+7)/8),
+		Signature: make([]byte, bls.SignatureLength),
+	}
+}
+
+// gapHeader returns the gap block of the epoch closed by a checkpoint, walking
+// back from its parent so as to follow the chain the checkpoint extends.
+func (c *Posv) gapHeader(chain consensus.ChainReader, checkpoint *types.Header) *types.Header {
+	header := checkpoint
+	for i := uint64(0); i < c.config.Gap && header != nil; i++ {
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header
+}
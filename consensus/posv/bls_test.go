@@ -0,0 +1,82 @@
+package posv
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that the checkpoint proofs aggregate the signatures of the gap block
+// recorded by the masternodes, and verify against their keys only.
+func TestCheckpointProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		registry    = common.HexToAddress(common.BLSRegistry)
+		masternodes = make([]common.Address, 10)
+		gap         = uint64(895)
+		gapHash     = common.HexToHash("0x895")
+	)
+	for i := range masternodes {
+		masternodes[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+		if i%3 == 0 {
+			continue // Some masternodes haven't registered any key
+		}
+		key, err := bls.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		pk := key.PublicKey().Marshal()
+		for word := uint64(0); word < 4; word++ {
+			statedb.SetState(registry, state.BLSKeySlot(masternodes[i], word), common.BytesToHash(pk[word*32:(word+1)*32]))
+		}
+		// Some masternodes signed the gap block of an older epoch
+		number := gap
+		if i == 5 {
+			number = gap - 900
+		}
+		sig := key.Sign(gapHash.Bytes()).Marshal()
+		statedb.SetState(registry, state.BLSSignatureSlot(masternodes[i], 0), common.BigToHash(new(big.Int).SetUint64(number)))
+		statedb.SetState(registry, state.BLSSignatureSlot(masternodes[i], 1), common.BytesToHash(sig[:32]))
+		statedb.SetState(registry, state.BLSSignatureSlot(masternodes[i], 2), common.BytesToHash(sig[32:]))
+	}
+	proof := BuildCheckpointProof(statedb, masternodes, gap)
+	if want := []byte{0x96, 0x01}; !bytes.Equal(proof.Signers, want) {
+		t.Fatalf("signers mismatch: have %x, want %x", []byte(proof.Signers), want)
+	}
+	if signers := CheckpointProofSigners(masternodes, proof); len(signers) != 5 {
+		t.Errorf("signer count mismatch: have %d, want 5", len(signers))
+	}
+	if err := VerifyCheckpointProof(statedb, masternodes, gapHash, proof); err != nil {
+		t.Fatalf("failed to verify proof: %v", err)
+	}
+	if err := VerifyCheckpointProof(statedb, masternodes, common.HexToHash("0x896"), proof); err != errInvalidCheckpointProof {
+		t.Errorf("wrong gap block: error mismatch: have %v, want %v", err, errInvalidCheckpointProof)
+	}
+	// Flagging more, less, unknown or unregistered signers must be rejected
+	for i, signers := range [][]byte{{0x97, 0x01}, {0xb6, 0x01}, {0x96, 0x00}, {0x96, 0x05}, {0x96}, {0x96, 0x01, 0x00}} {
+		tampered := &CheckpointProof{Signers: signers, Signature: proof.Signature}
+		if err := VerifyCheckpointProof(statedb, masternodes, gapHash, tampered); err != errInvalidCheckpointProof {
+			t.Errorf("tampered %d: error mismatch: have %v, want %v", i, err, errInvalidCheckpointProof)
+		}
+	}
+	// An epoch nobody signed proves an empty aggregate
+	empty := BuildCheckpointProof(statedb, masternodes, gap+900)
+	if !bytes.Equal(empty.Signers, []byte{0, 0}) || !bytes.Equal(empty.Signature, make([]byte, bls.SignatureLength)) {
+		t.Errorf("empty proof mismatch: have %+v", empty)
+	}
+	if err := VerifyCheckpointProof(statedb, masternodes, gapHash, empty); err != nil {
+		t.Errorf("failed to verify empty proof: %v", err)
+	}
+	empty.Signature = proof.Signature
+	if err := VerifyCheckpointProof(statedb, masternodes, gapHash, empty); err != errInvalidCheckpointProof {
+		t.Errorf("signed empty proof: error mismatch: have %v, want %v", err, errInvalidCheckpointProof)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posv
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// errUnauthorizedSigner is returned when signing an enode record on a node
+	// not authorized to seal blocks.
+	errUnauthorizedSigner = errors.New("no authorized signer")
+
+	// errInvalidRecordSignature is returned if an enode record signature is not
+	// 65 bytes long.
+	errInvalidRecordSignature = errors.New("invalid enode record signature")
+)
+
+// EnodeRecord is the announcement by a masternode of the enode URL it can be
+// reached at, signed with its sealing key so that the nodes of the masternode
+// set can find and trust each other.
+type EnodeRecord struct {
+	Enode     string // URL of the node of the masternode
+	Number    uint64 // Block number the record was signed at, newer records replace older ones
+	Signature []byte // Signature of the masternode over the enode URL and the block number
+}
+
+// Hash returns the hash signed by the masternode.
+func (r *EnodeRecord) Hash() (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+	rlp.Encode(hasher, []interface{}{r.Enode, r.Number})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// Signer recovers the address of the masternode which signed the record.
+func (r *EnodeRecord) Signer() (common.Address, error) {
+	if len(r.Signature) != extraSeal {
+		return common.Address{}, errInvalidRecordSignature
+	}
+	pubkey, err := crypto.Ecrecover(r.Hash().Bytes(), r.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// SignEnodeRecord signs a record announcing the enode URL of this node with
+// the key the engine is authorized to seal blocks with.
+func (c *Posv) SignEnodeRecord(enode string, number uint64) (*EnodeRecord, error) {
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errUnauthorizedSigner
+	}
+	record := &EnodeRecord{Enode: enode, Number: number}
+	sig, err := signFn(accounts.Account{Address: signer}, record.Hash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	record.Signature = sig
+	return record, nil
+}
@@ -0,0 +1,37 @@
+package posv
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestEnodeRecord(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	masternode := crypto.PubkeyToAddress(key.PublicKey)
+
+	engine := &Posv{}
+	if _, err := engine.SignEnodeRecord("enode://a@127.0.0.1:30303", 1); err != errUnauthorizedSigner {
+		t.Fatalf("unauthorized signing error mismatch: have %v, want %v", err, errUnauthorizedSigner)
+	}
+	engine.Authorize(masternode, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	record, err := engine.SignEnodeRecord("enode://a@127.0.0.1:30303", 1)
+	if err != nil {
+		t.Fatalf("failed to sign enode record: %v", err)
+	}
+	if signer, err := record.Signer(); err != nil || signer != masternode {
+		t.Fatalf("signer mismatch: have %x (%v), want %x", signer, err, masternode)
+	}
+	// Tampered records are not attributed to the masternode
+	record.Enode = "enode://b@127.0.0.1:30303"
+	if signer, _ := record.Signer(); signer == masternode {
+		t.Fatalf("tampered record attributed to the masternode")
+	}
+	record.Signature = record.Signature[:10]
+	if _, err := record.Signer(); err != errInvalidRecordSignature {
+		t.Fatalf("short signature error mismatch: have %v, want %v", err, errInvalidRecordSignature)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posv
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Versions of the layout of the extra-data of PoSV headers. The version is
+// implied by the chain rules in force at the header, the checkpoints of the
+// layouts carrying more than the masternodes also tag it in the last byte of
+// their vanity for the readers without the chain rules.
+const (
+	// ExtraVersion1 is the layout of the genesis: a 32 byte vanity, followed on
+	// checkpoints by the masternodes of the next epoch, and the 65 byte seal.
+	ExtraVersion1 = 1
+
+	// ExtraVersion2 adds to the checkpoints the proof of the BLS signatures of
+	// the gap block of the epoch by its masternodes: the masternodes of the
+	// next epoch are followed by the 64 byte aggregate signature, the bitmap
+	// of the signers and the length of the bitmap in one byte.
+	ExtraVersion2 = 2
+)
+
+var (
+	// errUnknownExtraVersion is returned when encoding extra-data in a layout
+	// that isn't known.
+	errUnknownExtraVersion = errors.New("unknown extra-data version")
+
+	// errInvalidVanity is returned when encoding a vanity longer than 32 bytes,
+	// or whose version tag doesn't match the layout.
+	errInvalidVanity = errors.New("extra-data vanity longer than 32 bytes")
+
+	// errInvalidSeal is returned when encoding a seal that is neither empty nor
+	// a 65 byte secp256k1 signature.
+	errInvalidSeal = errors.New("extra-data seal not 65 bytes")
+
+	// errMissingCheckpointProof is returned if a checkpoint of a layout carrying
+	// the BLS signatures has none, or another layout has one.
+	errMissingCheckpointProof = errors.New("checkpoint BLS proof missing or unexpected")
+)
+
+// ExtraData is the decoded extra-data of a PoSV header.
+type ExtraData struct {
+	Version     uint8            `json:"version"`
+	Checkpoint  bool             `json:"checkpoint"`
+	Vanity      hexutil.Bytes    `json:"vanity"`
+	Masternodes []common.Address `json:"masternodes"`     // Masternodes of the next epoch, checkpoints only
+	Proof       *CheckpointProof `json:"proof,omitempty"` // BLS signatures of the epoch, checkpoints from version 2
+	Seal        hexutil.Bytes    `json:"seal"`            // Signature of the signer, zero until sealed
+}
+
+// CheckpointProof is the aggregate of the BLS signatures of the gap block of an
+// epoch by its masternodes, carried by the checkpoint closing the epoch.
+type CheckpointProof struct {
+	Signers   hexutil.Bytes `json:"signers"`   // Bitmap of the signers among the masternodes of the epoch, least significant bit first
+	Signature hexutil.Bytes `json:"signature"` // Aggregate of their signatures, zero if none signed
+}
+
+// ExtraVersion returns the layout of the extra-data of the header with the
+// given number.
+func ExtraVersion(config *params.ChainConfig, num *big.Int) uint8 {
+	if config.IsBLS(num) {
+		return ExtraVersion2
+	}
+	return ExtraVersion1
+}
+
+// DecodeExtraData decodes and validates the extra-data of a header, checkpoint
+// telling whether the header closes an epoch and so lists the masternodes. The
+// layout of the checkpoints is recognized by its tag, the chain rules are to
+// be checked with the layout of the version in force.
+func DecodeExtraData(extra []byte, checkpoint bool) (*ExtraData, error) {
+	if checkpoint && len(extra) >= extraVanity && extra[extraVanity-1] == ExtraVersion2 {
+		if data, err := decodeExtraData(extra, checkpoint, ExtraVersion2); err == nil {
+			return data, nil
+		}
+	}
+	return decodeExtraData(extra, checkpoint, ExtraVersion1)
+}
+
+// DecodeHeaderExtra decodes and validates the extra-data of a header, given the
+// length of the epochs of the chain.
+func DecodeHeaderExtra(header *types.Header, epoch uint64) (*ExtraData, error) {
+	return DecodeExtraData(header.Extra, epoch != 0 && header.Number.Uint64()%epoch == 0)
+}
+
+// decodeExtraData decodes and validates extra-data in the layout of a version.
+func decodeExtraData(extra []byte, checkpoint bool, version uint8) (*ExtraData, error) {
+	if len(extra) < extraVanity {
+		return nil, errMissingVanity
+	}
+	if len(extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	var (
+		data = &ExtraData{
+			Version:    version,
+			Checkpoint: checkpoint,
+			Vanity:     common.CopyBytes(extra[:extraVanity]),
+			Seal:       common.CopyBytes(extra[len(extra)-extraSeal:]),
+		}
+		body = extra[extraVanity : len(extra)-extraSeal]
+	)
+	switch version {
+	case ExtraVersion1:
+	case ExtraVersion2:
+		if checkpoint {
+			if extra[extraVanity-1] != ExtraVersion2 {
+				return nil, errInvalidVanity
+			}
+			if len(body) == 0 {
+				return nil, errMissingCheckpointProof
+			}
+			bitmap := int(body[len(body)-1])
+			if len(body) < 1+bitmap+bls.SignatureLength {
+				return nil, errMissingCheckpointProof
+			}
+			proof := body[len(body)-1-bitmap-bls.SignatureLength : len(body)-1]
+			data.Proof = &CheckpointProof{
+				Signature: common.CopyBytes(proof[:bls.SignatureLength]),
+				Signers:   common.CopyBytes(proof[bls.SignatureLength:]),
+			}
+			body = body[:len(body)-1-bitmap-bls.SignatureLength]
+		}
+	default:
+		return nil, fmt.Errorf("%v: %d", errUnknownExtraVersion, version)
+	}
+	if !checkpoint && len(body) != 0 {
+		return nil, errExtraSigners
+	}
+	if len(body)%common.AddressLength != 0 {
+		return nil, errInvalidCheckpointSigners
+	}
+	data.Masternodes = make([]common.Address, len(body)/common.AddressLength)
+	for i := range data.Masternodes {
+		copy(data.Masternodes[i][:], body[i*common.AddressLength:])
+	}
+	if err := validateMasternodes(data.Masternodes); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Encode validates the extra-data and encodes it in the layout of its version.
+// A vanity shorter than 32 bytes is right padded with zeroes, an empty seal is
+// left for the signer to fill in.
+func (e *ExtraData) Encode() ([]byte, error) {
+	if e.Version != ExtraVersion1 && e.Version != ExtraVersion2 {
+		return nil, fmt.Errorf("%v: %d", errUnknownExtraVersion, e.Version)
+	}
+	if len(e.Vanity) > extraVanity {
+		return nil, errInvalidVanity
+	}
+	if len(e.Seal) != 0 && len(e.Seal) != extraSeal {
+		return nil, errInvalidSeal
+	}
+	if !e.Checkpoint && len(e.Masternodes) != 0 {
+		return nil, errExtraSigners
+	}
+	tagged := e.Version == ExtraVersion2 && e.Checkpoint
+	if tagged != (e.Proof != nil) {
+		return nil, errMissingCheckpointProof
+	}
+	if err := validateMasternodes(e.Masternodes); err != nil {
+		return nil, err
+	}
+	extra := make([]byte, extraVanity, extraVanity+len(e.Masternodes)*common.AddressLength+extraSeal)
+	copy(extra, e.Vanity)
+	for _, masternode := range e.Masternodes {
+		extra = append(extra, masternode[:]...)
+	}
+	if tagged {
+		if len(e.Vanity) == extraVanity && e.Vanity[extraVanity-1] != 0 && e.Vanity[extraVanity-1] != ExtraVersion2 {
+			return nil, errInvalidVanity
+		}
+		if len(e.Proof.Signature) != bls.SignatureLength || len(e.Proof.Signers) > 255 {
+			return nil, errMissingCheckpointProof
+		}
+		extra[extraVanity-1] = ExtraVersion2
+		extra = append(extra, e.Proof.Signature...)
+		extra = append(extra, e.Proof.Signers...)
+		extra = append(extra, byte(len(e.Proof.Signers)))
+	}
+	seal := make([]byte, extraSeal)
+	copy(seal, e.Seal)
+	return append(extra, seal...), nil
+}
+
+// validateMasternodes checks that a masternode list has neither empty nor
+// repeated addresses.
+func validateMasternodes(masternodes []common.Address) error {
+	seen := make(map[common.Address]bool, len(masternodes))
+	for _, masternode := range masternodes {
+		if masternode == (common.Address{}) || seen[masternode] {
+			return errInvalidCheckpointSigners
+		}
+		seen[masternode] = true
+	}
+	return nil
+}
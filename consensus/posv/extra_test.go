@@ -0,0 +1,160 @@
+package posv
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+)
+
+// Tests that the extra-data codec round trips the layout of the checkpoint and
+// regular headers.
+func TestExtraDataRoundTrip(t *testing.T) {
+	seal := bytes.Repeat([]byte{0x01}, extraSeal)
+	tests := []*ExtraData{
+		{Version: ExtraVersion1, Vanity: make([]byte, extraVanity), Masternodes: []common.Address{}, Seal: seal},
+		{Version: ExtraVersion1, Checkpoint: true, Vanity: bytes.Repeat([]byte{0x02}, extraVanity), Masternodes: []common.Address{}, Seal: seal},
+		{Version: ExtraVersion1, Checkpoint: true, Vanity: make([]byte, extraVanity), Masternodes: []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}, Seal: seal},
+	}
+	for i, tt := range tests {
+		enc, err := tt.Encode()
+		if err != nil {
+			t.Fatalf("test %d: failed to encode: %v", i, err)
+		}
+		if want := extraVanity + len(tt.Masternodes)*common.AddressLength + extraSeal; len(enc) != want {
+			t.Errorf("test %d: length mismatch: have %d, want %d", i, len(enc), want)
+		}
+		dec, err := DecodeExtraData(enc, tt.Checkpoint)
+		if err != nil {
+			t.Fatalf("test %d: failed to decode: %v", i, err)
+		}
+		if !reflect.DeepEqual(dec, tt) {
+			t.Errorf("test %d: extra-data mismatch: have %+v, want %+v", i, dec, tt)
+		}
+	}
+}
+
+// Tests that a short vanity is padded and an empty seal left zero for the signer.
+func TestExtraDataEncodePadding(t *testing.T) {
+	enc, err := (&ExtraData{Version: ExtraVersion1, Vanity: []byte("tomo")}).Encode()
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	want := append(append([]byte("tomo"), make([]byte, extraVanity-4)...), make([]byte, extraSeal)...)
+	if !bytes.Equal(enc, want) {
+		t.Errorf("encoding mismatch: have %x, want %x", enc, want)
+	}
+}
+
+// Tests that malformed extra-data is rejected by the codec.
+func TestExtraDataValidation(t *testing.T) {
+	var (
+		vanity = make([]byte, extraVanity)
+		seal   = make([]byte, extraSeal)
+		node   = common.HexToAddress("0x01")
+	)
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	decodes := []struct {
+		extra      []byte
+		checkpoint bool
+		err        error
+	}{
+		{vanity[:31], false, errMissingVanity},
+		{join(vanity, seal[:64]), false, errMissingSignature},
+		{join(vanity, node[:], seal), false, errExtraSigners},
+		{join(vanity, node[:10], seal), true, errInvalidCheckpointSigners},
+		{join(vanity, node[:], node[:], seal), true, errInvalidCheckpointSigners},
+		{join(vanity, make([]byte, common.AddressLength), seal), true, errInvalidCheckpointSigners},
+		{join(vanity, node[:], seal), true, nil},
+	}
+	for i, tt := range decodes {
+		if _, err := DecodeExtraData(tt.extra, tt.checkpoint); err != tt.err {
+			t.Errorf("decode %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	encodes := []struct {
+		extra *ExtraData
+		err   bool
+	}{
+		{&ExtraData{Version: 3}, true},
+		{&ExtraData{Version: ExtraVersion2, Checkpoint: true}, true},
+		{&ExtraData{Version: ExtraVersion1, Checkpoint: true, Proof: &CheckpointProof{Signature: make([]byte, bls.SignatureLength)}}, true},
+		{&ExtraData{Version: ExtraVersion2, Checkpoint: true, Proof: &CheckpointProof{Signature: make([]byte, 63)}}, true},
+		{&ExtraData{Version: ExtraVersion2, Checkpoint: true, Vanity: bytes.Repeat([]byte{0x01}, extraVanity), Proof: &CheckpointProof{Signature: make([]byte, bls.SignatureLength)}}, true},
+		{&ExtraData{Version: ExtraVersion2, Checkpoint: true, Masternodes: []common.Address{node}, Proof: &CheckpointProof{Signature: make([]byte, bls.SignatureLength), Signers: []byte{0x01}}}, false},
+		{&ExtraData{Version: ExtraVersion1, Vanity: make([]byte, extraVanity+1)}, true},
+		{&ExtraData{Version: ExtraVersion1, Seal: seal[:64]}, true},
+		{&ExtraData{Version: ExtraVersion1, Masternodes: []common.Address{node}}, true},
+		{&ExtraData{Version: ExtraVersion1, Checkpoint: true, Masternodes: []common.Address{node, node}}, true},
+		{&ExtraData{Version: ExtraVersion1, Checkpoint: true, Masternodes: []common.Address{node}}, false},
+	}
+	for i, tt := range encodes {
+		if _, err := tt.extra.Encode(); (err != nil) != tt.err {
+			t.Errorf("encode %d: error mismatch: have %v, want error %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the layouts carrying the BLS proofs are told apart by the version
+// rules, and by their tag without them.
+func TestExtraDataVersion2(t *testing.T) {
+	var (
+		vanity = make([]byte, extraVanity)
+		seal   = make([]byte, extraSeal)
+		node   = common.HexToAddress("0x01")
+		sig    = bytes.Repeat([]byte{0xff}, bls.SignatureLength)
+	)
+	tagged := common.CopyBytes(vanity)
+	tagged[extraVanity-1] = ExtraVersion2
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	// A tagged checkpoint is decoded with its proof, by the rules or the tag
+	extra := join(tagged, node[:], sig, []byte{0x01}, []byte{0x01}, seal)
+	for _, decode := range []func() (*ExtraData, error){
+		func() (*ExtraData, error) { return DecodeExtraData(extra, true) },
+		func() (*ExtraData, error) { return decodeExtraData(extra, true, ExtraVersion2) },
+	} {
+		data, err := decode()
+		if err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+		if data.Version != ExtraVersion2 || len(data.Masternodes) != 1 || data.Masternodes[0] != node {
+			t.Errorf("checkpoint mismatch: have %+v", data)
+		}
+		if data.Proof == nil || !bytes.Equal(data.Proof.Signature, sig) || !bytes.Equal(data.Proof.Signers, []byte{0x01}) {
+			t.Errorf("proof mismatch: have %+v", data.Proof)
+		}
+	}
+	// An untagged checkpoint falls back to the first layout, unless the rules
+	// require the second
+	extra = join(vanity, node[:], seal)
+	if data, err := DecodeExtraData(extra, true); err != nil || data.Version != ExtraVersion1 || data.Proof != nil {
+		t.Errorf("untagged checkpoint: have %+v, %v", data, err)
+	}
+	if _, err := decodeExtraData(extra, true, ExtraVersion2); err != errInvalidVanity {
+		t.Errorf("untagged checkpoint: error mismatch: have %v, want %v", err, errInvalidVanity)
+	}
+	if _, err := decodeExtraData(join(tagged, sig[:10], []byte{0x00}, seal), true, ExtraVersion2); err != errMissingCheckpointProof {
+		t.Errorf("short proof: error mismatch: have %v, want %v", err, errMissingCheckpointProof)
+	}
+	// Other headers keep the first layout
+	if data, err := decodeExtraData(join(vanity, seal), false, ExtraVersion2); err != nil || data.Proof != nil {
+		t.Errorf("non-checkpoint: have %+v, %v", data, err)
+	}
+	// The encoding round trips
+	want := &ExtraData{Version: ExtraVersion2, Checkpoint: true, Vanity: tagged, Masternodes: []common.Address{node}, Proof: &CheckpointProof{Signers: []byte{0x01, 0x00}, Signature: sig}, Seal: seal}
+	enc, err := want.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	have, err := DecodeExtraData(enc, true)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("extra-data mismatch: have %+v, want %+v", have, want)
+	}
+}
@@ -0,0 +1,108 @@
+package posv
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// verifyLightSeal checks in light mode that a header is sealed in turn by a
+// masternode of the epoch set in force at its parent, and validated by the
+// masternode assigned to its creator. Light chains hold no state, so the set
+// is tracked from the signer lists in the extra-data of the checkpoint headers
+// instead of the snapshots and the validator contract. The signer list of a
+// checkpoint is trusted once the checkpoint is sealed by the previous set.
+func (c *Posv) verifyLightSeal(chain consensus.ChainReader, header *types.Header, parent *types.Header, parents []*types.Header, fullVerify bool) error {
+	number := header.Number.Uint64()
+
+	checkpoint, err := c.epochCheckpoint(chain, parent, parents)
+	if err != nil {
+		return err
+	}
+	masternodes := GetMasternodesFromCheckpointHeader(checkpoint)
+
+	// The creator must be in the set, and must not seal two blocks in a row
+	creator, err := ecrecover(header, c.signatures)
+	if err != nil {
+		return err
+	}
+	curIndex := position(masternodes, creator)
+	if curIndex < 0 {
+		log.Debug("Unauthorized creator found", "number", number, "creator", creator, "checkpoint", checkpoint.Number)
+		return errUnauthorized
+	}
+	preIndex := -1
+	if parent.Number.Uint64() != 0 {
+		pre, err := ecrecover(parent, c.signatures)
+		if err != nil {
+			return err
+		}
+		preIndex = position(masternodes, pre)
+	}
+	if len(masternodes) > 1 && preIndex == curIndex && number%c.config.Epoch != 0 {
+		return errUnauthorized
+	}
+	// The difficulty tells how many masternodes were skipped, it must match the turn of the creator
+	if header.Difficulty.Int64() != int64(len(masternodes)-Hop(len(masternodes), preIndex, curIndex)) {
+		return errInvalidDifficulty
+	}
+	// Header must contain the signature of the validator assigned to the creator from epoch 2nd
+	if number > c.config.Epoch && fullVerify {
+		if number%c.config.Epoch == 0 {
+			checkpoint = header
+		}
+		validator, err := c.RecoverValidator(header)
+		if err != nil {
+			return err
+		}
+		m1m2, err := GetM1M2FromCheckpointHeader(checkpoint, header, chain.Config())
+		if err != nil {
+			return err
+		}
+		if validator != m1m2[creator] {
+			log.Debug("Bad block detected. Header contains wrong pair of creator-validator", "creator", creator, "assigned validator", m1m2[creator], "wrong validator", validator)
+			return errFailedDoubleValidation
+		}
+	}
+	return nil
+}
+
+// epochCheckpoint returns the checkpoint header listing the masternodes sealing
+// the children of a header: the header itself if it's a checkpoint, the one of
+// its epoch otherwise. The caller may pass in a batch of parents (ascending
+// order) which aren't yet part of the local chain.
+func (c *Posv) epochCheckpoint(chain consensus.ChainReader, header *types.Header, parents []*types.Header) (*types.Header, error) {
+	var (
+		checkpoint *types.Header
+		skipped    []common.Hash
+	)
+	for checkpoint == nil {
+		if header.Number.Uint64()%c.config.Epoch == 0 {
+			checkpoint = header
+			break
+		}
+		if cp, ok := c.epochCheckpoints.Get(header.Hash()); ok {
+			checkpoint = cp.(*types.Header)
+			break
+		}
+		skipped = append(skipped, header.Hash())
+
+		number, hash := header.Number.Uint64()-1, header.ParentHash
+		for len(parents) > 0 && parents[len(parents)-1].Number.Uint64() > number {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) > 0 && parents[len(parents)-1].Hash() == hash {
+			header = parents[len(parents)-1]
+		} else {
+			header = chain.GetHeader(hash, number)
+		}
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+	}
+	for _, hash := range skipped {
+		c.epochCheckpoints.Add(hash, checkpoint)
+	}
+	return checkpoint, nil
+}
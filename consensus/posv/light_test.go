@@ -0,0 +1,122 @@
+package posv
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testHeaderReader is a consensus.ChainReader holding the genesis header only.
+type testHeaderReader struct {
+	config  *params.ChainConfig
+	genesis *types.Header
+}
+
+func (r *testHeaderReader) Config() *params.ChainConfig  { return r.config }
+func (r *testHeaderReader) CurrentHeader() *types.Header { return r.genesis }
+func (r *testHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if hash == r.genesis.Hash() {
+		return r.genesis
+	}
+	return nil
+}
+func (r *testHeaderReader) GetHeaderByNumber(number uint64) *types.Header {
+	if number == 0 {
+		return r.genesis
+	}
+	return nil
+}
+func (r *testHeaderReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.GetHeader(hash, 0)
+}
+func (r *testHeaderReader) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+func TestLightModeVerifyHeaders(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	var signers []common.Address
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		signers = append(signers, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	config := &params.ChainConfig{ChainId: big.NewInt(1), Posv: &params.PosvConfig{Period: 2, Epoch: 5}}
+	extra := func(list []common.Address) []byte {
+		extra := make([]byte, extraVanity)
+		for _, signer := range list {
+			extra = append(extra, signer.Bytes()...)
+		}
+		return append(extra, make([]byte, extraSeal)...)
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Difficulty: big.NewInt(1), Extra: extra(signers), UncleHash: uncleHash}
+	reader := &testHeaderReader{config: config, genesis: genesis}
+
+	// makeChain seals a header chain with the given creator indexes and difficulties,
+	// the checkpoint at block 5 drops the last signer from the set
+	makeChain := func(creators []int, difficulties []int64) []*types.Header {
+		var headers []*types.Header
+		parent := genesis
+		for i, creator := range creators {
+			header := &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     big.NewInt(int64(i + 1)),
+				Time:       big.NewInt(int64(2 * (i + 1))),
+				Difficulty: big.NewInt(difficulties[i]),
+				Extra:      extra(nil),
+				UncleHash:  uncleHash,
+			}
+			if (i+1)%5 == 0 {
+				header.Extra = extra(signers[:2])
+			}
+			sig, _ := crypto.Sign(sigHash(header).Bytes(), keys[creator])
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+			headers = append(headers, header)
+			parent = header
+		}
+		return headers
+	}
+	verify := func(headers []*types.Header) error {
+		db, _ := ethdb.NewMemDatabase()
+		engine := New(config.Posv, db)
+		engine.LightMode = true
+
+		_, results := engine.VerifyHeaders(reader, headers, make([]bool, len(headers)))
+		for range headers {
+			if err := <-results; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tests := []struct {
+		creators     []int
+		difficulties []int64
+		err          error
+	}{
+		// In turn creators, then the two signers of the new set
+		{[]int{0, 1, 2, 0, 1, 0, 1}, []int64{3, 3, 3, 3, 3, 2, 2}, nil},
+		// Creator skipping a masternode
+		{[]int{0, 2}, []int64{3, 2}, nil},
+		{[]int{0, 2}, []int64{3, 3}, errInvalidDifficulty},
+		// Creator sealing two blocks in a row
+		{[]int{0, 0}, []int64{3, 1}, errUnauthorized},
+		// Creator dropped from the set at the checkpoint
+		{[]int{0, 1, 2, 0, 1, 2}, []int64{3, 3, 3, 3, 3, 1}, errUnauthorized},
+	}
+	for i, tt := range tests {
+		if err := verify(makeChain(tt.creators, tt.difficulties)); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Unknown signer
+	key, _ := crypto.GenerateKey()
+	keys = append(keys, key)
+	if err := verify(makeChain([]int{3}, []int64{3})); err != errUnauthorized {
+		t.Errorf("unknown signer: error mismatch: have %v, want %v", err, errUnauthorized)
+	}
+}
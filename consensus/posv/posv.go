@@ -117,6 +117,10 @@ var (
 
 	errInvalidCheckpointPenalties = errors.New("invalid penalty list on checkpoint block")
 
+	// errInvalidCheckpointProof is returned if the BLS proof of a checkpoint block
+	// doesn't cover its masternodes or doesn't verify against their keys.
+	errInvalidCheckpointProof = errors.New("invalid BLS proof on checkpoint block")
+
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	errInvalidMixDigest = errors.New("non-zero mix digest")
 
@@ -162,7 +166,7 @@ var (
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	fields := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -178,7 +182,12 @@ func sigHash(header *types.Header) (hash common.Hash) {
 		header.Extra[:len(header.Extra)-65], // Yes, this will panic if extra is too short
 		header.MixDigest,
 		header.Nonce,
-	})
+	}
+	// The trade root is sealed from its fork on, older seals stay valid
+	if header.TradeRoot != (common.Hash{}) {
+		fields = append(fields, header.TradeRoot)
+	}
+	rlp.Encode(hasher, fields)
 	hasher.Sum(hash[:0])
 	return hash
 }
@@ -222,6 +231,7 @@ type Posv struct {
 	signatures          *lru.ARCCache // Signatures of recent blocks to speed up mining
 	validatorSignatures *lru.ARCCache // Signatures of recent blocks to speed up mining
 	verifiedHeaders     *lru.ARCCache
+	epochCheckpoints    *lru.ARCCache           // Checkpoint headers of the epochs of recent blocks, in light mode
 	proposals           map[common.Address]bool // Current list of proposals we are pushing
 
 	signer common.Address  // Ethereum address of the signing key
@@ -236,6 +246,10 @@ type Posv struct {
 	HookVerifyMNs         func(header *types.Header, signers []common.Address) error
 	GetTomoXService       func() *tomox.TomoX
 	HookGetSignersFromContract func(blockHash common.Hash) ([]common.Address, error)
+	HookCheckpointState        func(root common.Hash) (*state.StateDB, error) // State the BLS checkpoint signatures are aggregated from
+
+	VerifyRewards bool // Recompute the rewards at each checkpoint and report mismatches
+	LightMode     bool // Verify the headers against the checkpoint signer lists only, for chains holding no state
 }
 
 // New creates a PoSV proof-of-stake-voting consensus engine with the initial
@@ -252,6 +266,7 @@ func New(config *params.PosvConfig, db ethdb.Database) *Posv {
 	signatures, _ := lru.NewARC(inmemorySnapshots)
 	validatorSignatures, _ := lru.NewARC(inmemorySnapshots)
 	verifiedHeaders, _ := lru.NewARC(inmemorySnapshots)
+	epochCheckpoints, _ := lru.NewARC(inmemorySnapshots)
 	return &Posv{
 		config:              &conf,
 		db:                  db,
@@ -260,6 +275,7 @@ func New(config *params.PosvConfig, db ethdb.Database) *Posv {
 		signatures:          signatures,
 		verifiedHeaders:     verifiedHeaders,
 		validatorSignatures: validatorSignatures,
+		epochCheckpoints:    epochCheckpoints,
 		proposals:           make(map[common.Address]bool),
 	}
 }
@@ -345,20 +361,10 @@ func (c *Posv) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 	if checkpoint && !bytes.Equal(header.Nonce[:], nonceDropVote) {
 		return errInvalidCheckpointVote
 	}
-	// Check that the extra-data contains both the vanity and signature
-	if len(header.Extra) < extraVanity {
-		return errMissingVanity
-	}
-	if len(header.Extra) < extraVanity+extraSeal {
-		return errMissingSignature
-	}
-	// Ensure that the extra-data contains a signer list on checkpoint, but none otherwise
-	signersBytes := len(header.Extra) - extraVanity - extraSeal
-	if !checkpoint && signersBytes != 0 {
-		return errExtraSigners
-	}
-	if checkpoint && signersBytes%common.AddressLength != 0 {
-		return errInvalidCheckpointSigners
+	// Check that the extra-data contains both the vanity and signature, and a
+	// signer list on checkpoint but none otherwise, in the layout of the rules
+	if _, err := decodeExtraData(header.Extra, checkpoint, ExtraVersion(chain.Config(), header.Number)); err != nil {
+		return err
 	}
 	// Ensure that the mix digest is zero as we don't have fork protection currently
 	if header.MixDigest != (common.Hash{}) {
@@ -399,6 +405,9 @@ func (c *Posv) verifyCascadingFields(chain consensus.ChainReader, header *types.
 	if parent.Time.Uint64()+c.config.Period > header.Time.Uint64() {
 		return ErrInvalidTimestamp
 	}
+	if c.LightMode && !common.IsTestnet {
+		return c.verifyLightSeal(chain, header, parent, parents, fullVerify)
+	}
 
 	if number%c.config.Epoch != 0 {
 		return c.verifySeal(chain, header, parents, fullVerify)
@@ -462,13 +471,12 @@ func (c *Posv) checkSignersOnCheckpoint(chain consensus.ChainReader, header *typ
 		}
 	}
 	signers = common.RemoveItemFromArray(signers, penPenalties)
-	for i := 1; i <= common.LimitPenaltyEpoch; i++ {
+	for i := 1; i <= c.config.PenaltyEpochs(); i++ {
 		if number > uint64(i)*c.config.Epoch {
 			signers = RemovePenaltiesFromBlock(chain, signers, number-uint64(i)*c.config.Epoch)
 		}
 	}
-	extraSuffix := len(header.Extra) - extraSeal
-	masternodesFromCheckpointHeader := common.ExtractAddressFromBytes(header.Extra[extraVanity:extraSuffix])
+	masternodesFromCheckpointHeader := GetMasternodesFromCheckpointHeader(header)
 	validSigners := compareSignersLists(masternodesFromCheckpointHeader, signers)
 	if !validSigners {
 		log.Error("Masternodes lists are different in checkpoint header and snapshot", "number", number, "masternodes_from_checkpoint_header", masternodesFromCheckpointHeader, "masternodes_in_snapshot", signers, "penList", penPenalties)
@@ -592,6 +600,28 @@ func (c *Posv) YourTurn(chain consensus.ChainReader, parent *types.Header, signe
 	return len(masternodes), preIndex, curIndex, false, nil
 }
 
+// IsMinorityFork reports whether the chain ending at header looks like a minority
+// fork, that is fewer than half of the masternodes created one of the last
+// len(masternodes) blocks. A node partitioned from the network keeps sealing
+// with the few masternodes it still sees, so a low participation gives it away.
+func (c *Posv) IsMinorityFork(chain consensus.ChainReader, header *types.Header) (bool, error) {
+	masternodes := c.GetMasternodes(chain, header)
+	window := uint64(len(masternodes))
+	if window < 2 || header.Number.Uint64() < window {
+		return false, nil
+	}
+	creators := make(map[common.Address]struct{})
+	for i := uint64(0); i < window && header != nil; i++ {
+		creator, err := c.Author(header)
+		if err != nil {
+			return false, err
+		}
+		creators[creator] = struct{}{}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return 2*len(creators) < len(masternodes), nil
+}
+
 // snapshot retrieves the authorization snapshot at a given point in time.
 func (c *Posv) snapshot(chain consensus.ChainReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
@@ -620,11 +650,7 @@ func (c *Posv) snapshot(chain consensus.ChainReader, number uint64, hash common.
 			if err := c.VerifyHeader(chain, genesis, true); err != nil {
 				return nil, err
 			}
-			signers := make([]common.Address, (len(genesis.Extra)-extraVanity-extraSeal)/common.AddressLength)
-			for i := 0; i < len(signers); i++ {
-				copy(signers[i][:], genesis.Extra[extraVanity+i*common.AddressLength:])
-			}
-			snap = newSnapshot(c.config, c.signatures, 0, genesis.Hash(), signers)
+			snap = newSnapshot(c.config, c.signatures, 0, genesis.Hash(), GetMasternodesFromCheckpointHeader(genesis))
 			if err := snap.store(c.db); err != nil {
 				return nil, err
 			}
@@ -847,10 +873,10 @@ func (c *Posv) Prepare(chain consensus.ChainReader, header *types.Header) error
 	header.Difficulty = c.calcDifficulty(chain, parent, c.signer)
 	log.Debug("CalcDifficulty ", "number", header.Number, "difficulty", header.Difficulty)
 	// Ensure the extra data has all it's components
-	if len(header.Extra) < extraVanity {
-		header.Extra = append(header.Extra, bytes.Repeat([]byte{0x00}, extraVanity-len(header.Extra))...)
+	extra := &ExtraData{Version: ExtraVersion(chain.Config(), header.Number), Vanity: header.Extra}
+	if len(extra.Vanity) > extraVanity {
+		extra.Vanity = extra.Vanity[:extraVanity]
 	}
-	header.Extra = header.Extra[:extraVanity]
 	masternodes := snap.GetSigners()
 	if number >= c.config.Epoch && number%c.config.Epoch == 0 {
 		if c.HookPenalty != nil || c.HookPenaltyTIPSigning != nil {
@@ -874,14 +900,12 @@ func (c *Posv) Prepare(chain consensus.ChainReader, header *types.Header) error
 			}
 		}
 		// Prevent penalized masternode(s) within 4 recent epochs
-		for i := 1; i <= common.LimitPenaltyEpoch; i++ {
+		for i := 1; i <= c.config.PenaltyEpochs(); i++ {
 			if number > uint64(i)*c.config.Epoch {
 				masternodes = RemovePenaltiesFromBlock(chain, masternodes, number-uint64(i)*c.config.Epoch)
 			}
 		}
-		for _, masternode := range masternodes {
-			header.Extra = append(header.Extra, masternode[:]...)
-		}
+		extra.Checkpoint, extra.Masternodes = true, masternodes
 		if c.HookValidator != nil {
 			validators, err := c.HookValidator(header, masternodes)
 			if err != nil {
@@ -889,8 +913,13 @@ func (c *Posv) Prepare(chain consensus.ChainReader, header *types.Header) error
 			}
 			header.Validators = validators
 		}
+		if extra.Version >= ExtraVersion2 {
+			extra.Proof = c.checkpointProof(chain, parent)
+		}
+	}
+	if header.Extra, err = extra.Encode(); err != nil {
+		return err
 	}
-	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
 
 	// Mix digest is reserved for now, set to empty
 	header.MixDigest = common.Hash{}
@@ -936,10 +965,18 @@ func (c *Posv) Finalize(chain consensus.ChainReader, header *types.Header, state
 	// _ = c.CacheData(header, txs, receipts)
 
 	if c.HookReward != nil && number%rCheckpoint == 0 {
+		// Keep the state before the rewards to audit them
+		before := state
+		if c.VerifyRewards {
+			before = state.Copy()
+		}
 		err, rewards := c.HookReward(chain, state, header)
 		if err != nil {
 			return nil, err
 		}
+		if c.VerifyRewards {
+			c.auditRewards(chain, header, before, state, rewards)
+		}
 		if len(common.StoreRewardFolder) > 0 {
 			data, err := json.Marshal(rewards)
 			if err == nil {
@@ -951,6 +988,12 @@ func (c *Posv) Finalize(chain consensus.ChainReader, header *types.Header, state
 		}
 	}
 
+	// Checkpoints carrying BLS proofs must prove signatures by registered keys
+	if number%c.config.Epoch == 0 && ExtraVersion(chain.Config(), header.Number) >= ExtraVersion2 {
+		if err := c.verifyCheckpointProof(chain, header, state); err != nil {
+			return nil, err
+		}
+	}
 	// the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
@@ -959,6 +1002,39 @@ func (c *Posv) Finalize(chain consensus.ChainReader, header *types.Header, state
 	return types.NewBlock(header, txs, nil, receipts), nil
 }
 
+// checkpointProof aggregates the BLS signatures of the gap block recorded by the
+// masternodes of the epoch closed by the checkpoint following parent. Without
+// access to the state, the checkpoint proves no signature.
+func (c *Posv) checkpointProof(chain consensus.ChainReader, parent *types.Header) *CheckpointProof {
+	masternodes := c.GetMasternodes(chain, parent)
+	if c.HookCheckpointState == nil {
+		return emptyCheckpointProof(len(masternodes))
+	}
+	statedb, err := c.HookCheckpointState(parent.Root)
+	if err != nil {
+		log.Warn("Failed to aggregate the checkpoint signatures", "number", parent.Number.Uint64()+1, "err", err)
+		return emptyCheckpointProof(len(masternodes))
+	}
+	proof := BuildCheckpointProof(statedb, masternodes, parent.Number.Uint64()+1-c.config.Gap)
+	log.Debug("Aggregated the checkpoint signatures", "number", parent.Number.Uint64()+1, "signers", len(CheckpointProofSigners(masternodes, proof)), "masternodes", len(masternodes))
+	return proof
+}
+
+// verifyCheckpointProof checks the BLS proof of a checkpoint against the keys
+// registered in its state.
+func (c *Posv) verifyCheckpointProof(chain consensus.ChainReader, header *types.Header, statedb *state.StateDB) error {
+	extra, err := decodeExtraData(header.Extra, true, ExtraVersion2)
+	if err != nil {
+		return err
+	}
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	gap := c.gapHeader(chain, header)
+	if parent == nil || gap == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	return VerifyCheckpointProof(statedb, c.GetMasternodes(chain, parent), gap.Hash(), extra.Proof)
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (c *Posv) Authorize(signer common.Address, signFn clique.SignerFn) {
@@ -969,6 +1045,15 @@ func (c *Posv) Authorize(signer common.Address, signFn clique.SignerFn) {
 	c.signFn = signFn
 }
 
+// SignerFn returns the account the engine is authorized to seal with and its
+// signing function, nil if not authorized.
+func (c *Posv) SignerFn() (common.Address, clique.SignerFn) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.signer, c.signFn
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (c *Posv) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
@@ -1105,12 +1190,7 @@ func (c *Posv) GetMasternodesFromCheckpointHeader(preCheckpointHeader *types.Hea
 		log.Info("Previous checkpoint's header is empty", "block number", n, "epoch", e)
 		return []common.Address{}
 	}
-	masternodes := make([]common.Address, (len(preCheckpointHeader.Extra)-extraVanity-extraSeal)/common.AddressLength)
-	for i := 0; i < len(masternodes); i++ {
-		copy(masternodes[i][:], preCheckpointHeader.Extra[extraVanity+i*common.AddressLength:])
-	}
-
-	return masternodes
+	return GetMasternodesFromCheckpointHeader(preCheckpointHeader)
 }
 
 func (c *Posv) CacheData(header *types.Header, txs []*types.Transaction, receipts []*types.Receipt) []*types.Transaction {
@@ -1176,16 +1256,17 @@ func RemovePenaltiesFromBlock(chain consensus.ChainReader, masternodes []common.
 
 // Get masternodes address from checkpoint Header.
 func GetMasternodesFromCheckpointHeader(checkpointHeader *types.Header) []common.Address {
-	masternodes := make([]common.Address, (len(checkpointHeader.Extra)-extraVanity-extraSeal)/common.AddressLength)
-	for i := 0; i < len(masternodes); i++ {
-		copy(masternodes[i][:], checkpointHeader.Extra[extraVanity+i*common.AddressLength:])
+	extra, err := DecodeExtraData(checkpointHeader.Extra, true)
+	if err != nil {
+		log.Warn("Invalid extra-data in checkpoint header", "number", checkpointHeader.Number, "err", err)
+		return []common.Address{}
 	}
-	return masternodes
+	return extra.Masternodes
 }
 
 // Get m2 list from checkpoint block.
 func GetM1M2FromCheckpointHeader(checkpointHeader *types.Header, currentHeader *types.Header, config *params.ChainConfig) (map[common.Address]common.Address, error) {
-	if checkpointHeader.Number.Uint64()%common.EpocBlockRandomize != 0 {
+	if checkpointHeader.Number.Uint64()%config.Posv.Epoch != 0 {
 		return nil, errors.New("This block is not checkpoint block epoc.")
 	}
 	// Get signers from this block.
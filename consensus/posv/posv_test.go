@@ -1,12 +1,15 @@
 package posv
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -82,3 +85,94 @@ func TestCompareSignersLists(t *testing.T) {
 		t.Error("Failed with list has only one signer")
 	}
 }
+
+// testChainReader is a consensus.ChainReader over a header chain.
+type testChainReader struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (r *testChainReader) Config() *params.ChainConfig  { return r.config }
+func (r *testChainReader) CurrentHeader() *types.Header { return r.headers[len(r.headers)-1] }
+func (r *testChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := r.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+func (r *testChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(r.headers)) {
+		return r.headers[number]
+	}
+	return nil
+}
+func (r *testChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range r.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+func (r *testChainReader) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+func TestIsMinorityFork(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	extra := make([]byte, extraVanity)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		extra = append(extra, crypto.PubkeyToAddress(key.PublicKey).Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraSeal)...)
+
+	config := &params.ChainConfig{ChainId: big.NewInt(1), Posv: &params.PosvConfig{Period: 2, Epoch: 10}}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), Difficulty: big.NewInt(1), Extra: extra, UncleHash: uncleHash}
+
+	// makeChain seals a header chain with the given creator indexes
+	makeChain := func(creators []int) *testChainReader {
+		reader := &testChainReader{config: config, headers: []*types.Header{genesis}}
+		for i, creator := range creators {
+			header := &types.Header{
+				ParentHash: reader.headers[i].Hash(),
+				Number:     big.NewInt(int64(i + 1)),
+				Time:       big.NewInt(int64(2 * (i + 1))),
+				Difficulty: big.NewInt(1),
+				Extra:      make([]byte, extraVanity+extraSeal),
+				UncleHash:  uncleHash,
+			}
+			sig, _ := crypto.Sign(sigHash(header).Bytes(), keys[creator])
+			copy(header.Extra[extraVanity:], sig)
+			reader.headers = append(reader.headers, header)
+		}
+		return reader
+	}
+	tests := []struct {
+		creators []int
+		minority bool
+	}{
+		// Too short a chain to tell
+		{[]int{0, 0}, false},
+		// All the masternodes seal in turn
+		{[]int{0, 1, 2, 3, 0, 1}, false},
+		// Half of the masternodes seal the last blocks
+		{[]int{0, 1, 2, 3, 0, 1, 0, 1}, false},
+		// A single masternode seals the last blocks
+		{[]int{0, 1, 2, 3, 0, 0, 0, 0}, true},
+		// and a second one rejoins
+		{[]int{0, 1, 2, 3, 0, 0, 0, 0, 1}, false},
+	}
+	for i, tt := range tests {
+		db, _ := ethdb.NewMemDatabase()
+		engine := New(config.Posv, db)
+
+		chain := makeChain(tt.creators)
+		minority, err := engine.IsMinorityFork(chain, chain.headers[len(chain.headers)-1])
+		if err != nil {
+			t.Fatalf("test %d: failed to check the fork: %v", i, err)
+		}
+		if minority != tt.minority {
+			t.Errorf("test %d: minority mismatch: have %v, want %v", i, minority, tt.minority)
+		}
+	}
+}
@@ -0,0 +1,895 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package posvtest runs in-memory networks of POSV masternodes to test the
+// consensus rules. Every masternode runs its own database, blockchain and
+// consensus engine, wired with the same rewards and penalties as a real node,
+// so that the masternode rotation, the double validation, the penalties, the
+// rewards and the fork choice are checked by every node independently.
+package posvtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/consensus/posv/reward"
+	"github.com/ethereum/go-ethereum/contracts"
+	blockSignerContract "github.com/ethereum/go-ethereum/contracts/blocksigner"
+	validatorContract "github.com/ethereum/go-ethereum/contracts/validator"
+	"github.com/ethereum/go-ethereum/contracts/validator/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox"
+)
+
+const (
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+
+	defaultPeriod = 2
+	defaultEpoch  = 30
+	defaultGap    = 5
+	gasLimit      = 42000000
+)
+
+var (
+	// MasternodeCap is the stake of every masternode candidate at genesis.
+	MasternodeCap = new(big.Int).Mul(big.NewInt(50000), big.NewInt(params.Ether))
+
+	// Owner owns the masternode candidates at genesis, earning their share of
+	// the rewards.
+	Owner = common.HexToAddress("0x00000000000000000000000000000000000000f1")
+
+	// Foundation is the foundation wallet of a network unless configured.
+	Foundation = common.HexToAddress("0x00000000000000000000000000000000000000f2")
+
+	// deployKey is a throwaway key used to deploy the system contracts on a
+	// simulated backend before copying them into the genesis allocation.
+	deployKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+
+	errNoCandidates = errors.New("posvtest: no masternode candidate")
+	errNoSealer     = errors.New("posvtest: no masternode can seal the next block")
+	errPartition    = errors.New("posvtest: partition must hold every node once")
+
+	// drainCheckpoints makes sure somebody listens to the checkpoint notifications
+	// the blockchains send on every epoch, as cmd/tomo does for a real node.
+	drainCheckpoints sync.Once
+)
+
+// Config describes a network and its genesis.
+type Config struct {
+	Posv        params.PosvConfig // Consensus parameters, test values are used for the period, epoch, reward checkpoint and foundation if unset
+	Candidates  int               // Number of masternode candidates at genesis, each running a node
+	Votes       []Vote            // Votes cast on the candidates at genesis
+	Alloc       core.GenesisAlloc // Additional genesis accounts
+	Seed        int64             // Seed of the randomization of the validators and of the offline masternodes
+	OfflineRate float64           // Chance of a masternode to stay offline for a whole epoch
+}
+
+// Vote is a vote cast at genesis on a masternode candidate.
+type Vote struct {
+	Voter     *ecdsa.PrivateKey
+	Candidate int // Index of the voted candidate
+	Cap       *big.Int
+}
+
+// Checkpoint is the consensus outcome of a checkpoint block, as compared with
+// the fixtures.
+type Checkpoint struct {
+	Number      uint64                      `json:"number"`
+	Masternodes []common.Address            `json:"masternodes"`
+	Penalties   []common.Address            `json:"penalties,omitempty"`
+	Validators  []int64                     `json:"validators,omitempty"`
+	Signs       map[common.Address]uint64   `json:"signs,omitempty"`   // Blocks signed by each masternode in the reward period
+	Rewards     map[common.Address]*big.Int `json:"rewards,omitempty"` // Rewards credited to each holder
+}
+
+// Node is a masternode candidate of a network, following the chain with its
+// own blockchain and consensus engine.
+type Node struct {
+	Key     *ecdsa.PrivateKey
+	Address common.Address
+	Chain   *core.BlockChain
+	Engine  *posv.Posv
+
+	index   int
+	network *Network
+	rewards map[common.Hash][]byte // Rewards applied at the checkpoints processed, by block hash
+	lock    sync.Mutex             // Protects the rewards
+}
+
+// signKey identifies the signature of a block by a masternode.
+type signKey struct {
+	signer common.Address
+	hash   common.Hash
+}
+
+// signRequest is a block signature waiting to be included in a block.
+type signRequest struct {
+	node   *Node
+	number uint64
+	hash   common.Hash
+}
+
+// Network is a set of masternodes sealing a POSV chain. Blocks are sealed by
+// the masternode in turn, skipping the offline ones, and every masternode signs
+// the blocks it imports in the next blocks. The nodes may be split in groups,
+// each sealing its own fork until the network heals.
+type Network struct {
+	Nodes []*Node
+
+	config      *params.ChainConfig
+	seed        int64
+	offlineRate float64
+	offline     map[int]bool // Masternodes forced offline, by node index
+
+	groups [][]*Node          // Groups of nodes connected together
+	signs  []signRequest      // Block signatures waiting to be included
+	signed map[signKey]bool   // Block signatures requested so far
+	txs    types.Transactions // Transactions sent to the network
+}
+
+// NewNetwork creates the nodes of a network, all connected together and
+// sharing a genesis holding the validator contract with every candidate and the
+// block signer contract.
+func NewNetwork(config Config) (*Network, error) {
+	if config.Candidates <= 0 {
+		return nil, errNoCandidates
+	}
+	posvConfig := config.Posv
+	if posvConfig.Period == 0 {
+		posvConfig.Period = defaultPeriod
+	}
+	if posvConfig.Epoch == 0 {
+		posvConfig.Epoch, posvConfig.Gap = defaultEpoch, defaultGap
+	}
+	if posvConfig.RewardCheckpoint == 0 {
+		posvConfig.RewardCheckpoint = posvConfig.Epoch
+	}
+	if posvConfig.FoudationWalletAddr == (common.Address{}) {
+		posvConfig.FoudationWalletAddr = Foundation
+	}
+	if err := posvConfig.Validate(); err != nil {
+		return nil, err
+	}
+	chainConfig := *params.AllEthashProtocolChanges
+	chainConfig.Ethash = nil
+	chainConfig.Posv = &posvConfig
+
+	n := &Network{
+		config:      &chainConfig,
+		seed:        config.Seed,
+		offlineRate: config.OfflineRate,
+		offline:     make(map[int]bool),
+		signed:      make(map[signKey]bool),
+	}
+	for i := 0; i < config.Candidates; i++ {
+		key := nodeKey(i)
+		n.Nodes = append(n.Nodes, &Node{
+			Key:     key,
+			Address: crypto.PubkeyToAddress(key.PublicKey),
+			index:   i,
+			network: n,
+			rewards: make(map[common.Hash][]byte),
+		})
+	}
+	genesis, err := n.genesis(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genesis: %v", err)
+	}
+	for _, node := range n.Nodes {
+		if err := node.start(genesis); err != nil {
+			n.Close()
+			return nil, err
+		}
+	}
+	n.groups = [][]*Node{n.Nodes}
+
+	drainCheckpoints.Do(func() {
+		go func() {
+			for range core.CheckpointCh {
+			}
+		}()
+	})
+	return n, nil
+}
+
+// nodeKey derives the key of a candidate from its index, so that the addresses
+// recorded in the fixtures don't change between runs.
+func nodeKey(index int) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("posvtest masternode %d", index))))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// genesis assembles the genesis of the network. The first candidates, up to
+// the masternodes limit, seal the first epoch.
+func (n *Network) genesis(config Config) (*core.Genesis, error) {
+	genesis := &core.Genesis{
+		Config:    n.config,
+		GasLimit:  gasLimit,
+		ExtraData: make([]byte, extraVanity),
+		Alloc:     core.GenesisAlloc{},
+	}
+	for addr, account := range config.Alloc {
+		genesis.Alloc[addr] = account
+	}
+	var (
+		candidates []common.Address
+		caps       []*big.Int
+		staked     = new(big.Int)
+	)
+	for i, node := range n.Nodes {
+		candidates = append(candidates, node.Address)
+		caps = append(caps, MasternodeCap)
+		staked.Add(staked, MasternodeCap)
+		if i < n.config.Posv.MasternodesLimit() {
+			genesis.ExtraData = append(genesis.ExtraData, node.Address[:]...)
+		}
+	}
+	genesis.ExtraData = append(genesis.ExtraData, make([]byte, extraSeal)...)
+
+	// Deploy the system contracts and cast the votes on a simulated backend
+	deployer := crypto.PubkeyToAddress(deployKey.PublicKey)
+	alloc := core.GenesisAlloc{deployer: {Balance: new(big.Int).Mul(staked, big.NewInt(2))}}
+	for _, vote := range config.Votes {
+		voter := crypto.PubkeyToAddress(vote.Voter.PublicKey)
+		balance := new(big.Int).Mul(vote.Cap, big.NewInt(2))
+		if account, ok := alloc[voter]; ok {
+			balance.Add(balance, account.Balance)
+		}
+		alloc[voter] = core.GenesisAccount{Balance: balance}
+	}
+	sim := backends.NewSimulatedBackend(alloc)
+	opts := bind.NewKeyedTransactor(deployKey)
+
+	validatorAddr, _, err := validatorContract.DeployValidator(opts, sim, candidates, caps, Owner)
+	if err != nil {
+		return nil, err
+	}
+	blockSignerAddr, _, err := blockSignerContract.DeployBlockSigner(opts, sim, new(big.Int).SetUint64(n.config.Posv.Epoch))
+	if err != nil {
+		return nil, err
+	}
+	sim.Commit()
+
+	validator, err := contract.NewTomoValidator(validatorAddr, sim)
+	if err != nil {
+		return nil, err
+	}
+	for _, vote := range config.Votes {
+		if vote.Candidate < 0 || vote.Candidate >= len(candidates) {
+			return nil, fmt.Errorf("vote for unknown candidate %d", vote.Candidate)
+		}
+		opts := bind.NewKeyedTransactor(vote.Voter)
+		opts.Value = vote.Cap
+		if _, err := validator.Vote(opts, candidates[vote.Candidate]); err != nil {
+			return nil, err
+		}
+		staked.Add(staked, vote.Cap)
+		sim.Commit()
+	}
+	contracts := []struct {
+		deployed common.Address
+		target   string
+		balance  *big.Int
+	}{
+		{validatorAddr, common.MasternodeVotingSMC, staked},
+		{blockSignerAddr, common.BlockSigners, new(big.Int)},
+	}
+	for _, contract := range contracts {
+		account, err := sim.DumpContract(contract.deployed)
+		if err != nil {
+			return nil, err
+		}
+		account.Balance = contract.balance
+		genesis.Alloc[common.HexToAddress(contract.target)] = account
+	}
+	return genesis, nil
+}
+
+// start creates the database, consensus engine and blockchain of a node, with
+// the same hooks as a masternode.
+func (node *Node) start(genesis *core.Genesis) error {
+	db, _ := ethdb.NewMemDatabase()
+	genesis.MustCommit(db)
+
+	engine := posv.New(node.network.config.Posv, db)
+	engine.Authorize(node.Address, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, node.Key)
+	})
+	engine.GetTomoXService = func() *tomox.TomoX {
+		return nil
+	}
+	engine.HookPenalty = node.penalties
+	engine.HookValidator = node.validators
+	engine.HookVerifyMNs = node.verifyValidators
+	engine.HookGetSignersFromContract = node.signersFromContract
+	engine.HookReward = node.reward
+
+	chain, err := core.NewBlockChain(db, nil, node.network.config, engine, vm.Config{})
+	if err != nil {
+		return err
+	}
+	// The masternodes of the next epoch are read from the validator contract
+	if chain.Client, err = backends.NewChainClient(db, chain); err != nil {
+		return err
+	}
+	node.Chain, node.Engine = chain, engine
+	return nil
+}
+
+// penalties returns the masternodes of the previous epoch which signed none of
+// its blocks, as a masternode does.
+func (node *Node) penalties(chain consensus.ChainReader, number uint64) ([]common.Address, error) {
+	statedb, err := node.Chain.State()
+	if err != nil {
+		return nil, err
+	}
+	return contracts.GetPenaltiesForEpoch(node.Engine, chain, statedb, number)
+}
+
+// validators assigns the double validators of the masternodes of a checkpoint
+// from random numbers drawn from the seed of the network, in place of the
+// secrets the masternodes commit to the randomize contract.
+func (node *Node) validators(header *types.Header, masternodes []common.Address) ([]byte, error) {
+	if len(masternodes) == 0 {
+		return nil, core.ErrNotFoundM1
+	}
+	rnd := rand.New(rand.NewSource(node.network.seed + header.Number.Int64()))
+	randomizes := make([]int64, len(masternodes))
+	for i := range randomizes {
+		randomizes[i] = rnd.Int63n(int64(node.network.config.Posv.Epoch))
+	}
+	m2, err := contracts.GenM2FromRandomize(randomizes, int64(len(masternodes)))
+	if err != nil {
+		return nil, err
+	}
+	return contracts.BuildValidatorFromM2(m2), nil
+}
+
+// verifyValidators checks the double validators of a checkpoint.
+func (node *Node) verifyValidators(header *types.Header, masternodes []common.Address) error {
+	number := header.Number.Uint64()
+	if number == 0 || number%node.network.config.Posv.Epoch != 0 {
+		return nil
+	}
+	validators, err := node.validators(header, masternodes)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header.Validators, validators) {
+		return posv.ErrInvalidCheckpointValidators
+	}
+	return nil
+}
+
+// signersFromContract returns the candidates with the highest stakes at a block,
+// up to the masternodes limit.
+func (node *Node) signersFromContract(hash common.Hash) ([]common.Address, error) {
+	header := node.Chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, fmt.Errorf("unknown block %x", hash)
+	}
+	statedb, err := node.Chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []posv.Masternode
+	for _, candidate := range state.GetCandidates(statedb) {
+		if candidate != (common.Address{}) {
+			candidates = append(candidates, posv.Masternode{Address: candidate, Stake: state.GetCandidateCap(statedb, candidate)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Stake.Cmp(candidates[j].Stake) >= 0
+	})
+	if limit := node.network.config.Posv.MasternodesLimit(); len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	signers := make([]common.Address, len(candidates))
+	for i, candidate := range candidates {
+		signers[i] = candidate.Address
+	}
+	return signers, nil
+}
+
+// reward credits the rewards of a checkpoint, recording them to compare the
+// rewards computed by all the nodes.
+func (node *Node) reward(chain consensus.ChainReader, statedb *state.StateDB, header *types.Header) (error, map[string]interface{}) {
+	number := header.Number.Uint64()
+	if number <= chain.Config().Posv.RewardCheckpoint {
+		return nil, nil
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor, nil
+	}
+	parentState, err := node.Chain.StateAt(parent.Root)
+	if err != nil {
+		return err, nil
+	}
+	ctx := context.Background()
+	in, err := reward.NewInput(ctx, node.Engine, chain, header, contracts.NewStorageValidatorCaller(parentState))
+	if err != nil {
+		return err, nil
+	}
+	result, err := reward.Compute(ctx, chain.Config().Posv, in)
+	if err != nil {
+		return err, nil
+	}
+	result.Apply(statedb)
+	rewards := result.Rewards()
+	data, err := json.Marshal(rewards)
+	if err != nil {
+		return err, nil
+	}
+	node.lock.Lock()
+	node.rewards[header.Hash()] = data
+	node.lock.Unlock()
+	return nil, rewards
+}
+
+// recordedRewards returns the rewards a node applied at a checkpoint.
+func (node *Node) recordedRewards(hash common.Hash) []byte {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	return node.rewards[hash]
+}
+
+// SetOffline takes a masternode offline or back online. Offline masternodes
+// keep following the chain, but neither seal nor sign blocks.
+func (n *Network) SetOffline(index int, offline bool) {
+	n.offline[index] = offline
+}
+
+// online reports whether a masternode seals and signs the blocks of the epoch
+// of number, drawing its availability for the epoch from the network seed.
+func (n *Network) online(node *Node, number uint64) bool {
+	if n.offline[node.index] {
+		return false
+	}
+	if n.offlineRate == 0 {
+		return true
+	}
+	epoch := int64(number / n.config.Posv.Epoch)
+	rnd := rand.New(rand.NewSource(n.seed*1000003 + epoch*1009 + int64(node.index)))
+	return rnd.Float64() >= n.offlineRate
+}
+
+// SendTransaction queues a transaction to be included in the next blocks of
+// every group of nodes.
+func (n *Network) SendTransaction(tx *types.Transaction) {
+	n.txs = append(n.txs, tx)
+}
+
+// Commit seals a block on top of the chain of every group of nodes and imports
+// it in all the nodes of the group. A group where no masternode can seal stalls,
+// an error is only returned if all the groups do.
+func (n *Network) Commit() error {
+	sealed := false
+	for _, group := range n.groups {
+		block, err := n.seal(group)
+		if err == errNoSealer {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := n.deliver(group, []*types.Block{block}); err != nil {
+			return err
+		}
+		sealed = true
+	}
+	if !sealed {
+		return errNoSealer
+	}
+	n.prune()
+	return nil
+}
+
+// AdvanceEpoch commits blocks until the nodes reach the next checkpoint and
+// returns its number.
+func (n *Network) AdvanceEpoch() (uint64, error) {
+	for {
+		if err := n.Commit(); err != nil {
+			return 0, err
+		}
+		if number := n.Nodes[0].Chain.CurrentBlock().NumberU64(); number%n.config.Posv.Epoch == 0 {
+			return number, nil
+		}
+	}
+}
+
+// Partition splits the nodes in groups, given by node indexes, which keep
+// sealing their own forks until the network heals.
+func (n *Network) Partition(groups ...[]int) error {
+	var (
+		partition [][]*Node
+		seen      = make(map[int]bool)
+	)
+	for _, indexes := range groups {
+		var group []*Node
+		for _, index := range indexes {
+			if index < 0 || index >= len(n.Nodes) || seen[index] {
+				return errPartition
+			}
+			seen[index] = true
+			group = append(group, n.Nodes[index])
+		}
+		if len(group) > 0 {
+			partition = append(partition, group)
+		}
+	}
+	if len(seen) != len(n.Nodes) {
+		return errPartition
+	}
+	n.groups = partition
+	return nil
+}
+
+// Heal reconnects all the nodes, exchanging their forks so that every node
+// switches to the heaviest one, and checks that they all agree on it.
+func (n *Network) Heal() error {
+	for _, node := range n.Nodes {
+		for _, peer := range n.Nodes {
+			if peer == node {
+				continue
+			}
+			// Gather the blocks of the peer the node doesn't know
+			var blocks []*types.Block
+			for block := peer.Chain.CurrentBlock(); !node.Chain.HasBlock(block.Hash(), block.NumberU64()); block = peer.Chain.GetBlock(block.ParentHash(), block.NumberU64()-1) {
+				blocks = append([]*types.Block{block}, blocks...)
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			if err := n.deliver([]*Node{node}, blocks); err != nil {
+				return err
+			}
+		}
+	}
+	n.groups = [][]*Node{n.Nodes}
+	return n.CheckHeads()
+}
+
+// CheckHeads checks that every node follows the same chain.
+func (n *Network) CheckHeads() error {
+	head := n.Nodes[0].Chain.CurrentBlock()
+	for _, node := range n.Nodes[1:] {
+		if current := node.Chain.CurrentBlock(); current.Hash() != head.Hash() {
+			return fmt.Errorf("node %d head #%d [%x…] differs from node 0 head #%d [%x…]", node.index, current.NumberU64(), current.Hash().Bytes()[:4], head.NumberU64(), head.Hash().Bytes()[:4])
+		}
+	}
+	return nil
+}
+
+// seal picks the next masternode in turn which is connected to the group and
+// online, and seals a block with it.
+func (n *Network) seal(group []*Node) (*types.Block, error) {
+	ref := group[0]
+	parent := ref.Chain.CurrentBlock()
+	number := parent.NumberU64() + 1
+
+	masternodes := ref.Engine.GetMasternodes(ref.Chain, parent.Header())
+	if len(masternodes) == 0 {
+		return nil, errNoSealer
+	}
+	var (
+		creator  common.Address
+		preIndex = -1
+	)
+	if parent.NumberU64() > 0 {
+		var err error
+		if creator, err = ref.Engine.RecoverSigner(parent.Header()); err != nil {
+			return nil, err
+		}
+		preIndex = indexOf(masternodes, creator)
+	}
+	for i := 1; i <= len(masternodes); i++ {
+		candidate := masternodes[(preIndex+i)%len(masternodes)]
+		// Masternodes can't seal two blocks in a row, but at checkpoints
+		if candidate == creator && len(masternodes) > 1 && number%n.config.Posv.Epoch != 0 {
+			continue
+		}
+		node := member(group, candidate)
+		if node == nil || !n.online(node, number) {
+			continue
+		}
+		block, err := n.sealWith(node, group, parent)
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			return block, nil
+		}
+	}
+	return nil, errNoSealer
+}
+
+// sealWith assembles, seals and double validates a block on top of parent with
+// the given masternode. It returns nil if the masternode is not allowed to seal
+// the block, or if its validator is not connected to the group.
+func (n *Network) sealWith(node *Node, group []*Node, parent *types.Block) (*types.Block, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+	}
+	if err := node.Engine.Prepare(node.Chain, header); err != nil {
+		return nil, err
+	}
+	// Keep the timestamps, hence the blocks, independent from the wall clock
+	header.Time = new(big.Int).Add(parent.Time(), new(big.Int).SetUint64(n.config.Posv.Period))
+
+	number := header.Number.Uint64()
+	if number%n.config.Posv.Epoch == 0 && indexOf(posv.GetMasternodesFromCheckpointHeader(header), node.Address) < 0 {
+		return nil, nil
+	}
+	validator, err := node.Engine.GetValidator(node.Address, node.Chain, header)
+	if err != nil {
+		return nil, err
+	}
+	var validatorNode *Node
+	if validator != (common.Address{}) {
+		if validatorNode = member(group, validator); validatorNode == nil {
+			return nil, nil
+		}
+	}
+	// Run the pending signatures and transactions as the miner does
+	statedb, err := node.Chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	txs, err := n.pending(node, group, statedb, header)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		receipts       types.Receipts
+		usedGas        = new(uint64)
+		gp             = new(core.GasPool).AddGas(header.GasLimit)
+		feeCapacity    = core.TRC21FeeCapacity(n.config, header.Number, statedb)
+		balanceUpdated = map[common.Address]*big.Int{}
+		totalFeeUsed   = big.NewInt(0)
+	)
+	core.InitSignerInTransactions(n.config, header, txs)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		receipt, gas, err, tokenFeeUsed := core.ApplyTransaction(n.config, feeCapacity, node.Chain, &node.Address, gp, statedb, header, tx, usedGas, vm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction %x: %v", tx.Hash(), err)
+		}
+		receipts = append(receipts, receipt)
+		if tokenFeeUsed {
+			fee := new(big.Int).SetUint64(gas)
+			if header.Number.Cmp(common.TIPTRC21Fee) > 0 {
+				fee = fee.Mul(fee, common.TRC21GasPrice)
+			}
+			feeCapacity[*tx.To()] = new(big.Int).Sub(feeCapacity[*tx.To()], fee)
+			balanceUpdated[*tx.To()] = feeCapacity[*tx.To()]
+			totalFeeUsed = totalFeeUsed.Add(totalFeeUsed, fee)
+		}
+	}
+	state.UpdateTRC21Fee(statedb, balanceUpdated, totalFeeUsed)
+	header.GasUsed = *usedGas
+
+	block, err := node.Engine.Finalize(node.Chain, header, statedb, txs, nil, receipts)
+	if err != nil {
+		return nil, err
+	}
+	if block, err = node.Engine.Seal(node.Chain, block, nil); err != nil {
+		return nil, err
+	}
+	// Append the signature of the validator, as it does on receiving the block
+	if validatorNode != nil && validatorNode != node {
+		header := block.Header()
+		sig, err := crypto.Sign(posv.SigHash(header).Bytes(), validatorNode.Key)
+		if err != nil {
+			return nil, err
+		}
+		header.Validator = sig
+		block = types.NewBlockWithHeader(header).WithBody(block.Transactions(), block.Uncles()).WithTrades(block.Trades())
+	}
+	return block, nil
+}
+
+// pending returns the signatures of the group not included yet on the chain of
+// the node, followed by the transactions sent to the network next in line.
+func (n *Network) pending(node *Node, group []*Node, statedb *state.StateDB, header *types.Header) (types.Transactions, error) {
+	var (
+		txs    types.Transactions
+		nonces = make(map[common.Address]uint64)
+		signer = types.MakeSigner(n.config, header.Number)
+	)
+	nonce := func(addr common.Address) uint64 {
+		if _, ok := nonces[addr]; !ok {
+			nonces[addr] = statedb.GetNonce(addr)
+		}
+		return nonces[addr]
+	}
+	for _, sign := range n.signs {
+		if member(group, sign.node.Address) == nil || !canonical(node, sign.number, sign.hash) {
+			continue
+		}
+		block := node.Chain.GetBlock(sign.hash, sign.number)
+		if indexOf(state.GetSigners(statedb, block), sign.node.Address) >= 0 {
+			continue
+		}
+		tx, err := types.SignTx(contracts.CreateTxSign(new(big.Int).SetUint64(sign.number), sign.hash, nonce(sign.node.Address), common.HexToAddress(common.BlockSigners)), signer, sign.node.Key)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+		nonces[sign.node.Address]++
+	}
+	for _, tx := range n.txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		if tx.Nonce() == nonce(from) {
+			txs = append(txs, tx)
+			nonces[from]++
+		}
+	}
+	return txs, nil
+}
+
+// deliver imports blocks in the given nodes, each node then requesting the
+// signature of the blocks it has to sign.
+func (n *Network) deliver(nodes []*Node, blocks []*types.Block) error {
+	for _, node := range nodes {
+		if _, err := node.Chain.InsertChain(blocks); err != nil {
+			return fmt.Errorf("node %d failed to import block #%d: %v", node.index, blocks[0].NumberU64(), err)
+		}
+		for _, block := range blocks {
+			if !canonical(node, block.NumberU64(), block.Hash()) || !n.online(node, block.NumberU64()) {
+				continue
+			}
+			if indexOf(node.Engine.GetMasternodes(node.Chain, block.Header()), node.Address) < 0 {
+				continue
+			}
+			key := signKey{node.Address, block.Hash()}
+			if !n.signed[key] {
+				n.signed[key] = true
+				n.signs = append(n.signs, signRequest{node, block.NumberU64(), block.Hash()})
+			}
+		}
+	}
+	return nil
+}
+
+// prune drops the signatures of blocks too old to be signed anymore.
+func (n *Network) prune() {
+	head := n.Nodes[0].Chain.CurrentBlock().NumberU64()
+	signs := n.signs[:0]
+	for _, sign := range n.signs {
+		if sign.number+2*n.config.Posv.Epoch > head {
+			signs = append(signs, sign)
+		}
+	}
+	n.signs = signs
+}
+
+// Checkpoints returns the outcome of the checkpoints on the chain of the first
+// node, checking that every node following the same chain applied the same
+// rewards.
+func (n *Network) Checkpoints() ([]*Checkpoint, error) {
+	var (
+		chain       = n.Nodes[0].Chain
+		epoch       = n.config.Posv.Epoch
+		checkpoints []*Checkpoint
+	)
+	for number := epoch; number <= chain.CurrentBlock().NumberU64(); number += epoch {
+		header := chain.GetHeaderByNumber(number)
+		checkpoint := &Checkpoint{
+			Number:      number,
+			Masternodes: posv.GetMasternodesFromCheckpointHeader(header),
+			Penalties:   common.ExtractAddressFromBytes(header.Penalties),
+			Validators:  posv.ExtractValidatorsFromBytes(header.Validators),
+		}
+		rewards := n.Nodes[0].recordedRewards(header.Hash())
+		for _, node := range n.Nodes[1:] {
+			if !canonical(node, number, header.Hash()) {
+				continue
+			}
+			if have := node.recordedRewards(header.Hash()); !bytes.Equal(have, rewards) {
+				return nil, fmt.Errorf("checkpoint #%d: node %d rewards %s differ from node 0 rewards %s", number, node.index, have, rewards)
+			}
+		}
+		if rewards != nil {
+			var decoded struct {
+				Signers map[common.Address]struct {
+					Sign uint64 `json:"sign"`
+				} `json:"signers"`
+				Rewards map[common.Address]map[common.Address]*big.Int `json:"rewards"`
+			}
+			if err := json.Unmarshal(rewards, &decoded); err != nil {
+				return nil, err
+			}
+			checkpoint.Signs = make(map[common.Address]uint64)
+			for signer, log := range decoded.Signers {
+				checkpoint.Signs[signer] = log.Sign
+			}
+			checkpoint.Rewards = make(map[common.Address]*big.Int)
+			for _, holders := range decoded.Rewards {
+				for holder, reward := range holders {
+					if checkpoint.Rewards[holder] == nil {
+						checkpoint.Rewards[holder] = new(big.Int)
+					}
+					checkpoint.Rewards[holder].Add(checkpoint.Rewards[holder], reward)
+				}
+			}
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, nil
+}
+
+// Close stops the blockchains of all the nodes.
+func (n *Network) Close() {
+	for _, node := range n.Nodes {
+		if node.Chain != nil {
+			node.Chain.Stop()
+		}
+	}
+}
+
+// canonical reports whether a block is on the chain followed by a node.
+func canonical(node *Node, number uint64, hash common.Hash) bool {
+	header := node.Chain.GetHeaderByNumber(number)
+	return header != nil && header.Hash() == hash
+}
+
+func member(group []*Node, addr common.Address) *Node {
+	for _, node := range group {
+		if node.Address == addr {
+			return node
+		}
+	}
+	return nil
+}
+
+func indexOf(list []common.Address, addr common.Address) int {
+	for i, item := range list {
+		if item == addr {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posvtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/validator/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var update = flag.Bool("update", false, "update the checkpoint fixtures in testdata")
+
+var (
+	voterKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	voter       = crypto.PubkeyToAddress(voterKey.PublicKey)
+	voteCap     = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+)
+
+// checkFixture compares the checkpoints of a network with the fixture of the
+// given name, or rewrites the fixture with the -update flag.
+func checkFixture(t *testing.T, network *Network, name string) {
+	checkpoints, err := network.Checkpoints()
+	if err != nil {
+		t.Fatalf("failed to get checkpoints: %v", err)
+	}
+	have, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode checkpoints: %v", err)
+	}
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := ioutil.WriteFile(path, append(have, '\n'), 0644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(have), bytes.TrimSpace(want)) {
+		t.Errorf("checkpoints mismatch with %s:\nhave %s", path, have)
+	}
+}
+
+func newTestNetwork(t *testing.T, config Config) *Network {
+	network, err := NewNetwork(config)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	return network
+}
+
+func advanceEpochs(t *testing.T, network *Network, epochs int) {
+	for i := 0; i < epochs; i++ {
+		if _, err := network.AdvanceEpoch(); err != nil {
+			t.Fatalf("failed to advance epoch %d: %v", i, err)
+		}
+	}
+	if err := network.CheckHeads(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that masternodes missing all the blocks of an epoch are penalized and
+// rewarded nothing, and come back once the penalty expires.
+func TestPenaltiesAndRewards(t *testing.T) {
+	network := newTestNetwork(t, Config{
+		Posv:       params.PosvConfig{Epoch: 10, Gap: 3, Reward: 250, LimitPenaltyEpoch: 1},
+		Candidates: 4,
+		Seed:       1,
+	})
+	defer network.Close()
+
+	network.SetOffline(3, true)
+	advanceEpochs(t, network, 1)
+	network.SetOffline(3, false)
+	advanceEpochs(t, network, 4)
+
+	checkpoints, err := network.Checkpoints()
+	if err != nil {
+		t.Fatalf("failed to get checkpoints: %v", err)
+	}
+	offline := network.Nodes[3].Address
+	if penalties := checkpoints[0].Penalties; len(penalties) != 1 || penalties[0] != offline {
+		t.Errorf("first checkpoint penalties mismatch: have %x, want [%x]", penalties, offline)
+	}
+	if indexOf(checkpoints[0].Masternodes, offline) >= 0 {
+		t.Errorf("penalized masternode %x kept in the first epoch", offline)
+	}
+	if _, ok := checkpoints[1].Signs[offline]; ok {
+		t.Errorf("penalized masternode %x rewarded for the first epoch", offline)
+	}
+	checkFixture(t, network, "penalties")
+}
+
+// Tests that votes cast during the chain reorder the candidates, rotating the
+// masternodes at the next checkpoint, and that the voters are rewarded.
+func TestVoteRotation(t *testing.T) {
+	network := newTestNetwork(t, Config{
+		Posv:       params.PosvConfig{Epoch: 10, Gap: 3, Reward: 250, MaxMasternodes: 3},
+		Candidates: 4,
+		Votes:      []Vote{{voterKey, 0, voteCap}, {voterKey, 1, voteCap}, {voterKey, 2, voteCap}},
+		Alloc:      core.GenesisAlloc{voter: {Balance: new(big.Int).Mul(voteCap, big.NewInt(3))}},
+		Seed:       2,
+	})
+	defer network.Close()
+
+	advanceEpochs(t, network, 1)
+
+	// Vote for the candidate left out, pushing it above the other ones
+	validatorABI, err := abi.JSON(strings.NewReader(contract.TomoValidatorABI))
+	if err != nil {
+		t.Fatalf("failed to parse validator abi: %v", err)
+	}
+	data, err := validatorABI.Pack("vote", network.Nodes[3].Address)
+	if err != nil {
+		t.Fatalf("failed to pack vote: %v", err)
+	}
+	tx := types.NewTransaction(0, common.HexToAddress(common.MasternodeVotingSMC), new(big.Int).Mul(voteCap, big.NewInt(2)), 1000000, big.NewInt(0), data)
+	signed, err := types.SignTx(tx, types.HomesteadSigner{}, voterKey)
+	if err != nil {
+		t.Fatalf("failed to sign vote: %v", err)
+	}
+	network.SendTransaction(signed)
+	advanceEpochs(t, network, 3)
+
+	checkpoints, err := network.Checkpoints()
+	if err != nil {
+		t.Fatalf("failed to get checkpoints: %v", err)
+	}
+	if indexOf(checkpoints[0].Masternodes, network.Nodes[3].Address) >= 0 {
+		t.Errorf("candidate %x sealing before the vote", network.Nodes[3].Address)
+	}
+	if indexOf(checkpoints[1].Masternodes, network.Nodes[3].Address) < 0 {
+		t.Errorf("candidate %x not sealing after the vote", network.Nodes[3].Address)
+	}
+	if reward := checkpoints[len(checkpoints)-1].Rewards[voter]; reward == nil || reward.Sign() <= 0 {
+		t.Errorf("voter not rewarded: have %v", reward)
+	}
+	checkFixture(t, network, "rotation")
+}
+
+// Tests that partitioned masternodes converge on the heaviest fork once the
+// network heals, and keep agreeing on the rewards.
+func TestForkChoice(t *testing.T) {
+	network := newTestNetwork(t, Config{
+		Posv:       params.PosvConfig{Epoch: 10, Gap: 3, Reward: 250},
+		Candidates: 5,
+		Seed:       3,
+	})
+	defer network.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := network.Commit(); err != nil {
+			t.Fatalf("failed to commit block: %v", err)
+		}
+	}
+	if err := network.Partition([]int{0, 1, 2}, []int{3, 4}); err != nil {
+		t.Fatalf("failed to partition network: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := network.Commit(); err != nil {
+			t.Fatalf("failed to commit partitioned block: %v", err)
+		}
+	}
+	majority, minority := network.Nodes[0].Chain, network.Nodes[3].Chain
+	if majority.CurrentBlock().Hash() == minority.CurrentBlock().Hash() {
+		t.Fatalf("partitions didn't fork")
+	}
+	if majority.GetTd(majority.CurrentBlock().Hash(), 5).Cmp(minority.GetTd(minority.CurrentBlock().Hash(), 5)) <= 0 {
+		t.Fatalf("majority fork not heavier than the minority one")
+	}
+	head := majority.CurrentBlock().Hash()
+	if err := network.Heal(); err != nil {
+		t.Fatalf("failed to heal network: %v", err)
+	}
+	if have := minority.CurrentBlock().Hash(); have != head {
+		t.Errorf("minority head mismatch: have %x, want %x", have, head)
+	}
+	advanceEpochs(t, network, 3)
+	checkFixture(t, network, "fork")
+}
+
+// Tests that randomly offline masternodes don't break the consensus among the
+// nodes.
+func TestRandomOffline(t *testing.T) {
+	network := newTestNetwork(t, Config{
+		Posv:        params.PosvConfig{Epoch: 10, Gap: 3, Reward: 250, LimitPenaltyEpoch: 1},
+		Candidates:  5,
+		Seed:        4,
+		OfflineRate: 0.2,
+	})
+	defer network.Close()
+
+	advanceEpochs(t, network, 5)
+	checkFixture(t, network, "offline")
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// remoteSignerTimeout is the time allowed to the remote signer to answer a
+// signing request, kept well below the block period.
+const remoteSignerTimeout = 2 * time.Second
+
+// RemoteSignerMethod is the JSON-RPC method called on the remote signer, with
+// the masternode address and the 32 bytes hash to sign as parameters. It must
+// return the 65 bytes [R || S || V] signature of the hash, as produced by
+// crypto.Sign.
+const RemoteSignerMethod = "posv_signHash"
+
+// remoteSignerAudit is an entry of the audit log of a RemoteSigner.
+type remoteSignerAudit struct {
+	Time      time.Time      `json:"time"`
+	Signer    common.Address `json:"signer"`
+	Hash      hexutil.Bytes  `json:"hash"`
+	Signature hexutil.Bytes  `json:"signature,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Duration  string         `json:"duration"`
+}
+
+// RemoteSigner delegates the seal signatures of a masternode to an external
+// signer (e.g. an HSM gateway) reached over HTTP, so that the masternode key
+// never lives on the sealing host. Every request is appended to an audit log
+// and every returned signature is checked against the masternode address.
+type RemoteSigner struct {
+	signer common.Address
+	client *rpc.Client
+
+	audit *os.File // Audit log of the signing requests, nil if disabled
+	lock  sync.Mutex
+}
+
+// NewRemoteSigner creates a signer sealing for the given masternode through the
+// remote signer at endpoint, logging the requests to auditPath if not empty.
+func NewRemoteSigner(endpoint string, signer common.Address, auditPath string) (*RemoteSigner, error) {
+	client, err := rpc.DialHTTP(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s := &RemoteSigner{
+		signer: signer,
+		client: client,
+	}
+	if auditPath != "" {
+		if s.audit, err = os.OpenFile(auditPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// SignHash implements clique.SignerFn, requesting the signature of the hash to
+// the remote signer.
+func (s *RemoteSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	if account.Address != s.signer {
+		return nil, fmt.Errorf("remote signer is configured for %s", s.signer.Hex())
+	}
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), remoteSignerTimeout)
+	defer cancel()
+
+	var sig hexutil.Bytes
+	err := s.client.CallContext(ctx, &sig, RemoteSignerMethod, s.signer, hexutil.Bytes(hash))
+	if err == nil {
+		err = verifySignature(s.signer, hash, sig)
+	}
+	s.record(remoteSignerAudit{
+		Time:      start.UTC(),
+		Signer:    s.signer,
+		Hash:      hash,
+		Signature: sig,
+		Error:     errString(err),
+		Duration:  common.PrettyDuration(time.Since(start)).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remote signer: %v", err)
+	}
+	return sig, nil
+}
+
+// record appends an entry to the audit log.
+func (s *RemoteSigner) record(entry remoteSignerAudit) {
+	if s.audit == nil {
+		return
+	}
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		log.Warn("Failed to encode remote signer audit", "err", err)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, err := s.audit.Write(append(blob, '\n')); err != nil {
+		log.Warn("Failed to write remote signer audit", "err", err)
+	}
+}
+
+// Close releases the connection to the remote signer and the audit log.
+func (s *RemoteSigner) Close() error {
+	s.client.Close()
+	if s.audit != nil {
+		return s.audit.Close()
+	}
+	return nil
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
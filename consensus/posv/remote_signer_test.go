@@ -0,0 +1,96 @@
+package posv
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SignerService is a remote signer holding a single key.
+type SignerService struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *SignerService) SignHash(signer common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("posv", &SignerService{key}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	dir, err := ioutil.TempDir("", "posv-remote-signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	audit := filepath.Join(dir, "audit.log")
+
+	// Signatures of the masternode key are accepted
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	signer, err := NewRemoteSigner(httpsrv.URL, account.Address, audit)
+	if err != nil {
+		t.Fatalf("failed to create remote signer: %v", err)
+	}
+	hash := crypto.Keccak256([]byte("header"))
+	sig, err := signer.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if err := verifySignature(account.Address, hash, sig); err != nil {
+		t.Errorf("invalid signature: %v", err)
+	}
+	signer.Close()
+
+	// Signatures of another key are rejected
+	account = accounts.Account{Address: crypto.PubkeyToAddress(other.PublicKey)}
+	signer, err = NewRemoteSigner(httpsrv.URL, account.Address, audit)
+	if err != nil {
+		t.Fatalf("failed to create remote signer: %v", err)
+	}
+	if _, err := signer.SignHash(account, hash); err == nil {
+		t.Errorf("signature of a foreign key accepted")
+	}
+	signer.Close()
+
+	// Both requests are audited
+	file, err := os.Open(audit)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []remoteSignerAudit
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		var entry remoteSignerAudit
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit entry count mismatch: have %d, want 2", len(entries))
+	}
+	if entries[0].Error != "" || entries[1].Error == "" {
+		t.Errorf("audit errors mismatch: have %q and %q", entries[0].Error, entries[1].Error)
+	}
+}
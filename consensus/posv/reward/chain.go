@@ -0,0 +1,57 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package reward
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/contracts"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// IsCheckpoint reports whether rewards are credited at a checkpoint block,
+// which needs a full reward period signed before it.
+func IsCheckpoint(chain consensus.ChainReader, header *types.Header) bool {
+	config := chain.Config().Posv
+	return config.FoudationWalletAddr != (common.Address{}) && header.Number.Uint64() > config.RewardCheckpoint
+}
+
+// NewInput collects the signs of the masternodes in the reward period of a
+// checkpoint from the chain, to compute its rewards with the validator
+// contract in the state before the checkpoint.
+func NewInput(ctx context.Context, c *posv.Posv, chain consensus.ChainReader, header *types.Header, validator contracts.ValidatorCaller) (*Input, error) {
+	totalSigner := new(uint64)
+	signers, err := contracts.GetRewardForCheckpoint(ctx, c, chain, header, chain.Config().Posv.RewardCheckpoint, totalSigner)
+	if err != nil {
+		if err == ctx.Err() {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get signers for reward checkpoint %d: %v", header.Number, err)
+	}
+	in := &Input{
+		Number:    header.Number.Uint64(),
+		Signers:   make(map[common.Address]uint64, len(signers)),
+		Validator: validator,
+	}
+	for signer, log := range signers {
+		in.Signers[signer] = log.Sign
+	}
+	return in, nil
+}
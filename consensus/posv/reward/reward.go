@@ -0,0 +1,165 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package reward computes the rewards of the PoSV reward checkpoints.
+//
+// The computation is a pure function of the checkpoint number, the blocks
+// signed by the masternodes in the reward period and the validator contract
+// before the checkpoint. It returns the transfers to credit without touching
+// any state, so that consensus, the APIs and dry runs share one implementation.
+package reward
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Input holds everything the rewards of a checkpoint are computed from.
+type Input struct {
+	Number      uint64                    // Checkpoint block number
+	Signers     map[common.Address]uint64 // Blocks signed by each masternode in the reward period
+	Validator   contracts.ValidatorCaller // Validator contract in the state before the checkpoint
+	Masternodes []common.Address          // Masternodes to split the reward of, all the signers if empty
+}
+
+// Signer is the reward of a masternode for the blocks it signed.
+type Signer struct {
+	Sign   uint64   `json:"sign"`
+	Reward *big.Int `json:"reward"`
+}
+
+// Transfer is an amount credited to a holder out of the reward of a masternode.
+type Transfer struct {
+	Masternode common.Address `json:"masternode"`
+	To         common.Address `json:"to"`
+	Amount     *big.Int       `json:"amount"`
+}
+
+// Result is the outcome of the rewards of a checkpoint.
+type Result struct {
+	Number      uint64                     `json:"number"`
+	ChainReward *big.Int                   `json:"chainReward"`
+	TotalSigns  uint64                     `json:"totalSigns"`
+	Signers     map[common.Address]*Signer `json:"signers"`
+	Transfers   []Transfer                 `json:"transfers"` // Sorted by masternode, then holder
+}
+
+// ChainReward returns the reward shared by the signers of a checkpoint, halved
+// after two years and quartered after six.
+func ChainReward(config *params.PosvConfig, number uint64) *big.Int {
+	reward := new(big.Int).Mul(new(big.Int).SetUint64(config.Reward), new(big.Int).SetUint64(params.Ether))
+	return inflation(reward, number, common.BlocksPerYear)
+}
+
+func inflation(chainReward *big.Int, number uint64, blockPerYear uint64) *big.Int {
+	if blockPerYear*2 <= number && number < blockPerYear*6 {
+		chainReward.Div(chainReward, new(big.Int).SetUint64(2))
+	}
+	if blockPerYear*6 <= number {
+		chainReward.Div(chainReward, new(big.Int).SetUint64(4))
+	}
+	return chainReward
+}
+
+// Compute splits the chain reward of a checkpoint among the masternodes which
+// signed blocks in the reward period, in proportion of their signs, then the
+// reward of each masternode among its owner, its voters and the foundation.
+func Compute(ctx context.Context, config *params.PosvConfig, in *Input) (*Result, error) {
+	result := &Result{
+		Number:      in.Number,
+		ChainReward: ChainReward(config, in.Number),
+		Signers:     make(map[common.Address]*Signer),
+		Transfers:   []Transfer{},
+	}
+	for _, sign := range in.Signers {
+		result.TotalSigns += sign
+	}
+	if result.TotalSigns == 0 {
+		return result, nil
+	}
+	for signer, sign := range in.Signers {
+		reward := new(big.Int).Div(result.ChainReward, new(big.Int).SetUint64(result.TotalSigns))
+		reward.Mul(reward, new(big.Int).SetUint64(sign))
+		result.Signers[signer] = &Signer{Sign: sign, Reward: reward}
+	}
+	masternodes := in.Masternodes
+	if len(masternodes) == 0 {
+		for signer := range result.Signers {
+			masternodes = append(masternodes, signer)
+		}
+	}
+	split := config.RewardSplitAt(new(big.Int).SetUint64(in.Number))
+	for _, masternode := range masternodes {
+		signer := result.Signers[masternode]
+		if signer == nil {
+			continue
+		}
+		holders, err := contracts.GetRewardBalancesRate(ctx, split, config.FoudationWalletAddr, in.Validator, masternode, signer.Reward, in.Number)
+		if err != nil {
+			return nil, err
+		}
+		for holder, amount := range holders {
+			result.Transfers = append(result.Transfers, Transfer{Masternode: masternode, To: holder, Amount: amount})
+		}
+	}
+	sort.Slice(result.Transfers, func(i, j int) bool {
+		if c := bytes.Compare(result.Transfers[i].Masternode[:], result.Transfers[j].Masternode[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(result.Transfers[i].To[:], result.Transfers[j].To[:]) < 0
+	})
+	return result, nil
+}
+
+// Holders returns the amounts credited to the holders out of the reward of a
+// masternode.
+func (r *Result) Holders(masternode common.Address) map[common.Address]*big.Int {
+	holders := make(map[common.Address]*big.Int)
+	for _, transfer := range r.Transfers {
+		if transfer.Masternode == masternode {
+			holders[transfer.To] = transfer.Amount
+		}
+	}
+	return holders
+}
+
+// Apply credits the transfers to the holders.
+func (r *Result) Apply(statedb *state.StateDB) {
+	for _, transfer := range r.Transfers {
+		statedb.AddBalance(transfer.To, transfer.Amount)
+	}
+}
+
+// Rewards returns the "signers" and the "rewards" of the holders by signer, as
+// reported by the consensus reward hook and stored in the reward folder.
+func (r *Result) Rewards() map[string]interface{} {
+	rewards := make(map[common.Address]interface{})
+	for _, transfer := range r.Transfers {
+		holders, _ := rewards[transfer.Masternode].(map[common.Address]*big.Int)
+		if holders == nil {
+			holders = make(map[common.Address]*big.Int)
+			rewards[transfer.Masternode] = holders
+		}
+		holders[transfer.To] = transfer.Amount
+	}
+	return map[string]interface{}{"signers": r.Signers, "rewards": rewards}
+}
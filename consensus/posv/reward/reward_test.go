@@ -0,0 +1,208 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package reward
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var update = flag.Bool("update", false, "update the reward fixtures in testdata")
+
+var (
+	masternode1 = common.HexToAddress("0x0000000000000000000000000000000000000101")
+	masternode2 = common.HexToAddress("0x0000000000000000000000000000000000000102")
+	owner1      = common.HexToAddress("0x0000000000000000000000000000000000000201")
+	owner2      = common.HexToAddress("0x0000000000000000000000000000000000000202")
+	voter1      = common.HexToAddress("0x0000000000000000000000000000000000000301")
+	voter2      = common.HexToAddress("0x0000000000000000000000000000000000000302")
+	foundation  = common.HexToAddress("0x0000000000000000000000000000000000000401")
+)
+
+// testValidator is a validator contract held in memory.
+type testValidator struct {
+	owners map[common.Address]common.Address
+	voters map[common.Address][]common.Address
+	caps   map[common.Address]map[common.Address]*big.Int
+}
+
+func (v *testValidator) Candidates() ([]common.Address, error) {
+	return []common.Address{masternode1, masternode2}, nil
+}
+
+func (v *testValidator) CandidateOwner(candidate common.Address) (common.Address, error) {
+	return v.owners[candidate], nil
+}
+
+func (v *testValidator) CandidateCap(candidate common.Address) (*big.Int, error) {
+	cap := new(big.Int)
+	for _, voterCap := range v.caps[candidate] {
+		cap.Add(cap, voterCap)
+	}
+	return cap, nil
+}
+
+func (v *testValidator) Voters(candidate common.Address) ([]common.Address, error) {
+	return v.voters[candidate], nil
+}
+
+func (v *testValidator) VoterCap(candidate, voter common.Address) (*big.Int, error) {
+	if cap := v.caps[candidate][voter]; cap != nil {
+		return new(big.Int).Set(cap), nil
+	}
+	return new(big.Int), nil
+}
+
+func newTestValidator() *testValidator {
+	ether := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.Ether)) }
+	return &testValidator{
+		owners: map[common.Address]common.Address{masternode1: owner1, masternode2: owner2},
+		voters: map[common.Address][]common.Address{
+			masternode1: {owner1, voter1, voter1},
+			masternode2: {owner2, voter1, voter2},
+		},
+		caps: map[common.Address]map[common.Address]*big.Int{
+			masternode1: {owner1: ether(50000), voter1: ether(30000)},
+			masternode2: {owner2: ether(50000), voter1: ether(10000), voter2: ether(3)},
+		},
+	}
+}
+
+// checkFixture compares the rewards with the fixture of the given name, or
+// rewrites the fixture with the -update flag.
+func checkFixture(t *testing.T, result *Result, name string) {
+	have, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode rewards: %v", err)
+	}
+	path := filepath.Join("testdata", name+".json")
+	if *update {
+		if err := ioutil.WriteFile(path, append(have, '\n'), 0644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(have), bytes.TrimSpace(want)) {
+		t.Errorf("rewards mismatch with %s:\nhave %s", path, have)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	signers := map[common.Address]uint64{masternode1: 3, masternode2: 1}
+	tests := []struct {
+		name        string
+		number      uint64
+		signers     map[common.Address]uint64
+		masternodes []common.Address
+		splits      []params.RewardSplit
+	}{
+		// Nobody signed, nothing is credited
+		{name: "no-signers", number: 900},
+		// Duplicate votes count twice before TIP2019
+		{name: "signers", number: 900, signers: signers},
+		// and once after
+		{name: "tip2019", number: common.TIP2019Block.Uint64(), signers: signers},
+		// The reward is halved after two years and split as configured
+		{name: "halved-split", number: 2 * common.BlocksPerYear, signers: signers, splits: []params.RewardSplit{
+			{Block: big.NewInt(1000), Masternode: 50, Voter: 40, Foundation: 10},
+		}},
+		// The holders of a single masternode are computed on request
+		{name: "masternode", number: 900, signers: signers, masternodes: []common.Address{masternode2}},
+	}
+	for _, tt := range tests {
+		config := &params.PosvConfig{Reward: 250, FoudationWalletAddr: foundation, RewardSplits: tt.splits}
+		in := &Input{Number: tt.number, Signers: tt.signers, Validator: newTestValidator(), Masternodes: tt.masternodes}
+		result, err := Compute(context.Background(), config, in)
+		if err != nil {
+			t.Fatalf("%s: failed to compute rewards: %v", tt.name, err)
+		}
+		checkFixture(t, result, tt.name)
+	}
+}
+
+func TestComputeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	config := &params.PosvConfig{Reward: 250, FoudationWalletAddr: foundation}
+	in := &Input{Number: 900, Signers: map[common.Address]uint64{masternode1: 1}, Validator: newTestValidator()}
+	if _, err := Compute(ctx, config, in); err != context.Canceled {
+		t.Fatalf("cancelled computation error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestApply(t *testing.T) {
+	config := &params.PosvConfig{Reward: 250, FoudationWalletAddr: foundation}
+	in := &Input{Number: 900, Signers: map[common.Address]uint64{masternode1: 3, masternode2: 1}, Validator: newTestValidator()}
+	result, err := Compute(context.Background(), config, in)
+	if err != nil {
+		t.Fatalf("failed to compute rewards: %v", err)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	result.Apply(statedb)
+
+	// Every holder is credited the sum of its rewards over the masternodes
+	rewards := result.Rewards()["rewards"].(map[common.Address]interface{})
+	total := new(big.Int)
+	for _, holder := range []common.Address{owner1, owner2, voter1, voter2, foundation} {
+		want := new(big.Int)
+		for _, masternode := range []common.Address{masternode1, masternode2} {
+			if amount := rewards[masternode].(map[common.Address]*big.Int)[holder]; amount != nil {
+				want.Add(want, amount)
+			}
+		}
+		if have := statedb.GetBalance(holder); have.Cmp(want) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %v", holder, have, want)
+		}
+		total.Add(total, want)
+	}
+	if total.Cmp(result.ChainReward) > 0 {
+		t.Errorf("credited more than the chain reward: have %v, want at most %v", total, result.ChainReward)
+	}
+}
+
+func TestRewardInflation(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		chainReward := new(big.Int).Mul(new(big.Int).SetUint64(250), new(big.Int).SetUint64(params.Ether))
+		chainReward = inflation(chainReward, uint64(i), 10)
+
+		halfReward := new(big.Int).Mul(new(big.Int).SetUint64(125), new(big.Int).SetUint64(params.Ether))
+		if 20 <= i && i < 60 && chainReward.Cmp(halfReward) != 0 {
+			t.Error("Fail tor calculate reward inflation for 2 -> 5 years", "chainReward", chainReward)
+		}
+
+		quarterReward := new(big.Int).Mul(new(big.Int).SetUint64(62.5*1000), new(big.Int).SetUint64(params.Finney))
+		if 60 <= i && chainReward.Cmp(quarterReward) != 0 {
+			t.Error("Fail tor calculate reward inflation above 6 years", "chainReward", chainReward)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	rewardsVerifiedMeter = metrics.NewRegisteredMeter("posv/rewards/verified", nil)
+	rewardsMismatchMeter = metrics.NewRegisteredMeter("posv/rewards/mismatch", nil)
+)
+
+// auditRewards verifies the rewards applied at a checkpoint, reporting any
+// mismatch without rejecting the block.
+func (c *Posv) auditRewards(chain consensus.ChainReader, header *types.Header, before, after *state.StateDB, rewards map[string]interface{}) {
+	if err := c.verifyRewards(chain, header, before, after, rewards); err != nil {
+		rewardsMismatchMeter.Mark(1)
+		log.Error("Checkpoint rewards mismatch", "number", header.Number, "hash", header.Hash(), "err", err)
+		return
+	}
+	rewardsVerifiedMeter.Mark(1)
+	log.Debug("Verified checkpoint rewards", "number", header.Number)
+}
+
+// verifyRewards recomputes the rewards of a checkpoint on a copy of the state
+// before they were applied, and checks that they match both the rewards the
+// hook reported and the balance changes it applied.
+func (c *Posv) verifyRewards(chain consensus.ChainReader, header *types.Header, before, after *state.StateDB, rewards map[string]interface{}) error {
+	err, recomputed := c.HookReward(chain, before.Copy(), header)
+	if err != nil {
+		return fmt.Errorf("failed to recompute rewards: %v", err)
+	}
+	have, err := json.Marshal(rewards)
+	if err != nil {
+		return err
+	}
+	want, err := json.Marshal(recomputed)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(have, want) {
+		return fmt.Errorf("recomputed rewards differ: have %s, want %s", have, want)
+	}
+	// Sum the rewards of every holder over all the signers and check the balances
+	holders := make(map[common.Address]*big.Int)
+	signers, _ := recomputed["rewards"].(map[common.Address]interface{})
+	for signer, rewards := range signers {
+		amounts, ok := rewards.(map[common.Address]*big.Int)
+		if !ok {
+			return fmt.Errorf("unexpected rewards of signer %x: %T", signer, rewards)
+		}
+		for holder, amount := range amounts {
+			if holders[holder] == nil {
+				holders[holder] = new(big.Int)
+			}
+			holders[holder].Add(holders[holder], amount)
+		}
+	}
+	for holder, amount := range holders {
+		applied := new(big.Int).Sub(after.GetBalance(holder), before.GetBalance(holder))
+		if applied.Cmp(amount) != 0 {
+			return fmt.Errorf("reward of %x mismatch: applied %v, want %v", holder, applied, amount)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package posv
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestVerifyRewards(t *testing.T) {
+	var (
+		signer = common.Address{0x01}
+		holder = common.Address{0x02}
+		header = &types.Header{Number: big.NewInt(900)}
+		reward = big.NewInt(100)
+	)
+	engine := &Posv{}
+	engine.HookReward = func(chain consensus.ChainReader, statedb *state.StateDB, header *types.Header) (error, map[string]interface{}) {
+		statedb.AddBalance(holder, reward)
+		return nil, map[string]interface{}{
+			"rewards": map[common.Address]interface{}{
+				signer: map[common.Address]*big.Int{holder: new(big.Int).Set(reward)},
+			},
+		}
+	}
+	db, _ := ethdb.NewMemDatabase()
+	before, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	before.AddBalance(holder, big.NewInt(1))
+
+	after := before.Copy()
+	_, rewards := engine.HookReward(nil, after, header)
+	if err := engine.verifyRewards(nil, header, before, after, rewards); err != nil {
+		t.Fatalf("matching rewards rejected: %v", err)
+	}
+	// Rewards applied twice to the state are detected
+	after.AddBalance(holder, reward)
+	if err := engine.verifyRewards(nil, header, before, after, rewards); err == nil {
+		t.Fatalf("mismatching balance accepted")
+	}
+	// Rewards not recomputed the same are detected
+	after = before.Copy()
+	_, rewards = engine.HookReward(nil, after, header)
+	reward = big.NewInt(101)
+	if err := engine.verifyRewards(nil, header, before, after, rewards); err == nil {
+		t.Fatalf("mismatching recomputed rewards accepted")
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package posv
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	signerLeaseDuration = 5 * time.Minute  // Time a healthy signer keeps the sealing rights without being checked
+	signerCheckInterval = 30 * time.Second // Time between two health checks of the standby signers
+	signerSealedHashes  = 1024             // Number of recent hashes signed, to tell the blocks sealed by this node
+)
+
+var (
+	signerLeasePrefix = []byte("posv-signer-lease-") // signerLeasePrefix + masternode address -> signerLease
+
+	// errNoHealthySigner is returned if none of the configured wallets can seal
+	// on behalf of the masternode.
+	errNoHealthySigner = errors.New("no healthy signer")
+
+	// errSignerFenced is returned once a block of the masternode sealed by
+	// another node was seen, sealing here too would sign conflicting blocks.
+	errSignerFenced = errors.New("masternode key sealing on another node")
+)
+
+// signerLease is the record kept in the chaindb about the wallet currently
+// holding the sealing rights of a masternode on this node.
+type signerLease struct {
+	Holder string // URL of the wallet sealing blocks
+	Expiry uint64 // Unix time until which the holder is not challenged by a primary coming back
+}
+
+// FailoverSigner seals with one of several wallets holding the same masternode
+// key, e.g. a hardware wallet backed up by a keystore file. The wallets are
+// listed by priority: sealing sticks to the lease holder while it stays healthy
+// and fails over to the next healthy wallet as soon as it errors. The lease is
+// persisted in the chaindb so that a restart resumes with the same wallet
+// instead of flapping back to a primary that just failed.
+//
+// The lease only arbitrates between the wallets attached to a single node, no
+// store is shared with other hosts. Instead the imported blocks are observed:
+// a block sealed with the masternode key but not signed by this node shows the
+// key staking elsewhere, and sealing is fenced off until the node restarts.
+type FailoverSigner struct {
+	signer common.Address
+	urls   []string
+	am     *accounts.Manager
+	db     ethdb.Database
+	since  uint64 // Head of the chain at creation, older blocks are not observed
+
+	lease  signerLease
+	sealed *lru.ARCCache // Recent hashes signed by this node
+	fenced common.Hash   // Block of the masternode sealed by another node, if any
+	lock   sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFailoverSigner creates a signer sealing for the given masternode with the
+// wallets at urls, the first being the primary one, on top of the chain head
+// with the given number.
+func NewFailoverSigner(db ethdb.Database, am *accounts.Manager, signer common.Address, urls []string, head uint64) *FailoverSigner {
+	sealed, _ := lru.NewARC(signerSealedHashes)
+	s := &FailoverSigner{
+		signer: signer,
+		urls:   urls,
+		am:     am,
+		db:     db,
+		since:  head,
+		sealed: sealed,
+	}
+	if blob, err := db.Get(append(signerLeasePrefix, signer[:]...)); err == nil {
+		if err := rlp.DecodeBytes(blob, &s.lease); err != nil {
+			log.Warn("Failed to decode signer lease", "err", err)
+		}
+	}
+	// Forget a lease held by a wallet which is not configured anymore
+	for _, url := range urls {
+		if url == s.lease.Holder {
+			return s
+		}
+	}
+	s.lease = signerLease{}
+	return s
+}
+
+// Start runs the health checks of the signer wallets in the background.
+func (s *FailoverSigner) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.quit != nil {
+		return
+	}
+	s.quit = make(chan struct{})
+	s.wg.Add(1)
+	go s.loop(s.quit)
+}
+
+// Stop terminates the background health checks.
+func (s *FailoverSigner) Stop() {
+	s.lock.Lock()
+	quit := s.quit
+	s.quit = nil
+	s.lock.Unlock()
+
+	if quit != nil {
+		close(quit)
+		s.wg.Wait()
+	}
+}
+
+// Holder returns the URL of the wallet currently holding the sealing rights.
+func (s *FailoverSigner) Holder() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.lease.Holder
+}
+
+// SignHash implements clique.SignerFn, signing with the lease holder and
+// failing over to the next healthy wallet if it cannot produce a signature
+// recoverable to the masternode.
+func (s *FailoverSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if account.Address != s.signer {
+		return nil, fmt.Errorf("failover signer is configured for %s", s.signer.Hex())
+	}
+	if s.fenced != (common.Hash{}) {
+		return nil, errSignerFenced
+	}
+	tried := make(map[string]bool)
+	for {
+		url, err := s.acquire(tried)
+		if err != nil {
+			return nil, err
+		}
+		tried[url] = true
+
+		sig, err := s.sign(url, hash)
+		if err == nil {
+			s.sealed.Add(common.BytesToHash(hash), true)
+			return sig, nil
+		}
+		log.Warn("Signer failed to seal, failing over", "wallet", url, "err", err)
+	}
+}
+
+// Observe checks a block imported in the chain, fencing off the sealing if it
+// was sealed with the masternode key but not signed by this node.
+func (s *FailoverSigner) Observe(header *types.Header) {
+	if header.Number.Uint64() <= s.since || len(header.Extra) < extraSeal {
+		return
+	}
+	hash := sigHash(header)
+	if verifySignature(s.signer, hash.Bytes(), header.Extra[len(header.Extra)-extraSeal:]) != nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.fenced != (common.Hash{}) || s.sealed.Contains(hash) {
+		return
+	}
+	s.fenced = header.Hash()
+	log.Error("Masternode key sealing on another node, sealing stopped", "number", header.Number, "hash", header.Hash())
+}
+
+// acquire returns the wallet to seal with: the lease holder if it is healthy
+// and was not tried yet, otherwise the healthy wallet of highest priority, to
+// which the lease is then transferred.
+func (s *FailoverSigner) acquire(tried map[string]bool) (string, error) {
+	if holder := s.lease.Holder; holder != "" && !tried[holder] && s.healthy(holder) == nil {
+		return holder, nil
+	}
+	for _, url := range s.urls {
+		if tried[url] || s.healthy(url) != nil {
+			continue
+		}
+		s.grant(url)
+		return url, nil
+	}
+	return "", errNoHealthySigner
+}
+
+// grant transfers the lease to the given wallet and persists it.
+func (s *FailoverSigner) grant(url string) {
+	if s.lease.Holder != url {
+		log.Info("Signer lease granted", "wallet", url, "previous", s.lease.Holder)
+	}
+	s.lease = signerLease{Holder: url, Expiry: uint64(time.Now().Add(signerLeaseDuration).Unix())}
+	blob, err := rlp.EncodeToBytes(&s.lease)
+	if err != nil {
+		log.Crit("Failed to encode signer lease", "err", err)
+	}
+	if err := s.db.Put(append(signerLeasePrefix, s.signer[:]...), blob); err != nil {
+		log.Warn("Failed to store signer lease", "err", err)
+	}
+}
+
+// healthy checks that a wallet is reachable and holds the masternode key.
+func (s *FailoverSigner) healthy(url string) error {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return err
+	}
+	if _, err := wallet.Status(); err != nil {
+		return err
+	}
+	if !wallet.Contains(accounts.Account{Address: s.signer}) {
+		return accounts.ErrUnknownAccount
+	}
+	return nil
+}
+
+// sign requests a signature from a wallet and verifies it was made by the
+// masternode key.
+func (s *FailoverSigner) sign(url string, hash []byte) ([]byte, error) {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := wallet.SignHash(accounts.Account{Address: s.signer}, hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(s.signer, hash, sig); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// verifySignature checks that sig is a signature of hash by the given signer.
+func verifySignature(signer common.Address, hash []byte, sig []byte) error {
+	pubkey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return err
+	}
+	var recovered common.Address
+	copy(recovered[:], crypto.Keccak256(pubkey[1:])[12:])
+	if recovered != signer {
+		return fmt.Errorf("signature made by %s", recovered.Hex())
+	}
+	return nil
+}
+
+// loop periodically renews the lease of a healthy holder, and hands it back
+// to a wallet of higher priority once the lease expires.
+func (s *FailoverSigner) loop(quit chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(signerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// check runs a health check of the signer wallets, updating the lease.
+func (s *FailoverSigner) check() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	holder := s.lease.Holder
+	if holder != "" && s.healthy(holder) != nil {
+		log.Warn("Signer unhealthy, failing over", "wallet", holder)
+		s.lease.Holder = ""
+	}
+	expired := uint64(time.Now().Unix()) >= s.lease.Expiry
+	for _, url := range s.urls {
+		if url == s.lease.Holder {
+			if expired {
+				s.grant(url)
+			}
+			return
+		}
+		if (expired || s.lease.Holder == "") && s.healthy(url) == nil {
+			s.grant(url)
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package posv
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that sealing fails over to the backup wallet when the primary cannot
+// sign, and that the lease survives a restart.
+func TestFailoverSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		stores []*keystore.KeyStore
+		urls   []string
+	)
+	for i := 0; i < 2; i++ {
+		dir, err := ioutil.TempDir("", "posv-signer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+		if _, err := ks.ImportECDSA(key, ""); err != nil {
+			t.Fatalf("failed to import key: %v", err)
+		}
+		stores = append(stores, ks)
+		urls = append(urls, ks.Wallets()[0].URL().String())
+	}
+	am := accounts.NewManager(stores[0], stores[1])
+	defer am.Close()
+
+	db, _ := ethdb.NewMemDatabase()
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	hash := crypto.Keccak256([]byte("header"))
+
+	// Both keystores are locked, nobody can seal
+	signer := NewFailoverSigner(db, am, account.Address, urls, 0)
+	if _, err := signer.SignHash(account, hash); err != errNoHealthySigner {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoHealthySigner)
+	}
+	// Only the backup is unlocked, sealing must fail over to it
+	if err := stores[1].Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock backup: %v", err)
+	}
+	sig, err := signer.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign with backup: %v", err)
+	}
+	if pubkey, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pubkey) != account.Address {
+		t.Fatalf("signature not made by the masternode key: %v", err)
+	}
+	if holder := signer.Holder(); holder != urls[1] {
+		t.Errorf("lease holder mismatch: have %s, want %s", holder, urls[1])
+	}
+	// A restarted signer resumes with the backup even if the primary is back
+	if err := stores[0].Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock primary: %v", err)
+	}
+	if holder := NewFailoverSigner(db, am, account.Address, urls, 0).Holder(); holder != urls[1] {
+		t.Errorf("restored lease holder mismatch: have %s, want %s", holder, urls[1])
+	}
+	// Unless the backup is not configured anymore
+	if holder := NewFailoverSigner(db, am, account.Address, urls[:1], 0).Holder(); holder != "" {
+		t.Errorf("stale lease restored for %s", holder)
+	}
+}
+
+// Tests that sealing is fenced off once a block of the masternode signed by
+// another node is imported.
+func TestFailoverSignerFenced(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	dir, err := ioutil.TempDir("", "posv-signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(key, "")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock key: %v", err)
+	}
+	am := accounts.NewManager(ks)
+	defer am.Close()
+
+	db, _ := ethdb.NewMemDatabase()
+	signer := NewFailoverSigner(db, am, account.Address, []string{ks.Wallets()[0].URL().String()}, 1)
+
+	// seal signs a header with the failover signer, or with the key of another
+	// node if given
+	seal := func(number int64, key *ecdsa.PrivateKey) *types.Header {
+		header := &types.Header{Number: big.NewInt(number), Extra: make([]byte, extraVanity+extraSeal)}
+		var (
+			sig []byte
+			err error
+		)
+		if key == nil {
+			sig, err = signer.SignHash(account, sigHash(header).Bytes())
+		} else {
+			sig, err = crypto.Sign(sigHash(header).Bytes(), key)
+		}
+		if err != nil {
+			t.Fatalf("failed to seal block %d: %v", number, err)
+		}
+		copy(header.Extra[extraVanity:], sig)
+		return header
+	}
+	tests := []struct {
+		name   string
+		header *types.Header
+		fenced bool
+	}{
+		{"sealed before the signer started", seal(1, key), false},
+		{"sealed by this node", seal(2, nil), false},
+		{"sealed by another masternode", seal(3, other), false},
+		{"sealed by the masternode elsewhere", seal(3, key), true},
+	}
+	for _, test := range tests {
+		signer.Observe(test.header)
+		if _, err := signer.SignHash(account, crypto.Keccak256([]byte(test.name))); (err == errSignerFenced) != test.fenced {
+			t.Fatalf("%s: error mismatch: have %v, fenced %v", test.name, err, test.fenced)
+		}
+	}
+}
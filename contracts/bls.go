@@ -0,0 +1,143 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	blsRegisterKeyGasLimit    = 250000 // Gas limit of the transactions registering the BLS key of a masternode
+	blsSignCheckpointGasLimit = 200000 // Gas limit of the transactions recording a checkpoint signature
+)
+
+var (
+	// blsKeyMessage is the hash signed by the account of a masternode to derive
+	// its BLS key from.
+	blsKeyMessage = crypto.Keccak256([]byte("Tomochain BLS masternode key"))
+
+	blsRegisterKeyMethod    = crypto.Keccak256([]byte("registerKey(bytes32[4],bytes32[2])"))[:4]
+	blsSignCheckpointMethod = crypto.Keccak256([]byte("signCheckpoint(uint256,bytes32[2])"))[:4]
+)
+
+// blsSubmitStatus tracks the BLS registry transactions sent by the masternode
+// run by this node, to send them again only when they don't make it.
+type blsSubmitStatus struct {
+	masternode   common.Address
+	keySentAt    uint64 // Block the key registration was last sent at
+	gap          uint64 // Gap block last signed
+	signedSentAt uint64 // Block the signature of the gap block was last sent at
+}
+
+var (
+	blsMu     sync.Mutex
+	blsStatus blsSubmitStatus
+)
+
+// BLSKey derives the BLS key of a masternode from the signature of a fixed
+// message by its account. Signatures being deterministic, the key needs no
+// storage of its own and is the same on any node running the account.
+func BLSKey(account accounts.Account, signFn clique.SignerFn) (*bls.SecretKey, error) {
+	seed, err := signFn(account, blsKeyMessage)
+	if err != nil {
+		return nil, err
+	}
+	return bls.SecretKeyFromSeed(seed)
+}
+
+// submitBLS registers the BLS key of the masternode once the BLS fork is active,
+// then signs the gap block of each epoch for its checkpoint, sending again the
+// transactions which don't make it into the chain.
+func submitBLS(chainConfig *params.ChainConfig, pool *core.TxPool, signer *txSigner, chain *core.BlockChain, block *types.Block, statedb *state.StateDB) error {
+	if !chainConfig.IsBLS(block.Number()) || statedb == nil {
+		return nil
+	}
+	var (
+		account    = signer.account
+		number     = block.NumberU64()
+		registered = state.GetBLSPublicKey(statedb, account.Address) != nil
+		gap        = vm.BLSCheckpointGap(chainConfig.Posv, number)
+		signed, _  = state.GetBLSCheckpointSignature(statedb, account.Address)
+		signing    = gap != 0 && gap <= number && number%chainConfig.Posv.Epoch != 0 && signed != gap
+	)
+	if registered && !signing {
+		return nil
+	}
+	blsMu.Lock()
+	defer blsMu.Unlock()
+
+	status := &blsStatus
+	if status.masternode != account.Address {
+		*status = blsSubmitStatus{masternode: account.Address}
+	}
+	if !registered && status.keySentAt != 0 && number < status.keySentAt+randomizeRetryInterval {
+		return nil
+	}
+	if registered && status.gap == gap && number < status.signedSentAt+randomizeRetryInterval {
+		return nil
+	}
+	key, err := BLSKey(account, signer.signFn)
+	if err != nil {
+		log.Error("Fail to derive BLS key", "error", err)
+		return err
+	}
+	var data []byte
+	gas := uint64(blsRegisterKeyGasLimit)
+	if !registered {
+		// The checkpoints can only be signed once the key is in
+		data = append(append(common.CopyBytes(blsRegisterKeyMethod), key.PublicKey().Marshal()...), key.ProvePossession().Marshal()...)
+		status.keySentAt = number
+	} else {
+		header := block.Header()
+		if gap != number {
+			if chain == nil {
+				return nil
+			}
+			if header = chain.GetHeaderByNumber(gap); header == nil {
+				return nil
+			}
+		}
+		data = append(common.CopyBytes(blsSignCheckpointMethod), common.LeftPadBytes(new(big.Int).SetUint64(gap).Bytes(), 32)...)
+		data = append(data, key.Sign(header.Hash().Bytes()).Marshal()...)
+		gas = blsSignCheckpointGasLimit
+		status.gap, status.signedSentAt = gap, number
+	}
+	nonce := pool.State().GetNonce(account.Address)
+	tx := types.NewTransaction(nonce, common.HexToAddress(common.BLSRegistry), big.NewInt(0), gas, big.NewInt(0), data)
+	txSigned, err := signer.SignTx(tx, chainConfig.ChainId)
+	if err != nil {
+		log.Error("Fail to create tx BLS", "error", err)
+		return err
+	}
+	if err := pool.AddLocal(txSigned); err != nil {
+		log.Error("Fail to add tx BLS to local pool.", "error", err, "number", number, "hash", block.Hash().Hex(), "from", account.Address, "nonce", nonce)
+		return err
+	}
+	return nil
+}
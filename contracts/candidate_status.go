@@ -0,0 +1,116 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// States of a masternode candidate in the validator contract.
+const (
+	CandidateStateNone       = "none"       // Never proposed
+	CandidateStateProposed   = "proposed"   // Proposed, not in the masternode set
+	CandidateStateMasternode = "masternode" // Proposed and sealing blocks
+	CandidateStateResigned   = "resigned"   // Resigned, its stake refunded after a delay
+)
+
+// CandidateWithdrawal is a stake refunded to the owner of a candidate, which
+// can be withdrawn from a block on.
+type CandidateWithdrawal struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"` // Block the stake is unlocked at
+	Index       hexutil.Uint64 `json:"index"`       // Index to pass to the withdraw function of the contract
+	Cap         *hexutil.Big   `json:"cap"`
+	Unlocked    bool           `json:"unlocked"`
+	UnlockTime  hexutil.Uint64 `json:"unlockTime"` // Estimated time the stake is unlocked at
+}
+
+// CandidateStatus is the lifecycle status of a masternode candidate in the
+// validator contract, with the actions its owner is expected to take.
+type CandidateStatus struct {
+	Candidate   common.Address         `json:"candidate"`
+	Owner       common.Address         `json:"owner"`
+	Number      hexutil.Uint64         `json:"number"`
+	State       string                 `json:"state"`
+	Cap         *hexutil.Big           `json:"cap"`
+	Voters      hexutil.Uint64         `json:"voters"`
+	Withdrawals []*CandidateWithdrawal `json:"withdrawals"`
+	Actions     []string               `json:"actions"`
+}
+
+// GetCandidateStatus returns the status of a candidate in the validator
+// contract at the given header, masternode telling whether the candidate is in
+// the masternode set and period being the block period used to estimate the
+// unlock times.
+func GetCandidateStatus(statedb state.StorageReader, header *types.Header, candidate common.Address, masternode bool, period uint64) *CandidateStatus {
+	number := header.Number.Uint64()
+	status := &CandidateStatus{
+		Candidate:   candidate,
+		Owner:       state.GetCandidateOwner(statedb, candidate),
+		Number:      hexutil.Uint64(number),
+		Cap:         (*hexutil.Big)(state.GetCandidateCap(statedb, candidate)),
+		Voters:      hexutil.Uint64(state.GetVoterCount(statedb, candidate)),
+		Withdrawals: []*CandidateWithdrawal{},
+		Actions:     []string{},
+	}
+	switch {
+	case state.IsCandidate(statedb, candidate) && masternode:
+		status.State = CandidateStateMasternode
+	case state.IsCandidate(statedb, candidate):
+		status.State = CandidateStateProposed
+	case status.Owner != (common.Address{}):
+		status.State = CandidateStateResigned
+	default:
+		status.State = CandidateStateNone
+		status.Actions = append(status.Actions, fmt.Sprintf("propose the candidate with a stake of at least %v", state.GetMinCandidateCap(statedb)))
+		return status
+	}
+	if status.State == CandidateStateProposed {
+		status.Actions = append(status.Actions, "gather votes to enter the masternode set")
+	}
+	// The stakes refunded to the owner, the withdrawn ones being zeroed. Stakes
+	// refunded at the same block are summed up and withdrawn together.
+	seen := make(map[uint64]bool)
+	for i, blockNumber := range state.GetWithdrawBlockNumbers(statedb, status.Owner) {
+		if blockNumber.Sign() == 0 || seen[blockNumber.Uint64()] {
+			continue
+		}
+		seen[blockNumber.Uint64()] = true
+		cap := state.GetWithdrawCap(statedb, status.Owner, blockNumber)
+		if cap.Sign() == 0 {
+			continue
+		}
+		withdrawal := &CandidateWithdrawal{
+			BlockNumber: hexutil.Uint64(blockNumber.Uint64()),
+			Index:       hexutil.Uint64(i),
+			Cap:         (*hexutil.Big)(cap),
+			Unlocked:    blockNumber.Uint64() <= number,
+			UnlockTime:  hexutil.Uint64(header.Time.Uint64()),
+		}
+		if !withdrawal.Unlocked {
+			withdrawal.UnlockTime += hexutil.Uint64((blockNumber.Uint64() - number) * period)
+		}
+		status.Withdrawals = append(status.Withdrawals, withdrawal)
+		if withdrawal.Unlocked {
+			status.Actions = append(status.Actions, fmt.Sprintf("withdraw %v at block %d with index %d", cap, blockNumber, i))
+		}
+	}
+	return status
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	validatorContract "github.com/ethereum/go-ethereum/contracts/validator/contract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// contractStorage reads the storage of a contract deployed on a simulated
+// backend, in place of the system contract.
+type contractStorage struct {
+	backend  *backends.SimulatedBackend
+	contract common.Address
+}
+
+func (s *contractStorage) GetState(_ common.Address, key common.Hash) common.Hash {
+	val, _ := s.backend.StorageAt(context.Background(), s.contract, key, nil)
+	return common.BytesToHash(val)
+}
+
+// Tests the lifecycle of a candidate read from the storage of the validator
+// contract, from its proposal to the withdrawal of its stake.
+func TestCandidateStatus(t *testing.T) {
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{
+		acc1Addr: {Balance: big.NewInt(10000000)},
+		acc4Addr: {Balance: big.NewInt(10000000)},
+	})
+	const withdrawDelay = 5
+	validatorAddr, _, validator, err := validatorContract.DeployTomoValidator(
+		bind.NewKeyedTransactor(acc1Key),
+		backend,
+		[]common.Address{acc1Addr},
+		[]*big.Int{big.NewInt(50000)},
+		acc1Addr,
+		big.NewInt(50000),
+		big.NewInt(1),
+		big.NewInt(99),
+		big.NewInt(withdrawDelay),
+		big.NewInt(withdrawDelay),
+	)
+	if err != nil {
+		t.Fatalf("can't deploy validator contract: %v", err)
+	}
+	backend.Commit()
+	number := int64(1)
+
+	storage := &contractStorage{backend, validatorAddr}
+	status := func(masternode bool) *CandidateStatus {
+		header := &types.Header{Number: big.NewInt(number), Time: big.NewInt(1000)}
+		return GetCandidateStatus(storage, header, acc3Addr, masternode, 2)
+	}
+	if s := status(false); s.State != CandidateStateNone || len(s.Actions) != 1 {
+		t.Fatalf("unproposed candidate: state %s, actions %v", s.State, s.Actions)
+	}
+	// Propose the candidate, owned by another account
+	opts := bind.NewKeyedTransactor(acc4Key)
+	opts.Value = big.NewInt(50000)
+	if _, err := validator.Propose(opts, acc3Addr); err != nil {
+		t.Fatalf("can't propose candidate: %v", err)
+	}
+	backend.Commit()
+	number++
+
+	s := status(false)
+	if s.State != CandidateStateProposed || s.Owner != acc4Addr || s.Cap.ToInt().Cmp(big.NewInt(50000)) != 0 || s.Voters != 1 {
+		t.Fatalf("proposed candidate mismatch: %+v", s)
+	}
+	if s := status(true); s.State != CandidateStateMasternode || len(s.Actions) != 0 {
+		t.Fatalf("masternode candidate: state %s, actions %v", s.State, s.Actions)
+	}
+	// Resign, the stake is locked until the delay passes
+	opts.Value = nil
+	if _, err := validator.Resign(opts, acc3Addr); err != nil {
+		t.Fatalf("can't resign candidate: %v", err)
+	}
+	backend.Commit()
+	number++
+
+	s = status(false)
+	if s.State != CandidateStateResigned || len(s.Withdrawals) != 1 || len(s.Actions) != 0 {
+		t.Fatalf("resigned candidate mismatch: %+v", s)
+	}
+	withdrawal := s.Withdrawals[0]
+	if withdrawal.BlockNumber != hexutil.Uint64(number+withdrawDelay) || withdrawal.Index != 0 || withdrawal.Cap.ToInt().Cmp(big.NewInt(50000)) != 0 {
+		t.Fatalf("withdrawal mismatch: %+v", withdrawal)
+	}
+	if withdrawal.Unlocked || withdrawal.UnlockTime != hexutil.Uint64(1000+withdrawDelay*2) {
+		t.Fatalf("locked withdrawal: unlocked %v, unlock time %d", withdrawal.Unlocked, withdrawal.UnlockTime)
+	}
+	// Past the delay, the owner is asked to withdraw
+	number += withdrawDelay
+	if s := status(false); len(s.Withdrawals) != 1 || !s.Withdrawals[0].Unlocked || len(s.Actions) != 1 {
+		t.Fatalf("unlocked withdrawal: %+v, actions %v", s.Withdrawals, s.Actions)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	randomizeRetryInterval = 10 // Blocks to wait for a randomize transaction to be included before sending it again
+	randomizeMaxRetries    = 3  // Times a randomize transaction is sent again within an epoch
+)
+
+// Phases of the randomize protocol within an epoch.
+const (
+	RandomizePhaseIdle    = "idle"    // Before the secrets are collected
+	RandomizePhaseSecret  = "secret"  // Masternodes commit their encrypted secret
+	RandomizePhaseOpening = "opening" // Masternodes reveal the key of their secret
+)
+
+// randomizeKeyName is the key of the database entry holding the key encrypting
+// the secret of the current epoch.
+var randomizeKeyName = []byte("randomizeKey")
+
+// RandomizeStatus is the commit/reveal status of the masternode run by this
+// node in the randomize contract for an epoch.
+type RandomizeStatus struct {
+	Masternode      common.Address `json:"masternode"`
+	Epoch           hexutil.Uint64 `json:"epoch"`
+	Phase           string         `json:"phase"`
+	SecretSentAt    hexutil.Uint64 `json:"secretSentAt"`    // Block the secret was last sent at, zero if it was not
+	SecretRetries   int            `json:"secretRetries"`   // Times the secret was sent again
+	SecretCommitted bool           `json:"secretCommitted"` // The secret of the epoch is stored in the contract
+	OpeningSentAt   hexutil.Uint64 `json:"openingSentAt"`   // Block the opening was last sent at, zero if it was not
+	OpeningRetries  int            `json:"openingRetries"`  // Times the opening was sent again
+	OpeningRevealed bool           `json:"openingRevealed"` // The opening of the epoch is stored in the contract
+	LastError       string         `json:"lastError,omitempty"`
+}
+
+var (
+	randomizeMu     sync.RWMutex
+	randomizeStatus RandomizeStatus // Status of the epoch being processed by CreateTransactionSign
+)
+
+// RandomizePhase returns the phase of the randomize protocol of a block.
+func RandomizePhase(number uint64, epoch uint64) string {
+	checkNumber := number % epoch
+	switch {
+	case checkNumber > 0 && common.EpocBlockSecret <= checkNumber && common.EpocBlockOpening > checkNumber:
+		return RandomizePhaseSecret
+	case checkNumber > 0 && common.EpocBlockOpening <= checkNumber && common.EpocBlockRandomize >= checkNumber:
+		return RandomizePhaseOpening
+	}
+	return RandomizePhaseIdle
+}
+
+// RandomizeCommitted reports whether the secret encrypted with the given key
+// and the key itself are stored in the randomize contract for a masternode.
+func RandomizeCommitted(statedb *state.StateDB, masternode common.Address, randomizeKey []byte) (secret bool, opening bool) {
+	if len(randomizeKey) == 0 {
+		return false, false
+	}
+	for _, s := range state.GetSecret(statedb, masternode) {
+		if isInt(Decrypt(randomizeKey, string(bytes.TrimLeft(s[:], "\x00")))) {
+			secret = true
+			break
+		}
+	}
+	stored := state.GetOpening(statedb, masternode)
+	return secret, bytes.Equal(stored[:], common.RightPadBytes(randomizeKey, 32))
+}
+
+// GetRandomizeStatus returns the commit/reveal status of a masternode for the
+// epoch of the given block.
+func GetRandomizeStatus(chainConfig *params.ChainConfig, chainDb ethdb.Database, statedb *state.StateDB, number uint64, masternode common.Address) *RandomizeStatus {
+	epoch := chainConfig.Posv.Epoch
+	randomizeMu.RLock()
+	status := randomizeStatus
+	randomizeMu.RUnlock()
+
+	if status.Masternode != masternode || uint64(status.Epoch) != number/epoch {
+		status = RandomizeStatus{Masternode: masternode, Epoch: hexutil.Uint64(number / epoch)}
+	}
+	status.Phase = RandomizePhase(number, epoch)
+	if randomizeKey, err := chainDb.Get(randomizeKeyName); err == nil {
+		secret, opening := RandomizeCommitted(statedb, masternode, randomizeKey)
+		status.SecretCommitted = status.SecretCommitted || secret
+		status.OpeningRevealed = status.OpeningRevealed || opening
+	}
+	return &status
+}
+
+// needsRetry reports whether a randomize transaction sent at the given block
+// and not included yet should be sent again.
+func needsRetry(sentAt uint64, retries int, number uint64) bool {
+	if sentAt == 0 {
+		return true
+	}
+	return retries < randomizeMaxRetries && number >= sentAt+randomizeRetryInterval
+}
+
+// submitRandomize sends the secret and the opening of the masternode to the
+// randomize contract in their phase of the epoch, sending them again when
+// they do not make it into the chain.
+func submitRandomize(chainConfig *params.ChainConfig, pool *core.TxPool, signer *txSigner, block *types.Block, chainDb ethdb.Database, statedb *state.StateDB, nonce uint64) error {
+	account := signer.account
+	epoch := chainConfig.Posv.Epoch
+	blockNumber := block.Number().Uint64()
+
+	randomizeMu.Lock()
+	defer randomizeMu.Unlock()
+
+	status := &randomizeStatus
+	if status.Masternode != account.Address || uint64(status.Epoch) != blockNumber/epoch {
+		*status = RandomizeStatus{Masternode: account.Address, Epoch: hexutil.Uint64(blockNumber / epoch)}
+	}
+	status.Phase = RandomizePhase(blockNumber, epoch)
+
+	randomizeKeyValue, _ := chainDb.Get(randomizeKeyName)
+	exist := len(randomizeKeyValue) > 0
+	if exist && statedb != nil {
+		secret, opening := RandomizeCommitted(statedb, account.Address, randomizeKeyValue)
+		status.SecretCommitted = status.SecretCommitted || secret
+		status.OpeningRevealed = status.OpeningRevealed || opening
+	}
+	send := func(tx *types.Transaction, kind string) error {
+		txSigned, err := signer.SignTx(tx, chainConfig.ChainId)
+		if err != nil {
+			log.Error("Fail to create tx "+kind, "error", err)
+			status.LastError = err.Error()
+			return err
+		}
+		// Add tx signed to local tx pool.
+		if err := pool.AddLocal(txSigned); err != nil {
+			log.Error("Fail to add tx "+kind+" to local pool.", "error", err, "number", blockNumber, "hash", block.Hash().Hex(), "from", account.Address, "nonce", nonce)
+			status.LastError = err.Error()
+			return err
+		}
+		return nil
+	}
+
+	switch status.Phase {
+	case RandomizePhaseSecret:
+		if status.SecretCommitted || !needsRetry(uint64(status.SecretSentAt), status.SecretRetries, blockNumber) {
+			return nil
+		}
+		// Generate random private key and save into chaindb, the key of a
+		// secret not committed yet is reused.
+		if !exist {
+			randomizeKeyValue = RandStringByte(32)
+		}
+		tx, err := BuildTxSecretRandomize(nonce, common.HexToAddress(common.RandomizeSMC), epoch, randomizeKeyValue)
+		if err != nil {
+			log.Error("Fail to get tx secret for randomize", "error", err)
+			return err
+		}
+		if err := send(tx, "secret"); err != nil {
+			return err
+		}
+		if !exist {
+			// Put randomize key into chainDb.
+			chainDb.Put(randomizeKeyName, randomizeKeyValue)
+		}
+		if status.SecretSentAt > 0 {
+			status.SecretRetries++
+			log.Warn("Secret not committed to randomize contract, sending it again", "number", blockNumber, "retries", status.SecretRetries)
+		}
+		status.SecretSentAt = hexutil.Uint64(blockNumber)
+
+	case RandomizePhaseOpening:
+		if !exist {
+			return nil
+		}
+		if status.OpeningRevealed {
+			// Clear randomize key in state db.
+			chainDb.Delete(randomizeKeyName)
+			return nil
+		}
+		if !needsRetry(uint64(status.OpeningSentAt), status.OpeningRetries, blockNumber) {
+			return nil
+		}
+		tx, err := BuildTxOpeningRandomize(nonce, common.HexToAddress(common.RandomizeSMC), randomizeKeyValue)
+		if err != nil {
+			log.Error("Fail to get tx opening for randomize", "error", err)
+			return err
+		}
+		if err := send(tx, "opening"); err != nil {
+			return err
+		}
+		if status.OpeningSentAt > 0 {
+			status.OpeningRetries++
+			log.Warn("Opening not revealed to randomize contract, sending it again", "number", blockNumber, "retries", status.OpeningRetries)
+		}
+		status.OpeningSentAt = hexutil.Uint64(blockNumber)
+
+	default:
+		if exist {
+			// The opening of the previous epoch never made it, drop its key
+			// for a new secret to be committed in this epoch.
+			log.Warn("Dropping randomize key of a past epoch", "number", blockNumber)
+			chainDb.Delete(randomizeKeyName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestRandomizePhase(t *testing.T) {
+	tests := []struct {
+		number uint64
+		phase  string
+	}{
+		{900, RandomizePhaseIdle},
+		{1799, RandomizePhaseOpening},
+		{1000, RandomizePhaseIdle},
+		{1700, RandomizePhaseSecret},
+		{1749, RandomizePhaseSecret},
+		{1750, RandomizePhaseOpening},
+	}
+	for _, tt := range tests {
+		if phase := RandomizePhase(tt.number, 900); phase != tt.phase {
+			t.Errorf("block %d: phase mismatch: have %s, want %s", tt.number, phase, tt.phase)
+		}
+	}
+}
+
+func TestRandomizeCommitted(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	contract := common.HexToAddress(common.RandomizeSMC)
+
+	key, otherKey := RandStringByte(32), RandStringByte(32)
+	if secret, opening := RandomizeCommitted(statedb, acc1Addr, key); secret || opening {
+		t.Fatalf("empty contract reported as committed: secret %v, opening %v", secret, opening)
+	}
+	// Store a secret encrypted with the key, as setSecret does
+	locSecret := common.BigToHash(state.GetLocMappingAtKey(acc1Addr.Hash(), 0))
+	statedb.SetState(contract, locSecret, common.BigToHash(big.NewInt(1)))
+	encrypted := common.LeftPadBytes([]byte(Encrypt(key, "42")), 32)
+	statedb.SetState(contract, state.GetLocDynamicArrAtElement(locSecret, 0, 1), common.BytesToHash(encrypted))
+
+	if secret, opening := RandomizeCommitted(statedb, acc1Addr, key); !secret || opening {
+		t.Errorf("committed secret mismatch: secret %v, opening %v", secret, opening)
+	}
+	if secret, _ := RandomizeCommitted(statedb, acc1Addr, otherKey); secret {
+		t.Errorf("secret of another key reported as committed")
+	}
+	// Reveal the key, as setOpening does
+	locOpening := common.BigToHash(state.GetLocMappingAtKey(acc1Addr.Hash(), 1))
+	statedb.SetState(contract, locOpening, common.BytesToHash(key))
+	if secret, opening := RandomizeCommitted(statedb, acc1Addr, key); !secret || !opening {
+		t.Errorf("revealed opening mismatch: secret %v, opening %v", secret, opening)
+	}
+	if random, err := GetRandomizeFromState(statedb, acc1Addr); err != nil || random != 42 {
+		t.Errorf("randomize mismatch: have %d (%v), want 42", random, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the reward of the voters is split by their caps, and that the
+// calculation stops once the deadline of its context is exceeded.
+func TestRewardBalancesRateDeadline(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	state.AddVote(statedb, acc1Addr, acc2Addr, big.NewInt(100))
+	state.AddVote(statedb, acc1Addr, acc3Addr, big.NewInt(300))
+
+	split := params.RewardSplit{Masternode: 40, Voter: 50, Foundation: 10}
+	rewards, err := GetRewardBalancesRate(context.Background(), split, acc4Addr, NewStorageValidatorCaller(statedb), acc1Addr, big.NewInt(1000), 1)
+	if err != nil {
+		t.Fatalf("failed to calculate rewards: %v", err)
+	}
+	if rewards[acc2Addr].Int64() != 125 || rewards[acc3Addr].Int64() != 375 {
+		t.Errorf("voter rewards mismatch: have %v and %v, want 125 and 375", rewards[acc2Addr], rewards[acc3Addr])
+	}
+	if rewards[acc4Addr].Int64() != 100 {
+		t.Errorf("foundation reward mismatch: have %v, want 100", rewards[acc4Addr])
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := GetRewardBalancesRate(ctx, split, acc4Addr, NewStorageValidatorCaller(statedb), acc1Addr, big.NewInt(1000), 1); err != context.DeadlineExceeded {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -17,11 +17,13 @@ package contracts
 
 import (
 	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	cryptoRand "crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -35,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/posv"
 	"github.com/ethereum/go-ethereum/contracts/blocksigner/contract"
 	randomizeContract "github.com/ethereum/go-ethereum/contracts/randomize/contract"
@@ -59,25 +62,44 @@ type rewardLog struct {
 
 var TxSignMu sync.RWMutex
 
+// errNoSigner is returned if the system transactions are created before the
+// engine is authorized to seal.
+var errNoSigner = errors.New("no authorized signer")
+
+// txSigner signs the system transactions of a masternode with the function
+// sealing its blocks, be it a local wallet, the failover or the remote signer.
+type txSigner struct {
+	account accounts.Account
+	signFn  clique.SignerFn
+}
+
+// SignTx signs a transaction with the sealing function of the masternode.
+func (s *txSigner) SignTx(tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	signer := types.NewEIP155Signer(chainId)
+	sig, err := s.signFn(s.account, signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
 // Send tx sign for block number to smart contract blockSigner.
-func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, manager *accounts.Manager, block *types.Block, chainDb ethdb.Database) error {
+func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, engine *posv.Posv, chain *core.BlockChain, block *types.Block, chainDb ethdb.Database) error {
 	TxSignMu.Lock()
 	defer TxSignMu.Unlock()
 	if chainConfig.Posv != nil {
-		// Find active account.
-		account := accounts.Account{}
-		var wallet accounts.Wallet
-		if wallets := manager.Wallets(); len(wallets) > 0 {
-			wallet = wallets[0]
-			if accts := wallets[0].Accounts(); len(accts) > 0 {
-				account = accts[0]
-			}
+		// Sign with the account and the function the engine seals with.
+		address, signFn := engine.SignerFn()
+		if signFn == nil {
+			return errNoSigner
 		}
+		signer := &txSigner{account: accounts.Account{Address: address}, signFn: signFn}
+		account := signer.account
 
 		// Create and send tx to smart contract for sign validate block.
 		nonce := pool.State().GetNonce(account.Address)
 		tx := CreateTxSign(block.Number(), block.Hash(), nonce, common.HexToAddress(common.BlockSigners))
-		txSigned, err := wallet.SignTx(account, tx, chainConfig.ChainId)
+		txSigned, err := signer.SignTx(tx, chainConfig.ChainId)
 		if err != nil {
 			log.Error("Fail to create tx sign", "error", err)
 			return err
@@ -89,66 +111,19 @@ func CreateTransactionSign(chainConfig *params.ChainConfig, pool *core.TxPool, m
 			return err
 		}
 
-		// Create secret tx.
-		blockNumber := block.Number().Uint64()
-		checkNumber := blockNumber % chainConfig.Posv.Epoch
-		// Generate random private key and save into chaindb.
-		randomizeKeyName := []byte("randomizeKey")
-		exist, _ := chainDb.Has(randomizeKeyName)
-
-		// Set secret for randomize.
-		if !exist && checkNumber > 0 && common.EpocBlockSecret <= checkNumber && common.EpocBlockOpening > checkNumber {
-			// Only process when private key empty in state db.
-			// Save randomize key into state db.
-			randomizeKeyValue := RandStringByte(32)
-			tx, err := BuildTxSecretRandomize(nonce+1, common.HexToAddress(common.RandomizeSMC), chainConfig.Posv.Epoch, randomizeKeyValue)
-			if err != nil {
-				log.Error("Fail to get tx opening for randomize", "error", err)
-				return err
-			}
-			txSigned, err := wallet.SignTx(account, tx, chainConfig.ChainId)
-			if err != nil {
-				log.Error("Fail to create tx secret", "error", err)
-				return err
+		// Commit and reveal the randomize secret of this epoch.
+		var statedb *state.StateDB
+		if chain != nil {
+			if statedb, err = chain.StateAt(block.Root()); err != nil {
+				log.Warn("Fail to get state of signed block for randomize", "number", block.NumberU64(), "error", err)
 			}
-			// Add tx signed to local tx pool.
-			err = pool.AddLocal(txSigned)
-			if err != nil {
-				log.Error("Fail to add tx secret to local pool.", "error", err, "number", block.NumberU64(), "hash", block.Hash().Hex(), "from", account.Address, "nonce", nonce)
-				return err
-			}
-
-			// Put randomize key into chainDb.
-			chainDb.Put(randomizeKeyName, randomizeKeyValue)
 		}
-
-		// Set opening for randomize.
-		if exist && checkNumber > 0 && common.EpocBlockOpening <= checkNumber && common.EpocBlockRandomize >= checkNumber {
-			randomizeKeyValue, err := chainDb.Get(randomizeKeyName)
-			if err != nil {
-				log.Error("Fail to get randomize key from state db.", "error", err)
-				return err
-			}
-
-			tx, err := BuildTxOpeningRandomize(nonce+1, common.HexToAddress(common.RandomizeSMC), randomizeKeyValue)
-			if err != nil {
-				log.Error("Fail to get tx opening for randomize", "error", err)
-				return err
-			}
-			txSigned, err := wallet.SignTx(account, tx, chainConfig.ChainId)
-			if err != nil {
-				log.Error("Fail to create tx opening", "error", err)
-				return err
-			}
-			// Add tx to pool.
-			err = pool.AddLocal(txSigned)
-			if err != nil {
-				log.Error("Fail to add tx opening to local pool.", "error", err, "number", block.NumberU64(), "hash", block.Hash().Hex(), "from", account.Address, "nonce", nonce)
-				return err
-			}
-
-			// Clear randomize key in state db.
-			chainDb.Delete(randomizeKeyName)
+		if err := submitRandomize(chainConfig, pool, signer, block, chainDb, statedb, nonce+1); err != nil {
+			return err
+		}
+		// Register the BLS key and sign the gap block for the checkpoint.
+		if err := submitBLS(chainConfig, pool, signer, chain, block, statedb); err != nil {
+			return err
 		}
 	}
 
@@ -203,6 +178,45 @@ func GetSignersFromContract(state *stateDatabase.StateDB, block *types.Block) ([
 	return stateDatabase.GetSigners(state, block), nil
 }
 
+// GetPenaltiesForEpoch returns the masternodes of the epoch before the
+// checkpoint number which signed none of its blocks, as recorded in the block
+// signer contract of the given state.
+func GetPenaltiesForEpoch(c *posv.Posv, chain consensus.ChainReader, statedb *state.StateDB, number uint64) ([]common.Address, error) {
+	prevEpoc := number - chain.Config().Posv.Epoch
+	prevHeader := chain.GetHeaderByNumber(prevEpoc)
+	penSigners := c.GetMasternodes(chain, prevHeader)
+	if len(penSigners) > 0 {
+		// Loop for each block to check missing sign.
+		for i := prevEpoc; i < number; i++ {
+			if i%common.MergeSignRange == 0 || !chain.Config().IsTIP2019(big.NewInt(int64(i))) {
+				bheader := chain.GetHeaderByNumber(i)
+				bhash := bheader.Hash()
+				block := chain.GetBlock(bhash, i)
+				if len(penSigners) > 0 {
+					signedMasternodes, err := GetSignersFromContract(statedb, block)
+					if err != nil {
+						return nil, err
+					}
+					if len(signedMasternodes) > 0 {
+						// Check signer signed?
+						for _, signed := range signedMasternodes {
+							for j, addr := range penSigners {
+								if signed == addr {
+									// Remove it from dupSigners.
+									penSigners = append(penSigners[:j], penSigners[j+1:]...)
+								}
+							}
+						}
+					}
+				} else {
+					break
+				}
+			}
+		}
+	}
+	return penSigners, nil
+}
+
 // Get signers signed for blockNumber from blockSigner contract.
 func GetSignersByExecutingEVM(addrBlockSigner common.Address, client bind.ContractBackend, blockHash common.Hash) ([]common.Address, error) {
 	blockSigner, err := contract.NewBlockSigner(addrBlockSigner, client)
@@ -238,6 +252,12 @@ func GetRandomizeFromContract(client bind.ContractBackend, addrMasternode common
 	return DecryptRandomizeFromSecretsAndOpening(secrets, opening)
 }
 
+// GetRandomizeFromState returns the random number committed by a masternode,
+// reading the storage of the randomize contract in the given state.
+func GetRandomizeFromState(statedb *state.StateDB, addrMasternode common.Address) (int64, error) {
+	return DecryptRandomizeFromSecretsAndOpening(state.GetSecret(statedb, addrMasternode), state.GetOpening(statedb, addrMasternode))
+}
+
 // Generate m2 listing from randomize array.
 func GenM2FromRandomize(randomizes []int64, lenSigners int64) ([]int64, error) {
 	blockValidator := NewSlice(int64(0), lenSigners, 1)
@@ -308,7 +328,7 @@ func DecryptRandomizeFromSecretsAndOpening(secrets [][32]byte, opening [32]byte)
 }
 
 // Calculate reward for reward checkpoint.
-func GetRewardForCheckpoint(c *posv.Posv, chain consensus.ChainReader, header *types.Header, rCheckpoint uint64, totalSigner *uint64) (map[common.Address]*rewardLog, error) {
+func GetRewardForCheckpoint(ctx context.Context, c *posv.Posv, chain consensus.ChainReader, header *types.Header, rCheckpoint uint64, totalSigner *uint64) (map[common.Address]*rewardLog, error) {
 	// Not reward for singer of genesis block and only calculate reward at checkpoint block.
 	number := header.Number.Uint64()
 	prevCheckpoint := number - (rCheckpoint * 2)
@@ -319,6 +339,9 @@ func GetRewardForCheckpoint(c *posv.Posv, chain consensus.ChainReader, header *t
 
 	data := make(map[common.Hash][]common.Address)
 	for i := prevCheckpoint + (rCheckpoint * 2) - 1; i >= startBlockNumber; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		header = chain.GetHeader(header.ParentHash, i)
 		mapBlkHash[i] = header.Hash()
 		signData, ok := c.BlockSigners.Get(header.Hash())
@@ -378,65 +401,44 @@ func GetRewardForCheckpoint(c *posv.Posv, chain consensus.ChainReader, header *t
 	return signers, nil
 }
 
-// Calculate reward for signers.
-func CalculateRewardForSigner(chainReward *big.Int, signers map[common.Address]*rewardLog, totalSigner uint64) (map[common.Address]*big.Int, error) {
-	resultSigners := make(map[common.Address]*big.Int)
-	// Add reward for signers.
-	if totalSigner > 0 {
-		for signer, rLog := range signers {
-			// Add reward for signer.
-			calcReward := new(big.Int)
-			calcReward.Div(chainReward, new(big.Int).SetUint64(totalSigner))
-			calcReward.Mul(calcReward, new(big.Int).SetUint64(rLog.Sign))
-			rLog.Reward = calcReward
-
-			resultSigners[signer] = calcReward
-		}
-	}
-	jsonSigners, err := json.Marshal(signers)
-	if err != nil {
-		log.Error("Fail to parse json signers", "error", err)
-		return nil, err
-	}
-	log.Info("Signers data", "signers", string(jsonSigners), "totalSigner", totalSigner, "totalReward", chainReward)
-
-	return resultSigners, nil
-}
-
 // Get candidate owner by address.
 func GetCandidatesOwnerBySigner(state *state.StateDB, signerAddr common.Address) common.Address {
 	owner := stateDatabase.GetCandidateOwner(state, signerAddr)
 	return owner
 }
 
-func CalculateRewardForHolders(foundationWalletAddr common.Address, state *state.StateDB, signer common.Address, calcReward *big.Int, blockNumber uint64) (error, map[common.Address]*big.Int) {
-	rewards, err := GetRewardBalancesRate(foundationWalletAddr, state, signer, calcReward, blockNumber)
+func GetRewardBalancesRate(ctx context.Context, split params.RewardSplit, foundationWalletAddr common.Address, validator ValidatorCaller, masterAddr common.Address, totalReward *big.Int, blockNumber uint64) (map[common.Address]*big.Int, error) {
+	owner, err := validator.CandidateOwner(masterAddr)
 	if err != nil {
-		return err, nil
+		return nil, err
 	}
-	return nil, rewards
-}
-
-func GetRewardBalancesRate(foundationWalletAddr common.Address, state *state.StateDB, masterAddr common.Address, totalReward *big.Int, blockNumber uint64) (map[common.Address]*big.Int, error) {
-	owner := GetCandidatesOwnerBySigner(state, masterAddr)
 	balances := make(map[common.Address]*big.Int)
-	rewardMaster := new(big.Int).Mul(totalReward, new(big.Int).SetInt64(common.RewardMasterPercent))
+	rewardMaster := new(big.Int).Mul(totalReward, new(big.Int).SetUint64(split.Masternode))
 	rewardMaster = new(big.Int).Div(rewardMaster, new(big.Int).SetInt64(100))
 	balances[owner] = rewardMaster
 	// Get voters for masternode.
-	voters := stateDatabase.GetVoters(state, masterAddr)
+	voters, err := validator.Voters(masterAddr)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(voters) > 0 {
-		totalVoterReward := new(big.Int).Mul(totalReward, new(big.Int).SetUint64(common.RewardVoterPercent))
+		totalVoterReward := new(big.Int).Mul(totalReward, new(big.Int).SetUint64(split.Voter))
 		totalVoterReward = new(big.Int).Div(totalVoterReward, new(big.Int).SetUint64(100))
 		totalCap := new(big.Int)
 		// Get voters capacities.
 		voterCaps := make(map[common.Address]*big.Int)
 		for _, voteAddr := range voters {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			if _, ok := voterCaps[voteAddr]; ok && common.TIP2019Block.Uint64() <= blockNumber {
 				continue
 			}
-			voterCap := stateDatabase.GetVoterCap(state, masterAddr, voteAddr)
+			voterCap, err := validator.VoterCap(masterAddr, voteAddr)
+			if err != nil {
+				return nil, err
+			}
 			totalCap.Add(totalCap, voterCap)
 			voterCaps[voteAddr] = voterCap
 		}
@@ -456,7 +458,7 @@ func GetRewardBalancesRate(foundationWalletAddr common.Address, state *state.Sta
 		}
 	}
 
-	foundationReward := new(big.Int).Mul(totalReward, new(big.Int).SetInt64(common.RewardFoundationPercent))
+	foundationReward := new(big.Int).Mul(totalReward, new(big.Int).SetUint64(split.Foundation))
 	foundationReward = new(big.Int).Div(foundationReward, new(big.Int).SetInt64(100))
 	balances[foundationWalletAddr] = foundationReward
 
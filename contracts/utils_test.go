@@ -19,14 +19,19 @@ import (
 	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/posv"
 	"github.com/ethereum/go-ethereum/contracts/blocksigner"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
 	"math/big"
 	"math/rand"
 	"testing"
@@ -115,6 +120,60 @@ func TestSendTxSign(t *testing.T) {
 	}
 }
 
+// testPoolChain is the chain of a transaction pool over a single state.
+type testPoolChain struct {
+	statedb *state.StateDB
+	feed    event.Feed
+}
+
+func (c *testPoolChain) CurrentBlock() *types.Block {
+	return types.NewBlock(&types.Header{Number: big.NewInt(0), GasLimit: 10000000}, nil, nil, nil)
+}
+func (c *testPoolChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return c.CurrentBlock()
+}
+func (c *testPoolChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return c.statedb, nil
+}
+func (c *testPoolChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+func TestCreateTransactionSignWithoutWallets(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	config := &params.ChainConfig{ChainId: big.NewInt(89), Posv: &params.PosvConfig{Period: 2, Epoch: 900}}
+
+	pool := core.NewTxPool(core.DefaultTxPoolConfig, config, &testPoolChain{statedb: statedb})
+	defer pool.Stop()
+	pool.IsSigner = func(address common.Address) bool { return address == acc1Addr }
+
+	// There is no local wallet, the engine is not authorized to seal yet
+	engine := posv.New(config.Posv, db)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil, nil)
+	if err := CreateTransactionSign(config, pool, engine, nil, block, db); err != errNoSigner {
+		t.Fatalf("signing without signer: have %v, want %v", err, errNoSigner)
+	}
+	// The transaction is signed by the function the engine seals with
+	engine.Authorize(acc1Addr, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, acc1Key)
+	})
+	if err := CreateTransactionSign(config, pool, engine, nil, block, db); err != nil {
+		t.Fatalf("failed to create the sign transaction: %v", err)
+	}
+	pending, _ := pool.Pending()
+	if len(pending[acc1Addr]) != 1 {
+		t.Fatalf("pending transactions mismatch: have %d, want 1", len(pending[acc1Addr]))
+	}
+	tx := pending[acc1Addr][0]
+	if *tx.To() != common.HexToAddress(common.BlockSigners) {
+		t.Errorf("recipient mismatch: have %x, want %s", tx.To(), common.BlockSigners)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(config.ChainId), tx); err != nil || from != acc1Addr {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, acc1Addr)
+	}
+}
+
 // Generate random string.
 func randomHash() common.Hash {
 	letterBytes := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ123456789"
@@ -0,0 +1,393 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	validatorContract "github.com/ethereum/go-ethereum/contracts/validator/contract"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/log"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	validatorCallRetries    = 3                      // Attempts of a call to the validator contract before giving up
+	validatorCallRetryDelay = 100 * time.Millisecond // Delay between the attempts, doubled after each one
+)
+
+// ValidatorCaller reads the candidates and the votes of the validator contract.
+type ValidatorCaller interface {
+	Candidates() ([]common.Address, error)
+	CandidateOwner(candidate common.Address) (common.Address, error)
+	CandidateCap(candidate common.Address) (*big.Int, error)
+	Voters(candidate common.Address) ([]common.Address, error)
+	VoterCap(candidate, voter common.Address) (*big.Int, error)
+}
+
+// storageValidatorCaller reads the validator contract from its storage slots,
+// as laid out by the deployed contract. It never fails, so it is the one used
+// by the consensus.
+type storageValidatorCaller struct {
+	statedb state.StorageReader
+}
+
+// NewStorageValidatorCaller returns a caller reading the storage slots of the
+// validator contract in a state.
+func NewStorageValidatorCaller(statedb state.StorageReader) ValidatorCaller {
+	return &storageValidatorCaller{statedb: statedb}
+}
+
+func (c *storageValidatorCaller) Candidates() ([]common.Address, error) {
+	return state.GetCandidates(c.statedb), nil
+}
+
+func (c *storageValidatorCaller) CandidateOwner(candidate common.Address) (common.Address, error) {
+	return state.GetCandidateOwner(c.statedb, candidate), nil
+}
+
+func (c *storageValidatorCaller) CandidateCap(candidate common.Address) (*big.Int, error) {
+	return state.GetCandidateCap(c.statedb, candidate), nil
+}
+
+func (c *storageValidatorCaller) Voters(candidate common.Address) ([]common.Address, error) {
+	return state.GetVoters(c.statedb, candidate), nil
+}
+
+func (c *storageValidatorCaller) VoterCap(candidate, voter common.Address) (*big.Int, error) {
+	return state.GetVoterCap(c.statedb, candidate, voter), nil
+}
+
+// boundValidatorCaller reads the validator contract through its generated
+// bindings, executing the contract code, and retries the failed calls.
+type boundValidatorCaller struct {
+	contract *validatorContract.TomoValidatorCaller
+	opts     *bind.CallOpts
+	retries  int
+	delay    time.Duration
+}
+
+// NewBoundValidatorCaller returns a caller executing the getters of the
+// validator contract deployed at addr, on the latest state of the backend.
+func NewBoundValidatorCaller(addr common.Address, backend bind.ContractCaller) (ValidatorCaller, error) {
+	contract, err := validatorContract.NewTomoValidatorCaller(addr, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &boundValidatorCaller{
+		contract: contract,
+		opts:     new(bind.CallOpts),
+		retries:  validatorCallRetries,
+		delay:    validatorCallRetryDelay,
+	}, nil
+}
+
+// retry runs a call until it succeeds or its attempts are exhausted, returning
+// the last error.
+func (c *boundValidatorCaller) retry(call func() error) error {
+	delay := c.delay
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= c.retries {
+			return err
+		}
+		log.Debug("Retrying validator contract call", "attempt", attempt, "err", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (c *boundValidatorCaller) Candidates() (candidates []common.Address, err error) {
+	err = c.retry(func() (err error) {
+		candidates, err = c.contract.GetCandidates(c.opts)
+		return err
+	})
+	return candidates, err
+}
+
+func (c *boundValidatorCaller) CandidateOwner(candidate common.Address) (owner common.Address, err error) {
+	err = c.retry(func() (err error) {
+		owner, err = c.contract.GetCandidateOwner(c.opts, candidate)
+		return err
+	})
+	return owner, err
+}
+
+func (c *boundValidatorCaller) CandidateCap(candidate common.Address) (cap *big.Int, err error) {
+	err = c.retry(func() (err error) {
+		cap, err = c.contract.GetCandidateCap(c.opts, candidate)
+		return err
+	})
+	return cap, err
+}
+
+func (c *boundValidatorCaller) Voters(candidate common.Address) (voters []common.Address, err error) {
+	err = c.retry(func() (err error) {
+		voters, err = c.contract.GetVoters(c.opts, candidate)
+		return err
+	})
+	return voters, err
+}
+
+func (c *boundValidatorCaller) VoterCap(candidate, voter common.Address) (cap *big.Int, err error) {
+	err = c.retry(func() (err error) {
+		cap, err = c.contract.GetVoterCap(c.opts, candidate, voter)
+		return err
+	})
+	return cap, err
+}
+
+// fallbackValidatorCaller reads the validator contract with a primary caller,
+// falling back to another one when it fails.
+type fallbackValidatorCaller struct {
+	primary, fallback ValidatorCaller
+}
+
+// NewFallbackValidatorCaller returns a caller trying the primary caller first,
+// typically the bindings, and the fallback one, typically the storage slots,
+// when it fails.
+func NewFallbackValidatorCaller(primary, fallback ValidatorCaller) ValidatorCaller {
+	return &fallbackValidatorCaller{primary: primary, fallback: fallback}
+}
+
+func (c *fallbackValidatorCaller) failed(call string, err error) {
+	log.Warn("Validator contract call failed, reading its storage", "call", call, "err", err)
+}
+
+func (c *fallbackValidatorCaller) Candidates() ([]common.Address, error) {
+	candidates, err := c.primary.Candidates()
+	if err != nil {
+		c.failed("candidates", err)
+		return c.fallback.Candidates()
+	}
+	return candidates, nil
+}
+
+func (c *fallbackValidatorCaller) CandidateOwner(candidate common.Address) (common.Address, error) {
+	owner, err := c.primary.CandidateOwner(candidate)
+	if err != nil {
+		c.failed("candidate owner", err)
+		return c.fallback.CandidateOwner(candidate)
+	}
+	return owner, nil
+}
+
+func (c *fallbackValidatorCaller) CandidateCap(candidate common.Address) (*big.Int, error) {
+	cap, err := c.primary.CandidateCap(candidate)
+	if err != nil {
+		c.failed("candidate cap", err)
+		return c.fallback.CandidateCap(candidate)
+	}
+	return cap, nil
+}
+
+func (c *fallbackValidatorCaller) Voters(candidate common.Address) ([]common.Address, error) {
+	voters, err := c.primary.Voters(candidate)
+	if err != nil {
+		c.failed("voters", err)
+		return c.fallback.Voters(candidate)
+	}
+	return voters, nil
+}
+
+func (c *fallbackValidatorCaller) VoterCap(candidate, voter common.Address) (*big.Int, error) {
+	cap, err := c.primary.VoterCap(candidate, voter)
+	if err != nil {
+		c.failed("voter cap", err)
+		return c.fallback.VoterCap(candidate, voter)
+	}
+	return cap, nil
+}
+
+// cachedValidatorCaller memoizes the successful reads of a caller at a
+// checkpoint. The amounts returned are copies, callers being free to modify
+// them.
+type cachedValidatorCaller struct {
+	caller ValidatorCaller
+
+	candidates []common.Address
+	owners     map[common.Address]common.Address
+	caps       map[common.Address]*big.Int
+	voters     map[common.Address][]common.Address
+	voterCaps  map[[2]common.Address]*big.Int
+	lock       sync.Mutex
+}
+
+func (c *cachedValidatorCaller) Candidates() ([]common.Address, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.candidates == nil {
+		candidates, err := c.caller.Candidates()
+		if err != nil {
+			return nil, err
+		}
+		c.candidates = append([]common.Address{}, candidates...)
+	}
+	return append([]common.Address{}, c.candidates...), nil
+}
+
+func (c *cachedValidatorCaller) CandidateOwner(candidate common.Address) (common.Address, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	owner, ok := c.owners[candidate]
+	if !ok {
+		var err error
+		if owner, err = c.caller.CandidateOwner(candidate); err != nil {
+			return common.Address{}, err
+		}
+		c.owners[candidate] = owner
+	}
+	return owner, nil
+}
+
+func (c *cachedValidatorCaller) CandidateCap(candidate common.Address) (*big.Int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	cap, ok := c.caps[candidate]
+	if !ok {
+		var err error
+		if cap, err = c.caller.CandidateCap(candidate); err != nil {
+			return nil, err
+		}
+		c.caps[candidate] = cap
+	}
+	return new(big.Int).Set(cap), nil
+}
+
+func (c *cachedValidatorCaller) Voters(candidate common.Address) ([]common.Address, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	voters, ok := c.voters[candidate]
+	if !ok {
+		var err error
+		if voters, err = c.caller.Voters(candidate); err != nil {
+			return nil, err
+		}
+		c.voters[candidate] = voters
+	}
+	return append([]common.Address{}, voters...), nil
+}
+
+func (c *cachedValidatorCaller) VoterCap(candidate, voter common.Address) (*big.Int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := [2]common.Address{candidate, voter}
+	cap, ok := c.voterCaps[key]
+	if !ok {
+		var err error
+		if cap, err = c.caller.VoterCap(candidate, voter); err != nil {
+			return nil, err
+		}
+		c.voterCaps[key] = cap
+	}
+	return new(big.Int).Set(cap), nil
+}
+
+// ValidatorCache keeps the reads of the validator contract at the recent
+// checkpoints, the contract state being fixed at a given block.
+type ValidatorCache struct {
+	checkpoints *lru.Cache // Cached callers by checkpoint hash
+}
+
+// NewValidatorCache returns a cache of the validator contract reads at the
+// given number of checkpoints.
+func NewValidatorCache(size int) *ValidatorCache {
+	checkpoints, _ := lru.New(size)
+	return &ValidatorCache{checkpoints: checkpoints}
+}
+
+// At returns a caller caching the reads at a checkpoint, made with the given
+// caller the first time. The caller must read the state of the checkpoint, and
+// must not be used for a modified copy of it.
+func (c *ValidatorCache) At(checkpoint common.Hash, caller ValidatorCaller) ValidatorCaller {
+	if cached, ok := c.checkpoints.Get(checkpoint); ok {
+		return cached.(*cachedValidatorCaller)
+	}
+	cached := &cachedValidatorCaller{
+		caller:    caller,
+		owners:    make(map[common.Address]common.Address),
+		caps:      make(map[common.Address]*big.Int),
+		voters:    make(map[common.Address][]common.Address),
+		voterCaps: make(map[[2]common.Address]*big.Int),
+	}
+	c.checkpoints.Add(checkpoint, cached)
+	return cached
+}
+
+// CheckValidatorLayout compares the reads of the validator contract through
+// its bindings and through its storage slots, so that a change of the layout
+// of the contract storage is detected before it corrupts the rewards computed
+// from the slots. It returns the first mismatch found.
+func CheckValidatorLayout(bound, storage ValidatorCaller) error {
+	candidates, err := bound.Candidates()
+	if err != nil {
+		return err
+	}
+	slotCandidates, _ := storage.Candidates()
+	if len(candidates) != len(slotCandidates) {
+		return fmt.Errorf("validator layout mismatch: %d candidates bound, %d in the slots", len(candidates), len(slotCandidates))
+	}
+	for i, candidate := range candidates {
+		if slotCandidates[i] != candidate {
+			return fmt.Errorf("validator layout mismatch: candidate %d is %x bound, %x in the slots", i, candidate, slotCandidates[i])
+		}
+		owner, err := bound.CandidateOwner(candidate)
+		if err != nil {
+			return err
+		}
+		if slotOwner, _ := storage.CandidateOwner(candidate); slotOwner != owner {
+			return fmt.Errorf("validator layout mismatch: owner of %x is %x bound, %x in the slots", candidate, owner, slotOwner)
+		}
+		cap, err := bound.CandidateCap(candidate)
+		if err != nil {
+			return err
+		}
+		if slotCap, _ := storage.CandidateCap(candidate); slotCap.Cmp(cap) != 0 {
+			return fmt.Errorf("validator layout mismatch: cap of %x is %v bound, %v in the slots", candidate, cap, slotCap)
+		}
+		voters, err := bound.Voters(candidate)
+		if err != nil {
+			return err
+		}
+		slotVoters, _ := storage.Voters(candidate)
+		if len(voters) != len(slotVoters) {
+			return fmt.Errorf("validator layout mismatch: %x has %d voters bound, %d in the slots", candidate, len(voters), len(slotVoters))
+		}
+		for j, voter := range voters {
+			if slotVoters[j] != voter {
+				return fmt.Errorf("validator layout mismatch: voter %d of %x is %x bound, %x in the slots", j, candidate, voter, slotVoters[j])
+			}
+			cap, err := bound.VoterCap(candidate, voter)
+			if err != nil {
+				return err
+			}
+			if slotCap, _ := storage.VoterCap(candidate, voter); slotCap.Cmp(cap) != 0 {
+				return fmt.Errorf("validator layout mismatch: cap of %x voting %x is %v bound, %v in the slots", voter, candidate, cap, slotCap)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package contracts
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/contracts/validator"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// shiftedStorage reads the slots following the ones asked, as if a variable
+// was inserted at the top of the contract.
+type shiftedStorage struct {
+	state.StorageReader
+}
+
+func (s *shiftedStorage) GetState(addr common.Address, key common.Hash) common.Hash {
+	return s.StorageReader.GetState(addr, common.BigToHash(new(big.Int).Add(key.Big(), common.Big1)))
+}
+
+// flakyCaller fails the first calls to the contract.
+type flakyCaller struct {
+	bind.ContractCaller
+	failures int
+}
+
+func (c *flakyCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if c.failures > 0 {
+		c.failures--
+		return nil, errors.New("connection reset")
+	}
+	return c.ContractCaller.CallContract(ctx, call, blockNumber)
+}
+
+// countingCaller counts the reads of the candidates.
+type countingCaller struct {
+	ValidatorCaller
+	reads int
+}
+
+func (c *countingCaller) Candidates() ([]common.Address, error) {
+	c.reads++
+	return c.ValidatorCaller.Candidates()
+}
+
+func deployVotedValidator(t *testing.T) (*backends.SimulatedBackend, common.Address) {
+	balance := new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	backend := backends.NewSimulatedBackend(core.GenesisAlloc{acc1Addr: {Balance: balance}})
+	transactOpts := bind.NewKeyedTransactor(acc1Key)
+
+	candidateCap := new(big.Int).Mul(big.NewInt(50000), big.NewInt(params.Ether))
+	addr, contract, err := validator.DeployValidator(transactOpts, backend, []common.Address{acc2Addr}, []*big.Int{candidateCap}, acc3Addr)
+	if err != nil {
+		t.Fatalf("failed to deploy the validator contract: %v", err)
+	}
+	backend.Commit()
+
+	contract.TransactOpts.Value = new(big.Int).Mul(big.NewInt(20), big.NewInt(params.Ether))
+	if _, err := contract.Vote(acc2Addr); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	backend.Commit()
+	return backend, addr
+}
+
+func TestValidatorCallerLayout(t *testing.T) {
+	backend, addr := deployVotedValidator(t)
+	bound, err := NewBoundValidatorCaller(addr, backend)
+	if err != nil {
+		t.Fatalf("failed to bind the validator contract: %v", err)
+	}
+	storage := NewStorageValidatorCaller(&contractStorage{backend, addr})
+	if err := CheckValidatorLayout(bound, storage); err != nil {
+		t.Fatalf("layout check failed: %v", err)
+	}
+	// The owner of a genesis candidate votes for it first
+	voters, _ := storage.Voters(acc2Addr)
+	if len(voters) != 2 || voters[0] != acc3Addr || voters[1] != acc1Addr {
+		t.Fatalf("voters mismatch: have %x, want [%x %x]", voters, acc3Addr, acc1Addr)
+	}
+	if cap, _ := storage.VoterCap(acc2Addr, acc1Addr); cap.Cmp(new(big.Int).Mul(big.NewInt(20), big.NewInt(params.Ether))) != 0 {
+		t.Errorf("voter cap mismatch: have %v, want 20 TOMO", cap)
+	}
+	// Slots moved by a layout change are detected
+	shifted := NewStorageValidatorCaller(&shiftedStorage{&contractStorage{backend, addr}})
+	if err := CheckValidatorLayout(bound, shifted); err == nil {
+		t.Errorf("layout change not detected")
+	}
+}
+
+func TestValidatorCallerRetries(t *testing.T) {
+	backend, addr := deployVotedValidator(t)
+	storage := NewStorageValidatorCaller(&contractStorage{backend, addr})
+
+	// Failures are retried up to the attempts allowed
+	flaky := &flakyCaller{ContractCaller: backend, failures: validatorCallRetries - 1}
+	bound, _ := NewBoundValidatorCaller(addr, flaky)
+	bound.(*boundValidatorCaller).delay = 0
+	if owner, err := bound.CandidateOwner(acc2Addr); err != nil || owner != acc3Addr {
+		t.Fatalf("retried call mismatch: have %x, %v, want %x", owner, err, acc3Addr)
+	}
+	// The storage slots are read once they are exhausted
+	flaky.failures = validatorCallRetries
+	if _, err := bound.CandidateOwner(acc2Addr); err == nil {
+		t.Fatalf("exhausted retries succeeded")
+	}
+	flaky.failures = validatorCallRetries
+	fallback := NewFallbackValidatorCaller(bound, storage)
+	if candidates, err := fallback.Candidates(); err != nil || len(candidates) != 1 || candidates[0] != acc2Addr {
+		t.Fatalf("fallback candidates mismatch: have %x, %v, want [%x]", candidates, err, acc2Addr)
+	}
+	if flaky.failures != 0 {
+		t.Errorf("bindings not tried first, %d failures left", flaky.failures)
+	}
+}
+
+func TestValidatorCache(t *testing.T) {
+	backend, addr := deployVotedValidator(t)
+	caller := &countingCaller{ValidatorCaller: NewStorageValidatorCaller(&contractStorage{backend, addr})}
+	cache := NewValidatorCache(1)
+
+	for i := 0; i < 3; i++ {
+		candidates, err := cache.At(common.Hash{0x01}, caller).Candidates()
+		if err != nil || len(candidates) != 1 {
+			t.Fatalf("cached candidates mismatch: have %x, %v", candidates, err)
+		}
+		candidates[0] = common.Address{}
+	}
+	if caller.reads != 1 {
+		t.Errorf("checkpoint reads mismatch: have %d, want 1", caller.reads)
+	}
+	// Another checkpoint is read again, evicting the previous one
+	cache.At(common.Hash{0x02}, caller).Candidates()
+	cache.At(common.Hash{0x01}, caller).Candidates()
+	if caller.reads != 3 {
+		t.Errorf("evicted checkpoint reads mismatch: have %d, want 3", caller.reads)
+	}
+	cap, _ := cache.At(common.Hash{0x01}, caller).VoterCap(acc2Addr, acc1Addr)
+	cap.SetInt64(0)
+	if cap, _ := cache.At(common.Hash{0x01}, caller).VoterCap(acc2Addr, acc1Addr); cap.Sign() == 0 {
+		t.Errorf("cached voter cap modified by a caller")
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	blockSignersPrefix = []byte("signers-")        // blockSignersPrefix + num (uint64 big endian) + hash -> signer addresses
+	signerIndexTailKey = []byte("SignerIndexTail") // First block the signing transactions were indexed from
+)
+
+// GetBlockSigners retrieves the masternodes known to have signed a block.
+func GetBlockSigners(db DatabaseReader, hash common.Hash, number uint64) []common.Address {
+	data, _ := db.Get(append(append(blockSignersPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	var signers []common.Address
+	if err := rlp.DecodeBytes(data, &signers); err != nil {
+		log.Error("Invalid block signers RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return signers
+}
+
+// WriteBlockSigners stores the masternodes known to have signed a block.
+func WriteBlockSigners(db ethdb.Putter, hash common.Hash, number uint64, signers []common.Address) error {
+	data, err := rlp.EncodeToBytes(signers)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(blockSignersPrefix, encodeBlockNumber(number)...), hash.Bytes()...), data)
+}
+
+// GetSignerIndexTail retrieves the number of the first block the signing
+// transactions were indexed from, if any.
+func GetSignerIndexTail(db DatabaseReader) (uint64, bool) {
+	data, _ := db.Get(signerIndexTailKey)
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
+
+// indexBlockSigners records the signers of the blocks signed by the signing
+// transactions of a new canonical block. Signatures are matched by block hash,
+// so the ones of blocks not on the canonical chain are simply never queried.
+//
+// Note, this function assumes that the `mu` mutex is held!
+func (bc *BlockChain) indexBlockSigners(block *types.Block) {
+	if bc.chainConfig.Posv == nil {
+		return
+	}
+	// Before TIPSigning, the reverted signing transactions do not count
+	var receipts types.Receipts
+	if !bc.chainConfig.IsTIPSigning(block.Number()) {
+		receipts = bc.GetReceiptsByHash(block.Hash())
+	}
+	signer := types.MakeSigner(bc.chainConfig, block.Number())
+
+	signed := make(map[common.Hash][]common.Address)
+	for i, tx := range block.Transactions() {
+		if !tx.IsSigningTransaction() {
+			continue
+		}
+		if receipts != nil && (i >= len(receipts) || receipts[i].Status != types.ReceiptStatusSuccessful) {
+			continue
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			continue
+		}
+		hash := common.BytesToHash(tx.Data()[len(tx.Data())-common.HashLength:])
+		signed[hash] = append(signed[hash], from)
+	}
+	for hash, signers := range signed {
+		number := GetBlockNumber(bc.db, hash)
+		if number == missingNumber {
+			continue
+		}
+		known := GetBlockSigners(bc.db, hash, number)
+		for _, signer := range signers {
+			if !containsAddress(known, signer) {
+				known = append(known, signer)
+			}
+		}
+		if err := WriteBlockSigners(bc.db, hash, number, known); err != nil {
+			log.Crit("Failed to store block signers", "err", err)
+		}
+	}
+	if _, ok := GetSignerIndexTail(bc.db); !ok {
+		if err := bc.db.Put(signerIndexTailKey, encodeBlockNumber(block.NumberU64())); err != nil {
+			log.Crit("Failed to store signer index tail", "err", err)
+		}
+	}
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the signing transactions of new canonical blocks are indexed by
+// the block they sign.
+func TestIndexBlockSigners(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	bc := &BlockChain{chainConfig: params.AllPosvProtocolChanges, db: db}
+
+	// Write the signed block, after TIPSigning to skip the receipt checks
+	signed := &types.Header{Number: new(big.Int).Set(common.TIPSigning)}
+	if err := WriteHeader(db, signed); err != nil {
+		t.Fatalf("failed to write signed header: %v", err)
+	}
+	var (
+		first, _  = crypto.GenerateKey()
+		second, _ = crypto.GenerateKey()
+		number    = new(big.Int).Add(signed.Number, common.Big1)
+		signer    = types.MakeSigner(bc.chainConfig, number)
+	)
+	sign := func(key, nonce int) *types.Transaction {
+		data := append(common.FromHex(common.SignMethod), common.BigToHash(signed.Number).Bytes()...)
+		data = append(data, signed.Hash().Bytes()...)
+		tx := types.NewTransaction(uint64(nonce), common.HexToAddress(common.BlockSigners), new(big.Int), 200000, new(big.Int), data)
+		tx, _ = types.SignTx(tx, signer, []*ecdsa.PrivateKey{first, second}[key])
+		return tx
+	}
+	// The same signature included twice is only recorded once
+	bc.indexBlockSigners(types.NewBlock(&types.Header{Number: number}, []*types.Transaction{sign(0, 0), sign(0, 1)}, nil, nil))
+	bc.indexBlockSigners(types.NewBlock(&types.Header{Number: new(big.Int).Add(number, common.Big1)}, []*types.Transaction{sign(1, 0)}, nil, nil))
+
+	signers := GetBlockSigners(db, signed.Hash(), signed.Number.Uint64())
+	if len(signers) != 2 || signers[0] != crypto.PubkeyToAddress(first.PublicKey) || signers[1] != crypto.PubkeyToAddress(second.PublicKey) {
+		t.Errorf("block signers mismatch: have %x", signers)
+	}
+	if tail, ok := GetSignerIndexTail(db); !ok || tail != number.Uint64() {
+		t.Errorf("signer index tail mismatch: have %d (%v), want %d", tail, ok, number)
+	}
+}
@@ -74,6 +74,15 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	// From the trade root fork on, the header commits to the trades of the block
+	if forked := v.config.IsTradeRoot(header.Number); forked && header.TradeRoot == (common.Hash{}) {
+		return fmt.Errorf("missing trade root")
+	} else if !forked && header.TradeRoot != (common.Hash{}) {
+		return fmt.Errorf("trade root %x before its fork", header.TradeRoot)
+	}
+	if !types.VerifyTradeRoot(header, block.Trades()) {
+		return fmt.Errorf("trade root hash mismatch: have %x, want %x", types.DeriveSha(block.Trades()), header.TradeRoot)
+	}
 	return nil
 }
 
@@ -105,27 +114,20 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 	return nil
 }
 
-func (v *BlockValidator) ValidateMatchingOrder(tomoXService *tomox.TomoX, statedb *state.StateDB, tomoxStatedb *tomox_state.TomoXStateDB, txMatchBatch tomox.TxMatchBatch, coinbase common.Address) error {
+func (v *BlockValidator) ValidateMatchingOrder(tomoXService *tomox.TomoX, statedb *state.StateDB, tomoxStatedb *tomox_state.TomoXStateDB, txMatchBatch tomox.TxMatchBatch, coinbase common.Address, number uint64) (types.Trades, error) {
 	log.Debug("verify matching transaction found a TxMatches Batch", "numTxMatches", len(txMatchBatch.Data))
 
+	orders := make([]*tomox_state.OrderItem, 0, len(txMatchBatch.Data))
 	for _, txMatch := range txMatchBatch.Data {
 		// verify orderItem
 		order, err := txMatch.DecodeOrder()
 		if err != nil {
-			return fmt.Errorf("transaction match is corrupted. Failed decode order. Error: %s ", err)
-		}
-
-		log.Debug("process tx match", "order", order)
-		if err := order.VerifyOrder(statedb); err != nil {
-			return fmt.Errorf("invalid order . Error: %v", err)
-		}
-		// process Matching Engine
-		if _, _,  err := tomoXService.ApplyOrder(coinbase, v.bc.IPCEndpoint, statedb, tomoxStatedb, tomox.GetOrderBookHash(order.BaseToken,order.QuoteToken), order); err != nil {
-			return err
+			return nil, fmt.Errorf("transaction match is corrupted. Failed decode order. Error: %s ", err)
 		}
+		orders = append(orders, order)
 	}
-
-	return nil
+	// process Matching Engine
+	return tomoXService.ApplyOrders(coinbase, v.bc.IPCEndpoint, statedb, tomoxStatedb, number, orders)
 }
 
 // CalcGasLimit computes the gas limit of the next block after parent.
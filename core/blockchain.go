@@ -32,8 +32,11 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/tomox"
 	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/posv"
@@ -56,6 +59,7 @@ import (
 
 var (
 	blockInsertTimer = metrics.NewRegisteredTimer("chain/inserts", nil)
+	badBlockCounter  = metrics.NewRegisteredCounter("chain/badblocks", nil)
 	CheckpointCh     = make(chan int)
 	ErrNoGenesis     = errors.New("Genesis not found in chain")
 )
@@ -81,14 +85,16 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	Snapshot      bool          // Whether to maintain a flat snapshot of the head state
 }
 type ResultProcessBlock struct {
-	logs       []*types.Log
-	receipts   []*types.Receipt
-	state      *state.StateDB
-	tomoxState *tomox_state.TomoXStateDB
-	proctime   time.Duration
-	usedGas    uint64
+	logs         []*types.Log
+	receipts     []*types.Receipt
+	state        *state.StateDB
+	tomoxState   *tomox_state.TomoXStateDB
+	lendingState *lendingstate.LendingStateDB
+	proctime     time.Duration
+	usedGas      uint64
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -120,6 +126,7 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	badBlockFeed  event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -132,6 +139,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache state.Database // State database to reuse between imports (contains state cache)
+	snap       *state.Snapshot // Flat snapshot of the head state, nil if disabled
 
 	bodyCache        *lru.Cache    // Cache for the most recent block bodies
 	bodyRLPCache     *lru.Cache    // Cache for the most recent block bodies in RLP encoded format
@@ -213,6 +221,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if cacheConfig.Snapshot {
+		bc.snap = state.NewSnapshot(db)
+		bc.snap.Update(bc.stateCache, bc.CurrentBlock().Root())
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -368,6 +380,10 @@ func (bc *BlockChain) SetHead(head uint64) error {
 			bc.currentBlock.Store(bc.genesisBlock)
 		}
 	}
+	// The snapshot is ahead of the rewound state, build it again
+	if bc.snap != nil {
+		bc.snap.Rebuild(bc.stateCache, bc.CurrentBlock().Root())
+	}
 	// Rewind the fast block in a simpleton way to the target head
 	if currentFastBlock := bc.CurrentFastBlock(); currentFastBlock != nil && currentHeader.Number.Uint64() < currentFastBlock.NumberU64() {
 		bc.currentFastBlock.Store(bc.GetBlock(currentHeader.Hash(), currentHeader.Number.Uint64()))
@@ -462,9 +478,17 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	if bc.snap != nil {
+		return state.NewWithSnapshot(root, bc.stateCache, bc.snap)
+	}
 	return state.New(root, bc.stateCache)
 }
 
+// Snapshot returns the flat snapshot of the head state, nil if disabled.
+func (bc *BlockChain) Snapshot() *state.Snapshot {
+	return bc.snap
+}
+
 // OrderStateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) OrderStateAt(block *types.Block) (*tomox_state.TomoXStateDB, error) {
 	var tomoXService *tomox.TomoX
@@ -607,6 +631,7 @@ func (bc *BlockChain) insert(block *types.Block) {
 			engine.CacheData(block.Header(), block.Transactions(), bc.GetReceiptsByHash(block.Hash()))
 		}
 	}
+	bc.indexBlockSigners(block)
 
 	// If the block is better than our head or is on a different chain, force update heads
 	if updateHeads {
@@ -789,6 +814,9 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	if bc.snap != nil {
+		bc.snap.Stop()
+	}
 
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
@@ -980,6 +1008,9 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		if err := WriteTxLookupEntries(batch, block); err != nil {
 			return i, fmt.Errorf("failed to write lookup metadata: %v", err)
 		}
+		if err := WriteOrderReceipts(batch, block); err != nil {
+			return i, fmt.Errorf("failed to write order receipts: %v", err)
+		}
 		stats.processed++
 
 		if batch.ValueSize() >= ethdb.IdealBatchSize {
@@ -1040,7 +1071,7 @@ func (bc *BlockChain) WriteBlockWithoutState(block *types.Block, td *big.Int) (e
 }
 
 // WriteBlockWithState writes the block and all associated state to the database.
-func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB, tomoxState *tomox_state.TomoXStateDB) (status WriteStatus, err error) {
+func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB, tomoxState *tomox_state.TomoXStateDB, lendingState *lendingstate.LendingStateDB) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
@@ -1086,6 +1117,20 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if bc.Config().IsTIPTomoX(block.Number()) && tomoXService != nil {
 		tomoxTrieDb = tomoXService.StateCache.TrieDB()
 	}
+	if tomoxTrieDb != nil {
+		tomoXService.IndexOrderBooks(block)
+		if bc.chainConfig.Posv != nil && bc.chainConfig.Posv.Epoch > 0 {
+			tomoXService.IndexTradingFees(block, block.NumberU64()/bc.chainConfig.Posv.Epoch)
+			tomoXService.IndexTradedVolumes(block, block.NumberU64()/bc.chainConfig.Posv.Epoch)
+		}
+		tomoXService.UpdatePairs(block, state)
+	}
+	// The lending state is small, it is always flushed
+	if lendingState != nil && tomoXService != nil {
+		if err := tomoXService.GetLending().CommitState(lendingState); err != nil {
+			return NonStatTy, err
+		}
+	}
 	triedb := bc.stateCache.TrieDB()
 
 	// If we're running an archive node, always flush
@@ -1119,8 +1164,15 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			var (
 				size  = triedb.Size()
 				limit = common.StorageSize(bc.cacheConfig.TrieNodeLimit) * 1024 * 1024
+
+				tomoxOver, tomoxCritical bool
 			)
-			if size > limit || bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			// The TomoX tries have their own allowance, but are flushed along with the
+			// state ones for both of them to be available at the same blocks
+			if bc.Config().IsTIPTomoX(block.Number()) && tomoXService != nil {
+				tomoxOver, tomoxCritical = tomoXService.TriesOverLimit(bc.gcproc)
+			}
+			if size > limit || bc.gcproc > bc.cacheConfig.TrieTimeLimit || tomoxOver {
 				// If we're exceeding limits but haven't reached a large enough memory gap,
 				// warn the user that the system is becoming unstable.
 				if chosen < lastWrite+triesInMemory {
@@ -1129,10 +1181,12 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 						log.Warn("State memory usage too high, committing", "size", size, "limit", limit, "optimum", float64(chosen-lastWrite)/triesInMemory)
 					case bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit:
 						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
+					case tomoxCritical:
+						log.Warn("TomoX state over its allowance, committing", "size", tomoxTrieDb.Size(), "time", bc.gcproc, "optimum", float64(chosen-lastWrite)/triesInMemory)
 					}
 				}
 				// If optimum or critical limits reached, write to disk
-				if chosen >= lastWrite+triesInMemory || size >= 2*limit || bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
+				if chosen >= lastWrite+triesInMemory || size >= 2*limit || bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit || tomoxCritical {
 					triedb.Commit(header.Root, true)
 					lastWrite = chosen
 					bc.gcproc = 0
@@ -1174,17 +1228,23 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		// Split same-difficulty blocks by number
 		reorg = block.NumberU64() > currentBlock.NumberU64()
 	}
+	reorged := false
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
 			if err := bc.reorg(currentBlock, block); err != nil {
 				return NonStatTy, err
 			}
+			reorged = true
 		}
 		// Write the positional metadata for transaction and receipt lookups
 		if err := WriteTxLookupEntries(batch, block); err != nil {
 			return NonStatTy, err
 		}
+		// Write the receipts of the orders matched in the block
+		if err := WriteOrderReceipts(batch, block); err != nil {
+			return NonStatTy, err
+		}
 		// Write hash preimages
 		if err := WritePreimages(bc.db, block.NumberU64(), state.Preimages()); err != nil {
 			return NonStatTy, err
@@ -1200,6 +1260,17 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		if tomoxTrieDb != nil {
+			tomoXService.MarkProcessed(block, tomoxRoot)
+		}
+		// Move the snapshot along the head, the reorgs wipe it
+		if bc.snap != nil {
+			if reorged {
+				bc.snap.Rebuild(bc.stateCache, root)
+			} else {
+				bc.snap.Update(bc.stateCache, root)
+			}
+		}
 	}
 	// save cache BlockSigners
 	if bc.chainConfig.Posv != nil && bc.chainConfig.IsTIPSigning(block.Number()) {
@@ -1228,6 +1299,10 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 // only reason this method exists as a separate one is to make locking cleaner
 // with deferred statements.
 func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*types.Log, error) {
+	// If the chain is empty, there's nothing to insert
+	if len(chain) == 0 {
+		return 0, nil, nil, nil
+	}
 	var tomoXService *tomox.TomoX
 	engine, ok := bc.Engine().(*posv.Posv)
 	if ok {
@@ -1273,6 +1348,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
 	defer close(abort)
 
+	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
+	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain)
+
 	// Iterate over the blocks and insert when the verifier permits
 	for i, block := range chain {
 		// If the chain is terminating, stop processing blocks
@@ -1374,26 +1452,15 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		}
 		// clear the previous dry-run cache
 		var tomoxState *tomox_state.TomoXStateDB
+		var lendingState *lendingstate.LendingStateDB
 		if bc.Config().IsTIPTomoX(block.Number()) && tomoXService != nil {
-			txMatchBatchData, err := ExtractMatchingTransactions(block.Transactions())
+			var matches int
+			tomoxState, matches, err = bc.applyTomoXTransactions(tomoXService, block, parent, statedb, author)
 			if err != nil {
 				bc.reportBlock(block, nil, err)
 				return i, events, coalescedLogs, err
 			}
-			tomoxState, err = tomoXService.GetTomoxState(parent)
-			if err != nil {
-				bc.reportBlock(block, nil, err)
-				return i, events, coalescedLogs, err
-			}
-			for _, txMatchBatch := range txMatchBatchData {
-				log.Debug("Verify matching transaction", "txHash", txMatchBatch.TxHash.Hex())
-				err := bc.Validator().ValidateMatchingOrder(tomoXService, statedb, tomoxState, txMatchBatch, author)
-				if err != nil {
-					bc.reportBlock(block, nil, err)
-					return i, events, coalescedLogs, err
-				}
-			}
-			if len(txMatchBatchData) > 0 {
+			if matches > 0 {
 				gotRoot := tomoxState.IntermediateRoot()
 				expectRoot, _ := tomoXService.GetTomoxStateRoot(block)
 				if gotRoot != expectRoot {
@@ -1405,6 +1472,13 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			parentTomoXRoot, _ := tomoXService.GetTomoxStateRoot(parent)
 			nextTomoxRoot, _ := tomoXService.GetTomoxStateRoot(block)
 			log.Debug("TomoX State Root", "number", block.NumberU64(), "parent", parentTomoXRoot.Hex(), "nextTomoxRoot", nextTomoxRoot.Hex())
+			if bc.Config().IsLending(block.Number()) {
+				lendingState, err = bc.applyLendingTransaction(tomoXService, block, parent, statedb, tomoxState)
+				if err != nil {
+					bc.reportBlock(block, nil, err)
+					return i, events, coalescedLogs, err
+				}
+			}
 		}
 		feeCapacity := state.GetTRC21FeeCapacityFromStateWithCache(parent.Root(), statedb)
 		// Process block using the parent state as reference point.
@@ -1421,7 +1495,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		}
 		proctime := time.Since(bstart)
 		// Write the block to the chain and get the status.
-		status, err := bc.WriteBlockWithState(block, receipts, statedb, tomoxState)
+		status, err := bc.WriteBlockWithState(block, receipts, statedb, tomoxState, lendingState)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1507,6 +1581,9 @@ func (bc *BlockChain) PrepareBlock(block *types.Block) (err error) {
 		log.Debug("Stop prepare a block because inserting", "number", block.NumberU64(), "hash", block.Hash(), "validator", block.Header().Validator)
 		return nil
 	}
+	// Recover the signatures while the header is being verified
+	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, block.Number()), types.Blocks{block})
+
 	err = bc.engine.VerifyHeader(bc, block.Header(), false)
 	if err != nil {
 		return err
@@ -1607,26 +1684,15 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 		return nil, err
 	}
 	var tomoxState *tomox_state.TomoXStateDB
+	var lendingState *lendingstate.LendingStateDB
 	if bc.Config().IsTIPTomoX(block.Number()) && tomoXService != nil {
-		tomoxState, err = tomoXService.GetTomoxState(parent)
-		if err != nil {
-			bc.reportBlock(block, nil, err)
-			return nil, err
-		}
-		txMatchBatchData, err := ExtractMatchingTransactions(block.Transactions())
+		var matches int
+		tomoxState, matches, err = bc.applyTomoXTransactions(tomoXService, block, parent, statedb, author)
 		if err != nil {
 			bc.reportBlock(block, nil, err)
 			return nil, err
 		}
-		for _, txMatchBatch := range txMatchBatchData {
-			log.Debug("Verify matching transaction", "txHash", txMatchBatch.TxHash.Hex())
-			err := bc.Validator().ValidateMatchingOrder(tomoXService, statedb, tomoxState, txMatchBatch, author)
-			if err != nil {
-				bc.reportBlock(block, nil, err)
-				return nil, err
-			}
-		}
-		if len(txMatchBatchData) > 0 {
+		if matches > 0 {
 			gotRoot := tomoxState.IntermediateRoot()
 			expectRoot, _ := tomoXService.GetTomoxStateRoot(block)
 			if gotRoot != expectRoot {
@@ -1638,6 +1704,13 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 		parentTomoXRoot, _ := tomoXService.GetTomoxStateRoot(parent)
 		nextTomoxRoot, _ := tomoXService.GetTomoxStateRoot(block)
 		log.Debug("TomoX State Root", "number", block.NumberU64(), "parent", parentTomoXRoot.Hex(), "nextTomoxRoot", nextTomoxRoot.Hex())
+		if bc.Config().IsLending(block.Number()) {
+			lendingState, err = bc.applyLendingTransaction(tomoXService, block, parent, statedb, tomoxState)
+			if err != nil {
+				bc.reportBlock(block, nil, err)
+				return nil, err
+			}
+		}
 	}
 	feeCapacity := state.GetTRC21FeeCapacityFromStateWithCache(parent.Root(), statedb)
 	// Process block using the parent state as reference point.
@@ -1658,7 +1731,103 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 	proctime := time.Since(bstart)
 	log.Debug("Calculate new block", "number", block.Number(), "hash", block.Hash(), "uncles", len(block.Uncles()),
 		"txs", len(block.Transactions()), "gas", block.GasUsed(), "elapsed", common.PrettyDuration(time.Since(bstart)), "process", process)
-	return &ResultProcessBlock{receipts: receipts, logs: logs, state: statedb, tomoxState: tomoxState, proctime: proctime, usedGas: usedGas}, nil
+	return &ResultProcessBlock{receipts: receipts, logs: logs, state: statedb, tomoxState: tomoxState, lendingState: lendingState, proctime: proctime, usedGas: usedGas}, nil
+}
+
+// applyTomoXTransactions applies the orders of the matching transactions of a
+// block on top of the TomoX state of the parent block. It returns the TomoX
+// state after the block and the number of matching transactions.
+func (bc *BlockChain) applyTomoXTransactions(tomoXService *tomox.TomoX, block, parent *types.Block, statedb *state.StateDB, author common.Address) (*tomox_state.TomoXStateDB, int, error) {
+	txMatchBatchData, err := ExtractMatchingTransactions(block.Transactions())
+	if err != nil {
+		return nil, 0, err
+	}
+	tomoxState, err := tomoXService.GetTomoxState(parent)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The matching rules of a fork apply from the first order of its block
+	tomox_state.UpgradeMatchingVersion(bc.chainConfig.TomoXVersion(block.Number()), tomoxState)
+	if bc.chainConfig.IsPriceOracle(block.Number()) {
+		tomox_state.ActivatePriceOracle(tomoxState)
+	}
+	if block.NumberU64()%bc.chainConfig.Posv.Epoch == 0 && bc.chainConfig.IsRelayerFee(block.Number()) {
+		tomox_state.RefreshRelayerFees(tomoxState, statedb)
+	}
+	trades := types.Trades{}
+	for _, txMatchBatch := range txMatchBatchData {
+		log.Debug("Verify matching transaction", "txHash", txMatchBatch.TxHash.Hex())
+		matched, err := bc.Validator().ValidateMatchingOrder(tomoXService, statedb, tomoxState, txMatchBatch, author, block.NumberU64())
+		if err != nil {
+			return nil, 0, err
+		}
+		trades = append(trades, matched...)
+	}
+	// The trades committed to by the header must be the ones of the matching
+	if bc.chainConfig.IsTradeRoot(block.Number()) {
+		if root := types.DeriveSha(trades); root != block.TradeRoot() {
+			return nil, 0, fmt.Errorf("invalid trade root (remote: %x local: %x)", block.TradeRoot(), root)
+		}
+	}
+	// Orders expiring at this block are matched one last time before pruned
+	if tomox.StateMatchingRules(tomoxState).IsExpiry {
+		tomox_state.PruneExpiredOrders(block.NumberU64(), tomoxState)
+	}
+	tomox_state.UpdatePriceOracle(block.NumberU64(), tomoxState, statedb)
+	return tomoxState, len(txMatchBatchData), nil
+}
+
+// ReplayTomoX applies the matching transactions of a block on top of the states
+// of its parent block, as done when inserting it, and returns the computed TomoX
+// root. Nothing is committed, so the states of the parent block must be in the
+// database.
+func (bc *BlockChain) ReplayTomoX(block *types.Block) (common.Hash, error) {
+	engine, ok := bc.Engine().(*posv.Posv)
+	if !ok || engine.GetTomoXService == nil || engine.GetTomoXService() == nil {
+		return common.Hash{}, errors.New("tomox service not running")
+	}
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return common.Hash{}, consensus.ErrUnknownAncestor
+	}
+	statedb, err := state.New(parent.Root(), bc.stateCache)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	author, err := bc.Engine().Author(block.Header())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tomoxState, _, err := bc.applyTomoXTransactions(engine.GetTomoXService(), block, parent, statedb, author)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tomoxState.IntermediateRoot(), nil
+}
+
+// applyLendingTransaction verifies the lending transaction of a block,
+// liquidating the loans due at the block and applying its orders on top of the
+// lending state of the parent block. Loans are liquidated in every block, as
+// the miner does, a block without lending transaction carrying no order and
+// the empty lending state root. It returns the lending state after the block.
+func (bc *BlockChain) applyLendingTransaction(tomoXService *tomox.TomoX, block, parent *types.Block, statedb *state.StateDB, tomoxState *tomox_state.TomoXStateDB) (*lendingstate.LendingStateDB, error) {
+	lending := tomoXService.GetLending()
+	lendingState, err := lending.GetLendingState(parent)
+	if err != nil {
+		return nil, err
+	}
+	batch := &tomoxlending.LendingBatch{Root: lendingstate.EmptyRoot}
+	if tx := tomoxlending.GetLendingTransaction(block); tx != nil {
+		if batch, err = tomoxlending.DecodeLendingBatch(tx.Data()); err != nil {
+			return nil, err
+		}
+	}
+	liquidated := tomoXService.ProcessLiquidations(bc.IPCEndpoint, bc.chainConfig.TomoXLending, block.Time().Uint64(), statedb, tomoxState, lendingState)
+	if err := tomoxlending.ApplyLendingBatch(block.Time().Uint64(), batch, statedb, lendingState); err != nil {
+		return nil, err
+	}
+	log.Debug("Lending State Root", "number", block.NumberU64(), "liquidated", len(liquidated), "orders", len(batch.Orders), "root", batch.Root.Hex())
+	return lendingState, nil
 }
 
 // UpdateBlocksHashCache update BlocksHashCache by block number
@@ -1708,7 +1877,7 @@ func (bc *BlockChain) insertBlock(block *types.Block) ([]interface{}, []*types.L
 	if bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
 		return events, coalescedLogs, nil
 	}
-	status, err := bc.WriteBlockWithState(block, result.receipts, result.state, result.tomoxState)
+	status, err := bc.WriteBlockWithState(block, result.receipts, result.state, result.tomoxState, result.lendingState)
 
 	if err != nil {
 		return events, coalescedLogs, err
@@ -1914,6 +2083,9 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		if err := WriteTxLookupEntries(bc.db, newChain[i]); err != nil {
 			return err
 		}
+		if err := WriteOrderReceipts(bc.db, newChain[i]); err != nil {
+			return err
+		}
 		addedTxs = append(addedTxs, newChain[i].Transactions()...)
 	}
 	// calculate the difference between deleted and added transactions
@@ -1976,30 +2148,52 @@ func (bc *BlockChain) update() {
 
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash   common.Hash   `json:"hash"`
-	Header *types.Header `json:"header"`
+	Hash     common.Hash   `json:"hash"`
+	Header   *types.Header `json:"header"`
+	RLP      hexutil.Bytes `json:"rlp"`
+	Error    string        `json:"error"`
+	Reported time.Time     `json:"reported"`
+}
+
+// badBlock is a block that failed validation, with the reason of the failure.
+type badBlock struct {
+	block    *types.Block
+	err      error
+	reported time.Time
 }
 
 // BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
 func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
-	headers := make([]BadBlockArgs, 0, bc.badBlocks.Len())
+	blocks := make([]BadBlockArgs, 0, bc.badBlocks.Len())
 	for _, hash := range bc.badBlocks.Keys() {
-		if hdr, exist := bc.badBlocks.Peek(hash); exist {
-			header := hdr.(*types.Header)
-			headers = append(headers, BadBlockArgs{header.Hash(), header})
+		if bad, exist := bc.badBlocks.Peek(hash); exist {
+			bad := bad.(*badBlock)
+			blockRLP, err := rlp.EncodeToBytes(bad.block)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, BadBlockArgs{
+				Hash:     bad.block.Hash(),
+				Header:   bad.block.Header(),
+				RLP:      blockRLP,
+				Error:    bad.err.Error(),
+				Reported: bad.reported,
+			})
 		}
 	}
-	return headers, nil
+	return blocks, nil
 }
 
 // addBadBlock adds a bad block to the bad-block LRU cache
-func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Header().Hash(), block.Header())
+func (bc *BlockChain) addBadBlock(block *types.Block, err error) {
+	bc.badBlocks.Add(block.Hash(), &badBlock{block: block, err: err, reported: time.Now()})
 }
 
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	bc.addBadBlock(block)
+	bc.addBadBlock(block, err)
+	badBlockCounter.Inc(1)
+	go bc.badBlockFeed.Send(BadBlockEvent{Block: block, Err: err})
 
 	var receiptString string
 	for _, receipt := range receipts {
@@ -2149,6 +2343,11 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeBadBlockEvent registers a subscription of BadBlockEvent.
+func (bc *BlockChain) SubscribeBadBlockEvent(ch chan<- BadBlockEvent) event.Subscription {
+	return bc.scope.Track(bc.badBlockFeed.Subscribe(ch))
+}
+
 // Get current IPC Client.
 func (bc *BlockChain) GetClient() (*ethclient.Client, error) {
 	if bc.Client == nil {
@@ -2220,8 +2419,8 @@ func (bc *BlockChain) UpdateM1() error {
 		}
 		// update masternodes
 		log.Info("Updating new set of masternodes")
-		if len(ms) > common.MaxMasternodes {
-			err = engine.UpdateMasternodes(bc, bc.CurrentHeader(), ms[:common.MaxMasternodes])
+		if maxMasternodes := bc.chainConfig.Posv.MasternodesLimit(); len(ms) > maxMasternodes {
+			err = engine.UpdateMasternodes(bc, bc.CurrentHeader(), ms[:maxMasternodes])
 		} else {
 			err = engine.UpdateMasternodes(bc, bc.CurrentHeader(), ms)
 		}
@@ -2239,7 +2438,7 @@ func (bc *BlockChain) logExchangeData(block *types.Block) {
 	if ok {
 		tomoXService = engine.GetTomoXService()
 	}
-	if tomoXService == nil || !tomoXService.IsSDKNode() {
+	if tomoXService == nil || (!tomoXService.IsSDKNode() && !tomoXService.IsExporting()) {
 		return
 	}
 	txMatchBatchData, err := ExtractMatchingTransactions(block.Transactions())
@@ -2250,11 +2449,20 @@ func (bc *BlockChain) logExchangeData(block *types.Block) {
 	if len(txMatchBatchData) == 0 {
 		return
 	}
+	tomoXService.ExportTxMatches(block, txMatchBatchData)
+	if !tomoXService.IsSDKNode() {
+		return
+	}
 	currentState, err := bc.State()
 	if err != nil {
 		log.Error("failed to get current state", "err", err)
 		return
 	}
+	tomoxState, err := tomoXService.GetTomoxState(block)
+	if err != nil {
+		log.Error("failed to get tomox state", "err", err)
+		return
+	}
 	start := time.Now()
 	defer func() {
 		//The deferred call's arguments are evaluated immediately, but the function call is not executed until the surrounding function returns
@@ -2268,7 +2476,7 @@ func (bc *BlockChain) logExchangeData(block *types.Block) {
 			// old txData has been attached with nanosecond, to avoid hard fork, convert nanosecond to millisecond here
 			milliSecond := txMatchBatch.Timestamp / 1e6
 			txMatchTime := time.Unix(0, milliSecond * 1e6).UTC()
-			if err := tomoXService.SyncDataToSDKNode(txMatch, txMatchBatch.TxHash, txMatchTime, currentState); err != nil {
+			if err := tomoXService.SyncDataToSDKNode(txMatch, txMatchBatch.TxHash, txMatchTime, currentState, tomoxState); err != nil {
 				log.Error("failed to SyncDataToSDKNode ", "blockNumber", block.Number(), "err", err)
 				return
 			}
@@ -2282,7 +2490,7 @@ func (bc *BlockChain) reorgTxMatches(deletedTxs types.Transactions, newChain typ
 	if ok {
 		tomoXService = engine.GetTomoXService()
 	}
-	if tomoXService == nil || !tomoXService.IsSDKNode() {
+	if tomoXService == nil || (!tomoXService.IsSDKNode() && !tomoXService.IsExporting()) {
 		return
 	}
 	start := time.Now()
@@ -2293,8 +2501,11 @@ func (bc *BlockChain) reorgTxMatches(deletedTxs types.Transactions, newChain typ
 	}()
 	for _, deletedTx := range deletedTxs {
 		if deletedTx.IsMatchingTransaction() {
-			log.Debug("Rollback reorg txMatch", "txhash", deletedTx.Hash())
-			tomoXService.RollbackReorgTxMatch(deletedTx.Hash())
+			tomoXService.ExportReorgTxMatch(deletedTx)
+			if tomoXService.IsSDKNode() {
+				log.Debug("Rollback reorg txMatch", "txhash", deletedTx.Hash())
+				tomoXService.RollbackReorgTxMatch(deletedTx.Hash())
+			}
 		}
 	}
 
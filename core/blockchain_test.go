@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // Test fork of length N starting from block i
@@ -444,6 +445,43 @@ func testBadHashes(t *testing.T, full bool) {
 	}
 }
 
+// Tests that blocks failing validation are retained with their RLP and error.
+func TestBadBlocksTracking(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks := makeBlockChain(blockchain.CurrentBlock(), 3, ethash.NewFaker(), db, 10)
+	BadHashes[blocks[2].Hash()] = true
+	defer func() { delete(BadHashes, blocks[2].Hash()) }()
+
+	if _, err := blockchain.InsertChain(blocks); err != ErrBlacklistedHash {
+		t.Fatalf("error mismatch: have: %v, want: %v", err, ErrBlacklistedHash)
+	}
+	bad, err := blockchain.BadBlocks()
+	if err != nil {
+		t.Fatalf("failed to retrieve bad blocks: %v", err)
+	}
+	if len(bad) != 1 {
+		t.Fatalf("bad block count mismatch: have %d, want 1", len(bad))
+	}
+	if bad[0].Hash != blocks[2].Hash() {
+		t.Errorf("bad block hash mismatch: have %x, want %x", bad[0].Hash, blocks[2].Hash())
+	}
+	if bad[0].Error != ErrBlacklistedHash.Error() {
+		t.Errorf("bad block error mismatch: have %q, want %q", bad[0].Error, ErrBlacklistedHash)
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(bad[0].RLP, block); err != nil {
+		t.Fatalf("failed to decode bad block: %v", err)
+	}
+	if block.Hash() != blocks[2].Hash() {
+		t.Errorf("decoded bad block hash mismatch: have %x, want %x", block.Hash(), blocks[2].Hash())
+	}
+}
+
 // Tests that bad hashes are detected on boot, and the chain rolled back to a
 // good state prior to the bad hash.
 func TestReorgBadHeaderHashes(t *testing.T) { testReorgBadHashes(t, false) }
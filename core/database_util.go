@@ -58,12 +58,14 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	logIndexPrefix      = []byte("L") // logIndexPrefix + section (uint64 big endian) + hash + log key -> block numbers
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexPrefix       = []byte("iL") // LogIndexPrefix is the data table of the log indexer to track its progress
 
 	// used by old db, now only used for conversion
 	oldReceiptsPrefix = []byte("receipts-")
@@ -240,7 +242,7 @@ func GetBlock(db DatabaseReader, hash common.Hash, number uint64) *types.Block {
 		return nil
 	}
 	// Reassemble the block and return
-	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles)
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles).WithTrades(body.Trades)
 }
 
 // GetBlockReceipts retrieves the receipts generated by the transactions included
@@ -352,6 +354,20 @@ func GetBloomBits(db DatabaseReader, bit uint, section uint64, head common.Hash)
 	return db.Get(key)
 }
 
+// GetLogIndex retrieves the numbers of the blocks of the given section having
+// logs matching a log index key, none if the key is not in the index.
+func GetLogIndex(db DatabaseReader, section uint64, head common.Hash, key []byte) ([]uint64, error) {
+	data, _ := db.Get(logIndexKey(section, head, key))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var numbers []uint64
+	if err := rlp.DecodeBytes(data, &numbers); err != nil {
+		return nil, err
+	}
+	return numbers, nil
+}
+
 // WriteCanonicalHash stores the canonical hash for the given block number.
 func WriteCanonicalHash(db ethdb.Putter, hash common.Hash, number uint64) error {
 	key := append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...)
@@ -513,6 +529,35 @@ func WriteBloomBits(db ethdb.Putter, bit uint, section uint64, head common.Hash,
 	}
 }
 
+// LogIndexAddressKey returns the log index key of the logs emitted by an address.
+func LogIndexAddressKey(address common.Address) []byte {
+	return append([]byte{'a'}, address.Bytes()...)
+}
+
+// LogIndexTopicKey returns the log index key of the logs having a topic at the
+// given position.
+func LogIndexTopicKey(position int, topic common.Hash) []byte {
+	return append([]byte{'t', byte(position)}, topic.Bytes()...)
+}
+
+func logIndexKey(section uint64, head common.Hash, key []byte) []byte {
+	enc := append(append(logIndexPrefix, make([]byte, 8)...), head.Bytes()...)
+	binary.BigEndian.PutUint64(enc[1:], section)
+	return append(enc, key...)
+}
+
+// WriteLogIndex writes the numbers of the blocks of the given section having
+// logs matching a log index key.
+func WriteLogIndex(db ethdb.Putter, section uint64, head common.Hash, key []byte, numbers []uint64) {
+	data, err := rlp.EncodeToBytes(numbers)
+	if err != nil {
+		log.Crit("Failed to encode log index", "err", err)
+	}
+	if err := db.Put(logIndexKey(section, head, key), data); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
 // DeleteCanonicalHash removes the number to hash canonical mapping.
 func DeleteCanonicalHash(db DatabaseDeleter, number uint64) {
 	db.Delete(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
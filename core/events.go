@@ -24,6 +24,15 @@ import (
 // TxPreEvent is posted when a transaction enters the transaction pool.
 type TxPreEvent struct{ Tx *types.Transaction }
 
+// TxLifecycleEvent is posted when a transaction enters the transaction pool,
+// moves within it, or leaves it without being included in a block.
+type TxLifecycleEvent struct {
+	Tx          *types.Transaction
+	Status      string      // One of the TxLifecycle statuses
+	Reason      string      // Why a dropped transaction left the pool, one of the TxDrop reasons
+	Replacement common.Hash // Transaction taking the place of a replaced one
+}
+
 // OrderTxPreEvent is posted when a order transaction enters the order transaction pool.
 type OrderTxPreEvent struct{ Tx *types.OrderTransaction }
 
@@ -55,3 +64,9 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// BadBlockEvent is posted when a block fails validation.
+type BadBlockEvent struct {
+	Block *types.Block
+	Err   error
+}
@@ -153,6 +153,16 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.AllEthashProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil && genesis.Config.Posv != nil {
+		if err := genesis.Config.Posv.Validate(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
+	if genesis != nil && genesis.Config.TomoXLending != nil {
+		if err := genesis.Config.TomoXLending.Validate(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -345,12 +355,21 @@ func DefaultRinkebyGenesisBlock() *Genesis {
 	}
 }
 
-// DeveloperGenesisBlock returns the 'geth --dev' genesis block. Note, this must
-// be seeded with the
+// DeveloperGenesisBlock returns the 'tomo --dev' genesis block. The faucet is
+// the single masternode of the chain; the POSV system contracts are expected to
+// be added to the allocation by the caller.
 func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 	// Override the default period to the user requested one
 	config := *params.AllPosvProtocolChanges
-	config.Posv.Period = period
+	config.Posv = &params.PosvConfig{
+		Period:              period,
+		Epoch:               900,
+		Reward:              250,
+		RewardCheckpoint:    900,
+		Gap:                 5,
+		FoudationWalletAddr: common.HexToAddress(common.FoudationAddr),
+		MaxMasternodes:      1,
+	}
 
 	// Assemble and return the genesis with the precompiles and faucet pre-funded
 	return &Genesis{
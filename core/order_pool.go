@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
@@ -46,6 +47,11 @@ var (
 	ErrInvalidOrderPrice       = errors.New("invalid order price")
 	ErrInvalidOrderHash        = errors.New("invalid order hash")
 	ErrInvalidCancelledOrder   = errors.New("invalid cancel orderid")
+	ErrInvalidAmendedOrder     = errors.New("invalid amend orderid")
+	ErrOrderExpired            = errors.New("order expired")
+	ErrUnsupportedOrder        = errors.New("order not supported by the matching rules")
+
+	ErrInvalidOrderSelfTradePrevention = errors.New("invalid order self-trade prevention")
 )
 
 var (
@@ -53,6 +59,7 @@ var (
 	OrderTypeMarket   = "MO"
 	OrderStatusNew    = "NEW"
 	OrderStatusCancle = "CANCELLED"
+	OrderStatusAmend  = "AMENDED"
 	OrderSideBid      = "BUY"
 	OrderSideAsk      = "SELL"
 )
@@ -73,6 +80,10 @@ type OrderPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	Relayers     []common.Address // Relayers whose orders are accepted, all of them if empty
+	RelayerSlots uint64           // Maximum number of pooled orders per relayer (0 = unlimited)
+	RelayerRate  uint64           // Maximum number of orders accepted per relayer per minute (0 = unlimited)
 }
 
 // blockChain_tomox add order state
@@ -132,8 +143,9 @@ type OrderPool struct {
 	currentOrderState *tomox_state.TomoXStateDB      // Current order state in the blockchain head
 	pendingState      *tomox_state.TomoXManagedState // Pending state tracking virtual nonces
 
-	locals  *orderAccountSet // Set of local transaction to exempt from eviction rules
-	journal *ordertxJournal  // Journal of local transaction to back up to disk
+	locals   *orderAccountSet // Set of local transaction to exempt from eviction rules
+	journal  *ordertxJournal  // Journal of local transaction to back up to disk
+	relayers *relayerSet      // Relayers served by the pool and their quotas
 
 	pending   map[common.Address]*ordertxList         // All currently processable transactions
 	queue     map[common.Address]*ordertxList         // Queued but non-processable transactions
@@ -146,9 +158,9 @@ type OrderPool struct {
 
 // NewOrderPool creates a new transaction pool to gather, sort and filter inbound
 // transactions from the network.
-func NewOrderPool(chainconfig *params.ChainConfig, chain blockChainTomox) *OrderPool {
+func NewOrderPool(config OrderPoolConfig, chainconfig *params.ChainConfig, chain blockChainTomox) *OrderPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
-	config := (&DefaultOrderPoolConfig).sanitize()
+	config = (&config).sanitize()
 	log.Debug("NewOrderPool start...", "current block", chain.CurrentBlock().Header().Number)
 	// Create the transaction pool with its initial settings
 	pool := &OrderPool{
@@ -163,6 +175,7 @@ func NewOrderPool(chainconfig *params.ChainConfig, chain blockChainTomox) *Order
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 	}
 	pool.locals = newOrderAccountSet(pool.signer)
+	pool.relayers = newRelayerSet(&config)
 	pool.reset(nil, chain.CurrentBlock())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -428,12 +441,30 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 	if orderType != OrderTypeLimit && orderType != OrderTypeMarket {
 		return ErrInvalidOrderType
 	}
-	if orderStatus != OrderStatusNew && orderStatus != OrderStatusCancle {
+	if orderStatus != OrderStatusNew && orderStatus != OrderStatusCancle && orderStatus != OrderStatusAmend {
 		return ErrInvalidOrderStatus
 	}
+	// Only limit orders rest in the order book, to be amended
+	if orderStatus == OrderStatusAmend && orderType != OrderTypeLimit {
+		return ErrInvalidOrderType
+	}
+	switch tx.SelfTradePrevention() {
+	case "", types.SelfTradePreventionCancelNewest, types.SelfTradePreventionCancelOldest, types.SelfTradePreventionCancelBoth:
+	default:
+		return ErrInvalidOrderSelfTradePrevention
+	}
 	var signer = types.OrderTxSigner{}
 
-	if !tx.IsCancelledOrder() {
+	if tx.IsCancelledOrder() {
+		if tx.OrderID() == 0 {
+			return ErrInvalidCancelledOrder
+		}
+	} else if tx.IsAmendedOrder() {
+		// Amendments carry the hash of the order they amend
+		if tx.OrderID() == 0 || common.EmptyHash(tx.OrderHash()) {
+			return ErrInvalidAmendedOrder
+		}
+	} else {
 		if !common.EmptyHash(tx.OrderHash()) {
 			if signer.Hash(tx) != tx.OrderHash() {
 				return ErrInvalidOrderHash
@@ -441,11 +472,6 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 		} else {
 			tx.SetOrderHash(signer.Hash(tx))
 		}
-
-	} else {
-		if tx.OrderID() == 0 {
-			return ErrInvalidCancelledOrder
-		}
 	}
 
 	from, _ := types.OrderSender(pool.signer, tx)
@@ -453,6 +479,20 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 		return ErrInvalidOrderUserAddress
 	}
 
+	// Orders can be matched up to their expiry block, the next one at the earliest
+	if expiry := tx.ExpiryBlock(); expiry != 0 && expiry <= pool.chain.CurrentBlock().NumberU64() {
+		return ErrOrderExpired
+	}
+	// Amendments, expiries and self-trade prevention wait for the matching rules
+	// supporting them
+	next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+	rules := tomox.NewMatchingRules(pool.chainconfig.TomoXVersion(next))
+	if (tx.IsAmendedOrder() && !rules.IsAmendment) || (tx.ExpiryBlock() != 0 && !rules.IsExpiry) {
+		return ErrUnsupportedOrder
+	}
+	if tx.SelfTradePrevention() != "" && !rules.IsSelfTradePrevention {
+		return ErrUnsupportedOrder
+	}
 	statedb, err := pool.chain.StateAt(pool.chain.CurrentBlock().Root())
 	if err != nil {
 		return fmt.Errorf("failed to get statedb Error: %v", err)
@@ -463,6 +503,11 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 	if err := tomox_state.VerifyPair(statedb, tx.ExchangeAddress(), tx.BaseToken(), tx.QuoteToken()); err != nil {
 		return err
 	}
+	if !tx.IsCancelledOrder() {
+		if err := tomox_state.VerifyPairSize(statedb, tx.BaseToken(), tx.QuoteToken(), tx.Type(), price, quantity); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -514,6 +559,12 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 		log.Trace("Discarding already known transaction", "hash", hash)
 		return false, fmt.Errorf("known transaction: %x", hash)
 	}
+	// If the relayer isn't served by the node, discard it before validating
+	if !pool.relayers.serves(tx.ExchangeAddress()) {
+		log.Trace("Discarding order of unserved relayer", "hash", hash, "relayer", tx.ExchangeAddress())
+		invalidTxCounter.Inc(1)
+		return false, ErrRelayerNotServed
+	}
 
 	// If the transaction fails basic validation, discard it
 	if err := pool.validateTx(tx, local); err != nil {
@@ -523,6 +574,15 @@ func (pool *OrderPool) add(tx *types.OrderTransaction, local bool) (bool, error)
 	}
 	from, _ := types.OrderSender(pool.signer, tx) // already validated
 
+	// If the relayer exhausted its quotas, discard the order
+	pooled := uint64(0)
+	if pool.relayers.slots > 0 {
+		pooled = pool.relayerOrders(tx.ExchangeAddress())
+	}
+	if err := pool.relayers.admit(tx.ExchangeAddress(), pooled, time.Now()); err != nil {
+		log.Trace("Discarding order exceeding relayer quota", "hash", hash, "relayer", tx.ExchangeAddress(), "err", err)
+		return false, err
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 	}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// The relayer and pair listed in the genesis of the fuzzed pool, which the
+// corpus of tomox/gen_fuzz_corpus.go trades.
+var (
+	fuzzRelayer    = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+	fuzzBaseToken  = common.HexToAddress("0x4d7eA2cE949216D6b120f3AA10164173615A2b6C")
+	fuzzQuoteToken = common.HexToAddress("0x0000000000000000000000000000000000000001")
+)
+
+// fuzzChain is a chain stuck at a genesis block, enough for the order pool to
+// validate orders against.
+type fuzzChain struct {
+	db      ethdb.Database
+	genesis *types.Block
+	feed    event.Feed
+}
+
+func (bc *fuzzChain) CurrentBlock() *types.Block { return bc.genesis }
+
+func (bc *fuzzChain) GetBlock(hash common.Hash, number uint64) *types.Block { return bc.genesis }
+
+func (bc *fuzzChain) OrderStateAt(block *types.Block) (*tomox_state.TomoXStateDB, error) {
+	return tomox_state.New(common.Hash{}, tomox_state.NewDatabase(bc.db))
+}
+
+func (bc *fuzzChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, state.NewDatabase(bc.db))
+}
+
+func (bc *fuzzChain) SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription {
+	return bc.feed.Subscribe(ch)
+}
+
+var fuzzPool = newFuzzOrderPool()
+
+// newFuzzOrderPool creates an order pool on a genesis registering fuzzRelayer
+// with its single pair.
+func newFuzzOrderPool() *OrderPool {
+	storage := make(map[common.Hash]common.Hash)
+	loc := tomox_state.GetLocMappingAtKey(fuzzRelayer.Hash(), tomox_state.RelayerMappingSlot["RELAYER_LIST"])
+	storage[common.BigToHash(new(big.Int).Add(loc, tomox_state.RelayerStructMappingSlot["_deposit"]))] = common.BigToHash(big.NewInt(1))
+	for slot, token := range map[string]common.Address{"_fromTokens": fuzzBaseToken, "_toTokens": fuzzQuoteToken} {
+		lengthLoc := common.BigToHash(new(big.Int).Add(loc, tomox_state.RelayerStructMappingSlot[slot]))
+		storage[lengthLoc] = common.BigToHash(big.NewInt(1))
+		storage[state.GetLocDynamicArrAtElement(lengthLoc, 0, 1)] = token.Hash()
+	}
+	db, _ := ethdb.NewMemDatabase()
+	genesis := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			common.HexToAddress(common.RelayerRegistrationSMC): {Balance: new(big.Int), Storage: storage},
+		},
+	}
+	return NewOrderPool(DefaultOrderPoolConfig, params.TestChainConfig, &fuzzChain{db: db, genesis: genesis.MustCommit(db)})
+}
+
+// Fuzz implements a go-fuzz fuzzer method to test the validation of orders
+// received by the order pool, which accepts them without any fee.
+func Fuzz(data []byte) int {
+	tx := new(types.OrderTransaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		return 0
+	}
+	fuzzPool.mu.Lock()
+	err := fuzzPool.validateTx(tx, false)
+	fuzzPool.mu.Unlock()
+	if err != nil {
+		return 0
+	}
+	if from := tx.From(); from == nil || *from != tx.UserAddress() {
+		panic("valid order not signed by its user")
+	}
+	return 1
+}
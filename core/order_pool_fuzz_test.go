@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the fuzzer accepts the signed orders of the listed pair, and
+// rejects those of unlisted pairs, unsigned ones and those preventing self
+// trades before the matching rules support it.
+func TestFuzz(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	order := func(quote common.Address, mode string) *types.OrderTransaction {
+		tx := types.NewOrderTransaction(0, new(big.Int).Mul(big.NewInt(10), common.BasePrice), new(big.Int).Mul(big.NewInt(100), common.BasePrice), fuzzRelayer, user, fuzzBaseToken, quote, types.OrderStatusNew, "SELL", "LO", "BTC/TOMO", common.Hash{}, 0)
+		tx.SetSelfTradePrevention(mode)
+		tx.SetOrderHash(types.OrderTxSigner{}.Hash(tx))
+		return tx
+	}
+	sign := func(tx *types.OrderTransaction) []byte {
+		signed, err := types.OrderSignTx(tx, types.OrderTxSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, _ := rlp.EncodeToBytes(signed)
+		return enc
+	}
+	unsigned, _ := rlp.EncodeToBytes(order(fuzzQuoteToken, ""))
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"listed pair", sign(order(fuzzQuoteToken, "")), 1},
+		{"unlisted pair", sign(order(common.Address{4}, "")), 0},
+		{"self-trade prevention", sign(order(fuzzQuoteToken, types.SelfTradePreventionCancelNewest)), 0},
+		{"unsigned", unsigned, 0},
+		{"malformed", []byte{0xc1}, 0},
+	}
+	for _, tt := range tests {
+		if res := Fuzz(tt.data); res != tt.want {
+			t.Errorf("%s: fuzz result mismatch: have %d, want %d", tt.name, res, tt.want)
+		}
+	}
+}
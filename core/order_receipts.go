@@ -0,0 +1,58 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomox"
+)
+
+var orderReceiptPrefix = []byte("order-receipt-") // orderReceiptPrefix + order hash -> order receipt
+
+// GetOrderReceipt retrieves the receipt of the last execution of an order. The
+// block it refers to may have been dropped by a reorg since.
+func GetOrderReceipt(db DatabaseReader, hash common.Hash) *tomox.OrderReceipt {
+	data, _ := db.Get(append(orderReceiptPrefix, hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	receipt := new(tomox.OrderReceipt)
+	if err := json.Unmarshal(data, receipt); err != nil {
+		log.Error("Invalid order receipt JSON", "hash", hash, "err", err)
+		return nil
+	}
+	return receipt
+}
+
+// WriteOrderReceipts stores the receipts of the orders matched in a block,
+// enabling hash based order receipt lookups.
+func WriteOrderReceipts(db ethdb.Putter, block *types.Block) error {
+	for _, receipt := range tomox.NewOrderReceipts(block) {
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return err
+		}
+		if err := db.Put(append(orderReceiptPrefix, receipt.OrderHash.Bytes()...), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
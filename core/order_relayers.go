@@ -0,0 +1,165 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// relayerRateWindow is the period over which the order rate of a relayer is
+// limited.
+const relayerRateWindow = time.Minute
+
+var (
+	// ErrRelayerNotServed is returned if an order is sent to a relayer the node
+	// isn't configured to serve.
+	ErrRelayerNotServed = errors.New("relayer not served")
+
+	// ErrRelayerQuotaExceeded is returned if the relayer of an order already has
+	// the maximum number of orders allowed in the pool.
+	ErrRelayerQuotaExceeded = errors.New("relayer order quota exceeded")
+
+	// ErrRelayerRateLimited is returned if the relayer of an order had the
+	// maximum number of orders accepted within the rate window.
+	ErrRelayerRateLimited = errors.New("relayer order rate exceeded")
+)
+
+// RelayerStats is the usage of the order pool by a relayer.
+type RelayerStats struct {
+	Pending  int    `json:"pending"`  // Number of executable orders in the pool
+	Queued   int    `json:"queued"`   // Number of non-executable orders in the pool
+	Accepted uint64 `json:"accepted"` // Number of orders accepted since startup
+	Rejected uint64 `json:"rejected"` // Number of orders rejected by the quotas since startup
+	Slots    uint64 `json:"slots"`    // Maximum number of orders in the pool (0 = unlimited)
+	Rate     uint64 `json:"rate"`     // Maximum number of orders accepted per minute (0 = unlimited)
+}
+
+// relayerUsage is the quota accounting of a single relayer.
+type relayerUsage struct {
+	accepted uint64    // Number of orders accepted since startup
+	rejected uint64    // Number of orders rejected since startup
+	window   time.Time // Start of the current rate window
+	recent   uint64    // Number of orders accepted in the current rate window
+}
+
+// relayerSet tracks the relayers whose orders the pool accepts, enforcing their
+// quotas. It isn't thread safe, the pool lock guarding it.
+type relayerSet struct {
+	served map[common.Address]struct{} // Relayers served by the node, all of them if empty
+	slots  uint64                      // Maximum number of pooled orders per relayer
+	rate   uint64                      // Maximum number of orders accepted per relayer per window
+	usage  map[common.Address]*relayerUsage
+}
+
+// newRelayerSet creates the relayer set of an order pool configuration.
+func newRelayerSet(config *OrderPoolConfig) *relayerSet {
+	set := &relayerSet{
+		served: make(map[common.Address]struct{}),
+		slots:  config.RelayerSlots,
+		rate:   config.RelayerRate,
+		usage:  make(map[common.Address]*relayerUsage),
+	}
+	for _, relayer := range config.Relayers {
+		set.served[relayer] = struct{}{}
+		set.usage[relayer] = new(relayerUsage)
+	}
+	return set
+}
+
+// serves checks whether the orders of a relayer are accepted at all.
+func (set *relayerSet) serves(relayer common.Address) bool {
+	if len(set.served) == 0 {
+		return true
+	}
+	_, ok := set.served[relayer]
+	return ok
+}
+
+// admit accounts a new order of a served relayer already having pooled orders
+// in the pool, rejecting it if it exceeds the quotas of the relayer.
+func (set *relayerSet) admit(relayer common.Address, pooled uint64, now time.Time) error {
+	usage := set.usage[relayer]
+	if usage == nil {
+		usage = new(relayerUsage)
+		set.usage[relayer] = usage
+	}
+	if set.slots > 0 && pooled >= set.slots {
+		usage.rejected++
+		return ErrRelayerQuotaExceeded
+	}
+	if set.rate > 0 {
+		if now.Sub(usage.window) >= relayerRateWindow {
+			usage.window, usage.recent = now, 0
+		}
+		if usage.recent >= set.rate {
+			usage.rejected++
+			return ErrRelayerRateLimited
+		}
+		usage.recent++
+	}
+	usage.accepted++
+	return nil
+}
+
+// relayerOrders counts the orders of a relayer in the pool.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *OrderPool) relayerOrders(relayer common.Address) uint64 {
+	count := uint64(0)
+	for _, tx := range pool.all {
+		if tx.ExchangeAddress() == relayer {
+			count++
+		}
+	}
+	return count
+}
+
+// RelayerStats retrieves the usage of the order pool by the relayers served by
+// the node or, if it serves all of them, by the ones having sent orders.
+func (pool *OrderPool) RelayerStats() map[common.Address]RelayerStats {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	stats := make(map[common.Address]RelayerStats, len(pool.relayers.usage))
+	for relayer, usage := range pool.relayers.usage {
+		stats[relayer] = RelayerStats{
+			Accepted: usage.accepted,
+			Rejected: usage.rejected,
+			Slots:    pool.relayers.slots,
+			Rate:     pool.relayers.rate,
+		}
+	}
+	for _, list := range pool.pending {
+		for _, tx := range list.Flatten() {
+			if relayer, ok := stats[tx.ExchangeAddress()]; ok {
+				relayer.Pending++
+				stats[tx.ExchangeAddress()] = relayer
+			}
+		}
+	}
+	for _, list := range pool.queue {
+		for _, tx := range list.Flatten() {
+			if relayer, ok := stats[tx.ExchangeAddress()]; ok {
+				relayer.Queued++
+				stats[tx.ExchangeAddress()] = relayer
+			}
+		}
+	}
+	return stats
+}
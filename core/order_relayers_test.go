@@ -0,0 +1,70 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that only the configured relayers are served, all of them being served
+// if none is configured.
+func TestRelayerSetServes(t *testing.T) {
+	served, other := common.Address{0x01}, common.Address{0x02}
+
+	if set := newRelayerSet(&OrderPoolConfig{}); !set.serves(served) || !set.serves(other) {
+		t.Fatalf("relayers not served without configuration")
+	}
+	set := newRelayerSet(&OrderPoolConfig{Relayers: []common.Address{served}})
+	if !set.serves(served) {
+		t.Fatalf("configured relayer not served")
+	}
+	if set.serves(other) {
+		t.Fatalf("unconfigured relayer served")
+	}
+}
+
+// Tests that the orders of a relayer are rejected once it has its slots filled
+// or had its rate of orders accepted within the window.
+func TestRelayerSetQuotas(t *testing.T) {
+	relayer := common.Address{0x01}
+	now := time.Now()
+
+	set := newRelayerSet(&OrderPoolConfig{RelayerSlots: 2})
+	if err := set.admit(relayer, 1, now); err != nil {
+		t.Fatalf("order within slots rejected: %v", err)
+	}
+	if err := set.admit(relayer, 2, now); err != ErrRelayerQuotaExceeded {
+		t.Fatalf("order over slots error mismatch: have %v, want %v", err, ErrRelayerQuotaExceeded)
+	}
+	set = newRelayerSet(&OrderPoolConfig{RelayerRate: 2})
+	for i := 0; i < 2; i++ {
+		if err := set.admit(relayer, 0, now); err != nil {
+			t.Fatalf("order %d within rate rejected: %v", i, err)
+		}
+	}
+	if err := set.admit(relayer, 0, now.Add(time.Second)); err != ErrRelayerRateLimited {
+		t.Fatalf("order over rate error mismatch: have %v, want %v", err, ErrRelayerRateLimited)
+	}
+	if err := set.admit(relayer, 0, now.Add(relayerRateWindow)); err != nil {
+		t.Fatalf("order in next window rejected: %v", err)
+	}
+	if usage := set.usage[relayer]; usage.accepted != 3 || usage.rejected != 1 {
+		t.Fatalf("usage mismatch: have %d accepted, %d rejected, want 3, 1", usage.accepted, usage.rejected)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+)
+
+// senderCacher is a concurrent transaction sender recoverer and cacher.
+var senderCacher = newTxSenderCacher(runtime.NumCPU())
+
+// txSenderCacherRequest is a request for recovering transaction senders and
+// the signers of the orders carried by the TomoX special transactions, with a
+// specific signature scheme and caching them into the transactions themselves
+// and the order signer cache.
+//
+// The inc field defines the number of items to skip after each recovery, which
+// is used to feed the same underlying input arrays to different threads but
+// ensure they process the early items fast.
+type txSenderCacherRequest struct {
+	signer        types.Signer
+	txs           []*types.Transaction
+	orders        []*tomox_state.OrderItem
+	lendingOrders []*tomoxlending.LendingOrder
+	inc           int
+}
+
+// txSenderCacher is a helper structure to concurrently ecrecover transaction
+// senders and order signers from digital signatures on background threads.
+type txSenderCacher struct {
+	threads int
+	tasks   chan *txSenderCacherRequest
+}
+
+// newTxSenderCacher creates a new transaction sender background cacher and starts
+// as many processing goroutines as allowed by the GOMAXPROCS on construction.
+func newTxSenderCacher(threads int) *txSenderCacher {
+	cacher := &txSenderCacher{
+		tasks:   make(chan *txSenderCacherRequest, threads),
+		threads: threads,
+	}
+	for i := 0; i < threads; i++ {
+		go cacher.cache()
+	}
+	return cacher
+}
+
+// cache is an infinite loop, caching transaction senders and order signers
+// from various forms of data structures.
+func (cacher *txSenderCacher) cache() {
+	for task := range cacher.tasks {
+		for i := 0; i < len(task.txs); i += task.inc {
+			types.Sender(task.signer, task.txs[i])
+		}
+		// Failed orders are rejected again when the block is processed
+		for i := 0; i < len(task.orders); i += task.inc {
+			task.orders[i].VerifyBasicOrderInfo()
+		}
+		for i := 0; i < len(task.lendingOrders); i += task.inc {
+			task.lendingOrders[i].Sender()
+		}
+	}
+}
+
+// recover recovers the senders from a batch of transactions and caches them
+// back into the same data structures. There is no validation being done, nor
+// any reaction to invalid signatures. That is up to calling code later.
+func (cacher *txSenderCacher) recover(signer types.Signer, txs []*types.Transaction) {
+	cacher.recoverAll(signer, txs, nil, nil)
+}
+
+// recoverFromBlocks recovers the senders from a batch of blocks and caches them
+// back into the same data structures. The orders of the matching and lending
+// transactions are decoded and their signers recovered into the order signer
+// cache as well. There is no validation being done, nor any reaction to
+// invalid signatures. That is up to calling code later.
+func (cacher *txSenderCacher) recoverFromBlocks(signer types.Signer, blocks []*types.Block) {
+	var (
+		txs           []*types.Transaction
+		orders        []*tomox_state.OrderItem
+		lendingOrders []*tomoxlending.LendingOrder
+	)
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			txs = append(txs, tx)
+
+			switch {
+			case tx.IsMatchingTransaction():
+				batch, err := tomox.DecodeTxMatchesBatch(tx.Data())
+				if err != nil {
+					continue
+				}
+				for _, txMatch := range batch.Data {
+					if order, err := txMatch.DecodeOrder(); err == nil {
+						orders = append(orders, order)
+					}
+				}
+			case tx.IsLendingTransaction():
+				if batch, err := tomoxlending.DecodeLendingBatch(tx.Data()); err == nil {
+					lendingOrders = append(lendingOrders, batch.Orders...)
+				}
+			}
+		}
+	}
+	cacher.recoverAll(signer, txs, orders, lendingOrders)
+}
+
+// recoverAll spreads the recovery of the given transactions and orders over
+// the processing goroutines.
+func (cacher *txSenderCacher) recoverAll(signer types.Signer, txs []*types.Transaction, orders []*tomox_state.OrderItem, lendingOrders []*tomoxlending.LendingOrder) {
+	// If there's nothing to recover, abort
+	size := len(txs) + len(orders) + len(lendingOrders)
+	if size == 0 {
+		return
+	}
+	// Ensure we have meaningful task sizes and schedule the recoveries
+	tasks := cacher.threads
+	if size < tasks*4 {
+		tasks = (size + 3) / 4
+	}
+	for i := 0; i < tasks; i++ {
+		req := &txSenderCacherRequest{
+			signer: signer,
+			inc:    tasks,
+		}
+		if i < len(txs) {
+			req.txs = txs[i:]
+		}
+		if i < len(orders) {
+			req.orders = orders[i:]
+		}
+		if i < len(lendingOrders) {
+			req.lendingOrders = lendingOrders[i:]
+		}
+		cacher.tasks <- req
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrInvalidSigningTx is returned if a transaction sent to the block
+	// signers contract is not a block signature.
+	ErrInvalidSigningTx = errors.New("invalid block signing transaction")
+
+	// ErrBlockDataTx is returned if a transaction carrying block data, such as
+	// the TomoX matches, is submitted to the pool instead of being added by
+	// the block producer.
+	ErrBlockDataTx = errors.New("transaction reserved to block producers")
+)
+
+// SpecialTxValidator checks a transaction sent to a system contract before it
+// enters the transaction pool.
+type SpecialTxValidator func(contract *params.SpecialTxContract, tx *types.Transaction, from common.Address) error
+
+var (
+	specialTxValidatorsMu sync.RWMutex
+	specialTxValidators   = map[string][]SpecialTxValidator{
+		"blockSigners":  {validateSigningTx},
+		"tomoxMatching": {rejectBlockDataTx},
+		"tomoxState":    {rejectBlockDataTx},
+		"tomoxLending":  {rejectBlockDataTx},
+	}
+)
+
+// RegisterSpecialTxValidator adds a validator of the transactions sent to the
+// system contract with the given name in params.SpecialTxs.
+func RegisterSpecialTxValidator(name string, validator SpecialTxValidator) {
+	specialTxValidatorsMu.Lock()
+	defer specialTxValidatorsMu.Unlock()
+
+	specialTxValidators[name] = append(specialTxValidators[name], validator)
+}
+
+// ValidateSpecialTx runs the validators registered for the system contract a
+// transaction is sent to, if any.
+func ValidateSpecialTx(tx *types.Transaction, from common.Address) error {
+	contract := params.SpecialTxs.Lookup(tx.To())
+	if contract == nil {
+		return nil
+	}
+	specialTxValidatorsMu.RLock()
+	validators := specialTxValidators[contract.Name]
+	specialTxValidatorsMu.RUnlock()
+
+	for _, validate := range validators {
+		if err := validate(contract, tx, from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSigningTx(contract *params.SpecialTxContract, tx *types.Transaction, from common.Address) error {
+	if !tx.IsSigningTransaction() {
+		return ErrInvalidSigningTx
+	}
+	return nil
+}
+
+func rejectBlockDataTx(contract *params.SpecialTxContract, tx *types.Transaction, from common.Address) error {
+	return ErrBlockDataTx
+}
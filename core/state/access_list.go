@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accessList is the set of accounts and storage slots accessed by a
+// transaction, which are warm for the gas metering of EIP-2929.
+type accessList struct {
+	addresses map[common.Address]map[common.Hash]struct{}
+}
+
+func newAccessList() *accessList {
+	return &accessList{addresses: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+// containsAddress returns whether the address is in the access list.
+func (al *accessList) containsAddress(addr common.Address) bool {
+	_, ok := al.addresses[addr]
+	return ok
+}
+
+// contains returns whether the address and the slot are in the access list.
+func (al *accessList) contains(addr common.Address, slot common.Hash) (addressOk bool, slotOk bool) {
+	slots, ok := al.addresses[addr]
+	if !ok {
+		return false, false
+	}
+	_, slotOk = slots[slot]
+	return true, slotOk
+}
+
+// addAddress adds an address to the access list, returning whether it was
+// missing.
+func (al *accessList) addAddress(addr common.Address) bool {
+	if _, ok := al.addresses[addr]; ok {
+		return false
+	}
+	al.addresses[addr] = make(map[common.Hash]struct{})
+	return true
+}
+
+// addSlot adds a slot along with its address to the access list, returning
+// whether each of them was missing.
+func (al *accessList) addSlot(addr common.Address, slot common.Hash) (addrChange bool, slotChange bool) {
+	addrChange = al.addAddress(addr)
+	if _, ok := al.addresses[addr][slot]; ok {
+		return addrChange, false
+	}
+	al.addresses[addr][slot] = struct{}{}
+	return addrChange, true
+}
+
+// deleteAddress removes an address from the access list, it must have no
+// slot left.
+func (al *accessList) deleteAddress(addr common.Address) {
+	delete(al.addresses, addr)
+}
+
+// deleteSlot removes a slot from the access list, leaving its address.
+func (al *accessList) deleteSlot(addr common.Address, slot common.Hash) {
+	delete(al.addresses[addr], slot)
+}
+
+// copy creates an independent copy of the access list.
+func (al *accessList) copy() *accessList {
+	cpy := newAccessList()
+	for addr, slots := range al.addresses {
+		cpySlots := make(map[common.Hash]struct{}, len(slots))
+		for slot := range slots {
+			cpySlots[slot] = struct{}{}
+		}
+		cpy.addresses[addr] = cpySlots
+	}
+	return cpy
+}
@@ -0,0 +1,198 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// stateAccesses records the accounts and storage slots of a state read and
+// written since the tracking was started on it. Balances only ever increased
+// are kept apart, as increases in independent copies of a state add up.
+type stateAccesses struct {
+	accountReads  map[common.Address]struct{}
+	accountWrites map[common.Address]struct{}
+	storageScans  map[common.Address]struct{} // Accounts whose whole storage was read
+	slotReads     map[common.Address]map[common.Hash]struct{}
+	slotWrites    map[common.Address]map[common.Hash]struct{}
+	balanceAdds   map[common.Address]*big.Int // Balances before the first increase
+}
+
+func newStateAccesses() *stateAccesses {
+	return &stateAccesses{
+		accountReads:  make(map[common.Address]struct{}),
+		accountWrites: make(map[common.Address]struct{}),
+		storageScans:  make(map[common.Address]struct{}),
+		slotReads:     make(map[common.Address]map[common.Hash]struct{}),
+		slotWrites:    make(map[common.Address]map[common.Hash]struct{}),
+		balanceAdds:   make(map[common.Address]*big.Int),
+	}
+}
+
+func addSlot(slots map[common.Address]map[common.Hash]struct{}, addr common.Address, key common.Hash) {
+	if slots[addr] == nil {
+		slots[addr] = make(map[common.Hash]struct{})
+	}
+	slots[addr][key] = struct{}{}
+}
+
+// readAccount records a read of the fields of an account.
+func (self *StateDB) readAccount(addr common.Address) {
+	if self.accesses != nil {
+		self.accesses.accountReads[addr] = struct{}{}
+	}
+}
+
+// writeAccount records a write of the fields of an account.
+func (self *StateDB) writeAccount(addr common.Address) {
+	if self.accesses != nil {
+		self.accesses.accountWrites[addr] = struct{}{}
+	}
+}
+
+// addAccountBalance records an increase of the balance of an account, which
+// is a plain write when it creates the account.
+func (self *StateDB) addAccountBalance(addr common.Address) {
+	if self.accesses == nil {
+		return
+	}
+	if _, ok := self.accesses.balanceAdds[addr]; ok {
+		return
+	}
+	stateObject := self.getStateObject(addr)
+	if stateObject == nil {
+		self.accesses.accountWrites[addr] = struct{}{}
+		return
+	}
+	self.accesses.balanceAdds[addr] = new(big.Int).Set(stateObject.Balance())
+}
+
+// readSlot records a read of a storage slot.
+func (self *StateDB) readSlot(addr common.Address, key common.Hash) {
+	if self.accesses != nil {
+		addSlot(self.accesses.slotReads, addr, key)
+	}
+}
+
+// writeSlot records a write of a storage slot, which writes the account too
+// when it creates it.
+func (self *StateDB) writeSlot(addr common.Address, key common.Hash) {
+	if self.accesses == nil {
+		return
+	}
+	if self.getStateObject(addr) == nil {
+		self.accesses.accountWrites[addr] = struct{}{}
+	}
+	addSlot(self.accesses.slotWrites, addr, key)
+}
+
+// scanStorage records a read of the whole storage of an account.
+func (self *StateDB) scanStorage(addr common.Address) {
+	if self.accesses != nil {
+		self.accesses.accountReads[addr] = struct{}{}
+		self.accesses.storageScans[addr] = struct{}{}
+	}
+}
+
+// TrackAccesses starts recording the accounts and storage slots the state is
+// read and written at, dropping the ones recorded so far. It is meant for the
+// copies of a state changed concurrently, to check that their changes are
+// independent with ConflictsWith and to apply them with MergeWrites.
+func (self *StateDB) TrackAccesses() {
+	self.accesses = newStateAccesses()
+}
+
+// ConflictsWith reports whether an account or a storage slot written in one of
+// the tracked states was read or written in the other one, in which case the
+// changes of the states depend on the order they are applied in.
+func (self *StateDB) ConflictsWith(other *StateDB) bool {
+	if self.accesses == nil || other.accesses == nil {
+		return true
+	}
+	return self.accesses.overwrites(other.accesses) || other.accesses.overwrites(self.accesses)
+}
+
+// overwrites reports whether the writes of a change the state read or written
+// by b.
+func (a *stateAccesses) overwrites(b *stateAccesses) bool {
+	for addr := range a.accountWrites {
+		if _, ok := b.accountReads[addr]; ok {
+			return true
+		}
+		if _, ok := b.accountWrites[addr]; ok {
+			return true
+		}
+		if _, ok := b.balanceAdds[addr]; ok {
+			return true
+		}
+		// Creating an account drops its storage
+		if len(b.slotReads[addr]) > 0 || len(b.slotWrites[addr]) > 0 {
+			return true
+		}
+	}
+	for addr := range a.balanceAdds {
+		if _, ok := b.accountReads[addr]; ok {
+			return true
+		}
+	}
+	for addr, keys := range a.slotWrites {
+		if _, ok := b.storageScans[addr]; ok {
+			return true
+		}
+		for key := range keys {
+			if _, ok := b.slotReads[addr][key]; ok {
+				return true
+			}
+			if _, ok := b.slotWrites[addr][key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MergeWrites applies to the state the writes recorded in a tracked copy of
+// it. The balance increases of the copy are added to the balances of the
+// state, the other writes overwrite them. The merge is journalled like any
+// other change of the state.
+func (self *StateDB) MergeWrites(src *StateDB) {
+	if src.accesses == nil {
+		return
+	}
+	for addr := range src.accesses.accountWrites {
+		obj := src.getStateObject(addr)
+		if obj == nil {
+			continue
+		}
+		if !self.Exist(addr) {
+			self.CreateAccount(addr)
+		}
+		self.SetBalance(addr, obj.Balance())
+		self.SetNonce(addr, obj.Nonce())
+		if self.GetCodeHash(addr) != common.BytesToHash(obj.CodeHash()) {
+			self.SetCode(addr, obj.Code(src.db))
+		}
+		if obj.suicided {
+			self.Suicide(addr)
+		}
+	}
+	for addr, prev := range src.accesses.balanceAdds {
+		if _, ok := src.accesses.accountWrites[addr]; ok {
+			continue
+		}
+		if obj := src.getStateObject(addr); obj != nil {
+			if diff := new(big.Int).Sub(obj.Balance(), prev); diff.Sign() > 0 {
+				self.AddBalance(addr, diff)
+			}
+		}
+	}
+	for addr, keys := range src.accesses.slotWrites {
+		obj := src.getStateObject(addr)
+		if obj == nil {
+			continue
+		}
+		for key := range keys {
+			self.SetState(addr, key, obj.GetState(src.db, key))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestMergeWrites(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	token, fees := common.Address{0x01}, common.Address{0x02}
+	state.SetState(token, common.Hash{0x01}, common.Hash{0x01})
+	state.SetState(token, common.Hash{0x02}, common.Hash{0x02})
+	state.AddBalance(fees, big.NewInt(10))
+	state.IntermediateRoot(false)
+
+	// Copies changing distinct slots of a same account and adding to a same
+	// balance are independent
+	first, second := state.Copy(), state.Copy()
+	first.TrackAccesses()
+	second.TrackAccesses()
+
+	first.SetState(token, common.Hash{0x01}, common.Hash{0x11})
+	first.AddBalance(fees, big.NewInt(1))
+	second.SetState(token, common.Hash{0x02}, common.Hash{0x22})
+	second.AddBalance(fees, big.NewInt(2))
+	second.SetNonce(common.Address{0x03}, 7)
+
+	if first.ConflictsWith(second) || second.ConflictsWith(first) {
+		t.Fatalf("independent copies reported as conflicting")
+	}
+	state.MergeWrites(first)
+	state.MergeWrites(second)
+
+	if value := state.GetState(token, common.Hash{0x01}); value != (common.Hash{0x11}) {
+		t.Errorf("first slot mismatch: have %x, want %x", value, common.Hash{0x11})
+	}
+	if value := state.GetState(token, common.Hash{0x02}); value != (common.Hash{0x22}) {
+		t.Errorf("second slot mismatch: have %x, want %x", value, common.Hash{0x22})
+	}
+	if balance := state.GetBalance(fees); balance.Cmp(big.NewInt(13)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 13", balance)
+	}
+	if nonce := state.GetNonce(common.Address{0x03}); nonce != 7 {
+		t.Errorf("nonce mismatch: have %d, want 7", nonce)
+	}
+	// Copies reading what the other one wrote are not
+	tests := []struct {
+		name          string
+		first, second func(s *StateDB)
+	}{
+		{
+			"slot read and written",
+			func(s *StateDB) { s.GetState(token, common.Hash{0x01}) },
+			func(s *StateDB) { s.SetState(token, common.Hash{0x01}, common.Hash{}) },
+		},
+		{
+			"slot written twice",
+			func(s *StateDB) { s.SetState(token, common.Hash{0x01}, common.Hash{0x01}) },
+			func(s *StateDB) { s.SetState(token, common.Hash{0x01}, common.Hash{0x01}) },
+		},
+		{
+			"balance read and increased",
+			func(s *StateDB) { s.GetBalance(fees) },
+			func(s *StateDB) { s.AddBalance(fees, common.Big1) },
+		},
+		{
+			"balance decreased and increased",
+			func(s *StateDB) { s.SubBalance(fees, common.Big1) },
+			func(s *StateDB) { s.AddBalance(fees, common.Big1) },
+		},
+		{
+			"storage scanned and written",
+			func(s *StateDB) { s.ForEachStorage(token, func(key, value common.Hash) bool { return true }) },
+			func(s *StateDB) { s.SetState(token, common.Hash{0x03}, common.Hash{0x03}) },
+		},
+	}
+	for _, tt := range tests {
+		first, second := state.Copy(), state.Copy()
+		first.TrackAccesses()
+		second.TrackAccesses()
+		tt.first(first)
+		tt.second(second)
+		if !first.ConflictsWith(second) || !second.ConflictsWith(first) {
+			t.Errorf("%s: conflict not reported", tt.name)
+		}
+	}
+}
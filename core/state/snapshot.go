@@ -0,0 +1,496 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+var (
+	snapshotMarkerKey = []byte("SnapshotMarker") // generation (uint64 big endian) + root of the complete snapshot, if any
+	snapshotPrefix    = []byte("snap")           // snapshotPrefix + generation + kind + hash(es) -> leaf value
+	snapshotAccount   = byte('a')
+	snapshotStorage   = byte('o')
+
+	errSnapshotAborted = errors.New("snapshot generation aborted")
+)
+
+// snapshotIteratee is implemented by the databases able to iterate over their
+// keys, which allows the entries of the stale generations to be deleted.
+type snapshotIteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Snapshot is a flat copy of the accounts and storage of a single state, kept
+// alongside the trie to serve the state reads without traversing it. The leaf
+// values of the tries are stored keyed by the hashes of their keys, an empty
+// value standing for a deleted entry.
+//
+// The entries are namespaced by a generation number, so that wiping the
+// snapshot only takes to move on to the next generation. The snapshot serves
+// reads only when complete, for the very state root it was built for.
+type Snapshot struct {
+	db ethdb.Database
+
+	gen        uint64        // Generation of the entries
+	root       common.Hash   // State root the snapshot is complete for
+	ready      bool          // Whether the snapshot is complete
+	generating chan struct{} // Closed by the running generation when it exits, nil if none
+	abort      chan struct{} // Closed to abort the running generation
+	lock       sync.RWMutex
+}
+
+// NewSnapshot loads the snapshot stored in the database, if any.
+func NewSnapshot(db ethdb.Database) *Snapshot {
+	s := &Snapshot{db: db}
+	if marker, _ := db.Get(snapshotMarkerKey); len(marker) >= 8 {
+		s.gen = binary.BigEndian.Uint64(marker[:8])
+		if len(marker) == 8+common.HashLength {
+			s.root, s.ready = common.BytesToHash(marker[8:]), true
+		}
+	}
+	return s
+}
+
+// Root returns the state root the snapshot is complete for, and whether it is.
+func (s *Snapshot) Root() (common.Hash, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.root, s.ready
+}
+
+// Generating reports whether the snapshot is being generated.
+func (s *Snapshot) Generating() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.generating != nil
+}
+
+func (s *Snapshot) key(kind byte, hashes ...common.Hash) []byte {
+	key := make([]byte, len(snapshotPrefix)+9+len(hashes)*common.HashLength)
+	copy(key, snapshotPrefix)
+	binary.BigEndian.PutUint64(key[len(snapshotPrefix):], s.gen)
+	key[len(snapshotPrefix)+8] = kind
+	for i, hash := range hashes {
+		copy(key[len(snapshotPrefix)+9+i*common.HashLength:], hash[:])
+	}
+	return key
+}
+
+// account retrieves the leaf value of an account of the state root, empty if
+// the account doesn't exist. It reports false if the snapshot cannot serve the
+// state root.
+func (s *Snapshot) account(root, addrHash common.Hash) ([]byte, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.ready || s.root != root {
+		return nil, false
+	}
+	enc, _ := s.db.Get(s.key(snapshotAccount, addrHash))
+	return enc, true
+}
+
+// storage retrieves the leaf value of a storage slot of an account of the
+// state root, empty if the slot is not set. It reports false if the snapshot
+// cannot serve the state root.
+func (s *Snapshot) storage(root, addrHash, slotHash common.Hash) ([]byte, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.ready || s.root != root {
+		return nil, false
+	}
+	enc, _ := s.db.Get(s.key(snapshotStorage, addrHash, slotHash))
+	return enc, true
+}
+
+// writeMarker persists the generation and the root of the snapshot, if complete.
+func (s *Snapshot) writeMarker() error {
+	marker := make([]byte, 8, 8+common.HashLength)
+	binary.BigEndian.PutUint64(marker, s.gen)
+	if s.ready {
+		marker = append(marker, s.root[:]...)
+	}
+	return s.db.Put(snapshotMarkerKey, marker)
+}
+
+// Update moves the snapshot to a new state root, writing the differences
+// between the tries of the current and the new root. If the snapshot is not
+// complete, or the differences cannot be computed, it is regenerated instead.
+func (s *Snapshot) Update(db Database, root common.Hash) {
+	s.lock.RLock()
+	from, ready, generating := s.root, s.ready, s.generating != nil
+	s.lock.RUnlock()
+
+	switch {
+	case ready && from == root, generating:
+		return
+	case !ready:
+		s.Rebuild(db, root)
+		return
+	}
+	batch := s.db.NewBatch()
+	if err := s.diff(db, from, root, batch); err != nil {
+		log.Warn("Failed to update state snapshot, regenerating", "from", from, "root", root, "err", err)
+		s.Rebuild(db, root)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.root != from || s.generating != nil {
+		return // Rebuilt meanwhile
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write state snapshot", "err", err)
+		s.ready = false
+	} else {
+		s.root = root
+	}
+	if err := s.writeMarker(); err != nil {
+		log.Error("Failed to write state snapshot marker", "err", err)
+	}
+}
+
+// diff writes into a batch the entries of the accounts and storage slots
+// differing between the tries of two state roots.
+func (s *Snapshot) diff(db Database, from, to common.Hash, batch ethdb.Batch) error {
+	oldAccounts, newAccounts, err := diffTries(func() (Trie, error) { return db.OpenTrie(from) }, func() (Trie, error) { return db.OpenTrie(to) })
+	if err != nil {
+		return err
+	}
+	changed := make(map[common.Hash]struct{}, len(newAccounts))
+	for hash := range oldAccounts {
+		changed[hash] = struct{}{}
+	}
+	for hash := range newAccounts {
+		changed[hash] = struct{}{}
+	}
+	for addrHash := range changed {
+		oldRoot, err := storageRoot(oldAccounts[addrHash])
+		if err != nil {
+			return err
+		}
+		newRoot, err := storageRoot(newAccounts[addrHash])
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(s.key(snapshotAccount, addrHash), newAccounts[addrHash]); err != nil {
+			return err
+		}
+		if oldRoot == newRoot {
+			continue
+		}
+		oldSlots, newSlots, err := diffTries(func() (Trie, error) { return db.OpenStorageTrie(addrHash, oldRoot) }, func() (Trie, error) { return db.OpenStorageTrie(addrHash, newRoot) })
+		if err != nil {
+			return err
+		}
+		for slotHash := range oldSlots {
+			if _, ok := newSlots[slotHash]; !ok {
+				if err := batch.Put(s.key(snapshotStorage, addrHash, slotHash), nil); err != nil {
+					return err
+				}
+			}
+		}
+		for slotHash, enc := range newSlots {
+			if err := batch.Put(s.key(snapshotStorage, addrHash, slotHash), enc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffTries retrieves the leaves of the old trie not in the new one, and the
+// leaves of the new trie not in the old one, keyed by the hashes of their keys.
+func diffTries(openOld, openNew func() (Trie, error)) (map[common.Hash][]byte, map[common.Hash][]byte, error) {
+	leaves := func(a, b func() (Trie, error)) (map[common.Hash][]byte, error) {
+		ta, err := a()
+		if err != nil {
+			return nil, err
+		}
+		tb, err := b()
+		if err != nil {
+			return nil, err
+		}
+		diff, _ := trie.NewDifferenceIterator(ta.NodeIterator(nil), tb.NodeIterator(nil))
+		it := trie.NewIterator(diff)
+		found := make(map[common.Hash][]byte)
+		for it.Next() {
+			found[common.BytesToHash(it.Key)] = common.CopyBytes(it.Value)
+		}
+		return found, it.Err
+	}
+	oldLeaves, err := leaves(openNew, openOld)
+	if err != nil {
+		return nil, nil, err
+	}
+	newLeaves, err := leaves(openOld, openNew)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldLeaves, newLeaves, nil
+}
+
+// storageRoot decodes the storage root of an account leaf, empty if missing.
+func storageRoot(enc []byte) (common.Hash, error) {
+	if len(enc) == 0 {
+		return common.Hash{}, nil
+	}
+	var account Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return common.Hash{}, err
+	}
+	return account.Root, nil
+}
+
+// Rebuild wipes the snapshot and generates it again in the background for a
+// state root.
+func (s *Snapshot) Rebuild(db Database, root common.Hash) {
+	s.Stop()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.gen++
+	s.root, s.ready = common.Hash{}, false
+	if err := s.writeMarker(); err != nil {
+		log.Error("Failed to write state snapshot marker", "err", err)
+	}
+	s.generating, s.abort = make(chan struct{}), make(chan struct{})
+	go s.generate(db, root, s.gen, s.generating, s.abort)
+}
+
+// Stop aborts the running generation, if any, and waits for it to exit.
+func (s *Snapshot) Stop() {
+	s.lock.Lock()
+	generating, abort := s.generating, s.abort
+	if abort != nil {
+		close(abort)
+		s.abort = nil
+	}
+	s.lock.Unlock()
+
+	if generating != nil {
+		<-generating
+	}
+}
+
+// generate writes the entries of all the accounts and storage slots of a
+// state root, then deletes the entries of the stale generations.
+func (s *Snapshot) generate(db Database, root common.Hash, gen uint64, done chan struct{}, abort chan struct{}) {
+	start := time.Now()
+	log.Info("Generating state snapshot", "root", root)
+
+	accounts, slots, err := s.walk(db, root, gen, abort)
+
+	s.lock.Lock()
+	if s.gen == gen {
+		if err == nil {
+			s.root, s.ready = root, true
+			if err := s.writeMarker(); err != nil {
+				log.Error("Failed to write state snapshot marker", "err", err)
+			}
+		}
+		s.generating = nil
+	}
+	s.lock.Unlock()
+	close(done)
+
+	switch {
+	case err == errSnapshotAborted:
+		log.Debug("State snapshot generation aborted", "root", root)
+		return
+	case err != nil:
+		log.Warn("Failed to generate state snapshot", "root", root, "err", err)
+		return
+	}
+	log.Info("Generated state snapshot", "root", root, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	s.prune(gen)
+}
+
+// walk writes the entries of a state root with a generation.
+func (s *Snapshot) walk(db Database, root common.Hash, gen uint64, abort chan struct{}) (int, int, error) {
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return 0, 0, err
+	}
+	writer := &Snapshot{db: s.db, gen: gen}
+	batch := s.db.NewBatch()
+	flush := func() error {
+		if batch.ValueSize() < ethdb.IdealBatchSize {
+			return nil
+		}
+		select {
+		case <-abort:
+			return errSnapshotAborted
+		default:
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	accounts, slots := 0, 0
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		addrHash := common.BytesToHash(it.Key)
+		if err := batch.Put(writer.key(snapshotAccount, addrHash), common.CopyBytes(it.Value)); err != nil {
+			return accounts, slots, err
+		}
+		accounts++
+		storageRoot, err := storageRoot(it.Value)
+		if err != nil {
+			return accounts, slots, err
+		}
+		storage, err := db.OpenStorageTrie(addrHash, storageRoot)
+		if err != nil {
+			return accounts, slots, err
+		}
+		sit := trie.NewIterator(storage.NodeIterator(nil))
+		for sit.Next() {
+			if err := batch.Put(writer.key(snapshotStorage, addrHash, common.BytesToHash(sit.Key)), common.CopyBytes(sit.Value)); err != nil {
+				return accounts, slots, err
+			}
+			slots++
+			if err := flush(); err != nil {
+				return accounts, slots, err
+			}
+		}
+		if sit.Err != nil {
+			return accounts, slots, sit.Err
+		}
+		if err := flush(); err != nil {
+			return accounts, slots, err
+		}
+	}
+	if it.Err != nil {
+		return accounts, slots, it.Err
+	}
+	return accounts, slots, batch.Write()
+}
+
+// prune deletes the entries of the generations other than the given one, if
+// the database can iterate over its keys.
+func (s *Snapshot) prune(gen uint64) {
+	db, ok := s.db.(snapshotIteratee)
+	if !ok {
+		return
+	}
+	it := db.NewIteratorWithPrefix(snapshotPrefix)
+	defer it.Release()
+
+	deleted := 0
+	for it.Next() {
+		key := it.Key()
+		if len(key) < len(snapshotPrefix)+8 || binary.BigEndian.Uint64(key[len(snapshotPrefix):]) == gen {
+			continue
+		}
+		if err := s.db.Delete(common.CopyBytes(key)); err != nil {
+			log.Warn("Failed to delete stale snapshot entry", "err", err)
+			return
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		log.Debug("Deleted stale state snapshot entries", "count", deleted)
+	}
+}
+
+// Verify checks that the snapshot holds the same accounts and storage slots
+// as the trie of its state root, and returns the number of entries checked.
+func (s *Snapshot) Verify(db Database) (int, int, error) {
+	root, ready := s.Root()
+	if !ready {
+		return 0, 0, errors.New("state snapshot not complete")
+	}
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return 0, 0, err
+	}
+	accounts, slots := 0, 0
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		addrHash := common.BytesToHash(it.Key)
+		enc, ok := s.account(root, addrHash)
+		if !ok {
+			return accounts, slots, errors.New("state snapshot changed while verifying")
+		}
+		if !bytes.Equal(enc, it.Value) {
+			return accounts, slots, fmt.Errorf("account %x mismatch: snapshot %x, trie %x", addrHash, enc, it.Value)
+		}
+		accounts++
+		storageRoot, err := storageRoot(it.Value)
+		if err != nil {
+			return accounts, slots, err
+		}
+		storage, err := db.OpenStorageTrie(addrHash, storageRoot)
+		if err != nil {
+			return accounts, slots, err
+		}
+		sit := trie.NewIterator(storage.NodeIterator(nil))
+		for sit.Next() {
+			slotHash := common.BytesToHash(sit.Key)
+			enc, _ := s.storage(root, addrHash, slotHash)
+			if !bytes.Equal(enc, sit.Value) {
+				return accounts, slots, fmt.Errorf("storage %x of account %x mismatch: snapshot %x, trie %x", slotHash, addrHash, enc, sit.Value)
+			}
+			slots++
+		}
+		if sit.Err != nil {
+			return accounts, slots, sit.Err
+		}
+	}
+	if it.Err != nil {
+		return accounts, slots, it.Err
+	}
+	// Make sure the snapshot doesn't hold more entries than the trie
+	if db, ok := s.db.(snapshotIteratee); ok {
+		s.lock.RLock()
+		prefix := s.key(snapshotAccount)[:len(snapshotPrefix)+8]
+		s.lock.RUnlock()
+
+		it := db.NewIteratorWithPrefix(prefix)
+		defer it.Release()
+
+		entries := 0
+		for it.Next() {
+			if len(it.Value()) > 0 {
+				entries++
+			}
+		}
+		if entries != accounts+slots {
+			return accounts, slots, fmt.Errorf("entry count mismatch: snapshot %d, trie %d", entries, accounts+slots)
+		}
+	}
+	return accounts, slots, nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// waitSnapshot waits for the running generation of a snapshot to complete.
+func waitSnapshot(t *testing.T, snap *Snapshot) {
+	for i := 0; snap.Generating(); i++ {
+		if i == 500 {
+			t.Fatal("snapshot generation timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSnapshotGenerateAndUpdate(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	var (
+		alice = common.HexToAddress("0x01")
+		bob   = common.HexToAddress("0x02")
+		carol = common.HexToAddress("0x03")
+		slot1 = common.HexToHash("0x01")
+		slot2 = common.HexToHash("0x02")
+	)
+	statedb, _ := New(common.Hash{}, sdb)
+	statedb.SetBalance(alice, big.NewInt(100))
+	statedb.SetNonce(bob, 1)
+	statedb.SetState(bob, slot1, common.HexToHash("0x11"))
+	statedb.SetState(bob, slot2, common.HexToHash("0x22"))
+	root1, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	// Generate the snapshot of the first state
+	snap := NewSnapshot(db)
+	snap.Update(sdb, root1)
+	waitSnapshot(t, snap)
+
+	if root, ready := snap.Root(); !ready || root != root1 {
+		t.Fatalf("snapshot root mismatch: have %x (ready %v), want %x", root, ready, root1)
+	}
+	if accounts, slots, err := snap.Verify(sdb); err != nil || accounts != 2 || slots != 2 {
+		t.Fatalf("snapshot verification failed: %d accounts, %d slots, %v", accounts, slots, err)
+	}
+	// Move the snapshot along a new state
+	statedb, _ = NewWithSnapshot(root1, sdb, snap)
+	statedb.SetBalance(alice, big.NewInt(50))
+	statedb.SetState(bob, slot1, common.Hash{})
+	statedb.SetBalance(carol, big.NewInt(7))
+	root2, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	snap.Update(sdb, root2)
+	if root, ready := snap.Root(); !ready || root != root2 {
+		t.Fatalf("snapshot root mismatch: have %x (ready %v), want %x", root, ready, root2)
+	}
+	if accounts, slots, err := snap.Verify(sdb); err != nil || accounts != 3 || slots != 1 {
+		t.Fatalf("snapshot verification failed: %d accounts, %d slots, %v", accounts, slots, err)
+	}
+	if _, ok := snap.storage(root2, crypto.Keccak256Hash(bob[:]), crypto.Keccak256Hash(slot2[:])); !ok {
+		t.Fatalf("snapshot not serving its root")
+	}
+	// The states at both roots read the same values, with or without snapshot
+	for _, root := range []common.Hash{root1, root2} {
+		plain, _ := New(root, sdb)
+		snapped, _ := NewWithSnapshot(root, sdb, snap)
+		for _, addr := range []common.Address{alice, bob, carol} {
+			if have, want := snapped.GetBalance(addr), plain.GetBalance(addr); have.Cmp(want) != 0 {
+				t.Errorf("root %x: balance mismatch of %x: have %v, want %v", root, addr, have, want)
+			}
+		}
+		for _, slot := range []common.Hash{slot1, slot2} {
+			if have, want := snapped.GetState(bob, slot), plain.GetState(bob, slot); have != want {
+				t.Errorf("root %x: storage mismatch of slot %x: have %x, want %x", root, slot, have, want)
+			}
+		}
+	}
+	// A rebuild regenerates the snapshot under a new generation
+	snap.Rebuild(sdb, root1)
+	waitSnapshot(t, snap)
+	if accounts, slots, err := snap.Verify(sdb); err != nil || accounts != 2 || slots != 2 {
+		t.Fatalf("rebuilt snapshot verification failed: %d accounts, %d slots, %v", accounts, slots, err)
+	}
+	if reloaded := NewSnapshot(db); reloaded.gen != snap.gen {
+		t.Fatalf("snapshot generation not persisted: have %d, want %d", reloaded.gen, snap.gen)
+	}
+}
@@ -79,6 +79,7 @@ type stateObject struct {
 
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	originStorage Storage // Storage entries as of the last finalised transaction
 
 	// Cache flags.
 	// When an object is marked suicided it will be delete from the trie
@@ -87,6 +88,7 @@ type stateObject struct {
 	suicided  bool
 	touched   bool
 	deleted   bool
+	origin    bool                      // Whether loaded from the state, its storage can then be read from the snapshot
 	onDirty   func(addr common.Address) // Callback method to mark a state object newly dirty
 }
 
@@ -119,6 +121,7 @@ func newObject(db *StateDB, address common.Address, data Account, onDirty func(a
 		data:          data,
 		cachedStorage: make(Storage),
 		dirtyStorage:  make(Storage),
+		originStorage: make(Storage),
 		onDirty:       onDirty,
 	}
 }
@@ -174,11 +177,44 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
-	if err != nil {
-		self.setError(err)
-		return common.Hash{}
+	value = self.loadState(db, key)
+	if (value != common.Hash{}) {
+		self.cachedStorage[key] = value
+	}
+	self.originStorage[key] = value
+	return value
+}
+
+// GetCommittedState returns a value in account storage as of the last
+// finalised transaction, ignoring the changes of the current one.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	if value, exists := self.originStorage[key]; exists {
+		return value
+	}
+	if _, dirty := self.dirtyStorage[key]; !dirty {
+		return self.GetState(db, key)
+	}
+	value := self.loadState(db, key)
+	self.originStorage[key] = value
+	return value
+}
+
+// loadState reads a value of the account storage from the snapshot or the DB.
+func (self *stateObject) loadState(db Database, key common.Hash) common.Hash {
+	var (
+		value common.Hash
+		enc   []byte
+		err   error
+		ok    bool
+	)
+	if self.origin && self.db.snap != nil {
+		enc, ok = self.db.snap.storage(self.db.snapRoot, self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if !ok {
+		if enc, err = self.getTrie(db).TryGet(key[:]); err != nil {
+			self.setError(err)
+			return common.Hash{}
+		}
 	}
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
@@ -187,9 +223,6 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	if (value != common.Hash{}) {
-		self.cachedStorage[key] = value
-	}
 	return value
 }
 
@@ -218,6 +251,9 @@ func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
+		self.originStorage[key] = value
+		// The trie moves away from the snapshot root
+		self.origin = false
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
 			continue
@@ -300,9 +336,11 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 	stateObject.code = self.code
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
+	stateObject.originStorage = self.originStorage.Copy()
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
+	stateObject.origin = self.origin
 	return stateObject
 }
 
@@ -32,9 +32,64 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
+// Tests that the account and storage proofs verify against the state root and
+// the storage root of the account.
+func TestProofs(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+	state, _ := New(common.Hash{}, sdb)
+	addr, missing := common.BytesToAddress([]byte{0x01}), common.BytesToAddress([]byte{0x02})
+	state.SetBalance(addr, big.NewInt(42))
+	state.SetState(addr, common.Hash{0x01}, common.Hash{0x02})
+	root, _ := state.Commit(false)
+	state, err := New(root, sdb)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+
+	verify := func(root common.Hash, key []byte, proof [][]byte) []byte {
+		proofDb, _ := ethdb.NewMemDatabase()
+		for _, node := range proof {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+		value, err, _ := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+		if err != nil {
+			t.Fatalf("invalid proof of %x: %v", key, err)
+		}
+		return value
+	}
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(verify(root, addr.Bytes(), proof), &account); err != nil || account.Balance.Int64() != 42 {
+		t.Fatalf("proven account mismatch: have %+v, %v", account, err)
+	}
+	if proof, err = state.GetStorageProof(addr, common.Hash{0x01}); err != nil {
+		t.Fatalf("failed to prove storage: %v", err)
+	}
+	var value []byte
+	if err := rlp.DecodeBytes(verify(account.Root, common.Hash{0x01}.Bytes(), proof), &value); err != nil || !bytes.Equal(value, common.Hash{0x02}.Bytes()) {
+		t.Errorf("proven storage mismatch: have %x, %v", value, err)
+	}
+	if proof, err = state.GetProof(missing); err != nil {
+		t.Fatalf("failed to prove missing account: %v", err)
+	}
+	if value := verify(root, missing.Bytes(), proof); value != nil {
+		t.Errorf("missing account proven with value %x", value)
+	}
+	if _, err := state.GetStorageProof(missing, common.Hash{}); err == nil {
+		t.Error("proved storage of a missing account")
+	}
+}
+
 // Tests that updating a state trie does not leak any database writes prior to
 // actually committing the state.
 func TestUpdateLeaks(t *testing.T) {
@@ -421,3 +476,34 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+func TestAccessListRevert(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		sender = common.HexToAddress("0x01")
+		addr   = common.HexToAddress("0x02")
+		slot   = common.HexToHash("0x03")
+	)
+	state.PrepareAccessList(sender, nil, nil)
+	if !state.AddressInAccessList(sender) || state.AddressInAccessList(addr) {
+		t.Fatalf("access list not prepared")
+	}
+	snapshot := state.Snapshot()
+	state.AddSlotToAccessList(addr, slot)
+	if addrOk, slotOk := state.SlotInAccessList(addr, slot); !addrOk || !slotOk {
+		t.Fatalf("slot missing from the access list")
+	}
+	copied := state.Copy()
+	state.RevertToSnapshot(snapshot)
+	if addrOk, slotOk := state.SlotInAccessList(addr, slot); addrOk || slotOk {
+		t.Fatalf("slot left in the access list after revert")
+	}
+	if !state.AddressInAccessList(sender) {
+		t.Fatalf("sender removed from the access list by revert")
+	}
+	if addrOk, slotOk := copied.SlotInAccessList(addr, slot); !addrOk || !slotOk {
+		t.Fatalf("slot missing from the copied access list")
+	}
+}
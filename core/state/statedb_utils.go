@@ -1,6 +1,7 @@
 package state
 
 import (
+	"bytes"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -81,7 +82,13 @@ var (
 	}
 )
 
-func GetCandidates(statedb *StateDB) []common.Address {
+// StorageReader reads the storage of the system contracts. It is implemented
+// by StateDB and by the state seen by the EVM.
+type StorageReader interface {
+	GetState(common.Address, common.Hash) common.Hash
+}
+
+func GetCandidates(statedb StorageReader) []common.Address {
 	slot := slotValidatorMapping["candidates"]
 	slotHash := common.BigToHash(new(big.Int).SetUint64(slot))
 	arrLength := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), slotHash)
@@ -98,7 +105,7 @@ func GetCandidates(statedb *StateDB) []common.Address {
 	return rets
 }
 
-func GetCandidateOwner(statedb *StateDB, candidate common.Address) common.Address {
+func GetCandidateOwner(statedb StorageReader, candidate common.Address) common.Address {
 	slot := slotValidatorMapping["validatorsState"]
 	// validatorsState[_candidate].owner;
 	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
@@ -107,7 +114,7 @@ func GetCandidateOwner(statedb *StateDB, candidate common.Address) common.Addres
 	return common.HexToAddress(ret.Hex())
 }
 
-func GetCandidateCap(statedb *StateDB, candidate common.Address) *big.Int {
+func GetCandidateCap(statedb StorageReader, candidate common.Address) *big.Int {
 	slot := slotValidatorMapping["validatorsState"]
 	// validatorsState[_candidate].cap;
 	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
@@ -116,7 +123,52 @@ func GetCandidateCap(statedb *StateDB, candidate common.Address) *big.Int {
 	return ret.Big()
 }
 
-func GetVoters(statedb *StateDB, candidate common.Address) []common.Address {
+// IsCandidate reports whether a candidate is proposed and hasn't resigned.
+func IsCandidate(statedb StorageReader, candidate common.Address) bool {
+	slot := slotValidatorMapping["validatorsState"]
+	// validatorsState[_candidate].isCandidate, packed after the owner
+	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
+	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), common.BigToHash(locValidatorsState))
+	return ret[common.HashLength-common.AddressLength-1] != 0
+}
+
+// GetMinCandidateCap returns the stake required to propose a candidate.
+func GetMinCandidateCap(statedb StorageReader) *big.Int {
+	slot := slotValidatorMapping["minCandidateCap"]
+	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), GetLocSimpleVariable(slot))
+	return ret.Big()
+}
+
+// GetWithdrawBlockNumbers returns the blocks from which the stakes refunded to
+// an owner can be withdrawn, in the order of the withdrawsState array of the
+// contract. Withdrawn entries are zero and kept, as their index is needed to
+// withdraw the others.
+func GetWithdrawBlockNumbers(statedb StorageReader, owner common.Address) []*big.Int {
+	slot := slotValidatorMapping["withdrawsState"]
+	// withdrawsState[_owner].blockNumbers
+	locWithdrawsState := GetLocMappingAtKey(owner.Hash(), slot)
+	locBlockNumbers := common.BigToHash(locWithdrawsState.Add(locWithdrawsState, new(big.Int).SetUint64(uint64(1))))
+	arrLength := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), locBlockNumbers)
+	rets := []*big.Int{}
+	for i := uint64(0); i < arrLength.Big().Uint64(); i++ {
+		key := GetLocDynamicArrAtElement(locBlockNumbers, i, 1)
+		rets = append(rets, statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), key).Big())
+	}
+	return rets
+}
+
+// GetWithdrawCap returns the stake refunded to an owner that can be withdrawn
+// from the given block.
+func GetWithdrawCap(statedb StorageReader, owner common.Address, blockNumber *big.Int) *big.Int {
+	slot := slotValidatorMapping["withdrawsState"]
+	// withdrawsState[_owner].caps[_blockNumber]
+	locWithdrawsState := GetLocMappingAtKey(owner.Hash(), slot)
+	retByte := crypto.Keccak256(common.BigToHash(blockNumber).Bytes(), common.BigToHash(locWithdrawsState).Bytes())
+	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), common.BytesToHash(retByte))
+	return ret.Big()
+}
+
+func GetVoters(statedb StorageReader, candidate common.Address) []common.Address {
 	//mapping(address => address[]) voters;
 	slot := slotValidatorMapping["voters"]
 	locVoters := GetLocMappingAtKey(candidate.Hash(), slot)
@@ -135,7 +187,17 @@ func GetVoters(statedb *StateDB, candidate common.Address) []common.Address {
 	return rets
 }
 
-func GetVoterCap(statedb *StateDB, candidate, voter common.Address) *big.Int {
+// GetVoterCount returns the number of voters of a candidate without reading
+// the voter list itself.
+func GetVoterCount(statedb StorageReader, candidate common.Address) uint64 {
+	//mapping(address => address[]) voters;
+	slot := slotValidatorMapping["voters"]
+	locVoters := GetLocMappingAtKey(candidate.Hash(), slot)
+	arrLength := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), common.BigToHash(locVoters))
+	return arrLength.Big().Uint64()
+}
+
+func GetVoterCap(statedb StorageReader, candidate, voter common.Address) *big.Int {
 	slot := slotValidatorMapping["validatorsState"]
 	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
 	locCandidateVoters := locValidatorsState.Add(locValidatorsState, new(big.Int).SetUint64(uint64(2)))
@@ -143,3 +205,78 @@ func GetVoterCap(statedb *StateDB, candidate, voter common.Address) *big.Int {
 	ret := statedb.GetState(common.HexToAddress(common.MasternodeVotingSMC), common.BytesToHash(retByte))
 	return ret.Big()
 }
+
+// AddVote adds a vote of a voter for a candidate to the validator contract
+// storage, the same way the vote function of the contract does.
+func AddVote(statedb *StateDB, candidate, voter common.Address, cap *big.Int) {
+	contract := common.HexToAddress(common.MasternodeVotingSMC)
+	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slotValidatorMapping["validatorsState"])
+
+	// validatorsState[_candidate].cap = validatorsState[_candidate].cap.add(msg.value);
+	locCandidateCap := common.BigToHash(new(big.Int).Add(locValidatorsState, big.NewInt(1)))
+	candidateCap := new(big.Int).Add(statedb.GetState(contract, locCandidateCap).Big(), cap)
+	statedb.SetState(contract, locCandidateCap, common.BigToHash(candidateCap))
+
+	// voters[_candidate].push(msg.sender) for new voters
+	voterCap := GetVoterCap(statedb, candidate, voter)
+	if voterCap.Sign() == 0 {
+		locVoters := common.BigToHash(GetLocMappingAtKey(candidate.Hash(), slotValidatorMapping["voters"]))
+		length := statedb.GetState(contract, locVoters).Big().Uint64()
+		statedb.SetState(contract, GetLocDynamicArrAtElement(locVoters, length, 1), voter.Hash())
+		statedb.SetState(contract, locVoters, common.BigToHash(new(big.Int).SetUint64(length+1)))
+	}
+	// validatorsState[_candidate].voters[msg.sender] = validatorsState[_candidate].voters[msg.sender].add(msg.value);
+	locCandidateVoters := common.BigToHash(new(big.Int).Add(locValidatorsState, big.NewInt(2)))
+	locVoterCap := crypto.Keccak256Hash(voter.Hash().Bytes(), locCandidateVoters.Bytes())
+	statedb.SetState(contract, locVoterCap, common.BigToHash(new(big.Int).Add(voterCap, cap)))
+}
+
+var (
+	slotBLSRegistryMapping = map[string]uint64{
+		"keys":       0,
+		"signatures": 1,
+	}
+)
+
+// BLSKeySlot returns the storage slot of the BLS registry holding a word of the
+// public key of a masternode, keys[masternode][word] with word in [0, 4).
+func BLSKeySlot(masternode common.Address, word uint64) common.Hash {
+	loc := GetLocMappingAtKey(masternode.Hash(), slotBLSRegistryMapping["keys"])
+	return common.BigToHash(loc.Add(loc, new(big.Int).SetUint64(word)))
+}
+
+// BLSSignatureSlot returns the storage slot of the BLS registry holding a word of
+// the last checkpoint signature of a masternode: signatures[masternode].number
+// for the word 0, the number of the gap block signed, and the signature itself
+// in the words 1 and 2.
+func BLSSignatureSlot(masternode common.Address, word uint64) common.Hash {
+	loc := GetLocMappingAtKey(masternode.Hash(), slotBLSRegistryMapping["signatures"])
+	return common.BigToHash(loc.Add(loc, new(big.Int).SetUint64(word)))
+}
+
+// GetBLSPublicKey returns the BLS public key registered by a masternode, nil if
+// it registered none.
+func GetBLSPublicKey(statedb StorageReader, masternode common.Address) []byte {
+	registry := common.HexToAddress(common.BLSRegistry)
+	key := make([]byte, 0, 4*common.HashLength)
+	for word := uint64(0); word < 4; word++ {
+		key = append(key, statedb.GetState(registry, BLSKeySlot(masternode, word)).Bytes()...)
+	}
+	if bytes.Equal(key, make([]byte, len(key))) {
+		return nil
+	}
+	return key
+}
+
+// GetBLSCheckpointSignature returns the last checkpoint signature recorded by a
+// masternode along with the number of the gap block it signs, a nil signature
+// if it recorded none.
+func GetBLSCheckpointSignature(statedb StorageReader, masternode common.Address) (uint64, []byte) {
+	registry := common.HexToAddress(common.BLSRegistry)
+	number := statedb.GetState(registry, BLSSignatureSlot(masternode, 0)).Big().Uint64()
+	if number == 0 {
+		return 0, nil
+	}
+	sig := append(statedb.GetState(registry, BLSSignatureSlot(masternode, 1)).Bytes(), statedb.GetState(registry, BLSSignatureSlot(masternode, 2)).Bytes()...)
+	return number, sig
+}
@@ -0,0 +1,33 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestAddVote(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	candidate, first, second := common.Address{0x01}, common.Address{0x02}, common.Address{0x03}
+	AddVote(state, candidate, first, big.NewInt(10))
+	AddVote(state, candidate, second, big.NewInt(20))
+	AddVote(state, candidate, first, big.NewInt(5))
+
+	if cap := GetCandidateCap(state, candidate); cap.Cmp(big.NewInt(35)) != 0 {
+		t.Errorf("candidate cap mismatch: have %v, want 35", cap)
+	}
+	voters := GetVoters(state, candidate)
+	if len(voters) != 2 || voters[0] != first || voters[1] != second || GetVoterCount(state, candidate) != 2 {
+		t.Fatalf("voters mismatch: have %x", voters)
+	}
+	if cap := GetVoterCap(state, candidate, first); cap.Cmp(big.NewInt(15)) != 0 {
+		t.Errorf("first voter cap mismatch: have %v, want 15", cap)
+	}
+	if cap := GetVoterCap(state, candidate, second); cap.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("second voter cap mismatch: have %v, want 20", cap)
+	}
+}
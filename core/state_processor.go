@@ -190,17 +190,16 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*big.Int, bc *BlockChain, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error, bool) {
-	if tx.To() != nil && tx.To().String() == common.BlockSigners && config.IsTIPSigning(header.Number) {
-		return ApplySignTransaction(config, statedb, header, tx, usedGas)
-	}
-	if tx.To() != nil && tx.To().String() == common.TomoXStateAddr && config.IsTIPTomoX(header.Number) {
-		return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
-	}
-	if tx.IsMatchingTransaction() && config.IsTIPTomoX(header.Number) {
-		return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
+	if contract := params.SpecialTxs.Lookup(tx.To()); contract != nil {
+		switch config.SpecialTxApply(contract, header.Number) {
+		case params.SpecialTxApplySign:
+			return ApplySignTransaction(config, statedb, header, tx, usedGas)
+		case params.SpecialTxApplyEmpty:
+			return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
+		}
 	}
 	var balanceFee *big.Int
-	if tx.To() != nil {
+	if tx.To() != nil && config.IsTRC21Fee(header.Number) {
 		if value, ok := tokensFee[*tx.To()]; ok {
 			balanceFee = value
 		}
@@ -258,6 +257,15 @@ func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 	return receipt, gas, err, balanceFee != nil
 }
 
+// TRC21FeeCapacity returns the fee capacities of the sponsored TRC21 tokens in a
+// state, none before the activation of the sponsorship at the given block.
+func TRC21FeeCapacity(config *params.ChainConfig, number *big.Int, statedb *state.StateDB) map[common.Address]*big.Int {
+	if !config.IsTRC21Fee(number) {
+		return map[common.Address]*big.Int{}
+	}
+	return state.GetTRC21FeeCapacityFromState(statedb)
+}
+
 func ApplySignTransaction(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
 	// Update the state with pending changes
 	var root []byte
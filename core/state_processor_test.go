@@ -0,0 +1,53 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the fee capacities of the sponsored TRC21 tokens are only read
+// from the issuer once the sponsorship is activated by the chain config.
+func TestTRC21FeeCapacityActivation(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// Register a single token with its capacity in the issuer contract
+	token := common.HexToAddress("0x0a")
+	tokensSlot := common.BigToHash(new(big.Int).SetUint64(state.SlotTRC21Issuer["tokens"]))
+	statedb.SetState(common.TRC21IssuerSMC, tokensSlot, common.BigToHash(common.Big1))
+	statedb.SetState(common.TRC21IssuerSMC, state.GetLocDynamicArrAtElement(tokensSlot, 0, 1), token.Hash())
+	capacityKey := state.GetLocMappingAtKey(token.Hash(), state.SlotTRC21Issuer["tokensState"])
+	statedb.SetState(common.TRC21IssuerSMC, common.BigToHash(capacityKey), common.BigToHash(big.NewInt(1000)))
+
+	config := &params.ChainConfig{Posv: &params.PosvConfig{Epoch: 30, TRC21FeeBlock: big.NewInt(10)}}
+	if capacity := TRC21FeeCapacity(config, big.NewInt(9), statedb); len(capacity) != 0 {
+		t.Errorf("capacity before activation: have %v, want none", capacity)
+	}
+	if capacity := TRC21FeeCapacity(config, big.NewInt(10), statedb); capacity[token] == nil || capacity[token].Int64() != 1000 {
+		t.Errorf("capacity after activation mismatch: have %v, want 1000", capacity[token])
+	}
+	config.Posv.TRC21FeeBlock = nil
+	if capacity := TRC21FeeCapacity(config, common.Big0, statedb); capacity[token] == nil {
+		t.Errorf("capacity without activation block missing")
+	}
+}
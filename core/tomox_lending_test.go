@@ -0,0 +1,141 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+// Tests that importing a block liquidates the loans due at it whether or not
+// the block carries a lending transaction, as the miner does.
+func TestApplyLendingTransactionLiquidates(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-lending-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+	tomoX := tomox.New(&tomox.Config{DataDir: datadir})
+	chain := &BlockChain{chainConfig: params.TestChainConfig}
+
+	var (
+		relayer        = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+		token          = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		term           = uint64(30 * 24 * 60 * 60)
+		investorKey, _ = crypto.GenerateKey()
+		borrowerKey, _ = crypto.GenerateKey()
+		investor       = crypto.PubkeyToAddress(investorKey.PublicKey)
+		borrower       = crypto.PubkeyToAddress(borrowerKey.PublicKey)
+	)
+	// Open a loan of 400 tokens backed by 200 TOMO in the parent block
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	loc := tomox_state.GetLocMappingAtKey(relayer.Hash(), tomox_state.RelayerMappingSlot["RELAYER_LIST"])
+	statedb.SetState(common.HexToAddress(common.RelayerRegistrationSMC), common.BigToHash(loc), common.BigToHash(big.NewInt(1)))
+	statedb.SetNonce(token, 1)
+	tomox_state.SetTokenBalance(investor, big.NewInt(400), token, statedb)
+	statedb.SetBalance(borrower, big.NewInt(200))
+
+	lendingState, _ := lendingstate.New(lendingstate.EmptyRoot, tomoX.GetLending().StateCache)
+	for i, order := range []*tomoxlending.LendingOrder{
+		{Type: tomoxlending.Limit, Side: lendingstate.Investing, Interest: big.NewInt(10000000), Quantity: big.NewInt(400), UserAddress: investor},
+		{Type: tomoxlending.Limit, Side: lendingstate.Borrowing, Interest: big.NewInt(10000000), Quantity: big.NewInt(400), CollateralToken: common.HexToAddress(common.TomoNativeAddress), Collateral: big.NewInt(200), UserAddress: borrower},
+	} {
+		order.Relayer, order.LendingToken, order.Term = relayer, token, term
+		key := investorKey
+		if order.UserAddress == borrower {
+			key = borrowerKey
+		}
+		if err := tomoxlending.SignLendingOrder(order, key); err != nil {
+			t.Fatal(err)
+		}
+		if err := tomoxlending.ApplyLendingOrder(1000, order, statedb, lendingState); err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+	parentRoot := lendingState.IntermediateRoot()
+	trade := lendingState.GetLendingTrade(lendingstate.GetLendingBookHash(token, term), 1)
+
+	// The lending state once the loan is liquidated, without price
+	liquidatedStatedb, liquidatedState := statedb.Copy(), lendingState.Copy()
+	noPrice := func(common.Address, common.Address, *big.Int) *big.Int { return nil }
+	if liquidated := tomoxlending.ProcessLiquidations(trade.ExpiresAt, params.TestChainConfig.TomoXLending.LiquidationThreshold(), noPrice, liquidatedStatedb, liquidatedState); len(liquidated) != 1 {
+		t.Fatalf("liquidated trades mismatch: have %d, want 1", len(liquidated))
+	}
+	liquidatedRoot := liquidatedState.IntermediateRoot()
+	if err := tomoX.GetLending().CommitState(lendingState); err != nil {
+		t.Fatalf("failed to commit lending state: %v", err)
+	}
+	newBlock := func(number, time uint64, root *common.Hash) *types.Block {
+		var txs []*types.Transaction
+		if root != nil {
+			data, _ := tomoxlending.EncodeLendingBatch(&tomoxlending.LendingBatch{Root: *root})
+			txs = append(txs, types.NewTransaction(0, common.HexToAddress(common.TomoXLendingAddr), big.NewInt(0), 0, big.NewInt(0), data))
+		}
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Time: new(big.Int).SetUint64(time)}
+		return types.NewBlock(header, txs, nil, nil)
+	}
+	parent := newBlock(1, 1000, &parentRoot)
+
+	tests := []struct {
+		name   string
+		parent *types.Block
+		time   uint64
+		root   *common.Hash // Root of the lending transaction, nil without one
+		fail   bool
+		seized bool
+	}{
+		{"loan not due", parent, trade.ExpiresAt - 1, &parentRoot, false, false},
+		{"loan due", parent, trade.ExpiresAt, &liquidatedRoot, false, true},
+		{"loan due not liquidated", parent, trade.ExpiresAt, &parentRoot, true, false},
+		{"loan due without lending transaction", parent, trade.ExpiresAt, nil, true, false},
+		{"no loan without lending transaction", newBlock(1, 1000, nil), trade.ExpiresAt, nil, false, false},
+	}
+	for _, test := range tests {
+		blockStatedb := statedb.Copy()
+		tomoxState, _ := tomox_state.New(tomox_state.EmptyRoot, tomoX.StateCache)
+		result, err := chain.applyLendingTransaction(tomoX, newBlock(2, test.time, test.root), test.parent, blockStatedb, tomoxState)
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: block accepted", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: failed to apply block: %v", test.name, err)
+			continue
+		}
+		if test.root != nil && result.IntermediateRoot() != *test.root {
+			t.Errorf("%s: lending root mismatch: have %x, want %x", test.name, result.IntermediateRoot(), *test.root)
+		}
+		if seized := blockStatedb.GetBalance(investor).Sign() > 0; seized != test.seized {
+			t.Errorf("%s: collateral seized mismatch: have %v, want %v", test.name, seized, test.seized)
+		}
+	}
+}
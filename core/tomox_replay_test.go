@@ -0,0 +1,82 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// Tests that replaying the matching of a block requires the TomoX service and
+// the parent block, and computes the TomoX root of the block.
+func TestReplayTomoX(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-replay-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	config := *params.AllPosvProtocolChanges
+	config.Posv = &params.PosvConfig{Period: 2, Epoch: 900}
+	db, _ := ethdb.NewMemDatabase()
+	genesis := (&Genesis{Config: &config, ExtraData: make([]byte, 32+65)}).MustCommit(db)
+	engine := posv.New(config.Posv, db)
+
+	var tomoX *tomox.TomoX
+	engine.GetTomoXService = func() *tomox.TomoX { return tomoX }
+	chain, err := NewBlockChain(db, nil, &config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// A block on top of the genesis, sealed by a signer
+	key, _ := crypto.GenerateKey()
+	newBlock := func(parent common.Hash) *types.Block {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(1), Time: big.NewInt(2), Difficulty: big.NewInt(1), Extra: make([]byte, 32+65)}
+		sig, _ := crypto.Sign(posv.SigHash(header).Bytes(), key)
+		copy(header.Extra[32:], sig)
+		return types.NewBlockWithHeader(header)
+	}
+	block := newBlock(genesis.Hash())
+	if _, err := chain.ReplayTomoX(block); err == nil {
+		t.Fatalf("block replayed without the TomoX service")
+	}
+	tomoX = tomox.New(&tomox.Config{DataDir: datadir})
+
+	if _, err := chain.ReplayTomoX(newBlock(common.Hash{1})); err == nil {
+		t.Errorf("block replayed without its parent")
+	}
+	root, err := chain.ReplayTomoX(block)
+	if err != nil {
+		t.Fatalf("failed to replay block: %v", err)
+	}
+	if root != tomox_state.EmptyRoot {
+		t.Errorf("TomoX root mismatch: have %x, want %x", root, tomox_state.EmptyRoot)
+	}
+}
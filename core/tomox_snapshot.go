@@ -0,0 +1,188 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+)
+
+// tomoXSnapshotKind starts the TomoX snapshots of a chain export, telling them
+// from the blocks.
+const tomoXSnapshotKind = "tomox-snapshot"
+
+var errNoTomoXService = errors.New("tomox service not available")
+
+// TomoXSnapshot holds the TomoX and lending states after a checkpoint block.
+// Snapshots follow their block in a chain export, for the import not to depend
+// on replaying the TomoX matching of the whole chain.
+type TomoXSnapshot struct {
+	Kind        string
+	Number      uint64
+	Hash        common.Hash
+	TomoXRoot   common.Hash
+	LendingRoot common.Hash
+	Nodes       [][]byte // Trie nodes not carried by the previous snapshots of the export
+	Preimages   [][]byte // Preimages of the keys of the trie leaves
+}
+
+// IsTomoXSnapshot reports whether an item of a chain export is a TomoX snapshot
+// rather than a block.
+func IsTomoXSnapshot(item rlp.RawValue) bool {
+	content, _, err := rlp.SplitList(item)
+	if err != nil {
+		return false
+	}
+	kind, val, _, err := rlp.Split(content)
+	return err == nil && kind == rlp.String && string(val) == tomoXSnapshotKind
+}
+
+// getTomoXService returns the TomoX service of the PoSV engine, nil if there
+// is none.
+func (bc *BlockChain) getTomoXService() *tomox.TomoX {
+	engine, ok := bc.Engine().(*posv.Posv)
+	if !ok || engine.GetTomoXService == nil {
+		return nil
+	}
+	return engine.GetTomoXService()
+}
+
+// ExportNWithTomoX writes a subset of the active chain to the given writer as
+// ExportN does, each checkpoint block past the TomoX fork being followed by a
+// snapshot of the TomoX and lending states after it.
+func (bc *BlockChain) ExportNWithTomoX(w io.Writer, first uint64, last uint64) error {
+	tomoXService := bc.getTomoXService()
+	if tomoXService == nil || bc.chainConfig.Posv == nil {
+		return errNoTomoXService
+	}
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting batch of blocks with TomoX snapshots", "count", last-first+1)
+
+	var (
+		tomoxWalker   = tomox_state.NewTrieWalker(tomoXService.StateCache)
+		lendingWalker = tomox_state.NewTrieWalker(tomoXService.GetLending().StateCache)
+	)
+	for nr := first; nr <= last; nr++ {
+		block := bc.GetBlockByNumber(nr)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", nr)
+		}
+		if err := block.EncodeRLP(w); err != nil {
+			return err
+		}
+		if nr == 0 || nr%bc.chainConfig.Posv.Epoch != 0 || !bc.chainConfig.IsTIPTomoX(block.Number()) {
+			continue
+		}
+		tomoxRoot, err := tomoXService.GetTomoxStateRoot(block)
+		if err != nil {
+			return err
+		}
+		snapshot := &TomoXSnapshot{
+			Kind:        tomoXSnapshotKind,
+			Number:      nr,
+			Hash:        block.Hash(),
+			TomoXRoot:   tomoxRoot,
+			LendingRoot: tomoxlending.GetLendingStateRoot(block),
+		}
+		onNode := func(blob []byte) error {
+			snapshot.Nodes = append(snapshot.Nodes, blob)
+			return nil
+		}
+		onPreimage := func(key []byte) error {
+			snapshot.Preimages = append(snapshot.Preimages, key)
+			return nil
+		}
+		if err := tomoxWalker.Walk(snapshot.TomoXRoot, true, onNode, onPreimage); err != nil {
+			return fmt.Errorf("export failed on TomoX state of #%d: %v", nr, err)
+		}
+		if err := lendingWalker.Walk(snapshot.LendingRoot, false, onNode, onPreimage); err != nil {
+			return fmt.Errorf("export failed on lending state of #%d: %v", nr, err)
+		}
+		if err := rlp.Encode(w, snapshot); err != nil {
+			return err
+		}
+		log.Debug("Exported TomoX snapshot", "number", nr, "nodes", len(snapshot.Nodes), "preimages", len(snapshot.Preimages))
+	}
+	return nil
+}
+
+// ImportTomoXSnapshot writes the states of a TomoX snapshot to the TomoX
+// database, once checked against the canonical block it was taken after. The
+// states must be complete along with the ones of the previous snapshots.
+func (bc *BlockChain) ImportTomoXSnapshot(snapshot *TomoXSnapshot) error {
+	tomoXService := bc.getTomoXService()
+	if tomoXService == nil {
+		return errNoTomoXService
+	}
+	block := bc.GetBlockByNumber(snapshot.Number)
+	if block == nil || block.Hash() != snapshot.Hash {
+		return fmt.Errorf("snapshot of non canonical block #%d [%x…]", snapshot.Number, snapshot.Hash[:4])
+	}
+	if root, _ := tomoXService.GetTomoxStateRoot(block); root != snapshot.TomoXRoot {
+		return fmt.Errorf("snapshot of block #%d has TomoX root %x, want %x", snapshot.Number, snapshot.TomoXRoot, root)
+	}
+	if root := tomoxlending.GetLendingStateRoot(block); root != snapshot.LendingRoot {
+		return fmt.Errorf("snapshot of block #%d has lending root %x, want %x", snapshot.Number, snapshot.LendingRoot, root)
+	}
+	// Nodes and preimages are stored by their hash, there is nothing to
+	// check before writing them
+	batch := tomoXService.GetDB().NewBatch()
+	for _, blob := range snapshot.Nodes {
+		if err := batch.Put(crypto.Keccak256(blob), blob); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	for _, key := range snapshot.Preimages {
+		if err := batch.Put(append([]byte(preimagePrefix), crypto.Keccak256(key)...), key); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Make sure no node is missing from the states
+	skip := func([]byte) error { return nil }
+	if err := tomox_state.NewTrieWalker(tomoXService.StateCache).Walk(snapshot.TomoXRoot, true, skip, skip); err != nil {
+		return fmt.Errorf("incomplete TomoX state in snapshot of block #%d: %v", snapshot.Number, err)
+	}
+	if err := tomox_state.NewTrieWalker(tomoXService.GetLending().StateCache).Walk(snapshot.LendingRoot, false, skip, skip); err != nil {
+		return fmt.Errorf("incomplete lending state in snapshot of block #%d: %v", snapshot.Number, err)
+	}
+	log.Info("Imported TomoX snapshot", "number", snapshot.Number, "hash", snapshot.Hash, "nodes", len(snapshot.Nodes))
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that TomoX snapshots are told from blocks in a chain export.
+func TestIsTomoXSnapshot(t *testing.T) {
+	block, _ := rlp.EncodeToBytes(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(900)}))
+	if IsTomoXSnapshot(block) {
+		t.Errorf("block reported as a TomoX snapshot")
+	}
+	snapshot, _ := rlp.EncodeToBytes(&TomoXSnapshot{Kind: tomoXSnapshotKind, Number: 900, Nodes: [][]byte{{0x01}}})
+	if !IsTomoXSnapshot(snapshot) {
+		t.Errorf("TomoX snapshot not recognized")
+	}
+	other, _ := rlp.EncodeToBytes([]interface{}{"other", uint64(1)})
+	if IsTomoXSnapshot(other) {
+		t.Errorf("unknown item reported as a TomoX snapshot")
+	}
+}
@@ -436,7 +436,7 @@ func (l *txPricedList) Removed() {
 
 // Cap finds all the transactions below the given price threshold, drops them
 // from the priced list and returs them for further removal from the entire pool.
-func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transactions {
+func (l *txPricedList) Cap(threshold *big.Int, exempt func(*types.Transaction) bool) types.Transactions {
 	drop := make(types.Transactions, 0, 128) // Remote underpriced transactions to drop
 	save := make(types.Transactions, 0, 64)  // Local underpriced transactions to keep
 
@@ -452,8 +452,8 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 			save = append(save, tx)
 			break
 		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
+		// Non stale transaction found, discard unless exempt
+		if exempt(tx) {
 			save = append(save, tx)
 		} else {
 			drop = append(drop, tx)
@@ -467,9 +467,9 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 
 // Underpriced checks whether a transaction is cheaper than (or as cheap as) the
 // lowest priced transaction currently being tracked.
-func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) bool {
-	// Local transactions cannot be underpriced
-	if local.containsTx(tx) {
+func (l *txPricedList) Underpriced(tx *types.Transaction, exempt func(*types.Transaction) bool) bool {
+	// Local and governance transactions cannot be underpriced
+	if exempt(tx) {
 		return false
 	}
 	// Discard stale price points if found at the heap start
@@ -493,7 +493,7 @@ func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) boo
 
 // Discard finds a number of most underpriced transactions, removes them from the
 // priced list and returns them for further removal from the entire pool.
-func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions {
+func (l *txPricedList) Discard(count int, exempt func(*types.Transaction) bool) types.Transactions {
 	drop := make(types.Transactions, 0, count) // Remote underpriced transactions to drop
 	save := make(types.Transactions, 0, 64)    // Local underpriced transactions to keep
 
@@ -504,8 +504,8 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 			l.stales--
 			continue
 		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
+		// Non stale transaction found, discard unless exempt
+		if exempt(tx) {
 			save = append(save, tx)
 		} else {
 			drop = append(drop, tx)
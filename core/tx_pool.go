@@ -81,6 +81,10 @@ var (
 
 	ErrZeroGasPrice = errors.New("zero gas price")
 
+	// ErrUnprotectedTx is returned if a transaction without replay protection
+	// (EIP-155) is sent to a pool configured to reject them.
+	ErrUnprotectedTx = errors.New("only replay-protected (EIP-155) transactions allowed")
+
 	ErrUnderMinGasPrice = errors.New("under min gas price")
 
 	ErrDuplicateSpecialTransaction = errors.New("duplicate a special transaction")
@@ -121,6 +125,24 @@ const (
 	TxStatusIncluded
 )
 
+// Lifecycle statuses of the transactions notified by TxLifecycleEvent.
+const (
+	TxLifecyclePending  = "pending"  // Executable, waiting for inclusion
+	TxLifecycleQueued   = "queued"   // Waiting for a nonce gap to be filled
+	TxLifecycleDropped  = "dropped"  // Removed from the pool
+	TxLifecycleReplaced = "replaced" // Replaced by a transaction of the same nonce
+)
+
+// Reasons of the transactions dropped from the pool.
+const (
+	TxDropUnderpriced  = "underpriced"               // Pushed out by better priced transactions, or under the price limit
+	TxDropStaleNonce   = "nonce too low"             // Nonce used on chain, by this transaction or another one
+	TxDropUnpayable    = "insufficient funds or gas" // Balance or block gas limit too low
+	TxDropAccountLimit = "account limit exceeded"    // Over the slots of its sender
+	TxDropPoolLimit    = "pool limit exceeded"       // Over the global slots, evicted for fairness
+	TxDropExpired      = "expired"                   // Queued for longer than the lifetime
+)
+
 // blockChain provides the state of blockchain and current gas limit to do
 // some pre checks in tx pool and event subscribers.
 type blockChain interface {
@@ -145,7 +167,11 @@ type TxPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
+	PrioritySlots uint64 // Slots reserved above the global ones for the governance transactions of the masternodes
+
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	RejectUnprotected bool // Whether transactions without replay protection (EIP-155) should be rejected
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -162,9 +188,22 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue: 64,
 	GlobalQueue:  1024,
 
+	PrioritySlots: 256,
+
 	Lifetime: 3 * time.Hour,
 }
 
+// TxPoolLimits are the limits of the transaction pool adjustable at runtime.
+type TxPoolLimits struct {
+	PriceLimit   uint64 `json:"priceLimit"`   // Minimum gas price to enforce for acceptance into the pool
+	AccountSlots uint64 `json:"accountSlots"` // Minimum number of executable transaction slots guaranteed per account
+	GlobalSlots  uint64 `json:"globalSlots"`  // Maximum number of executable transaction slots for all accounts
+	AccountQueue uint64 `json:"accountQueue"` // Maximum number of non-executable transaction slots permitted per account
+	GlobalQueue  uint64 `json:"globalQueue"`  // Maximum number of non-executable transaction slots for all accounts
+
+	AccountLimits map[common.Address]uint64 `json:"accountLimits"` // Maximum number of executable transactions of specific accounts
+}
+
 // sanitize checks the provided user configurations and changes anything that's
 // unreasonable or unworkable.
 func (config *TxPoolConfig) sanitize() TxPoolConfig {
@@ -197,6 +236,7 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	txEventFeed  event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -210,6 +250,9 @@ type TxPool struct {
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
+	priority *accountSet              // Masternodes and their owners, whose governance transactions take the priority lane
+	reserved map[common.Hash]struct{} // Governance transactions admitted into the reserved slots
+
 	pending map[common.Address]*txList         // All currently processable transactions
 	queue   map[common.Address]*txList         // Queued but non-processable transactions
 	beats   map[common.Address]time.Time       // Last heartbeat from each known account
@@ -218,7 +261,10 @@ type TxPool struct {
 
 	wg sync.WaitGroup // for shutdown sync
 
+	accountLimits map[common.Address]uint64 // Caps on the executable transactions of specific accounts
+
 	homestead        bool
+	istanbul         bool // Fork indicator whether we are in the istanbul stage
 	IsSigner         func(address common.Address) bool
 	trc21FeeCapacity map[common.Address]*big.Int
 }
@@ -241,9 +287,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		all:              make(map[common.Hash]*types.Transaction),
 		chainHeadCh:      make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:         new(big.Int).SetUint64(config.PriceLimit),
+		accountLimits:    make(map[common.Address]uint64),
 		trc21FeeCapacity: map[common.Address]*big.Int{},
+		reserved:         make(map[common.Hash]struct{}),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	pool.priority = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -299,6 +348,7 @@ func (pool *TxPool) loop() {
 				if pool.chainconfig.IsHomestead(ev.Block.Number()) {
 					pool.homestead = true
 				}
+				pool.istanbul = pool.chainconfig.IsIstanbul(new(big.Int).Add(ev.Block.Number(), big.NewInt(1)))
 				pool.reset(head.Header(), ev.Block.Header())
 				head = ev.Block
 
@@ -331,7 +381,7 @@ func (pool *TxPool) loop() {
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
-						pool.removeTx(tx.Hash())
+						pool.dropTx(tx.Hash(), TxDropExpired)
 					}
 				}
 			}
@@ -419,12 +469,17 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		return
 	}
 	pool.currentState = statedb
-	pool.trc21FeeCapacity = state.GetTRC21FeeCapacityFromStateWithCache(newHead.Root, statedb)
+	pool.trc21FeeCapacity = map[common.Address]*big.Int{}
+	if pool.chainconfig.IsTRC21Fee(new(big.Int).Add(newHead.Number, common.Big1)) {
+		pool.trc21FeeCapacity = state.GetTRC21FeeCapacityFromStateWithCache(newHead.Root, statedb)
+	}
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.priority = pool.prioritySenders(statedb)
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
+	senderCacher.recover(pool.signer, reinject)
 	pool.addTxsLocked(reinject, false)
 
 	// validate the pool of pending transactions, this will remove
@@ -464,6 +519,23 @@ func (pool *TxPool) SubscribeTxPreEvent(ch chan<- TxPreEvent) event.Subscription
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxLifecycleEvent registers a subscription of TxLifecycleEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeTxLifecycleEvent(ch chan<- TxLifecycleEvent) event.Subscription {
+	return pool.scope.Track(pool.txEventFeed.Subscribe(ch))
+}
+
+// notifyDropped notifies the subsystems of a transaction leaving the pool.
+func (pool *TxPool) notifyDropped(tx *types.Transaction, reason string) {
+	go pool.txEventFeed.Send(TxLifecycleEvent{Tx: tx, Status: TxLifecycleDropped, Reason: reason})
+}
+
+// notifyReplaced notifies the subsystems of a transaction replaced by another
+// one of the same nonce.
+func (pool *TxPool) notifyReplaced(old *types.Transaction, tx *types.Transaction) {
+	go pool.txEventFeed.Send(TxLifecycleEvent{Tx: old, Status: TxLifecycleReplaced, Replacement: tx.Hash()})
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -479,12 +551,70 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
-		pool.removeTx(tx.Hash())
+	for _, tx := range pool.priced.Cap(price, pool.exempt) {
+		pool.dropTx(tx.Hash(), TxDropUnderpriced)
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// Limits retrieves the current limits of the transaction pool.
+func (pool *TxPool) Limits() TxPoolLimits {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	limits := TxPoolLimits{
+		PriceLimit:    pool.gasPrice.Uint64(),
+		AccountSlots:  pool.config.AccountSlots,
+		GlobalSlots:   pool.config.GlobalSlots,
+		AccountQueue:  pool.config.AccountQueue,
+		GlobalQueue:   pool.config.GlobalQueue,
+		AccountLimits: make(map[common.Address]uint64, len(pool.accountLimits)),
+	}
+	for addr, limit := range pool.accountLimits {
+		limits.AccountLimits[addr] = limit
+	}
+	return limits
+}
+
+// SetLimits updates the limits of the transaction pool, then drops the
+// transactions exceeding them.
+func (pool *TxPool) SetLimits(limits TxPoolLimits) error {
+	if limits.PriceLimit < 1 {
+		return fmt.Errorf("invalid price limit %d", limits.PriceLimit)
+	}
+	if limits.AccountSlots == 0 || limits.GlobalSlots == 0 || limits.AccountQueue == 0 || limits.GlobalQueue == 0 {
+		return errors.New("transaction slots must be positive")
+	}
+	for addr, limit := range limits.AccountLimits {
+		if limit == 0 {
+			return fmt.Errorf("invalid executable transaction limit of %x", addr)
+		}
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.config.PriceLimit = limits.PriceLimit
+	pool.config.AccountSlots = limits.AccountSlots
+	pool.config.GlobalSlots = limits.GlobalSlots
+	pool.config.AccountQueue = limits.AccountQueue
+	pool.config.GlobalQueue = limits.GlobalQueue
+
+	pool.accountLimits = make(map[common.Address]uint64, len(limits.AccountLimits))
+	for addr, limit := range limits.AccountLimits {
+		pool.accountLimits[addr] = limit
+	}
+	pool.gasPrice = new(big.Int).SetUint64(limits.PriceLimit)
+	for _, tx := range pool.priced.Cap(pool.gasPrice, pool.exempt) {
+		pool.dropTx(tx.Hash(), TxDropUnderpriced)
+	}
+	// Re-evaluate the pool against the new limits
+	pool.promoteExecutables(nil)
+
+	log.Info("Transaction pool limits updated", "price", limits.PriceLimit, "accountslots", limits.AccountSlots, "globalslots", limits.GlobalSlots,
+		"accountqueue", limits.AccountQueue, "globalqueue", limits.GlobalQueue, "accountlimits", len(limits.AccountLimits))
+	return nil
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -563,6 +693,74 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// PrioritySenders returns the masternodes and their owners, whose governance
+// transactions take the priority lane.
+func (pool *TxPool) PrioritySenders() map[common.Address]struct{} {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	senders := make(map[common.Address]struct{}, len(pool.priority.accounts))
+	for addr := range pool.priority.accounts {
+		senders[addr] = struct{}{}
+	}
+	return senders
+}
+
+// prioritySenders collects the masternode candidates and their owners from the
+// state of the validator contract.
+func (pool *TxPool) prioritySenders(statedb *state.StateDB) *accountSet {
+	senders := newAccountSet(pool.signer)
+	for _, candidate := range state.GetCandidates(statedb) {
+		if candidate == (common.Address{}) {
+			continue // Resigned candidate
+		}
+		senders.add(candidate)
+		if owner := state.GetCandidateOwner(statedb, candidate); owner != (common.Address{}) {
+			senders.add(owner)
+		}
+	}
+	return senders
+}
+
+// prioritized reports whether a transaction is a governance one sent by a
+// masternode or its owner.
+func (pool *TxPool) prioritized(tx *types.Transaction) bool {
+	return tx.IsGovernanceTransaction() && pool.priority.containsTx(tx)
+}
+
+// exempt reports whether a transaction is exempt from the price based eviction.
+func (pool *TxPool) exempt(tx *types.Transaction) bool {
+	return pool.locals.containsTx(tx) || pool.prioritized(tx)
+}
+
+// protected reports whether the transactions of an account are exempt from the
+// fairness based eviction, being local or holding a governance transaction.
+func (pool *TxPool) protected(addr common.Address, list *txList) bool {
+	if pool.locals.contains(addr) {
+		return true
+	}
+	if !pool.priority.contains(addr) {
+		return false
+	}
+	for _, tx := range list.Flatten() {
+		if tx.IsGovernanceTransaction() {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedSlots returns the number of reserved slots taken, releasing those of
+// the transactions gone from the pool.
+func (pool *TxPool) reservedSlots() uint64 {
+	for hash := range pool.reserved {
+		if pool.all[hash] == nil {
+			delete(pool.reserved, hash)
+		}
+	}
+	return uint64(len(pool.reserved))
+}
+
 func (pool *TxPool) GetSender(tx *types.Transaction) (common.Address, error) {
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
@@ -587,6 +785,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
 	}
+	// Reject the transactions replayable on other chains, if requested
+	if pool.config.RejectUnprotected && !tx.Protected() {
+		return ErrUnprotectedTx
+	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
@@ -601,6 +803,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Run the checks of the system contract the transaction is sent to, if any
+	if err := ValidateSpecialTx(tx, from); err != nil {
+		return err
+	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
@@ -635,7 +841,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 
 	if tx.To() == nil || (tx.To() != nil && !tx.IsSpecialTransaction()) {
-		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead, pool.istanbul)
 		if err != nil {
 			return err
 		}
@@ -690,20 +896,27 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		return pool.promoteSpecialTx(from, tx)
 	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	reserved := pool.reservedSlots()
+	if limit := pool.config.GlobalSlots + pool.config.GlobalQueue + reserved; uint64(len(pool.all)) >= limit {
 		log.Debug("Add transaction to pool full", "hash", hash, "nonce", tx.Nonce())
-		// If the new transaction is underpriced, don't accept it
-		if pool.priced.Underpriced(tx, pool.locals) {
-			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			return false, ErrUnderpriced
-		}
-		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(len(pool.all)-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
-		for _, tx := range drop {
-			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
-			underpricedTxCounter.Inc(1)
-			pool.removeTx(tx.Hash())
+		if pool.prioritized(tx) && reserved < pool.config.PrioritySlots {
+			// Governance transactions of the masternodes take a reserved slot
+			log.Trace("Reserving slot for governance transaction", "hash", hash, "from", from)
+			pool.reserved[hash] = struct{}{}
+		} else {
+			// If the new transaction is underpriced, don't accept it
+			if pool.priced.Underpriced(tx, pool.exempt) {
+				log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
+				underpricedTxCounter.Inc(1)
+				return false, ErrUnderpriced
+			}
+			// New transaction is better than our worse ones, make room for it
+			drop := pool.priced.Discard(len(pool.all)-int(limit-1), pool.exempt)
+			for _, tx := range drop {
+				log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
+				underpricedTxCounter.Inc(1)
+				pool.dropTx(tx.Hash(), TxDropUnderpriced)
+			}
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
@@ -719,6 +932,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			delete(pool.all, old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.notifyReplaced(old, tx)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
@@ -728,6 +942,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 
 		// We've directly injected a replacement transaction, notify subsystems
 		go pool.txFeed.Send(TxPreEvent{tx})
+		go pool.txEventFeed.Send(TxLifecycleEvent{Tx: tx, Status: TxLifecyclePending})
 
 		return old != nil, nil
 	}
@@ -766,9 +981,11 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.notifyReplaced(old, tx)
 	}
 	pool.all[hash] = tx
 	pool.priced.Put(tx)
+	go pool.txEventFeed.Send(TxLifecycleEvent{Tx: tx, Status: TxLifecycleQueued})
 	return old != nil, nil
 }
 
@@ -801,6 +1018,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.notifyDropped(tx, TxDropUnderpriced)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -809,6 +1027,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.notifyReplaced(old, tx)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
@@ -820,6 +1039,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
 
 	go pool.txFeed.Send(TxPreEvent{tx})
+	go pool.txEventFeed.Send(TxLifecycleEvent{Tx: tx, Status: TxLifecyclePending})
 }
 
 func (pool *TxPool) promoteSpecialTx(addr common.Address, tx *types.Transaction) (bool, error) {
@@ -837,6 +1057,7 @@ func (pool *TxPool) promoteSpecialTx(addr common.Address, tx *types.Transaction)
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 		pendingReplaceCounter.Inc(1)
+		pool.notifyReplaced(old, tx)
 	}
 	list.txs.Put(tx)
 	if cost := tx.Cost(); list.costcap.Cmp(cost) < 0 {
@@ -853,6 +1074,7 @@ func (pool *TxPool) promoteSpecialTx(addr common.Address, tx *types.Transaction)
 	pool.beats[addr] = time.Now()
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
 	go pool.txFeed.Send(TxPreEvent{tx})
+	go pool.txEventFeed.Send(TxLifecycleEvent{Tx: tx, Status: TxLifecyclePending})
 	return true, nil
 }
 
@@ -968,6 +1190,15 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all[hash]
 }
 
+// dropTx removes a single transaction from the pool like removeTx, notifying
+// the subsystems of the reason.
+func (pool *TxPool) dropTx(hash common.Hash, reason string) {
+	if tx := pool.all[hash]; tx != nil {
+		pool.notifyDropped(tx, reason)
+	}
+	pool.removeTx(hash)
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash) {
@@ -1036,6 +1267,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			log.Trace("Removed old queued transaction", "hash", hash)
 			delete(pool.all, hash)
 			pool.priced.Removed()
+			pool.notifyDropped(tx, TxDropStaleNonce)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, pool.trc21FeeCapacity)
@@ -1045,6 +1277,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
+			pool.notifyDropped(tx, TxDropUnpayable)
 		}
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
@@ -1059,6 +1292,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				delete(pool.all, hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				pool.notifyDropped(tx, TxDropAccountLimit)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -1067,6 +1301,26 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			delete(pool.queue, addr)
 		}
 	}
+	// Drop the executable transactions over the cap of an account, if any
+	for addr, limit := range pool.accountLimits {
+		list := pool.pending[addr]
+		if list == nil {
+			continue
+		}
+		for _, tx := range list.Cap(int(limit)) {
+			hash := tx.Hash()
+			delete(pool.all, hash)
+			pool.priced.Removed()
+
+			// Update the account nonce to the dropped transaction
+			if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
+				pool.pendingState.SetNonce(addr, nonce)
+			}
+			pendingRateLimitCounter.Inc(1)
+			pool.notifyDropped(tx, TxDropAccountLimit)
+			log.Trace("Removed limit-exceeding pending transaction", "hash", hash)
+		}
+	}
 	// If the pending limit is overflown, start equalizing allowances
 	pending := uint64(0)
 	for _, list := range pool.pending {
@@ -1078,7 +1332,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		spammers := prque.New()
 		for addr, list := range pool.pending {
 			// Only evict transactions from high rollers
-			if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
+			if !pool.protected(addr, list) && uint64(list.Len()) > pool.config.AccountSlots {
 				spammers.Push(addr, float32(list.Len()))
 			}
 		}
@@ -1108,6 +1362,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
 								pool.pendingState.SetNonce(offenders[i], nonce)
 							}
+							pool.notifyDropped(tx, TxDropPoolLimit)
 							log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 						}
 						pending--
@@ -1130,6 +1385,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
 							pool.pendingState.SetNonce(addr, nonce)
 						}
+						pool.notifyDropped(tx, TxDropPoolLimit)
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
 					pending--
@@ -1147,7 +1403,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		// Sort all accounts with queued transactions by heartbeat
 		addresses := make(addresssByHeartbeat, 0, len(pool.queue))
 		for addr := range pool.queue {
-			if !pool.locals.contains(addr) { // don't drop locals
+			if !pool.protected(addr, pool.queue[addr]) { // don't drop locals and governance transactions
 				addresses = append(addresses, addressByHeartbeat{addr, pool.beats[addr]})
 			}
 		}
@@ -1163,7 +1419,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
-					pool.removeTx(tx.Hash())
+					pool.dropTx(tx.Hash(), TxDropPoolLimit)
 				}
 				drop -= size
 				queuedRateLimitCounter.Inc(int64(size))
@@ -1172,7 +1428,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
-				pool.removeTx(txs[i].Hash())
+				pool.dropTx(txs[i].Hash(), TxDropPoolLimit)
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
@@ -1194,6 +1450,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			log.Trace("Removed old pending transaction", "hash", hash)
 			delete(pool.all, hash)
 			pool.priced.Removed()
+			pool.notifyDropped(tx, TxDropStaleNonce)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, pool.trc21FeeCapacity)
@@ -1203,6 +1460,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
+			pool.notifyDropped(tx, TxDropUnpayable)
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -261,6 +261,33 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that a pool configured to reject the transactions without replay
+// protection only accepts the EIP-155 ones.
+func TestRejectUnprotectedTransactions(t *testing.T) {
+	t.Parallel()
+
+	diskdb, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RejectUnprotected = true
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(0xffffffffffffff))
+
+	tx := transaction(0, 100000, key)
+	if err := pool.AddLocal(tx); err != ErrUnprotectedTx {
+		t.Error("expected", ErrUnprotectedTx, "got", err)
+	}
+	tx, _ = types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(common.DefaultMinGasPrice), nil), types.NewEIP155Signer(params.TestChainConfig.ChainId), key)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -329,6 +356,32 @@ func TestTransactionNegativeValue(t *testing.T) {
 	}
 }
 
+// Tests that the transactions sent to system contracts go through the checks of
+// these contracts before entering the pool.
+func TestSpecialTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
+
+	signers := common.HexToAddress(common.BlockSigners)
+	tx, _ := types.SignTx(types.NewTransaction(0, signers, big.NewInt(0), 100000, big.NewInt(1), []byte{0x01}), types.HomesteadSigner{}, key)
+	if err := pool.AddRemote(tx); err != ErrInvalidSigningTx {
+		t.Error("expected", ErrInvalidSigningTx, "got", err)
+	}
+	tx, _ = types.SignTx(types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), big.NewInt(0), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err := pool.AddLocal(tx); err != ErrBlockDataTx {
+		t.Error("expected", ErrBlockDataTx, "got", err)
+	}
+	tx, _ = types.SignTx(types.NewTransaction(0, signers, big.NewInt(0), 100000, big.NewInt(1), append(common.Hex2Bytes(common.HexSignMethod), make([]byte, 64)...)), types.HomesteadSigner{}, key)
+	if err := pool.AddRemote(tx); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
 func TestTransactionChainFork(t *testing.T) {
 	t.Parallel()
 
@@ -962,6 +1015,56 @@ func TestTransactionPendingLimiting(t *testing.T) {
 	}
 }
 
+// Tests that the limits of the pool can be updated at runtime, capping the
+// executable transactions of an account.
+func TestTransactionSetLimits(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account, _ := deriveSender(transaction(0, 0, key))
+	pool.currentState.AddBalance(account, big.NewInt(0xffffffffffffff))
+
+	price := big.NewInt(common.DefaultMinGasPrice)
+	for i := uint64(0); i < 5; i++ {
+		if err := pool.AddLocal(pricedTransaction(i, 100000, price, key)); err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	limits := pool.Limits()
+	limits.AccountLimits[account] = 2
+	if err := pool.SetLimits(limits); err != nil {
+		t.Fatalf("failed to set limits: %v", err)
+	}
+	if pending := pool.pending[account].Len(); pending != 2 {
+		t.Errorf("pending transactions mismatch: have %d, want %d", pending, 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Lift the cap and make sure the account can fill its slots again
+	delete(limits.AccountLimits, account)
+	limits.GlobalSlots = 8192
+	if err := pool.SetLimits(limits); err != nil {
+		t.Fatalf("failed to set limits: %v", err)
+	}
+	if err := pool.AddLocal(pricedTransaction(2, 100000, price, key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if pending := pool.pending[account].Len(); pending != 3 {
+		t.Errorf("pending transactions mismatch: have %d, want %d", pending, 3)
+	}
+	if have := pool.Limits(); have.GlobalSlots != 8192 || len(have.AccountLimits) != 0 {
+		t.Errorf("limits mismatch: have %+v", have)
+	}
+	// Invalid limits are rejected
+	limits.GlobalSlots = 0
+	if err := pool.SetLimits(limits); err == nil {
+		t.Errorf("zero global slots accepted")
+	}
+}
+
 // Tests that the transaction limits are enforced the same way irrelevant whether
 // the transactions are added one by one or in batches.
 func TestTransactionQueueLimitingEquivalency(t *testing.T)   { testTransactionLimitingEquivalency(t, 1) }
@@ -1433,6 +1536,133 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that the governance transactions of the masternode owners take the
+// reserved slots of a full pool, and are never pushed out by spam.
+func TestTransactionPoolPriorityLane(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool with a masternode candidate in the validator contract
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	owner, _ := crypto.GenerateKey()
+	validator := common.HexToAddress(common.MasternodeVotingSMC)
+	candidate := common.HexToAddress("0x0000000000000000000000000000000000000abc")
+	candidates := common.BigToHash(big.NewInt(3))
+	statedb.SetState(validator, candidates, common.BigToHash(big.NewInt(1)))
+	statedb.SetState(validator, state.GetLocDynamicArrAtElement(candidates, 0, 1), candidate.Hash())
+	statedb.SetState(validator, common.BigToHash(state.GetLocMappingAtKey(candidate.Hash(), 1)), crypto.PubkeyToAddress(owner.PublicKey).Hash())
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+	config.PrioritySlots = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	price := func(n int64) *big.Int { return big.NewInt(n * common.DefaultMinGasPrice) }
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	keys = append(keys, owner)
+	for _, key := range keys {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10)))
+	}
+	governance := func(nonce uint64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, validator, big.NewInt(0), 100000, price(1), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	// Fill up the pool with spam
+	for i := uint64(0); i < 4; i++ {
+		if err := pool.AddRemote(pricedTransaction(i, 100000, price(10), keys[0])); err != nil {
+			t.Fatalf("failed to add spam transaction %d: %v", i, err)
+		}
+	}
+	// The first governance transaction takes the reserved slot, the next one
+	// pushes out spam
+	votes := types.Transactions{governance(0, owner), governance(1, owner)}
+	for i, tx := range votes {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add governance transaction %d: %v", i, err)
+		}
+	}
+	if len(pool.reserved) != 1 {
+		t.Fatalf("reserved slots mismatch: have %d, want %d", len(pool.reserved), 1)
+	}
+	// Better priced spam is admitted, but not at the expense of governance
+	if err := pool.AddRemote(pricedTransaction(0, 100000, price(20), keys[1])); err != nil {
+		t.Fatalf("failed to add well priced transaction: %v", err)
+	}
+	for i, tx := range votes {
+		if pool.Get(tx.Hash()) == nil {
+			t.Errorf("governance transaction %d evicted", i)
+		}
+	}
+	// Governance transactions of anyone else don't take the priority lane
+	if err := pool.AddRemote(governance(0, keys[2])); err != ErrUnderpriced {
+		t.Fatalf("adding underpriced vote error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if senders := pool.PrioritySenders(); len(senders) != 2 {
+		t.Errorf("priority senders mismatch: have %d, want %d", len(senders), 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the lifecycle of the transactions in the pool is notified: queued,
+// pending, replaced and dropped with the reason.
+func TestTransactionLifecycleEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	events := make(chan TxLifecycleEvent, 32)
+	sub := pool.SubscribeTxLifecycleEvent(events)
+	defer sub.Unsubscribe()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(10)))
+	price := func(n int64) *big.Int { return big.NewInt(n * common.DefaultMinGasPrice) }
+
+	future := pricedTransaction(1, 100000, price(1), key)
+	head := pricedTransaction(0, 100000, price(1), key)
+	replacement := pricedTransaction(0, 100000, price(2), key)
+	for _, tx := range []*types.Transaction{future, head, replacement} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	pool.SetGasPrice(price(2))
+
+	want := map[TxLifecycleEvent]bool{
+		{Tx: future, Status: TxLifecycleQueued}:                                  true,
+		{Tx: head, Status: TxLifecycleQueued}:                                    true,
+		{Tx: head, Status: TxLifecyclePending}:                                   true,
+		{Tx: future, Status: TxLifecyclePending}:                                 true,
+		{Tx: head, Status: TxLifecycleReplaced, Replacement: replacement.Hash()}: true,
+		{Tx: replacement, Status: TxLifecyclePending}:                            true,
+		{Tx: future, Status: TxLifecycleDropped, Reason: TxDropUnderpriced}:      true,
+	}
+	for len(want) > 0 {
+		select {
+		case ev := <-events:
+			if !want[ev] {
+				t.Fatalf("unexpected event: %x %s %s", ev.Tx.Hash(), ev.Status, ev.Reason)
+			}
+			delete(want, ev)
+		case <-time.After(time.Second):
+			for ev := range want {
+				t.Errorf("event not fired: %x %s %s", ev.Tx.Hash(), ev.Status, ev.Reason)
+			}
+			return
+		}
+	}
+}
+
 // Tests that the pool rejects replacement transactions that don't meet the minimum
 // price bump required.
 func TestTransactionReplacement(t *testing.T) {
@@ -87,6 +87,7 @@ type Header struct {
 	Validators  []byte         `json:"validators"       gencodec:"required"`
 	Validator   []byte         `json:"validator"        gencodec:"required"`
 	Penalties   []byte         `json:"penalties"        gencodec:"required"`
+	TradeRoot   common.Hash    `json:"tradesRoot"       rlp:"optional"` // Root of the trades matched in the block, from the trade root fork
 }
 
 // field type overrides for gencodec
@@ -127,7 +128,7 @@ func (h *Header) HashNoNonce() common.Hash {
 
 // HashNoNonce returns the hash which is used as input for the proof-of-work search.
 func (h *Header) HashNoValidator() common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		h.ParentHash,
 		h.UncleHash,
 		h.Coinbase,
@@ -146,7 +147,12 @@ func (h *Header) HashNoValidator() common.Hash {
 		h.Validators,
 		[]byte{},
 		h.Penalties,
-	})
+	}
+	// The headers before the trade root fork are hashed without it
+	if h.TradeRoot != (common.Hash{}) {
+		fields = append(fields, h.TradeRoot)
+	}
+	return rlpHash(fields)
 }
 
 // Size returns the approximate memory used by all internal contents. It is used
@@ -163,10 +169,11 @@ func rlpHash(x interface{}) (h common.Hash) {
 }
 
 // Body is a simple (mutable, non-safe) data container for storing and moving
-// a block's data contents (transactions and uncles) together.
+// a block's data contents (transactions, uncles and trades) together.
 type Body struct {
 	Transactions []*Transaction
 	Uncles       []*Header
+	Trades       []*Trade `rlp:"optional"`
 }
 
 // Block represents an entire block in the Ethereum blockchain.
@@ -174,6 +181,7 @@ type Block struct {
 	header       *Header
 	uncles       []*Header
 	transactions Transactions
+	trades       Trades
 
 	// caches
 	hash atomic.Value
@@ -207,6 +215,7 @@ type extblock struct {
 	Header *Header
 	Txs    []*Transaction
 	Uncles []*Header
+	Trades []*Trade `rlp:"optional"`
 }
 
 // [deprecated by eth/63]
@@ -295,7 +304,7 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&eb); err != nil {
 		return err
 	}
-	b.header, b.uncles, b.transactions = eb.Header, eb.Uncles, eb.Txs
+	b.header, b.uncles, b.transactions, b.trades = eb.Header, eb.Uncles, eb.Txs, eb.Trades
 	b.size.Store(common.StorageSize(rlp.ListSize(size)))
 	return nil
 }
@@ -306,6 +315,7 @@ func (b *Block) EncodeRLP(w io.Writer) error {
 		Header: b.header,
 		Txs:    b.transactions,
 		Uncles: b.uncles,
+		Trades: b.trades,
 	})
 }
 
@@ -323,6 +333,7 @@ func (b *StorageBlock) DecodeRLP(s *rlp.Stream) error {
 
 func (b *Block) Uncles() []*Header          { return b.uncles }
 func (b *Block) Transactions() Transactions { return b.transactions }
+func (b *Block) Trades() Trades             { return b.trades }
 
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
@@ -349,6 +360,7 @@ func (b *Block) ParentHash() common.Hash  { return b.header.ParentHash }
 func (b *Block) TxHash() common.Hash      { return b.header.TxHash }
 func (b *Block) ReceiptHash() common.Hash { return b.header.ReceiptHash }
 func (b *Block) UncleHash() common.Hash   { return b.header.UncleHash }
+func (b *Block) TradeRoot() common.Hash   { return b.header.TradeRoot }
 func (b *Block) Extra() []byte            { return common.CopyBytes(b.header.Extra) }
 func (b *Block) Penalties() []byte        { return common.CopyBytes(b.header.Penalties) }
 func (b *Block) Validator() []byte        { return common.CopyBytes(b.header.Validator) }
@@ -356,7 +368,7 @@ func (b *Block) Validator() []byte        { return common.CopyBytes(b.header.Val
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
-func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles} }
+func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles, b.trades} }
 
 func (b *Block) HashNoNonce() common.Hash {
 	return b.header.HashNoNonce()
@@ -397,6 +409,7 @@ func (b *Block) WithSeal(header *Header) *Block {
 		header:       &cpy,
 		transactions: b.transactions,
 		uncles:       b.uncles,
+		trades:       b.trades,
 	}
 }
 
@@ -414,6 +427,19 @@ func (b *Block) WithBody(transactions []*Transaction, uncles []*Header) *Block {
 	return block
 }
 
+// WithTrades returns a new block with the data from b and the given trades.
+// The trade root of the header is left as is.
+func (b *Block) WithTrades(trades []*Trade) *Block {
+	block := &Block{
+		header:       CopyHeader(b.header),
+		transactions: b.transactions,
+		uncles:       b.uncles,
+		trades:       make(Trades, len(trades)),
+	}
+	copy(block.trades, trades)
+	return block
+}
+
 // Hash returns the keccak256 hash of b's header.
 // The hash is computed on the first call and cached thereafter.
 func (b *Block) Hash() common.Hash {
@@ -59,3 +59,52 @@ func TestBlockEncoding(t *testing.T) {
 		t.Errorf("encoded block mismatch:\ngot:  %x\nwant: %x", ourBlockEnc, blockEnc)
 	}
 }
+
+func TestBlockTradesEncoding(t *testing.T) {
+	trades := Trades{{
+		TakerOrderHash: common.HexToHash("0x01"),
+		MakerOrderHash: common.HexToHash("0x02"),
+		Maker:          common.HexToAddress("0x03"),
+		MakerExchange:  common.HexToAddress("0x04"),
+		BaseToken:      common.HexToAddress("0x05"),
+		QuoteToken:     common.HexToAddress("0x06"),
+		Price:          big.NewInt(100),
+		Quantity:       big.NewInt(2),
+		TakerFee:       big.NewInt(1),
+		MakerFee:       big.NewInt(1),
+	}}
+	header := &Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: big.NewInt(1)}
+	plain := NewBlockWithHeader(header)
+
+	header.TradeRoot = DeriveSha(trades)
+	block := NewBlockWithHeader(header).WithTrades(trades)
+	if block.Hash() == plain.Hash() {
+		t.Fatal("trade root not committed to by the block hash")
+	}
+	if block.HashNoValidator() == plain.HashNoValidator() {
+		t.Fatal("trade root not committed to by the sealed hash")
+	}
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	var dec Block
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if dec.Hash() != block.Hash() {
+		t.Errorf("hash mismatch: got %x, want %x", dec.Hash(), block.Hash())
+	}
+	if !reflect.DeepEqual(dec.Trades(), trades) {
+		t.Errorf("trades mismatch: got %v, want %v", dec.Trades(), trades)
+	}
+	if !VerifyTradeRoot(dec.Header(), dec.Trades()) {
+		t.Error("trades of the decoded block don't match its trade root")
+	}
+	if VerifyTradeRoot(dec.Header(), nil) {
+		t.Error("missing trades match the trade root")
+	}
+	if VerifyTradeRoot(plain.Header(), trades) {
+		t.Error("trades match a header without trade root")
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package types
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Fuzz implements a go-fuzz fuzzer method to test the decoding of order
+// transactions, as received from the network, and the recovery of their
+// senders. The order corpus of tomox/gen_fuzz_corpus.go seeds it.
+func Fuzz(data []byte) int {
+	tx := new(OrderTransaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		return 0
+	}
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		panic(err)
+	}
+	cpy := new(OrderTransaction)
+	if err := rlp.DecodeBytes(enc, cpy); err != nil {
+		panic(err)
+	}
+	if cpy.Hash() != tx.Hash() {
+		panic("order hash mismatch")
+	}
+	if reenc, _ := rlp.EncodeToBytes(cpy); !bytes.Equal(enc, reenc) {
+		panic("order encoding mismatch")
+	}
+	OrderTxSigner{}.Hash(tx)
+	OrderTypedData(tx)
+	if _, err := OrderSender(OrderTxSigner{}, tx); err != nil {
+		return 0
+	}
+	return 1
+}
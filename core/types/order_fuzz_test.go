@@ -0,0 +1,49 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the fuzzer accepts signed orders, with and without their optional
+// fields, and rejects malformed input.
+func TestFuzz(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	order := NewOrderTransaction(0, big.NewInt(100), big.NewInt(2), common.Address{1}, crypto.PubkeyToAddress(key.PublicKey), common.Address{2}, common.Address{3}, OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+	for i, mode := range []string{"", SelfTradePreventionCancelBoth} {
+		order.SetSelfTradePrevention(mode)
+		order.SetExpiryBlock(uint64(i * 1000))
+		signed, err := OrderSignTx(order, OrderTxSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, _ := rlp.EncodeToBytes(signed)
+		if res := Fuzz(enc); res != 1 {
+			t.Errorf("order %d: fuzz result mismatch: have %d, want 1", i, res)
+		}
+		if res := Fuzz(enc[:len(enc)-1]); res != 0 {
+			t.Errorf("order %d: truncated fuzz result mismatch: have %d, want 0", i, res)
+		}
+	}
+}
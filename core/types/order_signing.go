@@ -106,6 +106,9 @@ func (ordersign OrderTxSigner) SignatureValues(tx *OrderTransaction, sig []byte)
 	return r, s, v, nil
 }
 
+// orderHashExtensionVersion prefixes the optional fields of an order hash.
+const orderHashExtensionVersion = 1
+
 // OrderCreateHash hash of new order
 func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash {
 	sha := sha3.NewKeccak256()
@@ -121,6 +124,15 @@ func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash
 	sha.Write([]byte(tx.Status()))
 	sha.Write([]byte(tx.Type()))
 	sha.Write(common.BigToHash(big.NewInt(int64(tx.Nonce()))).Bytes())
+	// The optional fields are only hashed when set, keeping the hashes of
+	// orders without them. They follow a version byte and the mode is length
+	// prefixed, so that no two sets of fields share a preimage.
+	if mode, number := tx.SelfTradePrevention(), tx.ExpiryBlock(); mode != "" || number != 0 {
+		sha.Write([]byte{orderHashExtensionVersion})
+		sha.Write(common.BigToHash(big.NewInt(int64(len(mode)))).Bytes())
+		sha.Write([]byte(mode))
+		sha.Write(common.BigToHash(new(big.Int).SetUint64(number)).Bytes())
+	}
 	return common.BytesToHash(sha.Sum(nil))
 }
 
@@ -132,15 +144,139 @@ func (ordersign OrderTxSigner) OrderCancelHash(tx *OrderTransaction) common.Hash
 	return common.BytesToHash(sha.Sum(nil))
 }
 
+// OrderAmendHash hash of the amendment of an order, covering its new price and
+// quantity
+func (ordersign OrderTxSigner) OrderAmendHash(tx *OrderTransaction) common.Hash {
+	sha := sha3.NewKeccak256()
+	sha.Write(tx.OrderHash().Bytes())
+	sha.Write(common.BigToHash(tx.Quantity()).Bytes())
+	sha.Write(common.BigToHash(tx.Price()).Bytes())
+	sha.Write(common.BigToHash(big.NewInt(int64(tx.Nonce()))).Bytes())
+	return common.BytesToHash(sha.Sum(nil))
+}
+
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (ordersign OrderTxSigner) Hash(tx *OrderTransaction) common.Hash {
 	if tx.IsCancelledOrder() {
 		return ordersign.OrderCancelHash(tx)
 	}
+	if tx.IsAmendedOrder() {
+		return ordersign.OrderAmendHash(tx)
+	}
 	return ordersign.OrderCreateHash(tx)
 }
 
+// orderTypedDataTypes are the EIP-712 types of new, cancelled and amended orders.
+var orderTypedDataTypes = map[string][]TypedDataField{
+	typedDataDomain: {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+	},
+	"Order": {
+		{Name: "exchangeAddress", Type: "address"},
+		{Name: "userAddress", Type: "address"},
+		{Name: "baseToken", Type: "address"},
+		{Name: "quoteToken", Type: "address"},
+		{Name: "quantity", Type: "uint256"},
+		{Name: "price", Type: "uint256"},
+		{Name: "side", Type: "string"},
+		{Name: "status", Type: "string"},
+		{Name: "type", Type: "string"},
+		{Name: "nonce", Type: "uint256"},
+	},
+	"OrderCancel": {
+		{Name: "orderHash", Type: "bytes32"},
+		{Name: "nonce", Type: "uint256"},
+	},
+	"OrderAmend": {
+		{Name: "orderHash", Type: "bytes32"},
+		{Name: "quantity", Type: "uint256"},
+		{Name: "price", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+	},
+}
+
+// OrderTypedData returns the order as an EIP-712 structured message, which
+// wallets supporting eth_signTypedData can sign instead of the order hash.
+func OrderTypedData(tx *OrderTransaction) *TypedData {
+	nonce := new(big.Int).SetUint64(tx.Nonce()).String()
+	if tx.IsCancelledOrder() {
+		return &TypedData{
+			Types:       orderTypedDataTypes,
+			PrimaryType: "OrderCancel",
+			Domain:      map[string]interface{}{"name": "TomoX", "version": "1"},
+			Message: map[string]interface{}{
+				"orderHash": tx.OrderHash().Hex(),
+				"nonce":     nonce,
+			},
+		}
+	}
+	quantity, price := new(big.Int), new(big.Int)
+	if tx.Quantity() != nil {
+		quantity = tx.Quantity()
+	}
+	if tx.Price() != nil {
+		price = tx.Price()
+	}
+	if tx.IsAmendedOrder() {
+		return &TypedData{
+			Types:       orderTypedDataTypes,
+			PrimaryType: "OrderAmend",
+			Domain:      map[string]interface{}{"name": "TomoX", "version": "1"},
+			Message: map[string]interface{}{
+				"orderHash": tx.OrderHash().Hex(),
+				"quantity":  quantity.String(),
+				"price":     price.String(),
+				"nonce":     nonce,
+			},
+		}
+	}
+	typedData := &TypedData{
+		Types:       orderTypedDataTypes,
+		PrimaryType: "Order",
+		Domain:      map[string]interface{}{"name": "TomoX", "version": "1"},
+		Message: map[string]interface{}{
+			"exchangeAddress": tx.ExchangeAddress().Hex(),
+			"userAddress":     tx.UserAddress().Hex(),
+			"baseToken":       tx.BaseToken().Hex(),
+			"quoteToken":      tx.QuoteToken().Hex(),
+			"quantity":        quantity.String(),
+			"price":           price.String(),
+			"side":            tx.Side(),
+			"status":          tx.Status(),
+			"type":            tx.Type(),
+			"nonce":           nonce,
+		},
+	}
+	// The self-trade prevention mode and the expiry block extend the order
+	// type only when set
+	var extra []TypedDataField
+	if mode := tx.SelfTradePrevention(); mode != "" {
+		extra = append(extra, TypedDataField{Name: "selfTradePrevention", Type: "string"})
+		typedData.Message["selfTradePrevention"] = mode
+	}
+	if number := tx.ExpiryBlock(); number != 0 {
+		extra = append(extra, TypedDataField{Name: "expiryBlock", Type: "uint256"})
+		typedData.Message["expiryBlock"] = new(big.Int).SetUint64(number).String()
+	}
+	if len(extra) > 0 {
+		orderTypes := make(map[string][]TypedDataField, len(orderTypedDataTypes))
+		for name, fields := range orderTypedDataTypes {
+			orderTypes[name] = fields
+		}
+		orderTypes["Order"] = append(append([]TypedDataField{}, orderTypedDataTypes["Order"]...), extra...)
+		typedData.Types = orderTypes
+	}
+	return typedData
+}
+
+// TypedDataHash returns the EIP-712 hash of the order, the alternative to the
+// order hash signed as an Ethereum signed message.
+func (ordersign OrderTxSigner) TypedDataHash(tx *OrderTransaction) (common.Hash, error) {
+	return OrderTypedData(tx).Hash()
+}
+
 //MarshalSignature encode signature
 func MarshalSignature(R, S, V *big.Int) ([]byte, error) {
 	sigBytes1 := common.BigToHash(R).Bytes()
@@ -161,7 +297,8 @@ func MarshalSignature(R, S, V *big.Int) ([]byte, error) {
 	return sigBytes, nil
 }
 
-// Sender get signer from
+// Sender get signer from. The order hash is expected to be signed as an Ethereum
+// signed message, or else the EIP-712 typed data of the order.
 func (ordersign OrderTxSigner) Sender(tx *OrderTransaction) (common.Address, error) {
 	message := crypto.Keccak256(
 		[]byte("\x19Ethereum Signed Message:\n32"),
@@ -177,8 +314,16 @@ func (ordersign OrderTxSigner) Sender(tx *OrderTransaction) (common.Address, err
 		return common.Address{}, err
 	}
 	address := crypto.PubkeyToAddress(*pubKey)
+	if address == tx.UserAddress() {
+		return address, nil
+	}
+	// Not signed by the user as a message, try as typed data
+	if hash, err := ordersign.TypedDataHash(tx); err == nil {
+		if pubKey, err := crypto.SigToPub(hash.Bytes(), sigBytes); err == nil && crypto.PubkeyToAddress(*pubKey) == tx.UserAddress() {
+			return tx.UserAddress(), nil
+		}
+	}
 	return address, nil
-
 }
 
 // CacheOrderSigner cache signed order
@@ -41,6 +41,15 @@ const (
 	OrderStatusPartialFilled = "PARTIAL_FILLED"
 	OrderStatusFilled        = "FILLED"
 	OrderStatusCancelled     = "CANCELLED"
+	OrderStatusAmended       = "AMENDED"
+)
+
+// Self-trade prevention modes, applied when a taker order would match a maker
+// order of the same user. Without a mode the orders trade with each other.
+const (
+	SelfTradePreventionCancelNewest = "CANCEL_NEWEST" // the taker order is rejected
+	SelfTradePreventionCancelOldest = "CANCEL_OLDEST" // the maker order is cancelled
+	SelfTradePreventionCancelBoth   = "CANCEL_BOTH"   // both orders are cancelled
 )
 
 // OrderTransaction order transaction
@@ -72,6 +81,12 @@ type ordertxdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash common.Hash `json:"hash"`
+
+	// Self-trade prevention mode, left out of the encoding of orders without it
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty" rlp:"optional"`
+
+	// Last block the order can be matched in, zero for orders good till cancelled
+	ExpiryBlock uint64 `json:"expiryBlock,omitempty" rlp:"optional"`
 }
 
 // IsCancelledOrder check if tx is cancelled transaction
@@ -82,6 +97,11 @@ func (tx *OrderTransaction) IsCancelledOrder() bool {
 	return false
 }
 
+// IsAmendedOrder check if tx amends the price or quantity of a resting order
+func (tx *OrderTransaction) IsAmendedOrder() bool {
+	return tx.Status() == OrderStatusAmended
+}
+
 // EncodeRLP implements rlp.Encoder
 func (tx *OrderTransaction) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, &tx.data)
@@ -113,6 +133,8 @@ func (tx *OrderTransaction) PairName() string                { return tx.data.Pa
 func (tx *OrderTransaction) Signature() (V, R, S *big.Int)   { return tx.data.V, tx.data.R, tx.data.S }
 func (tx *OrderTransaction) OrderHash() common.Hash          { return tx.data.Hash }
 func (tx *OrderTransaction) OrderID() uint64                 { return tx.data.OrderID }
+func (tx *OrderTransaction) SelfTradePrevention() string     { return tx.data.SelfTradePrevention }
+func (tx *OrderTransaction) ExpiryBlock() uint64             { return tx.data.ExpiryBlock }
 func (tx *OrderTransaction) EncodedSide() *big.Int {
 	if tx.Side() == "BUY" {
 		return big.NewInt(0)
@@ -122,6 +144,19 @@ func (tx *OrderTransaction) EncodedSide() *big.Int {
 }
 func (tx *OrderTransaction) SetOrderHash(h common.Hash) { tx.data.Hash = h }
 
+// SetSelfTradePrevention sets the self-trade prevention mode of the order. It
+// is part of the order hash, so it must be set before the order is signed.
+func (tx *OrderTransaction) SetSelfTradePrevention(mode string) {
+	tx.data.SelfTradePrevention = mode
+}
+
+// SetExpiryBlock sets the last block the order can be matched in, after which
+// it's pruned from the order book. It is part of the order hash, so it must be
+// set before the order is signed.
+func (tx *OrderTransaction) SetExpiryBlock(number uint64) {
+	tx.data.ExpiryBlock = number
+}
+
 // From get transaction from
 func (tx *OrderTransaction) From() *common.Address {
 	if tx.data.V != nil {
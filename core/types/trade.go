@@ -0,0 +1,62 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Trade is a trade between a taker and a maker order produced by the matching
+// of the orders of a block. The trades of a block are committed to by the trade
+// root of its header, so that they can be proven without matching the orders
+// again.
+type Trade struct {
+	TakerOrderHash common.Hash    `json:"takerOrderHash"`
+	MakerOrderHash common.Hash    `json:"makerOrderHash"`
+	Maker          common.Address `json:"maker"`
+	MakerExchange  common.Address `json:"makerExchange"`
+	BaseToken      common.Address `json:"baseToken"`
+	QuoteToken     common.Address `json:"quoteToken"`
+	Price          *big.Int       `json:"price"`
+	Quantity       *big.Int       `json:"quantity"`
+	TakerFee       *big.Int       `json:"takerFee"`
+	MakerFee       *big.Int       `json:"makerFee"`
+}
+
+// Trades is a list of trades, in matching order.
+type Trades []*Trade
+
+// Len returns the length of s.
+func (s Trades) Len() int { return len(s) }
+
+// GetRlp implements Rlpable and returns the i'th element of s in rlp.
+func (s Trades) GetRlp(i int) []byte {
+	enc, _ := rlp.EncodeToBytes(s[i])
+	return enc
+}
+
+// VerifyTradeRoot reports whether the trades are the ones committed to by the
+// trade root of a header, none if the header has no trade root.
+func VerifyTradeRoot(header *Header, trades Trades) bool {
+	if header.TradeRoot == (common.Hash{}) {
+		return len(trades) == 0
+	}
+	return DeriveSha(trades) == header.TradeRoot
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -294,11 +295,25 @@ func (tx *Transaction) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
 	return tx.data.V, tx.data.R, tx.data.S
 }
 
+// IsSpecialTransaction reports whether the transaction is sent to a system
+// contract receiving free transactions from the masternodes.
 func (tx *Transaction) IsSpecialTransaction() bool {
+	contract := params.SpecialTxs.Lookup(tx.To())
+	return contract != nil && contract.Free
+}
+
+// IsGovernanceTransaction reports whether the transaction is sent to one of the
+// contracts governing the masternodes: the validator, block signers and
+// randomize contracts.
+func (tx *Transaction) IsGovernanceTransaction() bool {
 	if tx.To() == nil {
 		return false
 	}
-	return tx.To().String() == common.RandomizeSMC || tx.To().String() == common.BlockSigners
+	switch tx.To().String() {
+	case common.MasternodeVotingSMC, common.BlockSigners, common.RandomizeSMC:
+		return true
+	}
+	return false
 }
 
 func (tx *Transaction) IsMatchingTransaction() bool {
@@ -313,14 +328,20 @@ func (tx *Transaction) IsMatchingTransaction() bool {
 	return true
 }
 
-func (tx *Transaction) IsSkipNonceTransaction() bool {
+// IsLendingTransaction reports whether the transaction carries the lending
+// orders processed in its block.
+func (tx *Transaction) IsLendingTransaction() bool {
 	if tx.To() == nil {
 		return false
 	}
-	if tx.To().String() == common.TomoXAddr || tx.To().String() == common.TomoXStateAddr {
-		return true
-	}
-	return false
+	return tx.To().String() == common.TomoXLendingAddr
+}
+
+// IsSkipNonceTransaction reports whether the transaction is sent to a system
+// contract whose transactions do not consume the nonce of their sender.
+func (tx *Transaction) IsSkipNonceTransaction() bool {
+	contract := params.SpecialTxs.Lookup(tx.To())
+	return contract != nil && contract.SkipNonce
 }
 
 func (tx *Transaction) IsSigningTransaction() bool {
@@ -332,6 +353,9 @@ func (tx *Transaction) IsSigningTransaction() bool {
 		return false
 	}
 
+	if len(tx.Data()) < 4 {
+		return false
+	}
 	method := common.ToHex(tx.Data()[0:4])
 
 	if method != common.SignMethod {
@@ -482,10 +506,15 @@ func (s TxByNonce) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 type TxByPrice struct {
 	txs        Transactions
 	payersSwap map[common.Address]*big.Int
+	priority   map[common.Address]struct{} // Senders whose governance transactions come first
+	signer     Signer
 }
 
 func (s TxByPrice) Len() int { return len(s.txs) }
 func (s TxByPrice) Less(i, j int) bool {
+	if i_priority, j_priority := s.prioritized(s.txs[i]), s.prioritized(s.txs[j]); i_priority != j_priority {
+		return i_priority
+	}
 	i_price := s.txs[i].data.Price
 	if s.txs[i].To() != nil {
 		if _, ok := s.payersSwap[*s.txs[i].To()]; ok {
@@ -503,6 +532,20 @@ func (s TxByPrice) Less(i, j int) bool {
 }
 func (s TxByPrice) Swap(i, j int) { s.txs[i], s.txs[j] = s.txs[j], s.txs[i] }
 
+// prioritized reports whether a transaction is a governance one from one of the
+// priority senders.
+func (s TxByPrice) prioritized(tx *Transaction) bool {
+	if len(s.priority) == 0 || !tx.IsGovernanceTransaction() {
+		return false
+	}
+	from, err := Sender(s.signer, tx)
+	if err != nil {
+		return false
+	}
+	_, ok := s.priority[from]
+	return ok
+}
+
 func (s *TxByPrice) Push(x interface{}) {
 	s.txs = append(s.txs, x.(*Transaction))
 }
@@ -573,6 +616,13 @@ func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transa
 	}, specialTxs
 }
 
+// Prioritize moves the governance transactions of the given senders ahead of
+// all the others, whatever their price.
+func (t *TransactionsByPriceAndNonce) Prioritize(senders map[common.Address]struct{}) {
+	t.heads.priority, t.heads.signer = senders, t.signer
+	heap.Init(&t.heads)
+}
+
 // Peek returns the next transaction by price.
 func (t *TransactionsByPriceAndNonce) Peek() *Transaction {
 	if len(t.heads.txs) == 0 {
@@ -192,6 +192,47 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 	}
 }
 
+// Tests that the governance transactions of the priority senders come before
+// any other, whatever their price.
+func TestTransactionPriorityLane(t *testing.T) {
+	owner, _ := crypto.GenerateKey()
+	voter, _ := crypto.GenerateKey()
+	spammer, _ := crypto.GenerateKey()
+
+	signer := HomesteadSigner{}
+	validator := common.HexToAddress(common.MasternodeVotingSMC)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, price int64) *Transaction {
+		tx, _ := SignTx(NewTransaction(nonce, to, big.NewInt(0), 100, big.NewInt(price), nil), signer, key)
+		return tx
+	}
+	vote := sign(owner, 0, validator, 1)
+	groups := map[common.Address]Transactions{
+		crypto.PubkeyToAddress(owner.PublicKey):   {vote, sign(owner, 1, common.Address{}, 1)},
+		crypto.PubkeyToAddress(voter.PublicKey):   {sign(voter, 0, validator, 50)},
+		crypto.PubkeyToAddress(spammer.PublicKey): {sign(spammer, 0, common.Address{}, 100)},
+	}
+	txset, _ := NewTransactionsByPriceAndNonce(signer, groups, nil, map[common.Address]*big.Int{})
+	txset.Prioritize(map[common.Address]struct{}{crypto.PubkeyToAddress(owner.PublicKey): {}})
+
+	txs := Transactions{}
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		txs = append(txs, tx)
+		txset.Shift()
+	}
+	if len(txs) != 4 {
+		t.Fatalf("expected %d transactions, found %d", 4, len(txs))
+	}
+	if txs[0] != vote {
+		t.Errorf("governance transaction not first: have %x, want %x", txs[0].Hash(), vote.Hash())
+	}
+	// The rest are sorted by price, the vote of a non priority sender included
+	for i, price := range []int64{100, 50, 1} {
+		if have := txs[i+1].GasPrice().Int64(); have != price {
+			t.Errorf("tx #%d: price mismatch: have %d, want %d", i+1, have, price)
+		}
+	}
+}
+
 // TestTransactionJSON tests serializing/de-serializing to/from JSON.
 func TestTransactionJSON(t *testing.T) {
 	key, err := crypto.GenerateKey()
@@ -0,0 +1,291 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// typedDataDomain is the name of the EIP-712 type describing the domain.
+const typedDataDomain = "EIP712Domain"
+
+var (
+	typedDataArray   = regexp.MustCompile(`^(.+)\[(\d*)\]$`)
+	typedDataInteger = regexp.MustCompile(`^(u?)int(\d*)$`)
+	typedDataBytes   = regexp.MustCompile(`^bytes(\d+)$`)
+)
+
+// TypedDataField is a member of an EIP-712 structured type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is an EIP-712 structured message, as accepted by eth_signTypedData.
+// The domain is described by the EIP712Domain type, which must be part of the
+// types.
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// Hash returns the EIP-712 hash to sign for the typed data:
+//
+//	keccak256("\x19\x01" || domainSeparator || hashStruct(message)).
+func (typedData *TypedData) Hash() (common.Hash, error) {
+	if _, ok := typedData.Types[typedDataDomain]; !ok {
+		return common.Hash{}, fmt.Errorf("missing %s type", typedDataDomain)
+	}
+	domain, err := typedData.HashStruct(typedDataDomain, typedData.Domain)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	message, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domain[:], message[:]), nil
+}
+
+// HashStruct returns the hashStruct of the given data as an instance of the
+// given type.
+func (typedData *TypedData) HashStruct(primaryType string, data map[string]interface{}) (common.Hash, error) {
+	encoded, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// TypeHash returns the hash of the encoding of the given type.
+func (typedData *TypedData) TypeHash(primaryType string) common.Hash {
+	return crypto.Keccak256Hash([]byte(typedData.EncodeType(primaryType)))
+}
+
+// EncodeType returns the encoding of the given type, followed by the sorted
+// encodings of the structured types it references, e.g.
+//
+//	Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (typedData *TypedData) EncodeType(primaryType string) string {
+	deps := typedData.dependencies(primaryType, nil)
+	sort.Strings(deps[1:])
+
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range typedData.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String()
+}
+
+// dependencies returns the given type followed by the structured types it
+// references, recursively.
+func (typedData *TypedData) dependencies(primaryType string, found []string) []string {
+	if match := typedDataArray.FindStringSubmatch(primaryType); match != nil {
+		primaryType = match[1]
+	}
+	for _, dep := range found {
+		if dep == primaryType {
+			return found
+		}
+	}
+	if _, ok := typedData.Types[primaryType]; !ok {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range typedData.Types[primaryType] {
+		found = typedData.dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeData returns the encoding of the given data as an instance of the given
+// type: its type hash followed by the 32 bytes encoding of each member.
+func (typedData *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := typedData.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", primaryType)
+	}
+	encoded := typedData.TypeHash(primaryType).Bytes()
+	for _, field := range fields {
+		value, err := typedData.encodeValue(field.Type, data[field.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, field.Name, err)
+		}
+		encoded = append(encoded, value...)
+	}
+	return encoded, nil
+}
+
+// encodeValue returns the 32 bytes encoding of a member of the given type.
+func (typedData *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	// Arrays and structures are encoded by the hash of their content
+	if match := typedDataArray.FindStringSubmatch(typ); match != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		if match[2] != "" {
+			if length, _ := strconv.Atoi(match[2]); length != len(items) {
+				return nil, fmt.Errorf("invalid %s length %d", typ, len(items))
+			}
+		}
+		var encoded []byte
+		for _, item := range items {
+			value, err := typedData.encodeValue(match[1], item)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, value...)
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		hash, err := typedData.HashStruct(typ, data)
+		if err != nil {
+			return nil, err
+		}
+		return hash.Bytes(), nil
+	}
+	// Dynamic values are encoded by their hash
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := typedDataBytesValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address value %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+
+	case "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		if flag {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+	}
+	// Static values are padded to 32 bytes
+	if match := typedDataBytes.FindStringSubmatch(typ); match != nil {
+		blob, err := typedDataBytesValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if length, _ := strconv.Atoi(match[1]); length < 1 || length > 32 || len(blob) != length {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	if match := typedDataInteger.FindStringSubmatch(typ); match != nil {
+		number, err := typedDataIntegerValue(value)
+		if err != nil {
+			return nil, err
+		}
+		bits := 256
+		if match[2] != "" {
+			bits, _ = strconv.Atoi(match[2])
+		}
+		if match[1] == "u" {
+			if number.Sign() < 0 || number.BitLen() > bits {
+				return nil, fmt.Errorf("%s overflow %v", typ, number)
+			}
+		} else {
+			// Signed integers range over [-2^(bits-1), 2^(bits-1)-1]
+			max := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+			if number.Cmp(new(big.Int).Neg(max)) < 0 || number.Cmp(max) >= 0 {
+				return nil, fmt.Errorf("%s overflow %v", typ, number)
+			}
+		}
+		return math.PaddedBigBytes(math.U256(number), 32), nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", typ)
+}
+
+// typedDataBytesValue decodes a hex encoded byte array member.
+func typedDataBytesValue(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid bytes value %v", value)
+	}
+	return hexutil.Decode(str)
+}
+
+// typedDataIntegerValue decodes an integer member, given either as a JSON
+// number or as a decimal or hex string.
+func typedDataIntegerValue(value interface{}) (*big.Int, error) {
+	switch value := value.(type) {
+	case float64:
+		number, accuracy := new(big.Float).SetFloat64(value).Int(nil)
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("invalid integer value %v", value)
+		}
+		return number, nil
+	case string:
+		negative := strings.HasPrefix(value, "-")
+		number, ok := math.ParseBig256(strings.TrimPrefix(value, "-"))
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value %q", value)
+		}
+		if negative {
+			number.Neg(number)
+		}
+		return number, nil
+	case *big.Int:
+		return new(big.Int).Set(value), nil
+	}
+	return nil, fmt.Errorf("invalid integer value %v", value)
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The example message of the EIP-712 specification.
+const typedDataMail = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestTypedDataHash(t *testing.T) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(typedDataMail), &typedData); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := typedData.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("type encoding mismatch: have %s, want %s", have, want)
+	}
+	domain, err := typedData.HashStruct("EIP712Domain", typedData.Domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); domain != want {
+		t.Errorf("domain separator mismatch: have %x, want %x", domain, want)
+	}
+	message, err := typedData.HashStruct("Mail", typedData.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"); message != want {
+		t.Errorf("message hash mismatch: have %x, want %x", message, want)
+	}
+	hash, err := typedData.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); hash != want {
+		t.Errorf("hash mismatch: have %x, want %x", hash, want)
+	}
+}
+
+// Tests the range of the integers encoded as typed data.
+func TestTypedDataIntegerRange(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		ok    bool
+	}{
+		{"int8", "-128", true},
+		{"int8", "127", true},
+		{"int8", "-129", false},
+		{"int8", "128", false},
+		{"uint8", "255", true},
+		{"uint8", "256", false},
+		{"uint8", "-1", false},
+		{"int256", "-57896044618658097711785492504343953926634992332820282019728792003956564819968", true},
+		{"int256", "57896044618658097711785492504343953926634992332820282019728792003956564819967", true},
+		{"int256", "57896044618658097711785492504343953926634992332820282019728792003956564819968", false},
+	}
+	var typedData TypedData
+	for _, tt := range tests {
+		encoded, err := typedData.encodeValue(tt.typ, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s %s: error mismatch: have %v, want ok %v", tt.typ, tt.value, err, tt.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		// Values are encoded as their two's complement on 256 bits
+		want, _ := new(big.Int).SetString(tt.value, 10)
+		have := new(big.Int).SetBytes(encoded)
+		if want.Sign() < 0 {
+			have.Sub(have, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if have.Cmp(want) != 0 {
+			t.Errorf("%s %s: encoding mismatch: have %v", tt.typ, tt.value, have)
+		}
+	}
+}
+
+// Tests that orders signed as typed data are accepted as well as orders signed
+// as messages, and that a signature does not verify for another user.
+func TestOrderTypedDataSender(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	signer := OrderTxSigner{}
+
+	order := NewOrderTransaction(1, big.NewInt(100), big.NewInt(2), common.Address{1}, user, common.Address{2}, common.Address{3}, OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+	hash, err := signer.TypedDataHash(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := order.WithSignature(signer, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := signer.Sender(signed); err != nil || from != user {
+		t.Errorf("typed data sender mismatch: have %x (%v), want %x", from, err, user)
+	}
+	signed, err = OrderSignTx(order, signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := signer.Sender(signed); err != nil || from != user {
+		t.Errorf("message sender mismatch: have %x (%v), want %x", from, err, user)
+	}
+	other := NewOrderTransaction(1, big.NewInt(100), big.NewInt(2), common.Address{1}, common.Address{4}, common.Address{2}, common.Address{3}, OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+	if signed, err = other.WithSignature(signer, sig); err != nil {
+		t.Fatal(err)
+	}
+	if from, _ := signer.Sender(signed); from == other.UserAddress() {
+		t.Errorf("typed data signature accepted for another user")
+	}
+}
+
+// Tests that the self-trade prevention mode is signed and encoded only when it
+// is set, keeping the hashes and encodings of orders without it.
+func TestOrderSelfTradePrevention(t *testing.T) {
+	signer := OrderTxSigner{}
+	order := NewOrderTransaction(1, big.NewInt(100), big.NewInt(2), common.Address{1}, common.Address{4}, common.Address{2}, common.Address{3}, OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+	enc, _ := rlp.EncodeToBytes(order)
+	hash := signer.Hash(order)
+	typedHash, _ := signer.TypedDataHash(order)
+
+	order.SetSelfTradePrevention(SelfTradePreventionCancelBoth)
+	if signer.Hash(order) == hash {
+		t.Errorf("order hash does not cover the self-trade prevention")
+	}
+	if h, _ := signer.TypedDataHash(order); h == typedHash {
+		t.Errorf("typed data hash does not cover the self-trade prevention")
+	}
+	stpEnc, _ := rlp.EncodeToBytes(order)
+	decoded := new(OrderTransaction)
+	if err := rlp.DecodeBytes(stpEnc, decoded); err != nil {
+		t.Fatalf("failed to decode order: %v", err)
+	}
+	if decoded.SelfTradePrevention() != SelfTradePreventionCancelBoth {
+		t.Errorf("self-trade prevention mismatch: have %q, want %q", decoded.SelfTradePrevention(), SelfTradePreventionCancelBoth)
+	}
+	// Orders without a mode keep their 16 fields
+	for enc, want := range map[string]int{string(enc): 16, string(stpEnc): 17} {
+		content, _, _ := rlp.SplitList([]byte(enc))
+		if n, _ := rlp.CountValues(content); n != want {
+			t.Errorf("encoded field count mismatch: have %d, want %d", n, want)
+		}
+	}
+}
+
+// Tests that amendments sign the new price and quantity of the amended order.
+func TestOrderAmendHash(t *testing.T) {
+	signer := OrderTxSigner{}
+	amend := func(quantity, price int64) *OrderTransaction {
+		return NewOrderTransaction(1, big.NewInt(quantity), big.NewInt(price), common.Address{1}, common.Address{4}, common.Address{2}, common.Address{3}, OrderStatusAmended, "BUY", "LO", "TOMO/USDT", common.Hash{5}, 7)
+	}
+	hash := signer.Hash(amend(100, 2))
+	if hash != signer.OrderAmendHash(amend(100, 2)) {
+		t.Errorf("amendment not signed as such")
+	}
+	if signer.Hash(amend(100, 3)) == hash || signer.Hash(amend(101, 2)) == hash {
+		t.Errorf("amendment hash does not cover the price and quantity")
+	}
+	typedData := OrderTypedData(amend(100, 2))
+	if typedData.PrimaryType != "OrderAmend" || typedData.Message["price"] != "2" {
+		t.Errorf("typed data mismatch: have %s %v", typedData.PrimaryType, typedData.Message)
+	}
+	if _, err := typedData.Hash(); err != nil {
+		t.Errorf("failed to hash typed data: %v", err)
+	}
+}
+
+// Tests that the expiry block is signed and encoded only when it is set.
+func TestOrderExpiryBlock(t *testing.T) {
+	signer := OrderTxSigner{}
+	order := NewOrderTransaction(1, big.NewInt(100), big.NewInt(2), common.Address{1}, common.Address{4}, common.Address{2}, common.Address{3}, OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+	hash := signer.Hash(order)
+	typedHash, _ := signer.TypedDataHash(order)
+
+	order.SetExpiryBlock(1000)
+	if signer.Hash(order) == hash {
+		t.Errorf("order hash does not cover the expiry block")
+	}
+	if h, err := signer.TypedDataHash(order); err != nil || h == typedHash {
+		t.Errorf("typed data hash does not cover the expiry block: %v", err)
+	}
+	enc, _ := rlp.EncodeToBytes(order)
+	decoded := new(OrderTransaction)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("failed to decode order: %v", err)
+	}
+	if decoded.ExpiryBlock() != 1000 || decoded.SelfTradePrevention() != "" {
+		t.Errorf("decoded order mismatch: expiry %d, self-trade prevention %q", decoded.ExpiryBlock(), decoded.SelfTradePrevention())
+	}
+}
+
+// Tests that the optional order fields are hashed unambiguously, so that a
+// self-trade prevention mode can't pass for an expiry block.
+func TestOrderHashOptionalFields(t *testing.T) {
+	signer := OrderTxSigner{}
+	order := func(mode string, expiry uint64) *OrderTransaction {
+		tx := NewOrderTransaction(1, big.NewInt(100), big.NewInt(2), common.Address{1}, common.Address{4}, common.Address{2}, common.Address{3}, OrderStatusNew, "BUY", "LO", "TOMO/USDT", common.Hash{}, 0)
+		tx.SetSelfTradePrevention(mode)
+		tx.SetExpiryBlock(expiry)
+		return tx
+	}
+	expiry := common.BigToHash(big.NewInt(1000)).Bytes()
+	hashes := map[common.Hash]string{
+		signer.Hash(order("", 0)):                               "plain",
+		signer.Hash(order("", 1000)):                            "expiry",
+		signer.Hash(order(string(expiry), 0)):                   "mode",
+		signer.Hash(order(SelfTradePreventionCancelBoth, 0)):    "cancel both",
+		signer.Hash(order(SelfTradePreventionCancelBoth, 1000)): "cancel both with expiry",
+	}
+	if len(hashes) != 5 {
+		t.Errorf("order hashes collide: have %d distinct, want 5", len(hashes))
+	}
+}
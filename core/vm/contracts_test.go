@@ -22,6 +22,10 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -481,3 +485,58 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+func TestPrecompiledMasternodeData(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// Register a candidate in the validator contract
+	validator, candidate := common.HexToAddress(common.MasternodeVotingSMC), common.HexToAddress("0xc1")
+	candidatesSlot := common.BigToHash(big.NewInt(3))
+	statedb.SetState(validator, candidatesSlot, common.BigToHash(big.NewInt(1)))
+	statedb.SetState(validator, state.GetLocDynamicArrAtElement(candidatesSlot, 0, 1), candidate.Hash())
+	capSlot := state.GetLocMappingAtKey(candidate.Hash(), 1)
+	statedb.SetState(validator, common.BigToHash(capSlot.Add(capSlot, big.NewInt(1))), common.BigToHash(big.NewInt(50000)))
+
+	config := *params.AllPosvProtocolChanges
+	posv := *config.Posv
+	posv.Epoch, posv.MasternodeDataBlock = 900, big.NewInt(1000)
+	config.Posv = &posv
+
+	masternodes := []common.Address{common.HexToAddress("0xa1"), common.HexToAddress("0xa2")}
+	context := Context{
+		CanTransfer:    func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:       func(StateDB, common.Address, common.Address, *big.Int) {},
+		GetMasternodes: func() []common.Address { return masternodes },
+		BlockNumber:    big.NewInt(1800),
+	}
+	call := func(evm *EVM, method string, args ...common.Hash) []byte {
+		input := crypto.Keccak256([]byte(method))[:4]
+		for _, arg := range args {
+			input = append(input, arg.Bytes()...)
+		}
+		ret, _, err := evm.Call(AccountRef(common.HexToAddress("1337")), common.HexToAddress(common.MasternodeData), input, 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+		return ret
+	}
+	evm := NewEVM(context, statedb, &config, Config{})
+	if ret := call(evm, "getMasternodes()"); common.Bytes2Hex(ret) != common.Bytes2Hex(encodeAddresses(masternodes)) {
+		t.Errorf("masternodes mismatch: have %x", ret)
+	}
+	if ret := call(evm, "getCandidates()"); common.Bytes2Hex(ret) != common.Bytes2Hex(encodeAddresses([]common.Address{candidate})) {
+		t.Errorf("candidates mismatch: have %x", ret)
+	}
+	if ret := call(evm, "getCandidateCap(address)", candidate.Hash()); new(big.Int).SetBytes(ret).Int64() != 50000 {
+		t.Errorf("candidate cap mismatch: have %x", ret)
+	}
+	if ret := call(evm, "getEpoch()"); new(big.Int).SetBytes(ret).Int64() != 2 {
+		t.Errorf("epoch mismatch: have %x", ret)
+	}
+	// The contract does not exist before its activation
+	context.BlockNumber = big.NewInt(999)
+	if ret := call(NewEVM(context, statedb, &config, Config{}), "getEpoch()"); len(ret) != 0 {
+		t.Errorf("inactive contract returned %x", ret)
+	}
+}
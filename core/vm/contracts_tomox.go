@@ -0,0 +1,490 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// StatefulPrecompiledContract is a native contract working on the state of the
+// chain, such as the data published by the TomoX engine or the masternodes.
+type StatefulPrecompiledContract interface {
+	RequiredGas(evm *EVM, input []byte) uint64                              // RequiredPrice calculates the contract gas use
+	RunStateful(evm *EVM, contract *Contract, input []byte) ([]byte, error) // RunStateful runs the precompiled contract on the state of the EVM
+}
+
+// statefulPrecompile is a stateful pre-compiled contract along with the chain
+// rule activating it.
+type statefulPrecompile struct {
+	contract StatefulPrecompiledContract
+	isActive func(config *params.ChainConfig, num *big.Int) bool
+}
+
+// precompiledContractsTomo contains the stateful pre-compiled contracts of
+// TomoChain.
+var precompiledContractsTomo = map[common.Address]statefulPrecompile{
+	common.HexToAddress(common.TomoXPriceOracle): {&tomoxPriceOracle{}, (*params.ChainConfig).IsPriceOracle},
+	common.HexToAddress(common.MasternodeData):   {&masternodeData{}, (*params.ChainConfig).IsMasternodeData},
+	common.HexToAddress(common.TomoXPairHalt):    {&tomoxPairHalt{}, (*params.ChainConfig).IsTomoXPairHalt},
+	common.HexToAddress(common.TomoXPairSize):    {&tomoxPairSize{}, (*params.ChainConfig).IsTomoXPairSize},
+	common.HexToAddress(common.BLSRegistry):      {&blsRegistry{}, (*params.ChainConfig).IsBLS},
+}
+
+// statefulPrecompile returns the stateful pre-compiled contract active at an
+// address for the current block, nil if there is none.
+func (evm *EVM) statefulPrecompile(addr common.Address) StatefulPrecompiledContract {
+	p, ok := precompiledContractsTomo[addr]
+	if !ok || !p.isActive(evm.ChainConfig(), evm.BlockNumber) {
+		return nil
+	}
+	return p.contract
+}
+
+// RunStatefulPrecompiledContract runs and evaluates the output of a stateful
+// precompiled contract.
+func RunStatefulPrecompiledContract(evm *EVM, p StatefulPrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
+	gas := p.RequiredGas(evm, input)
+	if contract.UseGas(gas) {
+		return p.RunStateful(evm, contract, input)
+	}
+	return nil, ErrOutOfGas
+}
+
+// TomoXPriceSlots returns the storage slots of the price oracle account holding
+// the average price of a TomoX pair and the number of the block it was last
+// updated at.
+func TomoXPriceSlots(baseToken, quoteToken common.Address) (price common.Hash, number common.Hash) {
+	price = crypto.Keccak256Hash(baseToken[:], quoteToken[:])
+	number = common.BigToHash(new(big.Int).Add(price.Big(), big.NewInt(1)))
+	return price, number
+}
+
+// tomoxPriceOracle returns the volume weighted average price of a TomoX pair
+// over the last blocks with trades, as published by the TomoX engine.
+//
+// The input holds the base and quote token addresses as 32 byte words, the
+// output holds the price in quote token units for one base token and the
+// number of the block the price was computed at, zero if the pair was never
+// traded.
+type tomoxPriceOracle struct{}
+
+func (c *tomoxPriceOracle) RequiredGas(evm *EVM, input []byte) uint64 {
+	return params.TomoXPriceOracleGas
+}
+
+func (c *tomoxPriceOracle) RunStateful(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	baseToken := common.BytesToAddress(getData(input, 0, 32))
+	quoteToken := common.BytesToAddress(getData(input, 32, 32))
+
+	oracle := common.HexToAddress(common.TomoXPriceOracle)
+	priceSlot, numberSlot := TomoXPriceSlots(baseToken, quoteToken)
+	price, number := evm.StateDB.GetState(oracle, priceSlot), evm.StateDB.GetState(oracle, numberSlot)
+	return append(price.Bytes(), number.Bytes()...), nil
+}
+
+var (
+	errUnknownMasternodeMethod = errors.New("unknown masternode data method")
+
+	masternodesMethod  = string(crypto.Keccak256([]byte("getMasternodes()"))[:4])
+	candidatesMethod   = string(crypto.Keccak256([]byte("getCandidates()"))[:4])
+	candidateCapMethod = string(crypto.Keccak256([]byte("getCandidateCap(address)"))[:4])
+	epochMethod        = string(crypto.Keccak256([]byte("getEpoch()"))[:4])
+)
+
+// masternodeData exposes the masternodes of the current epoch, the candidates
+// and their caps as registered in the validator contract, and the current
+// epoch number. It is called with the ABI of the following interface:
+//
+//	interface MasternodeData {
+//		function getMasternodes() external view returns (address[]);
+//		function getCandidates() external view returns (address[]);
+//		function getCandidateCap(address candidate) external view returns (uint256);
+//		function getEpoch() external view returns (uint256);
+//	}
+type masternodeData struct{}
+
+func (c *masternodeData) masternodes(evm *EVM) []common.Address {
+	if evm.GetMasternodes == nil {
+		return nil
+	}
+	return evm.GetMasternodes()
+}
+
+func (c *masternodeData) RequiredGas(evm *EVM, input []byte) uint64 {
+	switch string(getData(input, 0, 4)) {
+	case masternodesMethod:
+		return params.MasternodeDataBaseGas + uint64(len(c.masternodes(evm)))*params.MasternodeDataItemGas
+	case candidatesMethod:
+		return params.MasternodeDataBaseGas + uint64(len(state.GetCandidates(evm.StateDB)))*params.MasternodeDataItemGas
+	}
+	return params.MasternodeDataBaseGas
+}
+
+func (c *masternodeData) RunStateful(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	switch string(getData(input, 0, 4)) {
+	case masternodesMethod:
+		return encodeAddresses(c.masternodes(evm)), nil
+	case candidatesMethod:
+		return encodeAddresses(state.GetCandidates(evm.StateDB)), nil
+	case candidateCapMethod:
+		candidate := common.BytesToAddress(getData(input, 4, 32))
+		return common.BigToHash(state.GetCandidateCap(evm.StateDB, candidate)).Bytes(), nil
+	case epochMethod:
+		var epoch uint64
+		if config := evm.ChainConfig().Posv; config != nil && config.Epoch > 0 {
+			epoch = evm.BlockNumber.Uint64() / config.Epoch
+		}
+		return common.BigToHash(new(big.Int).SetUint64(epoch)).Bytes(), nil
+	}
+	return nil, errUnknownMasternodeMethod
+}
+
+var (
+	errUnknownPairHaltMethod = errors.New("unknown pair halt method")
+	errPairHaltUnauthorized  = errors.New("pair halt: caller is not the relayer registration owner")
+
+	haltPairMethod   = string(crypto.Keccak256([]byte("haltPair(address,address)"))[:4])
+	resumePairMethod = string(crypto.Keccak256([]byte("resumePair(address,address)"))[:4])
+	isHaltedMethod   = string(crypto.Keccak256([]byte("isHalted(address,address)"))[:4])
+
+	pairHaltTopic = crypto.Keccak256Hash([]byte("PairHalt(address,address,bool)"))
+)
+
+// TomoXPairHaltSlot returns the storage slot of the pair halt account holding
+// whether the matching of a TomoX pair is halted.
+func TomoXPairHaltSlot(baseToken, quoteToken common.Address) common.Hash {
+	return crypto.Keccak256Hash(baseToken[:], quoteToken[:])
+}
+
+// tomoxPairHalt is the circuit breaker of the TomoX pairs. The owner of the
+// relayer registration contract halts the matching of a pair in an emergency,
+// the orders of a halted pair then rest in the order book without trading
+// until the pair is resumed. It is called with the ABI of the following
+// interface:
+//
+//	interface TomoXPairHalt {
+//		event PairHalt(address indexed baseToken, address indexed quoteToken, bool halted);
+//		function haltPair(address baseToken, address quoteToken) external;
+//		function resumePair(address baseToken, address quoteToken) external;
+//		function isHalted(address baseToken, address quoteToken) external view returns (bool);
+//	}
+type tomoxPairHalt struct{}
+
+func (c *tomoxPairHalt) RequiredGas(evm *EVM, input []byte) uint64 {
+	switch string(getData(input, 0, 4)) {
+	case haltPairMethod, resumePairMethod:
+		return params.TomoXPairHaltGas
+	}
+	return params.TomoXPairHaltedGas
+}
+
+func (c *tomoxPairHalt) RunStateful(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	var (
+		account    = common.HexToAddress(common.TomoXPairHalt)
+		baseToken  = common.BytesToAddress(getData(input, 4, 32))
+		quoteToken = common.BytesToAddress(getData(input, 36, 32))
+		slot       = TomoXPairHaltSlot(baseToken, quoteToken)
+	)
+	switch method := string(getData(input, 0, 4)); method {
+	case isHaltedMethod:
+		return evm.StateDB.GetState(account, slot).Bytes(), nil
+
+	case haltPairMethod, resumePairMethod:
+		if evm.interpreter.readOnly {
+			return nil, errWriteProtection
+		}
+		if !calledByRegistrationOwner(evm, contract, account) {
+			return nil, errPairHaltUnauthorized
+		}
+		halted := method == haltPairMethod
+		value := common.Hash{}
+		if halted {
+			value = common.BigToHash(big.NewInt(1))
+		}
+		if evm.StateDB.GetNonce(account) == 0 {
+			// Keep the account from being deleted as an empty account
+			evm.StateDB.SetNonce(account, 1)
+		}
+		evm.StateDB.SetState(account, slot, value)
+		evm.StateDB.AddLog(&types.Log{
+			Address:     account,
+			Topics:      []common.Hash{pairHaltTopic, baseToken.Hash(), quoteToken.Hash()},
+			Data:        value.Bytes(),
+			BlockNumber: evm.BlockNumber.Uint64(),
+		})
+		return nil, nil
+	}
+	return nil, errUnknownPairHaltMethod
+}
+
+var (
+	errUnknownPairSizeMethod = errors.New("unknown pair size method")
+	errPairSizeUnauthorized  = errors.New("pair size: caller is not the relayer registration owner")
+
+	setPairSizeMethod = string(crypto.Keccak256([]byte("setPairSize(address,address,uint256,uint256)"))[:4])
+	pairSizeMethod    = string(crypto.Keccak256([]byte("pairSize(address,address)"))[:4])
+
+	pairSizeTopic = crypto.Keccak256Hash([]byte("PairSize(address,address,uint256,uint256)"))
+)
+
+// TomoXPairSizeSlots returns the storage slots of the pair size account holding
+// the tick size and the lot size of a TomoX pair.
+func TomoXPairSizeSlots(baseToken, quoteToken common.Address) (tick common.Hash, lot common.Hash) {
+	tick = crypto.Keccak256Hash(baseToken[:], quoteToken[:])
+	lot = common.BigToHash(new(big.Int).Add(tick.Big(), big.NewInt(1)))
+	return tick, lot
+}
+
+// tomoxPairSize holds the listing parameters of the TomoX pairs, set by the
+// owner of the relayer registration contract. The price of a limit order must
+// be a multiple of the tick size of its pair and its quantity a multiple of the
+// lot size, which is then the minimum order size. Zero sizes leave the orders
+// unconstrained. It is called with the ABI of the following interface:
+//
+//	interface TomoXPairSize {
+//		event PairSize(address indexed baseToken, address indexed quoteToken, uint256 tickSize, uint256 lotSize);
+//		function setPairSize(address baseToken, address quoteToken, uint256 tickSize, uint256 lotSize) external;
+//		function pairSize(address baseToken, address quoteToken) external view returns (uint256 tickSize, uint256 lotSize);
+//	}
+type tomoxPairSize struct{}
+
+func (c *tomoxPairSize) RequiredGas(evm *EVM, input []byte) uint64 {
+	if string(getData(input, 0, 4)) == setPairSizeMethod {
+		return params.TomoXSetPairSizeGas
+	}
+	return params.TomoXPairSizeGas
+}
+
+func (c *tomoxPairSize) RunStateful(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	var (
+		account    = common.HexToAddress(common.TomoXPairSize)
+		baseToken  = common.BytesToAddress(getData(input, 4, 32))
+		quoteToken = common.BytesToAddress(getData(input, 36, 32))
+		tick, lot  = TomoXPairSizeSlots(baseToken, quoteToken)
+	)
+	switch string(getData(input, 0, 4)) {
+	case pairSizeMethod:
+		return append(evm.StateDB.GetState(account, tick).Bytes(), evm.StateDB.GetState(account, lot).Bytes()...), nil
+
+	case setPairSizeMethod:
+		if evm.interpreter.readOnly {
+			return nil, errWriteProtection
+		}
+		if !calledByRegistrationOwner(evm, contract, account) {
+			return nil, errPairSizeUnauthorized
+		}
+		tickSize, lotSize := common.BytesToHash(getData(input, 68, 32)), common.BytesToHash(getData(input, 100, 32))
+		if evm.StateDB.GetNonce(account) == 0 {
+			// Keep the account from being deleted as an empty account
+			evm.StateDB.SetNonce(account, 1)
+		}
+		evm.StateDB.SetState(account, tick, tickSize)
+		evm.StateDB.SetState(account, lot, lotSize)
+		evm.StateDB.AddLog(&types.Log{
+			Address:     account,
+			Topics:      []common.Hash{pairSizeTopic, baseToken.Hash(), quoteToken.Hash()},
+			Data:        append(tickSize.Bytes(), lotSize.Bytes()...),
+			BlockNumber: evm.BlockNumber.Uint64(),
+		})
+		return nil, nil
+	}
+	return nil, errUnknownPairSizeMethod
+}
+
+var (
+	errUnknownBLSMethod     = errors.New("unknown BLS registry method")
+	errBLSKeyRegistered     = errors.New("BLS registry: key already registered")
+	errBLSInvalidKey        = errors.New("BLS registry: invalid key or proof of possession")
+	errBLSKeyNotRegistered  = errors.New("BLS registry: key not registered")
+	errBLSInvalidCheckpoint = errors.New("BLS registry: not the gap block of the current epoch")
+	errBLSInvalidSignature  = errors.New("BLS registry: invalid checkpoint signature")
+	errBLSIndirectCall      = errors.New("BLS registry: delegated call")
+
+	registerKeyMethod            = string(crypto.Keccak256([]byte("registerKey(bytes32[4],bytes32[2])"))[:4])
+	signCheckpointMethod         = string(crypto.Keccak256([]byte("signCheckpoint(uint256,bytes32[2])"))[:4])
+	getKeyMethod                 = string(crypto.Keccak256([]byte("getKey(address)"))[:4])
+	getCheckpointSignatureMethod = string(crypto.Keccak256([]byte("getCheckpointSignature(address)"))[:4])
+
+	blsKeyTopic       = crypto.Keccak256Hash([]byte("KeyRegistered(address,bytes32[4])"))
+	blsSignatureTopic = crypto.Keccak256Hash([]byte("CheckpointSigned(address,uint256,bytes32[2])"))
+)
+
+// BLSCheckpointGap returns the number of the gap block of the epoch a block
+// belongs to, whose hash the masternodes sign for the checkpoint closing the
+// epoch, a checkpoint belonging to the epoch it closes.
+func BLSCheckpointGap(config *params.PosvConfig, number uint64) uint64 {
+	checkpoint := (number + config.Epoch - 1) / config.Epoch * config.Epoch
+	if checkpoint < config.Gap {
+		return 0
+	}
+	return checkpoint - config.Gap
+}
+
+// blsRegistry holds the BLS keys of the masternodes and their signatures of the
+// gap blocks, which the checkpoint headers aggregate. A key is registered once,
+// along with a proof of possession of its secret key, by the masternode itself.
+// A signature is recorded between the gap block of an epoch and its checkpoint,
+// checked against the key of the masternode. It is called with the ABI of the
+// following interface:
+//
+//	interface BLSRegistry {
+//		event KeyRegistered(address indexed masternode, bytes32[4] publicKey);
+//		event CheckpointSigned(address indexed masternode, uint256 indexed number, bytes32[2] signature);
+//		function registerKey(bytes32[4] publicKey, bytes32[2] proofOfPossession) external;
+//		function signCheckpoint(uint256 number, bytes32[2] signature) external;
+//		function getKey(address masternode) external view returns (bytes32[4]);
+//		function getCheckpointSignature(address masternode) external view returns (uint256 number, bytes32[2] signature);
+//	}
+type blsRegistry struct{}
+
+func (c *blsRegistry) RequiredGas(evm *EVM, input []byte) uint64 {
+	switch string(getData(input, 0, 4)) {
+	case registerKeyMethod:
+		return params.BLSRegisterKeyGas
+	case signCheckpointMethod:
+		return params.BLSSignCheckpointGas
+	}
+	return params.BLSRegistryReadGas
+}
+
+func (c *blsRegistry) RunStateful(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	account := common.HexToAddress(common.BLSRegistry)
+
+	switch string(getData(input, 0, 4)) {
+	case getKeyMethod:
+		masternode := common.BytesToAddress(getData(input, 4, 32))
+		if key := state.GetBLSPublicKey(evm.StateDB, masternode); key != nil {
+			return key, nil
+		}
+		return make([]byte, bls.PublicKeyLength), nil
+
+	case getCheckpointSignatureMethod:
+		masternode := common.BytesToAddress(getData(input, 4, 32))
+		number, sig := state.GetBLSCheckpointSignature(evm.StateDB, masternode)
+		if sig == nil {
+			sig = make([]byte, bls.SignatureLength)
+		}
+		return append(common.BigToHash(new(big.Int).SetUint64(number)).Bytes(), sig...), nil
+
+	case registerKeyMethod:
+		if evm.interpreter.readOnly {
+			return nil, errWriteProtection
+		}
+		if contract.Address() != account {
+			return nil, errBLSIndirectCall
+		}
+		masternode := contract.Caller()
+		if state.GetBLSPublicKey(evm.StateDB, masternode) != nil {
+			return nil, errBLSKeyRegistered
+		}
+		keyBytes := getData(input, 4, bls.PublicKeyLength)
+		key, err := bls.UnmarshalPublicKey(keyBytes)
+		if err != nil {
+			return nil, errBLSInvalidKey
+		}
+		proof, err := bls.UnmarshalSignature(getData(input, 4+bls.PublicKeyLength, bls.SignatureLength))
+		if err != nil || !proof.VerifyPossession(key) {
+			return nil, errBLSInvalidKey
+		}
+		if evm.StateDB.GetNonce(account) == 0 {
+			// Keep the account from being deleted as an empty account
+			evm.StateDB.SetNonce(account, 1)
+		}
+		for word := uint64(0); word < 4; word++ {
+			evm.StateDB.SetState(account, state.BLSKeySlot(masternode, word), common.BytesToHash(keyBytes[word*32:(word+1)*32]))
+		}
+		evm.StateDB.AddLog(&types.Log{
+			Address:     account,
+			Topics:      []common.Hash{blsKeyTopic, masternode.Hash()},
+			Data:        keyBytes,
+			BlockNumber: evm.BlockNumber.Uint64(),
+		})
+		return nil, nil
+
+	case signCheckpointMethod:
+		if evm.interpreter.readOnly {
+			return nil, errWriteProtection
+		}
+		if contract.Address() != account {
+			return nil, errBLSIndirectCall
+		}
+		config := evm.ChainConfig().Posv
+		if config == nil || config.Epoch == 0 {
+			return nil, errBLSInvalidCheckpoint
+		}
+		var (
+			masternode = contract.Caller()
+			number     = new(big.Int).SetBytes(getData(input, 4, 32))
+			sigBytes   = getData(input, 36, bls.SignatureLength)
+			current    = evm.BlockNumber.Uint64()
+			gap        = BLSCheckpointGap(config, current)
+		)
+		if !number.IsUint64() || number.Uint64() != gap || gap == 0 || gap >= current {
+			return nil, errBLSInvalidCheckpoint
+		}
+		keyBytes := state.GetBLSPublicKey(evm.StateDB, masternode)
+		if keyBytes == nil {
+			return nil, errBLSKeyNotRegistered
+		}
+		key, err := bls.UnmarshalPublicKey(keyBytes)
+		if err != nil {
+			return nil, errBLSKeyNotRegistered
+		}
+		sig, err := bls.UnmarshalSignature(sigBytes)
+		if err != nil || !sig.Verify(key, evm.GetHash(gap).Bytes()) {
+			return nil, errBLSInvalidSignature
+		}
+		evm.StateDB.SetState(account, state.BLSSignatureSlot(masternode, 0), common.BigToHash(number))
+		evm.StateDB.SetState(account, state.BLSSignatureSlot(masternode, 1), common.BytesToHash(sigBytes[:32]))
+		evm.StateDB.SetState(account, state.BLSSignatureSlot(masternode, 2), common.BytesToHash(sigBytes[32:]))
+		evm.StateDB.AddLog(&types.Log{
+			Address:     account,
+			Topics:      []common.Hash{blsSignatureTopic, masternode.Hash(), common.BigToHash(number)},
+			Data:        sigBytes,
+			BlockNumber: current,
+		})
+		return nil, nil
+	}
+	return nil, errUnknownBLSMethod
+}
+
+// calledByRegistrationOwner returns whether a precompiled contract account is
+// called by the owner of the relayer registration contract. Delegated calls run
+// in the context of the caller, only direct ones of the owner are accepted.
+func calledByRegistrationOwner(evm *EVM, contract *Contract, account common.Address) bool {
+	owner := evm.StateDB.GetState(common.HexToAddress(common.RelayerRegistrationSMC), common.Hash{})
+	return contract.Address() == account && contract.Caller() == common.BytesToAddress(owner.Bytes())
+}
+
+// encodeAddresses encodes a list of addresses as an ABI dynamic array return
+// value.
+func encodeAddresses(addrs []common.Address) []byte {
+	ret := make([]byte, 0, (len(addrs)+2)*32)
+	ret = append(ret, common.BigToHash(big.NewInt(32)).Bytes()...)
+	ret = append(ret, common.BigToHash(big.NewInt(int64(len(addrs)))).Bytes()...)
+	for _, addr := range addrs {
+		ret = append(ret, addr.Hash().Bytes()...)
+	}
+	return ret
+}
@@ -0,0 +1,282 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestTomoXPriceOracle(t *testing.T) {
+	var (
+		oracle = common.HexToAddress(common.TomoXPriceOracle)
+		base   = common.HexToAddress("0x1001")
+		quote  = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	priceSlot, numberSlot := TomoXPriceSlots(base, quote)
+	statedb.SetState(oracle, priceSlot, common.BigToHash(big.NewInt(175)))
+	statedb.SetState(oracle, numberSlot, common.BigToHash(big.NewInt(10)))
+
+	config := *params.TestChainConfig
+	config.Posv = &params.PosvConfig{Epoch: 900, PriceOracleBlock: big.NewInt(2)}
+	vmctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+	}
+	evm := NewEVM(vmctx, statedb, &config, Config{})
+
+	// The oracle is a plain account before its fork
+	if p := evm.statefulPrecompile(oracle); p != nil {
+		t.Fatalf("price oracle precompile active before its block")
+	}
+	evm.BlockNumber = big.NewInt(2)
+	ret, _, err := evm.StaticCall(AccountRef(base), oracle, append(base.Hash().Bytes(), quote.Hash().Bytes()...), 100000)
+	if err != nil {
+		t.Fatalf("failed to query the price: %v", err)
+	}
+	if price, number := new(big.Int).SetBytes(ret[:32]), new(big.Int).SetBytes(ret[32:]); price.Int64() != 175 || number.Int64() != 10 {
+		t.Fatalf("price mismatch: have %v at %v, want 175 at 10", price, number)
+	}
+}
+
+func TestTomoXPairHalt(t *testing.T) {
+	var (
+		owner   = common.HexToAddress("0x0a")
+		other   = common.HexToAddress("0x0b")
+		halt    = common.HexToAddress(common.TomoXPairHalt)
+		base    = common.HexToAddress("0x1001")
+		quote   = common.HexToAddress("0x1002")
+		pairArg = append(base.Hash().Bytes(), quote.Hash().Bytes()...)
+	)
+	call := func(method string) []byte {
+		return append(crypto.Keccak256([]byte(method))[:4], pairArg...)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetState(common.HexToAddress(common.RelayerRegistrationSMC), common.Hash{}, owner.Hash())
+
+	config := *params.TestChainConfig
+	config.Posv = &params.PosvConfig{Epoch: 900, TomoXPairHaltBlock: big.NewInt(0)}
+	vmctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+	}
+	evm := NewEVM(vmctx, statedb, &config, Config{})
+
+	halted := func() bool {
+		ret, _, err := evm.StaticCall(AccountRef(other), halt, call("isHalted(address,address)"), 100000)
+		if err != nil {
+			t.Fatalf("failed to query halt: %v", err)
+		}
+		return new(big.Int).SetBytes(ret).Sign() != 0
+	}
+	if halted() {
+		t.Fatalf("pair halted before any call")
+	}
+	// Only direct calls of the owner halt a pair
+	if _, _, err := evm.Call(AccountRef(other), halt, call("haltPair(address,address)"), 100000, new(big.Int)); err != errPairHaltUnauthorized {
+		t.Fatalf("halt by non owner: have %v, want %v", err, errPairHaltUnauthorized)
+	}
+	if _, _, err := evm.StaticCall(AccountRef(owner), halt, call("haltPair(address,address)"), 100000); err != errWriteProtection {
+		t.Fatalf("static halt: have %v, want %v", err, errWriteProtection)
+	}
+	if halted() {
+		t.Fatalf("pair halted by rejected calls")
+	}
+	if _, _, err := evm.Call(AccountRef(owner), halt, call("haltPair(address,address)"), 100000, new(big.Int)); err != nil {
+		t.Fatalf("failed to halt pair: %v", err)
+	}
+	if !halted() {
+		t.Fatalf("pair not halted")
+	}
+	if statedb.GetState(halt, TomoXPairHaltSlot(base, quote)) == (common.Hash{}) {
+		t.Fatalf("halt not recorded in the halt account storage")
+	}
+	if logs := statedb.Logs(); len(logs) != 1 || logs[0].Topics[1] != base.Hash() || logs[0].Topics[2] != quote.Hash() {
+		t.Fatalf("halt log mismatch: %v", logs)
+	}
+	if _, _, err := evm.Call(AccountRef(owner), halt, call("resumePair(address,address)"), 100000, new(big.Int)); err != nil {
+		t.Fatalf("failed to resume pair: %v", err)
+	}
+	if halted() {
+		t.Fatalf("pair still halted after resume")
+	}
+	// The halt is not active before its block
+	config.Posv.TomoXPairHaltBlock = big.NewInt(2)
+	if p := evm.statefulPrecompile(halt); p != nil {
+		t.Fatalf("halt precompile active before its block")
+	}
+}
+
+func TestTomoXPairSize(t *testing.T) {
+	var (
+		owner   = common.HexToAddress("0x0a")
+		other   = common.HexToAddress("0x0b")
+		sizes   = common.HexToAddress(common.TomoXPairSize)
+		base    = common.HexToAddress("0x1001")
+		quote   = common.HexToAddress("0x1002")
+		pairArg = append(base.Hash().Bytes(), quote.Hash().Bytes()...)
+	)
+	call := func(method string, args ...int64) []byte {
+		input := append(crypto.Keccak256([]byte(method))[:4], pairArg...)
+		for _, arg := range args {
+			input = append(input, common.BigToHash(big.NewInt(arg)).Bytes()...)
+		}
+		return input
+	}
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetState(common.HexToAddress(common.RelayerRegistrationSMC), common.Hash{}, owner.Hash())
+
+	config := *params.TestChainConfig
+	config.Posv = &params.PosvConfig{Epoch: 900, TomoXPairSizeBlock: big.NewInt(0)}
+	vmctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(1),
+	}
+	evm := NewEVM(vmctx, statedb, &config, Config{})
+
+	pairSize := func() (int64, int64) {
+		ret, _, err := evm.StaticCall(AccountRef(other), sizes, call("pairSize(address,address)"), 100000)
+		if err != nil || len(ret) != 64 {
+			t.Fatalf("failed to query pair size: %v (%x)", err, ret)
+		}
+		return new(big.Int).SetBytes(ret[:32]).Int64(), new(big.Int).SetBytes(ret[32:]).Int64()
+	}
+	if tick, lot := pairSize(); tick != 0 || lot != 0 {
+		t.Fatalf("pair sizes set before any call: %d/%d", tick, lot)
+	}
+	// Only direct calls of the owner set the sizes
+	if _, _, err := evm.Call(AccountRef(other), sizes, call("setPairSize(address,address,uint256,uint256)", 5, 100), 100000, new(big.Int)); err != errPairSizeUnauthorized {
+		t.Fatalf("set by non owner: have %v, want %v", err, errPairSizeUnauthorized)
+	}
+	if _, _, err := evm.StaticCall(AccountRef(owner), sizes, call("setPairSize(address,address,uint256,uint256)", 5, 100), 100000); err != errWriteProtection {
+		t.Fatalf("static set: have %v, want %v", err, errWriteProtection)
+	}
+	if _, _, err := evm.Call(AccountRef(owner), sizes, call("setPairSize(address,address,uint256,uint256)", 5, 100), 100000, new(big.Int)); err != nil {
+		t.Fatalf("failed to set pair size: %v", err)
+	}
+	if tick, lot := pairSize(); tick != 5 || lot != 100 {
+		t.Fatalf("pair size mismatch: have %d/%d, want 5/100", tick, lot)
+	}
+	tick, lot := TomoXPairSizeSlots(base, quote)
+	if statedb.GetState(sizes, tick).Big().Int64() != 5 || statedb.GetState(sizes, lot).Big().Int64() != 100 {
+		t.Fatalf("sizes not recorded in the pair size account storage")
+	}
+	if logs := statedb.Logs(); len(logs) != 1 || logs[0].Topics[1] != base.Hash() || logs[0].Topics[2] != quote.Hash() {
+		t.Fatalf("pair size log mismatch: %v", logs)
+	}
+	// The sizes are not active before their block
+	config.Posv.TomoXPairSizeBlock = big.NewInt(2)
+	if p := evm.statefulPrecompile(sizes); p != nil {
+		t.Fatalf("pair size precompile active before its block")
+	}
+}
+
+func TestBLSRegistry(t *testing.T) {
+	var (
+		masternode = common.HexToAddress("0x0a")
+		other      = common.HexToAddress("0x0b")
+		registry   = common.HexToAddress(common.BLSRegistry)
+		gapHash    = common.HexToHash("0xfeed")
+	)
+	sk, _ := bls.GenerateKey(rand.Reader)
+	key := sk.PublicKey().Marshal()
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := *params.TestChainConfig
+	config.Posv = &params.PosvConfig{Epoch: 900, Gap: 5, BLSBlock: big.NewInt(0)}
+	vmctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		GetHash: func(n uint64) common.Hash {
+			if n == 895 {
+				return gapHash
+			}
+			return common.Hash{}
+		},
+		BlockNumber: big.NewInt(897),
+	}
+	evm := NewEVM(vmctx, statedb, &config, Config{})
+
+	call := func(from common.Address, method string, args ...[]byte) error {
+		input := crypto.Keccak256([]byte(method))[:4]
+		for _, arg := range args {
+			input = append(input, arg...)
+		}
+		_, _, err := evm.Call(AccountRef(from), registry, input, 300000, new(big.Int))
+		return err
+	}
+	register := func(from common.Address, proof *bls.Signature) error {
+		return call(from, "registerKey(bytes32[4],bytes32[2])", key, proof.Marshal())
+	}
+	sign := func(from common.Address, number int64, sig *bls.Signature) error {
+		return call(from, "signCheckpoint(uint256,bytes32[2])", common.BigToHash(big.NewInt(number)).Bytes(), sig.Marshal())
+	}
+	// Keys are registered once, with a valid proof of possession
+	if err := register(masternode, sk.Sign(key)); err != errBLSInvalidKey {
+		t.Fatalf("registration without proof: have %v, want %v", err, errBLSInvalidKey)
+	}
+	if err := sign(masternode, 895, sk.Sign(gapHash[:])); err != errBLSKeyNotRegistered {
+		t.Fatalf("signature without key: have %v, want %v", err, errBLSKeyNotRegistered)
+	}
+	if err := register(masternode, sk.ProvePossession()); err != nil {
+		t.Fatalf("failed to register key: %v", err)
+	}
+	if have := state.GetBLSPublicKey(statedb, masternode); !bytes.Equal(have, key) {
+		t.Fatalf("registered key mismatch: have %x, want %x", have, key)
+	}
+	if err := register(masternode, sk.ProvePossession()); err != errBLSKeyRegistered {
+		t.Fatalf("second registration: have %v, want %v", err, errBLSKeyRegistered)
+	}
+	// Signatures are of the gap block of the current epoch, by the key owner
+	if err := sign(masternode, 894, sk.Sign(gapHash[:])); err != errBLSInvalidCheckpoint {
+		t.Fatalf("signature of another block: have %v, want %v", err, errBLSInvalidCheckpoint)
+	}
+	if err := sign(masternode, 895, sk.Sign([]byte("other"))); err != errBLSInvalidSignature {
+		t.Fatalf("signature of another hash: have %v, want %v", err, errBLSInvalidSignature)
+	}
+	if err := sign(other, 895, sk.Sign(gapHash[:])); err != errBLSKeyNotRegistered {
+		t.Fatalf("signature by another account: have %v, want %v", err, errBLSKeyNotRegistered)
+	}
+	if err := sign(masternode, 895, sk.Sign(gapHash[:])); err != nil {
+		t.Fatalf("failed to record signature: %v", err)
+	}
+	number, sig := state.GetBLSCheckpointSignature(statedb, masternode)
+	if number != 895 || !bytes.Equal(sig, sk.Sign(gapHash[:]).Marshal()) {
+		t.Fatalf("recorded signature mismatch: have %d %x", number, sig)
+	}
+	if logs := statedb.Logs(); len(logs) != 2 || logs[1].Topics[1] != masternode.Hash() {
+		t.Fatalf("registry logs mismatch: %v", logs)
+	}
+	// Signatures are only recorded after the gap block
+	evm.BlockNumber = big.NewInt(895)
+	if err := sign(masternode, 895, sk.Sign(gapHash[:])); err != errBLSInvalidCheckpoint {
+		t.Fatalf("signature at the gap block: have %v, want %v", err, errBLSInvalidCheckpoint)
+	}
+}
+
+func TestBLSCheckpointGap(t *testing.T) {
+	config := &params.PosvConfig{Epoch: 900, Gap: 5}
+	for _, tt := range []struct{ number, gap uint64 }{
+		{1, 895}, {896, 895}, {900, 895}, {901, 1795}, {1800, 1795},
+	} {
+		if gap := BLSCheckpointGap(config, tt.number); gap != tt.gap {
+			t.Errorf("gap of block %d mismatch: have %d, want %d", tt.number, gap, tt.gap)
+		}
+	}
+}
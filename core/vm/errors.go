@@ -25,4 +25,6 @@ var (
 	ErrTraceLimitReached        = errors.New("the number of logs reached the specified limit")
 	ErrInsufficientBalance      = errors.New("insufficient balance for transfer")
 	ErrContractAddressCollision = errors.New("contract address collision")
+
+	errSstoreSentry = errors.New("not enough gas for reentrancy sentry")
 )
@@ -36,18 +36,20 @@ type (
 	// GetHashFunc returns the nth block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// GetMasternodesFunc returns the masternodes of the current epoch and
+	// is used by the masternode data precompiled contract.
+	GetMasternodesFunc func() []common.Address
 )
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := activePrecompiles(evm.chainRules)[*contract.CodeAddr]; p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
+		if p := evm.statefulPrecompile(*contract.CodeAddr); p != nil {
+			return RunStatefulPrecompiledContract(evm, p, input, contract)
+		}
 	}
 	return evm.interpreter.Run(contract, input)
 }
@@ -62,6 +64,8 @@ type Context struct {
 	Transfer TransferFunc
 	// GetHash returns the hash corresponding to n
 	GetHash GetHashFunc
+	// GetMasternodes returns the masternodes of the current epoch
+	GetMasternodes GetMasternodesFunc
 
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
@@ -155,11 +159,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if activePrecompiles(evm.chainRules)[addr] == nil && evm.statefulPrecompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -425,3 +427,29 @@ func BenchmarkOpIsZero(b *testing.B) {
 	x := "FBCDEF090807060504030201ffffffffFBCDEF090807060504030201ffffffff"
 	opBenchmark(b, opIszero, x)
 }
+
+func TestIstanbulOpcodes(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		self  = common.BytesToAddress([]byte("contract"))
+		env   = NewEVM(Context{}, statedb, params.TestChainConfig, Config{})
+		stack = newstack()
+		pc    = uint64(0)
+	)
+	statedb.AddBalance(self, big.NewInt(1000))
+	contract := NewContract(AccountRef(common.Address{}), AccountRef(self), new(big.Int), 0)
+
+	opChainID(&pc, env, contract, nil, stack)
+	if have := stack.pop(); have.Cmp(params.TestChainConfig.ChainId) != 0 {
+		t.Errorf("chain id mismatch: have %v, want %v", have, params.TestChainConfig.ChainId)
+	}
+	opSelfBalance(&pc, env, contract, nil, stack)
+	if have := stack.pop(); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("self balance mismatch: have %v, want 1000", have)
+	}
+	if !istanbulInstructionSet[CHAINID].valid || constantinopleInstructionSet[CHAINID].valid {
+		t.Errorf("chain id opcode not gated by istanbul")
+	}
+}
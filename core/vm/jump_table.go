@@ -55,8 +55,51 @@ var (
 	homesteadInstructionSet      = NewHomesteadInstructionSet()
 	byzantiumInstructionSet      = NewByzantiumInstructionSet()
 	constantinopleInstructionSet = NewConstantinopleInstructionSet()
+	istanbulInstructionSet       = NewIstanbulInstructionSet()
+	berlinInstructionSet         = NewBerlinInstructionSet()
 )
 
+// NewBerlinInstructionSet returns the frontier, homestead, byzantium,
+// contantinople, istanbul and berlin instructions.
+func NewBerlinInstructionSet() [256]operation {
+	// instructions that can be executed during the istanbul phase, with
+	// the gas of the state accesses depending on the access list (EIP-2929).
+	instructionSet := NewIstanbulInstructionSet()
+	instructionSet[SLOAD].gasCost = gasSLoadEIP2929
+	instructionSet[SSTORE].gasCost = gasSStoreEIP2929
+	instructionSet[BALANCE].gasCost = gasBalanceEIP2929
+	instructionSet[EXTCODESIZE].gasCost = gasExtCodeSizeEIP2929
+	instructionSet[EXTCODECOPY].gasCost = gasExtCodeCopyEIP2929
+	instructionSet[CALL].gasCost = makeCallVariantGasEIP2929(gasCall)
+	instructionSet[CALLCODE].gasCost = makeCallVariantGasEIP2929(gasCallCode)
+	instructionSet[DELEGATECALL].gasCost = makeCallVariantGasEIP2929(gasDelegateCall)
+	instructionSet[STATICCALL].gasCost = makeCallVariantGasEIP2929(gasStaticCall)
+	instructionSet[SELFDESTRUCT].gasCost = gasSuicideEIP2929
+	return instructionSet
+}
+
+// NewIstanbulInstructionSet returns the frontier, homestead, byzantium,
+// contantinople and istanbul instructions.
+func NewIstanbulInstructionSet() [256]operation {
+	// instructions that can be executed during the constantinople phase,
+	// with the net gas metering of SSTORE (EIP-2200).
+	instructionSet := NewConstantinopleInstructionSet()
+	instructionSet[SSTORE].gasCost = gasSStoreEIP2200
+	instructionSet[CHAINID] = operation{
+		execute:       opChainID,
+		gasCost:       constGasFunc(GasQuickStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	instructionSet[SELFBALANCE] = operation{
+		execute:       opSelfBalance,
+		gasCost:       constGasFunc(GasFastStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	return instructionSet
+}
+
 // NewConstantinopleInstructionSet returns the frontier, homestead
 // byzantium and contantinople instructions.
 func NewConstantinopleInstructionSet() [256]operation {
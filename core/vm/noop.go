@@ -45,26 +45,33 @@ func (NoopEVMCallContext) DelegateCall(me ContractRef, addr common.Address, data
 
 type NoopStateDB struct{}
 
-func (NoopStateDB) CreateAccount(common.Address)                                       {}
-func (NoopStateDB) SubBalance(common.Address, *big.Int)                                {}
-func (NoopStateDB) AddBalance(common.Address, *big.Int)                                {}
-func (NoopStateDB) GetBalance(common.Address) *big.Int                                 { return nil }
-func (NoopStateDB) GetNonce(common.Address) uint64                                     { return 0 }
-func (NoopStateDB) SetNonce(common.Address, uint64)                                    {}
-func (NoopStateDB) GetCodeHash(common.Address) common.Hash                             { return common.Hash{} }
-func (NoopStateDB) GetCode(common.Address) []byte                                      { return nil }
-func (NoopStateDB) SetCode(common.Address, []byte)                                     {}
-func (NoopStateDB) GetCodeSize(common.Address) int                                     { return 0 }
-func (NoopStateDB) AddRefund(uint64)                                                   {}
-func (NoopStateDB) GetRefund() uint64                                                  { return 0 }
-func (NoopStateDB) GetState(common.Address, common.Hash) common.Hash                   { return common.Hash{} }
-func (NoopStateDB) SetState(common.Address, common.Hash, common.Hash)                  {}
-func (NoopStateDB) Suicide(common.Address) bool                                        { return false }
-func (NoopStateDB) HasSuicided(common.Address) bool                                    { return false }
-func (NoopStateDB) Exist(common.Address) bool                                          { return false }
-func (NoopStateDB) Empty(common.Address) bool                                          { return false }
-func (NoopStateDB) RevertToSnapshot(int)                                               {}
-func (NoopStateDB) Snapshot() int                                                      { return 0 }
-func (NoopStateDB) AddLog(*types.Log)                                                  {}
-func (NoopStateDB) AddPreimage(common.Hash, []byte)                                    {}
-func (NoopStateDB) ForEachStorage(common.Address, func(common.Hash, common.Hash) bool) {}
+func (NoopStateDB) CreateAccount(common.Address)                                        {}
+func (NoopStateDB) SubBalance(common.Address, *big.Int)                                 {}
+func (NoopStateDB) AddBalance(common.Address, *big.Int)                                 {}
+func (NoopStateDB) GetBalance(common.Address) *big.Int                                  { return nil }
+func (NoopStateDB) GetNonce(common.Address) uint64                                      { return 0 }
+func (NoopStateDB) SetNonce(common.Address, uint64)                                     {}
+func (NoopStateDB) GetCodeHash(common.Address) common.Hash                              { return common.Hash{} }
+func (NoopStateDB) GetCode(common.Address) []byte                                       { return nil }
+func (NoopStateDB) SetCode(common.Address, []byte)                                      {}
+func (NoopStateDB) GetCodeSize(common.Address) int                                      { return 0 }
+func (NoopStateDB) AddRefund(uint64)                                                    {}
+func (NoopStateDB) SubRefund(uint64)                                                    {}
+func (NoopStateDB) GetRefund() uint64                                                   { return 0 }
+func (NoopStateDB) GetCommittedState(common.Address, common.Hash) common.Hash           { return common.Hash{} }
+func (NoopStateDB) GetState(common.Address, common.Hash) common.Hash                    { return common.Hash{} }
+func (NoopStateDB) SetState(common.Address, common.Hash, common.Hash)                   {}
+func (NoopStateDB) Suicide(common.Address) bool                                         { return false }
+func (NoopStateDB) HasSuicided(common.Address) bool                                     { return false }
+func (NoopStateDB) Exist(common.Address) bool                                           { return false }
+func (NoopStateDB) Empty(common.Address) bool                                           { return false }
+func (NoopStateDB) PrepareAccessList(common.Address, *common.Address, []common.Address) {}
+func (NoopStateDB) AddressInAccessList(common.Address) bool                             { return false }
+func (NoopStateDB) SlotInAccessList(common.Address, common.Hash) (bool, bool)           { return false, false }
+func (NoopStateDB) AddAddressToAccessList(common.Address)                               {}
+func (NoopStateDB) AddSlotToAccessList(common.Address, common.Hash)                     {}
+func (NoopStateDB) RevertToSnapshot(int)                                                {}
+func (NoopStateDB) Snapshot() int                                                       { return 0 }
+func (NoopStateDB) AddLog(*types.Log)                                                   {}
+func (NoopStateDB) AddPreimage(common.Hash, []byte)                                     {}
+func (NoopStateDB) ForEachStorage(common.Address, func(common.Hash, common.Hash) bool)  {}
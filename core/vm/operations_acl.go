@@ -0,0 +1,157 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// The gas functions of the Berlin instruction set charge the first access to
+// an account or a storage slot in a transaction as cold, and the following
+// ones at the price of the gas table as warm (EIP-2929).
+
+// accessAccount adds an address to the access list, returning the surcharge of
+// a cold access on top of the warm price.
+func accessAccount(evm *EVM, addr common.Address) uint64 {
+	if evm.StateDB.AddressInAccessList(addr) {
+		return 0
+	}
+	evm.StateDB.AddAddressToAccessList(addr)
+	return params.ColdAccountAccessCostEIP2929 - params.WarmStorageReadCostEIP2929
+}
+
+// gasSLoadEIP2929 calculates the gas of SLOAD from the access list.
+func gasSLoadEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	slot := common.BigToHash(stack.Back(0))
+	if _, slotOk := evm.StateDB.SlotInAccessList(contract.Address(), slot); !slotOk {
+		evm.StateDB.AddSlotToAccessList(contract.Address(), slot)
+		return params.ColdSloadCostEIP2929, nil
+	}
+	return gt.SLoad, nil
+}
+
+// gasSStoreEIP2929 calculates the gas of SSTORE with the net gas metering of
+// EIP-2200, the reads of the slot being priced from the access list.
+func gasSStoreEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	// If we fail the minimum gas availability invariant, fail (0)
+	if contract.Gas <= params.SstoreSentryGasEIP2200 {
+		return 0, errSstoreSentry
+	}
+	var (
+		y, x    = stack.Back(1), stack.Back(0)
+		slot    = common.BigToHash(x)
+		current = evm.StateDB.GetState(contract.Address(), slot)
+		value   = common.BigToHash(y)
+		cost    uint64
+	)
+	// Check slot presence in the access list
+	if _, slotOk := evm.StateDB.SlotInAccessList(contract.Address(), slot); !slotOk {
+		cost = params.ColdSloadCostEIP2929
+		evm.StateDB.AddSlotToAccessList(contract.Address(), slot)
+	}
+	if current == value { // noop (1)
+		return cost + params.WarmStorageReadCostEIP2929, nil
+	}
+	original := evm.StateDB.GetCommittedState(contract.Address(), slot)
+	if original == current {
+		if original == (common.Hash{}) { // create slot (2.1.1)
+			return cost + params.SstoreSetGasEIP2200, nil
+		}
+		if value == (common.Hash{}) { // delete slot (2.1.2b)
+			evm.StateDB.AddRefund(params.SstoreClearsScheduleEIP2200)
+		}
+		// The cold read of the slot is part of the reset price
+		return cost + (params.SstoreResetGasEIP2200 - params.ColdSloadCostEIP2929), nil // write existing slot (2.1.2)
+	}
+	if original != (common.Hash{}) {
+		if current == (common.Hash{}) { // recreate slot (2.2.1.1)
+			evm.StateDB.SubRefund(params.SstoreClearsScheduleEIP2200)
+		} else if value == (common.Hash{}) { // delete slot (2.2.1.2)
+			evm.StateDB.AddRefund(params.SstoreClearsScheduleEIP2200)
+		}
+	}
+	if original == value {
+		if original == (common.Hash{}) { // reset to original inexistent slot (2.2.2.1)
+			evm.StateDB.AddRefund(params.SstoreSetGasEIP2200 - params.WarmStorageReadCostEIP2929)
+		} else { // reset to original existing slot (2.2.2.2)
+			evm.StateDB.AddRefund((params.SstoreResetGasEIP2200 - params.ColdSloadCostEIP2929) - params.WarmStorageReadCostEIP2929)
+		}
+	}
+	return cost + params.WarmStorageReadCostEIP2929, nil // dirty update (2.2)
+}
+
+// gasBalanceEIP2929 calculates the gas of BALANCE from the access list.
+func gasBalanceEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return gt.Balance + accessAccount(evm, common.BigToAddress(stack.Back(0))), nil
+}
+
+// gasExtCodeSizeEIP2929 calculates the gas of EXTCODESIZE from the access list.
+func gasExtCodeSizeEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return gt.ExtcodeSize + accessAccount(evm, common.BigToAddress(stack.Back(0))), nil
+}
+
+// gasExtCodeCopyEIP2929 calculates the gas of EXTCODECOPY from the access list.
+func gasExtCodeCopyEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	gas, err := gasExtCodeCopy(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	var overflow bool
+	if gas, overflow = math.SafeAdd(gas, accessAccount(evm, common.BigToAddress(stack.Back(0)))); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
+// makeCallVariantGasEIP2929 wraps the gas function of a call with the cold
+// access of the callee. The surcharge is deducted before the gas of the call
+// is calculated, so that the 63/64 rule applies to the gas left after it.
+func makeCallVariantGasEIP2929(oldCalculator gasFunc) gasFunc {
+	return func(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		coldCost := accessAccount(evm, common.BigToAddress(stack.Back(1)))
+		if !contract.UseGas(coldCost) {
+			return 0, ErrOutOfGas
+		}
+		gas, err := oldCalculator(gt, evm, contract, stack, mem, memorySize)
+		// Give the surcharge back, it is charged along with the call
+		contract.Gas += coldCost
+		if err != nil {
+			return 0, err
+		}
+		var overflow bool
+		if gas, overflow = math.SafeAdd(gas, coldCost); overflow {
+			return 0, errGasUintOverflow
+		}
+		return gas, nil
+	}
+}
+
+// gasSuicideEIP2929 calculates the gas of SELFDESTRUCT with the cold access of
+// the beneficiary, which has no warm price.
+func gasSuicideEIP2929(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var gas uint64
+	if address := common.BigToAddress(stack.Back(0)); !evm.StateDB.AddressInAccessList(address) {
+		evm.StateDB.AddAddressToAccessList(address)
+		gas = params.ColdAccountAccessCostEIP2929
+	}
+	suicideGas, err := gasSuicide(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return gas + suicideGas, nil
+}
@@ -0,0 +1,294 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package argon2 implements the argon2id key derivation function of RFC 9106,
+// version 1.3, used by the version 4 key files of the key store.
+package argon2
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync"
+)
+
+const (
+	version    = 0x13
+	modeID     = 2
+	syncPoints = 4 // Number of slices of the lanes
+
+	blockLength = 128 // Number of words in the blocks of 1 KiB
+)
+
+type block [blockLength]uint64
+
+// IDKey derives a key of keyLen bytes from the password and salt, making time
+// passes over memory KiB split into threads lanes computed in parallel.
+//
+// RFC 9106 recommends a time of 1 over 2 GiB of memory, or a time of 3 over
+// 64 MiB for the memory constrained environments, with 4 threads and a salt of
+// 16 bytes. It panics if time or threads is zero.
+func IDKey(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	return deriveKey(password, salt, nil, nil, time, memory, threads, keyLen)
+}
+
+// deriveKey derives an argon2id key, including the optional secret and
+// associated data of the specification.
+func deriveKey(password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if time < 1 {
+		panic("argon2: number of passes too small")
+	}
+	if threads < 1 {
+		panic("argon2: number of threads too small")
+	}
+	h0 := initHash(password, salt, secret, data, time, memory, uint32(threads), keyLen)
+
+	// The memory is rounded down to whole segments, at least two per lane
+	lanes := uint32(threads)
+	memory = memory / (syncPoints * lanes) * (syncPoints * lanes)
+	if memory < 2*syncPoints*lanes {
+		memory = 2 * syncPoints * lanes
+	}
+	B := initBlocks(&h0, memory, lanes)
+	processBlocks(B, time, memory, lanes)
+	return extractKey(B, memory, lanes, keyLen)
+}
+
+// initHash returns the hash H0 of the parameters, followed by room for the
+// block and lane indexes of the first blocks.
+func initHash(password, salt, secret, data []byte, time, memory, threads, keyLen uint32) [blake2bSize + 8]byte {
+	var (
+		h0     [blake2bSize + 8]byte
+		params [24]byte
+		length [4]byte
+	)
+	binary.LittleEndian.PutUint32(params[0:], threads)
+	binary.LittleEndian.PutUint32(params[4:], keyLen)
+	binary.LittleEndian.PutUint32(params[8:], memory)
+	binary.LittleEndian.PutUint32(params[12:], time)
+	binary.LittleEndian.PutUint32(params[16:], version)
+	binary.LittleEndian.PutUint32(params[20:], modeID)
+
+	d := newBlake2b(blake2bSize)
+	d.Write(params[:])
+	for _, field := range [][]byte{password, salt, secret, data} {
+		binary.LittleEndian.PutUint32(length[:], uint32(len(field)))
+		d.Write(length[:])
+		d.Write(field)
+	}
+	copy(h0[:], d.Sum())
+	return h0
+}
+
+// initBlocks allocates the memory and fills the first two blocks of the lanes.
+func initBlocks(h0 *[blake2bSize + 8]byte, memory, threads uint32) []block {
+	var buf [blockLength * 8]byte
+
+	B := make([]block, memory)
+	for lane := uint32(0); lane < threads; lane++ {
+		j := lane * (memory / threads)
+		binary.LittleEndian.PutUint32(h0[blake2bSize+4:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2bSize:], i)
+			blake2bLong(buf[:], h0[:])
+			for k := range B[j+i] {
+				B[j+i][k] = binary.LittleEndian.Uint64(buf[8*k:])
+			}
+		}
+	}
+	return B
+}
+
+// processBlocks makes the passes over the memory, computing the segments of a
+// slice of all lanes in parallel.
+func processBlocks(B []block, time, memory, threads uint32) {
+	lanes := memory / threads
+	segments := lanes / syncPoints
+
+	processSegment := func(n, slice, lane uint32, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		// The first half of the first pass references blocks independently of
+		// the password, the rest depending on the previous block
+		var addresses, in, zero block
+		independent := n == 0 && slice < syncPoints/2
+		if independent {
+			in[0], in[1], in[2] = uint64(n), uint64(lane), uint64(slice)
+			in[3], in[4], in[5] = uint64(memory), uint64(time), modeID
+		}
+		index := uint32(0)
+		if n == 0 && slice == 0 {
+			index = 2 // The first two blocks are already filled
+			if independent {
+				in[6]++
+				processBlock(&addresses, &in, &zero)
+				processBlock(&addresses, &addresses, &zero)
+			}
+		}
+		offset := lane*lanes + slice*segments + index
+		for index < segments {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += lanes // Last block of the lane
+			}
+			var random uint64
+			if independent {
+				if index%blockLength == 0 {
+					in[6]++
+					processBlock(&addresses, &in, &zero)
+					processBlock(&addresses, &addresses, &zero)
+				}
+				random = addresses[index%blockLength]
+			} else {
+				random = B[prev][0]
+			}
+			ref := indexAlpha(random, lanes, segments, threads, n, slice, lane, index)
+			if n == 0 {
+				processBlock(&B[offset], &B[prev], &B[ref])
+			} else {
+				processBlockXOR(&B[offset], &B[prev], &B[ref])
+			}
+			index, offset = index+1, offset+1
+		}
+	}
+	for n := uint32(0); n < time; n++ {
+		for slice := uint32(0); slice < syncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < threads; lane++ {
+				wg.Add(1)
+				go processSegment(n, slice, lane, &wg)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// extractKey hashes the xor of the last blocks of the lanes into the key.
+func extractKey(B []block, memory, threads, keyLen uint32) []byte {
+	lanes := memory / threads
+	for lane := uint32(0); lane < threads-1; lane++ {
+		for i, v := range B[lane*lanes+lanes-1] {
+			B[memory-1][i] ^= v
+		}
+	}
+	var buf [blockLength * 8]byte
+	for i, v := range B[memory-1] {
+		binary.LittleEndian.PutUint64(buf[8*i:], v)
+	}
+	key := make([]byte, keyLen)
+	blake2bLong(key, buf[:])
+	return key
+}
+
+// indexAlpha maps the pseudo-random value of a block to the index of the block
+// it references, among the ones already computed and not in the same slice of
+// another lane.
+func indexAlpha(random uint64, lanes, segments, threads, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(random>>32) % threads
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+	// Size of the reference area and its start within the lane
+	m, s := 3*segments, ((slice+1)%syncPoints)*segments
+	if lane == refLane {
+		m += index
+	}
+	if n == 0 {
+		m, s = slice*segments, 0
+		if slice == 0 || lane == refLane {
+			m += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		m--
+	}
+	// Favor the recent blocks, with a quadratic distribution
+	p := random & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * uint64(m)) >> 32
+	return refLane*lanes + uint32((uint64(s)+uint64(m)-(p+1))%uint64(lanes))
+}
+
+// processBlock sets out to the compression of in1 and in2.
+func processBlock(out, in1, in2 *block) {
+	compress(out, in1, in2, false)
+}
+
+// processBlockXOR xors the compression of in1 and in2 into out, for the passes
+// after the first one.
+func processBlockXOR(out, in1, in2 *block) {
+	compress(out, in1, in2, true)
+}
+
+func compress(out, in1, in2 *block, xor bool) {
+	var t block
+	for i := range t {
+		t[i] = in1[i] ^ in2[i]
+	}
+	// Permute the rows, then the columns of 8x8 registers of 16 bytes
+	for i := 0; i < blockLength; i += 16 {
+		blamka(&t[i], &t[i+1], &t[i+2], &t[i+3], &t[i+4], &t[i+5], &t[i+6], &t[i+7],
+			&t[i+8], &t[i+9], &t[i+10], &t[i+11], &t[i+12], &t[i+13], &t[i+14], &t[i+15])
+	}
+	for i := 0; i < blockLength/8; i += 2 {
+		blamka(&t[i], &t[i+1], &t[i+16], &t[i+17], &t[i+32], &t[i+33], &t[i+48], &t[i+49],
+			&t[i+64], &t[i+65], &t[i+80], &t[i+81], &t[i+96], &t[i+97], &t[i+112], &t[i+113])
+	}
+	if xor {
+		for i := range t {
+			out[i] ^= in1[i] ^ in2[i] ^ t[i]
+		}
+	} else {
+		for i := range t {
+			out[i] = in1[i] ^ in2[i] ^ t[i]
+		}
+	}
+}
+
+// blamka is the permutation P of Argon2, the round of BLAKE2b with its
+// additions hardened by multiplications.
+func blamka(t00, t01, t02, t03, t04, t05, t06, t07, t08, t09, t10, t11, t12, t13, t14, t15 *uint64) {
+	v00, v01, v02, v03 := *t00, *t01, *t02, *t03
+	v04, v05, v06, v07 := *t04, *t05, *t06, *t07
+	v08, v09, v10, v11 := *t08, *t09, *t10, *t11
+	v12, v13, v14, v15 := *t12, *t13, *t14, *t15
+
+	v00, v04, v08, v12 = blamkaMix(v00, v04, v08, v12)
+	v01, v05, v09, v13 = blamkaMix(v01, v05, v09, v13)
+	v02, v06, v10, v14 = blamkaMix(v02, v06, v10, v14)
+	v03, v07, v11, v15 = blamkaMix(v03, v07, v11, v15)
+
+	v00, v05, v10, v15 = blamkaMix(v00, v05, v10, v15)
+	v01, v06, v11, v12 = blamkaMix(v01, v06, v11, v12)
+	v02, v07, v08, v13 = blamkaMix(v02, v07, v08, v13)
+	v03, v04, v09, v14 = blamkaMix(v03, v04, v09, v14)
+
+	*t00, *t01, *t02, *t03 = v00, v01, v02, v03
+	*t04, *t05, *t06, *t07 = v04, v05, v06, v07
+	*t08, *t09, *t10, *t11 = v08, v09, v10, v11
+	*t12, *t13, *t14, *t15 = v12, v13, v14, v15
+}
+
+func blamkaMix(a, b, c, d uint64) (uint64, uint64, uint64, uint64) {
+	a += b + 2*uint64(uint32(a))*uint64(uint32(b))
+	d = bits.RotateLeft64(d^a, -32)
+	c += d + 2*uint64(uint32(c))*uint64(uint32(d))
+	b = bits.RotateLeft64(b^c, -24)
+	a += b + 2*uint64(uint32(a))*uint64(uint32(b))
+	d = bits.RotateLeft64(d^a, -16)
+	c += d + 2*uint64(uint32(c))*uint64(uint32(d))
+	b = bits.RotateLeft64(b^c, -63)
+	return a, b, c, d
+}
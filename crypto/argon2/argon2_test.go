@@ -0,0 +1,98 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package argon2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBlake2b(t *testing.T) {
+	tests := []struct {
+		input string
+		size  int
+		want  string
+	}{
+		{"", 64, "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+		{"abc", 64, "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+	}
+	for i, tt := range tests {
+		d := newBlake2b(tt.size)
+		d.Write([]byte(tt.input))
+		if have := hex.EncodeToString(d.Sum()); have != tt.want {
+			t.Errorf("test %d: digest mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the digests don't depend on how the input is split into writes,
+// across the block boundaries.
+func TestBlake2bWrites(t *testing.T) {
+	input := make([]byte, 3*blake2bBlockSize+7)
+	for i := range input {
+		input[i] = byte(i)
+	}
+	whole := newBlake2b(32)
+	whole.Write(input)
+	want := whole.Sum()
+
+	for _, size := range []int{1, 7, blake2bBlockSize - 1, blake2bBlockSize, blake2bBlockSize + 1} {
+		d := newBlake2b(32)
+		for p := input; len(p) > 0; {
+			n := size
+			if n > len(p) {
+				n = len(p)
+			}
+			d.Write(p[:n])
+			p = p[n:]
+		}
+		if have := d.Sum(); !bytes.Equal(have, want) {
+			t.Errorf("writes of %d bytes: digest mismatch: have %x, want %x", size, have, want)
+		}
+	}
+}
+
+// Tests the argon2id test vector of RFC 9106, section 5.3.
+func TestDeriveKeyVector(t *testing.T) {
+	var (
+		password = bytes.Repeat([]byte{0x01}, 32)
+		salt     = bytes.Repeat([]byte{0x02}, 16)
+		secret   = bytes.Repeat([]byte{0x03}, 8)
+		data     = bytes.Repeat([]byte{0x04}, 12)
+	)
+	want := "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"
+	if have := hex.EncodeToString(deriveKey(password, salt, secret, data, 3, 32, 4, 32)); have != want {
+		t.Fatalf("tag mismatch: have %s, want %s", have, want)
+	}
+}
+
+func TestIDKey(t *testing.T) {
+	key := IDKey([]byte("password"), []byte("somesalt"), 2, 64, 2, 40)
+	if len(key) != 40 {
+		t.Fatalf("key length mismatch: have %d, want 40", len(key))
+	}
+	if again := IDKey([]byte("password"), []byte("somesalt"), 2, 64, 2, 40); !bytes.Equal(key, again) {
+		t.Fatalf("derivation not deterministic: %x != %x", key, again)
+	}
+	if other := IDKey([]byte("passwore"), []byte("somesalt"), 2, 64, 2, 40); bytes.Equal(key, other) {
+		t.Fatal("different passwords derived the same key")
+	}
+	if other := IDKey([]byte("password"), []byte("somesalt"), 3, 64, 2, 40); bytes.Equal(key, other) {
+		t.Fatal("different passes derived the same key")
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package argon2
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	blake2bBlockSize = 128 // Size in bytes of the blocks compressed
+	blake2bSize      = 64  // Maximum size in bytes of the digests
+)
+
+var blake2bIV = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var blake2bSigma = [10][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+// blake2b is the unkeyed BLAKE2b hash of RFC 7693, with digests of 1 to 64
+// bytes.
+type blake2b struct {
+	h    [8]uint64
+	t    [2]uint64 // Number of bytes hashed
+	buf  [blake2bBlockSize]byte
+	n    int // Number of bytes buffered
+	size int
+}
+
+func newBlake2b(size int) *blake2b {
+	d := &blake2b{h: blake2bIV, size: size}
+	d.h[0] ^= 0x01010000 ^ uint64(size)
+	return d
+}
+
+func (d *blake2b) Write(p []byte) {
+	for len(p) > 0 {
+		// The last block is compressed differently, so a full buffer is only
+		// compressed once more data follows
+		if d.n == blake2bBlockSize {
+			d.count(blake2bBlockSize)
+			d.compress(false)
+			d.n = 0
+		}
+		c := copy(d.buf[d.n:], p)
+		d.n += c
+		p = p[c:]
+	}
+}
+
+// Sum finalizes the hash and returns its digest.
+func (d *blake2b) Sum() []byte {
+	d.count(d.n)
+	for i := d.n; i < blake2bBlockSize; i++ {
+		d.buf[i] = 0
+	}
+	d.compress(true)
+
+	var digest [blake2bSize]byte
+	for i, h := range d.h {
+		binary.LittleEndian.PutUint64(digest[8*i:], h)
+	}
+	return digest[:d.size]
+}
+
+func (d *blake2b) count(n int) {
+	if d.t[0] += uint64(n); d.t[0] < uint64(n) {
+		d.t[1]++
+	}
+}
+
+func (d *blake2b) compress(last bool) {
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(d.buf[8*i:])
+	}
+	var v [16]uint64
+	copy(v[:8], d.h[:])
+	copy(v[8:], blake2bIV[:])
+	v[12] ^= d.t[0]
+	v[13] ^= d.t[1]
+	if last {
+		v[14] = ^v[14]
+	}
+	for round := 0; round < 12; round++ {
+		s := &blake2bSigma[round%10]
+		blake2bMix(&v, 0, 4, 8, 12, m[s[0]], m[s[1]])
+		blake2bMix(&v, 1, 5, 9, 13, m[s[2]], m[s[3]])
+		blake2bMix(&v, 2, 6, 10, 14, m[s[4]], m[s[5]])
+		blake2bMix(&v, 3, 7, 11, 15, m[s[6]], m[s[7]])
+		blake2bMix(&v, 0, 5, 10, 15, m[s[8]], m[s[9]])
+		blake2bMix(&v, 1, 6, 11, 12, m[s[10]], m[s[11]])
+		blake2bMix(&v, 2, 7, 8, 13, m[s[12]], m[s[13]])
+		blake2bMix(&v, 3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+	for i := range d.h {
+		d.h[i] ^= v[i] ^ v[i+8]
+	}
+}
+
+func blake2bMix(v *[16]uint64, a, b, c, d int, x, y uint64) {
+	v[a] += v[b] + x
+	v[d] = bits.RotateLeft64(v[d]^v[a], -32)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -24)
+	v[a] += v[b] + y
+	v[d] = bits.RotateLeft64(v[d]^v[a], -16)
+	v[c] += v[d]
+	v[b] = bits.RotateLeft64(v[b]^v[c], -63)
+}
+
+// blake2bLong is the variable length hash H' of Argon2, filling out with the
+// digest of the input.
+func blake2bLong(out []byte, in ...[]byte) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(out)))
+
+	size := len(out)
+	if size > blake2bSize {
+		size = blake2bSize
+	}
+	d := newBlake2b(size)
+	d.Write(length[:])
+	for _, p := range in {
+		d.Write(p)
+	}
+	v := d.Sum()
+
+	// Longer digests chain hashes, keeping the first half of all but the last
+	for len(out) > blake2bSize {
+		copy(out, v[:blake2bSize/2])
+		out = out[blake2bSize/2:]
+
+		if size = len(out); size > blake2bSize {
+			size = blake2bSize
+		}
+		d = newBlake2b(size)
+		d.Write(v)
+		v = d.Sum()
+	}
+	copy(out, v)
+}
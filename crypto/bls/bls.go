@@ -0,0 +1,242 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bls implements BLS signatures over the BN256 curve, whose pairing is
+// available to the contracts of TomoChain and Ethereum as a precompile.
+//
+// Signatures are points of G1 and public keys points of G2, so signatures of
+// the same message aggregate into a single 64 byte signature verified against
+// the sum of the public keys of the signers with one pairing check. The public
+// keys are registered along with a proof of possession of their secret key,
+// preventing rogue key attacks on the aggregates.
+package bls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	bn256 "github.com/ethereum/go-ethereum/crypto/bn256/cloudflare"
+)
+
+const (
+	PublicKeyLength = 128 // Length of a marshalled public key, a point of G2
+	SignatureLength = 64  // Length of a marshalled signature, a point of G1
+)
+
+// Domains separating the hashes of the messages signed from the ones of the
+// proofs of possession.
+var (
+	signDomain       = []byte("TOMO_BLS_SIGN")
+	possessionDomain = []byte("TOMO_BLS_POP")
+)
+
+var (
+	errInvalidSecretKey = errors.New("bls: invalid secret key")
+	errInvalidPublicKey = errors.New("bls: invalid public key")
+	errInvalidSignature = errors.New("bls: invalid signature")
+
+	curveB = big.NewInt(3) // Coefficient of the G1 curve y² = x³ + b
+
+	g2Generator = generator() // Generator of G2, in affine coordinates
+)
+
+// SecretKey is a BLS secret key, a scalar of the curve order.
+type SecretKey struct {
+	k *big.Int
+}
+
+// PublicKey is a BLS public key, a point of G2.
+type PublicKey struct {
+	p *bn256.G2
+}
+
+// Signature is a BLS signature or an aggregate of signatures, a point of G1.
+type Signature struct {
+	p *bn256.G1
+}
+
+func generator() *bn256.G2 {
+	g := new(bn256.G2)
+	if _, err := g.Unmarshal(new(bn256.G2).ScalarBaseMult(big.NewInt(1)).Marshal()); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// GenerateKey creates a secret key from a source of randomness.
+func GenerateKey(r io.Reader) (*SecretKey, error) {
+	k, _, err := bn256.RandomG2(r)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretKey{k}, nil
+}
+
+// SecretKeyFromSeed derives a secret key from a seed of at least 32 bytes,
+// deterministically.
+func SecretKeyFromSeed(seed []byte) (*SecretKey, error) {
+	if len(seed) < 32 {
+		return nil, errInvalidSecretKey
+	}
+	k := new(big.Int).SetBytes(crypto.Keccak256(seed))
+	k.Mod(k, bn256.Order)
+	if k.Sign() == 0 {
+		return nil, errInvalidSecretKey
+	}
+	return &SecretKey{k}, nil
+}
+
+// PublicKey returns the public key of the secret key.
+func (sk *SecretKey) PublicKey() *PublicKey {
+	return &PublicKey{new(bn256.G2).ScalarBaseMult(sk.k)}
+}
+
+// Sign signs a message.
+func (sk *SecretKey) Sign(msg []byte) *Signature {
+	return &Signature{new(bn256.G1).ScalarMult(hashToG1(signDomain, msg), sk.k)}
+}
+
+// ProvePossession signs the public key of the secret key, proving the signer
+// holds it.
+func (sk *SecretKey) ProvePossession() *Signature {
+	pub := sk.PublicKey().Marshal()
+	return &Signature{new(bn256.G1).ScalarMult(hashToG1(possessionDomain, pub), sk.k)}
+}
+
+// UnmarshalPublicKey decodes a public key, rejecting the points out of the
+// curve, out of the prime order subgroup or at infinity.
+func UnmarshalPublicKey(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeyLength {
+		return nil, errInvalidPublicKey
+	}
+	p := new(bn256.G2)
+	if _, err := p.Unmarshal(b); err != nil {
+		return nil, errInvalidPublicKey
+	}
+	zero := make([]byte, PublicKeyLength)
+	if bytes.Equal(b, zero) || !bytes.Equal(new(bn256.G2).ScalarMult(p, bn256.Order).Marshal(), zero) {
+		return nil, errInvalidPublicKey
+	}
+	return &PublicKey{p}, nil
+}
+
+// Marshal encodes the public key in 128 bytes.
+func (pk *PublicKey) Marshal() []byte {
+	return pk.p.Marshal()
+}
+
+// UnmarshalSignature decodes a signature, the point at infinity being the
+// aggregate of no signatures.
+func UnmarshalSignature(b []byte) (*Signature, error) {
+	if len(b) != SignatureLength {
+		return nil, errInvalidSignature
+	}
+	p := new(bn256.G1)
+	if _, err := p.Unmarshal(b); err != nil {
+		return nil, errInvalidSignature
+	}
+	return &Signature{p}, nil
+}
+
+// Marshal encodes the signature in 64 bytes.
+func (s *Signature) Marshal() []byte {
+	return s.p.Marshal()
+}
+
+// Verify checks the signature of a message by the owner of a public key.
+func (s *Signature) Verify(pk *PublicKey, msg []byte) bool {
+	return verify(s, pk, hashToG1(signDomain, msg))
+}
+
+// VerifyPossession checks the proof of possession of the secret key of a
+// public key.
+func (s *Signature) VerifyPossession(pk *PublicKey) bool {
+	return verify(s, pk, hashToG1(possessionDomain, pk.Marshal()))
+}
+
+// verify checks e(s, g2) == e(h, pk), as e(-s, g2) * e(h, pk) == 1. The Miller
+// loop expects affine points, the ones out of the group operations are made
+// affine by a round trip through their encoding, negating s on the way.
+func verify(s *Signature, pk *PublicKey, h *bn256.G1) bool {
+	enc := s.p.Marshal()
+	if y := new(big.Int).SetBytes(enc[32:]); y.Sign() != 0 {
+		copy(enc[32:], math.PaddedBigBytes(y.Sub(bn256.P, y), 32))
+	}
+	var (
+		sp, hp = new(bn256.G1), new(bn256.G1)
+		pkp    = new(bn256.G2)
+	)
+	if _, err := sp.Unmarshal(enc); err != nil {
+		return false
+	}
+	if _, err := hp.Unmarshal(h.Marshal()); err != nil {
+		return false
+	}
+	if _, err := pkp.Unmarshal(pk.p.Marshal()); err != nil {
+		return false
+	}
+	return bn256.PairingCheck([]*bn256.G1{sp, hp}, []*bn256.G2{g2Generator, pkp})
+}
+
+// AggregateSignatures sums signatures of the same message into one, verified
+// against the aggregate of the public keys of the signers.
+func AggregateSignatures(sigs []*Signature) *Signature {
+	agg := new(bn256.G1).ScalarBaseMult(new(big.Int))
+	for _, sig := range sigs {
+		agg.Add(agg, sig.p)
+	}
+	return &Signature{agg}
+}
+
+// AggregatePublicKeys sums public keys into the one verifying the aggregate of
+// their signatures. The keys must come with proofs of possession.
+func AggregatePublicKeys(pks []*PublicKey) *PublicKey {
+	agg := new(bn256.G2).ScalarBaseMult(new(big.Int))
+	for _, pk := range pks {
+		agg.Add(agg, pk.p)
+	}
+	return &PublicKey{agg}
+}
+
+// hashToG1 maps a message to a point of G1 by try-and-increment: the first
+// hash of the domain, message and counter which is the x coordinate of a curve
+// point gives the point, with the even y. G1 has a cofactor of one, any point
+// of the curve is in the group.
+func hashToG1(domain, msg []byte) *bn256.G1 {
+	x, y, rhs := new(big.Int), new(big.Int), new(big.Int)
+	for counter := byte(0); ; counter++ {
+		x.SetBytes(crypto.Keccak256(domain, msg, []byte{counter}))
+		x.Mod(x, bn256.P)
+
+		// rhs = x³ + b
+		rhs.Exp(x, big.NewInt(3), bn256.P)
+		rhs.Add(rhs, curveB)
+		rhs.Mod(rhs, bn256.P)
+		if y.ModSqrt(rhs, bn256.P) == nil {
+			continue
+		}
+		if y.Bit(0) == 1 {
+			y.Sub(bn256.P, y)
+		}
+		p := new(bn256.G1)
+		if _, err := p.Unmarshal(append(math.PaddedBigBytes(x, 32), math.PaddedBigBytes(y, 32)...)); err == nil {
+			return p
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pk, err := UnmarshalPublicKey(sk.PublicKey().Marshal())
+	if err != nil {
+		t.Fatalf("failed to decode public key: %v", err)
+	}
+	msg := []byte("checkpoint")
+	sig, err := UnmarshalSignature(sk.Sign(msg).Marshal())
+	if err != nil {
+		t.Fatalf("failed to decode signature: %v", err)
+	}
+	if !sig.Verify(pk, msg) {
+		t.Fatalf("valid signature rejected")
+	}
+	if sig.Verify(pk, []byte("other")) {
+		t.Errorf("signature of another message accepted")
+	}
+	other, _ := GenerateKey(rand.Reader)
+	if sig.Verify(other.PublicKey(), msg) {
+		t.Errorf("signature accepted for another key")
+	}
+	// Proofs of possession don't verify as signatures of the public key
+	pop := sk.ProvePossession()
+	if !pop.VerifyPossession(pk) {
+		t.Errorf("valid proof of possession rejected")
+	}
+	if pop.Verify(pk, pk.Marshal()) || sk.Sign(pk.Marshal()).VerifyPossession(pk) {
+		t.Errorf("signature domains not separated")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	msg := []byte("checkpoint")
+
+	var (
+		pks  []*PublicKey
+		sigs []*Signature
+	)
+	for i := 0; i < 5; i++ {
+		sk, _ := GenerateKey(rand.Reader)
+		pks = append(pks, sk.PublicKey())
+		sigs = append(sigs, sk.Sign(msg))
+	}
+	agg, err := UnmarshalSignature(AggregateSignatures(sigs).Marshal())
+	if err != nil {
+		t.Fatalf("failed to decode aggregate: %v", err)
+	}
+	if !agg.Verify(AggregatePublicKeys(pks), msg) {
+		t.Fatalf("valid aggregate rejected")
+	}
+	if agg.Verify(AggregatePublicKeys(pks[1:]), msg) {
+		t.Errorf("aggregate accepted for a subset of the signers")
+	}
+	// The aggregate of no signatures is the point at infinity
+	if empty := AggregateSignatures(nil).Marshal(); !bytes.Equal(empty, make([]byte, SignatureLength)) {
+		t.Errorf("empty aggregate mismatch: %x", empty)
+	}
+}
+
+func TestSecretKeyFromSeed(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x01}, 65)
+
+	a, err := SecretKeyFromSeed(seed)
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	b, _ := SecretKeyFromSeed(seed)
+	if !bytes.Equal(a.PublicKey().Marshal(), b.PublicKey().Marshal()) {
+		t.Errorf("derivation not deterministic")
+	}
+	if _, err := SecretKeyFromSeed(seed[:31]); err != errInvalidSecretKey {
+		t.Errorf("short seed error mismatch: have %v, want %v", err, errInvalidSecretKey)
+	}
+}
+
+func TestUnmarshalPublicKey(t *testing.T) {
+	if _, err := UnmarshalPublicKey(make([]byte, PublicKeyLength)); err != errInvalidPublicKey {
+		t.Errorf("infinity error mismatch: have %v, want %v", err, errInvalidPublicKey)
+	}
+	if _, err := UnmarshalPublicKey(bytes.Repeat([]byte{0x01}, PublicKeyLength)); err != errInvalidPublicKey {
+		t.Errorf("off curve error mismatch: have %v, want %v", err, errInvalidPublicKey)
+	}
+	if _, err := UnmarshalPublicKey(make([]byte, 64)); err != errInvalidPublicKey {
+		t.Errorf("short key error mismatch: have %v, want %v", err, errInvalidPublicKey)
+	}
+}
@@ -0,0 +1,60 @@
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	maxTxAnnounces   = 4096            // Maximum transaction hashes handled from a single announcement
+	maxTxFetch       = 256             // Maximum transactions requested or served in a single message
+	maxTxRequests    = 16384           // Pending transaction requests above which expired ones are dropped
+	txRequestTimeout = 5 * time.Second // Time allowance for an announcing peer to deliver a transaction
+)
+
+// txRequests tracks the transactions, regular or order ones, requested from
+// the peers that announced them, so each one is only retrieved from a single
+// peer at a time. Transactions not delivered in time may be requested again
+// from the next peer announcing them.
+type txRequests struct {
+	pending map[common.Hash]time.Time // Requested transactions and the time of their request
+	lock    sync.Mutex
+}
+
+func newTxRequests() *txRequests {
+	return &txRequests{pending: make(map[common.Hash]time.Time)}
+}
+
+// schedule marks the announced transactions not already being retrieved as
+// requested, and returns them.
+func (r *txRequests) schedule(hashes []common.Hash, now time.Time) []common.Hash {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.pending) >= maxTxRequests {
+		for hash, requested := range r.pending {
+			if now.Sub(requested) >= txRequestTimeout {
+				delete(r.pending, hash)
+			}
+		}
+	}
+	scheduled := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		if requested, ok := r.pending[hash]; ok && now.Sub(requested) < txRequestTimeout {
+			continue
+		}
+		r.pending[hash] = now
+		scheduled = append(scheduled, hash)
+	}
+	return scheduled
+}
+
+// delivered removes a received transaction from the pending requests.
+func (r *txRequests) delivered(hash common.Hash) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.pending, hash)
+}
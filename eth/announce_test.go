@@ -0,0 +1,30 @@
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTxRequests(t *testing.T) {
+	var (
+		now      = time.Now()
+		requests = newTxRequests()
+		hashes   = []common.Hash{{0x01}, {0x02}, {0x03}}
+	)
+	if scheduled := requests.schedule(hashes[:2], now); len(scheduled) != 2 {
+		t.Fatalf("announced transactions not scheduled: %v", scheduled)
+	}
+	// Transactions being retrieved are not requested from other peers
+	scheduled := requests.schedule(hashes, now.Add(time.Second))
+	if len(scheduled) != 1 || scheduled[0] != hashes[2] {
+		t.Fatalf("scheduled transactions mismatch: have %v, want %v", scheduled, hashes[2:])
+	}
+	// Delivered or timed out ones are requested again when announced
+	requests.delivered(hashes[0])
+	scheduled = requests.schedule(hashes, now.Add(txRequestTimeout))
+	if len(scheduled) != 2 || scheduled[0] != hashes[0] || scheduled[1] != hashes[1] {
+		t.Fatalf("rescheduled transactions mismatch: have %v, want %v", scheduled, hashes[:2])
+	}
+}
@@ -19,17 +19,22 @@ package eth
 import (
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/contracts"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
@@ -187,6 +192,22 @@ func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	return true
 }
 
+// SetOrderBudget sets the share of the block construction given to matching
+// the pending orders.
+func (api *PrivateMinerAPI) SetOrderBudget(budget miner.OrderBudget) (bool, error) {
+	if err := api.e.Miner().SetOrderBudget(budget); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetForkGuard enables or disables holding back sealing while the chain looks
+// like a minority fork, disabling it resumes sealing right away.
+func (api *PrivateMinerAPI) SetForkGuard(enabled bool) bool {
+	api.e.Miner().SetForkGuard(enabled)
+	return true
+}
+
 // SetEtherbase sets the etherbase of the miner
 func (api *PrivateMinerAPI) SetEtherbase(etherbase common.Address) bool {
 	api.e.SetEtherbase(etherbase)
@@ -198,6 +219,119 @@ func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return uint64(api.e.miner.HashRate())
 }
 
+// MinerStatus is the sealing health report of the masternode run by this node.
+type MinerStatus struct {
+	Masternode      common.Address  `json:"masternode"`
+	Signer          string          `json:"signer,omitempty"` // Wallet sealing blocks when failover wallets are configured
+	Mining          bool            `json:"mining"`
+	IsMasternode    bool            `json:"isMasternode"`
+	Masternodes     int             `json:"masternodes"`
+	CurrentBlock    hexutil.Uint64  `json:"currentBlock"`
+	NextSlot        *hexutil.Uint64 `json:"nextSlot"`        // Block this node is expected to seal next
+	LastSealedBlock *hexutil.Uint64 `json:"lastSealedBlock"` // Latest block sealed by this node within the last epoch
+	SinceLastSealed *hexutil.Uint64 `json:"sinceLastSealed"` // Seconds elapsed since the last sealed block
+	Peers           int             `json:"peers"`
+	MinorityFork    bool            `json:"minorityFork"`
+	ForkGuard       bool            `json:"forkGuard"`
+	SealingPaused   bool            `json:"sealingPaused"`
+}
+
+// PrivateMasternodeAPI provides private RPC methods to monitor the masternode
+// run by this node.
+type PrivateMasternodeAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateMasternodeAPI creates a new RPC service which monitors the masternode
+// run by this node.
+func NewPrivateMasternodeAPI(e *Ethereum) *PrivateMasternodeAPI {
+	return &PrivateMasternodeAPI{e: e}
+}
+
+// MinerStatus reports whether the etherbase is in the current masternode set,
+// when it is expected to seal next, how long ago it last sealed a block and
+// whether the node looks cut off from the rest of the masternodes.
+func (api *PrivateMasternodeAPI) MinerStatus() (*MinerStatus, error) {
+	engine, ok := api.e.engine.(*posv.Posv)
+	if !ok {
+		return nil, core.ErrNotPoSV
+	}
+	etherbase, err := api.e.Etherbase()
+	if err != nil {
+		return nil, err
+	}
+	chain := api.e.blockchain
+	head := chain.CurrentHeader()
+	masternodes := engine.GetMasternodes(chain, head)
+	status := &MinerStatus{
+		Masternode:    etherbase,
+		Signer:        api.e.ActiveSigner(),
+		Mining:        api.e.IsStaking(),
+		Masternodes:   len(masternodes),
+		CurrentBlock:  hexutil.Uint64(head.Number.Uint64()),
+		Peers:         api.e.GetPeer(),
+		ForkGuard:     api.e.miner.ForkGuard(),
+		SealingPaused: api.e.miner.SealingPaused(),
+	}
+	for _, masternode := range masternodes {
+		if masternode == etherbase {
+			status.IsMasternode = true
+			break
+		}
+	}
+	if status.IsMasternode {
+		_, preIndex, curIndex, _, err := engine.YourTurn(chain, head, etherbase)
+		if err != nil {
+			return nil, err
+		}
+		next := hexutil.Uint64(head.Number.Uint64() + 1 + uint64(posv.Hop(len(masternodes), preIndex, curIndex)))
+		status.NextSlot = &next
+	}
+	// Look for the latest block of this node, within an epoch at most
+	for header, i := head, uint64(0); header != nil && header.Number.Sign() > 0 && i < chain.Config().Posv.Epoch; i++ {
+		if creator, err := engine.Author(header); err == nil && creator == etherbase {
+			number := hexutil.Uint64(header.Number.Uint64())
+			status.LastSealedBlock = &number
+			if elapsed := time.Now().Unix() - header.Time.Int64(); elapsed >= 0 {
+				since := hexutil.Uint64(elapsed)
+				status.SinceLastSealed = &since
+			}
+			break
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if status.MinorityFork, err = engine.IsMinorityFork(chain, head); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// RandomizeStatus reports whether the etherbase committed its secret and
+// revealed its opening to the randomize contract in the current epoch, and how
+// many times they were sent again.
+func (api *PrivateMasternodeAPI) RandomizeStatus() (*contracts.RandomizeStatus, error) {
+	if _, ok := api.e.engine.(*posv.Posv); !ok {
+		return nil, core.ErrNotPoSV
+	}
+	etherbase, err := api.e.Etherbase()
+	if err != nil {
+		return nil, err
+	}
+	head := api.e.blockchain.CurrentBlock()
+	statedb, err := api.e.blockchain.StateAt(head.Root())
+	if err != nil {
+		return nil, err
+	}
+	return contracts.GetRandomizeStatus(api.e.chainConfig, api.e.chainDb, statedb, head.NumberU64(), etherbase), nil
+}
+
+// CandidateStatus returns the status of the etherbase as a masternode candidate
+// in the validator contract, the stakes refunded to its owner with their unlock
+// times and the actions expected from the owner.
+func (api *PrivateMasternodeAPI) CandidateStatus() (*contracts.CandidateStatus, error) {
+	return api.e.candidateStatus(api.e.blockchain.CurrentHeader())
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -291,6 +425,81 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// PeerScores retrieves the reputation of the connected peers regarding the
+// order transactions they send, keyed by node id.
+func (api *PrivateAdminAPI) PeerScores() map[string]OrderScore {
+	return api.eth.protocolManager.peers.OrderScores()
+}
+
+// Watchdog retrieves the state of the stuck chain watchdog.
+func (api *PrivateAdminAPI) Watchdog() (WatchdogStatus, error) {
+	if api.eth.watchdog == nil {
+		return WatchdogStatus{}, errors.New("chain watchdog not enabled")
+	}
+	return api.eth.watchdog.Status(), nil
+}
+
+// TxPoolLimitsArgs are the limits of the transaction pool to update, the ones
+// left out keeping their current value.
+type TxPoolLimitsArgs struct {
+	PriceLimit   *uint64 `json:"priceLimit"`
+	AccountSlots *uint64 `json:"accountSlots"`
+	GlobalSlots  *uint64 `json:"globalSlots"`
+	AccountQueue *uint64 `json:"accountQueue"`
+	GlobalQueue  *uint64 `json:"globalQueue"`
+}
+
+// TxPoolLimits retrieves the current limits of the transaction pool.
+func (api *PrivateAdminAPI) TxPoolLimits() core.TxPoolLimits {
+	return api.eth.txPool.Limits()
+}
+
+// SetTxPoolLimits updates the limits of the transaction pool at runtime and
+// drops the transactions exceeding them.
+func (api *PrivateAdminAPI) SetTxPoolLimits(args TxPoolLimitsArgs) (core.TxPoolLimits, error) {
+	limits := api.eth.txPool.Limits()
+	if args.PriceLimit != nil {
+		limits.PriceLimit = *args.PriceLimit
+	}
+	if args.AccountSlots != nil {
+		limits.AccountSlots = *args.AccountSlots
+	}
+	if args.GlobalSlots != nil {
+		limits.GlobalSlots = *args.GlobalSlots
+	}
+	if args.AccountQueue != nil {
+		limits.AccountQueue = *args.AccountQueue
+	}
+	if args.GlobalQueue != nil {
+		limits.GlobalQueue = *args.GlobalQueue
+	}
+	if err := api.eth.txPool.SetLimits(limits); err != nil {
+		return core.TxPoolLimits{}, err
+	}
+	return api.eth.txPool.Limits(), nil
+}
+
+// SetTxPoolAccountLimit caps the number of executable transactions of an
+// account in the transaction pool, a limit of 0 removing the cap.
+func (api *PrivateAdminAPI) SetTxPoolAccountLimit(addr common.Address, limit uint64) (bool, error) {
+	limits := api.eth.txPool.Limits()
+	if limit == 0 {
+		delete(limits.AccountLimits, addr)
+	} else {
+		limits.AccountLimits[addr] = limit
+	}
+	if err := api.eth.txPool.SetLimits(limits); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RelayerStats retrieves the usage of the order pool by the relayers, along
+// with their quotas.
+func (api *PrivateAdminAPI) RelayerStats() map[common.Address]core.RelayerStats {
+	return api.eth.orderPool.RelayerStats()
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -347,12 +556,18 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return db.Get(hash.Bytes())
 }
 
-// GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network
-// and returns them as a JSON list of block-hashes
+// GetBadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
+// and returns them as a JSON list with their RLP and the reason they were rejected.
 func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockArgs, error) {
 	return api.eth.BlockChain().BadBlocks()
 }
 
+// DownloaderStatus retrieves the state of the chain downloader: the peers it
+// syncs from along with their in-flight requests, and the ranges still queued.
+func (api *PrivateDebugAPI) DownloaderStatus() *downloader.Status {
+	return api.eth.Downloader().Status()
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -17,16 +17,19 @@
 package eth
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
 
 	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/consensus/posv/reward"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -44,15 +47,26 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
-	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+var (
+	errNoCheckpoint       = &ethapi.NotFoundError{What: "reward checkpoint"}
+	errNoCheckpointReward = &ethapi.NotFoundError{What: "reward of the masternode at the last checkpoint"}
+	errNoFoundationWallet = errors.New("foundation wallet address not configured")
+)
+
+// validatorCacheCheckpoints is the number of checkpoints the validator contract
+// reads of the reward APIs are cached for.
+const validatorCacheCheckpoints = 4
+
 // EthApiBackend implements ethapi.Backend for full nodes
 type EthApiBackend struct {
-	eth *Ethereum
-	gpo *gasprice.Oracle
+	eth        *Ethereum
+	gpo        *gasprice.Oracle
+	validators *contracts.ValidatorCache // Validator contract reads at the recent checkpoints
 }
 
 func (b *EthApiBackend) ChainConfig() *params.ChainConfig {
@@ -202,6 +216,10 @@ func (b *EthApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.eth.TxPool().SubscribeTxPreEvent(ch)
 }
 
+func (b *EthApiBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeTxLifecycleEvent(ch)
+}
+
 func (b *EthApiBackend) Downloader() *downloader.Downloader {
 	return b.eth.Downloader()
 }
@@ -214,6 +232,10 @@ func (b *EthApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *EthApiBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
@@ -237,6 +259,18 @@ func (b *EthApiBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 	}
 }
 
+func (b *EthApiBackend) LogIndexStatus() (uint64, uint64) {
+	if b.eth.logIndexer == nil {
+		return params.BloomBitsBlocks, 0
+	}
+	sections, _, _ := b.eth.logIndexer.Sections()
+	return params.BloomBitsBlocks, sections
+}
+
+func (b *EthApiBackend) LogIndexBlocks(ctx context.Context, section uint64, key []byte) ([]uint64, error) {
+	return core.GetLogIndex(b.eth.chainDb, section, b.eth.logIndexer.SectionHead(section), key)
+}
+
 func (b *EthApiBackend) GetIPCClient() (*ethclient.Client, error) {
 	client, err := b.eth.blockchain.GetClient()
 	if err != nil {
@@ -256,7 +290,7 @@ func (s *EthApiBackend) GetRewardByHash(hash common.Hash) map[string]interface{}
 		data, err := ioutil.ReadFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.Hash().Hex()))
 		if err == nil {
 			rewards := make(map[string]interface{})
-			err = json.Unmarshal(data, &rewards)
+			err = decodeRewards(data, &rewards)
 			if err == nil {
 				return rewards
 			}
@@ -264,7 +298,7 @@ func (s *EthApiBackend) GetRewardByHash(hash common.Hash) map[string]interface{}
 			data, err = ioutil.ReadFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.HashNoValidator().Hex()))
 			if err == nil {
 				rewards := make(map[string]interface{})
-				err = json.Unmarshal(data, &rewards)
+				err = decodeRewards(data, &rewards)
 				if err == nil {
 					return rewards
 				}
@@ -274,97 +308,127 @@ func (s *EthApiBackend) GetRewardByHash(hash common.Hash) map[string]interface{}
 	return make(map[string]interface{})
 }
 
-// GetVotersRewards return a map of voters of snapshot at given block hash
-// there is a function engine.HookReward nearly does the same thing but
-// it does change the stateDB too - so can't use it here
-// Steps:
-// 1. Checking back to state of last checkpoint
-// 2. Get list signers + reward at that checkpoint
-// 3. Find out the list signers_reward for input masternode's reward
-// 4. Calculate voters's rewards for input masternode
-func (b *EthApiBackend) GetVotersRewards(masternodeAddr common.Address) map[common.Address]*big.Int {
-	chain := b.eth.blockchain
-	block := chain.CurrentBlock()
-	number := block.Number().Uint64()
-	engine := b.GetEngine().(*posv.Posv)
-	foundationWalletAddr := chain.Config().Posv.FoudationWalletAddr
-	lastCheckpointNumber := number - (number % b.ChainConfig().Posv.Epoch) - b.ChainConfig().Posv.Epoch // calculate for 2 epochs ago
-	lastCheckpointBlock := chain.GetBlockByNumber(lastCheckpointNumber)
-	rCheckpoint := chain.Config().Posv.RewardCheckpoint
+// decodeRewards decodes stored checkpoint rewards, keeping the amounts exact.
+func decodeRewards(data []byte, rewards *map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(rewards)
+}
 
-	state, err := chain.StateAt(lastCheckpointBlock.Root())
+// GetVotersRewards returns the rewards the holders of a masternode received at
+// the last reward checkpoint, as computed by the consensus.
+func (b *EthApiBackend) GetVotersRewards(ctx context.Context, masternodeAddr common.Address) (map[common.Address]*big.Int, error) {
+	in, parentState, checkpoint, err := b.checkpointRewardInput(ctx)
 	if err != nil {
-		fmt.Println("ERROR Trying to getting state at", lastCheckpointNumber, " Error ", err)
-		return nil
+		return nil, err
 	}
+	in.Validator = b.validators.At(checkpoint, contracts.NewStorageValidatorCaller(parentState))
+	return b.masternodeRewards(ctx, in, masternodeAddr)
+}
 
-	if foundationWalletAddr == (common.Address{}) {
-		log.Error("Foundation Wallet Address is empty", "error", foundationWalletAddr)
-		return nil
+// SimulateVoterReward returns the reward a voter would have received at the
+// last reward checkpoint if it had staked the given amount more on a masternode.
+// The vote is added to a copy of the state the checkpoint rewards are computed
+// from, which then goes through the same reward calculation as the chain.
+func (b *EthApiBackend) SimulateVoterReward(ctx context.Context, masternodeAddr common.Address, voter common.Address, stake *big.Int) (*big.Int, error) {
+	in, parentState, _, err := b.checkpointRewardInput(ctx)
+	if err != nil {
+		return nil, err
 	}
+	statedb := parentState.Copy()
+	stateDatabase.AddVote(statedb, masternodeAddr, voter, stake)
+	in.Validator = contracts.NewStorageValidatorCaller(statedb)
 
-	if lastCheckpointNumber <= 0 || lastCheckpointNumber-rCheckpoint <= 0 || foundationWalletAddr == (common.Address{}) {
-		return nil
+	rewards, err := b.masternodeRewards(ctx, in, masternodeAddr)
+	if err != nil {
+		return nil, err
 	}
+	if amount := rewards[voter]; amount != nil {
+		return amount, nil
+	}
+	return new(big.Int), nil
+}
 
-	// Get signers in blockSigner smartcontract.
-	// Get reward inflation.
-	chainReward := new(big.Int).Mul(new(big.Int).SetUint64(chain.Config().Posv.Reward), new(big.Int).SetUint64(params.Ether))
-	chainReward = rewardInflation(chainReward, lastCheckpointNumber, common.BlocksPerYear)
-
-	totalSigner := new(uint64)
-	signers, err := contracts.GetRewardForCheckpoint(engine, chain, lastCheckpointBlock.Header(), rCheckpoint, totalSigner)
-
+// checkpointRewardInput returns the signs of the reward checkpoint two epochs
+// ago, along with the state before it and its hash. The validator contract to
+// compute the rewards with is left to the caller.
+func (b *EthApiBackend) checkpointRewardInput(ctx context.Context) (*reward.Input, *state.StateDB, common.Hash, error) {
+	chain := b.eth.blockchain
+	config := b.ChainConfig().Posv
+	number := chain.CurrentBlock().NumberU64()
+	if config.FoudationWalletAddr == (common.Address{}) {
+		return nil, nil, common.Hash{}, errNoFoundationWallet
+	}
+	if number < 2*config.Epoch {
+		return nil, nil, common.Hash{}, errNoCheckpoint
+	}
+	lastCheckpointNumber := number - (number % config.Epoch) - config.Epoch // calculate for 2 epochs ago
+	header := chain.GetHeaderByNumber(lastCheckpointNumber)
+	if header == nil {
+		return nil, nil, common.Hash{}, &ethapi.NotFoundError{What: fmt.Sprintf("block %d", lastCheckpointNumber)}
+	}
+	if !reward.IsCheckpoint(chain, header) {
+		return nil, nil, common.Hash{}, errNoCheckpoint
+	}
+	parent := chain.GetHeader(header.ParentHash, lastCheckpointNumber-1)
+	if parent == nil {
+		return nil, nil, common.Hash{}, &ethapi.NotFoundError{What: fmt.Sprintf("block %d", lastCheckpointNumber-1)}
+	}
+	parentState, err := chain.StateAt(parent.Root)
 	if err != nil {
-		log.Crit("Fail to get signers for reward checkpoint", "error", err)
-		return nil
+		return nil, nil, common.Hash{}, &ethapi.StateUnavailableError{Number: lastCheckpointNumber - 1, Err: err}
 	}
-
-	rewardSigners, err := contracts.CalculateRewardForSigner(chainReward, signers, *totalSigner)
+	in, err := reward.NewInput(ctx, b.GetEngine().(*posv.Posv), chain, header, nil)
 	if err != nil {
-		log.Crit("Fail to calculate reward for signers", "error", err)
-		return nil
+		return nil, nil, common.Hash{}, err
 	}
+	return in, parentState, header.Hash(), nil
+}
 
-	if len(signers) <= 0 {
-		return nil
+// masternodeRewards computes the rewards the holders of a masternode receive
+// at a reward checkpoint.
+func (b *EthApiBackend) masternodeRewards(ctx context.Context, in *reward.Input, masternodeAddr common.Address) (map[common.Address]*big.Int, error) {
+	if in.Signers[masternodeAddr] == 0 {
+		return nil, errNoCheckpointReward
 	}
-
-	// Add reward for coin voters of input masternode.
-	var voterResults map[common.Address]*big.Int
-	for signer, calcReward := range rewardSigners {
-		if signer == masternodeAddr {
-			err, rewards := contracts.CalculateRewardForHolders(foundationWalletAddr, state, masternodeAddr, calcReward, number)
-			if err != nil {
-				log.Crit("Fail to calculate reward for holders.", "error", err)
-				return nil
-			}
-			voterResults = rewards
-			break
+	in.Masternodes = []common.Address{masternodeAddr}
+	result, err := reward.Compute(ctx, b.ChainConfig().Posv, in)
+	if err != nil {
+		if err == ctx.Err() {
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to calculate the reward of the voters: %v", err)
 	}
-
-	return voterResults
-
+	return result.Holders(masternodeAddr), nil
 }
 
 // GetVotersCap return all voters's capability at a checkpoint
-func (b *EthApiBackend) GetVotersCap(checkpoint *big.Int, masterAddr common.Address, voters []common.Address) map[common.Address]*big.Int {
-	chain := b.eth.blockchain
-	checkpointBlock := chain.GetBlockByNumber(checkpoint.Uint64())
-	state, err := chain.StateAt(checkpointBlock.Root())
-
+func (b *EthApiBackend) GetVotersCap(ctx context.Context, checkpoint *big.Int, masterAddr common.Address, voters []common.Address) (map[common.Address]*big.Int, error) {
+	state, err := b.checkpointState(checkpoint.Uint64())
 	if err != nil {
-		fmt.Println("ERROR Trying to getting state at", checkpoint, " Error ", err)
-		return nil
+		return nil, err
 	}
-
 	voterCaps := make(map[common.Address]*big.Int)
 	for _, voteAddr := range voters {
-		voterCap := stateDatabase.GetVoterCap(state, masterAddr, voteAddr)
-		voterCaps[voteAddr] = voterCap
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		voterCaps[voteAddr] = stateDatabase.GetVoterCap(state, masterAddr, voteAddr)
+	}
+	return voterCaps, nil
+}
+
+// checkpointState returns the state of a checkpoint block.
+func (b *EthApiBackend) checkpointState(checkpoint uint64) (*state.StateDB, error) {
+	block := b.eth.blockchain.GetBlockByNumber(checkpoint)
+	if block == nil {
+		return nil, &ethapi.NotFoundError{What: fmt.Sprintf("block %d", checkpoint)}
+	}
+	statedb, err := b.eth.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, &ethapi.StateUnavailableError{Number: checkpoint, Err: err}
 	}
-	return voterCaps
+	return statedb, nil
 }
 
 // GetEpochDuration return latest generating velocity epoch by minute
@@ -382,23 +446,21 @@ func (b *EthApiBackend) GetEpochDuration() *big.Int {
 }
 
 // GetMasternodesCap return a cap of all masternode at a checkpoint
-func (b *EthApiBackend) GetMasternodesCap(checkpoint uint64) map[common.Address]*big.Int {
-	checkpointBlock := b.eth.blockchain.GetBlockByNumber(checkpoint)
-	state, err := b.eth.blockchain.StateAt(checkpointBlock.Root())
-
+func (b *EthApiBackend) GetMasternodesCap(ctx context.Context, checkpoint uint64) (map[common.Address]*big.Int, error) {
+	state, err := b.checkpointState(checkpoint)
 	if err != nil {
-		fmt.Println("ERROR Trying to getting state at", checkpoint, " Error ", err)
-		return nil
+		return nil, err
 	}
-
 	candicates := stateDatabase.GetCandidates(state)
 
 	masternodesCap := map[common.Address]*big.Int{}
 	for _, candicate := range candicates {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		masternodesCap[candicate] = stateDatabase.GetCandidateCap(state, candicate)
 	}
-
-	return masternodesCap
+	return masternodesCap, nil
 }
 
 func (b *EthApiBackend) GetBlocksHashCache(blockNr uint64) []common.Hash {
@@ -422,6 +484,12 @@ func (b *EthApiBackend) GetOrderNonce(address common.Hash) (uint64, error) {
 	return 0, errors.New("cannot find tomox service")
 }
 
+// PendingTomoX returns the pending block with the TomoX state projected after
+// the pending order transactions, and their matching results.
+func (b *EthApiBackend) PendingTomoX() (*types.Block, *tomox_state.TomoXStateDB, []tomox.TxDataMatch) {
+	return b.eth.miner.PendingTomoX()
+}
+
 func (b *EthApiBackend) TomoxService() *tomox.TomoX {
 	return b.eth.TomoX
 }
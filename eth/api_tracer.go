@@ -190,7 +190,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 			// Fetch and execute the next block trace tasks
 			for task := range tasks {
 				signer := types.MakeSigner(api.config, task.block.Number())
-				feeCapacity := state.GetTRC21FeeCapacityFromState(task.statedb)
+				feeCapacity := core.TRC21FeeCapacity(api.config, task.block.Number(), task.statedb)
 				// Trace all the transactions contained within
 				for i, tx := range task.block.Transactions() {
 					var balacne *big.Int
@@ -431,7 +431,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 
 			// Fetch and execute the next transaction trace tasks
 			for task := range jobs {
-				feeCapacity := state.GetTRC21FeeCapacityFromState(task.statedb)
+				feeCapacity := core.TRC21FeeCapacity(api.config, block.Number(), task.statedb)
 				var balacne *big.Int
 				if txs[task.index].To() != nil {
 					if value, ok := feeCapacity[*txs[task.index].To()]; ok {
@@ -451,7 +451,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 		}()
 	}
 	// Feed the transactions into the tracers and return
-	feeCapacity := state.GetTRC21FeeCapacityFromState(statedb)
+	feeCapacity := core.TRC21FeeCapacity(api.config, block.Number(), statedb)
 	var failed error
 	for i, tx := range txs {
 		// Send the trace task over for execution
@@ -651,7 +651,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 	}
 	// Recompute transactions up to the target index.
 	signer := types.MakeSigner(api.config, block.Number())
-	feeCapacity := state.GetTRC21FeeCapacityFromState(statedb)
+	feeCapacity := core.TRC21FeeCapacity(api.config, block.Number(), statedb)
 	for idx, tx := range block.Transactions() {
 		var balacne *big.Int
 		if tx.To() != nil {
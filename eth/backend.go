@@ -18,6 +18,7 @@
 package eth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -40,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/consensus/posv/reward"
 	"github.com/ethereum/go-ethereum/contracts"
 	contractValidator "github.com/ethereum/go-ethereum/contracts/validator/contract"
 	"github.com/ethereum/go-ethereum/core"
@@ -52,6 +54,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/eventsink"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
@@ -67,6 +70,7 @@ type LesServer interface {
 	Stop()
 	Protocols() []p2p.Protocol
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
+	APIs() []rpc.API
 }
 
 // Ethereum implements the Ethereum full node service.
@@ -94,12 +98,19 @@ type Ethereum struct {
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	logIndexer    *core.ChainIndexer             // Log indexer operating during block imports, if enabled
 
 	ApiBackend *EthApiBackend
 
-	miner     *miner.Miner
-	gasPrice  *big.Int
-	etherbase common.Address
+	miner        *miner.Miner
+	gasPrice     *big.Int
+	etherbase    common.Address
+	signer       *posv.FailoverSigner // Sealing wallets with failover, if configured
+	signerSub    event.Subscription   // Imported blocks observed by the failover signer
+	remoteSigner *posv.RemoteSigner   // External signer sealing blocks, if configured
+	watchdog     *chainWatchdog       // Stuck chain detection, if configured
+	candidates   *candidateWatcher    // Candidate status alerts of the etherbase, on posv chains
+	events       *eventsink.Publisher // Chain and DEX events published to the sinks, if configured
 
 	networkId     uint64
 	netRPCService *ethapi.PublicNetAPI
@@ -165,7 +176,7 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, Snapshot: config.Snapshot}
 	)
 	if eth.chainConfig.Posv != nil {
 		c := eth.engine.(*posv.Posv)
@@ -183,13 +194,23 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 		eth.blockchain.SetHead(compat.RewindTo)
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	if tomoXServ != nil {
+		tomoXServ.SetHistoryChain(eth.blockchain)
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.LogIndex {
+		eth.logIndexer = NewLogIndexer(chainDb, params.BloomBitsBlocks)
+		eth.logIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
-	eth.orderPool = core.NewOrderPool(eth.chainConfig, eth.blockchain)
+	if config.OrderPool.Journal != "" {
+		config.OrderPool.Journal = ctx.ResolvePath(config.OrderPool.Journal)
+	}
+	eth.orderPool = core.NewOrderPool(config.OrderPool, eth.chainConfig, eth.blockchain)
 	if common.RollbackHash != common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000") {
 		curBlock := eth.blockchain.CurrentBlock()
 		prevBlock := eth.blockchain.GetBlockByHash(common.RollbackHash)
@@ -212,10 +233,23 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 	if eth.protocolManager, err = NewProtocolManagerEx(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.orderPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	// Advertise the subsystems run by the node, and sync from the peers running them
+	eth.protocolManager.features = nodeFeatures(eth.chainConfig, tomoXServ)
+	eth.protocolManager.requiredFeatures = eth.protocolManager.features
+	eth.protocolManager.downloader.SetConfig(downloader.Config{
+		RequestTTL:       config.DownloaderRequestTTL,
+		BlacklistTimeout: config.DownloaderBlacklist,
+		MinThroughput:    config.DownloaderMinThroughput,
+	})
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine, ctx.GetConfig().AnnounceTxs)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
+	eth.miner.SetForkGuard(config.MinorityForkGuard)
+	eth.miner.SetForkGuardTimeout(config.MinorityForkGuardTimeout)
+	if err := eth.miner.SetOrderBudget(config.OrderBudget); err != nil {
+		return nil, err
+	}
 
-	eth.ApiBackend = &EthApiBackend{eth, nil}
+	eth.ApiBackend = &EthApiBackend{eth, nil, contracts.NewValidatorCache(validatorCacheCheckpoints)}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.GasPrice
@@ -237,8 +271,12 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 			if !ok {
 				return nil
 			}
+			// Only a staking masternode signs the blocks it imports
+			if signer, signFn := c.SignerFn(); signFn == nil || signer != eb {
+				return nil
+			}
 			if block.NumberU64()%common.MergeSignRange == 0 || !eth.chainConfig.IsTIP2019(block.Number()) {
-				if err := contracts.CreateTransactionSign(chainConfig, eth.txPool, eth.accountManager, block, chainDb); err != nil {
+				if err := contracts.CreateTransactionSign(chainConfig, eth.txPool, c, eth.blockchain, block, chainDb); err != nil {
 					return fmt.Errorf("Fail to create tx sign for importing block: %v", err)
 				}
 			}
@@ -260,19 +298,14 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 				return block, false, fmt.Errorf("can't get block validator: %v", err)
 			}
 			if m2 == eb {
-				wallet, err := eth.accountManager.Find(accounts.Account{Address: eb})
-				if err != nil {
-					log.Error("Can't find coinbase account wallet", "err", err)
-					return block, false, err
-				}
 				header := block.Header()
-				sighash, err := wallet.SignHash(accounts.Account{Address: eb}, posv.SigHash(header).Bytes())
+				sighash, err := eth.signHash(eb, posv.SigHash(header).Bytes())
 				if err != nil || sighash == nil {
 					log.Error("Can't get signature hash of m2", "sighash", sighash, "err", err)
 					return block, false, err
 				}
 				header.Validator = sighash
-				return types.NewBlockWithHeader(header).WithBody(block.Transactions(), block.Uncles()), true, nil
+				return types.NewBlockWithHeader(header).WithBody(block.Transactions(), block.Uncles()).WithTrades(block.Trades()), true, nil
 			}
 			return block, false, nil
 		}
@@ -298,51 +331,20 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 			if canonicalState == nil || err != nil {
 				log.Crit("Can't get state at head of canonical chain", "head number", eth.blockchain.CurrentHeader().Number.Uint64(), "err", err)
 			}
-			prevEpoc := blockNumberEpoc - chain.Config().Posv.Epoch
-			if prevEpoc >= 0 {
-				start := time.Now()
-				prevHeader := chain.GetHeaderByNumber(prevEpoc)
-				penSigners := c.GetMasternodes(chain, prevHeader)
-				if len(penSigners) > 0 {
-					// Loop for each block to check missing sign.
-					for i := prevEpoc; i < blockNumberEpoc; i++ {
-						if i%common.MergeSignRange == 0 || !chainConfig.IsTIP2019(big.NewInt(int64(i))) {
-							bheader := chain.GetHeaderByNumber(i)
-							bhash := bheader.Hash()
-							block := chain.GetBlock(bhash, i)
-							if len(penSigners) > 0 {
-								signedMasternodes, err := contracts.GetSignersFromContract(canonicalState, block)
-								if err != nil {
-									return nil, err
-								}
-								if len(signedMasternodes) > 0 {
-									// Check signer signed?
-									for _, signed := range signedMasternodes {
-										for j, addr := range penSigners {
-											if signed == addr {
-												// Remove it from dupSigners.
-												penSigners = append(penSigners[:j], penSigners[j+1:]...)
-											}
-										}
-									}
-								}
-							} else {
-								break
-							}
-						}
-					}
-				}
-				log.Debug("Time Calculated HookPenalty ", "block", blockNumberEpoc, "time", common.PrettyDuration(time.Since(start)))
-				return penSigners, nil
+			start := time.Now()
+			penSigners, err := contracts.GetPenaltiesForEpoch(c, chain, canonicalState, blockNumberEpoc)
+			if err != nil {
+				return nil, err
 			}
-			return []common.Address{}, nil
+			log.Debug("Time Calculated HookPenalty ", "block", blockNumberEpoc, "time", common.PrettyDuration(time.Since(start)))
+			return penSigners, nil
 		}
 
 		// Hook scans for bad masternodes and decide to penalty them
 		c.HookPenaltyTIPSigning = func(chain consensus.ChainReader, header *types.Header, candidates []common.Address) ([]common.Address, error) {
 			prevEpoc := header.Number.Uint64() - chain.Config().Posv.Epoch
 			combackEpoch := uint64(0)
-			comebackLength := (common.LimitPenaltyEpoch + 1) * chain.Config().Posv.Epoch
+			comebackLength := uint64(chain.Config().Posv.PenaltyEpochs()+1) * chain.Config().Posv.Epoch
 			if header.Number.Uint64() > comebackLength {
 				combackEpoch = header.Number.Uint64() - comebackLength
 			}
@@ -486,8 +488,8 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 			sort.Slice(candidates, func(i, j int) bool {
 				return candidates[i].Stake.Cmp(candidates[j].Stake) >= 0
 			})
-			if len(candidates) > 150 {
-				candidates = candidates[:150]	
+			if maxMasternodes := chainConfig.Posv.MasternodesLimit(); len(candidates) > maxMasternodes {
+				candidates = candidates[:maxMasternodes]
 			}
 			result := []common.Address{}
 			for _, candidate := range candidates {
@@ -496,6 +498,8 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 			return result, nil
 		}
 
+		c.VerifyRewards = config.VerifyRewards
+
 		// Hook calculates reward for masternodes
 		c.HookReward = func(chain consensus.ChainReader, stateBlock *state.StateDB, header *types.Header) (error, map[string]interface{}) {
 			parentHeader := eth.blockchain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
@@ -503,50 +507,27 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 			if canonicalState == nil || err != nil {
 				log.Crit("Can't get state at head of canonical chain", "head number", header.Number.Uint64(), "err", err)
 			}
-			number := header.Number.Uint64()
-			rCheckpoint := chain.Config().Posv.RewardCheckpoint
 			foundationWalletAddr := chain.Config().Posv.FoudationWalletAddr
 			if foundationWalletAddr == (common.Address{}) {
 				log.Error("Foundation Wallet Address is empty", "error", foundationWalletAddr)
 				return err, nil
 			}
 			rewards := make(map[string]interface{})
-			if number > 0 && number-rCheckpoint > 0 && foundationWalletAddr != (common.Address{}) {
+			if reward.IsCheckpoint(chain, header) {
 				start := time.Now()
-				// Get signers in blockSigner smartcontract.
-				// Get reward inflation.
-				chainReward := new(big.Int).Mul(new(big.Int).SetUint64(chain.Config().Posv.Reward), new(big.Int).SetUint64(params.Ether))
-				chainReward = rewardInflation(chainReward, number, common.BlocksPerYear)
-
-				totalSigner := new(uint64)
-				signers, err := contracts.GetRewardForCheckpoint(c, chain, header, rCheckpoint, totalSigner)
+				// Block processing can't be abandoned halfway
+				ctx := context.Background()
 
-				log.Debug("Time Get Signers", "block", header.Number.Uint64(), "time", common.PrettyDuration(time.Since(start)))
+				in, err := reward.NewInput(ctx, c, chain, header, contracts.NewStorageValidatorCaller(canonicalState))
 				if err != nil {
-					log.Crit("Fail to get signers for reward checkpoint", "error", err)
+					log.Crit("Fail to get checkpoint signers", "error", err)
 				}
-				rewards["signers"] = signers
-				rewardSigners, err := contracts.CalculateRewardForSigner(chainReward, signers, *totalSigner)
+				result, err := reward.Compute(ctx, chain.Config().Posv, in)
 				if err != nil {
-					log.Crit("Fail to calculate reward for signers", "error", err)
+					log.Crit("Fail to calculate checkpoint rewards", "error", err)
 				}
-				// Add reward for coin holders.
-				voterResults := make(map[common.Address]interface{})
-				if len(signers) > 0 {
-					for signer, calcReward := range rewardSigners {
-						err, rewards := contracts.CalculateRewardForHolders(foundationWalletAddr, canonicalState, signer, calcReward, number)
-						if err != nil {
-							log.Crit("Fail to calculate reward for holders.", "error", err)
-						}
-						if len(rewards) > 0 {
-							for holder, reward := range rewards {
-								stateBlock.AddBalance(holder, reward)
-							}
-						}
-						voterResults[signer] = rewards
-					}
-				}
-				rewards["rewards"] = voterResults
+				result.Apply(stateBlock)
+				rewards = result.Rewards()
 				log.Debug("Time Calculated HookReward ", "block", header.Number.Uint64(), "time", common.PrettyDuration(time.Since(start)))
 			}
 			return nil, rewards
@@ -555,7 +536,7 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 		// Hook verifies masternodes set
 		c.HookVerifyMNs = func(header *types.Header, signers []common.Address) error {
 			number := header.Number.Int64()
-			if number > 0 && number%common.EpocBlockRandomize == 0 {
+			if number > 0 && uint64(number)%chainConfig.Posv.Epoch == 0 {
 				start := time.Now()
 				validators, err := GetValidators(eth.blockchain, signers)
 				log.Debug("Time Calculated HookVerifyMNs ", "block", header.Number.Uint64(), "time", common.PrettyDuration(time.Since(start)))
@@ -569,6 +550,9 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 			return nil
 		}
 
+		// Hook opens the state the checkpoint signatures are aggregated from
+		c.HookCheckpointState = eth.blockchain.StateAt
+
 		eth.txPool.IsSigner = func(address common.Address) bool {
 			currentHeader := eth.blockchain.CurrentHeader()
 			header := currentHeader
@@ -591,6 +575,21 @@ func New(ctx *node.ServiceContext, config *Config, tomoXServ *tomox.TomoX) (*Eth
 		}
 
 	}
+	// Publish the chain and DEX events to the configured sinks
+	if len(config.EventSinks) > 0 {
+		var sinks []eventsink.Sink
+		for _, url := range config.EventSinks {
+			sink, err := eventsink.New(url)
+			if err != nil {
+				for _, sink := range sinks {
+					sink.Close()
+				}
+				return nil, fmt.Errorf("invalid event sink %s: %v", url, err)
+			}
+			sinks = append(sinks, sink)
+		}
+		eth.events = eventsink.NewPublisher(eth.blockchain, sinks)
+	}
 	return eth, nil
 }
 
@@ -658,11 +657,19 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *ethash.Config, chai
 // APIs returns the collection of RPC services the ethereum package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *Ethereum) APIs() []rpc.API {
-	apis := ethapi.GetAPIs(s.ApiBackend)
+	apis := ethapi.GetAPIs(s.ApiBackend, ethapi.NewSignerACL(s.config.OrderSigners, s.config.TxSigners))
+
+	filterAPI := filters.NewPublicFilterAPI(s.ApiBackend, false)
+	filterAPI.SetRangeLimit(s.config.LogsRangeLimit)
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the APIs of the light server, if serving
+	if s.lesServer != nil {
+		apis = append(apis, s.lesServer.APIs()...)
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -685,10 +692,15 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateMinerAPI(s),
 			Public:    false,
+		}, {
+			Namespace: "posv",
+			Version:   "1.0",
+			Service:   NewPrivateMasternodeAPI(s),
+			Public:    false,
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, false),
+			Service:   filterAPI,
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -800,12 +812,21 @@ func (s *Ethereum) StartStaking(local bool) error {
 		return fmt.Errorf("etherbase missing: %v", err)
 	}
 	if posv, ok := s.engine.(*posv.Posv); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
-			return fmt.Errorf("signer missing: %v", err)
+		if s.config.RemoteSigner != "" {
+			if err := s.startRemoteSigner(posv, eb); err != nil {
+				log.Error("Cannot connect to the remote signer", "err", err)
+				return fmt.Errorf("remote signer: %v", err)
+			}
+		} else if len(s.config.SignerWallets) > 0 {
+			s.startFailoverSigner(posv, eb)
+		} else {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("signer missing: %v", err)
+			}
+			posv.Authorize(eb, wallet.SignHash)
 		}
-		posv.Authorize(eb, wallet.SignHash)
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -818,8 +839,116 @@ func (s *Ethereum) StartStaking(local bool) error {
 	return nil
 }
 
+// startFailoverSigner authorizes the engine to seal with the configured signer
+// wallets, replacing any failover signer of a previous etherbase.
+func (s *Ethereum) startFailoverSigner(engine *posv.Posv, eb common.Address) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.signer != nil {
+		s.signerSub.Unsubscribe()
+		s.signer.Stop()
+	}
+	s.signer = posv.NewFailoverSigner(s.chainDb, s.accountManager, eb, s.config.SignerWallets, s.blockchain.CurrentHeader().Number.Uint64())
+	s.signer.Start()
+	s.signerSub = s.observeBlocks(s.signer)
+	engine.Authorize(eb, s.signer.SignHash)
+}
+
+// observeBlocks feeds the blocks imported in the chain, side forks included, to
+// the failover signer, which stops sealing if the masternode key seals on
+// another node.
+func (s *Ethereum) observeBlocks(signer *posv.FailoverSigner) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		chainCh := make(chan core.ChainEvent, 16)
+		chainSub := s.blockchain.SubscribeChainEvent(chainCh)
+		defer chainSub.Unsubscribe()
+		sideCh := make(chan core.ChainSideEvent, 16)
+		sideSub := s.blockchain.SubscribeChainSideEvent(sideCh)
+		defer sideSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-chainCh:
+				signer.Observe(ev.Block.Header())
+			case ev := <-sideCh:
+				signer.Observe(ev.Block.Header())
+			case err := <-chainSub.Err():
+				return err
+			case err := <-sideSub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
+
+// startRemoteSigner authorizes the engine to seal through the configured
+// remote signer, replacing the one of a previous etherbase.
+func (s *Ethereum) startRemoteSigner(engine *posv.Posv, eb common.Address) error {
+	signer, err := posv.NewRemoteSigner(s.config.RemoteSigner, eb, s.config.RemoteSignerAudit)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.remoteSigner != nil {
+		s.remoteSigner.Close()
+	}
+	s.remoteSigner = signer
+	engine.Authorize(eb, signer.SignHash)
+	return nil
+}
+
 func (s *Ethereum) StopStaking() {
 	s.miner.Stop()
+
+	s.lock.Lock()
+	if s.signer != nil {
+		s.signerSub.Unsubscribe()
+		s.signer.Stop()
+		s.signer = nil
+	}
+	if s.remoteSigner != nil {
+		s.remoteSigner.Close()
+		s.remoteSigner = nil
+	}
+	s.lock.Unlock()
+}
+
+// signHash signs a hash with the etherbase key, going through the remote or the
+// failover signer if one is configured.
+func (s *Ethereum) signHash(eb common.Address, hash []byte) ([]byte, error) {
+	s.lock.RLock()
+	signer, remoteSigner := s.signer, s.remoteSigner
+	s.lock.RUnlock()
+
+	if remoteSigner != nil {
+		return remoteSigner.SignHash(accounts.Account{Address: eb}, hash)
+	}
+	if signer != nil {
+		return signer.SignHash(accounts.Account{Address: eb}, hash)
+	}
+	wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+	if err != nil {
+		log.Error("Can't find coinbase account wallet", "err", err)
+		return nil, err
+	}
+	return wallet.SignHash(accounts.Account{Address: eb}, hash)
+}
+
+// ActiveSigner returns the URL of the wallet sealing blocks when failover
+// signer wallets are configured.
+func (s *Ethereum) ActiveSigner() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.signer == nil {
+		return ""
+	}
+	return s.signer.Holder()
 }
 func (s *Ethereum) IsStaking() bool     { return s.miner.Mining() }
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
@@ -861,8 +990,41 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 		}
 		maxPeers -= s.config.LightPeers
 	}
+	// Prefer the TomoX nodes found over discovery to exchange order data
+	if tomoX := s.GetTomoX(); tomoX != nil {
+		s.protocolManager.tomoxPeer = tomoX.IsDiscoveredPeer
+	}
+	// Keep the masternodes connected to each other
+	if engine, ok := s.engine.(*posv.Posv); ok {
+		s.protocolManager.mesh = newMasternodeMesh(srvr, engine, s.blockchain, s.protocolManager.peers)
+	}
+	// Watch the chain head of the masternodes for stalls
+	if engine, ok := s.engine.(*posv.Posv); ok && s.config.WatchdogTimeout > 0 {
+		masternode := func(header *types.Header) bool {
+			for _, masternode := range engine.GetMasternodes(s.blockchain, header) {
+				if masternode == engine.Signer() {
+					return true
+				}
+			}
+			return false
+		}
+		s.watchdog = newChainWatchdog(s.protocolManager, s.config.WatchdogTimeout, s.config.WatchdogRotatePeers, s.IsStaking, masternode)
+	}
+	// Follow the status of the etherbase in the validator contract
+	if _, ok := s.engine.(*posv.Posv); ok {
+		s.candidates = newCandidateWatcher(s.blockchain, s.candidateStatus, s.config.CandidateWebhook)
+	}
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(maxPeers)
+	if s.watchdog != nil {
+		s.watchdog.start()
+	}
+	if s.candidates != nil {
+		s.candidates.start()
+	}
+	if s.events != nil {
+		s.events.Start()
+	}
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
@@ -876,13 +1038,25 @@ func (s *Ethereum) Stop() error {
 		s.stopDbUpgrade()
 	}
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
 	s.blockchain.Stop()
+	if s.watchdog != nil {
+		s.watchdog.stop()
+	}
+	if s.candidates != nil {
+		s.candidates.stop()
+	}
+	if s.events != nil {
+		s.events.Stop()
+	}
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
 	s.txPool.Stop()
-	s.miner.Stop()
+	s.StopStaking()
 	s.eventMux.Stop()
 
 	s.chainDb.Close()
@@ -925,21 +1099,25 @@ func GetValidators(bc *core.BlockChain, masternodes []common.Address) ([]byte, e
 	return nil, core.ErrNotFoundM1
 }
 
-func rewardInflation(chainReward *big.Int, number uint64, blockPerYear uint64) *big.Int {
-	if blockPerYear*2 <= number && number < blockPerYear*6 {
-		chainReward.Div(chainReward, new(big.Int).SetUint64(2))
-	}
-	if blockPerYear*6 <= number {
-		chainReward.Div(chainReward, new(big.Int).SetUint64(4))
-	}
-
-	return chainReward
-}
-
 func (s *Ethereum) GetPeer() int {
 	return len(s.protocolManager.peers.peers)
 }
 
+// nodeFeatures returns the subsystems run by a node, advertised to its peers.
+func nodeFeatures(config *params.ChainConfig, tomoX *tomox.TomoX) Features {
+	var features Features
+	if tomoX != nil {
+		features |= FeatureTomoX
+		if tomoX.GetLending() != nil {
+			features |= FeatureLending
+		}
+	}
+	if config.TradeRootBlock != nil {
+		features |= FeatureTradeRoot
+	}
+	return features
+}
+
 func (s *Ethereum) GetTomoX() *tomox.TomoX {
 	return s.TomoX
 }
@@ -0,0 +1,178 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/contracts"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// candidateWebhookTimeout is the time allowed to the webhook to accept an alert.
+const candidateWebhookTimeout = 10 * time.Second
+
+// Alerts raised on changes of the candidate status.
+const (
+	CandidateAlertState    = "state"    // The candidate changed state
+	CandidateAlertUnlocked = "unlocked" // A refunded stake can be withdrawn
+)
+
+// CandidateAlert is a change of the candidate status of the etherbase, posted
+// to the webhook as JSON.
+type CandidateAlert struct {
+	Event   string                     `json:"event"`
+	Message string                     `json:"message"`
+	Status  *contracts.CandidateStatus `json:"status"`
+}
+
+// candidateWatcher follows the status of the etherbase as a masternode
+// candidate in the validator contract at each chain head, and raises alerts
+// when it changes state or when a refunded stake can be withdrawn.
+type candidateWatcher struct {
+	chain   *core.BlockChain
+	status  func(*types.Header) (*contracts.CandidateStatus, error) // Status of the candidate at a head
+	webhook string                                                  // URL the alerts are posted to, if any
+	client  *http.Client
+
+	last *contracts.CandidateStatus // Status at the last head, only used by the loop
+	quit chan struct{}
+}
+
+func newCandidateWatcher(chain *core.BlockChain, status func(*types.Header) (*contracts.CandidateStatus, error), webhook string) *candidateWatcher {
+	return &candidateWatcher{
+		chain:   chain,
+		status:  status,
+		webhook: webhook,
+		client:  &http.Client{Timeout: candidateWebhookTimeout},
+		quit:    make(chan struct{}),
+	}
+}
+
+func (w *candidateWatcher) start() {
+	go w.loop()
+}
+
+func (w *candidateWatcher) stop() {
+	close(w.quit)
+}
+
+// loop checks the status of the candidate at each chain head.
+func (w *candidateWatcher) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := w.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	w.check(w.chain.CurrentHeader())
+	for {
+		select {
+		case ev := <-heads:
+			w.check(ev.Block.Header())
+		case <-sub.Err():
+			return
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// check compares the status of the candidate at a head with the previous one
+// and raises the alerts for the changes.
+func (w *candidateWatcher) check(head *types.Header) {
+	status, err := w.status(head)
+	if err != nil {
+		log.Debug("Failed to retrieve the candidate status", "number", head.Number, "err", err)
+		return
+	}
+	last := w.last
+	w.last = status
+
+	for _, alert := range candidateAlerts(last, status) {
+		w.raise(alert)
+	}
+}
+
+// candidateAlerts returns the alerts raised by the change of the status of a
+// candidate, none on the first status seen unless stakes can be withdrawn.
+func candidateAlerts(last, status *contracts.CandidateStatus) []*CandidateAlert {
+	var alerts []*CandidateAlert
+	if last != nil && last.Candidate == status.Candidate && last.State != status.State {
+		alerts = append(alerts, &CandidateAlert{
+			Event:   CandidateAlertState,
+			Message: fmt.Sprintf("candidate %s went from %s to %s", status.Candidate.Hex(), last.State, status.State),
+			Status:  status,
+		})
+	}
+	unlocked := make(map[uint64]bool)
+	if last != nil && last.Candidate == status.Candidate {
+		for _, withdrawal := range last.Withdrawals {
+			unlocked[uint64(withdrawal.BlockNumber)] = withdrawal.Unlocked
+		}
+	}
+	for _, withdrawal := range status.Withdrawals {
+		if withdrawal.Unlocked && !unlocked[uint64(withdrawal.BlockNumber)] {
+			alerts = append(alerts, &CandidateAlert{
+				Event:   CandidateAlertUnlocked,
+				Message: fmt.Sprintf("stake of %v refunded to %s can be withdrawn at block %d with index %d", withdrawal.Cap.ToInt(), status.Owner.Hex(), withdrawal.BlockNumber, withdrawal.Index),
+				Status:  status,
+			})
+		}
+	}
+	return alerts
+}
+
+// raise logs an alert and posts it to the webhook in the background.
+func (w *candidateWatcher) raise(alert *CandidateAlert) {
+	log.Warn("Masternode candidate alert", "event", alert.Event, "message", alert.Message)
+	if w.webhook != "" {
+		go w.post(alert)
+	}
+}
+
+// post sends an alert to the webhook.
+func (w *candidateWatcher) post(alert *CandidateAlert) {
+	blob, err := json.Marshal(alert)
+	if err != nil {
+		log.Warn("Failed to encode candidate alert", "err", err)
+		return
+	}
+	resp, err := w.client.Post(w.webhook, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		log.Warn("Failed to post candidate alert", "url", w.webhook, "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Warn("Candidate alert rejected by the webhook", "url", w.webhook, "status", resp.Status)
+	}
+}
+
+// candidateStatus returns the status of the etherbase as a masternode candidate
+// at the given head.
+func (s *Ethereum) candidateStatus(head *types.Header) (*contracts.CandidateStatus, error) {
+	engine, ok := s.engine.(*posv.Posv)
+	if !ok {
+		return nil, core.ErrNotPoSV
+	}
+	etherbase, err := s.Etherbase()
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := s.blockchain.StateAt(head.Root)
+	if err != nil {
+		return nil, err
+	}
+	masternode := false
+	for _, address := range engine.GetMasternodes(s.blockchain, head) {
+		if address == etherbase {
+			masternode = true
+			break
+		}
+	}
+	return contracts.GetCandidateStatus(statedb, head, etherbase, masternode, s.chainConfig.Posv.Period), nil
+}
@@ -0,0 +1,72 @@
+package eth
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/contracts"
+)
+
+// Tests that alerts are raised on state changes and newly unlocked stakes, and
+// posted to the webhook.
+func TestCandidateAlerts(t *testing.T) {
+	candidate := common.Address{0x01}
+	status := func(state string, unlocked ...bool) *contracts.CandidateStatus {
+		s := &contracts.CandidateStatus{Candidate: candidate, State: state}
+		for i, u := range unlocked {
+			s.Withdrawals = append(s.Withdrawals, &contracts.CandidateWithdrawal{BlockNumber: hexutil.Uint64(100 + i), Cap: (*hexutil.Big)(big.NewInt(1)), Unlocked: u})
+		}
+		return s
+	}
+	tests := []struct {
+		last, status *contracts.CandidateStatus
+		events       []string
+	}{
+		{nil, status(contracts.CandidateStateProposed), nil},
+		{nil, status(contracts.CandidateStateResigned, true), []string{CandidateAlertUnlocked}},
+		{status(contracts.CandidateStateProposed), status(contracts.CandidateStateMasternode), []string{CandidateAlertState}},
+		{status(contracts.CandidateStateMasternode), status(contracts.CandidateStateResigned, false), []string{CandidateAlertState}},
+		{status(contracts.CandidateStateResigned, false), status(contracts.CandidateStateResigned, false), nil},
+		{status(contracts.CandidateStateResigned, false, false), status(contracts.CandidateStateResigned, true, false), []string{CandidateAlertUnlocked}},
+		{status(contracts.CandidateStateResigned, true), status(contracts.CandidateStateResigned, true), nil},
+	}
+	for i, tt := range tests {
+		alerts := candidateAlerts(tt.last, tt.status)
+		if len(alerts) != len(tt.events) {
+			t.Errorf("test %d: alert count mismatch: have %d, want %d", i, len(alerts), len(tt.events))
+			continue
+		}
+		for j, alert := range alerts {
+			if alert.Event != tt.events[j] {
+				t.Errorf("test %d: alert %d mismatch: have %s, want %s", i, j, alert.Event, tt.events[j])
+			}
+		}
+	}
+	// Alerts are posted to the webhook as JSON
+	posted := make(chan *CandidateAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert CandidateAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("failed to decode alert: %v", err)
+		}
+		posted <- &alert
+	}))
+	defer server.Close()
+
+	watcher := newCandidateWatcher(nil, nil, server.URL)
+	watcher.raise(&CandidateAlert{Event: CandidateAlertState, Status: status(contracts.CandidateStateMasternode)})
+	select {
+	case alert := <-posted:
+		if alert.Event != CandidateAlertState || alert.Status.State != contracts.CandidateStateMasternode || alert.Status.Candidate != candidate {
+			t.Errorf("posted alert mismatch: %+v", alert)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert not posted to the webhook")
+	}
+}
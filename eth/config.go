@@ -30,6 +30,8 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -50,7 +52,10 @@ var DefaultConfig = Config{
 	TrieTimeout:   5 * time.Minute,
 	GasPrice:      big.NewInt(0.25 * params.Shannon),
 
-	TxPool: core.DefaultTxPoolConfig,
+	MinorityForkGuardTimeout: 10 * time.Minute,
+
+	TxPool:    core.DefaultTxPoolConfig,
+	OrderPool: core.DefaultOrderPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
@@ -87,18 +92,62 @@ type Config struct {
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Checkpoint to start light syncing from, instead of the one shipped for the network
+	Checkpoint *light.TrustedCheckpoint `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	Snapshot           bool   `toml:",omitempty"` // Maintain a flat snapshot of the head state
+	LogIndex           bool   `toml:",omitempty"` // Maintain a precise index of the log addresses and topics
+	LogsRangeLimit     uint64 `toml:",omitempty"` // Maximum number of blocks searched by a log query, unlimited if zero
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
 	MinerThreads int            `toml:",omitempty"`
 	ExtraData    []byte         `toml:",omitempty"`
 	GasPrice     *big.Int
+	OrderBudget  miner.OrderBudget // Share of the block construction given to order matching
+
+	// Stop sealing while the chain looks like a minority fork, at most for the timeout
+	MinorityForkGuard        bool          `toml:",omitempty"`
+	MinorityForkGuardTimeout time.Duration `toml:",omitempty"`
+
+	// URLs of the local wallets holding the etherbase key, by sealing priority. The
+	// failover is arbitrated on this node only, sealing stops if the key seals a
+	// block on another node.
+	SignerWallets []string `toml:",omitempty"`
+
+	// Remote signer sealing blocks over HTTP, and the audit log of its requests
+	RemoteSigner      string `toml:",omitempty"`
+	RemoteSignerAudit string `toml:",omitempty"`
+
+	// Recompute and verify the rewards applied at each checkpoint
+	VerifyRewards bool `toml:",omitempty"`
+
+	// Alert when the chain head of a masternode is stuck for longer than the
+	// timeout (disabled if zero), and drop peers to find new ones
+	WatchdogTimeout     time.Duration `toml:",omitempty"`
+	WatchdogRotatePeers bool          `toml:",omitempty"`
+
+	// Retry and blacklist policy of the downloader, see downloader.Config
+	DownloaderRequestTTL    time.Duration `toml:",omitempty"`
+	DownloaderBlacklist     time.Duration `toml:",omitempty"`
+	DownloaderMinThroughput float64       `toml:",omitempty"`
+
+	// URL notified of the changes of the candidate status of the etherbase
+	CandidateWebhook string `toml:",omitempty"`
+
+	// URLs of the sinks the chain and DEX events are published to
+	EventSinks []string `toml:",omitempty"`
+
+	// Accounts allowed to sign orders and transactions through the APIs, all
+	// the local ones if empty
+	OrderSigners []common.Address `toml:",omitempty"`
+	TxSigners    []common.Address `toml:",omitempty"`
 
 	// Ethash options
 	Ethash ethash.Config
@@ -106,6 +155,9 @@ type Config struct {
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Order pool options
+	OrderPool core.OrderPoolConfig
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
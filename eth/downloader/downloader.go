@@ -65,6 +65,8 @@ var (
 	fsHeaderForceVerify    = 24              // Number of headers to verify before and after the pivot to accept it
 	fsHeaderContCheck      = 3 * time.Second // Time interval to check for header continuations during state download
 	fsMinFullBlocks        = 64              // Number of blocks to retrieve fully even in fast sync
+
+	maxSlowDeliveries = 3 // Number of consecutive deliveries under the minimum throughput before rotating a peer
 )
 
 var (
@@ -91,8 +93,17 @@ var (
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
 	errEnoughBlock             = errors.New("downloader download enough block")
+	errBlacklistedPeer         = errors.New("peer is blacklisted")
 )
 
+// Config contains the tunable parameters of the retry and blacklist policy of
+// the downloader. Zero values keep the built-in behaviour.
+type Config struct {
+	RequestTTL       time.Duration // Maximum timeout allowance of a single request (0 = built-in limit)
+	BlacklistTimeout time.Duration // Time a peer dropped for stalling or bad data is refused for (0 = disabled)
+	MinThroughput    float64       // Items per second under which a peer is rotated out (0 = disabled)
+}
+
 type Downloader struct {
 	mode SyncMode       // Synchronisation mode defining the strategy used (per sync cycle)
 	mux  *event.TypeMux // Event multiplexer to announce sync operation events
@@ -116,6 +127,12 @@ type Downloader struct {
 	// Callbacks
 	dropPeer peerDropFn // Drops a peer for misbehaving
 
+	// Retry policy
+	config     Config               // Tunable timeouts, blacklisting and throughput floor
+	configLock sync.RWMutex         // Lock protecting the policy config
+	blacklist  map[string]time.Time // Peers dropped for misbehaving, refused until the deadline
+	blackLock  sync.Mutex           // Lock protecting the blacklist
+
 	// Status
 	synchroniseMock func(id string, hash common.Hash) error // Replacement for synchronise during testing
 	synchronising   int32
@@ -228,6 +245,14 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 			processed: core.GetTrieSyncProgress(stateDb),
 		},
 		trackStateReq: make(chan *stateReq),
+		blacklist:     make(map[string]time.Time),
+	}
+	// Blacklist the peers the downloader drops, so they can't rejoin right away
+	if dropPeer != nil {
+		dl.dropPeer = func(id string) {
+			dl.blacklistPeer(id)
+			dropPeer(id)
+		}
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
@@ -264,6 +289,48 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 	}
 }
 
+// SetConfig updates the retry and blacklist policy of the downloader.
+func (d *Downloader) SetConfig(config Config) {
+	d.configLock.Lock()
+	defer d.configLock.Unlock()
+
+	d.config = config
+}
+
+// Config retrieves the retry and blacklist policy of the downloader.
+func (d *Downloader) Config() Config {
+	d.configLock.RLock()
+	defer d.configLock.RUnlock()
+
+	return d.config
+}
+
+// blacklistPeer refuses a peer for the configured blacklist timeout.
+func (d *Downloader) blacklistPeer(id string) {
+	timeout := d.Config().BlacklistTimeout
+	if timeout <= 0 {
+		return
+	}
+	d.blackLock.Lock()
+	defer d.blackLock.Unlock()
+
+	d.blacklist[id] = time.Now().Add(timeout)
+}
+
+// blacklisted checks whether a peer is currently refused, pruning the entry
+// if its timeout already passed.
+func (d *Downloader) blacklisted(id string) bool {
+	d.blackLock.Lock()
+	defer d.blackLock.Unlock()
+
+	deadline, ok := d.blacklist[id]
+	if ok && time.Now().After(deadline) {
+		delete(d.blacklist, id)
+		return false
+	}
+	return ok
+}
+
 // Synchronising returns whether the downloader is currently retrieving blocks.
 func (d *Downloader) Synchronising() bool {
 	return atomic.LoadInt32(&d.synchronising) > 0
@@ -274,6 +341,10 @@ func (d *Downloader) Synchronising() bool {
 func (d *Downloader) RegisterPeer(id string, version int, peer Peer) error {
 	logger := log.New("peer", id)
 	logger.Trace("Registering sync peer")
+	if d.blacklisted(id) {
+		logger.Debug("Refusing blacklisted sync peer")
+		return errBlacklistedPeer
+	}
 	if err := d.peers.Register(newPeerConnection(id, version, peer, logger)); err != nil {
 		logger.Error("Failed to register sync peer", "err", err)
 		return err
@@ -926,7 +997,7 @@ func (d *Downloader) fetchBodies(from uint64) error {
 	var (
 		deliver = func(packet dataPack) (int, error) {
 			pack := packet.(*bodyPack)
-			return d.queue.DeliverBodies(pack.peerId, pack.transactions, pack.uncles)
+			return d.queue.DeliverBodies(pack.peerId, pack.transactions, pack.uncles, pack.trades)
 		}
 		expire   = func() map[string]int { return d.queue.ExpireBodies(d.requestTTL()) }
 		fetch    = func(p *peerConnection, req *fetchRequest) error { return p.FetchBodies(req) }
@@ -1022,6 +1093,9 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 				// idle. If the delivery's stale, the peer should have already been idled.
 				if err != errStaleDelivery {
 					setIdle(peer, accepted)
+					if accepted > 0 {
+						d.rotateSlowPeer(peer, kind)
+					}
 				}
 				// Issue a log to the user to see what's going on
 				switch {
@@ -1150,6 +1224,23 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 	}
 }
 
+// rotateSlowPeer drops a peer whose deliveries stayed under the configured
+// minimum throughput for several requests in a row, letting the sync move on
+// to faster peers. The last remaining peer is never dropped.
+func (d *Downloader) rotateSlowPeer(p *peerConnection, kind string) {
+	min := d.Config().MinThroughput
+	if p.trackThroughput(min) < maxSlowDeliveries || d.peers.Len() <= 1 {
+		return
+	}
+	p.log.Debug("Delivery throughput too low, rotating peer", "type", kind, "min", min)
+	if d.dropPeer == nil {
+		// The dropPeer method is nil when `--copydb` is used for a local copy.
+		p.log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", p.id)
+	} else {
+		d.dropPeer(p.id)
+	}
+}
+
 // processHeaders takes batches of retrieved headers from an input channel and
 // keeps processing and scheduling them into the header chain and downloader's
 // queue until the stream ends or a failure occurs.
@@ -1386,7 +1477,7 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 	)
 	blocks := make([]*types.Block, len(results))
 	for i, result := range results {
-		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
+		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles).WithTrades(result.Trades)
 	}
 	if index, err := d.blockchain.InsertChain(blocks); err != nil {
 		log.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
@@ -1529,7 +1620,7 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 	blocks := make([]*types.Block, len(results))
 	receipts := make([]types.Receipts, len(results))
 	for i, result := range results {
-		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
+		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles).WithTrades(result.Trades)
 		receipts[i] = result.Receipts
 	}
 	if index, err := d.blockchain.InsertReceiptChain(blocks, receipts); err != nil {
@@ -1540,7 +1631,7 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 }
 
 func (d *Downloader) commitPivotBlock(result *fetchResult) error {
-	block := types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
+	block := types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles).WithTrades(result.Trades)
 	log.Debug("Committing fast sync pivot as new head", "number", block.Number(), "hash", block.Hash())
 	if _, err := d.blockchain.InsertReceiptChain([]*types.Block{block}, []types.Receipts{result.Receipts}); err != nil {
 		return err
@@ -1559,8 +1650,8 @@ func (d *Downloader) DeliverHeaders(id string, headers []*types.Header) (err err
 }
 
 // DeliverBodies injects a new batch of block bodies received from a remote node.
-func (d *Downloader) DeliverBodies(id string, transactions [][]*types.Transaction, uncles [][]*types.Header) (err error) {
-	return d.deliver(id, d.bodyCh, &bodyPack{id, transactions, uncles}, bodyInMeter, bodyDropMeter)
+func (d *Downloader) DeliverBodies(id string, transactions [][]*types.Transaction, uncles [][]*types.Header, trades [][]*types.Trade) (err error) {
+	return d.deliver(id, d.bodyCh, &bodyPack{id, transactions, uncles, trades}, bodyInMeter, bodyDropMeter)
 }
 
 // DeliverReceipts injects a new batch of receipts received from a remote node.
@@ -1665,9 +1756,13 @@ func (d *Downloader) requestTTL() time.Duration {
 		rtt  = time.Duration(atomic.LoadUint64(&d.rttEstimate))
 		conf = float64(atomic.LoadUint64(&d.rttConfidence)) / 1000000.0
 	)
+	limit := ttlLimit
+	if custom := d.Config().RequestTTL; custom > 0 {
+		limit = custom
+	}
 	ttl := time.Duration(ttlScaling) * time.Duration(float64(rtt)/conf)
-	if ttl > ttlLimit {
-		ttl = ttlLimit
+	if ttl > limit {
+		ttl = limit
 	}
 	return ttl
 }
@@ -550,14 +550,16 @@ func (dlp *downloadTesterPeer) RequestBodies(hashes []common.Hash) error {
 
 	transactions := make([][]*types.Transaction, 0, len(hashes))
 	uncles := make([][]*types.Header, 0, len(hashes))
+	trades := make([][]*types.Trade, 0, len(hashes))
 
 	for _, hash := range hashes {
 		if block, ok := blocks[hash]; ok {
 			transactions = append(transactions, block.Transactions())
 			uncles = append(uncles, block.Uncles())
+			trades = append(trades, block.Trades())
 		}
 	}
-	go dlp.dl.downloader.DeliverBodies(dlp.id, transactions, uncles)
+	go dlp.dl.downloader.DeliverBodies(dlp.id, transactions, uncles, trades)
 
 	return nil
 }
@@ -926,7 +928,7 @@ func TestInactiveDownloader62(t *testing.T) {
 	if err := tester.downloader.DeliverHeaders("bad peer", []*types.Header{}); err != errNoSyncActive {
 		t.Errorf("error mismatch: have %v, want %v", err, errNoSyncActive)
 	}
-	if err := tester.downloader.DeliverBodies("bad peer", [][]*types.Transaction{}, [][]*types.Header{}); err != errNoSyncActive {
+	if err := tester.downloader.DeliverBodies("bad peer", [][]*types.Transaction{}, [][]*types.Header{}, [][]*types.Trade{}); err != errNoSyncActive {
 		t.Errorf("error mismatch: have %v, want %v", err, errNoSyncActive)
 	}
 }
@@ -943,7 +945,7 @@ func TestInactiveDownloader63(t *testing.T) {
 	if err := tester.downloader.DeliverHeaders("bad peer", []*types.Header{}); err != errNoSyncActive {
 		t.Errorf("error mismatch: have %v, want %v", err, errNoSyncActive)
 	}
-	if err := tester.downloader.DeliverBodies("bad peer", [][]*types.Transaction{}, [][]*types.Header{}); err != errNoSyncActive {
+	if err := tester.downloader.DeliverBodies("bad peer", [][]*types.Transaction{}, [][]*types.Header{}, [][]*types.Trade{}); err != errNoSyncActive {
 		t.Errorf("error mismatch: have %v, want %v", err, errNoSyncActive)
 	}
 	if err := tester.downloader.DeliverReceipts("bad peer", [][]*types.Receipt{}); err != errNoSyncActive {
@@ -1756,3 +1758,143 @@ func testDeliverHeadersHang(t *testing.T, protocol int, mode SyncMode) {
 		tester.downloader.peers.peers["peer"].peer.(*floodingTestPeer).pend.Wait()
 	}
 }
+
+// Tests that peers dropped by the downloader are refused until their blacklist
+// timeout passes.
+func TestBlacklistedPeer(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	tester.downloader.SetConfig(Config{BlacklistTimeout: time.Minute})
+	hashes, headers, blocks, receipts := tester.makeChain(5, 0, tester.genesis, nil, false)
+
+	if err := tester.newPeer("bad", 63, hashes, headers, blocks, receipts); err != nil {
+		t.Fatalf("failed to register peer: %v", err)
+	}
+	tester.downloader.dropPeer("bad")
+	if err := tester.newPeer("bad", 63, hashes, headers, blocks, receipts); err != errBlacklistedPeer {
+		t.Fatalf("blacklisted peer registration error mismatch: have %v, want %v", err, errBlacklistedPeer)
+	}
+	if _, ok := tester.downloader.Status().Blacklist["bad"]; !ok {
+		t.Fatalf("blacklisted peer missing from the status")
+	}
+	// Expire the ban and ensure the peer is accepted again
+	tester.downloader.blackLock.Lock()
+	tester.downloader.blacklist["bad"] = time.Now().Add(-time.Second)
+	tester.downloader.blackLock.Unlock()
+
+	if err := tester.newPeer("bad", 63, hashes, headers, blocks, receipts); err != nil {
+		t.Fatalf("failed to register peer after the ban: %v", err)
+	}
+	if len(tester.downloader.Status().Blacklist) != 0 {
+		t.Fatalf("expired ban still reported")
+	}
+}
+
+// Tests that the configured request timeout caps the estimated one.
+func TestRequestTTLLimit(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	if ttl := tester.downloader.requestTTL(); ttl != ttlLimit {
+		t.Fatalf("default request ttl mismatch: have %v, want %v", ttl, ttlLimit)
+	}
+	tester.downloader.SetConfig(Config{RequestTTL: 100 * time.Millisecond})
+	if ttl := tester.downloader.requestTTL(); ttl != 100*time.Millisecond {
+		t.Fatalf("configured request ttl mismatch: have %v, want %v", ttl, 100*time.Millisecond)
+	}
+}
+
+// Tests that peers delivering under the minimum throughput are rotated out,
+// unless they are the last ones to sync from.
+func TestSlowPeerRotation(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	tester.downloader.SetConfig(Config{MinThroughput: 100})
+	hashes, headers, blocks, receipts := tester.makeChain(5, 0, tester.genesis, nil, false)
+
+	tester.newPeer("fast", 63, hashes, headers, blocks, receipts)
+	tester.newPeer("slow", 63, hashes, headers, blocks, receipts)
+
+	deliver := func(id string, measured float64) {
+		p := tester.downloader.peers.Peer(id)
+		p.lock.Lock()
+		p.measured = measured
+		p.lock.Unlock()
+		tester.downloader.rotateSlowPeer(p, "bodies")
+	}
+	// A fast delivery resets the count of the slow ones
+	for i := 0; i < maxSlowDeliveries-1; i++ {
+		deliver("slow", 10)
+	}
+	deliver("slow", 1000)
+	for i := 0; i < maxSlowDeliveries-1; i++ {
+		deliver("slow", 10)
+	}
+	if tester.downloader.peers.Peer("slow") == nil {
+		t.Fatalf("peer rotated before reaching the slow delivery limit")
+	}
+	deliver("slow", 10)
+	if tester.downloader.peers.Peer("slow") != nil {
+		t.Fatalf("slow peer not rotated out")
+	}
+	// The last peer is kept no matter how slow it is
+	for i := 0; i < 2*maxSlowDeliveries; i++ {
+		deliver("fast", 10)
+	}
+	if tester.downloader.peers.Peer("fast") == nil {
+		t.Fatalf("last peer rotated out")
+	}
+}
+
+// Tests that the status reports the in-flight requests of the peers and the
+// ranges still queued.
+func TestDownloaderStatus(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	hashes, headers, blocks, receipts := tester.makeChain(5, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", 63, hashes, headers, blocks, receipts)
+
+	// Queue a few headers and assign the first ones to the peer
+	chain := make([]*types.Header, 10)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i + 1))}
+		if i > 0 {
+			chain[i].ParentHash = chain[i-1].Hash()
+		}
+	}
+	q := tester.downloader.queue
+	q.Prepare(1, FullSync)
+	q.Schedule(chain, 1)
+
+	q.lock.Lock()
+	for _, header := range chain[:4] {
+		delete(q.blockTaskPool, header.Hash())
+		q.blockTaskQueue.PopItem()
+	}
+	q.blockPendPool["peer"] = &fetchRequest{Peer: tester.downloader.peers.Peer("peer"), Headers: chain[:4], Time: time.Now()}
+	q.lock.Unlock()
+
+	status := tester.downloader.Status()
+	if status.Synchronising {
+		t.Errorf("idle downloader reported synchronising")
+	}
+	if len(status.Peers) != 1 || status.Peers[0].ID != "peer" {
+		t.Fatalf("peer status mismatch: have %+v", status.Peers)
+	}
+	want := Assignment{Kind: "bodies", From: 1, Count: 4}
+	if assigned := status.Peers[0].Assigned; len(assigned) != 1 || assigned[0].Kind != want.Kind || assigned[0].From != want.From || assigned[0].Count != want.Count {
+		t.Errorf("assignment mismatch: have %+v, want %+v", assigned, want)
+	}
+	if status.Queue.PendingBlocks != 6 {
+		t.Errorf("pending blocks mismatch: have %d, want %d", status.Queue.PendingBlocks, 6)
+	}
+	if r := status.Queue.BlockRange; r == nil || r.From != 5 || r.To != 10 {
+		t.Errorf("queued block range mismatch: have %+v, want 5-10", r)
+	}
+	if status.Queue.ReceiptRange != nil {
+		t.Errorf("unexpected queued receipts: %+v", status.Queue.ReceiptRange)
+	}
+}
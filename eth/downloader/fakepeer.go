@@ -124,14 +124,16 @@ func (p *FakePeer) RequestBodies(hashes []common.Hash) error {
 	var (
 		txs    [][]*types.Transaction
 		uncles [][]*types.Header
+		trades [][]*types.Trade
 	)
 	for _, hash := range hashes {
 		block := core.GetBlock(p.db, hash, p.hc.GetBlockNumber(hash))
 
 		txs = append(txs, block.Transactions())
 		uncles = append(uncles, block.Uncles())
+		trades = append(trades, block.Trades())
 	}
-	p.dl.DeliverBodies(p.id, txs, uncles)
+	p.dl.DeliverBodies(p.id, txs, uncles, trades)
 	return nil
 }
 
@@ -61,6 +61,9 @@ type peerConnection struct {
 
 	rtt time.Duration // Request round trip time to track responsiveness (QoS)
 
+	measured float64 // Throughput measured on the last delivery, irrelevant of the type
+	slow     int     // Number of consecutive deliveries under the minimum throughput
+
 	headerStarted  time.Time // Time instance when the last header fetch was started
 	blockStarted   time.Time // Time instance when the last block (body) fetch was started
 	receiptStarted time.Time // Time instance when the last receipt fetch was started
@@ -139,6 +142,8 @@ func (p *peerConnection) Reset() {
 	p.blockThroughput = 0
 	p.receiptThroughput = 0
 	p.stateThroughput = 0
+	p.measured = 0
+	p.slow = 0
 
 	p.lacking = make(map[common.Hash]struct{})
 }
@@ -269,11 +274,13 @@ func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *f
 	// If nothing was delivered (hard timeout / unavailable data), reduce throughput to minimum
 	if delivered == 0 {
 		*throughput = 0
+		p.measured = 0
 		return
 	}
 	// Otherwise update the throughput with a new measurement
 	elapsed := time.Since(started) + 1 // +1 (ns) to ensure non-zero divisor
 	measured := float64(delivered) / (float64(elapsed) / float64(time.Second))
+	p.measured = measured
 
 	*throughput = (1-measurementImpact)*(*throughput) + measurementImpact*measured
 	p.rtt = time.Duration((1-measurementImpact)*float64(p.rtt) + measurementImpact*float64(elapsed))
@@ -284,6 +291,20 @@ func (p *peerConnection) setIdle(started time.Time, delivered int, throughput *f
 		"miss", len(p.lacking), "rtt", p.rtt)
 }
 
+// trackThroughput counts the consecutive deliveries measured under the minimum
+// throughput, returning their number. A zero minimum disables the tracking.
+func (p *peerConnection) trackThroughput(min float64) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if min <= 0 || p.measured >= min {
+		p.slow = 0
+	} else {
+		p.slow++
+	}
+	return p.slow
+}
+
 // HeaderCapacity retrieves the peers header download allowance based on its
 // previously discovered throughput.
 func (p *peerConnection) HeaderCapacity(targetRTT time.Duration) int {
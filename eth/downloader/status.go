@@ -0,0 +1,198 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"sort"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Status is a snapshot of the downloader internals, meant to diagnose syncs
+// stalling on bad peers.
+type Status struct {
+	Synchronising bool                  `json:"synchronising"`
+	Mode          string                `json:"mode"`
+	Master        string                `json:"master"` // Peer the current sync cycle follows
+	Progress      ethereum.SyncProgress `json:"progress"`
+	RTT           string                `json:"rtt"` // Target round trip time of the requests
+	TTL           string                `json:"ttl"` // Timeout allowance of the requests
+	Peers         []PeerStatus          `json:"peers"`
+	Queue         QueueStatus           `json:"queue"`
+	Blacklist     map[string]time.Time  `json:"blacklist"` // Refused peers and the end of their ban
+}
+
+// PeerStatus is the download state of a single sync peer.
+type PeerStatus struct {
+	ID                string       `json:"id"`
+	Version           int          `json:"version"`
+	HeaderThroughput  float64      `json:"headerThroughput"`
+	BlockThroughput   float64      `json:"blockThroughput"`
+	ReceiptThroughput float64      `json:"receiptThroughput"`
+	StateThroughput   float64      `json:"stateThroughput"`
+	RTT               string       `json:"rtt"`
+	Lacking           int          `json:"lacking"`        // Number of items known to be unavailable
+	SlowDeliveries    int          `json:"slowDeliveries"` // Consecutive deliveries under the minimum throughput
+	Assigned          []Assignment `json:"assigned"`       // In-flight requests of the peer
+}
+
+// Assignment is a data retrieval request in flight to a peer.
+type Assignment struct {
+	Kind  string `json:"kind"`
+	From  uint64 `json:"from"`
+	Count int    `json:"count"`
+	Age   string `json:"age"`
+}
+
+// Range is an inclusive range of block numbers.
+type Range struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// QueueStatus is the amount and block range of the tasks waiting for a peer.
+type QueueStatus struct {
+	PendingHeaders  int    `json:"pendingHeaders"`
+	PendingBlocks   int    `json:"pendingBlocks"`
+	PendingReceipts int    `json:"pendingReceipts"`
+	HeaderRange     *Range `json:"headerRange"`
+	BlockRange      *Range `json:"blockRange"`
+	ReceiptRange    *Range `json:"receiptRange"`
+	ResultOffset    uint64 `json:"resultOffset"` // First block of the result cache
+}
+
+// Status retrieves a snapshot of the sync state, the peer assignments and the
+// queued ranges.
+func (d *Downloader) Status() *Status {
+	d.cancelLock.RLock()
+	master := d.cancelPeer
+	d.cancelLock.RUnlock()
+
+	synchronising := d.Synchronising()
+	if !synchronising {
+		master = ""
+	}
+	queue, assigned := d.queue.status()
+	status := &Status{
+		Synchronising: synchronising,
+		Mode:          d.mode.String(),
+		Master:        master,
+		Progress:      d.Progress(),
+		RTT:           d.requestRTT().String(),
+		TTL:           d.requestTTL().String(),
+		Peers:         []PeerStatus{},
+		Queue:         queue,
+		Blacklist:     make(map[string]time.Time),
+	}
+	for _, p := range d.peers.AllPeers() {
+		p.lock.RLock()
+		status.Peers = append(status.Peers, PeerStatus{
+			ID:                p.id,
+			Version:           p.version,
+			HeaderThroughput:  p.headerThroughput,
+			BlockThroughput:   p.blockThroughput,
+			ReceiptThroughput: p.receiptThroughput,
+			StateThroughput:   p.stateThroughput,
+			RTT:               p.rtt.String(),
+			Lacking:           len(p.lacking),
+			SlowDeliveries:    p.slow,
+			Assigned:          assigned[p.id],
+		})
+		p.lock.RUnlock()
+	}
+	sort.Slice(status.Peers, func(i, j int) bool { return status.Peers[i].ID < status.Peers[j].ID })
+
+	d.blackLock.Lock()
+	for id, deadline := range d.blacklist {
+		if time.Now().Before(deadline) {
+			status.Blacklist[id] = deadline
+		}
+	}
+	d.blackLock.Unlock()
+
+	return status
+}
+
+// status retrieves the amount and range of the queued tasks, along with the
+// requests in flight keyed by peer.
+func (q *queue) status() (QueueStatus, map[string][]Assignment) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	status := QueueStatus{
+		PendingBlocks:   q.blockTaskQueue.Size(),
+		PendingReceipts: q.receiptTaskQueue.Size(),
+		BlockRange:      headerRange(q.blockTaskPool),
+		ReceiptRange:    headerRange(q.receiptTaskPool),
+		ResultOffset:    q.resultOffset,
+	}
+	if q.headerTaskQueue != nil {
+		status.PendingHeaders = q.headerTaskQueue.Size()
+	}
+	for from := range q.headerTaskPool {
+		if status.HeaderRange == nil {
+			status.HeaderRange = &Range{From: from, To: from + uint64(MaxHeaderFetch) - 1}
+		}
+		if from < status.HeaderRange.From {
+			status.HeaderRange.From = from
+		}
+		if to := from + uint64(MaxHeaderFetch) - 1; to > status.HeaderRange.To {
+			status.HeaderRange.To = to
+		}
+	}
+	assigned := make(map[string][]Assignment)
+	for kind, pool := range map[string]map[string]*fetchRequest{
+		"headers":  q.headerPendPool,
+		"bodies":   q.blockPendPool,
+		"receipts": q.receiptPendPool,
+	} {
+		for id, request := range pool {
+			assignment := Assignment{Kind: kind, Age: time.Since(request.Time).String()}
+			if request.From > 0 {
+				assignment.From, assignment.Count = request.From, MaxHeaderFetch
+			} else if len(request.Headers) > 0 {
+				assignment.From, assignment.Count = request.Headers[0].Number.Uint64(), len(request.Headers)
+			}
+			assigned[id] = append(assigned[id], assignment)
+		}
+	}
+	for _, list := range assigned {
+		sort.Slice(list, func(i, j int) bool { return list[i].Kind < list[j].Kind })
+	}
+	return status, assigned
+}
+
+// headerRange returns the range of block numbers spanned by a task pool, or
+// nil if the pool is empty.
+func headerRange(pool map[common.Hash]*types.Header) *Range {
+	var r *Range
+	for _, header := range pool {
+		number := header.Number.Uint64()
+		if r == nil {
+			r = &Range{From: number, To: number}
+		}
+		if number < r.From {
+			r.From = number
+		}
+		if number > r.To {
+			r.To = number
+		}
+	}
+	return r
+}
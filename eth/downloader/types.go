@@ -47,16 +47,23 @@ type bodyPack struct {
 	peerId       string
 	transactions [][]*types.Transaction
 	uncles       [][]*types.Header
+	trades       [][]*types.Trade
 }
 
 func (p *bodyPack) PeerId() string { return p.peerId }
 func (p *bodyPack) Items() int {
-	if len(p.transactions) <= len(p.uncles) {
-		return len(p.transactions)
+	items := len(p.transactions)
+	if len(p.uncles) < items {
+		items = len(p.uncles)
 	}
-	return len(p.uncles)
+	if len(p.trades) < items {
+		items = len(p.trades)
+	}
+	return items
+}
+func (p *bodyPack) Stats() string {
+	return fmt.Sprintf("%d:%d:%d", len(p.transactions), len(p.uncles), len(p.trades))
 }
-func (p *bodyPack) Stats() string { return fmt.Sprintf("%d:%d", len(p.transactions), len(p.uncles)) }
 
 // receiptPack is a batch of receipts returned by a peer.
 type receiptPack struct {
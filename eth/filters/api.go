@@ -28,6 +28,7 @@ import (
 	ethereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -59,6 +60,8 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+
+	rangeLimit uint64 // Maximum number of blocks searched by a log query, unlimited if zero
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
@@ -75,6 +78,12 @@ func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 	return api
 }
 
+// SetRangeLimit limits the number of blocks searched by a log query, zero
+// lifting the limit. It must be called before the API is served.
+func (api *PublicFilterAPI) SetRangeLimit(limit uint64) {
+	api.rangeLimit = limit
+}
+
 // timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
 // Tt is started when the api is created.
 func (api *PublicFilterAPI) timeoutLoop() {
@@ -164,6 +173,65 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// PendingTransactionDetails is the notification of a transaction entering the
+// transaction pool, moving within it or leaving it without being included.
+type PendingTransactionDetails struct {
+	Hash       common.Hash     `json:"hash"`
+	From       *common.Address `json:"from"`
+	Nonce      hexutil.Uint64  `json:"nonce"`
+	Status     string          `json:"status"`               // pending, queued, replaced or dropped
+	Reason     string          `json:"reason,omitempty"`     // Why a dropped transaction left the pool
+	ReplacedBy *common.Hash    `json:"replacedBy,omitempty"` // Transaction taking the place of a replaced one
+}
+
+// newPendingTransactionDetails creates the notification of a lifecycle event.
+func newPendingTransactionDetails(ev core.TxLifecycleEvent) *PendingTransactionDetails {
+	details := &PendingTransactionDetails{
+		Hash:   ev.Tx.Hash(),
+		From:   ev.Tx.From(),
+		Nonce:  hexutil.Uint64(ev.Tx.Nonce()),
+		Status: ev.Status,
+		Reason: ev.Reason,
+	}
+	if ev.Replacement != (common.Hash{}) {
+		replacement := ev.Replacement
+		details.ReplacedBy = &replacement
+	}
+	return details
+}
+
+// NewPendingTransactionDetails creates a subscription that is triggered each time
+// a transaction enters the transaction pool, is queued, replaced or dropped from
+// it, telling why it was dropped.
+func (api *PublicFilterAPI) NewPendingTransactionDetails(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.TxLifecycleEvent)
+		detailsSub := api.events.SubscribePendingTxDetails(events)
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, newPendingTransactionDetails(ev))
+			case <-rpcSub.Err():
+				detailsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				detailsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
@@ -335,6 +403,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	}
 	// Create and run the filter to get all the logs
 	filter := New(api.backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
+	filter.rangeLimit = api.rangeLimit
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -383,6 +452,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	}
 	// Create and run the filter to get all the logs
 	filter := New(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+	filter.rangeLimit = api.rangeLimit
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
@@ -18,7 +18,9 @@ package filters
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -37,6 +39,7 @@ type Backend interface {
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
 
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
+	SubscribeTxLifecycleEvent(chan<- core.TxLifecycleEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
@@ -45,6 +48,18 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// LogIndexBackend is implemented by the backends maintaining a precise log index
+// next to the bloom bits. Filters use it over the sections it covers.
+type LogIndexBackend interface {
+	// LogIndexStatus returns the section size and the number of sections of the
+	// log index, none if it is not maintained.
+	LogIndexStatus() (uint64, uint64)
+
+	// LogIndexBlocks returns the numbers of the blocks of a section having logs
+	// matching a log index key.
+	LogIndexBlocks(ctx context.Context, section uint64, key []byte) ([]uint64, error)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -55,8 +70,23 @@ type Filter struct {
 	topics     [][]common.Hash
 
 	matcher *bloombits.Matcher
+
+	rangeLimit uint64 // Maximum number of blocks searched, unlimited if zero
+}
+
+// RangeLimitError is returned for the log queries spanning more blocks than
+// allowed, carrying the JSON-RPC limit exceeded code.
+type RangeLimitError struct {
+	Range, Limit uint64
+}
+
+func (e *RangeLimitError) Error() string {
+	return fmt.Sprintf("block range of %d blocks exceeds the limit of %d", e.Range, e.Limit)
 }
 
+// ErrorCode returns the JSON-RPC error code of the error.
+func (e *RangeLimitError) ErrorCode() int { return -32005 }
+
 // New creates a new filter which uses a bloom filter on blocks to figure out whether
 // a particular block is interesting or not.
 func New(backend Backend, begin, end int64, addresses []common.Address, topics [][]common.Hash) *Filter {
@@ -109,11 +139,27 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	if f.rangeLimit > 0 && int64(end) >= f.begin && end-uint64(f.begin)+1 > f.rangeLimit {
+		return nil, &RangeLimitError{Range: end - uint64(f.begin) + 1, Limit: f.rangeLimit}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if backend, ok := f.backend.(LogIndexBackend); ok && f.hasCriteria() {
+		size, sections := backend.LogIndexStatus()
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.preciseLogs(ctx, backend, size, end)
+			} else {
+				logs, err = f.preciseLogs(ctx, backend, size, indexed-1)
+			}
+			if err != nil || f.begin > int64(end) {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
 		if indexed > end {
@@ -177,12 +223,106 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
+// hasCriteria reports whether the filter restricts the addresses or the topics of
+// the logs, without which every block with logs matches.
+func (f *Filter) hasCriteria() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// preciseLogs returns the logs matching the filter criteria based on the log
+// index. The blocks of each section are the ones matching any of the addresses,
+// and any of the topics at each position.
+func (f *Filter) preciseLogs(ctx context.Context, backend LogIndexBackend, size, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section <= end/size; section++ {
+		var groups [][][]byte
+		if len(f.addresses) > 0 {
+			group := make([][]byte, len(f.addresses))
+			for i, address := range f.addresses {
+				group[i] = core.LogIndexAddressKey(address)
+			}
+			groups = append(groups, group)
+		}
+		for position, topics := range f.topics {
+			if len(topics) == 0 {
+				continue
+			}
+			group := make([][]byte, len(topics))
+			for i, topic := range topics {
+				group[i] = core.LogIndexTopicKey(position, topic)
+			}
+			groups = append(groups, group)
+		}
+		var matches map[uint64]bool
+		for _, group := range groups {
+			union := make(map[uint64]bool)
+			for _, key := range group {
+				numbers, err := backend.LogIndexBlocks(ctx, section, key)
+				if err != nil {
+					return logs, err
+				}
+				for _, number := range numbers {
+					if matches == nil || matches[number] {
+						union[number] = true
+					}
+				}
+			}
+			if matches = union; len(matches) == 0 {
+				break
+			}
+		}
+		numbers := make([]uint64, 0, len(matches))
+		for number := range matches {
+			if number >= uint64(f.begin) && number <= end {
+				numbers = append(numbers, number)
+			}
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+		for _, number := range numbers {
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.checkMatches(ctx, header)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+			f.begin = int64(number) + 1
+		}
+		if f.begin = int64((section + 1) * size); f.begin > int64(end) {
+			f.begin = int64(end) + 1
+		}
+		select {
+		case <-ctx.Done():
+			return logs, ctx.Err()
+		default:
+		}
+	}
+	return logs, nil
+}
+
 // indexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	var logs []*types.Log
 
 	for ; f.begin <= int64(end); f.begin++ {
+		select {
+		case <-ctx.Done():
+			return logs, ctx.Err()
+		default:
+		}
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
 			return logs, err
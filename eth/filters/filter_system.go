@@ -51,6 +51,9 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// PendingTransactionDetailsSubscription queries the lifecycle events of the
+	// transactions in the pool
+	PendingTransactionDetailsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -60,6 +63,8 @@ const (
 	// txChanSize is the size of channel listening to TxPreEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+	// txEventChanSize is the size of channel listening to TxLifecycleEvent.
+	txEventChanSize = 4096
 	// rmLogsChanSize is the size of channel listening to RemovedLogsEvent.
 	rmLogsChanSize = 10
 	// logsChanSize is the size of channel listening to LogsEvent.
@@ -80,6 +85,7 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan common.Hash
 	headers   chan *types.Header
+	details   chan core.TxLifecycleEvent
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -143,6 +149,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.details:
 			}
 		}
 
@@ -210,6 +217,7 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit ethereum.FilterQuery, logs
 		logs:      logs,
 		hashes:    make(chan common.Hash),
 		headers:   make(chan *types.Header),
+		details:   make(chan core.TxLifecycleEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -227,6 +235,7 @@ func (es *EventSystem) subscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 		logs:      logs,
 		hashes:    make(chan common.Hash),
 		headers:   make(chan *types.Header),
+		details:   make(chan core.TxLifecycleEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -244,6 +253,7 @@ func (es *EventSystem) subscribePendingLogs(crit ethereum.FilterQuery, logs chan
 		logs:      logs,
 		hashes:    make(chan common.Hash),
 		headers:   make(chan *types.Header),
+		details:   make(chan core.TxLifecycleEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -260,6 +270,7 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan common.Hash),
 		headers:   headers,
+		details:   make(chan core.TxLifecycleEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -276,6 +287,25 @@ func (es *EventSystem) SubscribePendingTxEvents(hashes chan common.Hash) *Subscr
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		details:   make(chan core.TxLifecycleEvent),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribePendingTxDetails creates a subscription that writes the lifecycle
+// events of the transactions in the pool: entering it, being replaced or being
+// dropped.
+func (es *EventSystem) SubscribePendingTxDetails(details chan core.TxLifecycleEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       PendingTransactionDetailsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan common.Hash),
+		headers:   make(chan *types.Header),
+		details:   details,
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -320,6 +350,10 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 		for _, f := range filters[PendingTransactionsSubscription] {
 			f.hashes <- e.Tx.Hash()
 		}
+	case core.TxLifecycleEvent:
+		for _, f := range filters[PendingTransactionDetailsSubscription] {
+			f.details <- e
+		}
 	case core.ChainEvent:
 		for _, f := range filters[BlocksSubscription] {
 			f.headers <- e.Block.Header()
@@ -417,6 +451,9 @@ func (es *EventSystem) eventLoop() {
 		// Subscribe TxPreEvent form txpool
 		txCh  = make(chan core.TxPreEvent, txChanSize)
 		txSub = es.backend.SubscribeTxPreEvent(txCh)
+		// Subscribe TxLifecycleEvent form txpool
+		txEventCh  = make(chan core.TxLifecycleEvent, txEventChanSize)
+		txEventSub = es.backend.SubscribeTxLifecycleEvent(txEventCh)
 		// Subscribe RemovedLogsEvent
 		rmLogsCh  = make(chan core.RemovedLogsEvent, rmLogsChanSize)
 		rmLogsSub = es.backend.SubscribeRemovedLogsEvent(rmLogsCh)
@@ -431,6 +468,7 @@ func (es *EventSystem) eventLoop() {
 	// Unsubscribe all events
 	defer sub.Unsubscribe()
 	defer txSub.Unsubscribe()
+	defer txEventSub.Unsubscribe()
 	defer rmLogsSub.Unsubscribe()
 	defer logsSub.Unsubscribe()
 	defer chainEvSub.Unsubscribe()
@@ -450,6 +488,8 @@ func (es *EventSystem) eventLoop() {
 		// Handle subscribed events
 		case ev := <-txCh:
 			es.broadcast(index, ev)
+		case ev := <-txEventCh:
+			es.broadcast(index, ev)
 		case ev := <-rmLogsCh:
 			es.broadcast(index, ev)
 		case ev := <-logsCh:
@@ -479,6 +519,8 @@ func (es *EventSystem) eventLoop() {
 		// System stopped
 		case <-txSub.Err():
 			return
+		case <-txEventSub.Err():
+			return
 		case <-rmLogsSub.Err():
 			return
 		case <-logsSub.Err():
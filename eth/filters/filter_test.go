@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -56,7 +57,7 @@ func BenchmarkFilters(b *testing.B) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1      = crypto.PubkeyToAddress(key1.PublicKey)
 		addr2      = common.BytesToAddress([]byte("jeff"))
@@ -121,7 +122,7 @@ func TestFilters(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr       = crypto.PubkeyToAddress(key1.PublicKey)
 
@@ -240,4 +241,122 @@ func TestFilters(t *testing.T) {
 	if len(logs) != 0 {
 		t.Error("expected 0 log, got", len(logs))
 	}
+
+	// Queries spanning more blocks than allowed are rejected
+	filter = New(backend, 990, -1, []common.Address{addr}, [][]common.Hash{{hash3}})
+	filter.rangeLimit = 10
+	if _, err := filter.Logs(context.Background()); err == nil {
+		t.Error("expected range limit error")
+	} else if rangeErr, ok := err.(*RangeLimitError); !ok || rangeErr.Range != 11 || rangeErr.ErrorCode() != -32005 {
+		t.Errorf("range limit error mismatch: %v", err)
+	}
+	filter = New(backend, 990, 999, []common.Address{addr}, [][]common.Hash{{hash3}})
+	filter.rangeLimit = 10
+	if logs, err := filter.Logs(context.Background()); err != nil || len(logs) != 1 {
+		t.Errorf("query within the range limit failed: %v, %d logs", err, len(logs))
+	}
+}
+
+// logIndexBackend serves a log index of its sections of 8 blocks.
+type logIndexBackend struct {
+	*testBackend
+	sections uint64
+	lookups  int
+}
+
+func (b *logIndexBackend) LogIndexStatus() (uint64, uint64) {
+	return 8, b.sections
+}
+
+func (b *logIndexBackend) LogIndexBlocks(ctx context.Context, section uint64, key []byte) ([]uint64, error) {
+	b.lookups++
+	return core.GetLogIndex(b.db, section, core.GetCanonicalHash(b.db, (section+1)*8-1), key)
+}
+
+func TestFiltersLogIndex(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		backend = &logIndexBackend{
+			testBackend: &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)},
+			sections:    2,
+		}
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key1.PublicKey)
+		other   = common.BytesToAddress([]byte("other"))
+
+		hash1 = common.BytesToHash([]byte("topic1"))
+		hash2 = common.BytesToHash([]byte("topic2"))
+	)
+	emitted := map[int]*types.Log{
+		3:  {Address: addr, Topics: []common.Hash{hash1}},
+		9:  {Address: addr, Topics: []common.Hash{hash2}},
+		12: {Address: other, Topics: []common.Hash{hash1}},
+		13: {Address: other, Topics: []common.Hash{hash2, hash1}},
+		18: {Address: addr, Topics: []common.Hash{hash1}},
+	}
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 20, func(i int, gen *core.BlockGen) {
+		if log, ok := emitted[i+1]; ok {
+			log.BlockNumber = uint64(i + 1)
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{log}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			gen.AddUncheckedReceipt(receipt)
+		}
+	})
+	for i, block := range chain {
+		core.WriteBlock(db, block)
+		core.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		core.WriteHeadBlockHash(db, block.Hash())
+		core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the logs of the first two sections, the last blocks are unindexed
+	for section := uint64(0); section < backend.sections; section++ {
+		blocks := make(map[string][]uint64)
+		for number := section * 8; number < (section+1)*8; number++ {
+			if log, ok := emitted[int(number)]; ok {
+				key := string(core.LogIndexAddressKey(log.Address))
+				blocks[key] = append(blocks[key], number)
+				for i, topic := range log.Topics {
+					key := string(core.LogIndexTopicKey(i, topic))
+					blocks[key] = append(blocks[key], number)
+				}
+			}
+		}
+		head := core.GetCanonicalHash(db, (section+1)*8-1)
+		for key, numbers := range blocks {
+			core.WriteLogIndex(db, section, head, []byte(key), numbers)
+		}
+	}
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		blocks     []uint64
+	}{
+		{0, -1, []common.Address{addr}, [][]common.Hash{{hash1}}, []uint64{3, 18}},
+		{0, -1, nil, [][]common.Hash{{hash1}}, []uint64{3, 12, 18}},
+		{0, -1, []common.Address{addr}, [][]common.Hash{{hash1, hash2}}, []uint64{3, 9, 18}},
+		{0, -1, nil, [][]common.Hash{nil, {hash1}}, []uint64{13}},
+		{0, -1, []common.Address{addr, other}, [][]common.Hash{{hash2}}, []uint64{9, 13}},
+		{4, 12, nil, [][]common.Hash{{hash1}}, []uint64{12}},
+		{10, 11, []common.Address{addr}, nil, nil},
+	}
+	for i, tt := range tests {
+		backend.lookups = 0
+		logs, err := New(backend, tt.begin, tt.end, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to filter logs: %v", i, err)
+		}
+		var blocks []uint64
+		for _, log := range logs {
+			blocks = append(blocks, log.BlockNumber)
+		}
+		if !reflect.DeepEqual(blocks, tt.blocks) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, blocks, tt.blocks)
+		}
+		if backend.lookups == 0 {
+			t.Errorf("test %d: log index not used", i)
+		}
+	}
 }
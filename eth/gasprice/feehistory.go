@@ -0,0 +1,218 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxFeeHistory is the number of blocks kept by the fee history ring buffer,
+// which is also the largest range a single request may cover.
+const maxFeeHistory = 1024
+
+var errRequestBeyondHead = errors.New("request beyond head block")
+
+// txFee is the gas price paid by a transaction and the gas it used.
+type txFee struct {
+	price   *big.Int
+	gasUsed uint64
+}
+
+// blockFees holds the fee statistics of a processed block.
+type blockFees struct {
+	hash         common.Hash
+	gasUsedRatio float64
+	gasUsed      uint64  // Gas used by the user transactions
+	txs          []txFee // User transactions in ascending gas price order
+}
+
+// percentiles returns the gas prices at the given percentiles of the gas used
+// by the user transactions of the block.
+func (fees *blockFees) percentiles(percentiles []float64) []*big.Int {
+	prices := make([]*big.Int, len(percentiles))
+	if len(fees.txs) == 0 {
+		for i := range prices {
+			prices[i] = new(big.Int)
+		}
+		return prices
+	}
+	var (
+		index   int
+		gasUsed = fees.txs[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(fees.gasUsed) * p / 100)
+		for gasUsed < threshold && index < len(fees.txs)-1 {
+			index++
+			gasUsed += fees.txs[index].gasUsed
+		}
+		prices[i] = fees.txs[index].price
+	}
+	return prices
+}
+
+// FeeHistory returns the gas used ratio and the requested gas price percentiles
+// of up to blocks consecutive blocks ending at lastBlock, along with the number
+// of the oldest returned block. Percentiles are weighted by the gas used by the
+// transactions paying for gas, the system transactions of masternodes are left
+// out.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	for i, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, nil, nil, fmt.Errorf("invalid reward percentile: %f", p)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return nil, nil, nil, fmt.Errorf("invalid reward percentile: #%d:%f > #%d:%f", i-1, percentiles[i-1], i, p)
+		}
+	}
+	if blocks < 1 {
+		return common.Big0, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if head == nil {
+		return nil, nil, nil, errRequestBeyondHead
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	var (
+		prices       = make([][]*big.Int, blocks)
+		gasUsedRatio = make([]float64, blocks)
+	)
+	for i := range gasUsedRatio {
+		fees, err := gpo.blockFees(ctx, oldest+uint64(i))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		gasUsedRatio[i] = fees.gasUsedRatio
+		if len(percentiles) == 0 {
+			continue
+		}
+		prices[i] = fees.percentiles(percentiles)
+	}
+	if len(percentiles) == 0 {
+		prices = nil
+	}
+	return new(big.Int).SetUint64(oldest), prices, gasUsedRatio, nil
+}
+
+// updateHistory records the fee statistics of the new heads into the ring
+// buffer until the subscription ends.
+func (gpo *Oracle) updateHistory(heads <-chan core.ChainHeadEvent, sub event.Subscription) {
+	defer sub.Unsubscribe()
+
+	// Blocks are processed aside, not to stall the chain head feed. The heads
+	// arriving meanwhile are skipped and processed on request.
+	work := make(chan common.Hash, 1)
+	defer close(work)
+	go func() {
+		for hash := range work {
+			if _, err := gpo.recordBlock(context.Background(), hash); err != nil {
+				log.Debug("Failed to record block fees", "hash", hash, "err", err)
+			}
+		}
+	}()
+	for {
+		select {
+		case head := <-heads:
+			select {
+			case work <- head.Block.Hash():
+			default:
+			}
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// blockFees returns the fee statistics of a canonical block, serving them from
+// the ring buffer unless the slot holds another block or a reorged one.
+func (gpo *Oracle) blockFees(ctx context.Context, number uint64) (*blockFees, error) {
+	header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errRequestBeyondHead
+	}
+	gpo.historyLock.Lock()
+	fees := gpo.history[number%maxFeeHistory]
+	gpo.historyLock.Unlock()
+	if fees != nil && fees.hash == header.Hash() {
+		return fees, nil
+	}
+	return gpo.recordBlock(ctx, header.Hash())
+}
+
+// recordBlock computes the fee statistics of a block and stores them in the
+// ring buffer.
+func (gpo *Oracle) recordBlock(ctx context.Context, hash common.Hash) (*blockFees, error) {
+	block, err := gpo.backend.GetBlock(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errRequestBeyondHead
+	}
+	receipts, err := gpo.backend.GetReceipts(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts of block %x missing: have %d, want %d", hash, len(receipts), len(block.Transactions()))
+	}
+	fees := &blockFees{hash: hash}
+	if block.GasLimit() > 0 {
+		fees.gasUsedRatio = float64(block.GasUsed()) / float64(block.GasLimit())
+	}
+	for i, tx := range block.Transactions() {
+		if tx.IsSpecialTransaction() || tx.IsSkipNonceTransaction() {
+			continue
+		}
+		fees.txs = append(fees.txs, txFee{price: tx.GasPrice(), gasUsed: receipts[i].GasUsed})
+		fees.gasUsed += receipts[i].GasUsed
+	}
+	sort.Slice(fees.txs, func(i, j int) bool {
+		return fees.txs[i].price.Cmp(fees.txs[j].price) < 0
+	})
+
+	gpo.historyLock.Lock()
+	gpo.history[block.NumberU64()%maxFeeHistory] = fees
+	gpo.historyLock.Unlock()
+	return fees, nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend serves a chain of blocks to the oracle.
+type testBackend struct {
+	ethapi.Backend
+
+	lock     sync.Mutex
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	fetches  int // Blocks retrieved by the oracle
+	heads    event.Feed
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.blocks) - 1)
+	}
+	if int(number) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[number].Header(), nil
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.fetches++
+	for _, block := range b.blocks {
+		if block.Hash() == hash {
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.receipts[hash], nil
+}
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.heads.Subscribe(ch)
+}
+
+// push appends a block paying the given gas prices for the given gas, or
+// replaces the block at the same number.
+func (b *testBackend) push(number uint64, prices []int64, gas []uint64, extra byte) *types.Block {
+	var (
+		txs      types.Transactions
+		receipts types.Receipts
+		used     uint64
+	)
+	for i, price := range prices {
+		txs = append(txs, types.NewTransaction(uint64(i), common.Address{0x01}, new(big.Int), gas[i], big.NewInt(price), nil))
+		used += gas[i]
+		receipt := types.NewReceipt(nil, false, used)
+		receipt.GasUsed = gas[i]
+		receipts = append(receipts, receipt)
+	}
+	// System transactions are left out of the percentiles
+	txs = append(txs, types.NewTransaction(0, common.HexToAddress(common.BlockSigners), new(big.Int), 50000, big.NewInt(1000), nil))
+	receipts = append(receipts, &types.Receipt{GasUsed: 50000})
+	used += 50000
+
+	header := &types.Header{Number: new(big.Int).SetUint64(number), GasLimit: 2 * used, GasUsed: used, Extra: []byte{extra}}
+	block := types.NewBlock(header, txs, nil, receipts)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if number < uint64(len(b.blocks)) {
+		b.blocks[number] = block
+	} else {
+		b.blocks = append(b.blocks, block)
+	}
+	b.receipts[block.Hash()] = receipts
+	return block
+}
+
+func (b *testBackend) fetched() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.fetches
+}
+
+func newTestOracle() (*Oracle, *testBackend) {
+	backend := &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+	backend.push(0, nil, nil, 0)
+	return NewOracle(backend, Config{Blocks: 20, Percentile: 60}), backend
+}
+
+func TestFeeHistoryPercentiles(t *testing.T) {
+	gpo, backend := newTestOracle()
+	backend.push(1, []int64{3, 1, 2}, []uint64{21000, 21000, 100000}, 0)
+
+	oldest, prices, ratios, err := gpo.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, []float64{0, 10, 50, 90, 100})
+	if err != nil {
+		t.Fatalf("failed to get fee history: %v", err)
+	}
+	if oldest.Uint64() != 1 || len(prices) != 1 || len(ratios) != 1 || ratios[0] != 0.5 {
+		t.Fatalf("fee history mismatch: oldest %v, %d blocks, ratios %v", oldest, len(prices), ratios)
+	}
+	// The percentiles are weighted by the gas used by each transaction
+	want := []int64{1, 1, 2, 3, 3}
+	for i, price := range prices[0] {
+		if price.Int64() != want[i] {
+			t.Errorf("percentile %d mismatch: have %v, want %d", i, price, want[i])
+		}
+	}
+	if _, _, _, err := gpo.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, []float64{50, 10}); err == nil {
+		t.Errorf("decreasing percentiles accepted")
+	}
+}
+
+func TestFeeHistoryHeads(t *testing.T) {
+	gpo, backend := newTestOracle()
+	for backend.heads.Send(core.ChainHeadEvent{Block: backend.push(1, []int64{1}, []uint64{21000}, 0)}) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The new head is recorded without a request
+	for i := 0; backend.fetched() == 0; i++ {
+		if i == 1000 {
+			t.Fatalf("new head not recorded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, prices, _, err := gpo.FeeHistory(context.Background(), 1, 1, []float64{50}); err != nil || prices[0][0].Int64() != 1 {
+		t.Fatalf("recorded fees mismatch: have %v, %v, want 1", prices, err)
+	}
+	if fetches := backend.fetched(); fetches != 1 {
+		t.Errorf("recorded head retrieved again: %d retrievals", fetches)
+	}
+	// A reorged block is computed again
+	backend.push(1, []int64{7}, []uint64{21000}, 1)
+	if _, prices, _, err := gpo.FeeHistory(context.Background(), 1, 1, []float64{50}); err != nil || prices[0][0].Int64() != 7 {
+		t.Fatalf("reorged fees mismatch: have %v, %v, want 7", prices, err)
+	}
+}
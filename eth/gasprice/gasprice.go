@@ -23,6 +23,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
@@ -48,6 +49,9 @@ type Oracle struct {
 
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+
+	historyLock sync.Mutex
+	history     [maxFeeHistory]*blockFees // Ring buffer of processed blocks, indexed by number
 }
 
 // NewOracle returns a new oracle.
@@ -63,7 +67,7 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
-	return &Oracle{
+	gpo := &Oracle{
 		backend:     backend,
 		lastPrice:   params.Default,
 		checkBlocks: blocks,
@@ -71,6 +75,9 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 		maxBlocks:   blocks * 5,
 		percentile:  percent,
 	}
+	heads := make(chan core.ChainHeadEvent, 1)
+	go gpo.updateHistory(heads, backend.SubscribeChainHeadEvent(heads))
+	return gpo
 }
 
 // SuggestPrice returns the recommended gas price.
@@ -4,6 +4,7 @@ package eth
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -11,29 +12,52 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/light"
+	"github.com/ethereum/go-ethereum/miner"
 )
 
 var _ = (*configMarshaling)(nil)
 
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               uint64
-		SyncMode                downloader.SyncMode
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
-		DatabaseCache           int
-		Etherbase               common.Address `toml:",omitempty"`
-		MinerThreads            int            `toml:",omitempty"`
-		ExtraData               hexutil.Bytes  `toml:",omitempty"`
-		GasPrice                *big.Int
-		Ethash                  ethash.Config
-		TxPool                  core.TxPoolConfig
-		GPO                     gasprice.Config
-		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                uint64
+		SyncMode                 downloader.SyncMode
+		LightServ                int                      `toml:",omitempty"`
+		LightPeers               int                      `toml:",omitempty"`
+		Checkpoint               *light.TrustedCheckpoint `toml:",omitempty"`
+		SkipBcVersionCheck       bool                     `toml:"-"`
+		DatabaseHandles          int                      `toml:"-"`
+		DatabaseCache            int
+		Snapshot                 bool           `toml:",omitempty"`
+		LogIndex                 bool           `toml:",omitempty"`
+		LogsRangeLimit           uint64         `toml:",omitempty"`
+		Etherbase                common.Address `toml:",omitempty"`
+		MinerThreads             int            `toml:",omitempty"`
+		ExtraData                hexutil.Bytes  `toml:",omitempty"`
+		GasPrice                 *big.Int
+		OrderBudget              miner.OrderBudget
+		MinorityForkGuard        bool             `toml:",omitempty"`
+		MinorityForkGuardTimeout time.Duration    `toml:",omitempty"`
+		SignerWallets            []string         `toml:",omitempty"`
+		RemoteSigner             string           `toml:",omitempty"`
+		RemoteSignerAudit        string           `toml:",omitempty"`
+		VerifyRewards            bool             `toml:",omitempty"`
+		WatchdogTimeout          time.Duration    `toml:",omitempty"`
+		WatchdogRotatePeers      bool             `toml:",omitempty"`
+		DownloaderRequestTTL     time.Duration    `toml:",omitempty"`
+		DownloaderBlacklist      time.Duration    `toml:",omitempty"`
+		DownloaderMinThroughput  float64          `toml:",omitempty"`
+		CandidateWebhook         string           `toml:",omitempty"`
+		EventSinks               []string         `toml:",omitempty"`
+		OrderSigners             []common.Address `toml:",omitempty"`
+		TxSigners                []common.Address `toml:",omitempty"`
+		Ethash                   ethash.Config
+		TxPool                   core.TxPoolConfig
+		OrderPool                core.OrderPoolConfig
+		GPO                      gasprice.Config
+		EnablePreimageRecording  bool
+		DocRoot                  string `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -41,15 +65,36 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.Checkpoint = c.Checkpoint
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.Snapshot = c.Snapshot
+	enc.LogIndex = c.LogIndex
+	enc.LogsRangeLimit = c.LogsRangeLimit
 	enc.Etherbase = c.Etherbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
+	enc.OrderBudget = c.OrderBudget
+	enc.MinorityForkGuard = c.MinorityForkGuard
+	enc.MinorityForkGuardTimeout = c.MinorityForkGuardTimeout
+	enc.SignerWallets = c.SignerWallets
+	enc.RemoteSigner = c.RemoteSigner
+	enc.RemoteSignerAudit = c.RemoteSignerAudit
+	enc.VerifyRewards = c.VerifyRewards
+	enc.WatchdogTimeout = c.WatchdogTimeout
+	enc.WatchdogRotatePeers = c.WatchdogRotatePeers
+	enc.DownloaderRequestTTL = c.DownloaderRequestTTL
+	enc.DownloaderBlacklist = c.DownloaderBlacklist
+	enc.DownloaderMinThroughput = c.DownloaderMinThroughput
+	enc.CandidateWebhook = c.CandidateWebhook
+	enc.EventSinks = c.EventSinks
+	enc.OrderSigners = c.OrderSigners
+	enc.TxSigners = c.TxSigners
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
+	enc.OrderPool = c.OrderPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
@@ -58,23 +103,44 @@ func (c Config) MarshalTOML() (interface{}, error) {
 
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               *uint64
-		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
-		DatabaseCache           *int
-		Etherbase               *common.Address `toml:",omitempty"`
-		MinerThreads            *int            `toml:",omitempty"`
-		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
-		GasPrice                *big.Int
-		Ethash                  *ethash.Config
-		TxPool                  *core.TxPoolConfig
-		GPO                     *gasprice.Config
-		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                *uint64
+		SyncMode                 *downloader.SyncMode
+		LightServ                *int                     `toml:",omitempty"`
+		LightPeers               *int                     `toml:",omitempty"`
+		Checkpoint               *light.TrustedCheckpoint `toml:",omitempty"`
+		SkipBcVersionCheck       *bool                    `toml:"-"`
+		DatabaseHandles          *int                     `toml:"-"`
+		DatabaseCache            *int
+		Snapshot                 *bool           `toml:",omitempty"`
+		LogIndex                 *bool           `toml:",omitempty"`
+		LogsRangeLimit           *uint64         `toml:",omitempty"`
+		Etherbase                *common.Address `toml:",omitempty"`
+		MinerThreads             *int            `toml:",omitempty"`
+		ExtraData                *hexutil.Bytes  `toml:",omitempty"`
+		GasPrice                 *big.Int
+		OrderBudget              *miner.OrderBudget
+		MinorityForkGuard        *bool            `toml:",omitempty"`
+		MinorityForkGuardTimeout *time.Duration   `toml:",omitempty"`
+		SignerWallets            []string         `toml:",omitempty"`
+		RemoteSigner             *string          `toml:",omitempty"`
+		RemoteSignerAudit        *string          `toml:",omitempty"`
+		VerifyRewards            *bool            `toml:",omitempty"`
+		WatchdogTimeout          *time.Duration   `toml:",omitempty"`
+		WatchdogRotatePeers      *bool            `toml:",omitempty"`
+		DownloaderRequestTTL     *time.Duration   `toml:",omitempty"`
+		DownloaderBlacklist      *time.Duration   `toml:",omitempty"`
+		DownloaderMinThroughput  *float64         `toml:",omitempty"`
+		CandidateWebhook         *string          `toml:",omitempty"`
+		EventSinks               []string         `toml:",omitempty"`
+		OrderSigners             []common.Address `toml:",omitempty"`
+		TxSigners                []common.Address `toml:",omitempty"`
+		Ethash                   *ethash.Config
+		TxPool                   *core.TxPoolConfig
+		OrderPool                *core.OrderPoolConfig
+		GPO                      *gasprice.Config
+		EnablePreimageRecording  *bool
+		DocRoot                  *string `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -95,6 +161,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.Checkpoint != nil {
+		c.Checkpoint = dec.Checkpoint
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -104,6 +173,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.Snapshot != nil {
+		c.Snapshot = *dec.Snapshot
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.LogsRangeLimit != nil {
+		c.LogsRangeLimit = *dec.LogsRangeLimit
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -116,12 +194,63 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.OrderBudget != nil {
+		c.OrderBudget = *dec.OrderBudget
+	}
+	if dec.MinorityForkGuard != nil {
+		c.MinorityForkGuard = *dec.MinorityForkGuard
+	}
+	if dec.MinorityForkGuardTimeout != nil {
+		c.MinorityForkGuardTimeout = *dec.MinorityForkGuardTimeout
+	}
+	if dec.SignerWallets != nil {
+		c.SignerWallets = dec.SignerWallets
+	}
+	if dec.RemoteSigner != nil {
+		c.RemoteSigner = *dec.RemoteSigner
+	}
+	if dec.RemoteSignerAudit != nil {
+		c.RemoteSignerAudit = *dec.RemoteSignerAudit
+	}
+	if dec.VerifyRewards != nil {
+		c.VerifyRewards = *dec.VerifyRewards
+	}
+	if dec.WatchdogTimeout != nil {
+		c.WatchdogTimeout = *dec.WatchdogTimeout
+	}
+	if dec.WatchdogRotatePeers != nil {
+		c.WatchdogRotatePeers = *dec.WatchdogRotatePeers
+	}
+	if dec.DownloaderRequestTTL != nil {
+		c.DownloaderRequestTTL = *dec.DownloaderRequestTTL
+	}
+	if dec.DownloaderBlacklist != nil {
+		c.DownloaderBlacklist = *dec.DownloaderBlacklist
+	}
+	if dec.DownloaderMinThroughput != nil {
+		c.DownloaderMinThroughput = *dec.DownloaderMinThroughput
+	}
+	if dec.CandidateWebhook != nil {
+		c.CandidateWebhook = *dec.CandidateWebhook
+	}
+	if dec.EventSinks != nil {
+		c.EventSinks = dec.EventSinks
+	}
+	if dec.OrderSigners != nil {
+		c.OrderSigners = dec.OrderSigners
+	}
+	if dec.TxSigners != nil {
+		c.TxSigners = dec.TxSigners
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.OrderPool != nil {
+		c.OrderPool = *dec.OrderPool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/posv"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -79,6 +82,12 @@ type ProtocolManager struct {
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
+	mesh       *masternodeMesh // Connections between the masternodes, nil if not running the posv engine
+
+	tomoxPeer func(id discover.NodeID) bool // Reports whether a peer runs the TomoX service, preferred for order data
+
+	features         Features // Subsystems run by this node, advertised in the handshake
+	requiredFeatures Features // Subsystems the peers must run to be synced from
 
 	SubProtocols []p2p.Protocol
 
@@ -97,9 +106,11 @@ type ProtocolManager struct {
 
 	// wait group is used for graceful shutdowns during downloading
 	// and processing
-	wg           sync.WaitGroup
-	knownTxs     *lru.Cache
-	knowOrderTxs *lru.Cache
+	wg            sync.WaitGroup
+	knownTxs      *lru.Cache
+	knowOrderTxs  *lru.Cache
+	txRequests    *txRequests // Transactions requested from the peers announcing them
+	orderRequests *txRequests // Order transactions requested from the peers announcing them
 }
 
 // NewProtocolManagerEx add order pool to protocol
@@ -119,20 +130,22 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	knowOrderTxs, _ := lru.New(maxKnownOrderTxs)
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
-		networkId:    networkId,
-		eventMux:     mux,
-		txpool:       txpool,
-		blockchain:   blockchain,
-		chainconfig:  config,
-		peers:        newPeerSet(),
-		newPeerCh:    make(chan *peer),
-		noMorePeers:  make(chan struct{}),
-		txsyncCh:     make(chan *txsync),
-		quitSync:     make(chan struct{}),
-		knownTxs:     knownTxs,
-		knowOrderTxs: knowOrderTxs,
-		orderpool:    nil,
-		orderTxSub:   nil,
+		networkId:     networkId,
+		eventMux:      mux,
+		txpool:        txpool,
+		blockchain:    blockchain,
+		chainconfig:   config,
+		peers:         newPeerSet(),
+		newPeerCh:     make(chan *peer),
+		noMorePeers:   make(chan struct{}),
+		txsyncCh:      make(chan *txsync),
+		quitSync:      make(chan struct{}),
+		knownTxs:      knownTxs,
+		knowOrderTxs:  knowOrderTxs,
+		txRequests:    newTxRequests(),
+		orderRequests: newTxRequests(),
+		orderpool:     nil,
+		orderTxSub:    nil,
 	}
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -225,7 +238,9 @@ func (pm *ProtocolManager) removePeer(id string) {
 	log.Debug("Removing Ethereum peer", "peer", id)
 
 	// Unregister the peer from the downloader and Ethereum peer set
-	pm.downloader.UnregisterPeer(id)
+	if peer.Supports(pm.requiredFeatures) {
+		pm.downloader.UnregisterPeer(id)
+	}
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Warn("Peer removal failed", "peer", id, "err", err)
 	}
@@ -256,6 +271,10 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	// start sync handlers
 	go pm.syncer()
 	go pm.txsyncLoop()
+
+	if pm.mesh != nil {
+		pm.mesh.start()
+	}
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -267,6 +286,9 @@ func (pm *ProtocolManager) Stop() {
 	}
 
 	pm.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if pm.mesh != nil {
+		pm.mesh.stop()
+	}
 
 	// Quit the sync loop.
 	// After this send has completed, no new peers will be accepted.
@@ -308,7 +330,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), pm.features); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -323,14 +345,26 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 	defer pm.removePeer(p.id)
 	if err != p2p.ErrAddPairPeer {
-		// Register the peer in the downloader. If the downloader considers it banned, we disconnect
-		if err := pm.downloader.RegisterPeer(p.id, p.version, p); err != nil {
-			return err
+		// Register the peer in the downloader if it runs the subsystems needed to
+		// sync from it. If the downloader considers it banned, we disconnect
+		if p.Supports(pm.requiredFeatures) {
+			if err := pm.downloader.RegisterPeer(p.id, p.version, p); err != nil {
+				return err
+			}
+		} else {
+			p.Log().Debug("Ethereum peer lacks required features, not syncing from it", "features", p.features.Names(), "required", pm.requiredFeatures.Names())
 		}
 		// Propagate existing transactions. new transactions appearing
 		// after this will be sent via broadcasts.
 		pm.syncTransactions(p)
 
+		// Share the known masternode enodes
+		if pm.mesh != nil && p.version >= eth66 {
+			if err := pm.mesh.sync(p); err != nil {
+				return err
+			}
+		}
+
 		// If we're DAO hard-fork aware, validate any remote peer with regard to the hard-fork
 		if daoBlock := pm.chainconfig.DAOForkBlock; daoBlock != nil {
 			// Request the peer's DAO fork header for extra-data validation
@@ -373,6 +407,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	}
 	defer msg.Discard()
 
+	// Account the time spent serving the requests of the peer
+	if servedMsgs[msg.Code] {
+		defer p.stats.serve(time.Now())
+	}
 	// Handle the message depending on its contents
 	switch {
 	case msg.Code == StatusMsg:
@@ -547,18 +585,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		// Deliver them all to the downloader for queuing
 		trasactions := make([][]*types.Transaction, len(request))
 		uncles := make([][]*types.Header, len(request))
+		trades := make([][]*types.Trade, len(request))
 
 		for i, body := range request {
 			trasactions[i] = body.Transactions
 			uncles[i] = body.Uncles
+			trades[i] = body.Trades
 		}
 		// Filter out any explicitly requested bodies, deliver the rest to the downloader
 		filter := len(trasactions) > 0 || len(uncles) > 0
 		if filter {
-			trasactions, uncles = pm.fetcher.FilterBodies(p.id, trasactions, uncles, time.Now())
+			trasactions, uncles, trades = pm.fetcher.FilterBodies(p.id, trasactions, uncles, trades, time.Now())
 		}
 		if len(trasactions) > 0 || len(uncles) > 0 || !filter {
-			err := pm.downloader.DeliverBodies(p.id, trasactions, uncles)
+			err := pm.downloader.DeliverBodies(p.id, trasactions, uncles, trades)
 			if err != nil {
 				log.Debug("Failed to deliver bodies", "err", err)
 			}
@@ -690,7 +730,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		)
 		// Update the peers total difficulty if better than the previous
 		if _, td := p.Head(); trueTD.Cmp(td) > 0 {
-			p.SetHead(trueHead, trueTD)
+			p.SetHead(trueHead, request.Block.NumberU64()-1, trueTD)
 
 			// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
 			// a singe block (as the true TD is below the propagated block), however this
@@ -719,6 +759,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "transaction %d is nil", i)
 			}
 			p.MarkTransaction(tx.Hash())
+			pm.txRequests.delivered(tx.Hash())
 			exist, _ := pm.knownTxs.ContainsOrAdd(tx.Hash(), true)
 			if !exist {
 				unkownTxs = append(unkownTxs, tx)
@@ -729,6 +770,76 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		pm.txpool.AddRemotes(txs)
 
+	case p.version >= eth65 && msg.Code == NewTxHashesMsg:
+		// Transactions announced, make sure we have a valid and fresh chain to handle them
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(hashes) > maxTxAnnounces {
+			hashes = hashes[:maxTxAnnounces]
+		}
+		// Retrieve the unknown transactions not already requested from other peers
+		unknown := make([]common.Hash, 0, len(hashes))
+		for _, hash := range hashes {
+			p.MarkTransaction(hash)
+			if !pm.knownTxs.Contains(hash) {
+				unknown = append(unknown, hash)
+			}
+		}
+		unknown = pm.txRequests.schedule(unknown, time.Now())
+		for len(unknown) > 0 {
+			batch := unknown
+			if len(batch) > maxTxFetch {
+				batch = batch[:maxTxFetch]
+			}
+			if err := p.RequestTxs(batch); err != nil {
+				return err
+			}
+			unknown = unknown[len(batch):]
+		}
+
+	case p.version >= eth65 && msg.Code == GetTxsMsg:
+		// Decode the retrieval message
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		// Gather the pooled transactions until the fetch or network limits is reached
+		var (
+			bytes int
+			txs   types.Transactions
+		)
+		for _, hash := range hashes {
+			if len(txs) >= maxTxFetch || bytes >= softResponseLimit {
+				break
+			}
+			if tx := pm.txpool.Get(hash); tx != nil {
+				txs = append(txs, tx)
+				bytes += int(tx.Size())
+			}
+		}
+		if len(txs) > 0 {
+			return p.SendTransactions(txs)
+		}
+
+	case p.version >= eth66 && msg.Code == EnodeRecordsMsg:
+		var records []*posv.EnodeRecord
+		if err := msg.Decode(&records); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(records) > maxEnodeRecords {
+			return errResp(ErrDecode, "too many enode records: %d > %d", len(records), maxEnodeRecords)
+		}
+		if pm.mesh != nil {
+			if err := pm.mesh.handle(p, records); err != nil {
+				return errResp(ErrDecode, "invalid enode record: %v", err)
+			}
+		}
+
 	case msg.Code == OrderTxMsg:
 		// Transactions arrived, make sure we have a valid and fresh chain to handle them
 		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
@@ -739,25 +850,106 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&txs); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		var unkownOrderTxs []*types.OrderTransaction
-
 		for i, tx := range txs {
-			// Validate and mark the remote transaction
 			if tx == nil {
 				return errResp(ErrDecode, "transaction %d is nil", i)
 			}
+		}
+		// Order transactions are fee-less, only accept them at a limited rate
+		if allowed := p.orderLimiter.allow(len(txs), time.Now()); allowed < len(txs) {
+			propOrderLimitedMeter.Mark(int64(len(txs) - allowed))
+			log.Debug("Discarding order transactions over rate limit", "peer", p.id, "orders", len(txs), "allowed", allowed)
+			txs = txs[:allowed]
+		}
+		unknown := make([]bool, len(txs))
+		for i, tx := range txs {
+			// Mark the remote transaction
 			p.MarkOrderTransaction(tx.Hash())
+			pm.orderRequests.delivered(tx.Hash())
 			exist, _ := pm.knowOrderTxs.ContainsOrAdd(tx.Hash(), true)
 			if !exist {
-				unkownOrderTxs = append(unkownOrderTxs, tx)
+				unknown[i] = true
 			} else {
 				log.Trace("Discard known tx", "hash", tx.Hash(), "nonce", tx.Nonce())
 			}
 
 		}
 
-		if pm.orderpool != nil {
-			pm.orderpool.AddRemotes(txs)
+		if pm.orderpool != nil && len(txs) > 0 {
+			accepted, rejected := 0, 0
+			for i, err := range pm.orderpool.AddRemotes(txs) {
+				switch {
+				case err == nil:
+					accepted++
+				case unknown[i]:
+					rejected++
+				}
+			}
+			propOrderRejectedMeter.Mark(int64(rejected))
+			p.orderLimiter.processed(accepted, rejected)
+		}
+		if p.orderLimiter.dropped() {
+			propOrderFloodMeter.Mark(1)
+			return errResp(ErrOrderFlood, "order score below %d", orderScoreDrop)
+		}
+
+	case p.version >= eth64 && msg.Code == NewOrderTxHashesMsg:
+		// Order transactions announced, make sure we have a valid and fresh chain to handle them
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 || pm.orderpool == nil {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(hashes) > maxTxAnnounces {
+			hashes = hashes[:maxTxAnnounces]
+		}
+		// Retrieve the unknown transactions not already requested from other peers
+		unknown := make([]common.Hash, 0, len(hashes))
+		for _, hash := range hashes {
+			p.MarkOrderTransaction(hash)
+			if !pm.knowOrderTxs.Contains(hash) {
+				unknown = append(unknown, hash)
+			}
+		}
+		unknown = pm.orderRequests.schedule(unknown, time.Now())
+		for len(unknown) > 0 {
+			batch := unknown
+			if len(batch) > maxTxFetch {
+				batch = batch[:maxTxFetch]
+			}
+			if err := p.RequestOrderTxs(batch); err != nil {
+				return err
+			}
+			unknown = unknown[len(batch):]
+		}
+
+	case p.version >= eth64 && msg.Code == GetOrderTxsMsg:
+		// Decode the retrieval message
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if pm.orderpool == nil {
+			break
+		}
+		// Gather the pooled transactions until the fetch or network limits is reached
+		var (
+			bytes int
+			txs   types.OrderTransactions
+		)
+		for _, hash := range hashes {
+			if len(txs) >= maxTxFetch || bytes >= softResponseLimit {
+				break
+			}
+			if tx := pm.orderpool.Get(hash); tx != nil {
+				txs = append(txs, tx)
+				bytes += int(tx.Size())
+			}
+		}
+		if len(txs) > 0 {
+			return p.SendOrderTransactions(txs)
 		}
 
 	default:
@@ -799,27 +991,57 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 }
 
 // BroadcastTx will propagate a transaction to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. The peers speaking eth/65 only receive the
+// full transaction in a square root subset, the rest is sent an announcement
+// of its hash and retrieves it on demand.
 func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
 	// Broadcast transaction to a batch of peers not knowing about it
 	peers := pm.peers.PeersWithoutTx(hash)
-	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
+	var (
+		direct    = int(math.Sqrt(float64(len(peers))))
+		sent      int
+		announced int
+	)
 	for _, peer := range peers {
-		peer.SendTransactions(types.Transactions{tx})
+		if peer.version < eth65 || sent < direct {
+			peer.SendTransactions(types.Transactions{tx})
+			sent++
+		} else {
+			peer.SendTxHashes([]common.Hash{hash})
+			announced++
+		}
 	}
-	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers))
+	log.Trace("Broadcast transaction", "hash", hash, "recipients", sent, "announced", announced)
 }
 
 // OrderBroadcastTx will propagate a transaction to all peers which are not known to
-// already have the given transaction.
+// already have the given transaction. The peers speaking eth/64 only receive the
+// full transaction in a square root subset, the rest is sent an announcement
+// of its hash and retrieves it on demand. The subset is picked among the peers
+// running the TomoX service first.
 func (pm *ProtocolManager) OrderBroadcastTx(hash common.Hash, tx *types.OrderTransaction) {
 	// Broadcast transaction to a batch of peers not knowing about it
 	peers := pm.peers.OrderPeersWithoutTx(hash)
-	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
+	if pm.tomoxPeer != nil {
+		sort.SliceStable(peers, func(i, j int) bool {
+			return pm.tomoxPeer(peers[i].ID()) && !pm.tomoxPeer(peers[j].ID())
+		})
+	}
+	var (
+		direct    = int(math.Sqrt(float64(len(peers))))
+		sent      int
+		announced int
+	)
 	for _, peer := range peers {
-		peer.SendOrderTransactions(types.OrderTransactions{tx})
+		if peer.version < eth64 || sent < direct {
+			peer.SendOrderTransactions(types.OrderTransactions{tx})
+			sent++
+		} else {
+			peer.SendOrderTxHashes([]common.Hash{hash})
+			announced++
+		}
 	}
-	log.Trace("Broadcast order transaction", "hash", hash, "recipients", len(peers))
+	log.Trace("Broadcast order transaction", "hash", hash, "recipients", sent, "announced", announced)
 }
 
 // Mined broadcast loop
@@ -872,6 +1094,7 @@ type NodeInfo struct {
 	Genesis    common.Hash         `json:"genesis"`    // SHA3 hash of the host's genesis block
 	Config     *params.ChainConfig `json:"config"`     // Chain configuration for the fork rules
 	Head       common.Hash         `json:"head"`       // SHA3 hash of the host's best owned block
+	Features   []string            `json:"features"`   // Subsystems run by the host, advertised from eth/67
 }
 
 // NodeInfo retrieves some protocol metadata about the running host node.
@@ -883,5 +1106,6 @@ func (self *ProtocolManager) NodeInfo() *NodeInfo {
 		Genesis:    self.blockchain.Genesis().Hash(),
 		Config:     self.blockchain.Config(),
 		Head:       currentBlock.Hash(),
+		Features:   self.features.Names(),
 	}
 }
@@ -0,0 +1,87 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// LogIndexer implements a core.ChainIndexer, building up a precise index of the
+// blocks emitting logs of each address and each topic at each position. Unlike
+// the bloom bits it has no false positives, at the cost of a larger index.
+type LogIndexer struct {
+	db ethdb.Database // database instance to read receipts from and write index data into
+
+	section uint64              // Section is the section number being processed currently
+	head    common.Hash         // Head is the hash of the last header processed
+	blocks  map[string][]uint64 // Numbers of the blocks of the section, by log index key
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index of the
+// canonical chain for precise logs filtering.
+func NewLogIndexer(db ethdb.Database, size uint64) *core.ChainIndexer {
+	backend := &LogIndexer{
+		db: db,
+	}
+	table := ethdb.NewTable(db, string(core.LogIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	l.section, l.head, l.blocks = section, common.Hash{}, make(map[string][]uint64)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header's
+// receipts into the index.
+func (l *LogIndexer) Process(header *types.Header) {
+	number, hash := header.Number.Uint64(), header.Hash()
+	l.head = hash
+
+	if header.Bloom == (types.Bloom{}) {
+		return
+	}
+	add := func(key []byte) {
+		blocks := l.blocks[string(key)]
+		if len(blocks) == 0 || blocks[len(blocks)-1] != number {
+			l.blocks[string(key)] = append(blocks, number)
+		}
+	}
+	for _, receipt := range core.GetBlockReceipts(l.db, hash, number) {
+		for _, log := range receipt.Logs {
+			add(core.LogIndexAddressKey(log.Address))
+			for i, topic := range log.Topics {
+				add(core.LogIndexTopicKey(i, topic))
+			}
+		}
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+
+	for key, blocks := range l.blocks {
+		core.WriteLogIndex(batch, l.section, l.head, []byte(key), blocks)
+	}
+	return batch.Write()
+}
@@ -0,0 +1,69 @@
+package eth
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestLogIndexer(t *testing.T) {
+	var (
+		db, _ = ethdb.NewMemDatabase()
+		addr1 = common.HexToAddress("0x0a")
+		addr2 = common.HexToAddress("0x0b")
+		hash1 = common.HexToHash("0x01")
+		hash2 = common.HexToHash("0x02")
+	)
+	emitted := map[int][]*types.Log{
+		2: {{Address: addr1, Topics: []common.Hash{hash1}}, {Address: addr1, Topics: []common.Hash{hash1}}},
+		5: {{Address: addr2, Topics: []common.Hash{hash2, hash1}}},
+		7: {{Address: addr1, Topics: []common.Hash{hash2}}},
+	}
+	genesis := core.GenesisBlockForTesting(db, addr1, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 7, func(i int, gen *core.BlockGen) {
+		if logs, ok := emitted[i+1]; ok {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = logs
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			gen.AddUncheckedReceipt(receipt)
+		}
+	})
+	indexer := &LogIndexer{db: db}
+	indexer.Reset(0, common.Hash{})
+	indexer.Process(genesis.Header())
+	for i, block := range chain {
+		core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		indexer.Process(block.Header())
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit log index: %v", err)
+	}
+	head := chain[len(chain)-1].Hash()
+	tests := []struct {
+		key    []byte
+		blocks []uint64
+	}{
+		{core.LogIndexAddressKey(addr1), []uint64{2, 7}},
+		{core.LogIndexAddressKey(addr2), []uint64{5}},
+		{core.LogIndexTopicKey(0, hash1), []uint64{2}},
+		{core.LogIndexTopicKey(0, hash2), []uint64{5, 7}},
+		{core.LogIndexTopicKey(1, hash1), []uint64{5}},
+		{core.LogIndexTopicKey(1, hash2), nil},
+	}
+	for i, tt := range tests {
+		blocks, err := core.GetLogIndex(db, 0, head, tt.key)
+		if err != nil {
+			t.Fatalf("test %d: failed to read log index: %v", i, err)
+		}
+		if !reflect.DeepEqual(blocks, tt.blocks) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, blocks, tt.blocks)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package eth
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+const (
+	enodeRecordInterval = 900 // Blocks between two announcements of the enode of a masternode
+	maxEnodeRecords     = 256 // Maximum enode records handled from a single message
+)
+
+// masternodeMesh keeps the nodes of the current masternode set connected to
+// each other, to avoid missed sealing slots caused by poor connectivity. The
+// masternodes announce their enode in records signed with their sealing key,
+// relayed by all the eth/66 nodes, and dial the announced enodes of the other
+// masternodes as trusted peers, which are reserved peer slots.
+type masternodeMesh struct {
+	server *p2p.Server
+	engine *posv.Posv
+	chain  *core.BlockChain
+	peers  *peerSet
+
+	masternodes map[common.Address]struct{}          // Masternode set at the current head
+	records     map[common.Address]*posv.EnodeRecord // Latest enode records of the masternodes
+	meshed      map[common.Address]string            // Enodes of the masternodes dialed as trusted peers
+	announced   uint64                               // Block number our own enode was last announced at
+	lock        sync.Mutex
+
+	quit chan struct{}
+}
+
+func newMasternodeMesh(server *p2p.Server, engine *posv.Posv, chain *core.BlockChain, peers *peerSet) *masternodeMesh {
+	return &masternodeMesh{
+		server:      server,
+		engine:      engine,
+		chain:       chain,
+		peers:       peers,
+		masternodes: make(map[common.Address]struct{}),
+		records:     make(map[common.Address]*posv.EnodeRecord),
+		meshed:      make(map[common.Address]string),
+		quit:        make(chan struct{}),
+	}
+}
+
+func (m *masternodeMesh) start() {
+	go m.loop()
+}
+
+func (m *masternodeMesh) stop() {
+	close(m.quit)
+}
+
+// loop follows the masternode set along the chain head.
+func (m *masternodeMesh) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	m.update(m.chain.CurrentBlock())
+	for {
+		select {
+		case ev := <-heads:
+			m.update(ev.Block)
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// update refreshes the masternode set at a new head, announces our own enode
+// if we are a masternode and rearranges the mesh.
+func (m *masternodeMesh) update(head *types.Block) {
+	masternodes := m.engine.GetMasternodes(m.chain, head.Header())
+
+	m.lock.Lock()
+	changed := len(masternodes) != len(m.masternodes)
+	for _, masternode := range masternodes {
+		if _, ok := m.masternodes[masternode]; !ok {
+			changed = true
+		}
+	}
+	if changed {
+		m.masternodes = make(map[common.Address]struct{}, len(masternodes))
+		for _, masternode := range masternodes {
+			m.masternodes[masternode] = struct{}{}
+		}
+		for signer := range m.records {
+			if _, ok := m.masternodes[signer]; !ok {
+				delete(m.records, signer)
+			}
+		}
+	}
+	_, master := m.masternodes[m.engine.Signer()]
+	announce := master && (changed || m.announced == 0 || head.NumberU64() >= m.announced+enodeRecordInterval)
+	m.lock.Unlock()
+
+	if announce {
+		m.announce(head.NumberU64())
+	}
+	m.connect()
+}
+
+// announce signs a record of our own enode and sends it to the eth/66 peers.
+func (m *masternodeMesh) announce(number uint64) {
+	record, err := m.engine.SignEnodeRecord(m.server.Self().String(), number)
+	if err != nil {
+		log.Debug("Failed to sign masternode enode record", "err", err)
+		return
+	}
+	m.lock.Lock()
+	m.records[m.engine.Signer()] = record
+	m.announced = number
+	m.lock.Unlock()
+
+	for _, p := range m.peers.PeersWithProtocol(eth66) {
+		p.SendEnodeRecords([]*posv.EnodeRecord{record})
+	}
+	log.Debug("Announced masternode enode", "number", number)
+}
+
+// handle stores the enode records of the current masternodes newer than the
+// known ones, relays them to the other eth/66 peers and rearranges the mesh.
+func (m *masternodeMesh) handle(p *peer, records []*posv.EnodeRecord) error {
+	head := m.chain.CurrentBlock().NumberU64()
+
+	var fresh []*posv.EnodeRecord
+	m.lock.Lock()
+	for _, record := range records {
+		signer, err := record.Signer()
+		if err != nil {
+			m.lock.Unlock()
+			return err
+		}
+		if _, ok := m.masternodes[signer]; !ok {
+			continue
+		}
+		if known := m.records[signer]; known != nil && known.Number >= record.Number {
+			continue
+		}
+		if record.Number > head+enodeRecordInterval {
+			continue
+		}
+		if _, err := discover.ParseNode(record.Enode); err != nil {
+			p.Log().Debug("Invalid masternode enode", "masternode", signer, "enode", record.Enode, "err", err)
+			continue
+		}
+		m.records[signer] = record
+		fresh = append(fresh, record)
+	}
+	m.lock.Unlock()
+
+	if len(fresh) == 0 {
+		return nil
+	}
+	for _, peer := range m.peers.PeersWithProtocol(eth66) {
+		if peer != p {
+			peer.SendEnodeRecords(fresh)
+		}
+	}
+	m.connect()
+	return nil
+}
+
+// sync sends the known enode records to a new eth/66 peer.
+func (m *masternodeMesh) sync(p *peer) error {
+	m.lock.Lock()
+	records := make([]*posv.EnodeRecord, 0, len(m.records))
+	for _, record := range m.records {
+		records = append(records, record)
+	}
+	m.lock.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+	return p.SendEnodeRecords(records)
+}
+
+// connect dials the enodes of the other masternodes as trusted peers if we are
+// a masternode, and releases the ones no longer in the masternode set.
+func (m *masternodeMesh) connect() {
+	var added, removed []*discover.Node
+
+	m.lock.Lock()
+	self := m.engine.Signer()
+	_, master := m.masternodes[self]
+	for signer, enode := range m.meshed {
+		record := m.records[signer]
+		if !master || record == nil || record.Enode != enode {
+			node, _ := discover.ParseNode(enode)
+			removed = append(removed, node)
+			delete(m.meshed, signer)
+		}
+	}
+	if master {
+		for signer, record := range m.records {
+			if _, ok := m.meshed[signer]; ok || signer == self {
+				continue
+			}
+			node, err := discover.ParseNode(record.Enode)
+			if err != nil || node.ID == m.server.Self().ID {
+				continue
+			}
+			m.meshed[signer] = record.Enode
+			added = append(added, node)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, node := range removed {
+		log.Debug("Removing masternode from mesh", "node", node)
+		m.server.RemoveTrustedPeer(node)
+		m.server.RemovePeer(node)
+	}
+	for _, node := range added {
+		log.Debug("Adding masternode to mesh", "node", node)
+		m.server.AddTrustedPeer(node)
+		m.server.AddPeer(node)
+	}
+}
@@ -26,6 +26,9 @@ var (
 	propTxnInTrafficMeter     = metrics.NewRegisteredMeter("eth/prop/txns/in/traffic", nil)
 	propTxnOutPacketsMeter    = metrics.NewRegisteredMeter("eth/prop/txns/out/packets", nil)
 	propTxnOutTrafficMeter    = metrics.NewRegisteredMeter("eth/prop/txns/out/traffic", nil)
+	propOrderLimitedMeter     = metrics.NewRegisteredMeter("eth/prop/orders/in/limited", nil)
+	propOrderRejectedMeter    = metrics.NewRegisteredMeter("eth/prop/orders/in/rejected", nil)
+	propOrderFloodMeter       = metrics.NewRegisteredMeter("eth/prop/orders/in/flood", nil)
 	propHashInPacketsMeter    = metrics.NewRegisteredMeter("eth/prop/hashes/in/packets", nil)
 	propHashInTrafficMeter    = metrics.NewRegisteredMeter("eth/prop/hashes/in/traffic", nil)
 	propHashOutPacketsMeter   = metrics.NewRegisteredMeter("eth/prop/hashes/out/packets", nil)
@@ -0,0 +1,103 @@
+package eth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	orderRate  = 100  // Order transactions a peer may send per second on average
+	orderBurst = 2000 // Order transactions a peer may send at once after being idle
+
+	orderScoreAccepted = 0.01  // Score credited for each order accepted by the pool
+	orderScoreLimited  = -1    // Score debited for each order over the rate limit
+	orderScoreRejected = -1    // Score debited for each new order rejected by the pool
+	orderScoreRecovery = 1     // Score recovered per second while negative
+	orderScoreMax      = 100   // Highest score a peer can build up
+	orderScoreDrop     = -1000 // Score at which a peer is disconnected
+)
+
+// OrderScore is the reputation of a peer regarding the order transactions it
+// sends, which are fee-less and thus rate limited.
+type OrderScore struct {
+	Score    float64 `json:"score"`    // Reputation of the peer, disconnected when reaching the drop threshold
+	Accepted uint64  `json:"accepted"` // Orders accepted by the pool
+	Limited  uint64  `json:"limited"`  // Orders discarded over the rate limit
+	Rejected uint64  `json:"rejected"` // New orders rejected by the pool
+}
+
+// orderLimiter rate limits the order transactions of a peer with a token
+// bucket, and scores the peer on its behaviour.
+type orderLimiter struct {
+	allowance float64   // Orders the peer may still send right away
+	updated   time.Time // Last time the allowance and the score were refilled
+	score     OrderScore
+	lock      sync.Mutex
+}
+
+func newOrderLimiter(now time.Time) *orderLimiter {
+	return &orderLimiter{allowance: orderBurst, updated: now}
+}
+
+// refill credits the allowance and the negative scores for the time elapsed
+// since the last update.
+func (l *orderLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.updated).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	l.updated = now
+	l.allowance = math.Min(orderBurst, l.allowance+elapsed*orderRate)
+	if l.score.Score < 0 {
+		l.score.Score = math.Min(0, l.score.Score+elapsed*orderScoreRecovery)
+	}
+}
+
+// allow takes orders from the allowance of the peer and returns how many of
+// them fit in it. The peer is penalised for the others, which are discarded.
+func (l *orderLimiter) allow(orders int, now time.Time) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.refill(now)
+	allowed := int(math.Min(float64(orders), math.Floor(l.allowance)))
+	l.allowance -= float64(allowed)
+
+	if limited := orders - allowed; limited > 0 {
+		l.score.Limited += uint64(limited)
+		l.adjust(float64(limited) * orderScoreLimited)
+	}
+	return allowed
+}
+
+// processed scores the peer on the outcome of its orders in the pool.
+func (l *orderLimiter) processed(accepted, rejected int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.score.Accepted += uint64(accepted)
+	l.score.Rejected += uint64(rejected)
+	l.adjust(float64(accepted)*orderScoreAccepted + float64(rejected)*orderScoreRejected)
+}
+
+func (l *orderLimiter) adjust(delta float64) {
+	l.score.Score = math.Max(orderScoreDrop, math.Min(orderScoreMax, l.score.Score+delta))
+}
+
+// dropped reports whether the score of the peer reached the drop threshold.
+func (l *orderLimiter) dropped() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.score.Score <= orderScoreDrop
+}
+
+// Score returns the current reputation of the peer.
+func (l *orderLimiter) Score(now time.Time) OrderScore {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.refill(now)
+	return l.score
+}
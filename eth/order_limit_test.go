@@ -0,0 +1,46 @@
+package eth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newOrderLimiter(now)
+
+	// Bursts are accepted up to the allowance, the excess is penalised
+	if allowed := limiter.allow(orderBurst+10, now); allowed != orderBurst {
+		t.Fatalf("burst allowance mismatch: have %d, want %d", allowed, orderBurst)
+	}
+	if score := limiter.Score(now); score.Limited != 10 || score.Score != 10*orderScoreLimited {
+		t.Fatalf("limited score mismatch: have %+v", score)
+	}
+	// The allowance refills at the order rate and the score recovers
+	now = now.Add(time.Second)
+	if allowed := limiter.allow(orderRate*2, now); allowed != orderRate {
+		t.Fatalf("refilled allowance mismatch: have %d, want %d", allowed, orderRate)
+	}
+	if score := limiter.Score(now); score.Score != 10*orderScoreLimited+orderScoreRecovery+orderRate*orderScoreLimited {
+		t.Fatalf("recovered score mismatch: have %+v", score)
+	}
+	// Accepted orders build up reputation, rejected ones are penalised
+	now = now.Add(time.Hour)
+	if score := limiter.Score(now); score.Score != 0 {
+		t.Fatalf("score not recovered: have %+v", score)
+	}
+	limiter.processed(100, 0)
+	if score := limiter.Score(now); score.Accepted != 100 || score.Score != 100*orderScoreAccepted {
+		t.Fatalf("accepted score mismatch: have %+v", score)
+	}
+	if limiter.dropped() {
+		t.Fatalf("well behaved peer dropped")
+	}
+	limiter.processed(0, -orderScoreDrop*2)
+	if !limiter.dropped() {
+		t.Fatalf("flooding peer not dropped")
+	}
+	if score := limiter.Score(now); score.Score != orderScoreDrop {
+		t.Fatalf("score below drop threshold: have %+v", score)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -46,9 +47,12 @@ const (
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version    int      `json:"version"`    // Ethereum protocol version negotiated
-	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
-	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Version    int       `json:"version"`            // Ethereum protocol version negotiated
+	Difficulty *big.Int  `json:"difficulty"`         // Total difficulty of the peer's blockchain
+	Head       string    `json:"head"`               // SHA3 hash of the peer's best owned block
+	Number     uint64    `json:"number,omitempty"`   // Number of the peer's best owned block, once announced
+	Features   []string  `json:"features,omitempty"` // Subsystems advertised by the peer, nil before eth/67
+	Stats      PeerStats `json:"stats"`              // Statistics of the exchanges with the peer
 }
 
 type peer struct {
@@ -59,15 +63,20 @@ type peer struct {
 	pairRw p2p.MsgReadWriter
 
 	version  int         // Protocol version negotiated
+	features Features    // Subsystems advertised by the peer, from eth/67
 	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
 
-	head common.Hash
-	td   *big.Int
-	lock sync.RWMutex
+	head   common.Hash
+	number uint64 // Number of the head, zero until a block is announced
+	td     *big.Int
+	lock   sync.RWMutex
 
 	knownTxs      *set.Set // Set of transaction hashes known to be known by this peer
 	knownBlocks   *set.Set // Set of block hashes known to be known by this peer
 	knownOrderTxs *set.Set // Set of order transaction hashes known to be known by this peer
+
+	orderLimiter *orderLimiter // Rate limiter and reputation of the peer regarding order transactions
+	stats        peerStats     // Statistics of the exchanges with the peer
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -81,6 +90,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		knownTxs:      set.New(),
 		knownBlocks:   set.New(),
 		knownOrderTxs: set.New(),
+		orderLimiter:  newOrderLimiter(time.Now()),
 	}
 }
 
@@ -88,11 +98,27 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 func (p *peer) Info() *PeerInfo {
 	hash, td := p.Head()
 
-	return &PeerInfo{
+	p.lock.RLock()
+	number := p.number
+	p.lock.RUnlock()
+
+	info := &PeerInfo{
 		Version:    p.version,
 		Difficulty: td,
 		Head:       hash.Hex(),
+		Number:     number,
+		Stats:      p.stats.Stats(p.orderLimiter.Score(time.Now())),
+	}
+	if p.version >= eth67 {
+		info.Features = p.features.Names()
 	}
+	return info
+}
+
+// Supports reports whether the peer runs the required subsystems. Peers older
+// than eth/67 don't advertise theirs and are assumed to run them.
+func (p *peer) Supports(required Features) bool {
+	return p.version < eth67 || p.features.Has(required)
 }
 
 // Head retrieves a copy of the current head hash and total difficulty of the
@@ -105,12 +131,13 @@ func (p *peer) Head() (hash common.Hash, td *big.Int) {
 	return hash, new(big.Int).Set(p.td)
 }
 
-// SetHead updates the head hash and total difficulty of the peer.
-func (p *peer) SetHead(hash common.Hash, td *big.Int) {
+// SetHead updates the head hash, number and total difficulty of the peer.
+func (p *peer) SetHead(hash common.Hash, number uint64, td *big.Int) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
 	copy(p.head[:], hash[:])
+	p.number = number
 	p.td.Set(td)
 }
 
@@ -153,15 +180,52 @@ func (p *peer) SendTransactions(txs types.Transactions) error {
 	return p2p.Send(p.rw, TxMsg, txs)
 }
 
+// SendTxHashes announces the availability of transactions through a hash
+// notification, the peer retrieving the ones it is missing.
+func (p *peer) SendTxHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.knownTxs.Add(hash)
+	}
+	return p2p.Send(p.rw, NewTxHashesMsg, hashes)
+}
+
+// RequestTxs fetches a batch of announced transactions from the remote peer.
+func (p *peer) RequestTxs(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of transactions", "count", len(hashes))
+	return p2p.Send(p.rw, GetTxsMsg, hashes)
+}
+
+// SendEnodeRecords sends a batch of masternode enode records to the peer.
+func (p *peer) SendEnodeRecords(records []*posv.EnodeRecord) error {
+	return p2p.Send(p.rw, EnodeRecordsMsg, records)
+}
+
 // SendTransactions sends transactions to the peer and includes the hashes
 // in its transaction hash set for future reference.
 func (p *peer) SendOrderTransactions(txs types.OrderTransactions) error {
 	for _, tx := range txs {
 		p.knownOrderTxs.Add(tx.Hash())
 	}
+	p.stats.relayOrders(len(txs))
 	return p2p.Send(p.rw, OrderTxMsg, txs)
 }
 
+// SendOrderTxHashes announces the availability of order transactions through
+// a hash notification, the peer retrieving the ones it is missing.
+func (p *peer) SendOrderTxHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.knownOrderTxs.Add(hash)
+	}
+	return p2p.Send(p.rw, NewOrderTxHashesMsg, hashes)
+}
+
+// RequestOrderTxs fetches a batch of announced order transactions from the
+// remote peer.
+func (p *peer) RequestOrderTxs(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of order transactions", "count", len(hashes))
+	return p2p.Send(p.rw, GetOrderTxsMsg, hashes)
+}
+
 // SendNewBlockHashes announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -300,20 +364,25 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 }
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks, and from eth/67 the
+// features of the nodes.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, features Features) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
 
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+		status := &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
 			TD:              td,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
-		})
+		}
+		if p.version >= eth67 {
+			status.Features = features
+		}
+		errc <- p2p.Send(p.rw, StatusMsg, status)
 	}()
 	go func() {
 		errc <- p.readStatus(network, &status, genesis)
@@ -330,7 +399,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td, p.head = status.TD, status.CurrentBlock
+	p.td, p.head, p.features = status.TD, status.CurrentBlock, status.Features
 	return nil
 }
 
@@ -479,8 +548,38 @@ func (ps *peerSet) OrderPeersWithoutTx(hash common.Hash) []*peer {
 	return list
 }
 
-// BestPeer retrieves the known peer with the currently highest total difficulty.
-func (ps *peerSet) BestPeer() *peer {
+// PeersWithProtocol retrieves a list of peers speaking at least the given
+// protocol version.
+func (ps *peerSet) PeersWithProtocol(version int) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.version >= version {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// OrderScores retrieves the order transaction reputation of the peers, keyed
+// by node id.
+func (ps *peerSet) OrderScores() map[string]OrderScore {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	now := time.Now()
+	scores := make(map[string]OrderScore, len(ps.peers))
+	for _, p := range ps.peers {
+		scores[p.ID().String()] = p.orderLimiter.Score(now)
+	}
+	return scores
+}
+
+// BestPeer retrieves the known peer with the currently highest total difficulty
+// among the ones running the required subsystems.
+func (ps *peerSet) BestPeer(required Features) *peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
 
@@ -489,6 +588,9 @@ func (ps *peerSet) BestPeer() *peer {
 		bestTd   *big.Int
 	)
 	for _, p := range ps.peers {
+		if !p.Supports(required) {
+			continue
+		}
 		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
 			bestPeer, bestTd = p, td
 		}
@@ -0,0 +1,67 @@
+package eth
+
+import (
+	"sync"
+	"time"
+)
+
+// servedMsgs are the requests of the peers answered from the local chain and
+// pools, whose serving time is accounted to the peer.
+var servedMsgs = map[uint64]bool{
+	GetBlockHeadersMsg: true,
+	GetBlockBodiesMsg:  true,
+	GetNodeDataMsg:     true,
+	GetReceiptsMsg:     true,
+	GetTxsMsg:          true,
+	GetOrderTxsMsg:     true,
+}
+
+// PeerStats are the statistics of the exchanges with a peer.
+type PeerStats struct {
+	Served       uint64  `json:"served"`       // Requests of the peer served
+	ServeLatency float64 `json:"serveLatency"` // Average time spent serving a request of the peer, in milliseconds
+	OrderTxsIn   uint64  `json:"orderTxsIn"`   // Order transactions relayed by the peer and accepted by the pool
+	OrderTxsOut  uint64  `json:"orderTxsOut"`  // Order transactions relayed to the peer
+}
+
+// peerStats accumulates the statistics of the exchanges with a peer.
+type peerStats struct {
+	served      uint64        // Requests of the peer served
+	serveTime   time.Duration // Total time spent serving them
+	orderTxsOut uint64        // Order transactions relayed to the peer
+	lock        sync.Mutex
+}
+
+// serve accounts a request of the peer whose serving started at the given time.
+func (s *peerStats) serve(start time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.served++
+	s.serveTime += time.Since(start)
+}
+
+// relayOrders accounts order transactions relayed to the peer.
+func (s *peerStats) relayOrders(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.orderTxsOut += uint64(n)
+}
+
+// Stats returns the statistics accumulated so far, the order transactions
+// received being accounted by the order limiter.
+func (s *peerStats) Stats(score OrderScore) PeerStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := PeerStats{
+		Served:      s.served,
+		OrderTxsIn:  score.Accepted,
+		OrderTxsOut: s.orderTxsOut,
+	}
+	if s.served > 0 {
+		stats.ServeLatency = float64(s.serveTime) / float64(s.served) / float64(time.Millisecond)
+	}
+	return stats
+}
@@ -0,0 +1,47 @@
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// Tests that the requests served to a peer and the orders relayed with it are
+// reported in its statistics.
+func TestPeerStats(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	peer, _ := newTestPeer("peer", 63, pm, true)
+	defer peer.close()
+	defer pm.Stop()
+
+	query := &getBlockHeadersData{Origin: hashOrNumber{Number: 1}, Amount: 1}
+	if err := p2p.Send(peer.app, GetBlockHeadersMsg, query); err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	if err := p2p.ExpectMsg(peer.app, BlockHeadersMsg, []interface{}{pm.blockchain.GetHeaderByNumber(1)}); err != nil {
+		t.Fatalf("headers mismatch: %v", err)
+	}
+	// Non-request messages aren't accounted
+	if err := p2p.Send(peer.app, NewBlockHashesMsg, newBlockHashesData{}); err != nil {
+		t.Fatalf("failed to send announcement: %v", err)
+	}
+	var stats PeerStats
+	for i := 0; i < 100; i++ {
+		if stats = pm.peers.Peer(peer.id).Info().Stats; stats.Served > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats.Served != 1 || stats.ServeLatency <= 0 {
+		t.Errorf("served stats mismatch: have %d requests in %fms, want 1", stats.Served, stats.ServeLatency)
+	}
+	// The orders relayed in both directions are accounted
+	var s peerStats
+	s.relayOrders(3)
+	s.relayOrders(2)
+	if stats := s.Stats(OrderScore{Accepted: 7}); stats.OrderTxsIn != 7 || stats.OrderTxsOut != 5 || stats.Served != 0 || stats.ServeLatency != 0 {
+		t.Errorf("order stats mismatch: have %+v", stats)
+	}
+}
@@ -32,16 +32,20 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
+	eth65 = 65
+	eth66 = 66
+	eth67 = 67
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth67, eth66, eth65, eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{22, 22, 21, 19, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -62,6 +66,17 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to eth/64
+	NewOrderTxHashesMsg = 0x11
+	GetOrderTxsMsg      = 0x12
+
+	// Protocol messages belonging to eth/65
+	NewTxHashesMsg = 0x13
+	GetTxsMsg      = 0x14
+
+	// Protocol messages belonging to eth/66
+	EnodeRecordsMsg = 0x15
 )
 
 type errCode int
@@ -76,6 +91,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrOrderFlood
 )
 
 func (e errCode) String() string {
@@ -93,12 +109,16 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrOrderFlood:              "Order flood",
 }
 
 type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// Get should return the pooled transaction with the given hash, or nil.
+	Get(hash common.Hash) *types.Transaction
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -112,6 +132,9 @@ type orderPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.OrderTransaction) []error
 
+	// Get should return the pooled transaction with the given hash, or nil.
+	Get(hash common.Hash) *types.OrderTransaction
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.OrderTransactions, error)
@@ -128,6 +151,44 @@ type statusData struct {
 	TD              *big.Int
 	CurrentBlock    common.Hash
 	GenesisBlock    common.Hash
+	Features        Features `rlp:"optional"` // Subsystems run by the node, from eth/67
+}
+
+// Features is the set of TomoChain subsystems a node advertises in the eth/67
+// handshake, so that its peers can avoid syncing from nodes missing the ones
+// they need.
+type Features uint64
+
+const (
+	FeatureTomoX     Features = 1 << iota // Runs TomoX, serving the order data and the DEX states
+	FeatureTradeRoot                      // Follows the trade root fork, serving the trades of the blocks
+	FeatureLending                        // Runs the TomoX lending, serving the lending states
+)
+
+// featureNames are the names of the features as reported by the APIs.
+var featureNames = []struct {
+	feature Features
+	name    string
+}{
+	{FeatureTomoX, "tomox"},
+	{FeatureTradeRoot, "tradeRoot"},
+	{FeatureLending, "lending"},
+}
+
+// Has reports whether all the required features are in the set.
+func (f Features) Has(required Features) bool {
+	return f&required == required
+}
+
+// Names returns the names of the known features in the set.
+func (f Features) Names() []string {
+	names := []string{}
+	for _, feature := range featureNames {
+		if f.Has(feature.feature) {
+			names = append(names, feature.name)
+		}
+	}
+	return names
 }
 
 // newBlockHashesData is the network packet for the block announcements.
@@ -190,6 +251,7 @@ type newBlockData struct {
 type blockBody struct {
 	Transactions []*types.Transaction // Transactions contained within a block
 	Uncles       []*types.Header      // Uncles contained within a block
+	Trades       []*types.Trade       `rlp:"optional"` // Trades matched in a block, from the trade root fork
 }
 
 // blockBodiesData is the network packet for block content distribution.
@@ -18,6 +18,7 @@ package eth
 
 import (
 	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -39,6 +40,7 @@ var testAccount, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6
 // Tests that handshake failures are detected and reported correctly.
 func TestStatusMsgErrors62(t *testing.T) { testStatusMsgErrors(t, 62) }
 func TestStatusMsgErrors63(t *testing.T) { testStatusMsgErrors(t, 63) }
+func TestStatusMsgErrors67(t *testing.T) { testStatusMsgErrors(t, 67) }
 
 func testStatusMsgErrors(t *testing.T, protocol int) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
@@ -59,15 +61,15 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 			wantError: errResp(ErrNoStatusMsg, "first msg has code 2 (!= 0)"),
 		},
 		{
-			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), 0},
 			wantError: errResp(ErrProtocolVersionMismatch, "10 (!= %d)", protocol),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: statusData{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), 0},
 			wantError: errResp(ErrNetworkIdMismatch, "999 (!= 88)"),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}},
+			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, td, head.Hash(), common.Hash{3}, 0},
 			wantError: errResp(ErrGenesisBlockMismatch, "0300000000000000 (!= %x)", genesis.Hash().Bytes()[:8]),
 		},
 	}
@@ -92,9 +94,70 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that the features are exchanged from eth/67, and that the peers missing
+// the required ones aren't synced from.
+func TestStatusFeatures(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.features = FeatureTomoX | FeatureTradeRoot | FeatureLending
+	pm.requiredFeatures = FeatureTomoX | FeatureTradeRoot
+	defer pm.Stop()
+
+	var (
+		genesis = pm.blockchain.Genesis()
+		head    = pm.blockchain.CurrentHeader()
+		td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+	)
+	connect := func(name string, version int, features Features) *testPeer {
+		p, _ := newTestPeer(name, version, pm, false)
+		status := &statusData{uint32(version), DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), 0}
+		if version >= eth67 {
+			status.Features = pm.features
+		}
+		if err := p2p.ExpectMsg(p.app, StatusMsg, status); err != nil {
+			t.Fatalf("%s: status recv: %v", name, err)
+		}
+		status.Features = features
+		if err := p2p.Send(p.app, StatusMsg, status); err != nil {
+			t.Fatalf("%s: status send: %v", name, err)
+		}
+		for i := 0; pm.peers.Peer(p.id) == nil; i++ {
+			if i == 100 {
+				t.Fatalf("%s: peer not registered", name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return p
+	}
+	lacking := connect("lacking", eth67, FeatureTomoX)
+	defer lacking.close()
+
+	if names := lacking.Info().Features; !reflect.DeepEqual(names, []string{"tomox"}) {
+		t.Errorf("features mismatch: have %v, want [tomox]", names)
+	}
+	if lacking.Supports(pm.requiredFeatures) {
+		t.Errorf("peer lacking the trade root supported")
+	}
+	if best := pm.peers.BestPeer(pm.requiredFeatures); best != nil {
+		t.Errorf("sync peer mismatch: have %v, want none", best)
+	}
+	legacy := connect("legacy", eth66, 0)
+	defer legacy.close()
+
+	if names := legacy.Info().Features; names != nil {
+		t.Errorf("legacy features mismatch: have %v, want none", names)
+	}
+	if best := pm.peers.BestPeer(pm.requiredFeatures); best == nil || best.id != legacy.id {
+		t.Errorf("sync peer mismatch: have %v, want %v", best, legacy.peer)
+	}
+	if names := pm.NodeInfo().Features; !reflect.DeepEqual(names, []string{"tomox", "tradeRoot", "lending"}) {
+		t.Errorf("node features mismatch: have %v", names)
+	}
+}
+
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
+func TestRecvTransactions65(t *testing.T) { testRecvTransactions(t, 65) }
 
 func testRecvTransactions(t *testing.T, protocol int) {
 	txAdded := make(chan []*types.Transaction)
@@ -178,6 +241,73 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// Tests that eth/65 peers are announced the pending transactions, and can
+// retrieve them.
+func TestAnnounceTransactions65(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	alltxs := make([]*types.Transaction, 10)
+	for nonce := range alltxs {
+		alltxs[nonce] = newTestTransaction(testAccount, uint64(nonce), 0)
+	}
+	pm.txpool.AddRemotes(alltxs)
+
+	p, _ := newTestPeer("peer", eth65, pm, true)
+	defer p.close()
+
+	var announced []common.Hash
+	for len(announced) < len(alltxs) {
+		msg, err := p.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		if msg.Code != NewTxHashesMsg {
+			t.Fatalf("got code %d, want NewTxHashesMsg", msg.Code)
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			t.Fatalf("failed to decode announcement: %v", err)
+		}
+		announced = append(announced, hashes...)
+	}
+	if err := p2p.Send(p.app, GetTxsMsg, announced); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, TxMsg, alltxs); err != nil {
+		t.Errorf("transactions mismatch: %v", err)
+	}
+}
+
+// Tests that transactions announced by eth/65 peers are retrieved from them.
+func TestFetchTransactions65(t *testing.T) {
+	txAdded := make(chan []*types.Transaction)
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1 // mark synced to accept transactions
+	p, _ := newTestPeer("peer", eth65, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewTxHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetTxsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction not requested: %v", err)
+	}
+	if err := p2p.Send(p.app, TxMsg, []interface{}{tx}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Errorf("added transactions mismatch: have %v, want %v", added, tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("no TxPreEvent received within 2 seconds")
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
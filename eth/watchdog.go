@@ -0,0 +1,214 @@
+package eth
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// WatchdogStatus is the state of the chain watchdog.
+type WatchdogStatus struct {
+	Masternode bool        `json:"masternode"`
+	Mining     bool        `json:"mining"`
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	HeadAge    uint64      `json:"headAge"` // Seconds since the head was reached
+	Timeout    uint64      `json:"timeout"` // Seconds before the head is considered stuck
+	Stuck      bool        `json:"stuck"`
+	Alerts     int         `json:"alerts"`
+	Rotations  int         `json:"rotations"`
+	Peers      int         `json:"peers"`
+	Pending    int         `json:"pending"`
+}
+
+// chainWatchdog detects a chain head not advancing on a masternode. When the
+// head is stuck for longer than the timeout, it dumps diagnostics, re-broadcasts
+// the head to all the peers and optionally drops the peer with the lowest total
+// difficulty to make room for a fresh one.
+type chainWatchdog struct {
+	pm         *ProtocolManager
+	timeout    time.Duration
+	rotate     bool
+	mining     func() bool              // Whether the node is sealing blocks
+	masternode func(*types.Header) bool // Whether the node is a masternode at a head
+
+	head      *types.Block // Current head of the chain
+	headTime  time.Time    // Time the current head was reached
+	alerted   time.Time    // Time of the last alert on the current head
+	alerts    int          // Number of alerts raised
+	rotations int          // Number of peers dropped
+	lock      sync.Mutex
+
+	quit chan struct{}
+}
+
+func newChainWatchdog(pm *ProtocolManager, timeout time.Duration, rotate bool, mining func() bool, masternode func(*types.Header) bool) *chainWatchdog {
+	return &chainWatchdog{
+		pm:         pm,
+		timeout:    timeout,
+		rotate:     rotate,
+		mining:     mining,
+		masternode: masternode,
+		head:       pm.blockchain.CurrentBlock(),
+		headTime:   time.Now(),
+		quit:       make(chan struct{}),
+	}
+}
+
+func (w *chainWatchdog) start() {
+	go w.loop()
+}
+
+func (w *chainWatchdog) stop() {
+	close(w.quit)
+}
+
+// loop follows the chain head and checks it periodically.
+func (w *chainWatchdog) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := w.pm.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	interval := w.timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev := <-heads:
+			w.setHead(ev.Block, time.Now())
+		case now := <-ticker.C:
+			w.check(now)
+		case <-sub.Err():
+			return
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// setHead records a new chain head.
+func (w *chainWatchdog) setHead(head *types.Block, now time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.head != nil && w.head.Hash() == head.Hash() {
+		return
+	}
+	w.head, w.headTime, w.alerted = head, now, time.Time{}
+}
+
+// check raises an alert if the head of a masternode is stuck, at most once per
+// timeout.
+func (w *chainWatchdog) check(now time.Time) {
+	w.lock.Lock()
+	head, age := w.head, now.Sub(w.headTime)
+	if age < w.timeout || !w.masternode(head.Header()) || (!w.alerted.IsZero() && now.Sub(w.alerted) < w.timeout) {
+		w.lock.Unlock()
+		return
+	}
+	w.alerted = now
+	w.alerts++
+	w.lock.Unlock()
+
+	w.dump(head, age)
+	w.rebroadcast(head)
+	if w.rotate {
+		w.rotatePeer()
+	}
+}
+
+// dump logs the diagnostics of a stuck chain.
+func (w *chainWatchdog) dump(head *types.Block, age time.Duration) {
+	log.Warn("Chain head not advancing", "number", head.NumberU64(), "hash", head.Hash(), "age", common.PrettyDuration(age), "mining", w.mining(), "peers", w.pm.peers.Len(), "pending", w.pending())
+	for _, p := range w.pm.peers.PeersWithProtocol(0) {
+		hash, td := p.Head()
+		log.Warn("Peer of a stuck chain", "peer", p.id, "version", p.version, "head", hash, "td", td, "trusted", p.Peer.Info().Network.Trusted)
+	}
+}
+
+// rebroadcast sends the current head to all the peers, whether they are known
+// to have it or not.
+func (w *chainWatchdog) rebroadcast(head *types.Block) {
+	td := w.pm.blockchain.GetTd(head.Hash(), head.NumberU64())
+	if td == nil {
+		return
+	}
+	for _, p := range w.pm.peers.PeersWithProtocol(0) {
+		p.SendNewBlock(head, td)
+	}
+}
+
+// rotatePeer drops the untrusted peer with the lowest total difficulty, so
+// that a new peer can take its slot.
+func (w *chainWatchdog) rotatePeer() {
+	var (
+		worst   *peer
+		worstTd *big.Int
+	)
+	peers := w.pm.peers.PeersWithProtocol(0)
+	if len(peers) < 2 {
+		return
+	}
+	for _, p := range peers {
+		if p.Peer.Info().Network.Trusted {
+			continue
+		}
+		if _, td := p.Head(); worst == nil || td.Cmp(worstTd) < 0 {
+			worst, worstTd = p, td
+		}
+	}
+	if worst == nil {
+		return
+	}
+	log.Warn("Dropping peer of a stuck chain", "peer", worst.id, "td", worstTd)
+	w.pm.removePeer(worst.id)
+
+	w.lock.Lock()
+	w.rotations++
+	w.lock.Unlock()
+}
+
+// pending counts the executable transactions of the pool.
+func (w *chainWatchdog) pending() int {
+	pending, err := w.pm.txpool.Pending()
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, txs := range pending {
+		count += len(txs)
+	}
+	return count
+}
+
+// Status retrieves the current state of the watchdog.
+func (w *chainWatchdog) Status() WatchdogStatus {
+	w.lock.Lock()
+	head, headTime, alerts, rotations := w.head, w.headTime, w.alerts, w.rotations
+	w.lock.Unlock()
+
+	age := time.Since(headTime)
+	masternode := w.masternode(head.Header())
+	return WatchdogStatus{
+		Masternode: masternode,
+		Mining:     w.mining(),
+		Number:     head.NumberU64(),
+		Hash:       head.Hash(),
+		HeadAge:    uint64(age / time.Second),
+		Timeout:    uint64(w.timeout / time.Second),
+		Stuck:      masternode && age >= w.timeout,
+		Alerts:     alerts,
+		Rotations:  rotations,
+		Peers:      w.pm.peers.Len(),
+		Pending:    w.pending(),
+	}
+}
@@ -0,0 +1,46 @@
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+)
+
+func TestChainWatchdog(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 2, nil, nil)
+	defer pm.Stop()
+
+	master := true
+	watchdog := newChainWatchdog(pm, time.Minute, true, func() bool { return true }, func(*types.Header) bool { return master })
+	start := watchdog.headTime
+
+	// No alert is raised before the timeout
+	watchdog.check(start.Add(30 * time.Second))
+	if status := watchdog.Status(); status.Alerts != 0 {
+		t.Fatalf("alert raised before the timeout: %+v", status)
+	}
+	// A stuck head raises a single alert per timeout
+	watchdog.check(start.Add(time.Minute))
+	watchdog.check(start.Add(90 * time.Second))
+	if status := watchdog.Status(); status.Alerts != 1 {
+		t.Fatalf("alert count mismatch: have %d, want 1", status.Alerts)
+	}
+	watchdog.check(start.Add(2 * time.Minute))
+	if status := watchdog.Status(); status.Alerts != 2 {
+		t.Fatalf("alert count mismatch: have %d, want 2", status.Alerts)
+	}
+	// A new head resets the timeout
+	watchdog.setHead(pm.blockchain.GetBlockByNumber(1), start.Add(2*time.Minute))
+	watchdog.check(start.Add(150 * time.Second))
+	if status := watchdog.Status(); status.Alerts != 2 || status.Number != 1 {
+		t.Fatalf("alert raised after a new head: %+v", status)
+	}
+	// Nodes out of the masternode set are not watched
+	master = false
+	watchdog.check(start.Add(time.Hour))
+	if status := watchdog.Status(); status.Alerts != 2 || status.Stuck {
+		t.Fatalf("alert raised on a non masternode: %+v", status)
+	}
+}
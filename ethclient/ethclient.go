@@ -486,10 +486,8 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
 }
 
-// SendOrderTransaction injects a signed transaction into the pending pool for execution.
-//
-// If the transaction was a contract creation use the TransactionReceipt method to get the
-// contract address after the transaction has been mined.
+// SendOrderTransaction injects a signed order transaction into the TomoX order pool
+// to be matched.
 func (ec *Client) SendOrderTransaction(ctx context.Context, tx *types.OrderTransaction) error {
 	data, err := rlp.EncodeToBytes(tx)
 	if err != nil {
@@ -0,0 +1,231 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// SignerReward is the reward of a masternode for the blocks it signed in a
+// reward checkpoint.
+type SignerReward struct {
+	Sign   uint64   `json:"sign"`
+	Reward *big.Int `json:"reward"`
+}
+
+// CheckpointRewards are the rewards distributed at a checkpoint block.
+type CheckpointRewards struct {
+	Signers map[common.Address]*SignerReward               `json:"signers"`
+	Rewards map[common.Address]map[common.Address]*big.Int `json:"rewards"` // Rewards of the holders, by masternode
+}
+
+// CandidateStatus is the status of a candidate at an epoch, one of MASTERNODE,
+// SLASHED or PROPOSED, or empty if the address is not a candidate.
+type CandidateStatus struct {
+	Status   string   `json:"status"`
+	Capacity *big.Int `json:"capacity"`
+	Epoch    int64    `json:"epoch"`
+	Success  bool     `json:"success"`
+}
+
+// CandidatesArgs are the sorting and pagination arguments of Candidates.
+type CandidatesArgs struct {
+	SortBy string `json:"sortBy"` // Either "capacity" (default), "voters" or "address"
+	Asc    bool   `json:"asc"`    // Sort in ascending instead of descending order
+	Offset uint64 `json:"offset"` // Number of sorted candidates to skip
+	Limit  uint64 `json:"limit"`  // Maximum number of candidates returned, all if zero
+}
+
+// CandidateInfo is the voting state of a single candidate at a checkpoint.
+type CandidateInfo struct {
+	Address  common.Address `json:"address"`
+	Owner    common.Address `json:"owner"`
+	Capacity *big.Int       `json:"capacity"`
+	Voters   uint64         `json:"voters"`
+	Status   string         `json:"status"`
+}
+
+// CandidatesPage is a sorted page of the candidates at a checkpoint.
+type CandidatesPage struct {
+	Epoch      uint64           `json:"epoch"`
+	Checkpoint uint64           `json:"checkpoint"`
+	Total      uint64           `json:"total"`
+	Candidates []*CandidateInfo `json:"candidates"`
+}
+
+// PriceVolume is the best price of a side of an order book and the volume
+// offered at it.
+type PriceVolume struct {
+	Price  *big.Int `json:"price"`
+	Volume *big.Int `json:"volume"`
+
+	NormalizedPrice  string `json:"normalizedPrice"`  // Price in whole quote tokens
+	NormalizedVolume string `json:"normalizedVolume"` // Volume in whole base tokens
+}
+
+// PairInfo describes a trading pair listed by a relayer.
+type PairInfo struct {
+	BaseToken     common.Address `json:"baseToken"`
+	QuoteToken    common.Address `json:"quoteToken"`
+	OrderBook     common.Hash    `json:"orderBook"`
+	BaseDecimals  *uint8         `json:"baseDecimals"`
+	QuoteDecimals *uint8         `json:"quoteDecimals"`
+	Relayer       common.Address `json:"relayer"`
+	ListedBlock   hexutil.Uint64 `json:"listedBlock"`
+}
+
+// RewardByHash returns the rewards distributed at a checkpoint block, nil if
+// the node doesn't store them or the block is not a reward checkpoint.
+func (ec *Client) RewardByHash(ctx context.Context, hash common.Hash) (*CheckpointRewards, error) {
+	var rewards *CheckpointRewards
+	if err := ec.c.CallContext(ctx, &rewards, "eth_getRewardByHash", hash); err != nil {
+		return nil, err
+	}
+	if rewards == nil || (rewards.Signers == nil && rewards.Rewards == nil) {
+		return nil, nil
+	}
+	return rewards, nil
+}
+
+// VotersCap returns the capacity the given voters staked on a masternode at a
+// checkpoint block.
+func (ec *Client) VotersCap(ctx context.Context, checkpoint *big.Int, masternode common.Address, voters []common.Address) (map[common.Address]*big.Int, error) {
+	var caps map[common.Address]*big.Int
+	err := ec.c.CallContext(ctx, &caps, "posv_getVotersCap", toBlockNumArg(checkpoint), masternode, voters)
+	return caps, err
+}
+
+// CandidateStatus returns the status of a candidate at an epoch. If epoch is
+// nil, the status at the latest epoch is returned.
+func (ec *Client) CandidateStatus(ctx context.Context, candidate common.Address, epoch *big.Int) (*CandidateStatus, error) {
+	var status CandidateStatus
+	if err := ec.c.CallContext(ctx, &status, "eth_getCandidateStatus", candidate, toEpochNumArg(epoch)); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Candidates returns a sorted page of the candidates at the checkpoint of an
+// epoch. If epoch is nil, the candidates at the latest epoch are returned.
+func (ec *Client) Candidates(ctx context.Context, epoch *big.Int, args CandidatesArgs) (*CandidatesPage, error) {
+	var page CandidatesPage
+	if err := ec.c.CallContext(ctx, &page, "posv_getCandidates", toEpochNumArg(epoch), args); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// StakerROI returns the yearly return of staking, in percent, estimated from
+// the rewards of the last epoch.
+func (ec *Client) StakerROI(ctx context.Context) (float64, error) {
+	var roi float64
+	err := ec.c.CallContext(ctx, &roi, "eth_getStakerROI")
+	return roi, err
+}
+
+// StakerROIMasternode returns the yearly return of staking on a masternode, in
+// percent, estimated from its rewards of the last epoch.
+func (ec *Client) StakerROIMasternode(ctx context.Context, masternode common.Address) (float64, error) {
+	var roi float64
+	err := ec.c.CallContext(ctx, &roi, "eth_getStakerROIMasternode", masternode)
+	return roi, err
+}
+
+// OrderCount returns the nonce of the next order of an account.
+func (ec *Client) OrderCount(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	err := ec.c.CallContext(ctx, &result, "tomox_getOrderCount", account)
+	return uint64(result), err
+}
+
+// BestBid returns the highest bid of the order book of a pair.
+func (ec *Client) BestBid(ctx context.Context, baseToken, quoteToken common.Address) (*PriceVolume, error) {
+	return ec.bestPrice(ctx, "tomox_getBestBid", baseToken, quoteToken)
+}
+
+// BestAsk returns the lowest ask of the order book of a pair.
+func (ec *Client) BestAsk(ctx context.Context, baseToken, quoteToken common.Address) (*PriceVolume, error) {
+	return ec.bestPrice(ctx, "tomox_getBestAsk", baseToken, quoteToken)
+}
+
+func (ec *Client) bestPrice(ctx context.Context, method string, baseToken, quoteToken common.Address) (*PriceVolume, error) {
+	var result PriceVolume
+	if err := ec.c.CallContext(ctx, &result, method, baseToken, quoteToken); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BidTree returns the bids of the order book of a pair, by decimal price.
+func (ec *Client) BidTree(ctx context.Context, baseToken, quoteToken common.Address) (tomox_state.DumpOrderTree, error) {
+	var result tomox_state.DumpOrderTree
+	err := ec.c.CallContext(ctx, &result, "tomox_getBidTree", baseToken, quoteToken)
+	return result, err
+}
+
+// AskTree returns the asks of the order book of a pair, by decimal price.
+func (ec *Client) AskTree(ctx context.Context, baseToken, quoteToken common.Address) (tomox_state.DumpOrderTree, error) {
+	var result tomox_state.DumpOrderTree
+	err := ec.c.CallContext(ctx, &result, "tomox_getAskTree", baseToken, quoteToken)
+	return result, err
+}
+
+// OrderBookDiff returns the price levels of the order book of a pair added,
+// removed or changed between two blocks, the latest one if toBlock is nil.
+func (ec *Client) OrderBookDiff(ctx context.Context, baseToken, quoteToken common.Address, fromBlock, toBlock *big.Int) (*tomox_state.OrderBookDiff, error) {
+	var diff *tomox_state.OrderBookDiff
+	err := ec.c.CallContext(ctx, &diff, "tomox_getOrderBookDiff", baseToken, quoteToken, toBlockNumArg(fromBlock), toBlockNumArg(toBlock))
+	return diff, err
+}
+
+// OrderByID returns an order resting in the order book of a pair.
+func (ec *Client) OrderByID(ctx context.Context, baseToken, quoteToken common.Address, orderID uint64) (*tomox_state.OrderItem, error) {
+	var order *tomox_state.OrderItem
+	if err := ec.c.CallContext(ctx, &order, "tomox_getOrderById", baseToken, quoteToken, orderID); err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ethereum.NotFound
+	}
+	return order, nil
+}
+
+// OpenOrders returns the orders of an account resting in the order books.
+func (ec *Client) OpenOrders(ctx context.Context, account common.Address) ([]tomox_state.OrderItem, error) {
+	var orders []tomox_state.OrderItem
+	err := ec.c.CallContext(ctx, &orders, "tomox_getOpenOrders", account)
+	return orders, err
+}
+
+// Pairs returns the trading pairs listed by the relayers.
+func (ec *Client) Pairs(ctx context.Context) ([]PairInfo, error) {
+	var pairs []PairInfo
+	err := ec.c.CallContext(ctx, &pairs, "tomox_getPairs")
+	return pairs, err
+}
+
+func toEpochNumArg(epoch *big.Int) string {
+	if epoch == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(epoch)
+}
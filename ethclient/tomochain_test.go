@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+var (
+	testMasternode = common.HexToAddress("0x0a")
+	testVoter      = common.HexToAddress("0x0b")
+	testRewardHash = common.HexToHash("0x01")
+)
+
+// EthTestService answers the eth namespace calls the way the node APIs do. The
+// test services are exported, as the rpc server only registers exported types.
+type EthTestService struct{}
+
+func (s *EthTestService) GetRewardByHash(hash common.Hash) map[string]interface{} {
+	if hash != testRewardHash {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"signers": map[common.Address]interface{}{
+			testMasternode: map[string]interface{}{"sign": 3, "reward": big.NewInt(100)},
+		},
+		"rewards": map[common.Address]interface{}{
+			testMasternode: map[common.Address]*big.Int{testVoter: big.NewInt(40)},
+		},
+	}
+}
+
+type PosvTestService struct{}
+
+func (s *PosvTestService) GetVotersCap(checkpoint rpc.BlockNumber, masternode common.Address, voters []common.Address) map[common.Address]*big.Int {
+	caps := make(map[common.Address]*big.Int)
+	for i, voter := range voters {
+		caps[voter] = big.NewInt(int64(checkpoint) + int64(i))
+	}
+	return caps
+}
+
+type TomoXTestService struct{}
+
+func (s *TomoXTestService) GetBidTree(baseToken, quoteToken common.Address) tomox_state.DumpOrderTree {
+	return tomox_state.DumpOrderTree{
+		"110": {Volume: big.NewInt(5), Orders: map[string]*big.Int{"1": big.NewInt(5)}},
+	}
+}
+
+func (s *TomoXTestService) GetOrderBookDiff(baseToken, quoteToken common.Address, fromBlock, toBlock rpc.BlockNumber) *tomox_state.OrderBookDiff {
+	return &tomox_state.OrderBookDiff{
+		Added:   []tomox_state.PriceLevelChange{{Side: tomox_state.Ask, Price: big.NewInt(int64(toBlock)), Volume: big.NewInt(2)}},
+		Removed: []tomox_state.PriceLevelChange{{Side: tomox_state.Bid, Price: big.NewInt(int64(fromBlock)), Volume: new(big.Int)}},
+		Changed: []tomox_state.PriceLevelChange{},
+	}
+}
+
+func newTestTomoClient(t *testing.T) *rpc.Client {
+	server := rpc.NewServer()
+	for name, service := range map[string]interface{}{"eth": new(EthTestService), "posv": new(PosvTestService), "tomox": new(TomoXTestService)} {
+		if err := server.RegisterName(name, service); err != nil {
+			t.Fatalf("failed to register %s service: %v", name, err)
+		}
+	}
+	return rpc.DialInProc(server)
+}
+
+func TestTomoChainRPCs(t *testing.T) {
+	rpcClient := newTestTomoClient(t)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+	ctx := context.Background()
+
+	rewards, err := client.RewardByHash(ctx, testRewardHash)
+	if err != nil {
+		t.Fatalf("failed to get rewards: %v", err)
+	}
+	if signer := rewards.Signers[testMasternode]; signer == nil || signer.Sign != 3 || signer.Reward.Int64() != 100 {
+		t.Errorf("signer reward mismatch: have %+v", signer)
+	}
+	if reward := rewards.Rewards[testMasternode][testVoter]; reward == nil || reward.Int64() != 40 {
+		t.Errorf("voter reward mismatch: have %v, want 40", reward)
+	}
+	if rewards, err := client.RewardByHash(ctx, common.Hash{}); rewards != nil || err != nil {
+		t.Errorf("rewards of a block without rewards: have %v/%v, want nil", rewards, err)
+	}
+
+	caps, err := client.VotersCap(ctx, big.NewInt(900), testMasternode, []common.Address{testMasternode, testVoter})
+	if err != nil {
+		t.Fatalf("failed to get voters cap: %v", err)
+	}
+	if len(caps) != 2 || caps[testMasternode].Int64() != 900 || caps[testVoter].Int64() != 901 {
+		t.Errorf("voters cap mismatch: have %v", caps)
+	}
+
+	bids, err := client.BidTree(ctx, common.Address{}, common.Address{})
+	if err != nil {
+		t.Fatalf("failed to get bid tree: %v", err)
+	}
+	if len(bids) != 1 {
+		t.Fatalf("bid tree size mismatch: have %d, want 1", len(bids))
+	}
+	if list, ok := bids["110"]; !ok || list.Volume.Int64() != 5 || list.Orders["1"].Int64() != 5 {
+		t.Errorf("bid mismatch: have %+v", bids)
+	}
+
+	diff, err := client.OrderBookDiff(ctx, common.Address{}, common.Address{}, big.NewInt(10), big.NewInt(20))
+	if err != nil {
+		t.Fatalf("failed to get order book diff: %v", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Price.Int64() != 20 || diff.Added[0].Volume.Int64() != 2 {
+		t.Errorf("added levels mismatch: have %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Side != tomox_state.Bid || diff.Removed[0].Price.Int64() != 10 {
+		t.Errorf("removed levels mismatch: have %+v", diff.Removed)
+	}
+}
@@ -0,0 +1,53 @@
+package eventsink
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var errMissingTopic = errors.New("missing topic in event sink url")
+
+func init() {
+	Register("kafka", newKafkaSink)
+}
+
+// kafkaSink produces the events to a Kafka topic through a Kafka REST proxy,
+// keyed by block hash, e.g. kafka://localhost:8082/tomochain-events.
+type kafkaSink struct {
+	url    string
+	client *http.Client
+}
+
+// kafkaRecords is the body of a produce request of the REST proxy.
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value *Event `json:"value"`
+}
+
+func newKafkaSink(u *url.URL) (Sink, error) {
+	topic := strings.Trim(u.Path, "/")
+	if topic == "" {
+		return nil, errMissingTopic
+	}
+	proxy := url.URL{Scheme: "http", User: u.User, Host: u.Host, Path: "/topics/" + topic}
+	return &kafkaSink{url: proxy.String(), client: &http.Client{Timeout: sinkTimeout}}, nil
+}
+
+func (s *kafkaSink) Publish(event *Event) error {
+	blob, err := json.Marshal(&kafkaRecords{Records: []kafkaRecord{{Key: event.Hash.Hex(), Value: event}}})
+	if err != nil {
+		return err
+	}
+	return post(s.client, s.url, "application/vnd.kafka.json.v2+json", blob)
+}
+
+func (s *kafkaSink) Close() error {
+	return nil
+}
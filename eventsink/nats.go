@@ -0,0 +1,140 @@
+package eventsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	Register("nats", newNatsSink)
+}
+
+// natsSink publishes the events to a NATS server with its text protocol, on
+// the subject given by the url path suffixed by the event type, e.g. the block
+// events of nats://localhost:4222/tomochain go to tomochain.block.
+type natsSink struct {
+	addr    string
+	subject string
+	connect []byte // Connect command sent once connected
+
+	lock sync.Mutex
+	conn net.Conn // Connection to the server, nil until an event is published
+}
+
+// natsConnect is the body of the connect command.
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Name     string `json:"name"`
+}
+
+func newNatsSink(u *url.URL) (Sink, error) {
+	subject := strings.Replace(strings.Trim(u.Path, "/"), "/", ".", -1)
+	if subject == "" {
+		return nil, errMissingTopic
+	}
+	connect := &natsConnect{Name: "tomo"}
+	if u.User != nil {
+		connect.User = u.User.Username()
+		connect.Pass, _ = u.User.Password()
+	}
+	blob, err := json.Marshal(connect)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &natsSink{addr: addr, subject: subject, connect: []byte(fmt.Sprintf("CONNECT %s\r\n", blob))}, nil
+}
+
+func (s *natsSink) Publish(event *Event) error {
+	blob, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		if err := s.dial(); err != nil {
+			return err
+		}
+	}
+	msg := fmt.Sprintf("PUB %s.%s %d\r\n%s\r\n", s.subject, event.Type, len(blob), blob)
+	s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout))
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// dial connects to the server, the lock being held.
+func (s *natsSink) dial() error {
+	conn, err := net.DialTimeout("tcp", s.addr, sinkTimeout)
+	if err != nil {
+		return err
+	}
+	// The server greets with its info before accepting commands
+	conn.SetDeadline(time.Now().Add(sinkTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err == nil && !strings.HasPrefix(line, "INFO") {
+		err = fmt.Errorf("unexpected nats greeting: %q", strings.TrimSpace(line))
+	}
+	if err == nil {
+		_, err = conn.Write(s.connect)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	s.conn = conn
+	go s.read(conn, reader)
+	return nil
+}
+
+// read answers the pings of the server, which drops the clients that don't,
+// and reports its errors.
+func (s *natsSink) read(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			s.lock.Lock()
+			conn.Write([]byte("PONG\r\n"))
+			s.lock.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Warn("NATS event sink error", "addr", s.addr, "err", strings.TrimSpace(line[4:]))
+		}
+	}
+}
+
+func (s *natsSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,249 @@
+package eventsink
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/posv"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomox"
+)
+
+const (
+	// sinkQueueSize is the number of events queued to a sink, the events being
+	// dropped when a sink falls behind rather than stalling the others.
+	sinkQueueSize = 1024
+
+	// maxBacklog is the maximum number of blocks published at once, when the
+	// head jumps ahead or is reorged deeply.
+	maxBacklog = 1024
+
+	// epochConfirmations is the number of blocks a checkpoint is buried under
+	// before its epoch is published as final.
+	epochConfirmations = 50
+)
+
+// BlockData is the data of a block event.
+type BlockData struct {
+	ParentHash   common.Hash    `json:"parentHash"`
+	Coinbase     common.Address `json:"coinbase"`
+	Time         uint64         `json:"timestamp"`
+	Transactions int            `json:"transactions"`
+	GasUsed      uint64         `json:"gasUsed"`
+}
+
+// ReorgData is the data of a reorg event, whose block is the common ancestor of
+// the old and new chains.
+type ReorgData struct {
+	Depth   int           `json:"depth"`
+	OldHead common.Hash   `json:"oldHead"`
+	NewHead common.Hash   `json:"newHead"`
+	Dropped []common.Hash `json:"dropped"` // Blocks of the old chain, from the head down
+	Added   []common.Hash `json:"added"`   // Blocks of the new chain, from the head down
+}
+
+// EpochData is the data of an epoch event, whose block is its checkpoint.
+type EpochData struct {
+	Epoch       uint64           `json:"epoch"`
+	Masternodes []common.Address `json:"masternodes"`
+	Penalties   []common.Address `json:"penalties"`
+}
+
+// Publisher follows the chain head and publishes its events to the sinks.
+type Publisher struct {
+	chain  *core.BlockChain
+	queues []chan *Event
+
+	last      *types.Header // Last head published, only used by the loop
+	finalized uint64        // Last checkpoint published, only used by the loop
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPublisher creates a publisher of the events of a chain, which owns the
+// sinks from then on.
+func NewPublisher(chain *core.BlockChain, sinks []Sink) *Publisher {
+	p := &Publisher{
+		chain: chain,
+		quit:  make(chan struct{}),
+	}
+	for _, sink := range sinks {
+		queue := make(chan *Event, sinkQueueSize)
+		p.queues = append(p.queues, queue)
+
+		p.wg.Add(1)
+		go p.deliver(sink, queue)
+	}
+	return p
+}
+
+// Start publishes the events of the chain in the background.
+func (p *Publisher) Start() {
+	p.last = p.chain.CurrentHeader()
+	if config := p.chain.Config().Posv; config != nil && config.Epoch > 0 {
+		// Only the epochs finalized from now on are published
+		if number := p.last.Number.Uint64(); number >= epochConfirmations {
+			number -= epochConfirmations
+			p.finalized = number - number%config.Epoch
+		}
+	}
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Stop ends the publishing and closes the sinks, dropping the events not yet
+// delivered.
+func (p *Publisher) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// loop publishes the events of each chain head.
+func (p *Publisher) loop() {
+	defer p.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := p.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			p.update(ev.Block)
+		case <-sub.Err():
+			return
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// update publishes the blocks made canonical by a new head, the reorg if it
+// replaces blocks of the previous head, and the epochs finalized.
+func (p *Publisher) update(head *types.Block) {
+	var (
+		added   []*types.Block
+		dropped []common.Hash
+		block   = head
+		old     = p.last
+	)
+	// Chain head events may skip blocks, walk back to the previous head or to
+	// the common ancestor of both
+	for block != nil && old != nil && block.Hash() != old.Hash() && len(added) < maxBacklog && len(dropped) < maxBacklog {
+		if number := block.NumberU64(); number >= old.Number.Uint64() {
+			added = append(added, block)
+			block = p.chain.GetBlock(block.ParentHash(), number-1)
+		}
+		if block != nil && old.Number.Uint64() > block.NumberU64() {
+			dropped = append(dropped, old.Hash())
+			old = p.chain.GetHeader(old.ParentHash, old.Number.Uint64()-1)
+		}
+	}
+	p.last = head.Header()
+
+	if len(dropped) > 0 && block != nil && old != nil && block.Hash() == old.Hash() {
+		reorg := &ReorgData{
+			Depth:   len(dropped),
+			OldHead: dropped[0],
+			NewHead: head.Hash(),
+			Dropped: dropped,
+			Added:   make([]common.Hash, len(added)),
+		}
+		for i, block := range added {
+			reorg.Added[i] = block.Hash()
+		}
+		p.publish(&Event{Type: EventReorg, Number: block.NumberU64(), Hash: block.Hash(), Data: reorg})
+	}
+	for i := len(added) - 1; i >= 0; i-- {
+		p.publishBlock(added[i])
+	}
+	p.publishEpochs(head.NumberU64())
+}
+
+// publishBlock publishes a canonical block and its matched trades.
+func (p *Publisher) publishBlock(block *types.Block) {
+	p.publish(&Event{
+		Type:   EventBlock,
+		Number: block.NumberU64(),
+		Hash:   block.Hash(),
+		Data: &BlockData{
+			ParentHash:   block.ParentHash(),
+			Coinbase:     block.Coinbase(),
+			Time:         block.Time().Uint64(),
+			Transactions: len(block.Transactions()),
+			GasUsed:      block.GasUsed(),
+		},
+	})
+	trades := block.Trades()
+	if len(trades) == 0 {
+		// Blocks before the trade root fork only carry the matching transactions
+		batches, err := core.ExtractMatchingTransactions(block.Transactions())
+		if err != nil {
+			log.Debug("Failed to extract matched trades", "number", block.NumberU64(), "err", err)
+		}
+		for _, batch := range batches {
+			trades = append(trades, tomox.MatchedTrades(batch.Data)...)
+		}
+	}
+	if len(trades) > 0 {
+		p.publish(&Event{Type: EventTrades, Number: block.NumberU64(), Hash: block.Hash(), Data: trades})
+	}
+}
+
+// publishEpochs publishes the epochs whose checkpoints are deep enough under
+// the head to be final.
+func (p *Publisher) publishEpochs(number uint64) {
+	config := p.chain.Config().Posv
+	if config == nil || config.Epoch == 0 || number < epochConfirmations {
+		return
+	}
+	for checkpoint := p.finalized + config.Epoch; checkpoint+epochConfirmations <= number; checkpoint += config.Epoch {
+		header := p.chain.GetHeaderByNumber(checkpoint)
+		if header == nil {
+			log.Debug("Finalized checkpoint not found", "number", checkpoint)
+			return
+		}
+		p.publish(&Event{
+			Type:   EventEpoch,
+			Number: checkpoint,
+			Hash:   header.Hash(),
+			Data: &EpochData{
+				Epoch:       checkpoint / config.Epoch,
+				Masternodes: posv.GetMasternodesFromCheckpointHeader(header),
+				Penalties:   append([]common.Address{}, common.ExtractAddressFromBytes(header.Penalties)...),
+			},
+		})
+		p.finalized = checkpoint
+	}
+}
+
+// publish queues an event to all the sinks.
+func (p *Publisher) publish(event *Event) {
+	for _, queue := range p.queues {
+		select {
+		case queue <- event:
+		default:
+			log.Warn("Event sink queue full, dropping event", "type", event.Type, "number", event.Number)
+		}
+	}
+}
+
+// deliver publishes the queued events to a sink until the publisher stops.
+func (p *Publisher) deliver(sink Sink, queue chan *Event) {
+	defer p.wg.Done()
+	defer sink.Close()
+
+	for {
+		select {
+		case event := <-queue:
+			if err := sink.Publish(event); err != nil {
+				log.Warn("Failed to publish event", "type", event.Type, "number", event.Number, "err", err)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
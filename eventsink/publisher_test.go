@@ -0,0 +1,133 @@
+package eventsink
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the blocks made canonical by new heads are published in order,
+// with the reorgs replacing blocks of the previous head and the epochs whose
+// checkpoints became final.
+func TestPublisherEvents(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	var (
+		config  = *params.TestChainConfig
+		genesis = (&core.Genesis{Config: &config}).MustCommit(db)
+		engine  = ethash.NewFaker()
+	)
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks, _ := core.GenerateChain(&config, genesis, engine, db, epochConfirmations+10, nil)
+	fork, _ := core.GenerateChain(&config, blocks[2], engine, db, 3, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := chain.InsertChain(blocks[:5]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	rec := &eventRecorder{}
+	p := &Publisher{chain: chain, queues: []chan *Event{make(chan *Event, sinkQueueSize)}}
+	p.last = blocks[0].Header()
+
+	// The head jumps ahead, the skipped blocks are published
+	p.update(blocks[4])
+	expect := []*Event{}
+	for _, block := range blocks[1:5] {
+		expect = append(expect, &Event{Type: EventBlock, Number: block.NumberU64(), Hash: block.Hash()})
+	}
+	rec.check(t, p.queues[0], expect)
+
+	// The fork replaces the last two blocks
+	p.update(fork[2])
+	expect = []*Event{{Type: EventReorg, Number: 3, Hash: blocks[2].Hash()}}
+	for _, block := range fork {
+		expect = append(expect, &Event{Type: EventBlock, Number: block.NumberU64(), Hash: block.Hash()})
+	}
+	rec.check(t, p.queues[0], expect)
+	if reorg := rec.events[0].Data.(*ReorgData); reorg.Depth != 2 || reorg.OldHead != blocks[4].Hash() || reorg.NewHead != fork[2].Hash() || len(reorg.Added) != 3 {
+		t.Errorf("reorg data mismatch: %+v", reorg)
+	}
+	// Back to the first chain, the epoch checkpoints deep enough are finalized.
+	// Epochs are only published on posv chains, enabled once the blocks are
+	// imported by ethash.
+	if _, err := chain.InsertChain(blocks[5:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	config.Posv = &params.PosvConfig{Epoch: 5}
+	p.update(blocks[len(blocks)-1])
+	rec.events = nil
+	for event := range drain(p.queues[0]) {
+		if event.Type == EventEpoch {
+			rec.events = append(rec.events, event)
+		}
+	}
+	if len(rec.events) != 2 || rec.events[0].Number != 5 || rec.events[1].Number != 10 {
+		t.Fatalf("finalized epochs mismatch: %v", rec.events)
+	}
+	if epoch := rec.events[1].Data.(*EpochData); epoch.Epoch != 2 || rec.events[1].Hash != blocks[9].Hash() {
+		t.Errorf("epoch data mismatch: %+v", epoch)
+	}
+}
+
+// eventRecorder collects the events queued by a publisher.
+type eventRecorder struct {
+	events []*Event
+}
+
+// check compares the queued events with the expected types and blocks.
+func (r *eventRecorder) check(t *testing.T, queue chan *Event, expect []*Event) {
+	r.events = nil
+	for event := range drain(queue) {
+		r.events = append(r.events, event)
+	}
+	if len(r.events) != len(expect) {
+		t.Fatalf("event count mismatch: have %d, want %d", len(r.events), len(expect))
+	}
+	for i, event := range r.events {
+		if event.Type != expect[i].Type || event.Number != expect[i].Number || event.Hash != expect[i].Hash {
+			t.Errorf("event %d mismatch: have %s #%d %x, want %s #%d %x", i, event.Type, event.Number, event.Hash, expect[i].Type, expect[i].Number, expect[i].Hash)
+		}
+	}
+}
+
+// drain returns the events queued so far.
+func drain(queue chan *Event) chan *Event {
+	events := make(chan *Event, len(queue))
+	for len(queue) > 0 {
+		events <- <-queue
+	}
+	close(events)
+	return events
+}
+
+// Tests that the trades committed to by a block are published after it.
+func TestPublisherTrades(t *testing.T) {
+	trades := types.Trades{{Price: big.NewInt(1), Quantity: big.NewInt(2)}}
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithTrades(trades)
+
+	p := &Publisher{queues: []chan *Event{make(chan *Event, 2)}}
+	p.publishBlock(block)
+
+	rec := &eventRecorder{}
+	rec.check(t, p.queues[0], []*Event{
+		{Type: EventBlock, Number: 1, Hash: block.Hash()},
+		{Type: EventTrades, Number: 1, Hash: block.Hash()},
+	})
+	if have := rec.events[1].Data.(types.Trades); len(have) != 1 || have[0].Quantity.Int64() != 2 {
+		t.Errorf("trades mismatch: %v", have)
+	}
+}
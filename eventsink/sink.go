@@ -0,0 +1,103 @@
+// Package eventsink publishes chain and DEX events to external systems, such as
+// HTTP webhooks, Kafka or NATS, so that they don't have to poll the JSON-RPC API.
+package eventsink
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Types of the published events.
+const (
+	EventBlock  = "block"  // A block became canonical
+	EventReorg  = "reorg"  // Canonical blocks were replaced by a fork
+	EventTrades = "trades" // Orders were matched in a canonical block
+	EventEpoch  = "epoch"  // An epoch checkpoint is deep enough to be final
+)
+
+var errUnknownDriver = errors.New("unknown event sink driver")
+
+// Event is a chain or DEX event, published as JSON.
+type Event struct {
+	Type   string      `json:"type"`
+	Number uint64      `json:"number"` // Number of the block the event relates to
+	Hash   common.Hash `json:"hash"`   // Hash of the block the event relates to
+	Data   interface{} `json:"data"`
+}
+
+// Sink is the destination of the published events.
+type Sink interface {
+	// Publish delivers an event, it may block until the event is accepted.
+	Publish(event *Event) error
+
+	// Close releases the resources held by the sink.
+	Close() error
+}
+
+// Driver creates a sink from its URL.
+type Driver func(u *url.URL) (Sink, error)
+
+var (
+	driversLock sync.RWMutex
+	drivers     = make(map[string]Driver)
+)
+
+// Register makes a driver available for the URLs with the given scheme. It
+// panics if a driver is already registered for the scheme.
+func Register(scheme string, driver Driver) {
+	driversLock.Lock()
+	defer driversLock.Unlock()
+
+	if _, ok := drivers[scheme]; ok {
+		panic(fmt.Sprintf("event sink driver already registered for %s", scheme))
+	}
+	drivers[scheme] = driver
+}
+
+// New creates a sink from its URL, the driver being picked by the scheme. The
+// events query parameter, if any, restricts the sink to a comma separated list
+// of event types, e.g. http://localhost:8080/hook?events=block,reorg.
+func New(rawurl string) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	driversLock.RLock()
+	driver, ok := drivers[u.Scheme]
+	driversLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%v: %s", errUnknownDriver, u.Scheme)
+	}
+	query := u.Query()
+	events := query.Get("events")
+	query.Del("events")
+	u.RawQuery = query.Encode()
+
+	sink, err := driver(u)
+	if err != nil || events == "" {
+		return sink, err
+	}
+	filter := &filterSink{Sink: sink, events: make(map[string]bool)}
+	for _, event := range strings.Split(events, ",") {
+		filter.events[strings.TrimSpace(event)] = true
+	}
+	return filter, nil
+}
+
+// filterSink only publishes some types of events to a sink.
+type filterSink struct {
+	Sink
+	events map[string]bool
+}
+
+func (s *filterSink) Publish(event *Event) error {
+	if !s.events[event.Type] {
+		return nil
+	}
+	return s.Sink.Publish(event)
+}
@@ -0,0 +1,158 @@
+package eventsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the drivers are picked by scheme and that the events parameter
+// filters the published events.
+func TestNewSink(t *testing.T) {
+	if _, err := New("smtp://localhost"); err == nil {
+		t.Error("unknown scheme accepted")
+	}
+	if _, err := New("kafka://localhost:8082"); err != errMissingTopic {
+		t.Errorf("kafka sink without topic: have %v, want %v", err, errMissingTopic)
+	}
+	if _, err := New("nats://localhost"); err != errMissingTopic {
+		t.Errorf("nats sink without subject: have %v, want %v", err, errMissingTopic)
+	}
+	posted := make(chan *Event, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "token=secret" {
+			t.Errorf("query mismatch: have %q, want %q", r.URL.RawQuery, "token=secret")
+		}
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode event: %v", err)
+		}
+		posted <- &event
+	}))
+	defer server.Close()
+
+	sink, err := New(server.URL + "/hook?events=reorg,epoch&token=secret")
+	if err != nil {
+		t.Fatalf("failed to create webhook sink: %v", err)
+	}
+	for _, typ := range []string{EventBlock, EventReorg, EventTrades} {
+		if err := sink.Publish(&Event{Type: typ, Number: 1}); err != nil {
+			t.Fatalf("failed to publish %s event: %v", typ, err)
+		}
+	}
+	if event := <-posted; event.Type != EventReorg {
+		t.Errorf("published event mismatch: have %s, want %s", event.Type, EventReorg)
+	}
+	if len(posted) != 0 {
+		t.Errorf("filtered events published: %d", len(posted))
+	}
+}
+
+// Tests that the kafka sink produces the events to the topic of the REST proxy.
+func TestKafkaSink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/chain" {
+			t.Errorf("path mismatch: have %s, want %s", r.URL.Path, "/topics/chain")
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/vnd.kafka.json.v2+json" {
+			t.Errorf("content type mismatch: have %s", ct)
+		}
+		var records kafkaRecords
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil || len(records.Records) != 1 {
+			t.Errorf("failed to decode records: %v", err)
+		} else if record := records.Records[0]; record.Key != (common.Hash{0x01}).Hex() || record.Value.Type != EventBlock {
+			t.Errorf("record mismatch: %+v", record)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink, err := New(strings.Replace(server.URL, "http", "kafka", 1) + "/chain")
+	if err != nil {
+		t.Fatalf("failed to create kafka sink: %v", err)
+	}
+	if err := sink.Publish(&Event{Type: EventBlock, Hash: common.Hash{0x01}}); err == nil {
+		t.Error("rejected event reported as published")
+	}
+}
+
+// Tests that the nats sink connects to the server, answers its pings and
+// publishes the events on the subject of their type.
+func TestNatsSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- strings.TrimSpace(line)
+			if strings.HasPrefix(line, "CONNECT") {
+				fmt.Fprint(conn, "PING\r\n")
+			}
+		}
+	}()
+	sink, err := New("nats://user:pass@" + listener.Addr().String() + "/tomo/chain")
+	if err != nil {
+		t.Fatalf("failed to create nats sink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Publish(&Event{Type: EventEpoch, Number: 900}); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+	blob, _ := json.Marshal(&Event{Type: EventEpoch, Number: 900})
+	expected := map[string]bool{
+		`CONNECT {"verbose":false,"pedantic":false,"user":"user","pass":"pass","name":"tomo"}`: true,
+		fmt.Sprintf("PUB tomo.chain.epoch %d", len(blob)):                                      true,
+		string(blob): true,
+		"PONG":       true,
+	}
+	for len(expected) > 0 {
+		select {
+		case line := <-lines:
+			if !expected[line] {
+				t.Fatalf("unexpected line: %q", line)
+			}
+			delete(expected, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("lines not received: %v", expected)
+		}
+	}
+}
+
+// Tests that a webhook failing is reported.
+func TestWebhookSinkRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink, _ := New(server.URL)
+	if err := sink.Publish(&Event{Type: EventBlock}); err == nil {
+		t.Error("rejected event reported as published")
+	}
+}
@@ -0,0 +1,53 @@
+package eventsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// sinkTimeout is the time allowed to a sink to accept an event.
+const sinkTimeout = 10 * time.Second
+
+func init() {
+	Register("http", newWebhookSink)
+	Register("https", newWebhookSink)
+}
+
+// webhookSink posts the events as JSON to an HTTP endpoint.
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookSink(u *url.URL) (Sink, error) {
+	return &webhookSink{url: u.String(), client: &http.Client{Timeout: sinkTimeout}}, nil
+}
+
+func (s *webhookSink) Publish(event *Event) error {
+	blob, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return post(s.client, s.url, "application/json", blob)
+}
+
+func (s *webhookSink) Close() error {
+	return nil
+}
+
+// post sends a request body to an HTTP endpoint, failing unless it's accepted.
+func post(client *http.Client, url string, contentType string, body []byte) error {
+	resp, err := client.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("event rejected by %s: %s", url, resp.Status)
+	}
+	return nil
+}
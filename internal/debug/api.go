@@ -57,9 +57,10 @@ func (*HandlerT) Verbosity(level int) {
 }
 
 // Vmodule sets the log verbosity pattern. See package log for details on the
-// pattern syntax.
+// pattern syntax. The tomox, tomoxlending, posv and contracts module names
+// also cover all the packages of these modules.
 func (*HandlerT) Vmodule(pattern string) error {
-	return Glogger.Vmodule(pattern)
+	return Glogger.Vmodule(expandVmodule(pattern))
 }
 
 // BacktraceAt sets the log backtrace location. See package log for details on
@@ -23,6 +23,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/log/term"
@@ -43,6 +44,11 @@ var (
 		Usage: "Per-module verbosity: comma-separated list of <pattern>=<level> (e.g. eth/*=5,p2p=4)",
 		Value: "",
 	}
+	logFormatFlag = cli.StringFlag{
+		Name:  "log.format",
+		Usage: "Log output format: terminal, logfmt or json",
+		Value: "terminal",
+	}
 	backtraceAtFlag = cli.StringFlag{
 		Name:  "backtrace",
 		Usage: "Request a stack trace at a specific logging statement (e.g. \"block.go:271\")",
@@ -88,7 +94,8 @@ var (
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	VerbosityFlag,
-	//vmoduleFlag,
+	vmoduleFlag,
+	logFormatFlag,
 	//backtraceAtFlag,
 	//debugFlag,
 	//pprofFlag,
@@ -102,6 +109,32 @@ var Flags = []cli.Flag{
 
 var Glogger *log.GlogHandler
 
+// moduleAliases maps the module names accepted by the vmodule patterns to the
+// source trees they cover, so that a single rule raises the verbosity of all
+// the packages of a module (e.g. tomox=5 also covers tomox/tomox_state).
+var moduleAliases = map[string]string{
+	"tomox":        "tomox/*",
+	"tomoxlending": "tomoxlending/*",
+	"posv":         "consensus/posv/*",
+	"contracts":    "contracts/*",
+}
+
+// expandVmodule rewrites the module aliases of a vmodule ruleset into their
+// source tree patterns.
+func expandVmodule(ruleset string) string {
+	rules := strings.Split(ruleset, ",")
+	for i, rule := range rules {
+		parts := strings.Split(rule, "=")
+		if len(parts) != 2 {
+			continue
+		}
+		if alias, ok := moduleAliases[strings.TrimSpace(parts[0])]; ok {
+			rules[i] = alias + "=" + parts[1]
+		}
+	}
+	return strings.Join(rules, ",")
+}
+
 func init() {
 	usecolor := term.IsTty(os.Stderr.Fd()) && os.Getenv("TERM") != "dumb"
 	output := io.Writer(os.Stderr)
@@ -115,9 +148,20 @@ func init() {
 // It should be called as early as possible in the program.
 func Setup(ctx *cli.Context) error {
 	// logging
+	switch format := ctx.GlobalString(logFormatFlag.Name); format {
+	case "terminal":
+	case "logfmt":
+		Glogger = log.NewGlogHandler(log.StreamHandler(os.Stderr, log.LogfmtFormat()))
+	case "json":
+		Glogger = log.NewGlogHandler(log.StreamHandler(os.Stderr, log.JsonFormat()))
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
 	Glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
-	Glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
+	if err := Glogger.Vmodule(expandVmodule(ctx.GlobalString(vmoduleFlag.Name))); err != nil {
+		return err
+	}
 	Glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
 	log.Root().SetHandler(Glogger)
 
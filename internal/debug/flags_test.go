@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"flag"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestExpandVmodule(t *testing.T) {
+	tests := []struct {
+		ruleset, want string
+	}{
+		{"", ""},
+		{"eth/*=5,p2p=4", "eth/*=5,p2p=4"},
+		{"tomox=5", "tomox/*=5"},
+		{"posv=4,eth/*=3, contracts=2", "consensus/posv/*=4,eth/*=3,contracts/*=2"},
+		{"tomoxlending", "tomoxlending"},
+	}
+	for _, tt := range tests {
+		if have := expandVmodule(tt.ruleset); have != tt.want {
+			t.Errorf("expandVmodule(%q) mismatch: have %q, want %q", tt.ruleset, have, tt.want)
+		}
+	}
+}
+
+func TestSetupLogFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--log.format", "yaml"}, false},
+		{[]string{"--vmodule", "tomox=x"}, false},
+		{[]string{"--log.format", "logfmt", "--vmodule", "tomox=5"}, true},
+	}
+	for _, tt := range tests {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		for _, f := range Flags {
+			f.Apply(set)
+		}
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatalf("%v: failed to parse flags: %v", tt.args, err)
+		}
+		if err := Setup(cli.NewContext(nil, set, nil)); (err == nil) != tt.ok {
+			t.Errorf("%v: setup error mismatch: have %v, want success %v", tt.args, err, tt.ok)
+		}
+	}
+}
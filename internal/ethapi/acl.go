@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SignerACL restricts the local accounts the APIs sign orders and transactions
+// with, so that relayer operators can keep the keys signing the orders of their
+// users apart from the ones moving their funds.
+type SignerACL struct {
+	orders map[common.Address]bool // Accounts signing orders, all if nil
+	txs    map[common.Address]bool // Accounts signing transactions, all if nil
+}
+
+// NewSignerACL creates an ACL allowing the given accounts to sign orders and
+// transactions, any account being allowed to if a list is empty.
+func NewSignerACL(orderSigners, txSigners []common.Address) *SignerACL {
+	return &SignerACL{orders: addressSet(orderSigners), txs: addressSet(txSigners)}
+}
+
+func addressSet(addrs []common.Address) map[common.Address]bool {
+	if len(addrs) == 0 {
+		return nil
+	}
+	set := make(map[common.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = true
+	}
+	return set
+}
+
+// checkOrder returns an error if the account may not sign orders.
+func (acl *SignerACL) checkOrder(addr common.Address) error {
+	if acl != nil && acl.orders != nil && !acl.orders[addr] {
+		return fmt.Errorf("account %s not allowed to sign orders", addr.Hex())
+	}
+	return nil
+}
+
+// checkTx returns an error if the account may not sign transactions.
+func (acl *SignerACL) checkTx(addr common.Address) error {
+	if acl != nil && acl.txs != nil && !acl.txs[addr] {
+		return fmt.Errorf("account %s not allowed to sign transactions", addr.Hex())
+	}
+	return nil
+}
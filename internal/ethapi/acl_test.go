@@ -0,0 +1,59 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSignerACL(t *testing.T) {
+	var (
+		orderKey = common.Address{0x01}
+		fundsKey = common.Address{0x02}
+		otherKey = common.Address{0x03}
+	)
+	// Without lists, or without an ACL, any account signs anything
+	for _, acl := range []*SignerACL{nil, NewSignerACL(nil, nil)} {
+		if acl.checkOrder(otherKey) != nil || acl.checkTx(otherKey) != nil {
+			t.Fatalf("unrestricted ACL %v refused an account", acl)
+		}
+	}
+	acl := NewSignerACL([]common.Address{orderKey}, []common.Address{fundsKey})
+	tests := []struct {
+		addr         common.Address
+		order, trans bool
+	}{
+		{orderKey, true, false},
+		{fundsKey, false, true},
+		{otherKey, false, false},
+	}
+	for i, tt := range tests {
+		if allowed := acl.checkOrder(tt.addr) == nil; allowed != tt.order {
+			t.Errorf("test %d: order signing allowed %v, want %v", i, allowed, tt.order)
+		}
+		if allowed := acl.checkTx(tt.addr) == nil; allowed != tt.trans {
+			t.Errorf("test %d: transaction signing allowed %v, want %v", i, allowed, tt.trans)
+		}
+	}
+	// Restricting a single duty leaves the other one open
+	acl = NewSignerACL([]common.Address{orderKey}, nil)
+	if acl.checkTx(otherKey) != nil {
+		t.Error("transaction signing restricted by the order signers")
+	}
+}
@@ -19,10 +19,13 @@ package ethapi
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/tomox"
 	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
 	"math/big"
 	"sort"
 	"strings"
@@ -64,7 +67,11 @@ const (
 	fieldEpoch       = "epoch"
 )
 
-var errEmptyHeader = errors.New("empty header")
+var (
+	errEmptyHeader     = errors.New("empty header")
+	errNoEpochDuration = errors.New("epoch duration not available")
+	errNoLending       = errors.New("tomox lending not activated")
+)
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
@@ -82,6 +89,37 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// feeHistoryResult is the response of FeeHistory. TomoChain has no base fee, so
+// the rewards are the gas price percentiles of the transactions of each block.
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratio and the gas price percentiles of up to
+// blockCount blocks ending at lastBlock, for wallets to estimate fees from.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, prices, gasUsedRatio, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsedRatio,
+	}
+	if prices != nil {
+		result.Reward = make([][]*hexutil.Big, len(prices))
+		for i, blockPrices := range prices {
+			result.Reward[i] = make([]*hexutil.Big, len(blockPrices))
+			for j, price := range blockPrices {
+				result.Reward[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -102,13 +140,21 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 		return false, nil
 	}
 	// Otherwise gather the block sync stats
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"startingBlock": hexutil.Uint64(progress.StartingBlock),
 		"currentBlock":  hexutil.Uint64(progress.CurrentBlock),
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
-	}, nil
+	}
+	// Along with how far the TomoX state was rebuilt
+	if tomoxService := s.b.TomoxService(); tomoxService != nil {
+		tomoxProgress := tomoxService.Progress()
+		result["tomoxProcessedBlock"] = hexutil.Uint64(tomoxProgress.ProcessedBlock)
+		result["tomoxProcessedRoot"] = tomoxProgress.ProcessedRoot
+		result["tomoxPendingPairs"] = hexutil.Uint64(tomoxProgress.PendingPairs)
+	}
+	return result, nil
 }
 
 // PublicTxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
@@ -220,14 +266,16 @@ func (s *PublicAccountAPI) Accounts() []common.Address {
 type PrivateAccountAPI struct {
 	am        *accounts.Manager
 	nonceLock *AddrLocker
+	acl       *SignerACL
 	b         Backend
 }
 
 // NewPrivateAccountAPI create a new PrivateAccountAPI.
-func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
+func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker, acl *SignerACL) *PrivateAccountAPI {
 	return &PrivateAccountAPI{
 		am:        b.AccountManager(),
 		nonceLock: nonceLock,
+		acl:       acl,
 		b:         b,
 	}
 }
@@ -355,6 +403,9 @@ func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
 // NOTE: the caller needs to ensure that the nonceLock is held, if applicable,
 // and release it after the transaction has been submitted to the tx pool
 func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs, passwd string) (*types.Transaction, error) {
+	if err := s.acl.checkTx(args.From); err != nil {
+		return nil, err
+	}
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: args.From}
 	wallet, err := s.am.Find(account)
@@ -419,18 +470,6 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	return &SignTransactionResult{data, signed}, nil
 }
 
-// signHash is a helper function that calculates a hash for the given message that can be
-// safely used to calculate a signature from.
-//
-// The hash is calulcated as
-//   keccak256("\x19Ethereum Signed Message:\n"${message length}${message}).
-//
-// This gives context to the signed message and prevents signing of transactions.
-func signHash(data []byte) []byte {
-	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
-	return crypto.Keccak256([]byte(msg))
-}
-
 // Sign calculates an Ethereum ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message))
 //
@@ -449,7 +488,7 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 		return nil, err
 	}
 	// Assemble sign the data with the wallet
-	signature, err := wallet.SignHashWithPassphrase(account, passwd, signHash(data))
+	signature, err := wallet.SignTextWithPassphrase(account, passwd, data)
 	if err != nil {
 		return nil, err
 	}
@@ -476,7 +515,7 @@ func (s *PrivateAccountAPI) EcRecover(ctx context.Context, data, sig hexutil.Byt
 	}
 	sig[64] -= 27 // Transform yellow paper V from 27/28 to 0/1
 
-	rpk, err := crypto.Ecrecover(signHash(data), sig)
+	rpk, err := crypto.Ecrecover(accounts.TextHash(data), sig)
 	if err != nil {
 		return common.Address{}, err
 	}
@@ -485,6 +524,19 @@ func (s *PrivateAccountAPI) EcRecover(ctx context.Context, data, sig hexutil.Byt
 	return recoveredAddr, nil
 }
 
+// SignOrder signs the given order with the key of its user address, decrypted
+// with the given password or unlocked if none is given. The hash of a new order
+// is filled in if missing. The signed order is returned for submission with
+// tomox_sendOrderRawTransaction.
+func (s *PrivateAccountAPI) SignOrder(ctx context.Context, msg OrderMsg, passwd *string) (*SignOrderResult, error) {
+	return signOrder(s.b, s.acl, msg, func(wallet accounts.Wallet, account accounts.Account, hash []byte) ([]byte, error) {
+		if passwd == nil {
+			return wallet.SignText(account, hash)
+		}
+		return wallet.SignTextWithPassphrase(account, *passwd, hash)
+	})
+}
+
 // SignAndSendTransaction was renamed to SendTransaction. This method is deprecated
 // and will be removed in the future. It primary goal is to give clients time to update.
 func (s *PrivateAccountAPI) SignAndSendTransaction(ctx context.Context, args SendTxArgs, passwd string) (common.Hash, error) {
@@ -502,6 +554,16 @@ func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
 	return &PublicBlockChainAPI{b}
 }
 
+// ChainId returns the chain ID used for the replay protection of the
+// transactions (EIP-155), which is only available once the chain is synced
+// beyond the fork activating it.
+func (s *PublicBlockChainAPI) ChainId() (*hexutil.Big, error) {
+	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
+		return (*hexutil.Big)(config.ChainId), nil
+	}
+	return nil, errors.New("chain not synced beyond EIP-155 replay-protection fork")
+}
+
 // BlockNumber returns the block number of the chain head.
 func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 	header, _ := s.b.HeaderByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
@@ -627,6 +689,75 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the EIP-1186 proof of an account and of some of its storage
+// slots.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the EIP-1186 proof of a storage slot.
+type StorageResult struct {
+	Key   string          `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the merkle proofs of an account and of its storage slots
+// at a block, as specified by EIP-1186. Non-existent accounts are proven by
+// proofs of absence, with empty storage proofs.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	storageHash, codeHash := types.EmptyRootHash, crypto.Keccak256Hash(nil)
+	storageTrie := state.StorageTrie(address)
+	if storageTrie != nil {
+		storageHash, codeHash = storageTrie.Hash(), state.GetCodeHash(address)
+	}
+	storageProof := make([]StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		storageProof[i] = StorageResult{Key: key, Value: new(hexutil.Big), Proof: []hexutil.Bytes{}}
+		if storageTrie == nil {
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		storageProof[i].Value = (*hexutil.Big)(state.GetState(address, common.HexToHash(key)).Big())
+		storageProof[i].Proof = toHexNodes(proof)
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexNodes(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexNodes converts the trie nodes of a proof for the RPC results.
+func toHexNodes(proof [][]byte) []hexutil.Bytes {
+	nodes := make([]hexutil.Bytes, len(proof))
+	for i, node := range proof {
+		nodes[i] = node
+	}
+	return nodes
+}
+
 func (s *PublicBlockChainAPI) GetBlockSignersByHash(ctx context.Context, blockHash common.Hash) ([]common.Address, error) {
 	block, err := s.b.GetBlock(ctx, blockHash)
 	if err != nil || block == nil {
@@ -768,7 +899,7 @@ func (s *PublicBlockChainAPI) GetCandidateStatus(ctx context.Context, coinbaseAd
 	// if it's SLASHED but it's out of top 150, the status should be still PROPOSED
 	for i := 0; i < len(candidates); i++ {
 		if coinbaseAddress == candidates[i].Address {
-			if i < common.MaxMasternodes {
+			if i < s.b.ChainConfig().Posv.MasternodesLimit() {
 				isTopCandidate = true
 			}
 			result[fieldStatus] = statusProposed
@@ -802,7 +933,7 @@ func (s *PublicBlockChainAPI) GetCandidateStatus(ctx context.Context, coinbaseAd
 	// Third, Get penalties list
 	penalties = append(penalties, header.Penalties...)
 	// check last 5 epochs to find penalize masternodes
-	for i := 1; i <= common.LimitPenaltyEpoch; i++ {
+	for i := 1; i <= s.b.ChainConfig().Posv.PenaltyEpochs(); i++ {
 		if header.Number.Uint64() < epochConfig*uint64(i) {
 			break
 		}
@@ -912,7 +1043,7 @@ func (s *PublicBlockChainAPI) GetCandidates(ctx context.Context, epoch rpc.Epoch
 	// Third, Get penalties list
 	penalties = append(penalties, header.Penalties...)
 	// check last 5 epochs to find penalize masternodes
-	for i := 1; i <= common.LimitPenaltyEpoch; i++ {
+	for i := 1; i <= s.b.ChainConfig().Posv.PenaltyEpochs(); i++ {
 		if header.Number.Uint64() < epochConfig*uint64(i) {
 			break
 		}
@@ -932,8 +1063,8 @@ func (s *PublicBlockChainAPI) GetCandidates(ctx context.Context, epoch rpc.Epoch
 	penaltyList = common.ExtractAddressFromBytes(penalties)
 
 	var topCandidates []posv.Masternode
-	if len(candidates) > common.MaxMasternodes {
-		topCandidates = candidates[:common.MaxMasternodes]
+	if maxMasternodes := s.b.ChainConfig().Posv.MasternodesLimit(); len(candidates) > maxMasternodes {
+		topCandidates = candidates[:maxMasternodes]
 	} else {
 		topCandidates = candidates
 	}
@@ -1014,6 +1145,394 @@ func (s *PublicBlockChainAPI) getCandidatesFromSmartContract() ([]posv.Masternod
 	return candidatesWithStakeInfo, nil
 }
 
+// PublicPosvAPI provides an API to query the masternode voting state of the
+// proof-of-stake-voting consensus.
+type PublicPosvAPI struct {
+	b     Backend
+	chain *PublicBlockChainAPI
+}
+
+// NewPublicPosvAPI creates a new masternode voting API.
+func NewPublicPosvAPI(b Backend) *PublicPosvAPI {
+	return &PublicPosvAPI{b: b, chain: NewPublicBlockChainAPI(b)}
+}
+
+// CandidatesArgs represents the sorting and pagination arguments of the
+// candidate queries.
+type CandidatesArgs struct {
+	SortBy string `json:"sortBy"` // Either "capacity" (default), "voters" or "address"
+	Asc    bool   `json:"asc"`    // Sort in ascending instead of descending order
+	Offset uint64 `json:"offset"` // Number of sorted candidates to skip
+	Limit  uint64 `json:"limit"`  // Maximum number of candidates returned, all if zero
+}
+
+// CandidateInfo is the voting state of a single candidate at a checkpoint.
+type CandidateInfo struct {
+	Address  common.Address `json:"address"`
+	Owner    common.Address `json:"owner"`
+	Capacity *big.Int       `json:"capacity"`
+	Voters   uint64         `json:"voters"`
+	Status   string         `json:"status"`
+}
+
+// CandidatesPage is a sorted page of the candidates at a checkpoint.
+type CandidatesPage struct {
+	Epoch      uint64           `json:"epoch"`
+	Checkpoint uint64           `json:"checkpoint"`
+	Total      uint64           `json:"total"`
+	Candidates []*CandidateInfo `json:"candidates"`
+}
+
+// GetCandidates returns the candidates at the checkpoint of an epoch with their
+// capacity, voter count and status, sorted and paginated.
+func (s *PublicPosvAPI) GetCandidates(ctx context.Context, epoch rpc.EpochNumber, args *CandidatesArgs) (*CandidatesPage, error) {
+	if args == nil {
+		args = new(CandidatesArgs)
+	}
+	less, err := args.less()
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.chain.GetCandidates(ctx, epoch)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, epochNumber := s.chain.GetPreviousCheckpointFromEpoch(ctx, epoch)
+	page := &CandidatesPage{
+		Epoch:      uint64(epochNumber),
+		Checkpoint: uint64(checkpoint),
+		Candidates: []*CandidateInfo{},
+	}
+	candidates, ok := statuses[fieldCandidates].(map[string]map[string]interface{})
+	if !ok {
+		return page, nil
+	}
+	statedb, _, err := s.b.StateAndHeaderByNumber(ctx, checkpoint)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	infos := make([]*CandidateInfo, 0, len(candidates))
+	for address, status := range candidates {
+		candidate := common.HexToAddress(address)
+		info := &CandidateInfo{
+			Address:  candidate,
+			Owner:    state.GetCandidateOwner(statedb, candidate),
+			Capacity: status[fieldCapacity].(*big.Int),
+			Voters:   state.GetVoterCount(statedb, candidate),
+			Status:   status[fieldStatus].(string),
+		}
+		infos = append(infos, info)
+	}
+	page.Total = uint64(len(infos))
+	page.Candidates = args.page(infos, less)
+	return page, nil
+}
+
+// less returns the ordering of the candidates by the sort field.
+func (args *CandidatesArgs) less() (func(a, b *CandidateInfo) bool, error) {
+	switch args.SortBy {
+	case "", "capacity":
+		return func(a, b *CandidateInfo) bool { return a.Capacity.Cmp(b.Capacity) < 0 }, nil
+	case "voters":
+		return func(a, b *CandidateInfo) bool { return a.Voters < b.Voters }, nil
+	case "address":
+		return func(a, b *CandidateInfo) bool { return bytes.Compare(a.Address[:], b.Address[:]) < 0 }, nil
+	default:
+		return nil, fmt.Errorf("invalid sort field %q", args.SortBy)
+	}
+}
+
+// page sorts the candidates and returns the requested page of them.
+func (args *CandidatesArgs) page(infos []*CandidateInfo, less func(a, b *CandidateInfo) bool) []*CandidateInfo {
+	// Sort the candidates, breaking ties by address to keep the pages stable
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if !args.Asc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return bytes.Compare(infos[i].Address[:], infos[j].Address[:]) < 0
+	})
+	if args.Offset >= uint64(len(infos)) {
+		return []*CandidateInfo{}
+	}
+	infos = infos[args.Offset:]
+	if args.Limit > 0 && args.Limit < uint64(len(infos)) {
+		infos = infos[:args.Limit]
+	}
+	return infos
+}
+
+// VoterRewardSimulation is the estimated reward of a hypothetical vote for a
+// masternode.
+type VoterRewardSimulation struct {
+	Masternode  common.Address `json:"masternode"`
+	Stake       *big.Int       `json:"stake"`
+	EpochReward *big.Int       `json:"epochReward"` // Reward of the stake at the last reward checkpoint
+	APR         float64        `json:"apr"`         // Yearly reward of the stake, in percent
+}
+
+// SimulateVoterReward estimates the reward of staking an amount on a masternode
+// by replaying the voter reward calculation of the last reward checkpoint with
+// the stake added. If a voter is given, the stake is added to its current vote.
+func (s *PublicPosvAPI) SimulateVoterReward(ctx context.Context, masternode common.Address, stake *hexutil.Big, voter *common.Address) (*VoterRewardSimulation, error) {
+	if stake == nil || stake.ToInt().Sign() <= 0 {
+		return nil, errors.New("stake must be positive")
+	}
+	var from common.Address
+	if voter != nil {
+		from = *voter
+	}
+	reward, err := s.b.SimulateVoterReward(ctx, masternode, from, stake.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	epochsPerYear := common.BlocksPerYear / s.b.ChainConfig().Posv.Epoch
+	yearly := new(big.Float).SetInt(new(big.Int).Mul(reward, new(big.Int).SetUint64(epochsPerYear*100)))
+	apr, _ := yearly.Quo(yearly, new(big.Float).SetInt(stake.ToInt())).Float64()
+
+	return &VoterRewardSimulation{
+		Masternode:  masternode,
+		Stake:       stake.ToInt(),
+		EpochReward: reward,
+		APR:         apr,
+	}, nil
+}
+
+// GetVotersCap returns the capacity the given voters staked on a masternode at
+// a checkpoint block.
+func (s *PublicPosvAPI) GetVotersCap(ctx context.Context, checkpoint rpc.BlockNumber, masternode common.Address, voters []common.Address) (map[common.Address]*big.Int, error) {
+	header, err := s.b.HeaderByNumber(ctx, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errEmptyHeader
+	}
+	if header.Number.Uint64()%s.b.ChainConfig().Posv.Epoch != 0 {
+		return nil, fmt.Errorf("block %d is not a checkpoint", header.Number.Uint64())
+	}
+	return s.b.GetVotersCap(ctx, header.Number, masternode, voters)
+}
+
+// EpochHead is the notification of a new epoch checkpoint block.
+type EpochHead struct {
+	Epoch       uint64           `json:"epoch"`
+	Number      uint64           `json:"number"`
+	Hash        common.Hash      `json:"hash"`
+	Masternodes []common.Address `json:"masternodes"`
+	Promoted    []common.Address `json:"promoted"` // Masternodes not in the set of the previous epoch
+	Demoted     []common.Address `json:"demoted"`  // Masternodes of the previous epoch no longer in the set
+	Rewards     *EpochRewards    `json:"rewards"`  // Nil if the rewards are not stored by the node
+}
+
+// EpochRewards summarizes the rewards distributed at an epoch checkpoint.
+type EpochRewards struct {
+	Signers int      `json:"signers"`
+	Signs   uint64   `json:"signs"`
+	Total   *big.Int `json:"total"`
+}
+
+// NewEpochHead sends a notification each time a checkpoint block is appended
+// to the chain, with the new masternode set and its changes.
+func (s *PublicPosvAPI) NewEpochHead(ctx context.Context) (*rpc.Subscription, error) {
+	config := s.b.ChainConfig().Posv
+	if config == nil {
+		return nil, errors.New("not a posv chain")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		heads := make(chan core.ChainHeadEvent, 16)
+		headSub := s.b.SubscribeChainHeadEvent(heads)
+		defer headSub.Unsubscribe()
+
+		// Chain head events may skip blocks, notify all the checkpoints passed
+		last := s.b.CurrentBlock().NumberU64()
+		for {
+			select {
+			case ev := <-heads:
+				number := ev.Block.NumberU64()
+				for checkpoint := last - last%config.Epoch + config.Epoch; checkpoint <= number; checkpoint += config.Epoch {
+					head, err := s.epochHead(ctx, checkpoint)
+					if err != nil {
+						log.Debug("Failed to assemble epoch head", "number", checkpoint, "err", err)
+						continue
+					}
+					notifier.Notify(rpcSub.ID, head)
+				}
+				if number > last {
+					last = number
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// epochHead assembles the notification of a checkpoint block.
+func (s *PublicPosvAPI) epochHead(ctx context.Context, number uint64) (*EpochHead, error) {
+	epoch := s.b.ChainConfig().Posv.Epoch
+	header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if header == nil || err != nil {
+		return nil, fmt.Errorf("checkpoint %d not found", number)
+	}
+	head := &EpochHead{
+		Epoch:       number / epoch,
+		Number:      number,
+		Hash:        header.Hash(),
+		Masternodes: posv.GetMasternodesFromCheckpointHeader(header),
+		Promoted:    []common.Address{},
+		Demoted:     []common.Address{},
+	}
+	var previous []common.Address
+	if number >= epoch {
+		parent, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number-epoch))
+		if parent == nil || err != nil {
+			return nil, fmt.Errorf("checkpoint %d not found", number-epoch)
+		}
+		previous = posv.GetMasternodesFromCheckpointHeader(parent)
+	}
+	head.Promoted = append(head.Promoted, addressesDiff(head.Masternodes, previous)...)
+	head.Demoted = append(head.Demoted, addressesDiff(previous, head.Masternodes)...)
+
+	// Summarize the signer rewards stored by the reward hook
+	if signers, ok := s.b.GetRewardByHash(head.Hash)["signers"].(map[string]interface{}); ok {
+		rewards := &EpochRewards{Signers: len(signers), Total: new(big.Int)}
+		for _, signer := range signers {
+			entry, ok := signer.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if sign, ok := entry["sign"].(json.Number); ok {
+				n, _ := sign.Int64()
+				rewards.Signs += uint64(n)
+			}
+			if reward, ok := entry["reward"].(json.Number); ok {
+				if n, ok := new(big.Int).SetString(reward.String(), 10); ok {
+					rewards.Total.Add(rewards.Total, n)
+				}
+			}
+		}
+		head.Rewards = rewards
+	}
+	return head, nil
+}
+
+// addressesDiff returns the addresses of a not in b.
+func addressesDiff(a, b []common.Address) []common.Address {
+	known := make(map[common.Address]struct{}, len(b))
+	for _, address := range b {
+		known[address] = struct{}{}
+	}
+	var diff []common.Address
+	for _, address := range a {
+		if _, ok := known[address]; !ok {
+			diff = append(diff, address)
+		}
+	}
+	return diff
+}
+
+// maxSignerCoverageBlocks is the maximum number of blocks covered by a single
+// signer coverage query.
+const maxSignerCoverageBlocks = 100000
+
+// SignerCoverage is the participation of a masternode in block signing.
+type SignerCoverage struct {
+	Expected   uint64  `json:"expected"`   // Blocks to sign while in the masternode set
+	Signed     uint64  `json:"signed"`     // Blocks signed among the expected ones
+	Percentage float64 `json:"percentage"` // Signed blocks over expected ones, in percent
+}
+
+// GetSignerCoverage returns the participation of the masternodes in the block
+// signing within a range of blocks, from the signer index built on import.
+// Only the blocks masternodes are rewarded for signing are counted.
+func (s *PublicPosvAPI) GetSignerCoverage(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) (map[common.Address]*SignerCoverage, error) {
+	config := s.b.ChainConfig()
+	if config.Posv == nil {
+		return nil, errors.New("not a posv chain")
+	}
+	head := s.b.CurrentBlock().NumberU64()
+	from, to := uint64(fromBlock.Int64()), uint64(toBlock.Int64())
+	if fromBlock == rpc.LatestBlockNumber || fromBlock == rpc.PendingBlockNumber {
+		from = head
+	}
+	if toBlock == rpc.LatestBlockNumber || toBlock == rpc.PendingBlockNumber {
+		to = head
+	}
+	if from == 0 {
+		from = 1
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from >= maxSignerCoverageBlocks {
+		return nil, fmt.Errorf("block range too large, maximum %d blocks", maxSignerCoverageBlocks)
+	}
+	tail, ok := core.GetSignerIndexTail(s.b.ChainDb())
+	if !ok {
+		return nil, errors.New("no block signers indexed")
+	}
+	if from < tail {
+		return nil, fmt.Errorf("block signers indexed from block %d", tail)
+	}
+	var (
+		coverage    = make(map[common.Address]*SignerCoverage)
+		checkpoint  = uint64(math.MaxUint64)
+		masternodes []common.Address
+	)
+	for number := from; number <= to; number++ {
+		if config.IsTIP2019(new(big.Int).SetUint64(number)) && number%common.MergeSignRange != 0 {
+			continue
+		}
+		// Blocks are signed by the masternodes of the checkpoint before them
+		if cp := (number - 1) - (number-1)%config.Posv.Epoch; cp != checkpoint {
+			header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(cp))
+			if header == nil || err != nil {
+				return nil, fmt.Errorf("checkpoint %d not found", cp)
+			}
+			checkpoint, masternodes = cp, posv.GetMasternodesFromCheckpointHeader(header)
+		}
+		header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		signers := make(map[common.Address]struct{})
+		for _, signer := range core.GetBlockSigners(s.b.ChainDb(), header.Hash(), number) {
+			signers[signer] = struct{}{}
+		}
+		for _, masternode := range masternodes {
+			stats := coverage[masternode]
+			if stats == nil {
+				stats = new(SignerCoverage)
+				coverage[masternode] = stats
+			}
+			stats.Expected++
+			if _, ok := signers[masternode]; ok {
+				stats.Signed++
+			}
+		}
+	}
+	for _, stats := range coverage {
+		stats.Percentage = float64(stats.Signed) * 100 / float64(stats.Expected)
+	}
+	return coverage, nil
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -1031,6 +1550,11 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if statedb == nil || err != nil {
 		return nil, 0, false, err
 	}
+	return s.applyCall(ctx, args, statedb, header, vmCfg, timeout)
+}
+
+// applyCall executes a call on top of the given state, which is modified.
+func (s *PublicBlockChainAPI) applyCall(ctx context.Context, args CallArgs, statedb *state.StateDB, header *types.Header, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -1095,6 +1619,49 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr r
 	return (hexutil.Bytes)(result), err
 }
 
+// maxMulticallCalls is the maximum number of calls executed by a multicall.
+const maxMulticallCalls = 100
+
+// MulticallResult is the outcome of a single call of a multicall.
+type MulticallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Failed     bool           `json:"failed"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Multicall executes an ordered list of calls against the state of the given
+// block number, in a single request. Calls are independent unless applyState
+// is set, in which case each call runs on top of the state changes of the
+// previous ones. The failure of a call is reported in its result only.
+func (s *PublicBlockChainAPI) Multicall(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, applyState *bool) ([]*MulticallResult, error) {
+	if len(calls) > maxMulticallCalls {
+		return nil, fmt.Errorf("too many calls: %d, maximum %d", len(calls), maxMulticallCalls)
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	chained := applyState != nil && *applyState
+
+	results := make([]*MulticallResult, len(calls))
+	for i, args := range calls {
+		callState := statedb
+		if !chained {
+			callState = statedb.Copy()
+		}
+		res, gas, failed, err := s.applyCall(ctx, args, callState, header, vm.Config{}, 5*time.Second)
+		results[i] = &MulticallResult{ReturnData: res, GasUsed: hexutil.Uint64(gas), Failed: failed}
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+		if chained {
+			statedb.Finalise(true)
+		}
+	}
+	return results, nil
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
@@ -1262,6 +1829,13 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		uncleHashes[i] = uncle.Hash()
 	}
 	fields["uncles"] = uncleHashes
+
+	if head.TradeRoot != (common.Hash{}) {
+		fields["tradesRoot"] = head.TradeRoot
+		if inclTx {
+			fields["trades"] = b.Trades()
+		}
+	}
 	return fields, nil
 }
 
@@ -1296,36 +1870,43 @@ func (s *PublicBlockChainAPI) findNearestSignedBlock(ctx context.Context, b *typ
 	Use blocksHashCache for to keep track - refer core/blockchain.go for more detail
 */
 func (s *PublicBlockChainAPI) findFinalityOfBlock(ctx context.Context, b *types.Block, masternodes []common.Address) (uint, error) {
+	blockSigners, err := s.findConfirmationsOfBlock(ctx, b)
+	if blockSigners == nil {
+		return 0, err
+	}
+	return uint(100 * len(blockSigners) / len(masternodes)), nil
+}
+
+/*
+	findConfirmationsOfBlock returns the masternodes which signed a block, nil
+	if the block is not signed yet or is on a fork path
+*/
+func (s *PublicBlockChainAPI) findConfirmationsOfBlock(ctx context.Context, b *types.Block) ([]common.Address, error) {
 	engine, _ := s.b.GetEngine().(*posv.Posv)
 	signedBlock := s.findNearestSignedBlock(ctx, b)
 
 	if signedBlock == nil {
-		return 0, nil
+		return nil, nil
 	}
 
 	signedBlocksHash := s.b.GetBlocksHashCache(signedBlock.Number().Uint64())
 
 	// there is no cache for this block's number
-	// return the number(signers) / number(masternode) * 100 if this block is on canonical path
-	// else return 0 for fork path
+	// return the signers if this block is on canonical path
+	// else return nil for fork path
 	if signedBlocksHash == nil {
 		if !s.b.AreTwoBlockSamePath(signedBlock.Hash(), b.Hash()) {
-			return 0, nil
-		}
-
-		blockSigners, err := s.getSigners(ctx, signedBlock, engine)
-		if blockSigners == nil {
-			return 0, err
+			return nil, nil
 		}
 
-		return uint(100 * len(blockSigners) / len(masternodes)), nil
+		return s.getSigners(ctx, signedBlock, engine)
 	}
 
 	/*
 		With Hashes cache - we can track all chain's path
 		back to current's block number by parent's Hash
-		If found the current block so the finality = signedBlock's finality
-		else return 0
+		If found the current block so the signers = signedBlock's signers
+		else return nil
 	*/
 
 	var signedBlockSamePath common.Hash
@@ -1338,23 +1919,18 @@ func (s *PublicBlockChainAPI) findFinalityOfBlock(ctx context.Context, b *types.
 		}
 	}
 
-	// return 0 if not same path with any signed block
+	// return nil if not same path with any signed block
 	if len(signedBlockSamePath) == 0 {
-		return 0, nil
+		return nil, nil
 	}
 
-	// get signers and return finality
+	// get signers of the signed block
 	samePathSignedBlock, err := s.b.GetBlock(ctx, signedBlockSamePath)
 	if samePathSignedBlock == nil {
-		return 0, err
-	}
-
-	blockSigners, err := s.getSigners(ctx, samePathSignedBlock, engine)
-	if blockSigners == nil {
-		return 0, err
+		return nil, err
 	}
 
-	return uint(100 * len(blockSigners) / len(masternodes)), nil
+	return s.getSigners(ctx, samePathSignedBlock, engine)
 }
 
 /*
@@ -1500,22 +2076,24 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	acl       *SignerACL
 }
 
 // PublicTransactionPoolAPI exposes methods for the RPC interface
 type PublicTomoXTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	acl       *SignerACL
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
-func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI {
-	return &PublicTransactionPoolAPI{b, nonceLock}
+func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker, acl *SignerACL) *PublicTransactionPoolAPI {
+	return &PublicTransactionPoolAPI{b, nonceLock, acl}
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
-func NewPublicTomoXTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTomoXTransactionPoolAPI {
-	return &PublicTomoXTransactionPoolAPI{b, nonceLock}
+func NewPublicTomoXTransactionPoolAPI(b Backend, nonceLock *AddrLocker, acl *SignerACL) *PublicTomoXTransactionPoolAPI {
+	return &PublicTomoXTransactionPoolAPI{b, nonceLock, acl}
 }
 
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
@@ -1655,11 +2233,48 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	// Count the masternodes which confirmed the block of a POSV chain
+	if _, ok := s.b.GetEngine().(*posv.Posv); ok {
+		block, err := s.b.GetBlock(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		signers, err := NewPublicBlockChainAPI(s.b).findConfirmationsOfBlock(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		fields["finality"] = hexutil.Uint(len(signers))
+	}
+	// Embed the trades resulting from the orders matched by the transaction
+	if tx.IsMatchingTransaction() {
+		trades, err := matchedTrades(tx)
+		if err != nil {
+			return nil, err
+		}
+		fields["trades"] = trades
+	}
 	return fields, nil
 }
 
+// matchedTrades returns the trades of the orders matched by a TomoX matching
+// transaction.
+func matchedTrades(tx *types.Transaction) ([]map[string]string, error) {
+	batch, err := tomox.DecodeTxMatchesBatch(tx.Data())
+	if err != nil {
+		return nil, err
+	}
+	trades := []map[string]string{}
+	for _, txMatch := range batch.Data {
+		trades = append(trades, txMatch.GetTrades()...)
+	}
+	return trades, nil
+}
+
 // sign is a helper function that signs a transaction with the private key of the given address.
 func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	if err := s.acl.checkTx(addr); err != nil {
+		return nil, err
+	}
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: addr}
 
@@ -1777,7 +2392,9 @@ func submitOrderTransaction(ctx context.Context, b Backend, tx *types.OrderTrans
 // SendTransaction creates a transaction for the given argument, sign it and submit it to the
 // transaction pool.
 func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
-
+	if err := s.acl.checkTx(args.From); err != nil {
+		return common.Hash{}, err
+	}
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: args.From}
 
@@ -1852,38 +2469,155 @@ type OrderMsg struct {
 
 	// This is only used when marshaling to JSON.
 	Hash common.Hash `json:"hash" rlp:"-"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty" rlp:"optional"`
+	ExpiryBlock         uint64 `json:"expiryBlock,omitempty" rlp:"optional"`
+}
+
+// toOrderTransaction creates the unsigned order transaction of the message.
+func (msg *OrderMsg) toOrderTransaction() *types.OrderTransaction {
+	tx := types.NewOrderTransaction(msg.AccountNonce, msg.Quantity, msg.Price, msg.ExchangeAddress, msg.UserAddress, msg.BaseToken, msg.QuoteToken, msg.Status, msg.Side, msg.Type, msg.PairName, msg.Hash, msg.OrderID)
+	tx.SetSelfTradePrevention(msg.SelfTradePrevention)
+	tx.SetExpiryBlock(msg.ExpiryBlock)
+	return tx
 }
 type PriceVolume struct {
 	Price  *big.Int `json:"price,omitempty"`
 	Volume *big.Int `json:"volume,omitempty"`
+
+	NormalizedPrice  string `json:"normalizedPrice,omitempty"`  // Price in whole quote tokens
+	NormalizedVolume string `json:"normalizedVolume,omitempty"` // Volume in whole base tokens
+}
+
+// PairInfo describes a trading pair listed by a relayer.
+type PairInfo struct {
+	BaseToken     common.Address `json:"baseToken"`
+	QuoteToken    common.Address `json:"quoteToken"`
+	OrderBook     common.Hash    `json:"orderBook"`
+	BaseDecimals  *uint8         `json:"baseDecimals"`
+	QuoteDecimals *uint8         `json:"quoteDecimals"`
+	Relayer       common.Address `json:"relayer"`
+	ListedBlock   hexutil.Uint64 `json:"listedBlock"`
+}
+
+// AveragePrice is the volume weighted average price of the trades of a pair
+// matched in a range of blocks.
+type AveragePrice struct {
+	Price     *big.Int       `json:"price"`
+	Volume    *big.Int       `json:"volume"`
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+
+	NormalizedPrice  string `json:"normalizedPrice,omitempty"`  // Price in whole quote tokens
+	NormalizedVolume string `json:"normalizedVolume,omitempty"` // Volume in whole base tokens
 }
 
 // SendOrder will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTomoXTransactionPoolAPI) SendOrder(ctx context.Context, msg OrderMsg) (common.Hash, error) {
-	tx := types.NewOrderTransaction(msg.AccountNonce, msg.Quantity, msg.Price, msg.ExchangeAddress, msg.UserAddress, msg.BaseToken, msg.QuoteToken, msg.Status, msg.Side, msg.Type, msg.PairName, msg.Hash, msg.OrderID)
+	tx := msg.toOrderTransaction()
 	tx = tx.ImportSignature(msg.V, msg.R, msg.S)
 	return submitOrderTransaction(ctx, s.b, tx)
 }
 
-// GetOrderCount returns the number of transactions the given address has sent for the given block number
-func (s *PublicTomoXTransactionPoolAPI) GetOrderCount(ctx context.Context, addr common.Address) (*hexutil.Uint64, error) {
+// GetOrderHash returns the canonical hash of the given order, as signed by the
+// user. The EIP-712 typed data of the order can be signed instead, see
+// types.OrderTypedData.
+func (s *PublicTomoXTransactionPoolAPI) GetOrderHash(ctx context.Context, msg OrderMsg) (common.Hash, error) {
+	tx := msg.toOrderTransaction()
+	if (tx.IsCancelledOrder() || tx.IsAmendedOrder()) && common.EmptyHash(tx.OrderHash()) {
+		return common.Hash{}, errors.New("missing hash of the order to cancel or amend")
+	}
+	return types.OrderTxSigner{}.Hash(tx), nil
+}
 
-	nonce, err := s.b.GetOrderNonce(addr.Hash())
-	if err != nil {
-		return (*hexutil.Uint64)(&nonce), err
+// GetOrderTypedData returns the EIP-712 typed data of the given order, to be
+// signed with eth_signTypedData as an alternative to its canonical hash.
+func (s *PublicTomoXTransactionPoolAPI) GetOrderTypedData(ctx context.Context, msg OrderMsg) (*types.TypedData, error) {
+	tx := msg.toOrderTransaction()
+	if (tx.IsCancelledOrder() || tx.IsAmendedOrder()) && common.EmptyHash(tx.OrderHash()) {
+		return nil, errors.New("missing hash of the order to cancel or amend")
 	}
-	return (*hexutil.Uint64)(&nonce), err
+	return types.OrderTypedData(tx), nil
 }
 
-func (s *PublicTomoXTransactionPoolAPI) GetBestBid(ctx context.Context, baseToken,quoteToken common.Address) (PriceVolume, error) {
+// SignOrderResult represents a RLP encoded signed order transaction.
+type SignOrderResult struct {
+	Raw   hexutil.Bytes `json:"raw"`
+	Order OrderMsg      `json:"order"`
+}
 
-	result := PriceVolume{}
-	block := s.b.CurrentBlock()
-	if block == nil {
-		return result, errors.New("Current block not found")
+// SignOrder signs the given order with the wallet holding its user address,
+// which may be a hardware wallet requesting a confirmation from the user. The
+// hash of a new order is filled in if missing. The signed order is returned
+// for submission with SendOrderRawTransaction.
+func (s *PublicTomoXTransactionPoolAPI) SignOrder(ctx context.Context, msg OrderMsg) (*SignOrderResult, error) {
+	return signOrder(s.b, s.acl, msg, func(wallet accounts.Wallet, account accounts.Account, hash []byte) ([]byte, error) {
+		return wallet.SignText(account, hash)
+	})
+}
+
+// signOrder signs an order with the wallet holding its user address, if the
+// ACL allows the account to.
+func signOrder(b Backend, acl *SignerACL, msg OrderMsg, sign func(accounts.Wallet, accounts.Account, []byte) ([]byte, error)) (*SignOrderResult, error) {
+	if err := acl.checkOrder(msg.UserAddress); err != nil {
+		return nil, err
 	}
-	tomoxService := s.b.TomoxService()
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: msg.UserAddress}
+
+	wallet, err := b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.OrderTxSigner{}
+	tx := msg.toOrderTransaction()
+	if tx.IsCancelledOrder() || tx.IsAmendedOrder() {
+		if common.EmptyHash(tx.OrderHash()) {
+			return nil, errors.New("missing hash of the order to cancel or amend")
+		}
+	} else if common.EmptyHash(tx.OrderHash()) {
+		tx.SetOrderHash(signer.Hash(tx))
+	}
+	// Orders are signed as Ethereum signed messages of their hash
+	sig, err := sign(wallet, account, signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	signed, err := tx.WithSignature(signer, sig)
+	if err != nil {
+		return nil, err
+	}
+	if from, err := types.OrderSender(signer, signed); err != nil || from != msg.UserAddress {
+		return nil, fmt.Errorf("invalid order signature from %s", wallet.URL())
+	}
+	data, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	msg.Hash = signed.OrderHash()
+	msg.V, msg.R, msg.S = signed.Signature()
+	return &SignOrderResult{data, msg}, nil
+}
+
+// GetOrderCount returns the number of transactions the given address has sent for the given block number
+func (s *PublicTomoXTransactionPoolAPI) GetOrderCount(ctx context.Context, addr common.Address) (*hexutil.Uint64, error) {
+
+	nonce, err := s.b.GetOrderNonce(addr.Hash())
+	if err != nil {
+		return (*hexutil.Uint64)(&nonce), err
+	}
+	return (*hexutil.Uint64)(&nonce), err
+}
+
+func (s *PublicTomoXTransactionPoolAPI) GetBestBid(ctx context.Context, baseToken,quoteToken common.Address) (PriceVolume, error) {
+
+	result := PriceVolume{}
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return result, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
 	if tomoxService == nil {
 		return result, errors.New("TomoX service not found")
 	}
@@ -1896,6 +2630,8 @@ func (s *PublicTomoXTransactionPoolAPI) GetBestBid(ctx context.Context, baseToke
 	if result.Price.Sign() == 0 {
 		return result, errors.New("Bid tree not found")
 	}
+	result.NormalizedPrice = s.normalizePrice(ctx, tomoxService, quoteToken, result.Price)
+	result.NormalizedVolume = s.normalizeQuantity(ctx, tomoxService, baseToken, result.Volume)
 	return result, nil
 }
 
@@ -1918,10 +2654,81 @@ func (s *PublicTomoXTransactionPoolAPI) GetBestAsk(ctx context.Context, baseToke
 	if result.Price.Sign() == 0 {
 		return result, errors.New("Ask tree not found")
 	}
+	result.NormalizedPrice = s.normalizePrice(ctx, tomoxService, quoteToken, result.Price)
+	result.NormalizedVolume = s.normalizeQuantity(ctx, tomoxService, baseToken, result.Volume)
 	return result, nil
 }
 
-func (s *PublicTomoXTransactionPoolAPI) GetBidTree(ctx context.Context, baseToken,quoteToken common.Address) (map[*big.Int]tomox_state.DumpOrderList, error) {
+// GetTWAP returns the volume weighted average price of the trades of a pair
+// matched in the last blocks, the whole price window of the oracle by default.
+func (s *PublicTomoXTransactionPoolAPI) GetTWAP(ctx context.Context, baseToken, quoteToken common.Address, blocks *uint64) (*AveragePrice, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	tomoxState, err := tomoxService.GetTomoxState(block)
+	if err != nil {
+		return nil, err
+	}
+	window := uint64(common.TomoXPriceWindow)
+	if blocks != nil {
+		if *blocks == 0 || *blocks > window {
+			return nil, fmt.Errorf("blocks must be between 1 and %d", window)
+		}
+		window = *blocks
+	}
+	since := uint64(0)
+	if number := block.NumberU64(); number >= window {
+		since = number - window + 1
+	}
+	price, volume := tomoxState.GetPriceHistory(baseToken, quoteToken).AveragePrice(since)
+	if price == nil {
+		return nil, errors.New("No trade found in the price window")
+	}
+	return &AveragePrice{
+		Price:            price,
+		Volume:           volume,
+		FromBlock:        hexutil.Uint64(since),
+		ToBlock:          hexutil.Uint64(block.NumberU64()),
+		NormalizedPrice:  s.normalizePrice(ctx, tomoxService, quoteToken, price),
+		NormalizedVolume: s.normalizeQuantity(ctx, tomoxService, baseToken, volume),
+	}, nil
+}
+
+// maxExpiringOrdersBlocks is the maximum number of blocks looked ahead for the
+// expiring orders.
+const maxExpiringOrdersBlocks = 10000
+
+// GetExpiringOrders returns the orders resting in the order books which expire
+// within the given number of blocks after the current one.
+func (s *PublicTomoXTransactionPoolAPI) GetExpiringOrders(ctx context.Context, blocks uint64) ([]tomox_state.ExpiringOrder, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	if blocks == 0 || blocks > maxExpiringOrdersBlocks {
+		return nil, fmt.Errorf("blocks must be between 1 and %d", maxExpiringOrdersBlocks)
+	}
+	tomoxState, err := tomoxService.GetTomoxState(block)
+	if err != nil {
+		return nil, err
+	}
+	orders := tomoxState.GetRestingExpiringOrders(block.NumberU64()+1, block.NumberU64()+blocks)
+	if orders == nil {
+		orders = []tomox_state.ExpiringOrder{}
+	}
+	return orders, nil
+}
+
+func (s *PublicTomoXTransactionPoolAPI) GetBidTree(ctx context.Context, baseToken,quoteToken common.Address) (tomox_state.DumpOrderTree, error) {
 	block := s.b.CurrentBlock()
 	if block == nil {
 		return nil, errors.New("Current block not found")
@@ -1934,14 +2741,15 @@ func (s *PublicTomoXTransactionPoolAPI) GetBidTree(ctx context.Context, baseToke
 	if err != nil {
 		return nil, err
 	}
-	result, err := tomoxState.DumpBidTrie(tomox.GetOrderBookHash(baseToken,quoteToken))
+	result, err := tomoxState.DumpBidTrie(ctx, tomox.GetOrderBookHash(baseToken,quoteToken))
 	if err != nil {
 		return nil, err
 	}
+	s.normalizeDump(ctx, tomoxService, baseToken, quoteToken, result)
 	return result, nil
 }
 
-func (s *PublicTomoXTransactionPoolAPI) GetAskTree(ctx context.Context, baseToken,quoteToken common.Address) (map[*big.Int]tomox_state.DumpOrderList, error) {
+func (s *PublicTomoXTransactionPoolAPI) GetAskTree(ctx context.Context, baseToken,quoteToken common.Address) (tomox_state.DumpOrderTree, error) {
 	block := s.b.CurrentBlock()
 	if block == nil {
 		return nil, errors.New("Current block not found")
@@ -1954,13 +2762,100 @@ func (s *PublicTomoXTransactionPoolAPI) GetAskTree(ctx context.Context, baseToke
 	if err != nil {
 		return nil, err
 	}
-	result, err := tomoxState.DumpAskTrie(tomox.GetOrderBookHash(baseToken,quoteToken))
+	result, err := tomoxState.DumpAskTrie(ctx, tomox.GetOrderBookHash(baseToken,quoteToken))
 	if err != nil {
 		return nil, err
 	}
+	s.normalizeDump(ctx, tomoxService, baseToken, quoteToken, result)
 	return result, nil
 }
 
+// GetOrderBookDiff returns the price levels of the order book of a pair added,
+// removed or changed between two blocks, for the clients maintaining a local
+// copy of the book instead of polling its trees.
+func (s *PublicTomoXTransactionPoolAPI) GetOrderBookDiff(ctx context.Context, baseToken, quoteToken common.Address, fromBlock, toBlock rpc.BlockNumber) (*tomox_state.OrderBookDiff, error) {
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	from, err := s.tomoxStateAt(ctx, tomoxService, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.tomoxStateAt(ctx, tomoxService, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	return tomox_state.DiffOrderBook(ctx, from, to, tomox.GetOrderBookHash(baseToken, quoteToken))
+}
+
+// tomoxStateAt returns the TomoX state at a block.
+func (s *PublicTomoXTransactionPoolAPI) tomoxStateAt(ctx context.Context, tomoxService *tomox.TomoX, number rpc.BlockNumber) (*tomox_state.TomoXStateDB, error) {
+	block, err := s.b.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, &NotFoundError{What: fmt.Sprintf("block %d", number)}
+	}
+	return tomoxService.GetTomoxState(block)
+}
+
+// EstimateMatch dry-runs the matching of an order against the order book of its
+// pair at the latest block, or the pending one, returning the fills it would get
+// without sending it. The order needn't be signed, its nonce being ignored.
+func (s *PublicTomoXTransactionPoolAPI) EstimateMatch(ctx context.Context, msg OrderMsg, blockNr *rpc.BlockNumber) (*tomox.MatchEstimate, error) {
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	if msg.Quantity == nil || msg.Price == nil {
+		return nil, errors.New("missing order quantity or price")
+	}
+	if msg.Status == tomox.OrderStatusCancelled || msg.Status == tomox.OrderStatusAmended {
+		return nil, errors.New("cancellations and amendments are not matched")
+	}
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, number)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	var tomoxState *tomox_state.TomoXStateDB
+	if number == rpc.PendingBlockNumber {
+		if _, tomoxState, _ = s.b.PendingTomoX(); tomoxState == nil {
+			return nil, errors.New("pending TomoX state not available")
+		}
+	} else if tomoxState, err = s.tomoxStateAt(ctx, tomoxService, number); err != nil {
+		return nil, err
+	}
+	// Resolve the decimals of the pair beforehand, the matching not querying
+	// the token contracts over IPC
+	if s.tokenDecimal(ctx, tomoxService, msg.BaseToken) == nil || s.tokenDecimal(ctx, tomoxService, msg.QuoteToken) == nil {
+		return nil, errors.New("unknown token decimals")
+	}
+	tx := msg.toOrderTransaction()
+	order := &tomox_state.OrderItem{
+		Quantity:            msg.Quantity,
+		Price:               msg.Price,
+		ExchangeAddress:     msg.ExchangeAddress,
+		UserAddress:         msg.UserAddress,
+		BaseToken:           msg.BaseToken,
+		QuoteToken:          msg.QuoteToken,
+		Status:              tomox.OrderStatusNew,
+		Side:                msg.Side,
+		Type:                msg.Type,
+		Hash:                types.OrderTxSigner{}.Hash(tx),
+		PairName:            msg.PairName,
+		SelfTradePrevention: msg.SelfTradePrevention,
+		ExpiryBlock:         msg.ExpiryBlock,
+	}
+	// The order would be matched in the block after the requested one
+	return tomoxService.EstimateMatch(header.Coinbase, "", statedb, tomoxState, header.Number.Uint64()+1, order)
+}
+
 func (s *PublicTomoXTransactionPoolAPI) GetOrderById(ctx context.Context, baseToken,quoteToken common.Address, orderId uint64) (interface{}, error) {
 	block := s.b.CurrentBlock()
 	if block == nil {
@@ -1982,6 +2877,275 @@ func (s *PublicTomoXTransactionPoolAPI) GetOrderById(ctx context.Context, baseTo
 	return orderitem, nil
 }
 
+// GetPairs returns the trading pairs listed by the relayers, with the decimals
+// of their tokens at the current block. The listing block of the pairs listed
+// before the node tracked them is zero.
+func (s *PublicTomoXTransactionPoolAPI) GetPairs(ctx context.Context) ([]PairInfo, error) {
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	pairs := tomoxService.GetPairs()
+	if pairs == nil {
+		statedb, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if statedb == nil || err != nil {
+			return nil, err
+		}
+		tomoxService.UpdatePairs(types.NewBlockWithHeader(header), statedb)
+		pairs = tomoxService.GetPairs()
+	}
+	result := make([]PairInfo, 0, len(pairs))
+	tokenDecimals := func(token common.Address) *uint8 {
+		tokenDecimal := s.tokenDecimal(ctx, tomoxService, token)
+		if tokenDecimal == nil {
+			return nil
+		}
+		decimals := tomox.TokenDecimals(tokenDecimal)
+		return &decimals
+	}
+	for _, pair := range pairs {
+		result = append(result, PairInfo{
+			BaseToken:     pair.BaseToken,
+			QuoteToken:    pair.QuoteToken,
+			OrderBook:     pair.OrderBook(),
+			BaseDecimals:  tokenDecimals(pair.BaseToken),
+			QuoteDecimals: tokenDecimals(pair.QuoteToken),
+			Relayer:       pair.Relayer,
+			ListedBlock:   hexutil.Uint64(pair.ListedBlock),
+		})
+	}
+	return result, nil
+}
+
+// tokenDecimal returns the decimal of a token, as the power of ten of its
+// decimals, calling the decimals method of its contract at the current block
+// if TomoX doesn't know it yet. Nil is returned if the token has no decimals.
+func (s *PublicTomoXTransactionPoolAPI) tokenDecimal(ctx context.Context, tomoxService *tomox.TomoX, token common.Address) *big.Int {
+	if token == common.HexToAddress(common.TomoNativeAddress) {
+		return common.BasePrice
+	}
+	if tokenDecimal, ok := tomoxService.CachedTokenDecimal(token); ok {
+		return tokenDecimal
+	}
+	// decimals() method selector
+	args := CallArgs{To: &token, Data: hexutil.Bytes{0x31, 0x3c, 0xe5, 0x67}}
+	result, _, failed, err := NewPublicBlockChainAPI(s.b).doCall(ctx, args, rpc.LatestBlockNumber, vm.Config{}, 5*time.Second)
+	if err != nil || failed || len(result) != 32 || new(big.Int).SetBytes(result).BitLen() > 8 {
+		return nil
+	}
+	tokenDecimal := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(result[31])), nil)
+	tomoxService.SetTokenDecimal(token, tokenDecimal)
+	return tokenDecimal
+}
+
+// normalizePrice formats a price of a pair as a number of whole quote tokens,
+// empty if the decimals of the quote token are unknown.
+func (s *PublicTomoXTransactionPoolAPI) normalizePrice(ctx context.Context, tomoxService *tomox.TomoX, quoteToken common.Address, price *big.Int) string {
+	return tomox.NormalizePrice(price, s.tokenDecimal(ctx, tomoxService, quoteToken))
+}
+
+// normalizeQuantity formats a quantity of a pair as a number of whole base
+// tokens, empty if the decimals of the base token are unknown.
+func (s *PublicTomoXTransactionPoolAPI) normalizeQuantity(ctx context.Context, tomoxService *tomox.TomoX, baseToken common.Address, quantity *big.Int) string {
+	return tomox.NormalizeQuantity(quantity, s.tokenDecimal(ctx, tomoxService, baseToken))
+}
+
+// normalizeDump fills in the normalized prices and volumes of an order book dump.
+func (s *PublicTomoXTransactionPoolAPI) normalizeDump(ctx context.Context, tomoxService *tomox.TomoX, baseToken, quoteToken common.Address, dump tomox_state.DumpOrderTree) {
+	for price, orderList := range dump {
+		if p, ok := new(big.Int).SetString(price, 10); ok {
+			orderList.NormalizedPrice = s.normalizePrice(ctx, tomoxService, quoteToken, p)
+		}
+		orderList.NormalizedVolume = s.normalizeQuantity(ctx, tomoxService, baseToken, orderList.Volume)
+		dump[price] = orderList
+	}
+}
+
+// GetOpenOrders returns the orders of a user open at the current block, in
+// the order books the user placed orders in.
+func (s *PublicTomoXTransactionPoolAPI) GetOpenOrders(ctx context.Context, user common.Address) ([]tomox_state.OrderItem, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	tomoxState, err := tomoxService.GetTomoxState(block)
+	if err != nil {
+		return nil, err
+	}
+	return tomoxService.GetOpenOrders(tomoxState, user), nil
+}
+
+// GetOrderReceipt returns the execution result of an order by the matching
+// engine, nil if the order is not matched on the canonical chain.
+func (s *PublicTomoXTransactionPoolAPI) GetOrderReceipt(ctx context.Context, orderHash common.Hash) (*tomox.OrderReceipt, error) {
+	receipt := core.GetOrderReceipt(s.b.ChainDb(), orderHash)
+	if receipt == nil {
+		return nil, nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(receipt.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	// The block of the receipt was dropped by a reorg
+	if header == nil || header.Hash() != receipt.BlockHash {
+		return nil, nil
+	}
+	return receipt, nil
+}
+
+// GetRelayerFees returns the trading fees earned and the matching fees paid by
+// a relayer in the given epoch, the current one by default, summed over the
+// canonical blocks from the fee index.
+func (s *PublicTomoXTransactionPoolAPI) GetRelayerFees(ctx context.Context, relayer common.Address, epoch *uint64) (*tomox.RelayerFees, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	config := s.b.ChainConfig()
+	if config.Posv == nil || config.Posv.Epoch == 0 {
+		return nil, errors.New("Chain has no epochs")
+	}
+	current := block.NumberU64() / config.Posv.Epoch
+	if epoch == nil {
+		epoch = &current
+	} else if *epoch > current {
+		return nil, fmt.Errorf("epoch %d is after the current epoch %d", *epoch, current)
+	}
+	canonical := func(number uint64, hash common.Hash) bool {
+		header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		return err == nil && header != nil && header.Hash() == hash
+	}
+	return tomoxService.TradingFees(relayer, *epoch, canonical), nil
+}
+
+// maxTopTraders is the maximum number of traders returned by GetTopTraders.
+const maxTopTraders = 1000
+
+// GetTopTraders returns the addresses which traded the largest volumes on a
+// pair in the given epoch, the current one by default, summed over the
+// canonical blocks from the volume index. At most limit traders are returned,
+// 10 by default.
+func (s *PublicTomoXTransactionPoolAPI) GetTopTraders(ctx context.Context, baseToken, quoteToken common.Address, epoch *uint64, limit *int) ([]*tomox.TraderVolume, error) {
+	block := s.b.CurrentBlock()
+	if block == nil {
+		return nil, errors.New("Current block not found")
+	}
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	config := s.b.ChainConfig()
+	if config.Posv == nil || config.Posv.Epoch == 0 {
+		return nil, errors.New("Chain has no epochs")
+	}
+	current := block.NumberU64() / config.Posv.Epoch
+	if epoch == nil {
+		epoch = &current
+	} else if *epoch > current {
+		return nil, fmt.Errorf("epoch %d is after the current epoch %d", *epoch, current)
+	}
+	count := 10
+	if limit != nil {
+		count = *limit
+	}
+	if count <= 0 || count > maxTopTraders {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxTopTraders)
+	}
+	canonical := func(number uint64, hash common.Hash) bool {
+		header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		return err == nil && header != nil && header.Hash() == hash
+	}
+	return tomoxService.TopTraders(baseToken, quoteToken, *epoch, count, canonical), nil
+}
+
+// PendingMatch is the projected matching result of a pending order.
+type PendingMatch struct {
+	Order    *tomox_state.OrderItem   `json:"order"`
+	Trades   []map[string]string      `json:"trades"`
+	Rejected []*tomox_state.OrderItem `json:"rejected"`
+}
+
+// PendingMatches is the TomoX state projected after the pending orders of the
+// pending block.
+type PendingMatches struct {
+	Number     uint64         `json:"number"`
+	ParentHash common.Hash    `json:"parentHash"`
+	TomoxRoot  common.Hash    `json:"tomoxRoot"`
+	Matches    []PendingMatch `json:"matches"`
+}
+
+// GetPendingMatches returns the matching results of the pending orders on top
+// of the pending block, so that traders can estimate their fills before the
+// block is sealed.
+func (s *PublicTomoXTransactionPoolAPI) GetPendingMatches(ctx context.Context) (*PendingMatches, error) {
+	block, tomoxState, txMatches := s.b.PendingTomoX()
+	if block == nil || tomoxState == nil {
+		return nil, errors.New("pending TomoX state not available")
+	}
+	pending := &PendingMatches{
+		Number:     block.NumberU64(),
+		ParentHash: block.ParentHash(),
+		TomoxRoot:  tomoxState.IntermediateRoot(),
+		Matches:    make([]PendingMatch, 0, len(txMatches)),
+	}
+	for _, txMatch := range txMatches {
+		order, err := txMatch.DecodeOrder()
+		if err != nil {
+			return nil, err
+		}
+		pending.Matches = append(pending.Matches, PendingMatch{Order: order, Trades: txMatch.GetTrades(), Rejected: txMatch.GetRejectedOrders()})
+	}
+	return pending, nil
+}
+
+// NewPendingMatches sends a notification each time the pending block changes,
+// with the matching results of the pending orders on top of it.
+func (s *PublicTomoXTransactionPoolAPI) NewPendingMatches(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		sub := s.b.EventMux().Subscribe(core.PendingStateEvent{})
+		defer sub.Unsubscribe()
+
+		var last *PendingMatches
+		for {
+			select {
+			case _, ok := <-sub.Chan():
+				if !ok {
+					return
+				}
+				pending, err := s.GetPendingMatches(ctx)
+				if err != nil {
+					continue
+				}
+				// Skip the pending state changes not affecting the projection
+				if last != nil && last.Number == pending.Number && last.ParentHash == pending.ParentHash && last.TomoxRoot == pending.TomoxRoot {
+					continue
+				}
+				last = pending
+				notifier.Notify(rpcSub.ID, pending)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -2000,7 +3164,35 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 		return nil, err
 	}
 	// Sign the requested hash with the wallet
-	signature, err := wallet.SignHash(account, signHash(data))
+	signature, err := wallet.SignText(account, data)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
+// SignTypedData calculates an Ethereum ECDSA signature for the EIP-712 hash of
+// the given structured data:
+// keccack256("\x19\x01" + domainSeparator + hashStruct(message)).
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, typedData types.TypedData) (hexutil.Bytes, error) {
+	hash, err := typedData.Hash()
+	if err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the requested hash with the wallet
+	signature, err := wallet.SignHash(account, hash.Bytes())
 	if err == nil {
 		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
 	}
@@ -2275,18 +3467,95 @@ func GetSignersFromBlocks(b Backend, blockNumber uint64, blockHash common.Hash,
 	return addrs, nil
 }
 
+// TomoXProofResult is the merkle proof of an entry of an order book, an order
+// or a price level, against the TomoX state root of a block, carried by its
+// TomoX state transaction. The exchange proof proves the order book object
+// under the order book hash, the entry proof the entry under the order, ask or
+// bid root of that object. A missing entry is proven by a proof of absence.
+type TomoXProofResult struct {
+	BlockHash     common.Hash     `json:"blockHash"`
+	TomoxRoot     common.Hash     `json:"tomoxRoot"`
+	OrderBook     common.Hash     `json:"orderBook"`
+	Exchange      hexutil.Bytes   `json:"exchange"`
+	ExchangeProof []hexutil.Bytes `json:"exchangeProof"`
+	Side          string          `json:"side,omitempty"`
+	Key           common.Hash     `json:"key"`
+	Value         hexutil.Bytes   `json:"value"`
+	Proof         []hexutil.Bytes `json:"proof"`
+}
+
+// GetOrderProof returns the proof of an order of the order book of a pair in
+// the TomoX state of a block.
+func (s *PublicBlockChainAPI) GetOrderProof(ctx context.Context, baseToken, quoteToken common.Address, orderId uint64, blockNr rpc.BlockNumber) (*TomoXProofResult, error) {
+	return s.tomoxProof(ctx, blockNr, func(statedb *tomox_state.TomoXStateDB) (*tomox_state.EntryProof, error) {
+		return statedb.ProveOrder(tomox.GetOrderBookHash(baseToken, quoteToken), common.BigToHash(new(big.Int).SetUint64(orderId)))
+	})
+}
+
+// GetPriceLevelProof returns the proof of a price level of a side, BUY or SELL,
+// of the order book of a pair in the TomoX state of a block.
+func (s *PublicBlockChainAPI) GetPriceLevelProof(ctx context.Context, baseToken, quoteToken common.Address, side string, price hexutil.Big, blockNr rpc.BlockNumber) (*TomoXProofResult, error) {
+	return s.tomoxProof(ctx, blockNr, func(statedb *tomox_state.TomoXStateDB) (*tomox_state.EntryProof, error) {
+		return statedb.ProvePriceLevel(tomox.GetOrderBookHash(baseToken, quoteToken), side, common.BigToHash(price.ToInt()))
+	})
+}
+
+func (s *PublicBlockChainAPI) tomoxProof(ctx context.Context, blockNr rpc.BlockNumber, prove func(*tomox_state.TomoXStateDB) (*tomox_state.EntryProof, error)) (*TomoXProofResult, error) {
+	tomoxService := s.b.TomoxService()
+	if tomoxService == nil {
+		return nil, errors.New("TomoX service not found")
+	}
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, &NotFoundError{What: fmt.Sprintf("block %d", blockNr)}
+	}
+	root, err := tomoxService.GetTomoxStateRoot(block)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := tomoxService.GetTomoxState(block)
+	if err != nil {
+		return nil, &StateUnavailableError{Number: block.NumberU64(), Err: err}
+	}
+	proof, err := prove(statedb)
+	if err != nil {
+		return nil, err
+	}
+	return &TomoXProofResult{
+		BlockHash:     block.Hash(),
+		TomoxRoot:     root,
+		OrderBook:     proof.OrderBook,
+		Exchange:      proof.Exchange,
+		ExchangeProof: toHexNodes(proof.ExchangeProof),
+		Side:          proof.Side,
+		Key:           proof.Key,
+		Value:         proof.Value,
+		Proof:         toHexNodes(proof.Proof),
+	}, nil
+}
+
 // GetStakerROI Estimate ROI for stakers using the last epoc reward
 // then multiple by epoch per year, if the address is not masternode of last epoch - return 0
 // Formular:
 // 		ROI = average_latest_epoch_reward_for_voters*number_of_epoch_per_year/latest_total_cap*100
-func (s *PublicBlockChainAPI) GetStakerROI() float64 {
+func (s *PublicBlockChainAPI) GetStakerROI(ctx context.Context) (float64, error) {
 	blockNumber := s.b.CurrentBlock().Number().Uint64()
+	if blockNumber < 2*s.b.ChainConfig().Posv.Epoch {
+		return 0, nil
+	}
 	lastCheckpointNumber := blockNumber - (blockNumber % s.b.ChainConfig().Posv.Epoch) - s.b.ChainConfig().Posv.Epoch // calculate for 2 epochs ago
 	totalCap := new(big.Int).SetUint64(0)
 
-	mastersCap := s.b.GetMasternodesCap(lastCheckpointNumber)
-	if mastersCap == nil {
-		return 0
+	mastersCap, err := s.b.GetMasternodesCap(ctx, lastCheckpointNumber)
+	if err != nil {
+		return 0, err
+	}
+	epochDuration := s.b.GetEpochDuration()
+	if epochDuration == nil || epochDuration.Sign() <= 0 {
+		return 0, errNoEpochDuration
 	}
 
 	masternodeReward := new(big.Int).Mul(new(big.Int).SetUint64(s.b.ChainConfig().Posv.Reward), new(big.Int).SetUint64(params.Ether))
@@ -2296,19 +3565,26 @@ func (s *PublicBlockChainAPI) GetStakerROI() float64 {
 	}
 
 	holderReward := new(big.Int).Div(masternodeReward, new(big.Int).SetUint64(2))
-	EpochPerYear := 365 * 86400 / s.b.GetEpochDuration().Uint64()
+	EpochPerYear := 365 * 86400 / epochDuration.Uint64()
 	voterRewardAYear := new(big.Int).Mul(holderReward, new(big.Int).SetUint64(EpochPerYear))
-	return 100.0 / float64(totalCap.Div(totalCap, voterRewardAYear).Uint64())
+	return 100.0 / float64(totalCap.Div(totalCap, voterRewardAYear).Uint64()), nil
 }
 
 // GetStakerROIMasternode Estimate ROI for stakers of a specific masternode using the last epoc reward
 // then multiple by epoch per year, if the address is not masternode of last epoch - return 0
 // Formular:
 // 		ROI = latest_epoch_reward_for_voters*number_of_epoch_per_year/latest_total_cap*100
-func (s *PublicBlockChainAPI) GetStakerROIMasternode(masternode common.Address) float64 {
-	votersReward := s.b.GetVotersRewards(masternode)
-	if votersReward == nil {
-		return 0
+func (s *PublicBlockChainAPI) GetStakerROIMasternode(ctx context.Context, masternode common.Address) (float64, error) {
+	votersReward, err := s.b.GetVotersRewards(ctx, masternode)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return 0, nil
+		}
+		return 0, err
+	}
+	epochDuration := s.b.GetEpochDuration()
+	if epochDuration == nil || epochDuration.Sign() <= 0 {
+		return 0, errNoEpochDuration
 	}
 
 	masternodeReward := new(big.Int).SetUint64(0) // this includes all reward for this masternode
@@ -2321,16 +3597,193 @@ func (s *PublicBlockChainAPI) GetStakerROIMasternode(masternode common.Address)
 	blockNumber := s.b.CurrentBlock().Number().Uint64()
 	lastCheckpointNumber := blockNumber - blockNumber%s.b.ChainConfig().Posv.Epoch
 	totalCap := new(big.Int).SetUint64(0)
-	votersCap := s.b.GetVotersCap(new(big.Int).SetUint64(lastCheckpointNumber), masternode, voters)
+	votersCap, err := s.b.GetVotersCap(ctx, new(big.Int).SetUint64(lastCheckpointNumber), masternode, voters)
+	if err != nil {
+		return 0, err
+	}
 
 	for _, cap := range votersCap {
 		totalCap.Add(totalCap, cap)
 	}
 
-	// holder reward = 50% total reward of a masternode
-	holderReward := new(big.Int).Div(masternodeReward, new(big.Int).SetUint64(2))
-	EpochPerYear := 365 * 86400 / s.b.GetEpochDuration().Uint64()
+	// holder reward = voter share of the total reward of a masternode
+	split := s.b.ChainConfig().Posv.RewardSplitAt(s.b.CurrentBlock().Number())
+	holderReward := new(big.Int).Mul(masternodeReward, new(big.Int).SetUint64(split.Voter))
+	holderReward.Div(holderReward, big.NewInt(100))
+	EpochPerYear := 365 * 86400 / epochDuration.Uint64()
 	voterRewardAYear := new(big.Int).Mul(holderReward, new(big.Int).SetUint64(EpochPerYear))
 
-	return 100.0 / float64(totalCap.Div(totalCap, voterRewardAYear).Uint64())
+	return 100.0 / float64(totalCap.Div(totalCap, voterRewardAYear).Uint64()), nil
+}
+
+// PublicTomoXLendingAPI exposes the TomoX lending books and accepts the lending
+// orders signed by the users.
+type PublicTomoXLendingAPI struct {
+	b Backend
+}
+
+// NewPublicTomoXLendingAPI creates a new RPC service for the TomoX lending.
+func NewPublicTomoXLendingAPI(b Backend) *PublicTomoXLendingAPI {
+	return &PublicTomoXLendingAPI{b}
+}
+
+// LendingMsg is a lending order signed by a user. The signature is the
+// [R || S || V] signature of the hash returned by tomoxlending_getLendingOrderHash,
+// signed as a message.
+type LendingMsg struct {
+	Nonce           uint64         `json:"nonce"`
+	Relayer         common.Address `json:"relayer"`
+	UserAddress     common.Address `json:"userAddress"`
+	LendingToken    common.Address `json:"lendingToken"`
+	CollateralToken common.Address `json:"collateralToken,omitempty"`
+	Term            uint64         `json:"term"`
+	Interest        *big.Int       `json:"interest,omitempty"`
+	Quantity        *big.Int       `json:"quantity,omitempty"`
+	Collateral      *big.Int       `json:"collateral,omitempty"`
+	Side            string         `json:"side,omitempty"`
+	Type            string         `json:"type,omitempty"`
+	LendingID       uint64         `json:"lendingId,omitempty"`
+	Hash            common.Hash    `json:"hash"`
+	Signature       hexutil.Bytes  `json:"signature"`
+}
+
+// LendingBookResult is the content of a lending book.
+type LendingBookResult struct {
+	Investing []*lendingstate.LendingItem  `json:"investing"`
+	Borrowing []*lendingstate.LendingItem  `json:"borrowing"`
+	Trades    []*lendingstate.LendingTrade `json:"trades"`
+}
+
+func (msg *LendingMsg) toOrder() *tomoxlending.LendingOrder {
+	return &tomoxlending.LendingOrder{
+		Relayer:         msg.Relayer,
+		UserAddress:     msg.UserAddress,
+		LendingToken:    msg.LendingToken,
+		CollateralToken: msg.CollateralToken,
+		Term:            msg.Term,
+		Interest:        msg.Interest,
+		Quantity:        msg.Quantity,
+		Collateral:      msg.Collateral,
+		Side:            msg.Side,
+		Type:            msg.Type,
+		LendingID:       msg.LendingID,
+		Nonce:           msg.Nonce,
+		Hash:            msg.Hash,
+		Signature:       msg.Signature,
+	}
+}
+
+// lendingState returns the lending service and its state at the head of the
+// chain.
+func (s *PublicTomoXLendingAPI) lendingState() (*tomoxlending.Lending, *lendingstate.LendingStateDB, error) {
+	tomoX := s.b.TomoxService()
+	if tomoX == nil || tomoX.GetLending() == nil {
+		return nil, nil, errors.New("cannot find tomox lending service")
+	}
+	lendingState, err := tomoX.GetLending().GetLendingState(s.b.CurrentBlock())
+	if err != nil {
+		return nil, nil, err
+	}
+	return tomoX.GetLending(), lendingState, nil
+}
+
+// submit queues a lending order to be processed in a block mined by this node.
+func (s *PublicTomoXLendingAPI) submit(order *tomoxlending.LendingOrder) (common.Hash, error) {
+	// The order would be processed in the block after the head
+	if next := new(big.Int).Add(s.b.CurrentBlock().Number(), common.Big1); !s.b.ChainConfig().IsLending(next) {
+		return common.Hash{}, errNoLending
+	}
+	lending, lendingState, err := s.lendingState()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := lending.Pool().Add(order, lendingState); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted lending order", "hash", order.Hash.Hex(), "user", order.UserAddress, "type", order.Type, "side", order.Side, "nonce", order.Nonce)
+	return order.Hash, nil
+}
+
+// Lend offers a quantity of a lending token for a term, at the given yearly
+// interest rate or above.
+func (s *PublicTomoXLendingAPI) Lend(ctx context.Context, msg LendingMsg) (common.Hash, error) {
+	order := msg.toOrder()
+	order.Type, order.Side = tomoxlending.Limit, lendingstate.Investing
+	return s.submit(order)
+}
+
+// Borrow asks for a quantity of a lending token for a term, at the given
+// yearly interest rate or below, pledging the given collateral.
+func (s *PublicTomoXLendingAPI) Borrow(ctx context.Context, msg LendingMsg) (common.Hash, error) {
+	order := msg.toOrder()
+	order.Type, order.Side = tomoxlending.Limit, lendingstate.Borrowing
+	return s.submit(order)
+}
+
+// Repay repays the loan lendingId of the lending book of the lending token and
+// term, releasing its collateral.
+func (s *PublicTomoXLendingAPI) Repay(ctx context.Context, msg LendingMsg) (common.Hash, error) {
+	order := msg.toOrder()
+	order.Type = tomoxlending.Repay
+	return s.submit(order)
+}
+
+// Cancel cancels the resting order lendingId of the lending book of the lending
+// token and term, refunding its remaining funds.
+func (s *PublicTomoXLendingAPI) Cancel(ctx context.Context, msg LendingMsg) (common.Hash, error) {
+	order := msg.toOrder()
+	order.Type = tomoxlending.Cancel
+	return s.submit(order)
+}
+
+// GetLendingOrderHash returns the hash of the given lending order, to be signed
+// by the user.
+func (s *PublicTomoXLendingAPI) GetLendingOrderHash(ctx context.Context, msg LendingMsg) common.Hash {
+	return msg.toOrder().ComputeHash()
+}
+
+// GetLendingNonce returns the nonce of the next lending order of a user.
+func (s *PublicTomoXLendingAPI) GetLendingNonce(ctx context.Context, addr common.Address) (hexutil.Uint64, error) {
+	_, lendingState, err := s.lendingState()
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(lendingState.GetNonce(addr)), nil
+}
+
+// GetLendingBook returns the resting orders and the open loans of the lending
+// book of a lending token for a term.
+func (s *PublicTomoXLendingAPI) GetLendingBook(ctx context.Context, lendingToken common.Address, term uint64) (*LendingBookResult, error) {
+	_, lendingState, err := s.lendingState()
+	if err != nil {
+		return nil, err
+	}
+	bookHash := lendingstate.GetLendingBookHash(lendingToken, term)
+	result := &LendingBookResult{
+		Investing: lendingState.GetLendingItems(bookHash, lendingstate.Investing),
+		Borrowing: lendingState.GetLendingItems(bookHash, lendingstate.Borrowing),
+		Trades:    []*lendingstate.LendingTrade{},
+	}
+	if book := lendingState.GetLendingBook(bookHash); book != nil {
+		for _, id := range book.Trades {
+			if trade := lendingState.GetLendingTrade(bookHash, id); trade != nil {
+				result.Trades = append(result.Trades, trade)
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetLendingTrade returns a loan of the lending book of a lending token for a
+// term, including the repaid and liquidated ones.
+func (s *PublicTomoXLendingAPI) GetLendingTrade(ctx context.Context, lendingToken common.Address, term uint64, id uint64) (*lendingstate.LendingTrade, error) {
+	_, lendingState, err := s.lendingState()
+	if err != nil {
+		return nil, err
+	}
+	trade := lendingState.GetLendingTrade(lendingstate.GetLendingBookHash(lendingToken, term), id)
+	if trade == nil {
+		return nil, tomoxlending.ErrUnknownTrade
+	}
+	return trade, nil
 }
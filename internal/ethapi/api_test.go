@@ -0,0 +1,298 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// testBackend is a backend serving a few headers, checkpoint rewards, a state
+// and the pending TomoX matches, the methods not overridden panic.
+type testBackend struct {
+	Backend
+	config  *params.ChainConfig
+	headers map[uint64]*types.Header
+	rewards map[common.Hash]map[string]interface{}
+	state   *state.StateDB
+
+	pending    *types.Block
+	tomoxState *tomox_state.TomoXStateDB
+	txMatches  []tomox.TxDataMatch
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.headers[uint64(number)], nil
+}
+
+func (b *testBackend) GetRewardByHash(hash common.Hash) map[string]interface{} {
+	if rewards, ok := b.rewards[hash]; ok {
+		return rewards
+	}
+	return make(map[string]interface{})
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.state.Copy(), b.headers[uint64(number)], nil
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, nil, &common.Address{})
+	return vm.NewEVM(context, state, b.config, vmCfg), func() error { return nil }, nil
+}
+
+func (b *testBackend) PendingTomoX() (*types.Block, *tomox_state.TomoXStateDB, []tomox.TxDataMatch) {
+	return b.pending, b.tomoxState, b.txMatches
+}
+
+// newCheckpointHeader creates a checkpoint header listing masternodes between
+// the 32 bytes of vanity and the 65 bytes of seal.
+func newCheckpointHeader(number uint64, masternodes ...common.Address) *types.Header {
+	extra := make([]byte, 32)
+	for _, masternode := range masternodes {
+		extra = append(extra, masternode.Bytes()...)
+	}
+	extra = append(extra, make([]byte, 65)...)
+	return &types.Header{Number: new(big.Int).SetUint64(number), Extra: extra}
+}
+
+// Tests that candidates are sorted by the requested field, ties broken by
+// address, and paginated.
+func TestCandidatesPage(t *testing.T) {
+	candidates := func() []*CandidateInfo {
+		return []*CandidateInfo{
+			{Address: common.Address{3}, Capacity: big.NewInt(10), Voters: 5},
+			{Address: common.Address{1}, Capacity: big.NewInt(30), Voters: 1},
+			{Address: common.Address{4}, Capacity: big.NewInt(20), Voters: 5},
+			{Address: common.Address{2}, Capacity: big.NewInt(10), Voters: 2},
+		}
+	}
+	tests := []struct {
+		args CandidatesArgs
+		want []byte
+	}{
+		{CandidatesArgs{}, []byte{1, 4, 2, 3}},
+		{CandidatesArgs{Asc: true}, []byte{2, 3, 4, 1}},
+		{CandidatesArgs{SortBy: "voters"}, []byte{3, 4, 2, 1}},
+		{CandidatesArgs{SortBy: "address", Asc: true}, []byte{1, 2, 3, 4}},
+		{CandidatesArgs{Offset: 1, Limit: 2}, []byte{4, 2}},
+		{CandidatesArgs{Offset: 3, Limit: 2}, []byte{3}},
+		{CandidatesArgs{Offset: 4}, []byte{}},
+	}
+	for i, tt := range tests {
+		less, err := tt.args.less()
+		if err != nil {
+			t.Fatalf("test %d: failed to order candidates: %v", i, err)
+		}
+		page := tt.args.page(candidates(), less)
+		have := make([]byte, len(page))
+		for j, info := range page {
+			have[j] = info.Address[0]
+		}
+		if string(have) != string(tt.want) {
+			t.Errorf("test %d: candidates mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if _, err := (&CandidatesArgs{SortBy: "stake"}).less(); err == nil {
+		t.Error("invalid sort field accepted")
+	}
+}
+
+// Tests that epoch heads carry the changes of the masternode set since the
+// previous checkpoint and the summary of the stored rewards.
+func TestEpochHead(t *testing.T) {
+	a, b, c := common.Address{1}, common.Address{2}, common.Address{3}
+	backend := &testBackend{
+		config: &params.ChainConfig{Posv: &params.PosvConfig{Epoch: 10}},
+		headers: map[uint64]*types.Header{
+			0:  newCheckpointHeader(0, a, b),
+			10: newCheckpointHeader(10, b, c),
+		},
+	}
+	backend.rewards = map[common.Hash]map[string]interface{}{
+		backend.headers[10].Hash(): {
+			"signers": map[string]interface{}{
+				b.Hex(): map[string]interface{}{"sign": json.Number("3"), "reward": json.Number("1000000000000000000000")},
+				c.Hex(): map[string]interface{}{"sign": json.Number("1"), "reward": json.Number("1")},
+			},
+		},
+	}
+	api := NewPublicPosvAPI(backend)
+
+	head, err := api.epochHead(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("failed to assemble genesis epoch head: %v", err)
+	}
+	if !reflect.DeepEqual(head.Promoted, []common.Address{a, b}) || len(head.Demoted) != 0 || head.Rewards != nil {
+		t.Errorf("genesis epoch head mismatch: promoted %x, demoted %x, rewards %v", head.Promoted, head.Demoted, head.Rewards)
+	}
+	head, err = api.epochHead(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("failed to assemble epoch head: %v", err)
+	}
+	if head.Epoch != 1 || head.Hash != backend.headers[10].Hash() || !reflect.DeepEqual(head.Masternodes, []common.Address{b, c}) {
+		t.Errorf("epoch head mismatch: epoch %d, hash %x, masternodes %x", head.Epoch, head.Hash, head.Masternodes)
+	}
+	if !reflect.DeepEqual(head.Promoted, []common.Address{c}) || !reflect.DeepEqual(head.Demoted, []common.Address{a}) {
+		t.Errorf("masternode set diff mismatch: promoted %x, demoted %x", head.Promoted, head.Demoted)
+	}
+	total, _ := new(big.Int).SetString("1000000000000000000001", 10)
+	if head.Rewards == nil || head.Rewards.Signers != 2 || head.Rewards.Signs != 4 || head.Rewards.Total.Cmp(total) != 0 {
+		t.Errorf("epoch rewards mismatch: have %+v", head.Rewards)
+	}
+	if _, err := api.epochHead(context.Background(), 20); err == nil {
+		t.Error("epoch head assembled for a missing checkpoint")
+	}
+}
+
+// Tests that the calls of a multicall run on independent copies of the state,
+// unless the state changes are applied, and that their failures are reported
+// in their results.
+func TestMulticall(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// A counter incrementing and returning its first storage slot, and a
+	// contract reverting
+	counter, reverter := common.Address{0xc0}, common.Address{0xc1}
+	statedb.SetCode(counter, common.FromHex("0x6000546001018060005560005260206000f3"))
+	statedb.SetCode(reverter, common.FromHex("0x60006000fd"))
+	statedb.Finalise(true)
+
+	backend := &testBackend{
+		config:  params.TestChainConfig,
+		headers: map[uint64]*types.Header{1: {Number: big.NewInt(1), Time: big.NewInt(0), Difficulty: big.NewInt(1), GasLimit: 10000000}},
+		state:   statedb,
+	}
+	api := NewPublicBlockChainAPI(backend)
+
+	from := common.Address{0xff}
+	calls := []CallArgs{{From: from, To: &counter}, {From: from, To: &reverter}, {From: from, To: &counter}}
+	for _, chained := range []bool{false, true} {
+		results, err := api.Multicall(context.Background(), calls, 1, &chained)
+		if err != nil {
+			t.Fatalf("chained %v: multicall failed: %v", chained, err)
+		}
+		last := int64(1)
+		if chained {
+			last = 2
+		}
+		for i, want := range []int64{1, -1, last} {
+			res := results[i]
+			if want < 0 {
+				if !res.Failed {
+					t.Errorf("chained %v: call %d did not fail", chained, i)
+				}
+				continue
+			}
+			if res.Failed || new(big.Int).SetBytes(res.ReturnData).Int64() != want {
+				t.Errorf("chained %v: call %d mismatch: have %x (failed %v), want %d", chained, i, res.ReturnData, res.Failed, want)
+			}
+		}
+	}
+	if statedb.GetState(counter, common.Hash{}) != (common.Hash{}) {
+		t.Error("multicall modified the state of the block")
+	}
+	if _, err := api.Multicall(context.Background(), make([]CallArgs, maxMulticallCalls+1), 1, nil); err == nil {
+		t.Error("too many calls accepted")
+	}
+}
+
+// Tests that the pending matches carry the decoded pending orders with their
+// trades and rejections, on top of the pending block.
+func TestGetPendingMatches(t *testing.T) {
+	backend := new(testBackend)
+	api := NewPublicTomoXTransactionPoolAPI(backend, new(AddrLocker), nil)
+	if _, err := api.GetPendingMatches(context.Background()); err == nil {
+		t.Fatal("pending matches returned without a pending block")
+	}
+	db, _ := ethdb.NewMemDatabase()
+	backend.tomoxState, _ = tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	backend.pending = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5), ParentHash: common.Hash{1}})
+
+	order := &tomox_state.OrderItem{Hash: common.Hash{2}, Quantity: big.NewInt(10), Price: big.NewInt(100), Status: tomox.OrderStatusFilled, Signature: &tomox_state.Signature{V: 27}}
+	enc, err := tomox.EncodeBytesItem(order)
+	if err != nil {
+		t.Fatalf("failed to encode order: %v", err)
+	}
+	rejected := []*tomox_state.OrderItem{{Hash: common.Hash{3}}}
+	trades := []map[string]string{{"quantity": "10"}}
+	backend.txMatches = []tomox.TxDataMatch{{Order: enc, Trades: trades, RejectedOders: rejected}}
+
+	pending, err := api.GetPendingMatches(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get pending matches: %v", err)
+	}
+	if pending.Number != 5 || pending.ParentHash != (common.Hash{1}) || pending.TomoxRoot != backend.tomoxState.IntermediateRoot() {
+		t.Errorf("pending block mismatch: number %d, parent %x, root %x", pending.Number, pending.ParentHash, pending.TomoxRoot)
+	}
+	if len(pending.Matches) != 1 {
+		t.Fatalf("pending match count mismatch: have %d, want 1", len(pending.Matches))
+	}
+	match := pending.Matches[0]
+	if match.Order.Hash != order.Hash || match.Order.Status != order.Status || !reflect.DeepEqual(match.Trades, trades) || !reflect.DeepEqual(match.Rejected, rejected) {
+		t.Errorf("pending match mismatch: have %+v", match)
+	}
+	backend.txMatches = []tomox.TxDataMatch{{Order: []byte{0x01}}}
+	if _, err := api.GetPendingMatches(context.Background()); err == nil {
+		t.Error("undecodable pending order accepted")
+	}
+}
+
+// Tests that the trades of all the orders of a matching transaction are
+// embedded in its receipt.
+func TestMatchedTrades(t *testing.T) {
+	first := []map[string]string{{"quantity": "1"}, {"quantity": "2"}}
+	second := []map[string]string{{"quantity": "3"}}
+	data, err := tomox.EncodeTxMatchesBatch(tomox.TxMatchBatch{Data: []tomox.TxDataMatch{{Trades: first}, {}, {Trades: second}}})
+	if err != nil {
+		t.Fatalf("failed to encode matches: %v", err)
+	}
+	tx := types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, data)
+	if !tx.IsMatchingTransaction() {
+		t.Fatalf("transaction not a matching one")
+	}
+	trades, err := matchedTrades(tx)
+	if err != nil {
+		t.Fatalf("failed to decode trades: %v", err)
+	}
+	if want := append(first, second...); !reflect.DeepEqual(trades, want) {
+		t.Errorf("trades mismatch: have %v, want %v", trades, want)
+	}
+	tx = types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, []byte{0x01})
+	if _, err := matchedTrades(tx); err == nil {
+		t.Error("undecodable matches accepted")
+	}
+}
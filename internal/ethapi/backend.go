@@ -20,6 +20,7 @@ package ethapi
 import (
 	"context"
 	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -44,10 +45,12 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error)
 	ChainDb() ethdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
 	TomoxService() *tomox.TomoX
+	PendingTomoX() (*types.Block, *tomox_state.TomoXStateDB, []tomox.TxDataMatch)
 
 	// BlockChain API
 	SetHead(number uint64)
@@ -78,16 +81,17 @@ type Backend interface {
 	GetEngine() consensus.Engine
 	GetRewardByHash(hash common.Hash) map[string]interface{}
 
-	GetVotersRewards(common.Address) map[common.Address]*big.Int
-	GetVotersCap(checkpoint *big.Int, masterAddr common.Address, voters []common.Address) map[common.Address]*big.Int
+	GetVotersRewards(ctx context.Context, masternode common.Address) (map[common.Address]*big.Int, error)
+	SimulateVoterReward(ctx context.Context, masternode common.Address, voter common.Address, stake *big.Int) (*big.Int, error)
+	GetVotersCap(ctx context.Context, checkpoint *big.Int, masterAddr common.Address, voters []common.Address) (map[common.Address]*big.Int, error)
 	GetEpochDuration() *big.Int
-	GetMasternodesCap(checkpoint uint64) map[common.Address]*big.Int
+	GetMasternodesCap(ctx context.Context, checkpoint uint64) (map[common.Address]*big.Int, error)
 	GetBlocksHashCache(blockNr uint64) []common.Hash
 	AreTwoBlockSamePath(newBlock common.Hash, oldBlock common.Hash) bool
 	GetOrderNonce(address common.Hash) (uint64, error)
 }
 
-func GetAPIs(apiBackend Backend) []rpc.API {
+func GetAPIs(apiBackend Backend, acl *SignerACL) []rpc.API {
 	nonceLock := new(AddrLocker)
 	return []rpc.API{
 		{
@@ -103,12 +107,22 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock, acl),
+			Public:    true,
+		}, {
+			Namespace: "posv",
+			Version:   "1.0",
+			Service:   NewPublicPosvAPI(apiBackend),
 			Public:    true,
 		}, {
 			Namespace: "tomox",
 			Version:   "1.0",
-			Service:   NewPublicTomoXTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTomoXTransactionPoolAPI(apiBackend, nonceLock, acl),
+			Public:    true,
+		}, {
+			Namespace: "tomoxlending",
+			Version:   "1.0",
+			Service:   NewPublicTomoXLendingAPI(apiBackend),
 			Public:    true,
 		}, {
 			Namespace: "txpool",
@@ -132,7 +146,7 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
-			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
+			Service:   NewPrivateAccountAPI(apiBackend, nonceLock, acl),
 			Public:    false,
 		},
 	}
@@ -0,0 +1,46 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import "fmt"
+
+// NotFoundError is returned by the backend queries of data the node doesn't
+// have, e.g. a block not synced yet or a masternode not rewarded at a
+// checkpoint, carrying the JSON-RPC resource not found code.
+type NotFoundError struct {
+	What string // Description of the missing data
+}
+
+func (e *NotFoundError) Error() string { return e.What + " not found" }
+
+// ErrorCode returns the JSON-RPC error code of the error.
+func (e *NotFoundError) ErrorCode() int { return -32001 }
+
+// StateUnavailableError is returned by the backend queries needing the state of
+// a block which is pruned or can't be retrieved from the network, carrying the
+// JSON-RPC resource unavailable code.
+type StateUnavailableError struct {
+	Number uint64 // Number of the block
+	Err    error  // Failure retrieving the state
+}
+
+func (e *StateUnavailableError) Error() string {
+	return fmt.Sprintf("state of block %d not available: %v", e.Number, e.Err)
+}
+
+// ErrorCode returns the JSON-RPC error code of the error.
+func (e *StateUnavailableError) ErrorCode() int { return -32002 }
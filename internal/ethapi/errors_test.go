@@ -0,0 +1,67 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type ErrorTestService struct{}
+
+func (s *ErrorTestService) NotFound() error {
+	return &NotFoundError{What: "block 7"}
+}
+
+func (s *ErrorTestService) StateUnavailable() error {
+	return &StateUnavailableError{Number: 7, Err: errors.New("missing trie node")}
+}
+
+// Tests that the backend errors reach the RPC clients with their codes.
+func TestErrorCodes(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", new(ErrorTestService)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	tests := []struct {
+		method  string
+		code    int
+		message string
+	}{
+		{"test_notFound", -32001, "block 7 not found"},
+		{"test_stateUnavailable", -32002, "state of block 7 not available: missing trie node"},
+	}
+	for _, tt := range tests {
+		err := client.CallContext(context.Background(), nil, tt.method)
+		rpcErr, ok := err.(rpc.Error)
+		if !ok {
+			t.Fatalf("%s: error %v has no code", tt.method, err)
+		}
+		if rpcErr.ErrorCode() != tt.code {
+			t.Errorf("%s: code mismatch: have %d, want %d", tt.method, rpcErr.ErrorCode(), tt.code)
+		}
+		if err.Error() != tt.message {
+			t.Errorf("%s: message mismatch: have %q, want %q", tt.method, err.Error(), tt.message)
+		}
+	}
+}
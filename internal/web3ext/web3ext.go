@@ -18,22 +18,44 @@
 package web3ext
 
 var Modules = map[string]string{
-	"admin":      Admin_JS,
-	"chequebook": Chequebook_JS,
-	"clique":     Clique_JS,
-	"posv":       Posv_JS,
-	"debug":      Debug_JS,
-	"eth":        Eth_JS,
-	"miner":      Miner_JS,
-	"net":        Net_JS,
-	"personal":   Personal_JS,
-	"rpc":        RPC_JS,
-	"shh":        Shh_JS,
-	"tomox":      TomoX_JS,
-	"swarmfs":    SWARMFS_JS,
-	"txpool":     TxPool_JS,
+	"admin":        Admin_JS,
+	"bridge":       Bridge_JS,
+	"chequebook":   Chequebook_JS,
+	"clique":       Clique_JS,
+	"posv":         Posv_JS,
+	"debug":        Debug_JS,
+	"eth":          Eth_JS,
+	"les":          LES_JS,
+	"miner":        Miner_JS,
+	"net":          Net_JS,
+	"personal":     Personal_JS,
+	"rpc":          RPC_JS,
+	"shh":          Shh_JS,
+	"tomox":        TomoX_JS,
+	"tomoxlending": TomoXLending_JS,
+	"swarmfs":      SWARMFS_JS,
+	"txpool":       TxPool_JS,
 }
 
+const Bridge_JS = `
+web3._extend({
+	property: 'bridge',
+	methods: [
+		new web3._extend.Method({
+			name: 'getAttestation',
+			call: 'bridge_getAttestation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getAttestations',
+			call: 'bridge_getAttestations',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	]
+});
+`
+
 const Chequebook_JS = `
 web3._extend({
 	property: 'chequebook',
@@ -137,12 +159,59 @@ web3._extend({
 			call: 'posv_getSignersAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getExtraData',
+			call: 'posv_getExtraData',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getExtraDataAtHash',
+			call: 'posv_getExtraDataAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getCandidates',
+			call: 'posv_getCandidates',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'simulateVoterReward',
+			call: 'posv_simulateVoterReward',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerCoverage',
+			call: 'posv_getSignerCoverage',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getVotersCap',
+			call: 'posv_getVotersCap',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'proposals',
 			getter: 'posv_proposals'
 		}),
+		new web3._extend.Property({
+			name: 'minerStatus',
+			getter: 'posv_minerStatus'
+		}),
+		new web3._extend.Property({
+			name: 'randomizeStatus',
+			getter: 'posv_randomizeStatus'
+		}),
+		new web3._extend.Property({
+			name: 'candidateStatus',
+			getter: 'posv_candidateStatus'
+		}),
 	]
 });
 `
@@ -161,6 +230,16 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -197,6 +276,16 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'setTxPoolLimits',
+			call: 'admin_setTxPoolLimits',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setTxPoolAccountLimit',
+			call: 'admin_setTxPoolAccountLimit',
+			params: 2
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -207,10 +296,30 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerDrops',
+			getter: 'admin_peerDrops'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'peerScores',
+			getter: 'admin_peerScores'
+		}),
+		new web3._extend.Property({
+			name: 'watchdog',
+			getter: 'admin_watchdog'
+		}),
+		new web3._extend.Property({
+			name: 'txPoolLimits',
+			getter: 'admin_txPoolLimits'
+		}),
+		new web3._extend.Property({
+			name: 'relayerStats',
+			getter: 'admin_relayerStats'
+		}),
 	]
 });
 `
@@ -254,6 +363,10 @@ web3._extend({
 			name: 'chaindbCompact',
 			call: 'debug_chaindbCompact',
 		}),
+		new web3._extend.Method({
+			name: 'downloaderStatus',
+			call: 'debug_downloaderStatus',
+		}),
 		new web3._extend.Method({
 			name: 'metrics',
 			call: 'debug_metrics',
@@ -438,12 +551,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'eth_signTypedData',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'resend',
 			call: 'eth_resend',
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'multicall',
+			call: 'eth_multicall',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'eth_signTransaction',
@@ -466,6 +591,36 @@ web3._extend({
 			call: 'eth_getRewardByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getOrderProof',
+			call: 'eth_getOrderProof',
+			params: 4,
+			inputFormatter: [null, null, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPriceLevelProof',
+			call: 'eth_getPriceLevelProof',
+			params: 5,
+			inputFormatter: [null, null, null, web3._extend.utils.toHex, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'chainId',
+			call: 'eth_chainId',
+			params: 0,
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'eth_feeHistory',
+			params: 3,
+			inputFormatter: [web3._extend.utils.toHex, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -523,6 +678,16 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'setOrderBudget',
+			call: 'miner_setOrderBudget',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setForkGuard',
+			call: 'miner_setForkGuard',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
@@ -581,6 +746,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signOrder',
+			call: 'personal_signOrder',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -711,6 +882,21 @@ web3._extend({
 		new web3._extend.Method({
             name: 'sendOrderTransaction',
             call: 'tomox_sendOrder',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'getOrderHash',
+            call: 'tomox_getOrderHash',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'getOrderTypedData',
+            call: 'tomox_getOrderTypedData',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'signOrder',
+            call: 'tomox_signOrder',
             params: 1
 		}),
 		new web3._extend.Method({
@@ -739,10 +925,115 @@ web3._extend({
             params: 2
 		}),
 		new web3._extend.Method({
+            name: 'getOrderBookDiff',
+            call: 'tomox_getOrderBookDiff',
+            params: 4,
+            inputFormatter: [null, null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
             name: 'getOrderById',
             call: 'tomox_getOrderById',
             params: 3
 		}),
+		new web3._extend.Method({
+            name: 'getOpenOrders',
+            call: 'tomox_getOpenOrders',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'getPendingMatches',
+            call: 'tomox_getPendingMatches',
+            params: 0
+		}),
+		new web3._extend.Method({
+            name: 'estimateMatch',
+            call: 'tomox_estimateMatch',
+            params: 2,
+            inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+            name: 'getPairs',
+            call: 'tomox_getPairs',
+            params: 0
+		}),
+		new web3._extend.Method({
+            name: 'getExpiringOrders',
+            call: 'tomox_getExpiringOrders',
+            params: 1
+		}),
+		new web3._extend.Method({
+            name: 'getTWAP',
+            call: 'tomox_getTWAP',
+            params: 3,
+            inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+            name: 'getRelayerFees',
+            call: 'tomox_getRelayerFees',
+            params: 2,
+            inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+            name: 'getTopTraders',
+            call: 'tomox_getTopTraders',
+            params: 4,
+            inputFormatter: [null, null, null, null]
+		}),
+		new web3._extend.Method({
+            name: 'getOrderReceipt',
+            call: 'tomox_getOrderReceipt',
+            params: 1
+		}),
+	]
+});
+`
+
+const TomoXLending_JS = `
+web3._extend({
+	property: 'tomoxlending',
+	methods: [
+		new web3._extend.Method({
+			name: 'lend',
+			call: 'tomoxlending_lend',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'borrow',
+			call: 'tomoxlending_borrow',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'repay',
+			call: 'tomoxlending_repay',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'cancel',
+			call: 'tomoxlending_cancel',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getLendingOrderHash',
+			call: 'tomoxlending_getLendingOrderHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getLendingNonce',
+			call: 'tomoxlending_getLendingNonce',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getLendingBook',
+			call: 'tomoxlending_getLendingBook',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getLendingTrade',
+			call: 'tomoxlending_getLendingTrade',
+			params: 3
+		}),
 	]
 });
 `
@@ -787,6 +1078,20 @@ web3._extend({
 });
 `
 
+const LES_JS = `
+web3._extend({
+	property: 'les',
+	methods: [],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'latestCheckpoint',
+			getter: 'les_latestCheckpoint'
+		}),
+	]
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
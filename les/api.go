@@ -0,0 +1,46 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/light"
+)
+
+var errNoCheckpoint = errors.New("no checkpoint built yet")
+
+// PublicLesServerAPI provides an API to access the structures served by the
+// LES server.
+type PublicLesServerAPI struct {
+	server *LesServer
+}
+
+// NewPublicLesServerAPI creates a new LES server API.
+func NewPublicLesServerAPI(server *LesServer) *PublicLesServerAPI {
+	return &PublicLesServerAPI{server}
+}
+
+// LatestCheckpoint returns the latest checkpoint of the CHT and BloomTrie
+// served, which light clients can be shipped with to skip syncing the headers
+// before it.
+func (api *PublicLesServerAPI) LatestCheckpoint() (*light.TrustedCheckpoint, error) {
+	cp := api.server.LatestCheckpoint()
+	if cp == nil {
+		return nil, errNoCheckpoint
+	}
+	return cp, nil
+}
@@ -17,13 +17,15 @@
 package les
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/tomox"
-	"io/ioutil"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
 	"math/big"
-	"path/filepath"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -39,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -112,7 +115,7 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 func (b *LesApiBackend) SendOrderTx(ctx context.Context, signedTx *types.OrderTransaction) error {
-	return nil
+	return errors.New("order transactions not supported by light clients")
 }
 
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
@@ -143,6 +146,15 @@ func (b *LesApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.eth.txPool.SubscribeTxPreEvent(ch)
 }
 
+// SubscribeTxLifecycleEvent returns a subscription firing no event, the light
+// transaction pool does not evict transactions.
+func (b *LesApiBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeChainEvent(ch)
 }
@@ -175,6 +187,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, percentiles)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
@@ -208,47 +224,144 @@ func (b *LesApiBackend) GetIPCClient() (*ethclient.Client, error) {
 func (b *LesApiBackend) GetEngine() consensus.Engine {
 	return b.eth.engine
 }
+
+// odrTimeout is the time allowed to the servers to answer the requests of the
+// API methods taking no context.
+const odrTimeout = 10 * time.Second
+
+// GetRewardByHash returns the rewards record of a checkpoint block, retrieved
+// from the light servers storing them.
 func (s *LesApiBackend) GetRewardByHash(hash common.Hash) map[string]interface{} {
+	rewards := make(map[string]interface{})
 	header := s.eth.blockchain.GetHeaderByHash(hash)
-	if header != nil {
-		data, err := ioutil.ReadFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.Hash().Hex()))
-		if err == nil {
-			rewards := make(map[string]interface{})
-			err = json.Unmarshal(data, &rewards)
-			if err == nil {
-				return rewards
-			}
-		} else {
-			data, err = ioutil.ReadFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+header.HashNoValidator().Hex()))
-			if err == nil {
-				rewards := make(map[string]interface{})
-				err = json.Unmarshal(data, &rewards)
-				if err == nil {
-					return rewards
-				}
-			}
-		}
+	if header == nil {
+		return rewards
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), odrTimeout)
+	defer cancel()
+
+	data, err := light.GetRewards(ctx, s.eth.odr, hash, header.Number.Uint64())
+	if err != nil || len(data) == 0 {
+		return rewards
+	}
+	if err := decodeRewards(data, &rewards); err != nil {
+		return make(map[string]interface{})
 	}
-	return make(map[string]interface{})
+	return rewards
 }
 
-// GetVotersRewards return a map of voters of snapshot at given block hash
-func (b *LesApiBackend) GetVotersRewards(masternodeAddr common.Address) map[common.Address]*big.Int {
-	return map[common.Address]*big.Int{}
+// decodeRewards decodes stored checkpoint rewards, keeping the amounts exact.
+func decodeRewards(data []byte, rewards *map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(rewards)
 }
 
-// GetVotersCap return all voters's capability at a checkpoint
-func (b *LesApiBackend) GetVotersCap(checkpoint *big.Int, masterAddr common.Address, voters []common.Address) map[common.Address]*big.Int {
-	return map[common.Address]*big.Int{}
+// GetVotersRewards returns the rewards of the voters of a masternode paid at
+// the checkpoint before the last one, from its rewards record.
+func (b *LesApiBackend) GetVotersRewards(ctx context.Context, masternodeAddr common.Address) (map[common.Address]*big.Int, error) {
+	epoch := b.ChainConfig().Posv.Epoch
+	number := b.eth.blockchain.CurrentHeader().Number.Uint64()
+	if number < 2*epoch {
+		return nil, &ethapi.NotFoundError{What: "reward checkpoint"}
+	}
+	lastCheckpointNumber := number - (number % epoch) - epoch
+
+	ctx, cancel := context.WithTimeout(ctx, odrTimeout)
+	defer cancel()
+
+	header, err := b.eth.blockchain.GetHeaderByNumberOdr(ctx, lastCheckpointNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, &ethapi.NotFoundError{What: fmt.Sprintf("block %d", lastCheckpointNumber)}
+	}
+	data, err := light.GetRewards(ctx, b.eth.odr, header.Hash(), lastCheckpointNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, &ethapi.NotFoundError{What: fmt.Sprintf("rewards of checkpoint %d", lastCheckpointNumber)}
+	}
+	var record struct {
+		Rewards map[common.Address]map[common.Address]*big.Int `json:"rewards"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid rewards of checkpoint %d: %v", lastCheckpointNumber, err)
+	}
+	if rewards := record.Rewards[masternodeAddr]; rewards != nil {
+		return rewards, nil
+	}
+	return map[common.Address]*big.Int{}, nil
+}
+
+// SimulateVoterReward is not supported by light clients.
+func (b *LesApiBackend) SimulateVoterReward(ctx context.Context, masternodeAddr common.Address, voter common.Address, stake *big.Int) (*big.Int, error) {
+	return nil, errors.New("not supported")
+}
+
+// GetVotersCap return all voters's capability at a checkpoint, read from the
+// validator contract storage proven against the checkpoint state root
+func (b *LesApiBackend) GetVotersCap(ctx context.Context, checkpoint *big.Int, masterAddr common.Address, voters []common.Address) (map[common.Address]*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, odrTimeout)
+	defer cancel()
+
+	statedb, err := b.checkpointState(ctx, checkpoint.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	voterCaps := make(map[common.Address]*big.Int)
+	for _, voteAddr := range voters {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		voterCaps[voteAddr] = state.GetVoterCap(statedb, masterAddr, voteAddr)
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, &ethapi.StateUnavailableError{Number: checkpoint.Uint64(), Err: err}
+	}
+	return voterCaps, nil
+}
+
+// checkpointState returns the state of a checkpoint block, retrieving its
+// entries from the light servers with merkle proofs on access.
+func (b *LesApiBackend) checkpointState(ctx context.Context, checkpoint uint64) (*state.StateDB, error) {
+	header, err := b.eth.blockchain.GetHeaderByNumberOdr(ctx, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, &ethapi.NotFoundError{What: fmt.Sprintf("block %d", checkpoint)}
+	}
+	return light.NewState(ctx, header, b.eth.odr), nil
 }
 
 func (b *LesApiBackend) GetEpochDuration() *big.Int {
 	return nil
 }
 
-// GetMasternodesCap return a cap of all masternode at a checkpoint
-func (b *LesApiBackend) GetMasternodesCap(checkpoint uint64) map[common.Address]*big.Int {
-	return nil
+// GetMasternodesCap return a cap of all masternode at a checkpoint, read from
+// the validator contract storage proven against the checkpoint state root
+func (b *LesApiBackend) GetMasternodesCap(ctx context.Context, checkpoint uint64) (map[common.Address]*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, odrTimeout)
+	defer cancel()
+
+	statedb, err := b.checkpointState(ctx, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	masternodesCap := make(map[common.Address]*big.Int)
+	for _, candidate := range state.GetCandidates(statedb) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		masternodesCap[candidate] = state.GetCandidateCap(statedb, candidate)
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, &ethapi.StateUnavailableError{Number: checkpoint, Err: err}
+	}
+	return masternodesCap, nil
 }
 
 func (b *LesApiBackend) GetBlocksHashCache(blockNr uint64) []common.Hash {
@@ -264,6 +377,10 @@ func (b *LesApiBackend) GetOrderNonce(address common.Hash) (uint64, error) {
 	return 0, errors.New("cannot find tomox service")
 }
 
+func (b *LesApiBackend) PendingTomoX() (*types.Block, *tomox_state.TomoXStateDB, []tomox.TxDataMatch) {
+	return nil, nil, nil
+}
+
 func (b *LesApiBackend) TomoxService() *tomox.TomoX {
 	return nil
 }
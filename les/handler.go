@@ -21,8 +21,10 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"net"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -58,6 +60,7 @@ const (
 	MaxHelperTrieProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxTxSend                = 64  // Amount of transactions to be send per request
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
+	MaxRewardsFetch          = 32  // Amount of checkpoint reward records to be fetched per retrieval request
 
 	disableClientRemovePeer = false
 )
@@ -330,7 +333,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg, GetRewardsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -684,6 +687,51 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			Obj:     resp.Receipts,
 		}
 
+	case GetRewardsMsg:
+		p.Log().Trace("Received rewards request")
+		// Decode the retrieval message
+		var req struct {
+			ReqID  uint64
+			Hashes []common.Hash
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqCnt := len(req.Hashes)
+		if reject(uint64(reqCnt), MaxRewardsFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+		// Answer every hash, with an empty record if unknown to us
+		rewards := make([][]byte, len(req.Hashes))
+		for i, hash := range req.Hashes {
+			if header := pm.blockchain.GetHeaderByHash(hash); header != nil {
+				rewards[i] = readRewards(header)
+			}
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendRewards(req.ReqID, bv, rewards)
+
+	case RewardsMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received rewards response")
+		var resp struct {
+			ReqID, BV uint64
+			Rewards   [][]byte
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgRewards,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Rewards,
+		}
+
 	case GetProofsV1Msg:
 		p.Log().Trace("Received proofs request")
 		// Decode the retrieval message
@@ -1134,6 +1182,20 @@ func (pm *ProtocolManager) getHelperTrieAuxData(req HelperTrieReq) []byte {
 	return nil
 }
 
+// readRewards returns the rewards record stored for a checkpoint block, nil if
+// rewards are not stored or the block is not a checkpoint.
+func readRewards(header *types.Header) []byte {
+	if len(common.StoreRewardFolder) == 0 {
+		return nil
+	}
+	for _, hash := range []common.Hash{header.Hash(), header.HashNoValidator()} {
+		if data, err := ioutil.ReadFile(filepath.Join(common.StoreRewardFolder, header.Number.String()+"."+hash.Hex())); err == nil {
+			return data
+		}
+	}
+	return nil
+}
+
 func (pm *ProtocolManager) txStatus(hashes []common.Hash) []txStatus {
 	stats := make([]txStatus, len(hashes))
 	for i, stat := range pm.txpool.Status(hashes) {
@@ -18,8 +18,11 @@ package les
 
 import (
 	"encoding/binary"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -314,6 +317,46 @@ func testGetReceipt(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that the stored rewards records of checkpoint blocks can be retrieved.
+func TestGetRewardsLes3(t *testing.T) {
+	folder, err := ioutil.TempDir("", "rewards")
+	if err != nil {
+		t.Fatalf("failed to create temporary folder: %v", err)
+	}
+	defer os.RemoveAll(folder)
+	defer func(folder string) { common.StoreRewardFolder = folder }(common.StoreRewardFolder)
+	common.StoreRewardFolder = folder
+
+	// Assemble the test environment
+	db, _ := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	bc := pm.blockchain.(*core.BlockChain)
+	peer, _ := newTestPeer(t, "peer", lpv3, pm, true)
+	defer peer.close()
+
+	// Store a record for one block only, the others have none
+	hashes, rewards := []common.Hash{}, [][]byte{}
+	for i := uint64(0); i <= bc.CurrentBlock().NumberU64(); i++ {
+		block := bc.GetBlockByNumber(i)
+
+		var record []byte
+		if i == 2 {
+			record = []byte(`{"rewards":{},"signers":{}}`)
+			if err := ioutil.WriteFile(filepath.Join(folder, block.Number().String()+"."+block.Hash().Hex()), record, 0644); err != nil {
+				t.Fatalf("failed to store rewards: %v", err)
+			}
+		}
+		hashes = append(hashes, block.Hash())
+		rewards = append(rewards, record)
+	}
+	// Send the hash request and verify the response
+	cost := peer.GetRequestCost(GetRewardsMsg, len(hashes))
+	sendRequest(peer.app, GetRewardsMsg, 42, cost, hashes)
+	if err := expectResponse(peer.app, RewardsMsg, 42, testBufLimit, rewards); err != nil {
+		t.Errorf("rewards mismatch: %v", err)
+	}
+}
+
 // Tests that trie merkle proofs can be retrieved
 func TestGetProofsLes1(t *testing.T) { testGetProofs(t, 1) }
 func TestGetProofsLes2(t *testing.T) { testGetProofs(t, 2) }
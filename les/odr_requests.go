@@ -20,6 +20,7 @@ package les
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -40,11 +41,13 @@ var (
 	errHeaderUnavailable   = errors.New("header unavailable")
 	errTxHashMismatch      = errors.New("transaction hash mismatch")
 	errUncleHashMismatch   = errors.New("uncle hash mismatch")
+	errTradeRootMismatch   = errors.New("trade root mismatch")
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
 	errDataHashMismatch    = errors.New("data hash mismatch")
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
 	errCHTNumberMismatch   = errors.New("cht number mismatch")
 	errUselessNodes        = errors.New("useless nodes in merkle proof nodeset")
+	errInvalidRewards      = errors.New("invalid rewards record")
 )
 
 type LesOdrRequest interface {
@@ -68,6 +71,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*ChtRequest)(r)
 	case *light.BloomRequest:
 		return (*BloomRequest)(r)
+	case *light.RewardsRequest:
+		return (*RewardsRequest)(r)
 	default:
 		return nil
 	}
@@ -120,6 +125,9 @@ func (r *BlockRequest) Validate(db ethdb.Database, msg *Msg) error {
 	if header.UncleHash != types.CalcUncleHash(body.Uncles) {
 		return errUncleHashMismatch
 	}
+	if !types.VerifyTradeRoot(header, body.Trades) {
+		return errTradeRootMismatch
+	}
 	// Validations passed, encode and store RLP
 	data, err := rlp.EncodeToBytes(body)
 	if err != nil {
@@ -193,7 +201,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -353,7 +361,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
@@ -543,6 +551,55 @@ func (r *BloomRequest) Validate(db ethdb.Database, msg *Msg) error {
 	return nil
 }
 
+// RewardsRequest is the ODR request type for checkpoint reward records, see LesOdrRequest interface
+type RewardsRequest light.RewardsRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *RewardsRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetRewardsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *RewardsRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv3 && peer.HasBlock(r.Hash, r.Number)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *RewardsRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting rewards", "hash", r.Hash)
+	return peer.RequestRewards(reqID, r.GetCost(peer), []common.Hash{r.Hash})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *RewardsRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating rewards", "hash", r.Hash)
+
+	// Ensure we have a correct message with a single rewards record
+	if msg.MsgType != MsgRewards {
+		return errInvalidMessageType
+	}
+	rewards := msg.Obj.([][]byte)
+	if len(rewards) != 1 {
+		return errInvalidEntryCount
+	}
+	// The record is not part of the state, only check that it belongs to a
+	// known header and is well formed
+	if core.GetHeader(db, r.Hash, r.Number) == nil {
+		return errHeaderUnavailable
+	}
+	if len(rewards[0]) > 0 {
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(rewards[0], &record); err != nil {
+			return errInvalidRewards
+		}
+	}
+	r.Rewards = rewards[0]
+	return nil
+}
+
 // readTraceDB stores the keys of database reads. We use this to check that received node
 // sets contain only the trie nodes necessary to make proofs pass.
 type readTraceDB struct {
@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discv5"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type LesServer struct {
@@ -102,6 +103,34 @@ func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 	return srv, nil
 }
 
+// APIs returns the RPC services of the LES server.
+func (s *LesServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPublicLesServerAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// LatestCheckpoint returns the checkpoint of the last LES/2 section for which
+// both the CHT and the BloomTrie are built, nil if there is none yet.
+func (s *LesServer) LatestCheckpoint() *light.TrustedCheckpoint {
+	chtSections, _, _ := s.chtIndexer.Sections()
+	sections := chtSections / (light.CHTFrequencyClient / light.CHTFrequencyServer)
+	if bloomTrieSections, _, _ := s.bloomTrieIndexer.Sections(); bloomTrieSections < sections {
+		sections = bloomTrieSections
+	}
+	if sections == 0 {
+		return nil
+	}
+	section := sections - 1
+	head := s.bloomTrieIndexer.SectionHead(section)
+	return light.GetTrustedCheckpoint(s.protocolManager.chainDb, section, head)
+}
+
 func (s *LesServer) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
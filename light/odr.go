@@ -128,6 +128,25 @@ func (req *ReceiptsRequest) StoreResult(db ethdb.Database) {
 	core.WriteBlockReceipts(db, req.Hash, req.Number, req.Receipts)
 }
 
+// RewardsRequest is the ODR request type for retrieving the rewards record of a
+// checkpoint block, in the JSON format the full nodes store it in
+type RewardsRequest struct {
+	OdrRequest
+	Hash    common.Hash
+	Number  uint64
+	Rewards []byte // empty if the server keeps no record
+}
+
+// rewardsPrefix + hash -> rewards record of a checkpoint block
+var rewardsPrefix = []byte("rewards-")
+
+// StoreResult stores the retrieved data in local database
+func (req *RewardsRequest) StoreResult(db ethdb.Database) {
+	if len(req.Rewards) > 0 {
+		db.Put(append(rewardsPrefix, req.Hash.Bytes()...), req.Rewards)
+	}
+}
+
 // ChtRequest is the ODR request type for state/storage trie entries
 type ChtRequest struct {
 	OdrRequest
@@ -143,6 +143,44 @@ func odrAccounts(ctx context.Context, db ethdb.Database, bc *core.BlockChain, lc
 	return res, st.Error()
 }
 
+func TestOdrProofsLes1(t *testing.T) { testChainOdr(t, 1, odrProofs) }
+
+// odrProofs concatenates the proofs of accounts and of a storage slot of the
+// test contract, identical for the light clients and the full nodes.
+func odrProofs(ctx context.Context, db ethdb.Database, bc *core.BlockChain, lc *LightChain, bhash common.Hash) ([]byte, error) {
+	dummyAddr := common.HexToAddress("1234567812345678123456781234567812345678")
+
+	var st *state.StateDB
+	if bc == nil {
+		header := lc.GetHeaderByHash(bhash)
+		st = NewState(ctx, header, lc.Odr())
+	} else {
+		header := bc.GetHeaderByHash(bhash)
+		st, _ = state.New(header.Root, state.NewDatabase(db))
+	}
+
+	var res []byte
+	for _, addr := range []common.Address{testBankAddress, acc1Addr, dummyAddr, testContractAddr} {
+		proof, err := st.GetProof(addr)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range proof {
+			res = append(res, node...)
+		}
+	}
+	if st.Exist(testContractAddr) {
+		proof, err := st.GetStorageProof(testContractAddr, common.Hash{})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range proof {
+			res = append(res, node...)
+		}
+	}
+	return res, st.Error()
+}
+
 func TestOdrContractCallLes1(t *testing.T) { testChainOdr(t, 1, odrContractCall) }
 
 type callmsg struct {
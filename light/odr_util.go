@@ -120,7 +120,7 @@ func GetBlock(ctx context.Context, odr OdrBackend, hash common.Hash, number uint
 		return nil, err
 	}
 	// Reassemble the block and return
-	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles), nil
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles).WithTrades(body.Trades), nil
 }
 
 // GetBlockReceipts retrieves the receipts generated by the transactions included
@@ -152,6 +152,20 @@ func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, num
 	return receipts, nil
 }
 
+// GetRewards retrieves the rewards record of a checkpoint block given by its
+// hash, nil if the servers keep none. The records are not part of the state, so
+// unlike the state entries they are not proven.
+func GetRewards(ctx context.Context, odr OdrBackend, hash common.Hash, number uint64) ([]byte, error) {
+	if data, _ := odr.Database().Get(append(rewardsPrefix, hash.Bytes()...)); len(data) > 0 {
+		return data, nil
+	}
+	r := &RewardsRequest{Hash: hash, Number: number}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Rewards, nil
+}
+
 // GetBlockLogs retrieves the logs generated by the transactions included in a
 // block given by its hash.
 func GetBlockLogs(ctx context.Context, odr OdrBackend, hash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -46,35 +46,39 @@ const (
 	HelperTrieProcessConfirmations = 256  // number of confirmations before a HelperTrie is generated
 )
 
-// trustedCheckpoint represents a set of post-processed trie roots (CHT and BloomTrie) associated with
+// TrustedCheckpoint represents a set of post-processed trie roots (CHT and BloomTrie) associated with
 // the appropriate section index and head hash. It is used to start light syncing from this checkpoint
 // and avoid downloading the entire header chain while still being able to securely access old headers/logs.
-type trustedCheckpoint struct {
-	name                                string
-	sectionIdx                          uint64
-	sectionHead, chtRoot, bloomTrieRoot common.Hash
+type TrustedCheckpoint struct {
+	Name          string      `json:"name,omitempty" toml:",omitempty"`
+	SectionIdx    uint64      `json:"sectionIndex"` // LES/2 section index
+	SectionHead   common.Hash `json:"sectionHead"`
+	CHTRoot       common.Hash `json:"chtRoot"`
+	BloomTrieRoot common.Hash `json:"bloomTrieRoot"`
 }
 
 var (
-	mainnetCheckpoint = trustedCheckpoint{
-		name:          "mainnet",
-		sectionIdx:    161,
-		sectionHead:   common.HexToHash("75b0c4baa7a62cece48abdcb03b6f31601961c9bece67dcd61df87aad4fc0d8d"),
-		chtRoot:       common.HexToHash("bbbfaa67b29716348997ec21a39c03b8d1fb973f6a43740b865595ba26ee812f"),
-		bloomTrieRoot: common.HexToHash("d6db6e6248354d7453391ce97830072a28ea4216be0bd95a5db9f53b1a64677b"),
+	mainnetCheckpoint = &TrustedCheckpoint{
+		Name:          "mainnet",
+		SectionIdx:    161,
+		SectionHead:   common.HexToHash("75b0c4baa7a62cece48abdcb03b6f31601961c9bece67dcd61df87aad4fc0d8d"),
+		CHTRoot:       common.HexToHash("bbbfaa67b29716348997ec21a39c03b8d1fb973f6a43740b865595ba26ee812f"),
+		BloomTrieRoot: common.HexToHash("d6db6e6248354d7453391ce97830072a28ea4216be0bd95a5db9f53b1a64677b"),
 	}
 
-	ropstenCheckpoint = trustedCheckpoint{
-		name:          "ropsten",
-		sectionIdx:    87,
-		sectionHead:   common.HexToHash("ebc0adcb30ed21cbe95bd77499cc1af0bada621fee3644cb80dbcf1444c123fe"),
-		chtRoot:       common.HexToHash("d9830f4893c821ddf149b8cb9d3e3bfe3109d2eea8e3c4a4ede7c8b2ee8a7800"),
-		bloomTrieRoot: common.HexToHash("c76e12d713f65b84c5a36d06bc77d0c8419248ea0b36e0812a78b76aa6da0ddb"),
+	ropstenCheckpoint = &TrustedCheckpoint{
+		Name:          "ropsten",
+		SectionIdx:    87,
+		SectionHead:   common.HexToHash("ebc0adcb30ed21cbe95bd77499cc1af0bada621fee3644cb80dbcf1444c123fe"),
+		CHTRoot:       common.HexToHash("d9830f4893c821ddf149b8cb9d3e3bfe3109d2eea8e3c4a4ede7c8b2ee8a7800"),
+		BloomTrieRoot: common.HexToHash("c76e12d713f65b84c5a36d06bc77d0c8419248ea0b36e0812a78b76aa6da0ddb"),
 	}
 )
 
-// trustedCheckpoints associates each known checkpoint with the genesis hash of the chain it belongs to
-var trustedCheckpoints = map[common.Hash]trustedCheckpoint{
+// trustedCheckpoints associates each known checkpoint with the genesis hash of the chain it belongs to.
+// The checkpoints of the TomoChain networks are the ones returned by les_latestCheckpoint on a
+// synced light server, they are added here at each release.
+var trustedCheckpoints = map[common.Hash]*TrustedCheckpoint{
 	params.MainnetGenesisHash: mainnetCheckpoint,
 	params.TestnetGenesisHash: ropstenCheckpoint,
 }
@@ -87,6 +91,21 @@ var (
 	ChtTablePrefix        = "cht-"
 )
 
+// GetTrustedCheckpoint assembles the checkpoint of the given LES/2 section from the CHT and BloomTrie
+// roots stored in the database, nil if either of them is missing.
+func GetTrustedCheckpoint(db ethdb.Database, sectionIdx uint64, sectionHead common.Hash) *TrustedCheckpoint {
+	cp := &TrustedCheckpoint{
+		SectionIdx:    sectionIdx,
+		SectionHead:   sectionHead,
+		CHTRoot:       GetChtV2Root(db, sectionIdx, sectionHead),
+		BloomTrieRoot: GetBloomTrieRoot(db, sectionIdx, sectionHead),
+	}
+	if cp.CHTRoot == (common.Hash{}) || cp.BloomTrieRoot == (common.Hash{}) {
+		return nil
+	}
+	return cp
+}
+
 // ChtNode structures are stored in the Canonical Hash Trie in an RLP encoded format
 type ChtNode struct {
 	Hash common.Hash
@@ -0,0 +1,48 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestGetTrustedCheckpoint(t *testing.T) {
+	var (
+		db, _         = ethdb.NewMemDatabase()
+		head          = common.HexToHash("0x01")
+		chtRoot       = common.HexToHash("0x02")
+		bloomTrieRoot = common.HexToHash("0x03")
+	)
+	// The server stores the CHTs by LES/1 section, the checkpoints are by LES/2 section
+	StoreChtRoot(db, 2*(CHTFrequencyClient/CHTFrequencyServer)-1, head, chtRoot)
+	if cp := GetTrustedCheckpoint(db, 1, head); cp != nil {
+		t.Fatalf("checkpoint without bloom trie: %+v", cp)
+	}
+	StoreBloomTrieRoot(db, 1, head, bloomTrieRoot)
+	cp := GetTrustedCheckpoint(db, 1, head)
+	if cp == nil {
+		t.Fatalf("checkpoint missing")
+	}
+	if cp.SectionIdx != 1 || cp.SectionHead != head || cp.CHTRoot != chtRoot || cp.BloomTrieRoot != bloomTrieRoot {
+		t.Errorf("checkpoint mismatch: %+v", cp)
+	}
+	if cp := GetTrustedCheckpoint(db, 0, head); cp != nil {
+		t.Errorf("checkpoint of an unbuilt section: %+v", cp)
+	}
+}
@@ -18,7 +18,6 @@ package light
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -141,8 +140,23 @@ func (t *odrTrie) GetKey(sha []byte) []byte {
 	return nil
 }
 
+// Prove constructs a merkle proof for the hashed key, retrieving the missing
+// nodes of its path from the light servers.
 func (t *odrTrie) Prove(key []byte, fromLevel uint, proofDb ethdb.Putter) error {
-	return errors.New("not implemented, needs client/server interface split")
+	var nodes NodeList
+	err := t.do(key, func() error {
+		// Resolve the path first, the proof only reads local nodes
+		if _, err := t.trie.TryGet(key); err != nil {
+			return err
+		}
+		nodes = nil
+		return t.trie.Prove(key, fromLevel, &nodes)
+	})
+	if err != nil {
+		return err
+	}
+	nodes.Store(proofDb)
+	return nil
 }
 
 // do tries and retries to execute a function until it returns with no error or
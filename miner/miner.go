@@ -20,6 +20,7 @@ package miner
 import (
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
 )
 
 // Backend wraps all methods required for mining.
@@ -160,11 +162,63 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetForkGuard enables or disables holding back sealing while the chain looks
+// like a minority fork of the masternode network.
+func (self *Miner) SetForkGuard(enabled bool) {
+	var guard int32
+	if enabled {
+		guard = 1
+	}
+	atomic.StoreInt32(&self.worker.forkGuard, guard)
+}
+
+// SetForkGuardTimeout sets the longest time the minority fork guard holds back
+// sealing before resuming it anyway.
+func (self *Miner) SetForkGuardTimeout(timeout time.Duration) {
+	atomic.StoreInt64(&self.worker.forkGuardTimeout, int64(timeout))
+}
+
+// ForkGuard returns whether the minority fork guard is enabled.
+func (self *Miner) ForkGuard() bool {
+	return atomic.LoadInt32(&self.worker.forkGuard) == 1
+}
+
+// SetOrderBudget sets the share of the block construction given to matching
+// the pending orders.
+func (self *Miner) SetOrderBudget(budget OrderBudget) error {
+	if budget.MaxOrders < 0 {
+		return fmt.Errorf("negative order limit %d", budget.MaxOrders)
+	}
+	if budget.TimeShare < 0 || budget.TimeShare > 100 {
+		return fmt.Errorf("order matching time share %d%% out of range", budget.TimeShare)
+	}
+	self.worker.setOrderBudget(budget)
+	return nil
+}
+
+// OrderBudget returns the share of the block construction given to matching
+// the pending orders.
+func (self *Miner) OrderBudget() OrderBudget {
+	return self.worker.getOrderBudget()
+}
+
+// SealingPaused returns whether the minority fork guard currently holds back
+// sealing.
+func (self *Miner) SealingPaused() bool {
+	return self.Mining() && atomic.LoadInt32(&self.worker.sealingPaused) == 1
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
 }
 
+// PendingTomoX returns the currently pending block, the TomoX state projected
+// after its pending order transactions and their matching results.
+func (self *Miner) PendingTomoX() (*types.Block, *tomox_state.TomoXStateDB, []tomox.TxDataMatch) {
+	return self.worker.pendingTomoX()
+}
+
 // PendingBlock returns the currently pending block.
 //
 // Note, to access both the pending block and the pending state
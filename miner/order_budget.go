@@ -0,0 +1,42 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox"
+)
+
+// OrderBudget is the share of the construction of a block given to matching the
+// pending orders, so that blocks packed with orders don't starve the regular
+// transactions. Zero values leave the matching unlimited.
+type OrderBudget struct {
+	MaxOrders int `json:"maxOrders" toml:",omitempty"` // Maximum number of orders matched per block
+	TimeShare int `json:"timeShare" toml:",omitempty"` // Percentage of the block period spent matching
+}
+
+// limit returns the matching budget of a block whose construction started at
+// the given time.
+func (b OrderBudget) limit(config *params.ChainConfig, start time.Time) tomox.OrderBudget {
+	budget := tomox.OrderBudget{MaxOrders: b.MaxOrders}
+	if b.TimeShare > 0 && config.Posv != nil {
+		period := time.Duration(config.Posv.Period) * time.Second
+		budget.Deadline = start.Add(period * time.Duration(b.TimeShare) / 100)
+	}
+	return budget
+}
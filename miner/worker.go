@@ -29,6 +29,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/tomox"
 	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -81,12 +83,14 @@ type Work struct {
 	config *params.ChainConfig
 	signer types.Signer
 
-	state      *state.StateDB // apply state changes here
-	tomoxState *tomox_state.TomoXStateDB
-	ancestors  *set.Set // ancestor set (used for checking uncle parent validity)
-	family     *set.Set // family set (used for checking uncle invalidity)
-	uncles     *set.Set // uncle set
-	tcount     int      // tx count in cycle
+	state        *state.StateDB // apply state changes here
+	tomoxState   *tomox_state.TomoXStateDB
+	lendingState *lendingstate.LendingStateDB
+	txMatches    []tomox.TxDataMatch // matching results of the pending orders, if sealing
+	ancestors    *set.Set            // ancestor set (used for checking uncle parent validity)
+	family       *set.Set            // family set (used for checking uncle invalidity)
+	uncles       *set.Set            // uncle set
+	tcount       int                 // tx count in cycle
 
 	Block *types.Block // the new block
 
@@ -127,8 +131,9 @@ type worker struct {
 	proc    core.Validator
 	chainDb ethdb.Database
 
-	coinbase common.Address
-	extra    []byte
+	coinbase    common.Address
+	extra       []byte
+	orderBudget OrderBudget // Share of the block construction given to order matching
 
 	currentMu sync.Mutex
 	current   *Work
@@ -141,6 +146,10 @@ type worker struct {
 	// atomic status counters
 	mining                int32
 	atWork                int32
+	forkGuard             int32     // Whether sealing stops on a minority fork
+	sealingPaused         int32     // Whether sealing is currently held back by the fork guard
+	forkGuardTimeout      int64     // Longest time the fork guard holds back sealing, in nanoseconds
+	pausedAt              time.Time // When the fork guard started holding back sealing
 	announceTxs           bool
 	lastParentBlockCommit string
 }
@@ -191,6 +200,18 @@ func (self *worker) setExtra(extra []byte) {
 	self.extra = extra
 }
 
+func (self *worker) setOrderBudget(budget OrderBudget) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.orderBudget = budget
+}
+
+func (self *worker) getOrderBudget() OrderBudget {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.orderBudget
+}
+
 func (self *worker) pending() (*types.Block, *state.StateDB) {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
@@ -221,6 +242,42 @@ func (self *worker) pendingBlock() *types.Block {
 	return self.current.Block
 }
 
+// pendingTomoX returns the pending block with the TomoX state projected after
+// the pending order transactions, and their matching results. Orders are only
+// matched by the sealing work, so they are matched on copies of the pending
+// states if not sealing.
+func (self *worker) pendingTomoX() (*types.Block, *tomox_state.TomoXStateDB, []tomox.TxDataMatch) {
+	self.currentMu.Lock()
+	current := self.current
+	if current == nil || current.tomoxState == nil {
+		self.currentMu.Unlock()
+		return nil, nil, nil
+	}
+	if atomic.LoadInt32(&self.mining) == 1 {
+		defer self.currentMu.Unlock()
+		return current.Block, current.tomoxState.Copy(), current.txMatches
+	}
+	block := types.NewBlock(current.header, current.txs, nil, current.receipts)
+	statedb, tomoxState := current.state.Copy(), current.tomoxState.Copy()
+	self.currentMu.Unlock()
+
+	var txMatches []tomox.TxDataMatch
+	if self.matchesOrders(block.Header()) {
+		pending, _ := self.eth.OrderPool().Pending()
+		txMatches = self.eth.GetTomoX().ProcessOrderPending(self.coinbase, self.chain.IPCEndpoint, pending, statedb, tomoxState, block.NumberU64(), tomox.OrderBudget{})
+	}
+	return block, tomoxState, txMatches
+}
+
+// matchesOrders reports whether the pending orders are matched in a block.
+func (self *worker) matchesOrders(header *types.Header) bool {
+	if self.config.Posv == nil || !self.chain.Config().IsTIPTomoX(header.Number) || self.eth.GetTomoX() == nil {
+		return false
+	}
+	number := header.Number.Uint64()
+	return number%self.config.Posv.Epoch != 0 && number > self.config.Posv.Epoch
+}
+
 func (self *worker) start() {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -310,7 +367,7 @@ func (self *worker) update() {
 				self.currentMu.Lock()
 				acc, _ := types.Sender(self.current.signer, ev.Tx)
 				txs := map[common.Address]types.Transactions{acc: {ev.Tx}}
-				feeCapacity := state.GetTRC21FeeCapacityFromState(self.current.state)
+				feeCapacity := core.TRC21FeeCapacity(self.config, self.current.header.Number, self.current.state)
 				txset, specialTxs := types.NewTransactionsByPriceAndNonce(self.current.signer, txs, nil, feeCapacity)
 				self.current.commitTransactions(self.mux, feeCapacity, txset, specialTxs, self.chain, self.coinbase)
 				self.currentMu.Unlock()
@@ -354,7 +411,7 @@ func (self *worker) wait() {
 			for _, log := range work.state.Logs() {
 				log.BlockHash = block.Hash()
 			}
-			stat, err := self.chain.WriteBlockWithState(block, work.receipts, work.state, work.tomoxState)
+			stat, err := self.chain.WriteBlockWithState(block, work.receipts, work.state, work.tomoxState, work.lendingState)
 			if err != nil {
 				log.Error("Failed writing block to chain", "err", err)
 				continue
@@ -421,7 +478,7 @@ func (self *worker) wait() {
 				}
 				// Send tx sign to smart contract blockSigners.
 				if block.NumberU64()%common.MergeSignRange == 0 || !self.config.IsTIP2019(block.Number()) {
-					if err := contracts.CreateTransactionSign(self.config, self.eth.TxPool(), self.eth.AccountManager(), block, self.chainDb); err != nil {
+					if err := contracts.CreateTransactionSign(self.config, self.eth.TxPool(), self.engine.(*posv.Posv), self.chain, block, self.chainDb); err != nil {
 						log.Error("Fail to create tx sign for signer", "error", "err")
 					}
 				}
@@ -449,7 +506,10 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 	if err != nil {
 		return err
 	}
-	var tomoxState *tomox_state.TomoXStateDB
+	var (
+		tomoxState   *tomox_state.TomoXStateDB
+		lendingState *lendingstate.LendingStateDB
+	)
 	if self.config.Posv != nil {
 		tomoX := self.eth.GetTomoX()
 		tomoxState, err = tomoX.GetTomoxState(parent)
@@ -457,18 +517,30 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 			log.Error("Failed to create mining context", "err", err)
 			return err
 		}
+		if self.config.IsTIPTomoX(header.Number) {
+			tomox_state.UpgradeMatchingVersion(self.config.TomoXVersion(header.Number), tomoxState)
+		}
+		if self.config.IsPriceOracle(header.Number) {
+			tomox_state.ActivatePriceOracle(tomoxState)
+		}
+		lendingState, err = tomoX.GetLending().GetLendingState(parent)
+		if err != nil {
+			log.Error("Failed to create lending mining context", "err", err)
+			return err
+		}
 	}
 
 	work := &Work{
-		config:     self.config,
-		signer:     types.NewEIP155Signer(self.config.ChainId),
-		state:      state,
-		tomoxState: tomoxState,
-		ancestors:  set.New(),
-		family:     set.New(),
-		uncles:     set.New(),
-		header:     header,
-		createdAt:  time.Now(),
+		config:       self.config,
+		signer:       types.NewEIP155Signer(self.config.ChainId),
+		state:        state,
+		tomoxState:   tomoxState,
+		lendingState: lendingState,
+		ancestors:    set.New(),
+		family:       set.New(),
+		uncles:       set.New(),
+		header:       header,
+		createdAt:    time.Now(),
 	}
 
 	if self.config.Posv == nil {
@@ -495,6 +567,25 @@ func abs(x int64) int64 {
 	return x
 }
 
+// holdBack reports whether the fork guard holds back sealing on top of a
+// minority fork. Sealing resumes once it was held back for the guard timeout,
+// so that a chain the masternodes really left does not halt forever.
+func (self *worker) holdBack(minority bool, now time.Time) bool {
+	if !minority {
+		self.pausedAt = time.Time{}
+		return false
+	}
+	if self.pausedAt.IsZero() {
+		self.pausedAt = now
+	}
+	timeout := time.Duration(atomic.LoadInt64(&self.forkGuardTimeout))
+	if now.Sub(self.pausedAt) >= timeout {
+		log.Warn("Fork guard timed out, resuming sealing", "since", self.pausedAt, "timeout", timeout)
+		return false
+	}
+	return true
+}
+
 func (self *worker) commitNewWork() {
 	self.mu.Lock()
 	defer self.mu.Unlock()
@@ -504,6 +595,9 @@ func (self *worker) commitNewWork() {
 	defer self.currentMu.Unlock()
 
 	tstart := time.Now()
+	// The order budget is read once under the worker lock, setOrderBudget
+	// waiting for the construction of the block
+	budget := self.orderBudget.limit(self.config, tstart)
 	parent := self.chain.CurrentBlock()
 	var signers map[common.Address]struct{}
 	if parent.Hash().Hex() == self.lastParentBlockCommit {
@@ -550,6 +644,19 @@ func (self *worker) commitNewWork() {
 				}
 				log.Info("Wait enough. It's my turn", "waited seconds", waitedTime)
 			}
+			minority := false
+			if atomic.LoadInt32(&self.forkGuard) == 1 {
+				if minority, err = c.IsMinorityFork(self.chain, parent.Header()); err != nil {
+					log.Warn("Failed to check masternode participation", "err", err)
+					return
+				}
+			}
+			if self.holdBack(minority, time.Now()) {
+				atomic.StoreInt32(&self.sealingPaused, 1)
+				log.Warn("Too few masternodes seal this chain, holding back sealing", "number", parent.Number(), "since", self.pausedAt)
+				return
+			}
+			atomic.StoreInt32(&self.sealingPaused, 0)
 		}
 	}
 	tstamp := tstart.Unix()
@@ -613,8 +720,12 @@ func (self *worker) commitNewWork() {
 		specialTxs          types.Transactions
 		matchingTransaction *types.Transaction
 		txMatches           []tomox.TxDataMatch
+		lendingBatch        *tomoxlending.LendingBatch
 	)
-	feeCapacity := state.GetTRC21FeeCapacityFromStateWithCache(parent.Root(), work.state)
+	feeCapacity := map[common.Address]*big.Int{}
+	if self.config.IsTRC21Fee(header.Number) {
+		feeCapacity = state.GetTRC21FeeCapacityFromStateWithCache(parent.Root(), work.state)
+	}
 	if self.config.Posv != nil && header.Number.Uint64()%self.config.Posv.Epoch != 0 {
 		pending, err := self.eth.TxPool().Pending()
 		if err != nil {
@@ -622,16 +733,37 @@ func (self *worker) commitNewWork() {
 			return
 		}
 		txs, specialTxs = types.NewTransactionsByPriceAndNonce(self.current.signer, pending, signers, feeCapacity)
+		// Governance transactions of the masternodes come before any other
+		txs.Prioritize(self.eth.TxPool().PrioritySenders())
 	}
 	if atomic.LoadInt32(&self.mining) == 1 {
-		if self.config.Posv != nil && header.Number.Uint64()%self.config.Posv.Epoch != 0 && self.chain.Config().IsTIPTomoX(header.Number) {
+		if self.matchesOrders(header) {
+			tomoX := self.eth.GetTomoX()
+			log.Debug("Start processing order pending")
+			orderPending, _ := self.eth.OrderPool().Pending()
+			log.Debug("Start processing order pending", "len", len(orderPending))
+			txMatches = tomoX.ProcessOrderPending(self.coinbase, self.chain.IPCEndpoint, orderPending, work.state, work.tomoxState, header.Number.Uint64(), budget)
+			work.txMatches = txMatches
+			log.Debug("transaction matches found", "txMatches", len(txMatches))
+		}
+		if self.config.Posv != nil && header.Number.Uint64()%self.config.Posv.Epoch == 0 && self.config.IsRelayerFee(header.Number) {
+			// Relayer trading fees are refreshed at each checkpoint
+			tomox_state.RefreshRelayerFees(work.tomoxState, work.state)
+		}
+		if self.chain.Config().IsTIPTomoX(header.Number) {
+			if tomox.StateMatchingRules(work.tomoxState).IsExpiry {
+				tomox_state.PruneExpiredOrders(header.Number.Uint64(), work.tomoxState)
+			}
+			tomox_state.UpdatePriceOracle(header.Number.Uint64(), work.tomoxState, work.state)
+		}
+		if self.config.Posv != nil && work.lendingState != nil && self.chain.Config().IsLending(header.Number) {
 			tomoX := self.eth.GetTomoX()
-			if tomoX != nil && header.Number.Uint64() > self.config.Posv.Epoch {
-				log.Debug("Start processing order pending")
-				orderPending, _ := self.eth.OrderPool().Pending()
-				log.Debug("Start processing order pending", "len", len(orderPending))
-				txMatches = tomoX.ProcessOrderPending(self.coinbase, self.chain.IPCEndpoint, orderPending, work.state, work.tomoxState)
-				log.Debug("transaction matches found", "txMatches", len(txMatches))
+			// Loans are liquidated every block at the prices of this block, before the new lending orders
+			liquidated := tomoX.ProcessLiquidations(self.chain.IPCEndpoint, self.config.TomoXLending, header.Time.Uint64(), work.state, work.tomoxState, work.lendingState)
+			log.Debug("Lending trades liquidated", "trades", len(liquidated))
+			if header.Number.Uint64()%self.config.Posv.Epoch != 0 && header.Number.Uint64() > self.config.Posv.Epoch {
+				lendingBatch = tomoX.GetLending().ProcessLendingPending(header.Time.Uint64(), work.state, work.lendingState)
+				log.Debug("Lending orders processed", "orders", len(lendingBatch.Orders))
 			}
 		}
 		TomoxStateRoot := work.tomoxState.IntermediateRoot()
@@ -668,6 +800,25 @@ func (self *worker) commitNewWork() {
 		// force adding matching transaction to this block
 		specialTxs = append(specialTxs, matchingTransaction)
 		specialTxs = append(specialTxs, txStateRoot)
+
+		// The lending transaction carries the lending state root even if no order was processed
+		if work.lendingState != nil && self.chain.Config().IsLending(header.Number) {
+			if lendingBatch == nil {
+				lendingBatch = &tomoxlending.LendingBatch{Root: work.lendingState.IntermediateRoot()}
+			}
+			lendingBytes, err := tomoxlending.EncodeLendingBatch(lendingBatch)
+			if err != nil {
+				log.Error("Fail to encode lending batch", "error", err)
+				return
+			}
+			tx = types.NewTransaction(nonce, common.HexToAddress(common.TomoXLendingAddr), big.NewInt(0), txMatchGasLimit, big.NewInt(0), lendingBytes)
+			txLending, err := wallet.SignTx(accounts.Account{Address: self.coinbase}, tx, self.config.ChainId)
+			if err != nil {
+				log.Error("Fail to create lending tx", "error", err)
+				return
+			}
+			specialTxs = append(specialTxs, txLending)
+		}
 	}
 	work.commitTransactions(self.mux, feeCapacity, txs, specialTxs, self.chain, self.coinbase)
 	// compute uncles for the new block.
@@ -694,17 +845,29 @@ func (self *worker) commitNewWork() {
 			delete(self.possibleUncles, hash)
 		}
 	}
+	// From the trade root fork on, the header commits to the trades of the block
+	var trades types.Trades
+	if self.config.IsTradeRoot(header.Number) {
+		trades = tomox.MatchedTrades(work.txMatches)
+		header.TradeRoot = types.DeriveSha(trades)
+	}
 	// Create the new block to seal with the consensus engine
 	if work.Block, err = self.engine.Finalize(self.chain, header, work.state, work.txs, uncles, work.receipts); err != nil {
 		log.Error("Failed to finalize block for sealing", "err", err)
 		return
 	}
+	if trades != nil {
+		work.Block = work.Block.WithTrades(trades)
+	}
 	if atomic.LoadInt32(&self.mining) == 1 {
 		log.Info("Committing new block", "number", work.Block.Number(), "txs", work.tcount, "special-txs", len(specialTxs), "uncles", len(uncles), "elapsed", common.PrettyDuration(time.Since(tstart)))
 		self.unconfirmed.Shift(work.Block.NumberU64() - 1)
 		self.lastParentBlockCommit = parent.Hash().Hex()
 	}
 	self.push(work)
+
+	// Notify the new pending state, also projected through the pending orders
+	go self.mux.Post(core.PendingStateEvent{})
 }
 
 func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
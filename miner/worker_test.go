@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestForkGuardHoldBack(t *testing.T) {
+	w := &worker{forkGuardTimeout: int64(time.Minute)}
+	start := time.Unix(1000, 0)
+
+	// Sealing goes on while the chain has enough masternodes
+	if w.holdBack(false, start) {
+		t.Fatalf("sealing held back on a majority chain")
+	}
+	// and is held back on a minority fork until the timeout
+	if !w.holdBack(true, start) {
+		t.Fatalf("sealing not held back on a minority fork")
+	}
+	if !w.holdBack(true, start.Add(59*time.Second)) {
+		t.Fatalf("sealing resumed before the timeout")
+	}
+	if w.holdBack(true, start.Add(time.Minute)) {
+		t.Fatalf("sealing still held back after the timeout")
+	}
+	// Rejoining the majority restarts the timeout for the next minority fork
+	if w.holdBack(false, start.Add(2*time.Minute)) {
+		t.Fatalf("sealing held back after rejoining the majority")
+	}
+	if !w.holdBack(true, start.Add(3*time.Minute)) {
+		t.Fatalf("sealing not held back on a new minority fork")
+	}
+	if !w.pausedAt.Equal(start.Add(3 * time.Minute)) {
+		t.Fatalf("pause start mismatch: have %v, want %v", w.pausedAt, start.Add(3*time.Minute))
+	}
+	// A zero timeout never holds back sealing
+	atomic.StoreInt64(&w.forkGuardTimeout, 0)
+	if w.holdBack(true, start.Add(3*time.Minute)) {
+		t.Fatalf("sealing held back without a timeout")
+	}
+}
+
+// Tests that the order budget can be set while blocks are constructed, run
+// with -race.
+func TestOrderBudgetConcurrentSet(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	w := &worker{config: params.TestChainConfig, chain: chain}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			w.setOrderBudget(OrderBudget{MaxOrders: i})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w.commitNewWork()
+	}
+	<-done
+	if budget := w.getOrderBudget(); budget.MaxOrders != 100 {
+		t.Errorf("order budget mismatch: have %d, want 100", budget.MaxOrders)
+	}
+}
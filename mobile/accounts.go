@@ -147,6 +147,26 @@ func (ks *KeyStore) SignTxPassphrase(account *Account, passphrase string, tx *Tr
 	return &Transaction{signed}, nil
 }
 
+// SignOrder signs the given order with the requested account, as an Ethereum
+// signed message of its signature hash.
+func (ks *KeyStore) SignOrder(account *Account, order *OrderTransaction) (*OrderTransaction, error) {
+	sig, err := ks.keystore.SignHash(account.account, accounts.TextHash(order.GetSigHash().GetBytes()))
+	if err != nil {
+		return nil, err
+	}
+	return order.WithSignature(sig)
+}
+
+// SignOrderPassphrase signs the order if the private key matching the given
+// address can be decrypted with the given passphrase.
+func (ks *KeyStore) SignOrderPassphrase(account *Account, passphrase string, order *OrderTransaction) (*OrderTransaction, error) {
+	sig, err := ks.keystore.SignHashWithPassphrase(account.account, passphrase, accounts.TextHash(order.GetSigHash().GetBytes()))
+	if err != nil {
+		return nil, err
+	}
+	return order.WithSignature(sig)
+}
+
 // Unlock unlocks the given account indefinitely.
 func (ks *KeyStore) Unlock(account *Account, passphrase string) error {
 	return ks.keystore.TimedUnlock(account.account, passphrase, 0)
@@ -310,3 +310,52 @@ func (ec *EthereumClient) EstimateGas(ctx *Context, msg *CallMsg) (gas int64, _
 func (ec *EthereumClient) SendTransaction(ctx *Context, tx *Transaction) error {
 	return ec.client.SendTransaction(ctx.context, tx.tx)
 }
+
+// SendOrderTransaction injects a signed order into the pending pool of orders
+// for matching. The order methods are served by full nodes only, the light ones
+// don't keep the order books.
+func (ec *EthereumClient) SendOrderTransaction(ctx *Context, order *OrderTransaction) error {
+	return ec.client.SendOrderTransaction(ctx.context, order.tx)
+}
+
+// GetOrderCount returns the nonce of the next order of an account.
+func (ec *EthereumClient) GetOrderCount(ctx *Context, account *Address) (count int64, _ error) {
+	rawCount, err := ec.client.OrderCount(ctx.context, account.address)
+	return int64(rawCount), err
+}
+
+// GetBestBid returns the highest bid of the order book of a pair.
+func (ec *EthereumClient) GetBestBid(ctx *Context, baseToken, quoteToken *Address) (bid *PriceVolume, _ error) {
+	rawBid, err := ec.client.BestBid(ctx.context, baseToken.address, quoteToken.address)
+	if err != nil {
+		return nil, err
+	}
+	return &PriceVolume{rawBid}, nil
+}
+
+// GetBestAsk returns the lowest ask of the order book of a pair.
+func (ec *EthereumClient) GetBestAsk(ctx *Context, baseToken, quoteToken *Address) (ask *PriceVolume, _ error) {
+	rawAsk, err := ec.client.BestAsk(ctx.context, baseToken.address, quoteToken.address)
+	if err != nil {
+		return nil, err
+	}
+	return &PriceVolume{rawAsk}, nil
+}
+
+// GetOrderByID returns an order resting in the order book of a pair.
+func (ec *EthereumClient) GetOrderByID(ctx *Context, baseToken, quoteToken *Address, orderID int64) (order *Order, _ error) {
+	rawOrder, err := ec.client.OrderByID(ctx.context, baseToken.address, quoteToken.address, uint64(orderID))
+	if err != nil {
+		return nil, err
+	}
+	return &Order{*rawOrder}, nil
+}
+
+// GetOpenOrders returns the orders of an account resting in the order books.
+func (ec *EthereumClient) GetOpenOrders(ctx *Context, account *Address) (orders *Orders, _ error) {
+	rawOrders, err := ec.client.OpenOrders(ctx.context, account.address)
+	if err != nil {
+		return nil, err
+	}
+	return &Orders{rawOrders}, nil
+}
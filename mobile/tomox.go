@@ -0,0 +1,172 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains all the wrappers of the TomoX orders and order books.
+
+package geth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// Sides and types of the orders.
+const (
+	OrderSideBuy    = tomox.Bid
+	OrderSideSell   = tomox.Ask
+	OrderTypeLimit  = tomox.Limit
+	OrderTypeMarket = tomox.Market
+)
+
+// OrderTransaction represents a TomoX order, or the cancellation of one, signed
+// by its user.
+type OrderTransaction struct {
+	tx *types.OrderTransaction
+}
+
+// NewOrderTransaction creates a new order with the given properties, to be
+// signed by its user. The nonce is the order count of the user.
+func NewOrderTransaction(nonce int64, quantity, price *BigInt, exchange, user, baseToken, quoteToken *Address, side, orderType, pairName string) *OrderTransaction {
+	tx := types.NewOrderTransaction(uint64(nonce), quantity.bigint, price.bigint, exchange.address, user.address, baseToken.address, quoteToken.address,
+		tomox.OrderStatusNew, side, orderType, pairName, common.Hash{}, 0)
+	tx.SetOrderHash(types.OrderTxSigner{}.Hash(tx))
+	return &OrderTransaction{tx}
+}
+
+// NewOrderCancellation creates the cancellation of the order with the given hash
+// and ID, to be signed by its user.
+func NewOrderCancellation(nonce int64, exchange, user, baseToken, quoteToken *Address, orderHash *Hash, orderID int64) *OrderTransaction {
+	return &OrderTransaction{types.NewOrderTransaction(uint64(nonce), nil, nil, exchange.address, user.address, baseToken.address, quoteToken.address,
+		tomox.OrderStatusCancelled, "", "", "", orderHash.hash, uint64(orderID))}
+}
+
+// NewOrderAmendment creates the amendment of the price and quantity of the limit
+// order with the given hash and ID, to be signed by its user. The order loses
+// its time priority unless only its quantity is decreased.
+func NewOrderAmendment(nonce int64, quantity, price *BigInt, exchange, user, baseToken, quoteToken *Address, side, pairName string, orderHash *Hash, orderID int64) *OrderTransaction {
+	return &OrderTransaction{types.NewOrderTransaction(uint64(nonce), quantity.bigint, price.bigint, exchange.address, user.address, baseToken.address, quoteToken.address,
+		tomox.OrderStatusAmended, side, tomox.Limit, pairName, orderHash.hash, uint64(orderID))}
+}
+
+// NewOrderTransactionFromRLP parses an order from an RLP data dump.
+func NewOrderTransactionFromRLP(data []byte) (*OrderTransaction, error) {
+	tx := &OrderTransaction{
+		tx: new(types.OrderTransaction),
+	}
+	if err := rlp.DecodeBytes(common.CopyBytes(data), tx.tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// EncodeRLP encodes an order into an RLP data dump.
+func (tx *OrderTransaction) EncodeRLP() ([]byte, error) {
+	return rlp.EncodeToBytes(tx.tx)
+}
+
+// String implements the fmt.Stringer interface to print some semi-meaningful
+// data dump of the order for debugging purposes.
+func (tx *OrderTransaction) String() string {
+	return fmt.Sprintf("order %x: %s %s %v @ %v of %s, nonce %d", tx.tx.OrderHash(), tx.tx.Status(), tx.tx.Side(), tx.tx.Quantity(), tx.tx.Price(), tx.tx.PairName(), tx.tx.Nonce())
+}
+
+func (tx *OrderTransaction) GetNonce() int64              { return int64(tx.tx.Nonce()) }
+func (tx *OrderTransaction) GetQuantity() *BigInt         { return &BigInt{tx.tx.Quantity()} }
+func (tx *OrderTransaction) GetPrice() *BigInt            { return &BigInt{tx.tx.Price()} }
+func (tx *OrderTransaction) GetExchangeAddress() *Address { return &Address{tx.tx.ExchangeAddress()} }
+func (tx *OrderTransaction) GetUserAddress() *Address     { return &Address{tx.tx.UserAddress()} }
+func (tx *OrderTransaction) GetBaseToken() *Address       { return &Address{tx.tx.BaseToken()} }
+func (tx *OrderTransaction) GetQuoteToken() *Address      { return &Address{tx.tx.QuoteToken()} }
+func (tx *OrderTransaction) GetStatus() string            { return tx.tx.Status() }
+func (tx *OrderTransaction) GetSide() string              { return tx.tx.Side() }
+func (tx *OrderTransaction) GetType() string              { return tx.tx.Type() }
+func (tx *OrderTransaction) GetPairName() string          { return tx.tx.PairName() }
+func (tx *OrderTransaction) GetOrderHash() *Hash          { return &Hash{tx.tx.OrderHash()} }
+func (tx *OrderTransaction) GetOrderID() int64            { return int64(tx.tx.OrderID()) }
+func (tx *OrderTransaction) GetHash() *Hash               { return &Hash{tx.tx.Hash()} }
+
+// GetSigHash returns the hash signed by the user, as an Ethereum signed message.
+func (tx *OrderTransaction) GetSigHash() *Hash { return &Hash{types.OrderTxSigner{}.Hash(tx.tx)} }
+
+// GetSender returns the user who signed the order.
+func (tx *OrderTransaction) GetSender() (address *Address, _ error) {
+	from, err := types.OrderSender(types.OrderTxSigner{}, tx.tx)
+	return &Address{from}, err
+}
+
+// WithSignature returns a copy of the order with the given signature of its
+// signature hash, in the [R || S || V] format where V is 0 or 1.
+func (tx *OrderTransaction) WithSignature(sig []byte) (signedTx *OrderTransaction, _ error) {
+	rawTx, err := tx.tx.WithSignature(types.OrderTxSigner{}, common.CopyBytes(sig))
+	return &OrderTransaction{rawTx}, err
+}
+
+// Order represents an order resting in an order book.
+type Order struct {
+	order tomox_state.OrderItem
+}
+
+func (o *Order) GetHash() *Hash               { return &Hash{o.order.Hash} }
+func (o *Order) GetOrderID() int64            { return int64(o.order.OrderID) }
+func (o *Order) GetUserAddress() *Address     { return &Address{o.order.UserAddress} }
+func (o *Order) GetExchangeAddress() *Address { return &Address{o.order.ExchangeAddress} }
+func (o *Order) GetBaseToken() *Address       { return &Address{o.order.BaseToken} }
+func (o *Order) GetQuoteToken() *Address      { return &Address{o.order.QuoteToken} }
+func (o *Order) GetQuantity() *BigInt         { return &BigInt{o.order.Quantity} }
+func (o *Order) GetPrice() *BigInt            { return &BigInt{o.order.Price} }
+func (o *Order) GetFilledAmount() *BigInt     { return &BigInt{o.order.FilledAmount} }
+func (o *Order) GetStatus() string            { return o.order.Status }
+func (o *Order) GetSide() string              { return o.order.Side }
+func (o *Order) GetType() string              { return o.order.Type }
+func (o *Order) GetPairName() string          { return o.order.PairName }
+
+// Orders represents a slice of orders.
+type Orders struct{ orders []tomox_state.OrderItem }
+
+// Size returns the number of orders in the slice.
+func (o *Orders) Size() int {
+	return len(o.orders)
+}
+
+// Get returns the order at the given index from the slice.
+func (o *Orders) Get(index int) (order *Order, _ error) {
+	if index < 0 || index >= len(o.orders) {
+		return nil, errors.New("index out of bounds")
+	}
+	return &Order{o.orders[index]}, nil
+}
+
+// PriceVolume represents the best price of a side of an order book, and the
+// volume of the orders at that price.
+type PriceVolume struct {
+	pv *ethclient.PriceVolume
+}
+
+func (pv *PriceVolume) GetPrice() *BigInt  { return &BigInt{pv.pv.Price} }
+func (pv *PriceVolume) GetVolume() *BigInt { return &BigInt{pv.pv.Volume} }
+
+// GetNormalizedPrice returns the price in whole quote tokens.
+func (pv *PriceVolume) GetNormalizedPrice() string { return pv.pv.NormalizedPrice }
+
+// GetNormalizedVolume returns the volume in whole base tokens.
+func (pv *PriceVolume) GetNormalizedVolume() string { return pv.pv.NormalizedVolume }
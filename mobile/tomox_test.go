@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package geth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that orders built and signed through the mobile wrappers recover their
+// user, and survive an RLP round trip.
+func TestSignOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mobile-orders-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := NewKeyStore(dir, LightScryptN, LightScryptP)
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	user := account.GetAddress()
+	exchange, base, quote := &Address{common.Address{1}}, &Address{common.Address{2}}, &Address{common.Address{3}}
+
+	order := NewOrderTransaction(1, NewBigInt(100), NewBigInt(2), exchange, user, base, quote, OrderSideBuy, OrderTypeLimit, "TOMO/USDT")
+	if order.GetOrderHash().GetHex() != order.GetSigHash().GetHex() {
+		t.Errorf("order hash mismatch: have %s, want %s", order.GetOrderHash().GetHex(), order.GetSigHash().GetHex())
+	}
+	if _, err := ks.SignOrderPassphrase(account, "wrong", order); err == nil {
+		t.Errorf("order signed with a wrong passphrase")
+	}
+	signed, err := ks.SignOrderPassphrase(account, "secret", order)
+	if err != nil {
+		t.Fatalf("failed to sign order: %v", err)
+	}
+	if sender, err := signed.GetSender(); err != nil || sender.GetHex() != user.GetHex() {
+		t.Errorf("sender mismatch: have %v (%v), want %s", sender, err, user.GetHex())
+	}
+	enc, err := signed.EncodeRLP()
+	if err != nil {
+		t.Fatalf("failed to encode order: %v", err)
+	}
+	decoded, err := NewOrderTransactionFromRLP(enc)
+	if err != nil {
+		t.Fatalf("failed to decode order: %v", err)
+	}
+	if decoded.GetHash().GetHex() != signed.GetHash().GetHex() || decoded.GetSide() != OrderSideBuy || decoded.GetPrice().GetInt64() != 2 {
+		t.Errorf("decoded order mismatch: have %v, want %v", decoded, signed)
+	}
+	// Cancellations refer to the order by hash and ID
+	cancel := NewOrderCancellation(2, exchange, user, base, quote, signed.GetOrderHash(), 7)
+	if err := ks.Unlock(account, "secret"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	if cancel, err = ks.SignOrder(account, cancel); err != nil {
+		t.Fatalf("failed to sign cancellation: %v", err)
+	}
+	if cancel.GetOrderHash().GetHex() != signed.GetOrderHash().GetHex() || cancel.GetOrderID() != 7 || cancel.GetStatus() != "CANCELLED" {
+		t.Errorf("cancellation mismatch: have %v", cancel)
+	}
+	if sender, err := cancel.GetSender(); err != nil || sender.GetHex() != user.GetHex() {
+		t.Errorf("cancellation sender mismatch: have %v (%v), want %s", sender, err, user.GetHex())
+	}
+}
@@ -75,6 +75,48 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// AddTrustedPeer allows a remote node to always connect, even if slots are full,
+// and maintains a connection to it at all times. The node is also persisted to
+// the static and trusted node lists, so it is kept across restarts.
+func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.AddTrustedPeer(node)
+	server.AddPeer(node)
+	if err := api.node.config.AddPersistentNode(node); err != nil {
+		return false, fmt.Errorf("failed to persist trusted node: %v", err)
+	}
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set and
+// disconnects from it, also dropping it from the persisted static and trusted
+// node lists.
+func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.RemoveTrustedPeer(node)
+	server.RemovePeer(node)
+	if err := api.node.config.RemovePersistentNode(node); err != nil {
+		return false, fmt.Errorf("failed to persist trusted node: %v", err)
+	}
+	return true, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -251,6 +293,16 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerDrops retrieves the last peer disconnections along with their reasons,
+// the oldest first.
+func (api *PublicAdminAPI) PeerDrops() ([]*p2p.PeerDrop, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerDrops(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -18,12 +18,17 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
@@ -33,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const (
@@ -41,8 +47,13 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+
+	minJWTSecretLength = 32 // Minimum length in bytes of the secret authenticating RPC clients
 )
 
+// persistentNodesLock serializes the updates of the persistent node lists.
+var persistentNodesLock sync.Mutex
+
 // Config represents a small collection of configuration values to fine tune the
 // P2P network layer of a protocol stack. These values can be further extended by
 // all registered services.
@@ -82,6 +93,19 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreScryptN and KeyStoreScryptP override the scrypt parameters of the
+	// version 3 key files if set.
+	KeyStoreScryptN int `toml:",omitempty"`
+	KeyStoreScryptP int `toml:",omitempty"`
+
+	// KeyStoreArgon2 stores the new and updated keys in version 4 key files,
+	// encrypted with a key derived by argon2id, e.g. for the masternode keys.
+	// KeyStoreArgon2Time and KeyStoreArgon2Memory (in KiB) override the
+	// argon2id parameters if set.
+	KeyStoreArgon2       bool   `toml:",omitempty"`
+	KeyStoreArgon2Time   uint32 `toml:",omitempty"`
+	KeyStoreArgon2Memory uint32 `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
@@ -119,6 +143,17 @@ type Config struct {
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPHosts configures the requests made via the HTTP RPC interface through
+	// some virtual hosts, overriding the CORS origins and methods served. The
+	// hosts configured are accepted on top of HTTPVirtualHosts.
+	HTTPHosts map[string]rpc.VirtualHost `toml:",omitempty"`
+
+	// HTTPTLSCert and HTTPTLSKey are the paths of the PEM encoded certificate and
+	// private key the HTTP RPC interface serves TLS with. If they are empty, plain
+	// HTTP is served.
+	HTTPTLSCert string `toml:",omitempty"`
+	HTTPTLSKey  string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -145,6 +180,41 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// HTTPMethods and WSMethods whitelist the methods served via the HTTP and
+	// websocket RPC interfaces, as namespace_method or namespace_* entries. If the
+	// list is empty, all the methods of the exposed modules are served.
+	HTTPMethods []string `toml:",omitempty"`
+	WSMethods   []string `toml:",omitempty"`
+
+	// RPCRateLimit is the number of requests per second served to a client IP via
+	// the HTTP and websocket RPC interfaces, unlimited if zero. RPCMethodRateLimits
+	// further limits the requests per second of a client IP to some methods.
+	RPCRateLimit        float64            `toml:",omitempty"`
+	RPCMethodRateLimits map[string]float64 `toml:",omitempty"`
+
+	// RPCTimeout is the execution time allowed to the expensive methods, like
+	// eth_call and eth_getLogs, via the HTTP and websocket RPC interfaces. Their
+	// requests are answered with an error once it elapses. Zero disables it.
+	RPCTimeout time.Duration `toml:",omitempty"`
+
+	// RPCBatchLimit is the maximum number of requests in a batch, and
+	// RPCBatchResponseSize the maximum size in bytes of the responses to a batch,
+	// via the HTTP and websocket RPC interfaces. Zero disables them.
+	RPCBatchLimit        int `toml:",omitempty"`
+	RPCBatchResponseSize int `toml:",omitempty"`
+
+	// RPCJWTSecret is the path of the file holding the hex encoded secret of the
+	// HS256 tokens authenticating the clients of the HTTP and websocket RPC
+	// interfaces, generated if missing. The methods of the RPCAuthModules, or of
+	// the admin, debug, miner and personal modules if empty, are only served to
+	// authenticated clients. Authentication is disabled if the path is empty.
+	RPCJWTSecret   string   `toml:",omitempty"`
+	RPCAuthModules []string `toml:",omitempty"`
+
+	// Endpoints are additional IPC and HTTP RPC endpoints serving their own sets
+	// of modules and methods, e.g. a read-only socket for explorer backends.
+	Endpoints []Endpoint `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -159,21 +229,26 @@ func (c *Config) IPCEndpoint() string {
 	if c.IPCPath == "" {
 		return ""
 	}
+	return c.ipcPath(c.IPCPath)
+}
+
+// ipcPath resolves the path of an IPC socket.
+func (c *Config) ipcPath(path string) string {
 	// On windows we can only use plain top-level pipes
 	if runtime.GOOS == "windows" {
-		if strings.HasPrefix(c.IPCPath, `\\.\pipe\`) {
-			return c.IPCPath
+		if strings.HasPrefix(path, `\\.\pipe\`) {
+			return path
 		}
-		return `\\.\pipe\` + c.IPCPath
+		return `\\.\pipe\` + path
 	}
 	// Resolve names into the data directory full paths otherwise
-	if filepath.Base(c.IPCPath) == c.IPCPath {
+	if filepath.Base(path) == path {
 		if c.DataDir == "" {
-			return filepath.Join(os.TempDir(), c.IPCPath)
+			return filepath.Join(os.TempDir(), path)
 		}
-		return filepath.Join(c.DataDir, c.IPCPath)
+		return filepath.Join(c.DataDir, path)
 	}
-	return c.IPCPath
+	return path
 }
 
 // NodeDB returns the path to the discovery node database.
@@ -332,6 +407,37 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// JWTSecret loads the secret authenticating the RPC clients, generating and
+// storing a new one if the configured file doesn't exist. It returns nil if
+// authentication is disabled.
+func (c *Config) JWTSecret() ([]byte, error) {
+	if c.RPCJWTSecret == "" {
+		return nil, nil
+	}
+	if blob, err := ioutil.ReadFile(c.RPCJWTSecret); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", c.RPCJWTSecret, err)
+		}
+		if len(secret) < minJWTSecretLength {
+			return nil, fmt.Errorf("JWT secret in %s too short: %d bytes, want at least %d", c.RPCJWTSecret, len(secret), minJWTSecretLength)
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No secret found, generate and store a new one
+	secret := make([]byte, minJWTSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(c.RPCJWTSecret, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, fmt.Errorf("failed to store JWT secret: %v", err)
+	}
+	log.Info("Generated RPC JWT secret", "path", c.RPCJWTSecret)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*discover.Node {
 	return c.parsePersistentNodes(c.resolvePath(datadirStaticNodes))
@@ -374,6 +480,79 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 	return nodes
 }
 
+// AddPersistentNode adds a node to the static and trusted node lists within the
+// data directory, so that it is dialed and trusted on the next start too.
+func (c *Config) AddPersistentNode(node *discover.Node) error {
+	for _, file := range []string{datadirStaticNodes, datadirTrustedNodes} {
+		if err := c.updatePersistentNodes(c.resolvePath(file), node, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemovePersistentNode removes a node from the static and trusted node lists
+// within the data directory.
+func (c *Config) RemovePersistentNode(node *discover.Node) error {
+	for _, file := range []string{datadirStaticNodes, datadirTrustedNodes} {
+		if err := c.updatePersistentNodes(c.resolvePath(file), node, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updatePersistentNodes adds or removes a node in a list of discovery node URLs
+// stored in a .json file from within the data directory. Entries are matched by
+// node ID, the other entries of the list are kept untouched.
+func (c *Config) updatePersistentNodes(path string, node *discover.Node, add bool) error {
+	// Short circuit if there is no data directory to persist into
+	if c.DataDir == "" {
+		return nil
+	}
+	persistentNodesLock.Lock()
+	defer persistentNodesLock.Unlock()
+
+	var nodelist []string
+	if _, err := os.Stat(path); err == nil {
+		if err := common.LoadJSON(path, &nodelist); err != nil {
+			return fmt.Errorf("can't load node file %s: %v", path, err)
+		}
+	}
+	var (
+		updated []string
+		found   bool
+	)
+	for _, url := range nodelist {
+		if n, err := discover.ParseNode(url); err == nil && n.ID == node.ID {
+			if !add || found {
+				continue
+			}
+			url, found = node.String(), true
+		}
+		updated = append(updated, url)
+	}
+	if add && !found {
+		updated = append(updated, node.String())
+	}
+	if updated == nil {
+		updated = []string{}
+	}
+	blob, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first to never leave a truncated list behind
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(blob, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 // AccountConfig determines the settings for scrypt and keydirectory
 func (c *Config) AccountConfig() (int, int, string, error) {
 	scryptN := keystore.StandardScryptN
@@ -382,6 +561,12 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	if c.KeyStoreScryptN != 0 {
+		scryptN = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptP != 0 {
+		scryptP = c.KeyStoreScryptP
+	}
 
 	var (
 		keydir string
@@ -402,6 +587,25 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 	return scryptN, scryptP, keydir, err
 }
 
+// Argon2Config returns the argon2id parameters of the version 4 key files, or
+// nil if the key store uses version 3 ones.
+func (c *Config) Argon2Config() *keystore.Argon2Params {
+	if !c.KeyStoreArgon2 {
+		return nil
+	}
+	params := keystore.StandardArgon2
+	if c.UseLightweightKDF {
+		params = keystore.LightArgon2
+	}
+	if c.KeyStoreArgon2Time != 0 {
+		params.Time = c.KeyStoreArgon2Time
+	}
+	if c.KeyStoreArgon2Memory != 0 {
+		params.Memory = c.KeyStoreArgon2Memory
+	}
+	return &params
+}
+
 func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	scryptN, scryptP, keydir, err := conf.AccountConfig()
 	var ephemeral string
@@ -418,9 +622,13 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 		return nil, "", err
 	}
 	// Assemble the account manager and supported backends
-	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+	var ks *keystore.KeyStore
+	if params := conf.Argon2Config(); params != nil {
+		ks = keystore.NewKeyStoreArgon2(keydir, *params)
+	} else {
+		ks = keystore.NewKeyStore(keydir, scryptN, scryptP)
 	}
+	backends := []accounts.Backend{ks}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
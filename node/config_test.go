@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
 )
 
 // Tests that datadirs can be successfully created, be them manually configured
@@ -148,3 +149,64 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that nodes added and removed at runtime are persisted to the static and
+// trusted node lists.
+func TestPersistentNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		config = &Config{Name: "unit-test", DataDir: dir}
+		first  = discover.MustParseNode("enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@127.0.0.1:30303")
+		second = discover.MustParseNode("enode://1dd9d65c4552b5eb43d5ad55a2ee3f56c6cbc1c64a5c8d659f51fcd51bace24351232b8d7821617d2b29b54b81cdefb9b3e9c37d7fd5f63270bcc9e1a6f6a439@127.0.0.1:30304")
+	)
+	for _, node := range []*discover.Node{first, second, first} {
+		if err := config.AddPersistentNode(node); err != nil {
+			t.Fatalf("failed to add persistent node: %v", err)
+		}
+	}
+	for name, nodes := range map[string][]*discover.Node{"static": config.StaticNodes(), "trusted": config.TrustedNodes()} {
+		if len(nodes) != 2 || nodes[0].ID != first.ID || nodes[1].ID != second.ID {
+			t.Errorf("%s nodes mismatch after adding: %v", name, nodes)
+		}
+	}
+	if err := config.RemovePersistentNode(first); err != nil {
+		t.Fatalf("failed to remove persistent node: %v", err)
+	}
+	for name, nodes := range map[string][]*discover.Node{"static": config.StaticNodes(), "trusted": config.TrustedNodes()} {
+		if len(nodes) != 1 || nodes[0].ID != second.ID {
+			t.Errorf("%s nodes mismatch after removing: %v", name, nodes)
+		}
+	}
+}
+
+// Tests that the JWT secret is generated if missing, then loaded, and that
+// short secrets are refused.
+func TestJWTSecretPersistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-jwt-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if secret, err := (&Config{}).JWTSecret(); secret != nil || err != nil {
+		t.Fatalf("secret loaded with authentication disabled: %x, %v", secret, err)
+	}
+	config := &Config{RPCJWTSecret: filepath.Join(dir, "jwt.hex")}
+	generated, err := config.JWTSecret()
+	if err != nil || len(generated) != minJWTSecretLength {
+		t.Fatalf("failed to generate secret: %x, %v", generated, err)
+	}
+	loaded, err := config.JWTSecret()
+	if err != nil || !bytes.Equal(loaded, generated) {
+		t.Fatalf("secret mismatch: have %x, want %x (%v)", loaded, generated, err)
+	}
+	ioutil.WriteFile(config.RPCJWTSecret, []byte("0x1234\n"), 0600)
+	if _, err := config.JWTSecret(); err == nil {
+		t.Fatal("short secret accepted")
+	}
+}
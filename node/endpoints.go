@@ -0,0 +1,130 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// readOnlyDenied are the methods refused by the read-only endpoints, the ones
+// sending transactions or orders and signing with the keys of the node.
+var readOnlyDenied = []string{
+	"eth_sendTransaction", "eth_sendRawTransaction", "eth_resend",
+	"eth_sign", "eth_signTransaction", "eth_signTypedData",
+	"eth_submitWork", "eth_submitHashrate",
+	"tomox_sendOrder", "tomox_sendOrderRawTransaction", "tomox_signOrder",
+	"tomoxlending_lend", "tomoxlending_borrow", "tomoxlending_repay", "tomoxlending_cancel",
+	"admin_*", "debug_*", "miner_*", "personal_*",
+}
+
+var errEndpointKind = errors.New("endpoint needs exactly one of an IPC path and an HTTP address")
+
+// Endpoint is an additional RPC endpoint of a node serving its own set of
+// modules, e.g. a read-only IPC socket for explorer backends next to the full
+// one of the local tools.
+type Endpoint struct {
+	IPCPath  string   `toml:",omitempty"` // Path of the IPC socket, resolved like the IPCPath of the node
+	HTTPAddr string   `toml:",omitempty"` // Listening address of the HTTP endpoint, as host:port
+	Modules  []string `toml:",omitempty"` // Modules served, all the public ones if empty
+	Methods  []string `toml:",omitempty"` // Methods served, as namespace_method or namespace_*, all if empty
+	ReadOnly bool     `toml:",omitempty"` // Refuse the methods sending transactions or using the node keys
+}
+
+// endpoint is a running additional endpoint.
+type endpoint struct {
+	url      string
+	listener net.Listener
+	handler  *rpc.Server
+}
+
+// startEndpoints initializes and starts the additional RPC endpoints.
+func (n *Node) startEndpoints(apis []rpc.API) error {
+	for _, config := range n.config.Endpoints {
+		if err := n.startEndpoint(config, apis); err != nil {
+			n.stopEndpoints()
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Node) startEndpoint(config Endpoint, apis []rpc.API) error {
+	if (config.IPCPath == "") == (config.HTTPAddr == "") {
+		return errEndpointKind
+	}
+	// Register the APIs of the whitelisted modules
+	whitelist := make(map[string]bool)
+	for _, module := range config.Modules {
+		whitelist[module] = true
+	}
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+				return err
+			}
+		}
+	}
+	// The HTTP endpoints are public ones, the IPC sockets local ones
+	policy := &rpc.Policy{Methods: config.Methods}
+	if config.HTTPAddr != "" {
+		var err error
+		if policy, err = n.rpcPolicy(config.Methods); err != nil {
+			return err
+		}
+	}
+	if config.ReadOnly {
+		policy.Denied = readOnlyDenied
+	}
+	handler.SetPolicy(policy)
+
+	var (
+		listener net.Listener
+		url      string
+		err      error
+	)
+	if config.IPCPath != "" {
+		url = n.config.ipcPath(config.IPCPath)
+		if listener, err = rpc.CreateIPCListener(url); err != nil {
+			return err
+		}
+		go handler.ServeListener(listener)
+	} else {
+		if listener, err = net.Listen("tcp", config.HTTPAddr); err != nil {
+			return err
+		}
+		url = fmt.Sprintf("http://%s", listener.Addr())
+		go rpc.NewHTTPServerWithHosts(n.config.HTTPCors, n.config.HTTPVirtualHosts, nil, handler).Serve(listener)
+	}
+	n.log.Info("RPC endpoint opened", "url", url, "modules", config.Modules, "readonly", config.ReadOnly)
+	n.endpoints = append(n.endpoints, &endpoint{url: url, listener: listener, handler: handler})
+	return nil
+}
+
+// stopEndpoints terminates the additional RPC endpoints.
+func (n *Node) stopEndpoints() {
+	for _, e := range n.endpoints {
+		e.listener.Close()
+		e.handler.Stop()
+		n.log.Info("RPC endpoint closed", "url", e.url)
+	}
+	n.endpoints = nil
+}
@@ -17,6 +17,7 @@
 package node
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -67,6 +68,8 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	endpoints []*endpoint // Additional RPC endpoints serving their own modules
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -274,6 +277,13 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
+	if err := n.startEndpoints(apis); err != nil {
+		n.stopWS()
+		n.stopHTTP()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
 	// All API endpoints started successfully
 	n.rpcAPIs = apis
 	return nil
@@ -385,16 +395,34 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 			n.log.Debug("HTTP registered", "service", api.Service, "namespace", api.Namespace)
 		}
 	}
-	// All APIs registered, start the HTTP listener
+	policy, err := n.rpcPolicy(n.config.HTTPMethods)
+	if err != nil {
+		return err
+	}
+	handler.SetPolicy(policy)
+
+	// Load the TLS certificate if the endpoint is served over TLS
 	var (
-		listener net.Listener
-		err      error
+		tlsConfig *tls.Config
+		scheme    = "http"
 	)
+	if n.config.HTTPTLSCert != "" || n.config.HTTPTLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(n.config.HTTPTLSCert, n.config.HTTPTLSKey)
+		if err != nil {
+			return fmt.Errorf("failed to load HTTP TLS certificate: %v", err)
+		}
+		tlsConfig, scheme = &tls.Config{Certificates: []tls.Certificate{cert}}, "https"
+	}
+	// All APIs registered, start the HTTP listener
+	var listener net.Listener
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	go rpc.NewHTTPServerWithHosts(cors, vhosts, n.config.HTTPHosts, handler).Serve(listener)
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("%s://%s", scheme, endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -403,6 +431,27 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	return nil
 }
 
+// rpcPolicy returns the restrictions of the requests served by a public RPC
+// endpoint whitelisting the given methods.
+func (n *Node) rpcPolicy(methods []string) (*rpc.Policy, error) {
+	secret, err := n.config.JWTSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.Policy{
+		Methods:      methods,
+		RateLimit:    n.config.RPCRateLimit,
+		MethodLimits: n.config.RPCMethodRateLimits,
+		Timeout:      n.config.RPCTimeout,
+
+		BatchLimit:        n.config.RPCBatchLimit,
+		BatchResponseSize: n.config.RPCBatchResponseSize,
+
+		JWTSecret:      secret,
+		AuthNamespaces: n.config.RPCAuthModules,
+	}, nil
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -438,11 +487,14 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 			n.log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
 		}
 	}
+	policy, err := n.rpcPolicy(n.config.WSMethods)
+	if err != nil {
+		return err
+	}
+	handler.SetPolicy(policy)
+
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
+	var listener net.Listener
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
@@ -483,6 +535,7 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
+	n.stopEndpoints()
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
@@ -17,9 +17,19 @@
 package node
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
+	"math/big"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -573,3 +583,116 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the HTTP RPC endpoint is served over TLS when a certificate is
+// configured, and that the node refuses to start with an invalid one.
+func TestHTTPTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-tls-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	// A missing key is reported at startup
+	config := testNodeConfig()
+	config.HTTPHost, config.HTTPTLSCert, config.HTTPTLSKey = "127.0.0.1", certFile, filepath.Join(dir, "missing.pem")
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err == nil {
+		stack.Stop()
+		t.Fatal("node started with a missing TLS key")
+	}
+	// A valid certificate serves the endpoint over TLS
+	config = testNodeConfig()
+	config.HTTPHost, config.HTTPTLSCert, config.HTTPTLSKey = "127.0.0.1", certFile, keyFile
+	if stack, err = New(config); err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	client, err := rpc.DialHTTPWithClient("https://"+stack.httpListener.Addr().String(), &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial endpoint: %v", err)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("failed to call over TLS: %v", err)
+	}
+	if resp, err := http.Post("http://"+stack.httpListener.Addr().String(), "application/json", nil); err == nil && resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain HTTP served by the TLS endpoint: %s", resp.Status)
+	}
+}
+
+// Tests that the additional endpoints serve their own modules, the read-only
+// ones refusing the privileged methods, and are closed with the node.
+func TestAdditionalEndpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-endpoints-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := testNodeConfig()
+	config.Endpoints = []Endpoint{
+		{IPCPath: filepath.Join(dir, "readonly.ipc"), ReadOnly: true},
+		{HTTPAddr: "127.0.0.1:0", Modules: []string{"admin"}},
+	}
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	ipcClient, err := rpc.Dial(filepath.Join(dir, "readonly.ipc"))
+	if err != nil {
+		t.Fatalf("failed to dial read-only endpoint: %v", err)
+	}
+	defer ipcClient.Close()
+	httpClient, err := rpc.Dial(stack.endpoints[1].url)
+	if err != nil {
+		t.Fatalf("failed to dial HTTP endpoint: %v", err)
+	}
+	var result interface{}
+	if err := ipcClient.Call(&result, "web3_clientVersion"); err != nil {
+		t.Errorf("public method refused by the read-only endpoint: %v", err)
+	}
+	if err := ipcClient.Call(&result, "admin_nodeInfo"); err == nil {
+		t.Error("admin method served by the read-only endpoint")
+	}
+	if err := httpClient.Call(&result, "admin_nodeInfo"); err != nil {
+		t.Errorf("whitelisted module refused: %v", err)
+	}
+	if err := httpClient.Call(&result, "web3_clientVersion"); err == nil {
+		t.Error("module beyond the whitelist served")
+	}
+	if err := stack.Stop(); err != nil {
+		t.Fatalf("failed to stop protocol stack: %v", err)
+	}
+	if _, err := rpc.Dial(filepath.Join(dir, "readonly.ipc")); err == nil {
+		t.Error("read-only endpoint left open")
+	}
+}
@@ -22,8 +22,10 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -97,6 +99,9 @@ type PeerEvent struct {
 
 // Peer represents a connected remote node.
 type Peer struct {
+	ingress uint64 // Bytes of the messages received, accessed atomically
+	egress  uint64 // Bytes of the protocol messages sent, accessed atomically
+
 	rw      *conn
 	running map[string]*protoRW
 	log     log.Logger
@@ -262,6 +267,7 @@ func (p *Peer) readLoop(errc chan<- error) {
 			return
 		}
 		msg.ReceivedAt = time.Now()
+		atomic.AddUint64(&p.ingress, uint64(msg.Size))
 		if err = p.handle(msg); err != nil {
 			errc <- err
 			return
@@ -343,6 +349,7 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		proto.closed = p.closed
 		proto.wstart = writeStart
 		proto.werr = writeErr
+		proto.egress = &p.egress
 		var rw MsgReadWriter = proto
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name)
@@ -382,6 +389,7 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+	egress *uint64 // Bytes sent by the peer, accessed atomically
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
@@ -392,6 +400,9 @@ func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
+		if err == nil && rw.egress != nil {
+			atomic.AddUint64(rw.egress, uint64(msg.Size))
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -426,6 +437,9 @@ type PeerInfo struct {
 		Inbound       bool   `json:"inbound"`
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
+		Ingress       uint64 `json:"ingress"`  // Bytes of the messages received from the peer
+		Egress        uint64 `json:"egress"`   // Bytes of the protocol messages sent to the peer
+		Duration      string `json:"duration"` // Time since the peer connected
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -449,6 +463,9 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	info.Network.Ingress = atomic.LoadUint64(&p.ingress)
+	info.Network.Egress = atomic.LoadUint64(&p.egress)
+	info.Network.Duration = common.PrettyDuration(mclock.Now() - p.created).String()
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
@@ -0,0 +1,91 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// maxPeerDrops is the number of peer disconnections kept by the server.
+const maxPeerDrops = 256
+
+// PeerDrop records the disconnection of a peer, to diagnose connectivity issues.
+type PeerDrop struct {
+	ID            string    `json:"id"`            // Unique node identifier of the peer
+	Name          string    `json:"name"`          // Name of the node of the peer
+	RemoteAddress string    `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+	Inbound       bool      `json:"inbound"`
+	Requested     bool      `json:"requested"` // Whether the peer requested the disconnection
+	Reason        string    `json:"reason"`    // Reason of the disconnection
+	Duration      string    `json:"duration"`  // Time the peer stayed connected
+	Ingress       uint64    `json:"ingress"`   // Bytes of the messages received from the peer
+	Egress        uint64    `json:"egress"`    // Bytes of the protocol messages sent to the peer
+	Time          time.Time `json:"time"`      // Time of the disconnection
+}
+
+// peerDropLog is a rolling log of the last peer disconnections.
+type peerDropLog struct {
+	drops []*PeerDrop // Ring of the disconnections, next holding the oldest once full
+	next  int
+	lock  sync.Mutex
+}
+
+// add records the disconnection of a peer.
+func (l *peerDropLog) add(pd peerDrop, now time.Time) {
+	drop := &PeerDrop{
+		ID:            pd.ID().String(),
+		Name:          pd.Name(),
+		RemoteAddress: pd.RemoteAddr().String(),
+		Inbound:       pd.Inbound(),
+		Requested:     pd.requested,
+		Duration:      common.PrettyDuration(mclock.Now() - pd.created).String(),
+		Ingress:       atomic.LoadUint64(&pd.ingress),
+		Egress:        atomic.LoadUint64(&pd.egress),
+		Time:          now,
+	}
+	if pd.err != nil {
+		drop.Reason = pd.err.Error()
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.drops) < maxPeerDrops {
+		l.drops = append(l.drops, drop)
+		return
+	}
+	l.drops[l.next] = drop
+	l.next = (l.next + 1) % maxPeerDrops
+}
+
+// list returns the disconnections recorded, the oldest first.
+func (l *peerDropLog) list() []*PeerDrop {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	drops := make([]*PeerDrop, 0, len(l.drops))
+	drops = append(drops, l.drops[l.next:]...)
+	return append(drops, l.drops[:l.next]...)
+}
+
+// PeerDrops returns the last peer disconnections, the oldest first.
+func (srv *Server) PeerDrops() []*PeerDrop {
+	return srv.drops.list()
+}
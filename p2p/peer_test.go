@@ -98,6 +98,42 @@ func TestPeerProtoReadMsg(t *testing.T) {
 	}
 }
 
+// Tests that the bytes of the messages exchanged with a peer are accounted.
+func TestPeerTraffic(t *testing.T) {
+	done := make(chan struct{})
+	proto := Protocol{
+		Name:   "a",
+		Length: 5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+				t.Error(err)
+			}
+			if err := Send(rw, 3, []byte("reply")); err != nil {
+				t.Error(err)
+			}
+			<-done
+			return nil
+		},
+	}
+	closer, rw, peer, _ := testPeer([]Protocol{proto})
+	defer closer()
+	defer close(done)
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []byte("reply")); err != nil {
+		t.Fatal(err)
+	}
+	// The write is accounted once it returned, after the reply was read
+	info := peer.Info()
+	for i := 0; i < 100 && info.Network.Egress == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		info = peer.Info()
+	}
+	if info.Network.Ingress != 2 || info.Network.Egress != 6 {
+		t.Errorf("traffic mismatch: have %d in, %d out, want 2 in, 6 out", info.Network.Ingress, info.Network.Egress)
+	}
+}
+
 func TestPeerProtoEncodeMsg(t *testing.T) {
 	proto := Protocol{
 		Name:   "a",
@@ -19,6 +19,7 @@ package p2p
 import (
 	"crypto/ecdsa"
 	"errors"
+	"fmt"
 	"math/rand"
 	"net"
 	"reflect"
@@ -378,6 +379,26 @@ func TestServerAtCap(t *testing.T) {
 		t.Error("Server did not set trusted flag")
 	}
 
+	// Add a node to the trusted set while the server is running.
+	anotherID := randomID()
+	srv.AddTrustedPeer(&discover.Node{ID: anotherID})
+	c = newconn(anotherID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
+		t.Error("unexpected error for trusted conn @posthandshake:", err)
+	}
+	if !c.is(trustedConn) {
+		t.Error("Server did not set trusted flag")
+	}
+
+	// Remove it and check it is treated as a regular peer again.
+	srv.RemoveTrustedPeer(&discover.Node{ID: anotherID})
+	c = newconn(anotherID)
+	if err := srv.checkpoint(c, srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for insert:", err)
+	}
+	if c.is(trustedConn) {
+		t.Error("Server set trusted flag after removal")
+	}
 }
 
 func TestServerSetupConn(t *testing.T) {
@@ -516,3 +537,33 @@ func randomID() (id discover.NodeID) {
 	}
 	return id
 }
+
+// Tests that the server keeps a rolling log of the last peer disconnections.
+func TestServerPeerDrops(t *testing.T) {
+	var (
+		srv   = &Server{}
+		peers []*Peer
+	)
+	for i := 0; i < maxPeerDrops+10; i++ {
+		var id discover.NodeID
+		id[0], id[1] = byte(i>>8), byte(i)
+		p := NewPeer(id, fmt.Sprintf("peer-%d", i), nil)
+		p.ingress, p.egress = uint64(i), uint64(2*i)
+		peers = append(peers, p)
+
+		srv.drops.add(peerDrop{p, DiscUselessPeer, i%2 == 0}, time.Unix(int64(i), 0))
+		if drops := srv.PeerDrops(); len(drops) != i+1 && len(drops) != maxPeerDrops {
+			t.Fatalf("drop %d: log length mismatch: have %d", i, len(drops))
+		}
+	}
+	drops := srv.PeerDrops()
+	for i, drop := range drops {
+		p := peers[i+10]
+		if drop.ID != p.ID().String() || drop.Name != p.Name() || drop.Reason != DiscUselessPeer.Error() || drop.Requested != ((i+10)%2 == 0) {
+			t.Errorf("drop %d: mismatch: have %+v", i, drop)
+		}
+		if drop.Ingress != p.ingress || drop.Egress != p.egress || !drop.Time.Equal(time.Unix(int64(i+10), 0)) {
+			t.Errorf("drop %d: stats mismatch: have %+v", i, drop)
+		}
+	}
+}
@@ -102,16 +102,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil, nil}
 
 	// AllPosvProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Posv consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllPosvProtocolChanges   = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, &PosvConfig{Period: 0, Epoch: 30000}}
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
-	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil}
+	AllPosvProtocolChanges   = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, &PosvConfig{Period: 0, Epoch: 30000}, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil}
+	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil, nil}
 	TestRules                = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -137,11 +137,16 @@ type ChainConfig struct {
 
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+	BerlinBlock         *big.Int `json:"berlinBlock,omitempty"`         // Berlin switch block (nil = no fork, 0 = already on berlin)
+	TradeRootBlock      *big.Int `json:"tradeRootBlock,omitempty"`      // TomoX trade root switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 	Posv   *PosvConfig   `json:"posv,omitempty"`
+
+	TomoXLending *TomoXLendingConfig `json:"tomoxLending,omitempty"` // Liquidation parameters of the TomoX lending (nil = defaults)
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -165,12 +170,41 @@ func (c *CliqueConfig) String() string {
 
 // PosvConfig is the consensus engine configs for proof-of-stake-voting based sealing.
 type PosvConfig struct {
-	Period              uint64         `json:"period"`              // Number of seconds between blocks to enforce
-	Epoch               uint64         `json:"epoch"`               // Epoch length to reset votes and checkpoint
-	Reward              uint64         `json:"reward"`              // Block reward - unit Ether
-	RewardCheckpoint    uint64         `json:"rewardCheckpoint"`    // Checkpoint block for calculate rewards.
-	Gap                 uint64         `json:"gap"`                 // Gap time preparing for the next epoch
-	FoudationWalletAddr common.Address `json:"foudationWalletAddr"` // Foundation Address Wallet
+	Period              uint64         `json:"period"`                      // Number of seconds between blocks to enforce
+	Epoch               uint64         `json:"epoch"`                       // Epoch length to reset votes and checkpoint
+	Reward              uint64         `json:"reward"`                      // Block reward - unit Ether
+	RewardCheckpoint    uint64         `json:"rewardCheckpoint"`            // Checkpoint block for calculate rewards.
+	Gap                 uint64         `json:"gap"`                         // Gap time preparing for the next epoch
+	FoudationWalletAddr common.Address `json:"foudationWalletAddr"`         // Foundation Address Wallet
+	MaxMasternodes      int            `json:"maxMasternodes,omitempty"`    // Maximum number of masternodes sealing an epoch (0 = common.MaxMasternodes)
+	LimitPenaltyEpoch   int            `json:"limitPenaltyEpoch,omitempty"` // Number of epochs a penalized masternode stays out (0 = common.LimitPenaltyEpoch)
+
+	MasternodeDataBlock *big.Int `json:"masternodeDataBlock,omitempty"` // Block activating the masternode data precompiled contract (nil = not activated)
+	TomoXPairHaltBlock  *big.Int `json:"tomoxPairHaltBlock,omitempty"`  // Block activating the halts of the TomoX pairs (nil = not activated)
+	TomoXPairSizeBlock  *big.Int `json:"tomoxPairSizeBlock,omitempty"`  // Block activating the tick and lot sizes of the TomoX pairs (nil = not activated)
+	TRC21FeeBlock       *big.Int `json:"trc21FeeBlock,omitempty"`       // Block activating the fees of the TRC21 tokens paid by their sponsors (nil = from genesis)
+	BLSBlock            *big.Int `json:"blsBlock,omitempty"`            // Block activating the BLS key registry and the checkpoint signature aggregates (nil = not activated)
+	RelayerFeeBlock     *big.Int `json:"relayerFeeBlock,omitempty"`     // Block activating the relayer fees cached in the TomoX state at checkpoints (nil = not activated)
+	PriceOracleBlock    *big.Int `json:"priceOracleBlock,omitempty"`    // Block activating the TomoX price oracle and its precompiled contract (nil = not activated)
+	LendingBlock        *big.Int `json:"lendingBlock,omitempty"`        // Block activating the TomoX lending books and the liquidation of their loans (nil = not activated)
+
+	RewardSplits []RewardSplit `json:"rewardSplits,omitempty"` // Reward splits by activation block (none = common.Reward*Percent)
+	TomoXForks   []TomoXFork   `json:"tomoxForks,omitempty"`   // Versions of the TomoX matching rules by activation block (none = version 0)
+}
+
+// TomoXFork schedules a version of the TomoX matching rules from a block on.
+type TomoXFork struct {
+	Block   *big.Int `json:"block"`
+	Version uint64   `json:"version"`
+}
+
+// RewardSplit is the share of the checkpoint reward of a masternode paid to its
+// owner, to its voters and to the foundation, in percent, from a block on.
+type RewardSplit struct {
+	Block      *big.Int `json:"block"`
+	Masternode uint64   `json:"masternode"`
+	Voter      uint64   `json:"voter"`
+	Foundation uint64   `json:"foundation"`
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -178,6 +212,138 @@ func (c *PosvConfig) String() string {
 	return "posv"
 }
 
+// MasternodesLimit returns the maximum number of masternodes allowed to seal
+// blocks in an epoch, falling back to the mainnet value if not configured.
+func (c *PosvConfig) MasternodesLimit() int {
+	if c.MaxMasternodes > 0 {
+		return c.MaxMasternodes
+	}
+	return common.MaxMasternodes
+}
+
+// PenaltyEpochs returns the number of epochs a penalized masternode is kept out
+// of the masternode set, falling back to the mainnet value if not configured.
+func (c *PosvConfig) PenaltyEpochs() int {
+	if c.LimitPenaltyEpoch > 0 {
+		return c.LimitPenaltyEpoch
+	}
+	return common.LimitPenaltyEpoch
+}
+
+// RewardSplitAt returns the reward split active at a block, falling back to the
+// mainnet split before the first configured one.
+func (c *PosvConfig) RewardSplitAt(num *big.Int) RewardSplit {
+	split := RewardSplit{
+		Block:      common.Big0,
+		Masternode: common.RewardMasterPercent,
+		Voter:      common.RewardVoterPercent,
+		Foundation: common.RewardFoundationPercent,
+	}
+	for _, next := range c.RewardSplits {
+		if !isForked(next.Block, num) {
+			break
+		}
+		split = next
+	}
+	return split
+}
+
+// TomoXLendingConfig holds the liquidation parameters of the TomoX lending.
+type TomoXLendingConfig struct {
+	LiquidationRate uint64                    `json:"liquidationRate,omitempty"` // Collateral value in percent of the debt below which loans are liquidated (0 = common.LendingLiquidationRate)
+	PriceSources    []TomoXLendingPriceSource `json:"priceSources,omitempty"`    // Pairs valuing the collaterals, instead of the collateral/lending token pairs
+}
+
+// TomoXLendingPriceSource designates the TomoX pair whose last traded price
+// values a collateral token in a lending token. The pair must be made of both
+// tokens, in either order.
+type TomoXLendingPriceSource struct {
+	CollateralToken common.Address `json:"collateralToken"`
+	LendingToken    common.Address `json:"lendingToken"`
+	BaseToken       common.Address `json:"baseToken"`
+	QuoteToken      common.Address `json:"quoteToken"`
+}
+
+// LiquidationThreshold returns the collateral value, in percent of the debt,
+// below which loans are liquidated, falling back to the default if not
+// configured.
+func (c *TomoXLendingConfig) LiquidationThreshold() uint64 {
+	if c != nil && c.LiquidationRate > 0 {
+		return c.LiquidationRate
+	}
+	return common.LendingLiquidationRate
+}
+
+// PriceSource returns the pair configured to value a collateral token in a
+// lending token, ok is false if none is configured.
+func (c *TomoXLendingConfig) PriceSource(collateralToken, lendingToken common.Address) (baseToken, quoteToken common.Address, ok bool) {
+	if c == nil {
+		return common.Address{}, common.Address{}, false
+	}
+	for _, source := range c.PriceSources {
+		if source.CollateralToken == collateralToken && source.LendingToken == lendingToken {
+			return source.BaseToken, source.QuoteToken, true
+		}
+	}
+	return common.Address{}, common.Address{}, false
+}
+
+// Validate checks that the configured price sources are pairs of their
+// collateral and lending tokens.
+func (c *TomoXLendingConfig) Validate() error {
+	for _, source := range c.PriceSources {
+		direct := source.BaseToken == source.CollateralToken && source.QuoteToken == source.LendingToken
+		inverse := source.BaseToken == source.LendingToken && source.QuoteToken == source.CollateralToken
+		if !direct && !inverse {
+			return fmt.Errorf("tomoxlending: price source of %s in %s is not a pair of both tokens", source.CollateralToken.Hex(), source.LendingToken.Hex())
+		}
+	}
+	return nil
+}
+
+// Validate checks the consistency of the consensus parameters so a misconfigured
+// genesis is rejected before any block is processed.
+func (c *PosvConfig) Validate() error {
+	if c.Epoch == 0 {
+		return fmt.Errorf("posv: epoch must be greater than zero")
+	}
+	if c.Gap >= c.Epoch {
+		return fmt.Errorf("posv: gap (%d) must be lower than epoch (%d)", c.Gap, c.Epoch)
+	}
+	if c.RewardCheckpoint != 0 && c.RewardCheckpoint%c.Epoch != 0 {
+		return fmt.Errorf("posv: reward checkpoint (%d) must be a multiple of epoch (%d)", c.RewardCheckpoint, c.Epoch)
+	}
+	if c.MaxMasternodes < 0 {
+		return fmt.Errorf("posv: invalid max masternodes %d", c.MaxMasternodes)
+	}
+	if c.LimitPenaltyEpoch < 0 {
+		return fmt.Errorf("posv: invalid penalty epoch limit %d", c.LimitPenaltyEpoch)
+	}
+	for i, split := range c.RewardSplits {
+		if split.Block == nil {
+			return fmt.Errorf("posv: reward split %d has no activation block", i)
+		}
+		if i > 0 && split.Block.Cmp(c.RewardSplits[i-1].Block) <= 0 {
+			return fmt.Errorf("posv: reward split %d activates at block %v, not after the previous one", i, split.Block)
+		}
+		if total := split.Masternode + split.Voter + split.Foundation; total != 100 {
+			return fmt.Errorf("posv: reward split %d sums to %d percent, not 100", i, total)
+		}
+	}
+	for i, fork := range c.TomoXForks {
+		if fork.Block == nil {
+			return fmt.Errorf("posv: tomox fork %d has no activation block", i)
+		}
+		if i > 0 && fork.Block.Cmp(c.TomoXForks[i-1].Block) <= 0 {
+			return fmt.Errorf("posv: tomox fork %d activates at block %v, not after the previous one", i, fork.Block)
+		}
+		if i > 0 && fork.Version <= c.TomoXForks[i-1].Version {
+			return fmt.Errorf("posv: tomox fork %d downgrades the matching rules to version %d", i, fork.Version)
+		}
+	}
+	return nil
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -189,7 +355,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Istanbul: %v Berlin: %v TradeRoot: %v Engine: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -199,6 +365,9 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.IstanbulBlock,
+		c.BerlinBlock,
+		c.TradeRootBlock,
 		engine,
 	)
 }
@@ -233,6 +402,23 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsIstanbul returns whether num is either equal to the Istanbul fork block or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
+// IsBerlin returns whether num is either equal to the Berlin fork block or greater.
+func (c *ChainConfig) IsBerlin(num *big.Int) bool {
+	return isForked(c.BerlinBlock, num)
+}
+
+// IsTradeRoot returns whether num is either equal to the trade root fork block
+// or greater, from which the blocks carry their matched trades committed to by
+// the trade root of their header.
+func (c *ChainConfig) IsTradeRoot(num *big.Int) bool {
+	return isForked(c.TradeRootBlock, num)
+}
+
 func (c *ChainConfig) IsTIP2019(num *big.Int) bool {
 	return isForked(common.TIP2019Block, num)
 }
@@ -245,6 +431,83 @@ func (c *ChainConfig) IsTIPRandomize(num *big.Int) bool {
 	return isForked(common.TIPRandomize, num)
 }
 
+// IsMasternodeData returns whether num is past the activation of the
+// masternode data precompiled contract.
+func (c *ChainConfig) IsMasternodeData(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.MasternodeDataBlock, num)
+}
+
+// IsTomoXPairHalt returns whether num is past the activation of the halts of
+// the TomoX pairs.
+func (c *ChainConfig) IsTomoXPairHalt(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.TomoXPairHaltBlock, num)
+}
+
+// IsTomoXPairSize returns whether num is past the activation of the tick and
+// lot sizes of the TomoX pairs.
+func (c *ChainConfig) IsTomoXPairSize(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.TomoXPairSizeBlock, num)
+}
+
+// IsTRC21Fee returns whether num is past the activation of the fees of the calls
+// to the TRC21 tokens paid by the sponsors of the tokens, from the genesis if
+// the activation block is not configured.
+func (c *ChainConfig) IsTRC21Fee(num *big.Int) bool {
+	if c.Posv == nil {
+		return true
+	}
+	return isForked(c.Posv.trc21FeeBlock(), num)
+}
+
+// trc21FeeBlock returns the activation block of the TRC21 fees, the genesis if
+// it is not configured.
+func (c *PosvConfig) trc21FeeBlock() *big.Int {
+	if c.TRC21FeeBlock == nil {
+		return new(big.Int)
+	}
+	return c.TRC21FeeBlock
+}
+
+// IsBLS returns whether num is past the activation of the BLS key registry and
+// of the aggregates of the masternode signatures in the checkpoint headers.
+func (c *ChainConfig) IsBLS(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.BLSBlock, num)
+}
+
+// IsRelayerFee returns whether num is past the activation of the relayer fees
+// cached in the TomoX state at the epoch checkpoints.
+func (c *ChainConfig) IsRelayerFee(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.RelayerFeeBlock, num)
+}
+
+// IsPriceOracle returns whether num is past the activation of the TomoX price
+// oracle recording the trades and its precompiled contract.
+func (c *ChainConfig) IsPriceOracle(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.PriceOracleBlock, num)
+}
+
+// IsLending returns whether num is past the activation of the TomoX lending
+// books, their lending transactions and the liquidation of their loans.
+func (c *ChainConfig) IsLending(num *big.Int) bool {
+	return c.Posv != nil && isForked(c.Posv.LendingBlock, num)
+}
+
+// TomoXVersion returns the version of the TomoX matching rules active at num,
+// the version of the last fork scheduled before it, 0 if none.
+func (c *ChainConfig) TomoXVersion(num *big.Int) uint64 {
+	var version uint64
+	if c.Posv == nil {
+		return version
+	}
+	for _, fork := range c.Posv.TomoXForks {
+		if !isForked(fork.Block, num) {
+			break
+		}
+		version = fork.Version
+	}
+	return version
+}
+
 func (c *ChainConfig) IsTIPTomoX(num *big.Int) bool {
 	if common.IsTestnet {
 		return isForked(common.TIPTomoXTestnet, num)
@@ -261,6 +524,10 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 		return GasTableHomestead
 	}
 	switch {
+	case c.IsBerlin(num):
+		return GasTableBerlin
+	case c.IsIstanbul(num):
+		return GasTableIstanbul
 	case c.IsEIP158(num):
 		return GasTableEIP158
 	case c.IsEIP150(num):
@@ -316,9 +583,102 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
+		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
+	}
+	if isForkIncompatible(c.BerlinBlock, newcfg.BerlinBlock, head) {
+		return newCompatError("Berlin fork block", c.BerlinBlock, newcfg.BerlinBlock)
+	}
+	if isForkIncompatible(c.TradeRootBlock, newcfg.TradeRootBlock, head) {
+		return newCompatError("Trade root fork block", c.TradeRootBlock, newcfg.TradeRootBlock)
+	}
+	if c.Posv != nil && newcfg.Posv != nil {
+		// The masternode limits apply from the first checkpoint on
+		checkpoint := new(big.Int).SetUint64(c.Posv.Epoch)
+		if isForked(checkpoint, head) && c.Posv.MasternodesLimit() != newcfg.Posv.MasternodesLimit() {
+			return newCompatError("POSV masternodes limit", checkpoint, checkpoint)
+		}
+		if isForked(checkpoint, head) && c.Posv.PenaltyEpochs() != newcfg.Posv.PenaltyEpochs() {
+			return newCompatError("POSV penalty epoch limit", checkpoint, checkpoint)
+		}
+		if isForkIncompatible(c.Posv.MasternodeDataBlock, newcfg.Posv.MasternodeDataBlock, head) {
+			return newCompatError("Masternode data fork block", c.Posv.MasternodeDataBlock, newcfg.Posv.MasternodeDataBlock)
+		}
+		if isForkIncompatible(c.Posv.TomoXPairHaltBlock, newcfg.Posv.TomoXPairHaltBlock, head) {
+			return newCompatError("TomoX pair halt fork block", c.Posv.TomoXPairHaltBlock, newcfg.Posv.TomoXPairHaltBlock)
+		}
+		if isForkIncompatible(c.Posv.TomoXPairSizeBlock, newcfg.Posv.TomoXPairSizeBlock, head) {
+			return newCompatError("TomoX pair size fork block", c.Posv.TomoXPairSizeBlock, newcfg.Posv.TomoXPairSizeBlock)
+		}
+		// The TRC21 fees apply from the genesis unless scheduled
+		if stored, fork := c.Posv.trc21FeeBlock(), newcfg.Posv.trc21FeeBlock(); isForkIncompatible(stored, fork, head) {
+			return newCompatError("TRC21 fee fork block", stored, fork)
+		}
+		if isForkIncompatible(c.Posv.BLSBlock, newcfg.Posv.BLSBlock, head) {
+			return newCompatError("BLS fork block", c.Posv.BLSBlock, newcfg.Posv.BLSBlock)
+		}
+		if isForkIncompatible(c.Posv.RelayerFeeBlock, newcfg.Posv.RelayerFeeBlock, head) {
+			return newCompatError("Relayer fee fork block", c.Posv.RelayerFeeBlock, newcfg.Posv.RelayerFeeBlock)
+		}
+		if isForkIncompatible(c.Posv.PriceOracleBlock, newcfg.Posv.PriceOracleBlock, head) {
+			return newCompatError("Price oracle fork block", c.Posv.PriceOracleBlock, newcfg.Posv.PriceOracleBlock)
+		}
+		if isForkIncompatible(c.Posv.LendingBlock, newcfg.Posv.LendingBlock, head) {
+			return newCompatError("Lending fork block", c.Posv.LendingBlock, newcfg.Posv.LendingBlock)
+		}
+	}
+	storedSplits, splits := c.rewardSplits(), newcfg.rewardSplits()
+	for i := 0; i < len(storedSplits) || i < len(splits); i++ {
+		var (
+			s1, s2 *big.Int
+			r1, r2 RewardSplit
+		)
+		if i < len(storedSplits) {
+			s1, r1 = storedSplits[i].Block, storedSplits[i]
+		}
+		if i < len(splits) {
+			s2, r2 = splits[i].Block, splits[i]
+		}
+		differ := r1.Masternode != r2.Masternode || r1.Voter != r2.Voter || r1.Foundation != r2.Foundation
+		if isForkIncompatible(s1, s2, head) || ((isForked(s1, head) || isForked(s2, head)) && differ) {
+			return newCompatError(fmt.Sprintf("Reward split %d block", i), s1, s2)
+		}
+	}
+	stored, forks := c.tomoxForks(), newcfg.tomoxForks()
+	for i := 0; i < len(stored) || i < len(forks); i++ {
+		var (
+			s1, s2 *big.Int
+			v1, v2 uint64
+		)
+		if i < len(stored) {
+			s1, v1 = stored[i].Block, stored[i].Version
+		}
+		if i < len(forks) {
+			s2, v2 = forks[i].Block, forks[i].Version
+		}
+		if isForkIncompatible(s1, s2, head) || ((isForked(s1, head) || isForked(s2, head)) && v1 != v2) {
+			return newCompatError(fmt.Sprintf("TomoX fork %d block", i), s1, s2)
+		}
+	}
 	return nil
 }
 
+// rewardSplits returns the scheduled splits of the checkpoint rewards.
+func (c *ChainConfig) rewardSplits() []RewardSplit {
+	if c.Posv == nil {
+		return nil
+	}
+	return c.Posv.RewardSplits
+}
+
+// tomoxForks returns the scheduled versions of the TomoX matching rules.
+func (c *ChainConfig) tomoxForks() []TomoXFork {
+	if c.Posv == nil {
+		return nil
+	}
+	return c.Posv.TomoXForks
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -382,7 +742,7 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainId                                   *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	IsByzantium, IsIstanbul, IsBerlin         bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -390,5 +750,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsIstanbul: c.IsIstanbul(num), IsBerlin: c.IsBerlin(num)}
 }
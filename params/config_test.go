@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +72,201 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{TomoXForks: []TomoXFork{{Block: big.NewInt(10), Version: 1}}}},
+			new:    &ChainConfig{Posv: &PosvConfig{TomoXForks: []TomoXFork{{Block: big.NewInt(10), Version: 2}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "TomoX fork 0 block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{Posv: &PosvConfig{TomoXForks: []TomoXFork{{Block: big.NewInt(10), Version: 1}}}},
+			new:     &ChainConfig{Posv: &PosvConfig{TomoXForks: []TomoXFork{{Block: big.NewInt(10), Version: 1}, {Block: big.NewInt(20), Version: 2}}}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{Posv: &PosvConfig{Epoch: 900, MaxMasternodes: 150}},
+			new:     &ChainConfig{Posv: &PosvConfig{Epoch: 900, MaxMasternodes: 18}},
+			head:    899,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, MaxMasternodes: 150}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, MaxMasternodes: 18}},
+			head:   900,
+			wantErr: &ConfigCompatError{
+				What:         "POSV masternodes limit",
+				StoredConfig: big.NewInt(900),
+				NewConfig:    big.NewInt(900),
+				RewindTo:     899,
+			},
+		},
+		{
+			stored:  &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			new:     &ChainConfig{Posv: &PosvConfig{Epoch: 900, MaxMasternodes: common.MaxMasternodes}},
+			head:    2000,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, LimitPenaltyEpoch: 2}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			head:   2000,
+			wantErr: &ConfigCompatError{
+				What:         "POSV penalty epoch limit",
+				StoredConfig: big.NewInt(900),
+				NewConfig:    big.NewInt(900),
+				RewindTo:     899,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 50, Voter: 40, Foundation: 10}}}},
+			new:    &ChainConfig{Posv: &PosvConfig{RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 40, Voter: 50, Foundation: 10}}}},
+			head:   9,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 50, Voter: 40, Foundation: 10}}}},
+			new:    &ChainConfig{Posv: &PosvConfig{RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 40, Voter: 50, Foundation: 10}}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "Reward split 0 block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 50, Voter: 40, Foundation: 10}}}},
+			new:    &ChainConfig{Posv: &PosvConfig{RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 50, Voter: 40, Foundation: 10}, {Block: big.NewInt(20), Masternode: 40, Voter: 50, Foundation: 10}}}},
+			head:   15,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, MasternodeDataBlock: big.NewInt(100)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, MasternodeDataBlock: big.NewInt(200)}},
+			head:   99,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, MasternodeDataBlock: big.NewInt(100)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, MasternodeDataBlock: big.NewInt(200)}},
+			head:   150,
+			wantErr: &ConfigCompatError{
+				What:         "Masternode data fork block",
+				StoredConfig: big.NewInt(100),
+				NewConfig:    big.NewInt(200),
+				RewindTo:     99,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairHaltBlock: big.NewInt(1000)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairHaltBlock: big.NewInt(2000)}},
+			head:   999,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairHaltBlock: big.NewInt(1000)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairHaltBlock: big.NewInt(2000)}},
+			head:   1500,
+			wantErr: &ConfigCompatError{
+				What:         "TomoX pair halt fork block",
+				StoredConfig: big.NewInt(1000),
+				NewConfig:    big.NewInt(2000),
+				RewindTo:     999,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairSizeBlock: big.NewInt(3000)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairSizeBlock: big.NewInt(2000)}},
+			head:   1999,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairSizeBlock: big.NewInt(3000)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, TomoXPairSizeBlock: big.NewInt(2000)}},
+			head:   2500,
+			wantErr: &ConfigCompatError{
+				What:         "TomoX pair size fork block",
+				StoredConfig: big.NewInt(3000),
+				NewConfig:    big.NewInt(2000),
+				RewindTo:     1999,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, BLSBlock: big.NewInt(4500)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, BLSBlock: big.NewInt(5400)}},
+			head:   4499,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, BLSBlock: big.NewInt(4500)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, BLSBlock: big.NewInt(5400)}},
+			head:   5000,
+			wantErr: &ConfigCompatError{
+				What:         "BLS fork block",
+				StoredConfig: big.NewInt(4500),
+				NewConfig:    big.NewInt(5400),
+				RewindTo:     4499,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, TRC21FeeBlock: big.NewInt(0)}},
+			head:   100,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, TRC21FeeBlock: big.NewInt(1000)}},
+			head:   100,
+			wantErr: &ConfigCompatError{
+				What:         "TRC21 fee fork block",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    big.NewInt(1000),
+				RewindTo:     0,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, RelayerFeeBlock: big.NewInt(1800)}},
+			head:   1799,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, RelayerFeeBlock: big.NewInt(1800)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, RelayerFeeBlock: big.NewInt(2700)}},
+			head:   2000,
+			wantErr: &ConfigCompatError{
+				What:         "Relayer fee fork block",
+				StoredConfig: big.NewInt(1800),
+				NewConfig:    big.NewInt(2700),
+				RewindTo:     1799,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, PriceOracleBlock: big.NewInt(1800)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			head:   2000,
+			wantErr: &ConfigCompatError{
+				What:         "Price oracle fork block",
+				StoredConfig: big.NewInt(1800),
+				NewConfig:    nil,
+				RewindTo:     1799,
+			},
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, LendingBlock: big.NewInt(1800)}},
+			head:   1799,
+		},
+		{
+			stored: &ChainConfig{Posv: &PosvConfig{Epoch: 900, LendingBlock: big.NewInt(1800)}},
+			new:    &ChainConfig{Posv: &PosvConfig{Epoch: 900, LendingBlock: big.NewInt(2700)}},
+			head:   2000,
+			wantErr: &ConfigCompatError{
+				What:         "Lending fork block",
+				StoredConfig: big.NewInt(1800),
+				NewConfig:    big.NewInt(2700),
+				RewindTo:     1799,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -79,3 +276,117 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestPosvConfigValidate(t *testing.T) {
+	tests := []struct {
+		config *PosvConfig
+		valid  bool
+	}{
+		{config: TomoMainnetChainConfig.Posv, valid: true},
+		{config: &PosvConfig{Period: 2, Epoch: 30, RewardCheckpoint: 30, Gap: 5, MaxMasternodes: 5}, valid: true},
+		{config: &PosvConfig{Period: 2, Epoch: 0}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, Gap: 30}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, RewardCheckpoint: 45}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, MaxMasternodes: -1}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 30, Voter: 60, Foundation: 10}}}, valid: true},
+		{config: &PosvConfig{Period: 2, Epoch: 30, RewardSplits: []RewardSplit{{Masternode: 30, Voter: 60, Foundation: 10}}}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, RewardSplits: []RewardSplit{{Block: big.NewInt(10), Masternode: 30, Voter: 60, Foundation: 20}}}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, RewardSplits: []RewardSplit{
+			{Block: big.NewInt(10), Masternode: 30, Voter: 60, Foundation: 10},
+			{Block: big.NewInt(10), Masternode: 40, Voter: 50, Foundation: 10},
+		}}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, TomoXForks: []TomoXFork{{Block: big.NewInt(10), Version: 1}, {Block: big.NewInt(20), Version: 2}}}, valid: true},
+		{config: &PosvConfig{Period: 2, Epoch: 30, TomoXForks: []TomoXFork{{Version: 1}}}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, TomoXForks: []TomoXFork{{Block: big.NewInt(20), Version: 1}, {Block: big.NewInt(10), Version: 2}}}, valid: false},
+		{config: &PosvConfig{Period: 2, Epoch: 30, TomoXForks: []TomoXFork{{Block: big.NewInt(10), Version: 2}, {Block: big.NewInt(20), Version: 1}}}, valid: false},
+	}
+	for i, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
+
+func TestPosvConfigDefaults(t *testing.T) {
+	if limit := TomoMainnetChainConfig.Posv.MasternodesLimit(); limit != common.MaxMasternodes {
+		t.Errorf("mainnet masternodes limit mismatch: have %d, want %d", limit, common.MaxMasternodes)
+	}
+	if epochs := TomoMainnetChainConfig.Posv.PenaltyEpochs(); epochs != common.LimitPenaltyEpoch {
+		t.Errorf("mainnet penalty epochs mismatch: have %d, want %d", epochs, common.LimitPenaltyEpoch)
+	}
+	config := &PosvConfig{Epoch: 30, MaxMasternodes: 5, LimitPenaltyEpoch: 2}
+	if limit := config.MasternodesLimit(); limit != 5 {
+		t.Errorf("custom masternodes limit mismatch: have %d, want 5", limit)
+	}
+	if epochs := config.PenaltyEpochs(); epochs != 2 {
+		t.Errorf("custom penalty epochs mismatch: have %d, want 2", epochs)
+	}
+}
+
+func TestPosvRewardSplitAt(t *testing.T) {
+	config := &PosvConfig{Epoch: 30, RewardSplits: []RewardSplit{
+		{Block: big.NewInt(100), Masternode: 30, Voter: 60, Foundation: 10},
+		{Block: big.NewInt(200), Masternode: 20, Voter: 70, Foundation: 10},
+	}}
+	tests := []struct {
+		number     int64
+		masternode uint64
+	}{
+		{0, common.RewardMasterPercent},
+		{99, common.RewardMasterPercent},
+		{100, 30},
+		{199, 30},
+		{200, 20},
+		{1000, 20},
+	}
+	for _, test := range tests {
+		if split := config.RewardSplitAt(big.NewInt(test.number)); split.Masternode != test.masternode {
+			t.Errorf("block %d: masternode share mismatch: have %d, want %d", test.number, split.Masternode, test.masternode)
+		}
+	}
+}
+
+func TestTomoXVersion(t *testing.T) {
+	config := &ChainConfig{Posv: &PosvConfig{Epoch: 30, TomoXForks: []TomoXFork{
+		{Block: big.NewInt(100), Version: 1},
+		{Block: big.NewInt(200), Version: 3},
+	}}}
+	tests := []struct {
+		number  int64
+		version uint64
+	}{
+		{0, 0},
+		{99, 0},
+		{100, 1},
+		{199, 1},
+		{200, 3},
+		{1000, 3},
+	}
+	for _, test := range tests {
+		if version := config.TomoXVersion(big.NewInt(test.number)); version != test.version {
+			t.Errorf("block %d: matching version mismatch: have %d, want %d", test.number, version, test.version)
+		}
+	}
+	if version := (&ChainConfig{}).TomoXVersion(big.NewInt(1000)); version != 0 {
+		t.Errorf("unscheduled matching version mismatch: have %d, want 0", version)
+	}
+}
+
+func TestSpecialTxApplyLending(t *testing.T) {
+	contract := SpecialTxs.Get("tomoxLending")
+	tests := []struct {
+		config *ChainConfig
+		number int64
+		apply  string
+	}{
+		{&ChainConfig{}, 1000, SpecialTxApplyEVM},
+		{&ChainConfig{Posv: &PosvConfig{Epoch: 900}}, 1000, SpecialTxApplyEVM},
+		{&ChainConfig{Posv: &PosvConfig{Epoch: 900, LendingBlock: big.NewInt(100)}}, 99, SpecialTxApplyEVM},
+		{&ChainConfig{Posv: &PosvConfig{Epoch: 900, LendingBlock: big.NewInt(100)}}, 100, SpecialTxApplyEmpty},
+	}
+	for i, test := range tests {
+		if apply := test.config.SpecialTxApply(contract, big.NewInt(test.number)); apply != test.apply {
+			t.Errorf("test %d: apply rule mismatch: have %s, want %s", i, apply, test.apply)
+		}
+	}
+}
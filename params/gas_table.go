@@ -72,4 +72,33 @@ var (
 
 		CreateBySuicide: 25000,
 	}
+
+	// GasTableIstanbul contain the gas re-prices of the
+	// trie-size-dependent opcodes (EIP-1884).
+	GasTableIstanbul = GasTable{
+		ExtcodeSize: 700,
+		ExtcodeCopy: 700,
+		Balance:     700,
+		SLoad:       800,
+		Calls:       700,
+		Suicide:     5000,
+		ExpByte:     50,
+
+		CreateBySuicide: 25000,
+	}
+
+	// GasTableBerlin contain the gas prices of the state accesses to
+	// warm accounts and slots, the cold accesses being charged on top of
+	// them (EIP-2929).
+	GasTableBerlin = GasTable{
+		ExtcodeSize: WarmStorageReadCostEIP2929,
+		ExtcodeCopy: WarmStorageReadCostEIP2929,
+		Balance:     WarmStorageReadCostEIP2929,
+		SLoad:       WarmStorageReadCostEIP2929,
+		Calls:       WarmStorageReadCostEIP2929,
+		Suicide:     5000,
+		ExpByte:     50,
+
+		CreateBySuicide: 25000,
+	}
 )
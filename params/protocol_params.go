@@ -62,6 +62,17 @@ const (
 	MemoryGas        uint64 = 3     // Times the address of the (highest referenced byte in memory + 1). NOTE: referencing happens on read, write and in instructions such as RETURN and CALL.
 	TxDataNonZeroGas uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 
+	TxDataNonZeroGasEIP2028 uint64 = 16 // Per byte of non zero data attached to a transaction after EIP 2028 (Istanbul)
+
+	SstoreSentryGasEIP2200      uint64 = 2300  // Minimum gas required to be present for an SSTORE call, not consumed
+	SstoreSetGasEIP2200         uint64 = 20000 // Once per SSTORE operation from clean zero to non-zero
+	SstoreResetGasEIP2200       uint64 = 5000  // Once per SSTORE operation from clean non-zero to something else
+	SstoreClearsScheduleEIP2200 uint64 = 15000 // Once per SSTORE operation for clearing an originally existing storage slot
+
+	ColdAccountAccessCostEIP2929 uint64 = 2600 // Cost of the first access to an account in a transaction
+	ColdSloadCostEIP2929         uint64 = 2100 // Cost of the first access to a storage slot in a transaction
+	WarmStorageReadCostEIP2929   uint64 = 100  // Cost of the later accesses to an account or a storage slot
+
 	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
 
 	// Precompiled contract gas prices
@@ -78,6 +89,25 @@ const (
 	Bn256ScalarMulGas       uint64 = 40000  // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 80000  // Per-point price for an elliptic curve pairing check
+
+	Bn256AddGasIstanbul             uint64 = 150   // Gas needed for an elliptic curve addition (EIP-1108)
+	Bn256ScalarMulGasIstanbul       uint64 = 6000  // Gas needed for an elliptic curve scalar multiplication (EIP-1108)
+	Bn256PairingBaseGasIstanbul     uint64 = 45000 // Base price for an elliptic curve pairing check (EIP-1108)
+	Bn256PairingPerPointGasIstanbul uint64 = 34000 // Per-point price for an elliptic curve pairing check (EIP-1108)
+
+	TomoXPriceOracleGas   uint64 = 400 // Gas needed to read the average price of a TomoX pair
+	MasternodeDataBaseGas uint64 = 400 // Base price for a masternode data query
+	MasternodeDataItemGas uint64 = 200 // Per-item price for a masternode data query returning a list
+
+	TomoXPairHaltedGas uint64 = 400   // Gas needed to read whether the trading of a TomoX pair is halted
+	TomoXPairHaltGas   uint64 = 20000 // Gas needed to halt or resume the trading of a TomoX pair
+
+	TomoXPairSizeGas    uint64 = 400   // Gas needed to read the tick and lot sizes of a TomoX pair
+	TomoXSetPairSizeGas uint64 = 40000 // Gas needed to set the tick and lot sizes of a TomoX pair
+
+	BLSRegistryReadGas   uint64 = 800    // Gas needed to read a BLS key or checkpoint signature of a masternode
+	BLSRegisterKeyGas    uint64 = 200000 // Gas needed to register a BLS key, checking its proof of possession
+	BLSSignCheckpointGas uint64 = 150000 // Gas needed to record a BLS checkpoint signature, checking it
 )
 
 var (
@@ -0,0 +1,111 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Rules applying the special transactions of a system contract.
+const (
+	SpecialTxApplyEVM   = "evm"   // Executed by the EVM like any transaction
+	SpecialTxApplySign  = "sign"  // Recorded as a block signature, outside of the EVM
+	SpecialTxApplyEmpty = "empty" // Carries data of its block, applied without execution
+)
+
+// Forks activating the apply rule of a system contract.
+const (
+	SpecialTxForkTIPSigning = "tipSigning"
+	SpecialTxForkTIPTomoX   = "tipTomoX"
+	SpecialTxForkLending    = "lending"
+)
+
+// SpecialTxContract describes a system contract receiving special transactions
+// from the masternodes.
+type SpecialTxContract struct {
+	Name      string         `json:"name"`
+	Address   common.Address `json:"address"`
+	Apply     string         `json:"apply"`          // How the transactions are applied, one of the SpecialTxApply rules
+	Fork      string         `json:"fork,omitempty"` // Fork activating the apply rule, SpecialTxApplyEVM is used before it
+	Free      bool           `json:"free"`           // Masternodes send the transactions without gas price and intrinsic gas
+	SkipNonce bool           `json:"skipNonce"`      // The transactions do not consume the nonce of their sender
+}
+
+// SpecialTxConfig lists the system contracts receiving special transactions.
+type SpecialTxConfig struct {
+	Contracts []*SpecialTxContract `json:"contracts"`
+}
+
+// SpecialTxs are the system contracts of TomoChain.
+var SpecialTxs = &SpecialTxConfig{
+	Contracts: []*SpecialTxContract{
+		{Name: "blockSigners", Address: common.HexToAddress(common.BlockSigners), Apply: SpecialTxApplySign, Fork: SpecialTxForkTIPSigning, Free: true},
+		{Name: "randomize", Address: common.HexToAddress(common.RandomizeSMC), Apply: SpecialTxApplyEVM, Free: true},
+		{Name: "tomoxMatching", Address: common.HexToAddress(common.TomoXAddr), Apply: SpecialTxApplyEmpty, Fork: SpecialTxForkTIPTomoX, SkipNonce: true},
+		{Name: "tomoxState", Address: common.HexToAddress(common.TomoXStateAddr), Apply: SpecialTxApplyEmpty, Fork: SpecialTxForkTIPTomoX, SkipNonce: true},
+		{Name: "tomoxLending", Address: common.HexToAddress(common.TomoXLendingAddr), Apply: SpecialTxApplyEmpty, Fork: SpecialTxForkLending, SkipNonce: true},
+		{Name: "blsRegistry", Address: common.HexToAddress(common.BLSRegistry), Apply: SpecialTxApplyEVM, Free: true},
+	},
+}
+
+// Lookup returns the system contract a transaction is sent to, nil if the
+// transaction is not a special one.
+func (c *SpecialTxConfig) Lookup(to *common.Address) *SpecialTxContract {
+	if c == nil || to == nil {
+		return nil
+	}
+	for _, contract := range c.Contracts {
+		if contract.Address == *to {
+			return contract
+		}
+	}
+	return nil
+}
+
+// Get returns a system contract by name, nil if it does not exist.
+func (c *SpecialTxConfig) Get(name string) *SpecialTxContract {
+	if c == nil {
+		return nil
+	}
+	for _, contract := range c.Contracts {
+		if contract.Name == name {
+			return contract
+		}
+	}
+	return nil
+}
+
+// SpecialTxApply returns how the transactions sent to a system contract are
+// applied in the block with the given number.
+func (c *ChainConfig) SpecialTxApply(contract *SpecialTxContract, num *big.Int) string {
+	switch contract.Fork {
+	case SpecialTxForkTIPSigning:
+		if !c.IsTIPSigning(num) {
+			return SpecialTxApplyEVM
+		}
+	case SpecialTxForkTIPTomoX:
+		if !c.IsTIPTomoX(num) {
+			return SpecialTxApplyEVM
+		}
+	case SpecialTxForkLending:
+		if !c.IsLending(num) {
+			return SpecialTxApplyEVM
+		}
+	}
+	return contract.Apply
+}
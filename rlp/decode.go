@@ -64,12 +64,16 @@ type Decoder interface {
 // error if there are too few or too many elements.
 //
 // The decoding of struct fields honours certain struct tags, "tail",
-// "nil" and "-".
+// "nil", "optional" and "-".
 //
 // The "-" tag ignores fields.
 //
 // For an explanation of "tail", see the example.
 //
+// The "optional" tag allows the input list to end before the field, which is
+// then left zero along with the following fields, all optional. It lets
+// fields be appended to a struct while still decoding its older encodings.
+//
 // The "nil" tag applies to pointer-typed fields and changes the decoding
 // rules for the field such that input values of size zero decode as a nil
 // pointer. This tag can be useful when decoding recursive types.
@@ -422,8 +426,15 @@ func makeStructDecoder(typ reflect.Type) (decoder, error) {
 		if _, err := s.List(); err != nil {
 			return wrapStreamError(err, typ)
 		}
-		for _, f := range fields {
+		for i, f := range fields {
 			err := f.info.decoder(s, val.Field(f.index))
+			if err == EOL && f.optional {
+				// The list ended, zero the remaining optional fields
+				for _, f := range fields[i:] {
+					val.Field(f.index).Set(reflect.Zero(val.Field(f.index).Type()))
+				}
+				break
+			}
 			if err == EOL {
 				return &decodeError{msg: "too few elements", typ: typ}
 			} else if err != nil {
@@ -62,7 +62,8 @@ type Encoder interface {
 // if the array has element type byte).
 //
 // Struct values are encoded as an RLP list of all their encoded
-// public fields. Recursive struct types are supported.
+// public fields. Recursive struct types are supported. Trailing fields with
+// the "optional" tag are left out of the list while they are zero.
 //
 // To encode slices and arrays, the elements are encoded as an RLP
 // list of the value's elements. Note that arrays and slices with
@@ -532,8 +533,13 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 		return nil, err
 	}
 	writer := func(val reflect.Value, w *encbuf) error {
+		// Trailing zero optional fields are omitted
+		n := len(fields)
+		for n > 0 && fields[n-1].optional && val.Field(fields[n-1].index).IsZero() {
+			n--
+		}
 		lh := w.list()
-		for _, f := range fields {
+		for _, f := range fields[:n] {
 			if err := f.info.writer(val.Field(f.index), w); err != nil {
 				return err
 			}
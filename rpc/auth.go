@@ -0,0 +1,103 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// DefaultAuthNamespaces are the namespaces requiring authentication when a
+// policy with a JWT secret doesn't list any.
+var DefaultAuthNamespaces = []string{"admin", "debug", "miner", "personal"}
+
+// jwtIssuanceWindow is how far the issuance time of the tokens without expiry
+// may be off the local clock.
+const jwtIssuanceWindow = time.Minute
+
+var errStaleToken = errors.New("token without expiry issued too far from now")
+
+// authKey is the context key marking the requests of authenticated clients.
+type authKey struct{}
+
+// requestToken returns the bearer token of an HTTP request, taken from its
+// Authorization header or, if allowed, from its token query parameter for the
+// websocket handshakes browsers can't set headers on.
+func requestToken(r *http.Request, query bool) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if query {
+		return r.URL.Query().Get("token")
+	}
+	return ""
+}
+
+// verifyToken checks that a JWT is signed with HS256 by the secret and valid
+// now. Tokens without expiry must have been issued within a minute.
+func verifyToken(secret []byte, token string, now time.Time) error {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}}
+	_, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := claims["exp"]; !ok {
+		iat, ok := claims["iat"].(float64)
+		if !ok {
+			return errStaleToken
+		}
+		if diff := now.Sub(time.Unix(int64(iat), 0)); diff > jwtIssuanceWindow || diff < -jwtIssuanceWindow {
+			return errStaleToken
+		}
+	}
+	return nil
+}
+
+// authenticate returns the context of the requests of an HTTP client, marked
+// as authenticated if it presents a valid token. Invalid tokens are reported
+// rather than ignored.
+func (p *serverPolicy) authenticate(ctx context.Context, r *http.Request, query bool) (context.Context, error) {
+	if p == nil || len(p.secret) == 0 {
+		return ctx, nil
+	}
+	token := requestToken(r, query)
+	if token == "" {
+		return ctx, nil
+	}
+	if err := verifyToken(p.secret, token, time.Now()); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, authKey{}, true), nil
+}
+
+// authorized reports whether the requests of a context may call the methods
+// of a namespace.
+func (p *serverPolicy) authorized(ctx context.Context, namespace string) bool {
+	if p == nil || !p.protected[namespace] {
+		return true
+	}
+	authed, _ := ctx.Value(authKey{}).(bool)
+	return authed
+}
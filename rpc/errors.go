@@ -64,3 +64,35 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when a client exceeds the request rate allowed by the server policy
+type limitExceededError struct{ message string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string { return e.message }
+
+// issued when a method runs longer than allowed by the server policy
+type timeoutError struct{ method string }
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string { return e.method + " execution timed out" }
+
+// issued when the responses to a batch grow larger than allowed by the server policy
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("batch responses exceed the limit of %d bytes", e.limit)
+}
+
+// issued when an unauthenticated client calls a namespace requiring authentication
+type unauthorizedError struct{ namespace string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("the %s namespace requires authentication", e.namespace)
+}
@@ -146,13 +146,36 @@ func (t *httpReadWriteNopCloser) Close() error {
 	return nil
 }
 
+// VirtualHost configures the requests made to an HTTP RPC server through a
+// virtual host, overriding the settings of the server.
+type VirtualHost struct {
+	Cors    []string `toml:",omitempty"` // Origins allowed cross origin requests, the server ones if empty
+	Methods []string `toml:",omitempty"` // Methods served as namespace_method or namespace_*, the server ones if empty
+}
+
 // NewHTTPServer creates a new HTTP RPC server around an API provider.
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
+	return NewHTTPServerWithHosts(cors, vhosts, nil, srv)
+}
+
+// NewHTTPServerWithHosts creates a new HTTP RPC server around an API provider,
+// serving the configured virtual hosts next to the whitelisted ones.
+func NewHTTPServerWithHosts(cors []string, vhosts []string, hosts map[string]VirtualHost, srv *Server) *http.Server {
 	// Wrap the CORS-handler within a host-handler
-	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
+	handler := newVHostHandler(vhosts, newCorsHandler(srv, cors))
+	for host, config := range hosts {
+		var next http.Handler = srv
+		if methods := newMethodSet(config.Methods); methods != nil {
+			next = &whitelistHandler{methods, srv}
+		}
+		origins := cors
+		if len(config.Cors) > 0 {
+			origins = config.Cors
+		}
+		handler.hosts[strings.ToLower(host)] = newCorsHandler(next, origins)
+	}
 	return &http.Server{
 		Handler:      handler,
 		ReadTimeout:  5 * time.Second,
@@ -171,6 +194,11 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), code)
 		return
 	}
+	ctx, err := srv.policy.authenticate(withRemote(context.Background(), r.RemoteAddr), r, false)
+	if err != nil {
+		http.Error(w, "invalid token: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
@@ -179,7 +207,10 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	if methods, ok := r.Context().Value(whitelistKey{}).(*methodSet); ok {
+		ctx = context.WithValue(ctx, whitelistKey{}, methods)
+	}
+	srv.serveRequest(ctx, codec, true, OptionMethodInvocation)
 }
 
 // validateRequest returns a non-zero response code and error message if the
@@ -200,7 +231,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -220,6 +251,7 @@ func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
 // which domain was used, and validate that against a whitelist.
 type virtualHostHandler struct {
 	vhosts map[string]struct{}
+	hosts  map[string]http.Handler // Handlers of the individually configured hosts
 	next   http.Handler
 }
 
@@ -235,6 +267,10 @@ func (h *virtualHostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Either invalid (too many colons) or no port specified
 		host = r.Host
 	}
+	if next, exist := h.hosts[strings.ToLower(host)]; exist {
+		next.ServeHTTP(w, r)
+		return
+	}
 	if ipAddr := net.ParseIP(host); ipAddr != nil {
 		// It's an IP address, we can serve that
 		h.next.ServeHTTP(w, r)
@@ -253,10 +289,21 @@ func (h *virtualHostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "invalid host specified", http.StatusForbidden)
 }
 
-func newVHostHandler(vhosts []string, next http.Handler) http.Handler {
+func newVHostHandler(vhosts []string, next http.Handler) *virtualHostHandler {
 	vhostMap := make(map[string]struct{})
 	for _, allowedHost := range vhosts {
 		vhostMap[strings.ToLower(allowedHost)] = struct{}{}
 	}
-	return &virtualHostHandler{vhostMap, next}
+	return &virtualHostHandler{vhostMap, make(map[string]http.Handler), next}
+}
+
+// whitelistHandler restricts the methods served to the requests of a virtual
+// host.
+type whitelistHandler struct {
+	methods *methodSet
+	next    http.Handler
+}
+
+func (h *whitelistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), whitelistKey{}, h.methods)))
 }
@@ -17,6 +17,7 @@
 package rpc
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -52,3 +53,51 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+// Tests that the virtual hosts configured individually are served with their
+// own CORS origins and methods.
+func TestHTTPVirtualHosts(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewHTTPServerWithHosts(nil, []string{"localhost"}, map[string]VirtualHost{
+		"Public.example": {Cors: []string{"https://dapp.example"}, Methods: []string{"test_echo"}},
+		"internal":       {},
+	}, server).Handler
+	call := func(host, method string) (int, *jsonErrResponse) {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":["x",1,{"S":"y"}]}`
+		request := httptest.NewRequest(http.MethodPost, "http://"+host+"/", strings.NewReader(body))
+		request.Header.Set("content-type", contentType)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		result := new(jsonErrResponse)
+		json.Unmarshal(recorder.Body.Bytes(), result)
+		return recorder.Code, result
+	}
+	if code, _ := call("unknown.example", "test_echo"); code != http.StatusForbidden {
+		t.Errorf("unknown host served: code %d", code)
+	}
+	if code, result := call("internal", "test_rets"); code != http.StatusOK || result.Error.Code != 0 {
+		t.Errorf("configured host not served: code %d, error %v", code, result.Error)
+	}
+	if _, result := call("public.example:8545", "test_echo"); result.Error.Code != 0 {
+		t.Errorf("whitelisted method of the host not served: %v", result.Error)
+	}
+	if _, result := call("public.example", "test_rets"); result.Error.Code != -32601 {
+		t.Errorf("method beyond the host whitelist served: %v", result.Error)
+	}
+	if _, result := call("localhost", "test_rets"); result.Error.Code != 0 {
+		t.Errorf("default host restricted: %v", result.Error)
+	}
+	// Preflight requests are answered with the origins of the host
+	request := httptest.NewRequest(http.MethodOptions, "http://public.example/", nil)
+	request.Header.Set("Origin", "https://dapp.example")
+	request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if origin := recorder.Header().Get("Access-Control-Allow-Origin"); origin != "https://dapp.example" {
+		t.Errorf("allowed origin mismatch: have %q", origin)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeoutMethods are the methods whose execution time is bounded by the
+// timeout of a policy when it doesn't list any.
+var DefaultTimeoutMethods = []string{"eth_call", "eth_estimateGas", "eth_getLogs"}
+
+// limiterSweepInterval is how often the rate limiters forget idle clients.
+const limiterSweepInterval = time.Minute
+
+// Policy restricts the requests a server serves to remote clients, for the
+// endpoints open to the public. The requests of local clients (in-process and
+// IPC) are not rate limited.
+type Policy struct {
+	Methods        []string           // Methods served, as namespace_method or namespace_*, all if empty
+	Denied         []string           // Methods refused, as namespace_method or namespace_*, overriding the whitelists
+	RateLimit      float64            // Requests per second served to a client IP, unlimited if zero
+	MethodLimits   map[string]float64 // Requests per second served to a client IP per method
+	Timeout        time.Duration      // Execution time allowed to the timed methods, unlimited if zero
+	TimeoutMethods []string           // Methods timed, DefaultTimeoutMethods if empty
+
+	BatchLimit        int // Maximum number of requests in a batch, unlimited if zero
+	BatchResponseSize int // Maximum size in bytes of the responses to a batch, unlimited if zero
+
+	JWTSecret      []byte   // Secret of the HS256 tokens authenticating clients, no authentication if empty
+	AuthNamespaces []string // Namespaces served to authenticated clients only, DefaultAuthNamespaces if empty
+}
+
+// serverPolicy is a policy compiled for the lookups of a server.
+type serverPolicy struct {
+	methods *methodSet // Methods served, all if nil
+	denied  *methodSet // Methods refused
+
+	limiter        *rateLimiter            // Requests per client
+	methodLimiters map[string]*rateLimiter // Requests per client per method
+
+	timeout time.Duration
+	timed   map[string]bool
+
+	batchLimit        int
+	batchResponseSize int
+
+	secret    []byte
+	protected map[string]bool // Namespaces requiring authentication
+}
+
+// SetPolicy restricts the requests served to the given policy, nil lifting
+// the restrictions. It must be called before the server serves requests.
+func (s *Server) SetPolicy(policy *Policy) {
+	if policy == nil {
+		s.policy = nil
+		return
+	}
+	p := &serverPolicy{
+		timeout:           policy.Timeout,
+		timed:             make(map[string]bool),
+		batchLimit:        policy.BatchLimit,
+		batchResponseSize: policy.BatchResponseSize,
+	}
+	p.methods, p.denied = newMethodSet(policy.Methods), newMethodSet(policy.Denied)
+	if policy.RateLimit > 0 {
+		p.limiter = newRateLimiter(policy.RateLimit)
+	}
+	if len(policy.MethodLimits) > 0 {
+		p.methodLimiters = make(map[string]*rateLimiter)
+		for method, limit := range policy.MethodLimits {
+			if limit > 0 {
+				p.methodLimiters[method] = newRateLimiter(limit)
+			}
+		}
+	}
+	timed := policy.TimeoutMethods
+	if len(timed) == 0 {
+		timed = DefaultTimeoutMethods
+	}
+	for _, method := range timed {
+		p.timed[method] = true
+	}
+	if len(policy.JWTSecret) > 0 {
+		p.secret, p.protected = policy.JWTSecret, make(map[string]bool)
+
+		protected := policy.AuthNamespaces
+		if len(protected) == 0 {
+			protected = DefaultAuthNamespaces
+		}
+		for _, namespace := range protected {
+			p.protected[namespace] = true
+		}
+	}
+	s.policy = p
+}
+
+// serves reports whether a method of a namespace is served to the requests of
+// a context, whitelisted by their virtual host if configured, or by the policy.
+func (p *serverPolicy) serves(ctx context.Context, namespace, method string) bool {
+	whitelist, _ := ctx.Value(whitelistKey{}).(*methodSet)
+	if whitelist == nil && p != nil {
+		whitelist = p.methods
+	}
+	if whitelist != nil && !whitelist.contains(namespace, method) {
+		return false
+	}
+	return p == nil || !p.denied.contains(namespace, method)
+}
+
+// limit checks whether the client of the context may send another request for
+// the method, returning the error to answer it with otherwise.
+func (p *serverPolicy) limit(ctx context.Context, method string) Error {
+	if p == nil {
+		return nil
+	}
+	ip, ok := ctx.Value(remoteKey{}).(string)
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if p.limiter != nil && !p.limiter.allow(ip, now) {
+		return &limitExceededError{"request rate limit exceeded"}
+	}
+	if limiter := p.methodLimiters[method]; limiter != nil && !limiter.allow(ip, now) {
+		return &limitExceededError{"request rate limit exceeded for " + method}
+	}
+	return nil
+}
+
+// timeoutOf returns the execution time allowed to a method, zero if unlimited.
+func (p *serverPolicy) timeoutOf(method string) time.Duration {
+	if p == nil || !p.timed[method] {
+		return 0
+	}
+	return p.timeout
+}
+
+// checkBatch returns the error to answer a batch of requests with if it holds
+// more than allowed.
+func (p *serverPolicy) checkBatch(size int) Error {
+	if p == nil || p.batchLimit == 0 || size <= p.batchLimit {
+		return nil
+	}
+	return &limitExceededError{fmt.Sprintf("batch of %d requests exceeds the limit of %d", size, p.batchLimit)}
+}
+
+// responseBudget returns the size in bytes of the responses allowed to a
+// batch, zero if unlimited.
+func (p *serverPolicy) responseBudget() int {
+	if p == nil {
+		return 0
+	}
+	return p.batchResponseSize
+}
+
+// methodSet is a set of methods and whole namespaces.
+type methodSet struct {
+	methods    map[string]bool
+	namespaces map[string]bool
+}
+
+// newMethodSet parses namespace_method and namespace_* entries, returning nil
+// if there are none.
+func newMethodSet(entries []string) *methodSet {
+	if len(entries) == 0 {
+		return nil
+	}
+	w := &methodSet{methods: make(map[string]bool), namespaces: make(map[string]bool)}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, serviceMethodSeparator+"*") {
+			w.namespaces[strings.TrimSuffix(entry, serviceMethodSeparator+"*")] = true
+		} else {
+			w.methods[entry] = true
+		}
+	}
+	return w
+}
+
+// contains reports whether a method of a namespace is in the set.
+func (w *methodSet) contains(namespace, method string) bool {
+	if w == nil {
+		return false
+	}
+	return w.namespaces[namespace] || w.methods[namespace+serviceMethodSeparator+method]
+}
+
+// whitelistKey is the context key of the methods served to the requests of a
+// virtual host.
+type whitelistKey struct{}
+
+// remoteKey is the context key of the IP address of the remote client.
+type remoteKey struct{}
+
+// withRemote returns a context carrying the IP address of a remote client.
+func withRemote(ctx context.Context, addr string) context.Context {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return context.WithValue(ctx, remoteKey{}, addr)
+}
+
+// rateLimiter is a token bucket per client, allowing bursts of a second of
+// requests.
+type rateLimiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+	lock    sync.Mutex
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{rate: rate, burst: burst, buckets: make(map[string]*bucket), swept: time.Now()}
+}
+
+// allow takes a token from the bucket of a client, reporting whether one was
+// left.
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// Forget the clients idle long enough for their buckets to be full
+	if now.Sub(l.swept) > limiterSweepInterval {
+		for key, b := range l.buckets {
+			if now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+	b := l.buckets[client]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	if b.tokens += now.Sub(b.last).Seconds() * l.rate; b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,245 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// newPolicyTestClient serves a test service over HTTP under the given policy.
+func newPolicyTestClient(t *testing.T, policy *Policy) (*Client, func()) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetPolicy(policy)
+	httpsrv := httptest.NewServer(server)
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() {
+		client.Close()
+		httpsrv.Close()
+		server.Stop()
+	}
+}
+
+func TestPolicyMethodWhitelist(t *testing.T) {
+	client, closer := newPolicyTestClient(t, &Policy{Methods: []string{"test_echo", "rpc_*"}})
+	defer closer()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("whitelisted method rejected: %v", err)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("whitelisted namespace rejected: %v", err)
+	}
+	err := client.Call(nil, "test_noArgsRets")
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("unlisted method served: %v", err)
+	}
+}
+
+func TestPolicyRateLimits(t *testing.T) {
+	client, closer := newPolicyTestClient(t, &Policy{
+		RateLimit:    3,
+		MethodLimits: map[string]float64{"test_rets": 0.1},
+	})
+	defer closer()
+
+	// One request per second of the method limit fits in the burst
+	if err := client.Call(nil, "test_rets"); err != nil {
+		t.Fatalf("first request rejected: %v", err)
+	}
+	if err := client.Call(nil, "test_rets"); err == nil || !strings.Contains(err.Error(), "test_rets") {
+		t.Fatalf("method rate limit not enforced: %v", err)
+	}
+	// The rejected request used a token of the client limit
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatalf("request within the client limit rejected: %v", err)
+	}
+	if err := client.Call(nil, "test_noArgsRets"); err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+		t.Fatalf("client rate limit not enforced: %v", err)
+	}
+}
+
+func TestPolicyTimeout(t *testing.T) {
+	client, closer := newPolicyTestClient(t, &Policy{Timeout: 50 * time.Millisecond, TimeoutMethods: []string{"test_sleep"}})
+	defer closer()
+
+	start := time.Now()
+	err := client.Call(nil, "test_sleep", 10*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("long running method not timed out: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("timed out request answered after %v", elapsed)
+	}
+	if err := client.Call(nil, "test_sleep", time.Millisecond); err != nil {
+		t.Fatalf("short method failed: %v", err)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	limiter := newRateLimiter(2)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if !limiter.allow("a", now) {
+			t.Fatalf("request %d of the burst rejected", i)
+		}
+	}
+	if limiter.allow("a", now) {
+		t.Fatal("request beyond the burst allowed")
+	}
+	if !limiter.allow("b", now) {
+		t.Fatal("request of another client rejected")
+	}
+	if !limiter.allow("a", now.Add(500*time.Millisecond)) {
+		t.Fatal("request after the refill rejected")
+	}
+	// Idle clients are forgotten
+	limiter.allow("a", now.Add(2*limiterSweepInterval))
+	if len(limiter.buckets) != 1 {
+		t.Fatalf("idle clients kept: %d", len(limiter.buckets))
+	}
+}
+
+func TestPolicyBatchLimit(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetPolicy(&Policy{BatchLimit: 2})
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	post := func(body string) *http.Response {
+		resp, err := http.Post(httpsrv.URL, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	// Batches within the limit are served
+	resp := post(`[{"jsonrpc":"2.0","id":1,"method":"test_rets"},{"jsonrpc":"2.0","id":2,"method":"test_rets"}]`)
+	var results []jsonSuccessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil || len(results) != 2 {
+		t.Fatalf("batch within the limit not served: %v", err)
+	}
+	resp.Body.Close()
+
+	// Larger batches are rejected as a whole
+	resp = post(`[{"jsonrpc":"2.0","id":1,"method":"test_rets"},{"jsonrpc":"2.0","id":2,"method":"test_rets"},{"jsonrpc":"2.0","id":3,"method":"test_rets"}]`)
+	var result jsonErrResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	resp.Body.Close()
+	if result.Error.Code != -32005 || !strings.Contains(result.Error.Message, "batch of 3 requests") {
+		t.Fatalf("batch limit error mismatch: %+v", result.Error)
+	}
+}
+
+func TestPolicyBatchResponseSize(t *testing.T) {
+	client, closer := newPolicyTestClient(t, &Policy{BatchResponseSize: 200})
+	defer closer()
+
+	batch := make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{strings.Repeat("x", 50), i, &Args{"y"}}, Result: new(Result)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	// The responses are served until they exceed the limit
+	if batch[0].Error != nil {
+		t.Fatalf("first response dropped: %v", batch[0].Error)
+	}
+	for i, elem := range batch[2:] {
+		if elem.Error == nil || !strings.Contains(elem.Error.Error(), "200 bytes") {
+			t.Errorf("response %d beyond the limit served: %v", i+2, elem.Error)
+		}
+	}
+}
+
+func TestPolicyAuthentication(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("admin", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	server.SetPolicy(&Policy{JWTSecret: secret})
+
+	sign := func(key []byte, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	call := func(token, method string) (int, *jsonErrResponse) {
+		request := httptest.NewRequest(http.MethodPost, "http://localhost/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`))
+		request.Header.Set("content-type", contentType)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+
+		result := new(jsonErrResponse)
+		json.Unmarshal(recorder.Body.Bytes(), result)
+		return recorder.Code, result
+	}
+	now := time.Now().Unix()
+	tests := []struct {
+		token  string
+		method string
+		code   int
+		rpcErr int
+	}{
+		{"", "test_rets", http.StatusOK, 0},
+		{"", "admin_rets", http.StatusOK, -32001},
+		{sign(secret, jwt.MapClaims{"iat": now}), "admin_rets", http.StatusOK, 0},
+		{sign(secret, jwt.MapClaims{"iat": now - 3600, "exp": now + 60}), "admin_rets", http.StatusOK, 0},
+		{sign(secret, jwt.MapClaims{"iat": now - 120}), "admin_rets", http.StatusUnauthorized, 0},
+		{sign(secret, jwt.MapClaims{"exp": now - 60}), "test_rets", http.StatusUnauthorized, 0},
+		{sign([]byte("another secret"), jwt.MapClaims{"iat": now}), "admin_rets", http.StatusUnauthorized, 0},
+	}
+	for i, tt := range tests {
+		code, result := call(tt.token, tt.method)
+		if code != tt.code {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, code, tt.code)
+			continue
+		}
+		if code == http.StatusOK && result.Error.Code != tt.rpcErr {
+			t.Errorf("test %d: error code mismatch: have %d, want %d", i, result.Error.Code, tt.rpcErr)
+		}
+	}
+}
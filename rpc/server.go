@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -125,7 +126,7 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -140,7 +141,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// if the codec supports notification include a notifier that callbacks can use
@@ -159,7 +160,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 
 	// test if the server is ordered to stop
 	for atomic.LoadInt32(&s.run) == 1 {
-		reqs, batch, err := s.readRequest(codec)
+		reqs, batch, err := s.readRequest(ctx, codec)
 		if err != nil {
 			// If a parsing error occurred, send an error
 			if err.Error() != "EOF" {
@@ -170,6 +171,16 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 			pend.Wait()
 			return nil
 		}
+		// reject the batches holding more requests than allowed as a whole
+		if batch {
+			if err := s.policy.checkBatch(len(reqs)); err != nil {
+				codec.Write(codec.CreateErrorResponse(nil, err))
+				if singleShot {
+					return nil
+				}
+				continue
+			}
+		}
 
 		// check if server is ordered to shutdown and return an error
 		// telling the client that his request failed.
@@ -215,14 +226,14 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(context.Background(), codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -258,6 +269,9 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.err != nil {
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
+	if err := s.policy.limit(ctx, req.method); err != nil {
+		return codec.CreateErrorResponse(&req.id, err), nil
+	}
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
@@ -299,6 +313,14 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// bound the execution time of the expensive methods, the context of the
+	// method being cancelled at the timeout
+	timeout := s.policy.timeoutOf(req.method)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -308,7 +330,15 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
-	reply := req.callb.method.Func.Call(arguments)
+	var reply []reflect.Value
+	if timeout > 0 {
+		var ok bool
+		if reply, ok = callWithTimeout(ctx, req.callb, arguments); !ok {
+			return codec.CreateErrorResponse(&req.id, &timeoutError{req.method}), nil
+		}
+	} else {
+		reply = req.callb.method.Func.Call(arguments)
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -316,6 +346,9 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			if rpcErr, ok := e.(Error); ok { // keep the code of structured errors
+				return codec.CreateErrorResponse(&req.id, rpcErr), nil
+			}
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
@@ -323,6 +356,37 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// callWithTimeout calls a method, giving up on it once its context is done.
+// Methods ignoring their context keep running in the background, their reply
+// being discarded.
+func callWithTimeout(ctx context.Context, callb *callback, arguments []reflect.Value) ([]reflect.Value, bool) {
+	done := make(chan []reflect.Value, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				log.Error(fmt.Sprintf("RPC method %s crashed: %v\n%s", callb.method.Name, err, buf))
+				close(done)
+			}
+		}()
+		done <- callb.method.Func.Call(arguments)
+	}()
+	select {
+	case reply, ok := <-done:
+		return reply, ok
+	case <-ctx.Done():
+		// Prefer a reply racing with the timeout
+		select {
+		case reply, ok := <-done:
+			return reply, ok
+		default:
+			return nil, false
+		}
+	}
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
@@ -349,7 +413,13 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
 	responses := make([]interface{}, len(requests))
 	var callbacks []func()
+	budget, size := s.policy.responseBudget(), 0
 	for i, req := range requests {
+		// once the responses grow too large, answer the remaining requests with errors
+		if budget > 0 && size > budget {
+			responses[i] = codec.CreateErrorResponse(&req.id, &responseTooLargeError{budget})
+			continue
+		}
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
 		} else {
@@ -358,6 +428,14 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 				callbacks = append(callbacks, callback)
 			}
 		}
+		if budget > 0 {
+			if blob, err := json.Marshal(responses[i]); err == nil {
+				size += len(blob)
+			}
+			if size > budget {
+				responses[i] = codec.CreateErrorResponse(&req.id, &responseTooLargeError{budget})
+			}
+		}
 	}
 
 	if err := codec.Write(responses); err != nil {
@@ -374,7 +452,7 @@ func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*s
 // readRequest requests the next (batch) request from the codec. It will return the collection
 // of requests, an indication if the request was a batch, the invalid request identifier and an
 // error when the request could not be read/parsed.
-func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error) {
+func (s *Server) readRequest(ctx context.Context, codec ServerCodec) ([]*serverRequest, bool, Error) {
 	reqs, batch, err := codec.ReadRequestHeaders()
 	if err != nil {
 		return nil, batch, err
@@ -403,14 +481,22 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 			continue
 		}
 
+		if !s.policy.authorized(ctx, r.service) { // namespace reserved to authenticated clients
+			requests[i] = &serverRequest{id: r.id, err: &unauthorizedError{r.service}}
+			continue
+		}
 		if svc, ok = s.services[r.service]; !ok { // rpc method isn't available
 			requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, r.method}}
 			continue
 		}
 
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
+			if !s.policy.serves(ctx, r.service, "subscribe") {
+				requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, "subscribe"}}
+				continue
+			}
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + subscribeMethodSuffix, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -426,8 +512,12 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 			continue
 		}
 
+		if !s.policy.serves(ctx, r.service, r.method) { // rpc method isn't whitelisted
+			requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, r.method}}
+			continue
+		}
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + serviceMethodSeparator + r.method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
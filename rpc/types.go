@@ -57,6 +57,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // Full method name, e.g. eth_call
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -74,6 +75,8 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	policy *serverPolicy
 }
 
 // rpcRequest represents a raw incoming RPC request
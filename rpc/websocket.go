@@ -68,7 +68,17 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+
+			// Clients with an invalid token are served as unauthenticated ones
+			ctx := withRemote(context.Background(), conn.Request().RemoteAddr)
+			if authed, err := srv.policy.authenticate(ctx, conn.Request(), true); err != nil {
+				log.Debug("Rejected websocket token", "addr", conn.Request().RemoteAddr, "err", err)
+			} else {
+				ctx = authed
+			}
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
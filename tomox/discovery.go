@@ -0,0 +1,97 @@
+package tomox
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/discv5"
+)
+
+const (
+	maxDiscoveredPeers  = 10               // Maximum TomoX nodes found through the discovery topic kept connected
+	discoveryFastPeriod = time.Second      // Time between two topic lookups until enough TomoX nodes are found
+	discoverySlowPeriod = 30 * time.Second // Time between two topic lookups once enough TomoX nodes are found
+)
+
+// discoveryTopic is the discovery v5 topic advertised by the nodes running the
+// TomoX service.
+var discoveryTopic = discv5.Topic("TOMOX")
+
+// startDiscovery advertises the TomoX topic and connects to the other TomoX
+// nodes found for it, which are then preferred to exchange order data.
+func (tomox *TomoX) startDiscovery(server *p2p.Server) {
+	if server.DiscV5 == nil {
+		log.Warn("TomoX discovery requires the discovery v5 protocol (--v5disc)")
+		return
+	}
+	go func() {
+		log.Info("Starting TomoX topic registration")
+		defer log.Info("Terminated TomoX topic registration")
+
+		server.DiscV5.RegisterTopic(discoveryTopic, tomox.quit)
+	}()
+
+	var (
+		setPeriod = make(chan time.Duration, 1)
+		found     = make(chan *discv5.Node, 100)
+		lookups   = make(chan bool, 100)
+	)
+	setPeriod <- discoveryFastPeriod
+	go server.DiscV5.SearchTopic(discoveryTopic, setPeriod, found, lookups)
+
+	go func() {
+		defer close(setPeriod)
+		fast := true
+		for {
+			select {
+			case node := <-found:
+				id := discover.NodeID(node.ID)
+				if id == server.Self().ID || !tomox.addDiscoveredPeer(id) {
+					continue
+				}
+				log.Debug("Found TomoX node", "id", id, "addr", node.IP)
+				server.AddPeer(discover.NewNode(id, node.IP, node.UDP, node.TCP))
+
+				if fast && tomox.discoveredPeers() >= maxDiscoveredPeers {
+					fast = false
+					setPeriod <- discoverySlowPeriod
+				}
+			case <-lookups:
+			case <-tomox.quit:
+				return
+			}
+		}
+	}()
+}
+
+// addDiscoveredPeer records a TomoX node found through the discovery topic,
+// returning false if it was already known or enough nodes were found.
+func (tomox *TomoX) addDiscoveredPeer(id discover.NodeID) bool {
+	tomox.discoveredLock.Lock()
+	defer tomox.discoveredLock.Unlock()
+
+	if _, ok := tomox.discovered[id]; ok || len(tomox.discovered) >= maxDiscoveredPeers {
+		return false
+	}
+	tomox.discovered[id] = struct{}{}
+	return true
+}
+
+func (tomox *TomoX) discoveredPeers() int {
+	tomox.discoveredLock.RLock()
+	defer tomox.discoveredLock.RUnlock()
+
+	return len(tomox.discovered)
+}
+
+// IsDiscoveredPeer reports whether a node was found advertising the TomoX
+// discovery topic.
+func (tomox *TomoX) IsDiscoveredPeer(id discover.NodeID) bool {
+	tomox.discoveredLock.RLock()
+	defer tomox.discoveredLock.RUnlock()
+
+	_, ok := tomox.discovered[id]
+	return ok
+}
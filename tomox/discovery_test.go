@@ -0,0 +1,26 @@
+package tomox
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+func TestDiscoveredPeers(t *testing.T) {
+	tomox := &TomoX{discovered: make(map[discover.NodeID]struct{})}
+
+	for i := 0; i < maxDiscoveredPeers; i++ {
+		if !tomox.addDiscoveredPeer(discover.NodeID{byte(i)}) {
+			t.Fatalf("node %d not added", i)
+		}
+	}
+	if tomox.addDiscoveredPeer(discover.NodeID{0}) {
+		t.Errorf("known node added again")
+	}
+	if tomox.addDiscoveredPeer(discover.NodeID{0xff}) {
+		t.Errorf("node added over the limit")
+	}
+	if !tomox.IsDiscoveredPeer(discover.NodeID{1}) || tomox.IsDiscoveredPeer(discover.NodeID{0xff}) {
+		t.Errorf("discovered nodes mismatch")
+	}
+}
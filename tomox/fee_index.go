@@ -0,0 +1,157 @@
+package tomox
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// feeIndexPrefix prefixes the keys of the fee index, which records the trading
+// fees earned and the matching fees paid by the relayers in each epoch.
+var feeIndexPrefix = []byte("fee-index-")
+
+func feeIndexKey(relayer common.Address, epoch uint64) []byte {
+	key := append(append([]byte{}, feeIndexPrefix...), relayer.Bytes()...)
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, epoch)
+	return append(key, enc...)
+}
+
+// TokenFees are the trading fees a relayer earned in a token.
+type TokenFees struct {
+	Token    common.Address `json:"token"`
+	TakerFee *big.Int       `json:"takerFee"` // Fees paid by the takers of the relayer
+	MakerFee *big.Int       `json:"makerFee"` // Fees paid by the makers of the relayer
+	Trades   uint64         `json:"trades"`   // Trades of the relayer orders, counted once per side
+}
+
+// RelayerFees are the fees of a relayer summed over an epoch.
+type RelayerFees struct {
+	Relayer     common.Address `json:"relayer"`
+	Epoch       uint64         `json:"epoch"`
+	Blocks      uint64         `json:"blocks"`      // Blocks with trades of the relayer
+	MatchingFee *big.Int       `json:"matchingFee"` // Matching fees paid to the masternodes, in TOMO
+	Fees        []*TokenFees   `json:"fees"`        // Trading fees earned, by fee token
+}
+
+// feeIndexEntry are the fees of a relayer in a block.
+type feeIndexEntry struct {
+	Number      uint64
+	Hash        common.Hash
+	MatchingFee *big.Int
+	Fees        []*TokenFees
+}
+
+// addTokenFees adds the trading fees earned in a token to a list of fees.
+func addTokenFees(fees []*TokenFees, token common.Address, takerFee, makerFee *big.Int, trades uint64) []*TokenFees {
+	for _, total := range fees {
+		if total.Token == token {
+			total.TakerFee.Add(total.TakerFee, takerFee)
+			total.MakerFee.Add(total.MakerFee, makerFee)
+			total.Trades += trades
+			return fees
+		}
+	}
+	return append(fees, &TokenFees{
+		Token:    token,
+		TakerFee: new(big.Int).Set(takerFee),
+		MakerFee: new(big.Int).Set(makerFee),
+		Trades:   trades,
+	})
+}
+
+// IndexTradingFees adds the fees of the trades of a block to the fee index of
+// their relayers, under the given epoch. The fees are recorded per block, so
+// that indexing a block again replaces its fees and the blocks dropped by a
+// reorg can be told apart when summing them.
+func (tomox *TomoX) IndexTradingFees(block *types.Block, epoch uint64) {
+	entries := make(map[common.Address]*feeIndexEntry)
+	entry := func(relayer common.Address) *feeIndexEntry {
+		if entries[relayer] == nil {
+			entries[relayer] = &feeIndexEntry{Number: block.NumberU64(), Hash: block.Hash(), MatchingFee: new(big.Int)}
+		}
+		return entries[relayer]
+	}
+	for _, tx := range block.Transactions() {
+		if !tx.IsMatchingTransaction() {
+			continue
+		}
+		batch, err := DecodeTxMatchesBatch(tx.Data())
+		if err != nil {
+			continue
+		}
+		for _, txMatch := range batch.Data {
+			order, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			for _, trade := range txMatch.GetTrades() {
+				token := common.HexToAddress(trade[TradeQuoteToken])
+				// Trades of older blocks carry no fee, they are counted only
+				taker := entry(order.ExchangeAddress)
+				taker.Fees = addTokenFees(taker.Fees, token, ToBigInt(trade[TradeTakerFee]), Zero(), 1)
+				taker.MatchingFee.Add(taker.MatchingFee, common.RelayerFee)
+
+				maker := entry(common.HexToAddress(trade[TradeMakerExchange]))
+				maker.Fees = addTokenFees(maker.Fees, token, Zero(), ToBigInt(trade[TradeMakerFee]), 1)
+				maker.MatchingFee.Add(maker.MatchingFee, common.RelayerFee)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	tomox.feeIndexLock.Lock()
+	defer tomox.feeIndexLock.Unlock()
+
+	batch := tomox.db.NewBatch()
+	for relayer, added := range entries {
+		indexed := tomox.feeIndexEntries(relayer, epoch)
+		for i, entry := range indexed {
+			if entry.Hash == added.Hash {
+				indexed = append(indexed[:i], indexed[i+1:]...)
+				break
+			}
+		}
+		enc, _ := rlp.EncodeToBytes(append(indexed, added))
+		batch.Put(feeIndexKey(relayer, epoch), enc)
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write fee index", "block", block.Number(), "err", err)
+	}
+}
+
+// feeIndexEntries returns the fees of a relayer indexed in an epoch, by block.
+func (tomox *TomoX) feeIndexEntries(relayer common.Address, epoch uint64) []*feeIndexEntry {
+	enc, err := tomox.db.Get(feeIndexKey(relayer, epoch))
+	if err != nil || len(enc) == 0 {
+		return nil
+	}
+	var entries []*feeIndexEntry
+	if err := rlp.DecodeBytes(enc, &entries); err != nil {
+		log.Error("Failed to decode fee index", "relayer", relayer, "epoch", epoch, "err", err)
+		return nil
+	}
+	return entries
+}
+
+// TradingFees sums the fees of a relayer indexed in an epoch by
+// IndexTradingFees, over the blocks for which canonical returns true.
+func (tomox *TomoX) TradingFees(relayer common.Address, epoch uint64, canonical func(number uint64, hash common.Hash) bool) *RelayerFees {
+	sum := &RelayerFees{Relayer: relayer, Epoch: epoch, MatchingFee: new(big.Int), Fees: []*TokenFees{}}
+	for _, entry := range tomox.feeIndexEntries(relayer, epoch) {
+		if !canonical(entry.Number, entry.Hash) {
+			continue
+		}
+		sum.Blocks++
+		sum.MatchingFee.Add(sum.MatchingFee, entry.MatchingFee)
+		for _, fees := range entry.Fees {
+			sum.Fees = addTokenFees(sum.Fees, fees.Token, fees.TakerFee, fees.MakerFee, fees.Trades)
+		}
+	}
+	return sum
+}
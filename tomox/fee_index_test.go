@@ -0,0 +1,70 @@
+package tomox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestTradingFees(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-fees-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+	tomox := New(&Config{DataDir: datadir})
+	defer tomox.db.Close()
+
+	var (
+		takerRelayer, makerRelayer = common.Address{0x01}, common.Address{0x02}
+		tomo, btc                  = common.HexToAddress(common.TomoNativeAddress), common.Address{0xb7}
+	)
+	sig := &tomox_state.Signature{V: 27, R: common.Hash{0x01}, S: common.Hash{0x02}}
+	order := &tomox_state.OrderItem{ExchangeAddress: takerRelayer, BaseToken: btc, QuoteToken: tomo, Quantity: big.NewInt(2), Price: big.NewInt(10), Side: tomox_state.Bid, Signature: sig}
+	enc, _ := EncodeBytesItem(order)
+	trade := func(makerRelayer common.Address, takerFee, makerFee string) map[string]string {
+		return map[string]string{
+			TradeMakerExchange: makerRelayer.Hex(),
+			TradeQuoteToken:    tomo.Hex(),
+			TradeTakerFee:      takerFee,
+			TradeMakerFee:      makerFee,
+		}
+	}
+	newBlock := func(number int64, extra byte, trades ...map[string]string) *types.Block {
+		data, _ := EncodeTxMatchesBatch(TxMatchBatch{Data: []TxDataMatch{{Order: enc, Trades: trades}}})
+		tx := types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, data)
+		return types.NewBlock(&types.Header{Number: big.NewInt(number), Extra: []byte{extra}}, []*types.Transaction{tx}, nil, nil)
+	}
+	first := newBlock(1, 0, trade(makerRelayer, "10", "5"), trade(takerRelayer, "20", "8"))
+	second := newBlock(2, 0, trade(makerRelayer, "1", "2"))
+	side := newBlock(2, 1, trade(makerRelayer, "100", "100"))
+	for _, block := range []*types.Block{first, first, second, side} {
+		tomox.IndexTradingFees(block, 0)
+	}
+	canonical := func(number uint64, hash common.Hash) bool {
+		return hash == first.Hash() || hash == second.Hash()
+	}
+	// Indexing a block again doesn't count it twice, side blocks are skipped
+	fees := tomox.TradingFees(takerRelayer, 0, canonical)
+	if fees.Blocks != 2 || len(fees.Fees) != 1 {
+		t.Fatalf("taker relayer fees mismatch: %+v", fees)
+	}
+	if total := fees.Fees[0]; total.TakerFee.Int64() != 31 || total.MakerFee.Int64() != 8 || total.Trades != 4 {
+		t.Errorf("taker relayer token fees mismatch: have %v/%v in %d trades, want 31/8 in 4", total.TakerFee, total.MakerFee, total.Trades)
+	}
+	if want := new(big.Int).Mul(common.RelayerFee, big.NewInt(4)); fees.MatchingFee.Cmp(want) != 0 {
+		t.Errorf("taker relayer matching fee mismatch: have %v, want %v", fees.MatchingFee, want)
+	}
+	fees = tomox.TradingFees(makerRelayer, 0, canonical)
+	if total := fees.Fees[0]; total.TakerFee.Sign() != 0 || total.MakerFee.Int64() != 7 || total.Trades != 2 {
+		t.Errorf("maker relayer token fees mismatch: have %v/%v in %d trades, want 0/7 in 2", total.TakerFee, total.MakerFee, total.Trades)
+	}
+	if fees := tomox.TradingFees(makerRelayer, 1, canonical); fees.Blocks != 0 || len(fees.Fees) != 0 {
+		t.Errorf("fees indexed in another epoch: %+v", fees)
+	}
+}
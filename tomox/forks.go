@@ -0,0 +1,55 @@
+package tomox
+
+import (
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// Versions of the matching rules, activated by the TomoX forks scheduled in the
+// chain configuration. A change of the rules altering the outcome of a matching
+// comes with a new version, so that the nodes switch to it at the same block.
+const (
+	MatchingVersionGenesis   uint64 = iota // Limit and market orders, cancellations
+	MatchingVersionLifecycle               // Amendments of the resting orders, expiry blocks
+	MatchingVersionSelfTrade               // Self-trade prevention chosen by the takers
+
+	LatestMatchingVersion = MatchingVersionSelfTrade
+)
+
+// MatchingRules are the features of a version of the matching rules.
+type MatchingRules struct {
+	Version               uint64
+	IsAmendment           bool // Resting limit orders can be amended
+	IsExpiry              bool // Orders can expire at a block, pruned from the order books then
+	IsSelfTradePrevention bool // Takers can prevent matching their own resting orders
+}
+
+// NewMatchingRules returns the matching rules of a version, the versions after
+// the latest known one having its rules.
+func NewMatchingRules(version uint64) MatchingRules {
+	return MatchingRules{
+		Version:               version,
+		IsAmendment:           version >= MatchingVersionLifecycle,
+		IsExpiry:              version >= MatchingVersionLifecycle,
+		IsSelfTradePrevention: version >= MatchingVersionSelfTrade,
+	}
+}
+
+// StateMatchingRules returns the matching rules the orders are applied with on
+// top of a tomox state.
+func StateMatchingRules(tomoxStatedb *tomox_state.TomoXStateDB) MatchingRules {
+	return NewMatchingRules(tomoxStatedb.MatchingVersion())
+}
+
+// Supports reports whether an order can be applied under the rules.
+func (rules MatchingRules) Supports(order *tomox_state.OrderItem) bool {
+	if order.Status == OrderStatusAmended && !rules.IsAmendment {
+		return false
+	}
+	if order.ExpiryBlock != 0 && !rules.IsExpiry {
+		return false
+	}
+	if order.SelfTradePrevention != "" && !rules.IsSelfTradePrevention {
+		return false
+	}
+	return true
+}
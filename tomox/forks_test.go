@@ -0,0 +1,95 @@
+package tomox
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestApplyOrderMatchingRules(t *testing.T) {
+	var (
+		user  = common.HexToAddress("0x0a")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+
+	tomox := &TomoX{}
+	orderBook := GetOrderBookHash(base, quote)
+	newOrder := func(status string, id, expiry uint64, mode string) *tomox_state.OrderItem {
+		nonce := tomoxState.GetNonce(user.Hash())
+		return &tomox_state.OrderItem{
+			UserAddress:         user,
+			BaseToken:           base,
+			QuoteToken:          quote,
+			Status:              status,
+			Side:                Ask,
+			Type:                Limit,
+			Price:               big.NewInt(100),
+			Quantity:            big.NewInt(10),
+			Nonce:               new(big.Int).SetUint64(nonce),
+			Hash:                common.Hash{0x01},
+			OrderID:             id,
+			ExpiryBlock:         expiry,
+			SelfTradePrevention: mode,
+		}
+	}
+	if _, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusNew, 0, 0, "")); err != nil || len(rejects) != 0 {
+		t.Fatalf("failed to apply order: %v, %d rejects", err, len(rejects))
+	}
+	// Amendments, expiries and self-trade prevention are rejected until the
+	// forks supporting them
+	root := tomoxState.IntermediateRoot()
+	for _, test := range []struct {
+		status     string
+		id, expiry uint64
+		mode       string
+	}{
+		{OrderStatusAmended, 1, 0, ""},
+		{OrderStatusNew, 0, 100, ""},
+		{OrderStatusNew, 0, 0, tomox_state.CancelNewest},
+	} {
+		order := newOrder(test.status, test.id, test.expiry, test.mode)
+		_, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, order)
+		if err != nil {
+			t.Fatalf("failed to apply order: %v", err)
+		}
+		if len(rejects) != 1 || rejects[0].RejectReason != RejectUnsupported {
+			t.Errorf("order %s expiring at %d preventing %q not rejected: %d rejects", order.Status, order.ExpiryBlock, order.SelfTradePrevention, len(rejects))
+		}
+	}
+	if tomoxState.IntermediateRoot() == root {
+		t.Errorf("rejections didn't consume the user nonces")
+	}
+	// The state is left untouched until the first fork
+	root = tomoxState.IntermediateRoot()
+	if tomox_state.UpgradeMatchingVersion(MatchingVersionGenesis, tomoxState) || tomoxState.IntermediateRoot() != root {
+		t.Errorf("genesis matching rules upgraded")
+	}
+	if !tomox_state.UpgradeMatchingVersion(MatchingVersionLifecycle, tomoxState) {
+		t.Fatalf("lifecycle matching rules not upgraded")
+	}
+	if tomox_state.UpgradeMatchingVersion(MatchingVersionGenesis, tomoxState) || tomoxState.MatchingVersion() != MatchingVersionLifecycle {
+		t.Errorf("matching rules downgraded to version %d", tomoxState.MatchingVersion())
+	}
+	if _, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusAmended, 1, 0, "")); err != nil || len(rejects) != 0 {
+		t.Errorf("failed to amend order after the fork: %v, %d rejects", err, len(rejects))
+	}
+	if ask, volume := tomoxState.GetBestAskPrice(orderBook); ask.Int64() != 100 || volume.Int64() != 10 {
+		t.Errorf("best ask mismatch: have %v/%v, want 100/10", ask, volume)
+	}
+	// Self-trade prevention waits for its own fork
+	if _, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusNew, 0, 0, tomox_state.CancelNewest)); err != nil || len(rejects) != 1 {
+		t.Errorf("self-trade prevention accepted before its fork: %v, %d rejects", err, len(rejects))
+	}
+	tomox_state.UpgradeMatchingVersion(MatchingVersionSelfTrade, tomoxState)
+	if _, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusNew, 0, 0, tomox_state.CancelNewest)); err != nil || len(rejects) != 0 {
+		t.Errorf("failed to apply order preventing self trades after the fork: %v, %d rejects", err, len(rejects))
+	}
+}
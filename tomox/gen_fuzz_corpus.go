@@ -0,0 +1,138 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build none
+
+// This program generates the seed corpora of the order fuzzers. The orders of
+// the order corpus seed the fuzzers of core/types and core, the lists of orders
+// of the matching corpus the fuzzer of tomox:
+//
+//	go run gen_fuzz_corpus.go -out /tmp/corpus
+//	go-fuzz-build -o tomox-fuzz.zip github.com/ethereum/go-ethereum/tomox
+//	go-fuzz -bin tomox-fuzz.zip -workdir /tmp/corpus/matching
+//
+// The order pool fuzzer lists the relayer and the pair traded here.
+package main
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	relayer    = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+	baseToken  = common.HexToAddress("0x4d7eA2cE949216D6b120f3AA10164173615A2b6C")
+	quoteToken = common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	makerKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	takerKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+)
+
+// orderSigner signs the successive orders of a user, with prices and quantities
+// in whole tokens of 18 decimals.
+type orderSigner struct {
+	key   *ecdsa.PrivateKey
+	nonce uint64
+}
+
+func (s *orderSigner) order(side, orderType string, price, quantity int64, mode string) *types.OrderTransaction {
+	user := crypto.PubkeyToAddress(s.key.PublicKey)
+	tx := types.NewOrderTransaction(s.nonce, new(big.Int).Mul(big.NewInt(quantity), common.BasePrice), new(big.Int).Mul(big.NewInt(price), common.BasePrice), relayer, user, baseToken, quoteToken, "NEW", side, orderType, "BTC/TOMO", common.Hash{}, 0)
+	tx.SetSelfTradePrevention(mode)
+	tx.SetOrderHash(types.OrderTxSigner{}.Hash(tx))
+	return s.sign(tx)
+}
+
+func (s *orderSigner) cancel(order *types.OrderTransaction, orderID uint64) *types.OrderTransaction {
+	user := crypto.PubkeyToAddress(s.key.PublicKey)
+	tx := types.NewOrderTransaction(s.nonce, order.Quantity(), order.Price(), relayer, user, baseToken, quoteToken, "CANCELLED", order.Side(), order.Type(), "BTC/TOMO", order.OrderHash(), orderID)
+	return s.sign(tx)
+}
+
+func (s *orderSigner) sign(tx *types.OrderTransaction) *types.OrderTransaction {
+	signed, err := types.OrderSignTx(tx, types.OrderTxSigner{}, s.key)
+	if err != nil {
+		panic(err)
+	}
+	s.nonce++
+	return signed
+}
+
+// write stores an RLP encoded corpus entry, named after its hash.
+func write(dir string, val interface{}) {
+	enc, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%x", crypto.Keccak256(enc)[:8])), enc, 0644); err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	out := flag.String("out", "corpus", "directory to write the corpora to")
+	flag.Parse()
+
+	orderDir, matchingDir := filepath.Join(*out, "order", "corpus"), filepath.Join(*out, "matching", "corpus")
+	for _, dir := range []string{orderDir, matchingDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			panic(err)
+		}
+	}
+	maker, taker := &orderSigner{key: makerKey}, &orderSigner{key: takerKey}
+
+	// Crossing limit orders, partially filled
+	ask := maker.order("SELL", "LO", 100, 10, "")
+	bid := taker.order("BUY", "LO", 110, 4, "")
+	write(matchingDir, []*types.OrderTransaction{ask, bid})
+
+	// A book of resting orders swept by market orders
+	book := []*types.OrderTransaction{
+		maker.order("SELL", "LO", 120, 5, ""),
+		maker.order("SELL", "LO", 130, 5, ""),
+		maker.order("BUY", "LO", 90, 5, ""),
+		maker.order("BUY", "LO", 80, 5, ""),
+	}
+	sweep := append(book, taker.order("BUY", "MO", 1, 8, ""), taker.order("SELL", "MO", 1, 8, ""))
+	write(matchingDir, sweep)
+
+	// Cancellation of a resting order
+	resting := maker.order("SELL", "LO", 150, 3, "")
+	cancel := maker.cancel(resting, 1)
+	write(matchingDir, []*types.OrderTransaction{resting, cancel})
+
+	// Self trades under each of the prevention modes
+	selfTrades := []*types.OrderTransaction{}
+	for _, mode := range []string{"", types.SelfTradePreventionCancelNewest, types.SelfTradePreventionCancelOldest, types.SelfTradePreventionCancelBoth} {
+		selfTrades = append(selfTrades, taker.order("SELL", "LO", 200, 2, ""), taker.order("BUY", "LO", 200, 2, mode))
+	}
+	write(matchingDir, selfTrades)
+
+	for _, orders := range [][]*types.OrderTransaction{{ask, bid, resting, cancel}, sweep, selfTrades} {
+		for _, order := range orders {
+			write(orderDir, order)
+		}
+	}
+}
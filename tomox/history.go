@@ -0,0 +1,549 @@
+package tomox
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// Order history protocol, by which a new MongoDB backed node snapshots the
+// trade and order documents of the recent blocks from archive nodes instead of
+// replaying the blocks.
+const (
+	historyProtocolName    = "tomoxh"
+	historyProtocolVersion = 1
+	historyProtocolLength  = 3
+	historyMaxMsgSize      = 10 * 1024 * 1024 // Maximum size of a protocol message
+
+	historyStatusMsg   = 0x00
+	getOrderHistoryMsg = 0x01
+	orderHistoryMsg    = 0x02
+)
+
+const (
+	historyBatch   = 64               // Maximum number of blocks requested or served at once
+	historyTimeout = 30 * time.Second // Time allowance of an archive node to answer a request
+	historyRetry   = 10 * time.Second // Delay before requesting again when no archive node could answer
+)
+
+// historyHeadKey is the key of the next block whose order history is to be
+// snapshotted.
+var historyHeadKey = []byte("tomox-history-head")
+
+var (
+	errHistoryBlock   = errors.New("order history of another block")
+	errHistoryTrades  = errors.New("trades not matching the trade index")
+	errHistoryOrders  = errors.New("orders not matched in the block")
+	errHistoryTimeout = errors.New("order history request timed out")
+)
+
+// HistoryChain is the part of the blockchain the order history is served and
+// verified from.
+type HistoryChain interface {
+	CurrentHeader() *types.Header
+	GetBlockByNumber(number uint64) *types.Block
+}
+
+// historyStatus is the handshake of the order history protocol.
+type historyStatus struct {
+	Serve bool // Whether the node serves the order history of its MongoDB
+}
+
+// getOrderHistoryData requests the order history of a range of blocks.
+type getOrderHistoryData struct {
+	ReqID uint64
+	From  uint64
+	Count uint64
+}
+
+// orderHistoryData is the order history of consecutive blocks, from the
+// requested one until the first block unknown to the archive node.
+type orderHistoryData struct {
+	ReqID  uint64
+	Blocks []*historyBlock
+}
+
+// historyBlock is the order history of a single block, the trade and order
+// documents being JSON encoded the way they are stored.
+type historyBlock struct {
+	Number uint64
+	Hash   common.Hash
+	Trades []byte // Trades matched in the block, in matching order
+	Orders []byte // Orders taking part in the block, in their latest state
+}
+
+// historyPeer is a node speaking the order history protocol.
+type historyPeer struct {
+	peer  *p2p.Peer
+	rw    p2p.MsgReadWriter
+	serve bool
+}
+
+// historyResponse is an order history delivered by a peer.
+type historyResponse struct {
+	peer *historyPeer
+	data *orderHistoryData
+}
+
+// historySync serves and snapshots the order history of the MongoDB.
+type historySync struct {
+	serve    bool                               // Whether to serve the order history to the other nodes
+	archives map[discover.NodeID]*discover.Node // Archive nodes the order history is snapshotted from
+	blocks   uint64                             // Number of recent blocks to snapshot, all if zero
+
+	chain HistoryChain // Blockchain the order history is served and verified from, nil until set
+
+	peers     map[discover.NodeID]*historyPeer
+	peersLock sync.RWMutex
+
+	reqID     uint64
+	responses chan *historyResponse
+}
+
+// newHistorySync creates the order history service, returning nil if it
+// neither serves nor snapshots the order history.
+func newHistorySync(cfg *Config) *historySync {
+	if !cfg.HistoryServe && len(cfg.HistoryPeers) == 0 {
+		return nil
+	}
+	h := &historySync{
+		serve:     cfg.HistoryServe,
+		archives:  make(map[discover.NodeID]*discover.Node),
+		blocks:    cfg.HistoryBlocks,
+		peers:     make(map[discover.NodeID]*historyPeer),
+		responses: make(chan *historyResponse, 1),
+	}
+	for _, url := range cfg.HistoryPeers {
+		node, err := discover.ParseNode(url)
+		if err != nil {
+			log.Crit("Invalid order history archive node", "url", url, "err", err)
+		}
+		h.archives[node.ID] = node
+	}
+	return h
+}
+
+// SetHistoryChain sets the blockchain the order history is served and
+// verified from.
+func (tomox *TomoX) SetHistoryChain(chain HistoryChain) {
+	if tomox.history != nil {
+		tomox.history.chain = chain
+	}
+}
+
+// historyProtocol returns the order history sub-protocol.
+func (tomox *TomoX) historyProtocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    historyProtocolName,
+		Version: historyProtocolVersion,
+		Length:  historyProtocolLength,
+		Run:     tomox.handleHistoryPeer,
+	}
+}
+
+// startHistory connects to the archive nodes and snapshots the order history
+// from them.
+func (tomox *TomoX) startHistory(server *p2p.Server) {
+	if len(tomox.history.archives) == 0 {
+		return
+	}
+	for _, node := range tomox.history.archives {
+		server.AddPeer(node)
+	}
+	go tomox.syncHistory()
+}
+
+// handleHistoryPeer runs the order history protocol with a peer until it
+// disconnects.
+func (tomox *TomoX) handleHistoryPeer(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	h := tomox.history
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p2p.Send(rw, historyStatusMsg, &historyStatus{Serve: h.serve})
+	}()
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != historyStatusMsg {
+		msg.Discard()
+		return fmt.Errorf("first message is %#x, want status", msg.Code)
+	}
+	var status historyStatus
+	if err := msg.Decode(&status); err != nil {
+		return err
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	p := &historyPeer{peer: peer, rw: rw, serve: status.Serve}
+
+	h.peersLock.Lock()
+	h.peers[peer.ID()] = p
+	h.peersLock.Unlock()
+
+	defer func() {
+		h.peersLock.Lock()
+		delete(h.peers, peer.ID())
+		h.peersLock.Unlock()
+	}()
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > historyMaxMsgSize {
+			msg.Discard()
+			return fmt.Errorf("message too large: %v > %v", msg.Size, historyMaxMsgSize)
+		}
+		switch msg.Code {
+		case getOrderHistoryMsg:
+			var req getOrderHistoryData
+			if err := msg.Decode(&req); err != nil {
+				return err
+			}
+			blocks := []*historyBlock{}
+			if h.serve {
+				blocks = tomox.serveHistory(req.From, req.Count)
+			}
+			if err := p2p.Send(rw, orderHistoryMsg, &orderHistoryData{ReqID: req.ReqID, Blocks: blocks}); err != nil {
+				return err
+			}
+
+		case orderHistoryMsg:
+			var res orderHistoryData
+			if err := msg.Decode(&res); err != nil {
+				return err
+			}
+			// Only accept the order history of the designated archive nodes
+			if _, ok := h.archives[peer.ID()]; !ok {
+				break
+			}
+			select {
+			case h.responses <- &historyResponse{peer: p, data: &res}:
+			default:
+			}
+
+		default:
+			msg.Discard()
+		}
+	}
+}
+
+// serveHistory retrieves the order history of consecutive blocks from the
+// MongoDB, stopping at the first block unknown to the chain.
+func (tomox *TomoX) serveHistory(from, count uint64) []*historyBlock {
+	if count > historyBatch {
+		count = historyBatch
+	}
+	blocks := []*historyBlock{}
+	if tomox.history.chain == nil || tomox.mongodb == nil {
+		return blocks
+	}
+	for number := from; number < from+count; number++ {
+		block := tomox.history.chain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		served, err := tomox.blockHistory(block)
+		if err != nil {
+			log.Warn("Failed to serve order history", "number", number, "err", err)
+			break
+		}
+		blocks = append(blocks, served)
+	}
+	return blocks
+}
+
+// blockHistory retrieves the trade and order documents of a block.
+func (tomox *TomoX) blockHistory(block *types.Block) (*historyBlock, error) {
+	var (
+		index  = newTradeIndex(block)
+		trades = make([]*Trade, 0, len(index.trades))
+		hashes = make([]string, 0, len(index.orders))
+	)
+	for _, trade := range index.trades {
+		hash := (&Trade{MakerOrderHash: trade.MakerOrderHash, TakerOrderHash: trade.TakerOrderHash}).ComputeHash()
+		doc, err := tomox.mongodb.GetObject(hash, &Trade{})
+		if err != nil || doc == nil {
+			return nil, fmt.Errorf("missing trade %x: %v", hash, err)
+		}
+		trades = append(trades, doc.(*Trade))
+	}
+	for hash := range index.orders {
+		hashes = append(hashes, hash.Hex())
+	}
+	orders := []*tomox_state.OrderItem{}
+	if len(hashes) > 0 {
+		orders = tomox.mongodb.GetListOrderByHashes(hashes)
+	}
+	encTrades, err := json.Marshal(trades)
+	if err != nil {
+		return nil, err
+	}
+	encOrders, err := json.Marshal(orders)
+	if err != nil {
+		return nil, err
+	}
+	return &historyBlock{Number: block.NumberU64(), Hash: block.Hash(), Trades: encTrades, Orders: encOrders}, nil
+}
+
+// orderOwner is the part of an order its matching commits to.
+type orderOwner struct {
+	user     common.Address
+	exchange common.Address
+}
+
+// tradeIndex is what the matching transactions of a block commit to, which the
+// order history of the block is verified against.
+type tradeIndex struct {
+	trades types.Trades               // Trades of the block, in matching order
+	txs    []common.Hash              // Matching transaction of each trade
+	orders map[common.Hash]orderOwner // Orders taking part in the block
+}
+
+// newTradeIndex collects the trades and orders matched in a block.
+func newTradeIndex(block *types.Block) *tradeIndex {
+	index := &tradeIndex{orders: make(map[common.Hash]orderOwner)}
+	for _, tx := range block.Transactions() {
+		if !tx.IsMatchingTransaction() {
+			continue
+		}
+		batch, err := DecodeTxMatchesBatch(tx.Data())
+		if err != nil {
+			continue
+		}
+		for _, txMatch := range batch.Data {
+			taker, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			index.orders[taker.Hash] = orderOwner{user: taker.UserAddress, exchange: taker.ExchangeAddress}
+			for _, trade := range NewTrades(txMatch.GetTrades()) {
+				index.trades = append(index.trades, trade)
+				index.txs = append(index.txs, tx.Hash())
+				index.orders[trade.MakerOrderHash] = orderOwner{user: trade.Maker, exchange: trade.MakerExchange}
+			}
+			for _, rejected := range txMatch.GetRejectedOrders() {
+				index.orders[rejected.Hash] = orderOwner{user: rejected.UserAddress, exchange: rejected.ExchangeAddress}
+			}
+		}
+	}
+	return index
+}
+
+// verifyHistory checks the order history served for a block against the trade
+// index of the local block, returning the decoded documents. The trades must
+// all be present and match the ones committed to by the trade root, while the
+// orders must take part in the block. The fees and the status of the documents
+// are computed by the archive node and can't be verified.
+func verifyHistory(block *types.Block, served *historyBlock) ([]*Trade, []*tomox_state.OrderItem, error) {
+	if served.Number != block.NumberU64() || served.Hash != block.Hash() {
+		return nil, nil, errHistoryBlock
+	}
+	index := newTradeIndex(block)
+	if root := block.TradeRoot(); root != (common.Hash{}) && types.DeriveSha(index.trades) != root {
+		return nil, nil, fmt.Errorf("%v: local trade root mismatch", errHistoryTrades)
+	}
+	var (
+		trades []*Trade
+		orders []*tomox_state.OrderItem
+	)
+	if err := json.Unmarshal(served.Trades, &trades); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(served.Orders, &orders); err != nil {
+		return nil, nil, err
+	}
+	if len(trades) != len(index.trades) {
+		return nil, nil, fmt.Errorf("%v: have %d trades, want %d", errHistoryTrades, len(trades), len(index.trades))
+	}
+	for i, trade := range trades {
+		want := index.trades[i]
+		switch {
+		case trade == nil || trade.PricePoint == nil || trade.Amount == nil,
+			trade.Hash != trade.ComputeHash(),
+			trade.TxHash != index.txs[i],
+			trade.TakerOrderHash != want.TakerOrderHash,
+			trade.MakerOrderHash != want.MakerOrderHash,
+			trade.Maker != want.Maker,
+			trade.MakerExchange != want.MakerExchange,
+			trade.BaseToken != want.BaseToken,
+			trade.QuoteToken != want.QuoteToken,
+			trade.PricePoint.Cmp(want.Price) != 0,
+			trade.Amount.Cmp(want.Quantity) != 0:
+			return nil, nil, fmt.Errorf("%v: trade %d differs", errHistoryTrades, i)
+		}
+	}
+	for _, order := range orders {
+		if order == nil {
+			return nil, nil, errHistoryOrders
+		}
+		owner, ok := index.orders[order.Hash]
+		if !ok || owner.user != order.UserAddress || owner.exchange != order.ExchangeAddress {
+			return nil, nil, fmt.Errorf("%v: order %x", errHistoryOrders, order.Hash)
+		}
+	}
+	return trades, orders, nil
+}
+
+// storeHistory writes verified trade and order documents into the MongoDB,
+// keeping the orders already known in a more recent state.
+func (tomox *TomoX) storeHistory(trades []*Trade, orders []*tomox_state.OrderItem) error {
+	db := tomox.mongodb
+	sc := db.InitBulk()
+	for _, trade := range trades {
+		if err := db.PutObject(trade.Hash, trade); err != nil {
+			return err
+		}
+	}
+	for _, order := range orders {
+		if known, err := db.GetObject(order.Hash, &tomox_state.OrderItem{}); err == nil && known != nil {
+			if !order.UpdatedAt.After(known.(*tomox_state.OrderItem).UpdatedAt) {
+				continue
+			}
+		}
+		if err := db.PutObject(order.Hash, order); err != nil {
+			return err
+		}
+	}
+	return db.CommitBulk(sc)
+}
+
+// historyHead retrieves the next block whose order history is to be snapshotted.
+func (tomox *TomoX) historyHead() (uint64, bool) {
+	enc, err := tomox.db.Get(historyHeadKey)
+	if err != nil || len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+func (tomox *TomoX) setHistoryHead(number uint64) {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	if err := tomox.db.Put(historyHeadKey, enc); err != nil {
+		log.Error("Failed to store order history progress", "number", number, "err", err)
+	}
+}
+
+// archivePeer returns a connected archive node serving the order history.
+func (h *historySync) archivePeer() *historyPeer {
+	h.peersLock.RLock()
+	defer h.peersLock.RUnlock()
+
+	for id := range h.archives {
+		if p := h.peers[id]; p != nil && p.serve {
+			return p
+		}
+	}
+	return nil
+}
+
+// requestHistory asks an archive node for the order history of a range of
+// blocks, waiting for its answer.
+func (tomox *TomoX) requestHistory(p *historyPeer, from, count uint64) ([]*historyBlock, error) {
+	h := tomox.history
+	h.reqID++
+	if err := p2p.Send(p.rw, getOrderHistoryMsg, &getOrderHistoryData{ReqID: h.reqID, From: from, Count: count}); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(historyTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case res := <-h.responses:
+			if res.peer == p && res.data.ReqID == h.reqID {
+				return res.data.Blocks, nil
+			}
+		case <-timeout.C:
+			return nil, errHistoryTimeout
+		case <-tomox.quit:
+			return nil, errors.New("terminated")
+		}
+	}
+}
+
+// syncHistory snapshots the order history of the blocks up to the current head
+// from the archive nodes. The blocks imported later are processed as usual.
+func (tomox *TomoX) syncHistory() {
+	h := tomox.history
+	if h.chain == nil {
+		log.Warn("Order history sync requires the blockchain")
+		return
+	}
+	target := h.chain.CurrentHeader().Number.Uint64()
+	next, ok := tomox.historyHead()
+	if !ok && h.blocks > 0 && target >= h.blocks {
+		next = target - h.blocks + 1
+	}
+	if next > target {
+		return
+	}
+	log.Info("Snapshotting order history from archive nodes", "from", next, "to", target)
+
+	for next <= target {
+		p := h.archivePeer()
+		if p == nil {
+			select {
+			case <-time.After(historyRetry):
+				continue
+			case <-tomox.quit:
+				return
+			}
+		}
+		count := target - next + 1
+		if count > historyBatch {
+			count = historyBatch
+		}
+		blocks, err := tomox.requestHistory(p, next, count)
+		if err != nil {
+			log.Debug("Order history request failed", "peer", p.peer.ID(), "from", next, "err", err)
+			continue
+		}
+		if len(blocks) == 0 {
+			// The archive node doesn't have the blocks yet, give it some time
+			select {
+			case <-time.After(historyRetry):
+			case <-tomox.quit:
+				return
+			}
+			continue
+		}
+		for _, served := range blocks {
+			block := h.chain.GetBlockByNumber(next)
+			if block == nil {
+				break
+			}
+			trades, orders, err := verifyHistory(block, served)
+			if err != nil {
+				log.Warn("Invalid order history from archive node, dropping", "peer", p.peer.ID(), "number", next, "err", err)
+				p.peer.Disconnect(p2p.DiscUselessPeer)
+				break
+			}
+			if err := tomox.storeHistory(trades, orders); err != nil {
+				log.Error("Failed to store order history", "number", next, "err", err)
+				return
+			}
+			next++
+			tomox.setHistoryHead(next)
+			if next > target {
+				break
+			}
+		}
+		log.Info("Snapshotted order history", "number", next-1, "target", target)
+	}
+	log.Info("Order history snapshot completed", "number", target)
+}
@@ -0,0 +1,92 @@
+package tomox
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestVerifyHistory(t *testing.T) {
+	var (
+		taker, maker = common.Address{0x01}, common.Address{0x02}
+		exchange     = common.Address{0xee}
+		tomo, btc    = common.HexToAddress(common.TomoNativeAddress), common.Address{0xb7}
+		sig          = &tomox_state.Signature{V: 27, R: common.Hash{0x01}, S: common.Hash{0x02}}
+	)
+	takerOrder := &tomox_state.OrderItem{OrderID: 2, UserAddress: taker, ExchangeAddress: exchange, BaseToken: btc, QuoteToken: tomo, Quantity: big.NewInt(1), Price: big.NewInt(10), Side: tomox_state.Bid, Signature: sig, Hash: common.Hash{0x0b}}
+	makerOrder := &tomox_state.OrderItem{OrderID: 1, UserAddress: maker, ExchangeAddress: exchange, BaseToken: btc, QuoteToken: tomo, Quantity: big.NewInt(1), Price: big.NewInt(10), Side: tomox_state.Ask, Signature: sig, Hash: common.Hash{0x0a}}
+
+	enc, _ := EncodeBytesItem(takerOrder)
+	record := map[string]string{
+		TradeTakerOrderHash: takerOrder.Hash.Hex(),
+		TradeMakerOrderHash: makerOrder.Hash.Hex(),
+		TradeMaker:          maker.Hex(),
+		TradeMakerExchange:  exchange.Hex(),
+		TradeBaseToken:      btc.Hex(),
+		TradeQuoteToken:     tomo.Hex(),
+		TradePrice:          "10",
+		TradeQuantity:       "1",
+	}
+	data, _ := EncodeTxMatchesBatch(TxMatchBatch{Data: []TxDataMatch{{Order: enc, Trades: []map[string]string{record}}}})
+	tx := types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, data)
+	block := types.NewBlock(&types.Header{Number: common.Big1}, []*types.Transaction{tx}, nil, nil)
+
+	// serve assembles the documents an archive node would serve for the block
+	serve := func(trade *Trade, orders ...*tomox_state.OrderItem) *historyBlock {
+		encTrades, _ := json.Marshal([]*Trade{trade})
+		encOrders, _ := json.Marshal(orders)
+		return &historyBlock{Number: 1, Hash: block.Hash(), Trades: encTrades, Orders: encOrders}
+	}
+	newTrade := func() *Trade {
+		trade := &Trade{
+			Taker:          taker,
+			Maker:          maker,
+			BaseToken:      btc,
+			QuoteToken:     tomo,
+			MakerOrderHash: makerOrder.Hash,
+			TakerOrderHash: takerOrder.Hash,
+			MakerExchange:  exchange,
+			TakerExchange:  exchange,
+			TxHash:         tx.Hash(),
+			PricePoint:     big.NewInt(10),
+			Amount:         big.NewInt(1),
+			MakeFee:        big.NewInt(7),
+			TakeFee:        big.NewInt(7),
+			Status:         TradeStatusSuccess,
+		}
+		trade.Hash = trade.ComputeHash()
+		return trade
+	}
+	trades, orders, err := verifyHistory(block, serve(newTrade(), takerOrder, makerOrder))
+	if err != nil {
+		t.Fatalf("failed to verify valid history: %v", err)
+	}
+	if len(trades) != 1 || len(orders) != 2 {
+		t.Fatalf("verified documents mismatch: have %d trades, %d orders, want 1, 2", len(trades), len(orders))
+	}
+	// Tampered trades, foreign orders and other blocks must be rejected
+	tampered := newTrade()
+	tampered.Amount = big.NewInt(2)
+	if _, _, err := verifyHistory(block, serve(tampered, takerOrder)); err == nil {
+		t.Fatalf("tampered trade accepted")
+	}
+	foreign := *makerOrder
+	foreign.UserAddress = common.Address{0x03}
+	if _, _, err := verifyHistory(block, serve(newTrade(), &foreign)); err == nil {
+		t.Fatalf("foreign order accepted")
+	}
+	other := serve(newTrade())
+	other.Hash = common.Hash{0xff}
+	if _, _, err := verifyHistory(block, other); err != errHistoryBlock {
+		t.Fatalf("other block error mismatch: have %v, want %v", err, errHistoryBlock)
+	}
+	missing := serve(newTrade())
+	missing.Trades, _ = json.Marshal([]*Trade{})
+	if _, _, err := verifyHistory(block, missing); err == nil {
+		t.Fatalf("missing trade accepted")
+	}
+}
@@ -0,0 +1,324 @@
+package tomox
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// DEX events exported to Kafka.
+const (
+	KafkaEventOrder  = "order"  // An order was placed, possibly matched
+	KafkaEventCancel = "cancel" // An order was cancelled
+	KafkaEventTrade  = "trade"  // A taker order was matched with a maker order
+)
+
+const (
+	kafkaBatchSize = 500 // Maximum number of records produced at once
+
+	kafkaRetryMin = time.Second      // Delay before retrying a failed export
+	kafkaRetryMax = 30 * time.Second // Maximum delay between retries
+)
+
+var (
+	kafkaOutboxPrefix = []byte("tomox-kafka-")     // kafkaOutboxPrefix + seq (uint64 big endian) -> kafkaRecord
+	kafkaHeadKey      = []byte("tomox-kafka-head") // Sequence of the next record stored
+	kafkaTailKey      = []byte("tomox-kafka-tail") // Sequence of the next record produced
+)
+
+// KafkaEvent is a DEX event exported to Kafka as JSON. Its record is keyed by
+// the matching transaction, the event type and the order or trade hash, and
+// deleted by a tombstone with the same key when the transaction is reorged.
+type KafkaEvent struct {
+	Type        string                 `json:"type"`
+	BlockNumber uint64                 `json:"blockNumber"`
+	BlockHash   common.Hash            `json:"blockHash"`
+	TxHash      common.Hash            `json:"txHash"`
+	Time        int64                  `json:"time"` // Milliseconds since the epoch at matching
+	Order       *tomox_state.OrderItem `json:"order,omitempty"`
+	Trade       *types.Trade           `json:"trade,omitempty"`
+}
+
+// kafkaRecord is a record waiting in the outbox to be produced.
+type kafkaRecord struct {
+	OrderBook common.Hash // Partitioning key, the records of a pair staying in order
+	Key       []byte
+	Value     []byte
+	Tombstone bool // Whether the record deletes the earlier ones of the key
+	Time      uint64
+}
+
+// kafkaRecords returns the records of the events of a matching transaction,
+// or their tombstones.
+func kafkaRecords(number uint64, hash common.Hash, batch TxMatchBatch, tombstone bool) []*kafkaRecord {
+	var records []*kafkaRecord
+	add := func(orderBook common.Hash, id common.Hash, event *KafkaEvent) {
+		record := &kafkaRecord{
+			OrderBook: orderBook,
+			Key:       []byte(batch.TxHash.Hex() + "/" + event.Type + "/" + id.Hex()),
+			Tombstone: tombstone,
+			Time:      uint64(event.Time),
+		}
+		if !tombstone {
+			record.Value, _ = json.Marshal(event)
+		}
+		records = append(records, record)
+	}
+	// The batches are timestamped in nanoseconds
+	millis := batch.Timestamp / 1e6
+	for _, match := range batch.Data {
+		order, err := match.DecodeOrder()
+		if err != nil {
+			log.Warn("Failed to decode exported order", "txhash", batch.TxHash, "err", err)
+			continue
+		}
+		orderBook := GetOrderBookHash(order.BaseToken, order.QuoteToken)
+		event := &KafkaEvent{Type: KafkaEventOrder, BlockNumber: number, BlockHash: hash, TxHash: batch.TxHash, Time: millis, Order: order}
+		if order.Status == OrderStatusCancelled {
+			event.Type = KafkaEventCancel
+		}
+		add(orderBook, order.Hash, event)
+
+		for _, trade := range NewTrades(match.GetTrades()) {
+			id := crypto.Keccak256Hash(trade.MakerOrderHash.Bytes(), trade.TakerOrderHash.Bytes())
+			add(orderBook, id, &KafkaEvent{Type: KafkaEventTrade, BlockNumber: number, BlockHash: hash, TxHash: batch.TxHash, Time: millis, Trade: trade})
+		}
+	}
+	return records
+}
+
+// kafkaExporter produces the DEX events to a Kafka topic with at-least-once
+// semantics: the records are stored in an outbox in the database along the
+// blocks, and only removed once acknowledged by the brokers, retrying until
+// then, also across restarts. Consumers may see a record more than once.
+type kafkaExporter struct {
+	db     OrderDao
+	client *kafkaClient
+
+	head uint64     // Sequence of the next record stored
+	tail uint64     // Sequence of the next record produced, only used by the loop
+	lock sync.Mutex // Lock protecting the head
+
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newKafkaExporter(db OrderDao, brokers []string, topic string) *kafkaExporter {
+	e := &kafkaExporter{
+		db:     db,
+		client: newKafkaClient(brokers, topic),
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+	e.head, e.tail = e.sequence(kafkaHeadKey), e.sequence(kafkaTailKey)
+	return e
+}
+
+// sequence loads a sequence of the outbox, zero if not stored yet.
+func (e *kafkaExporter) sequence(key []byte) uint64 {
+	enc, err := e.db.Get(key)
+	if err != nil || len(enc) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc)
+}
+
+func kafkaOutboxKey(seq uint64) []byte {
+	key := make([]byte, len(kafkaOutboxPrefix)+8)
+	copy(key, kafkaOutboxPrefix)
+	binary.BigEndian.PutUint64(key[len(kafkaOutboxPrefix):], seq)
+	return key
+}
+
+func encodeSequence(seq uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, seq)
+	return enc
+}
+
+func (e *kafkaExporter) start() {
+	e.wg.Add(1)
+	go e.loop()
+}
+
+func (e *kafkaExporter) stop() {
+	close(e.quit)
+	e.wg.Wait()
+	e.client.close()
+}
+
+// enqueue stores records in the outbox and wakes the loop up.
+func (e *kafkaExporter) enqueue(records []*kafkaRecord) {
+	if len(records) == 0 {
+		return
+	}
+	e.lock.Lock()
+	batch := e.db.NewBatch()
+	head := e.head
+	for _, record := range records {
+		enc, err := rlp.EncodeToBytes(record)
+		if err != nil {
+			log.Error("Failed to encode exported record", "err", err)
+			continue
+		}
+		batch.Put(kafkaOutboxKey(head), enc)
+		head++
+	}
+	batch.Put(kafkaHeadKey, encodeSequence(head))
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to store exported records", "err", err)
+	} else {
+		e.head = head
+	}
+	e.lock.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop produces the records of the outbox, backing off while the brokers fail.
+func (e *kafkaExporter) loop() {
+	defer e.wg.Done()
+
+	retry := kafkaRetryMin
+	for {
+		sent, err := e.flush()
+		if err != nil {
+			e.lock.Lock()
+			pending := e.head - e.tail
+			e.lock.Unlock()
+			log.Warn("Failed to export DEX events to Kafka", "pending", pending, "retry", retry, "err", err)
+
+			select {
+			case <-time.After(retry):
+			case <-e.quit:
+				return
+			}
+			if retry *= 2; retry > kafkaRetryMax {
+				retry = kafkaRetryMax
+			}
+			continue
+		}
+		retry = kafkaRetryMin
+		if sent == 0 {
+			select {
+			case <-e.wake:
+			case <-e.quit:
+				return
+			}
+		}
+		select {
+		case <-e.quit:
+			return
+		default:
+		}
+	}
+}
+
+// flush produces the next records of the outbox and removes them once
+// acknowledged, returning how many were.
+func (e *kafkaExporter) flush() (int, error) {
+	e.lock.Lock()
+	head := e.head
+	e.lock.Unlock()
+
+	if head-e.tail > kafkaBatchSize {
+		head = e.tail + kafkaBatchSize
+	}
+	if head == e.tail {
+		return 0, nil
+	}
+	partitions, err := e.client.partitions()
+	if err != nil {
+		return 0, err
+	}
+	// Group the records by partition, keeping their order
+	var (
+		order []int
+		msgs  = make(map[int][]*kafkaMessage)
+	)
+	for seq := e.tail; seq < head; seq++ {
+		enc, err := e.db.Get(kafkaOutboxKey(seq))
+		if err != nil {
+			return 0, err
+		}
+		record := new(kafkaRecord)
+		if err := rlp.DecodeBytes(enc, record); err != nil {
+			return 0, err
+		}
+		msg := &kafkaMessage{Key: record.Key, Time: int64(record.Time)}
+		if !record.Tombstone {
+			msg.Value = record.Value
+		}
+		hash := fnv.New32a()
+		hash.Write(record.OrderBook[:])
+		partition := int(hash.Sum32() % uint32(partitions))
+		if _, ok := msgs[partition]; !ok {
+			order = append(order, partition)
+		}
+		msgs[partition] = append(msgs[partition], msg)
+	}
+	for _, partition := range order {
+		if err := e.client.produce(partition, msgs[partition]); err != nil {
+			return 0, err
+		}
+	}
+	// Move the tail before deleting the records, a crash in between leaving
+	// them behind rather than the tail on a deleted record
+	if err := e.db.Put(kafkaTailKey, encodeSequence(head)); err != nil {
+		return 0, err
+	}
+	for seq := e.tail; seq < head; seq++ {
+		if err := e.db.Delete(kafkaOutboxKey(seq)); err != nil {
+			log.Debug("Failed to delete exported record", "seq", seq, "err", err)
+		}
+	}
+	sent := int(head - e.tail)
+	e.tail = head
+	return sent, nil
+}
+
+// IsExporting reports whether the DEX events are exported to Kafka.
+func (tomox *TomoX) IsExporting() bool {
+	return tomox.kafka != nil
+}
+
+// ExportTxMatches exports the orders and trades of the matching transactions
+// of a canonical block.
+func (tomox *TomoX) ExportTxMatches(block *types.Block, batches []TxMatchBatch) {
+	if tomox.kafka == nil {
+		return
+	}
+	var records []*kafkaRecord
+	for _, batch := range batches {
+		records = append(records, kafkaRecords(block.NumberU64(), block.Hash(), batch, false)...)
+	}
+	tomox.kafka.enqueue(records)
+}
+
+// ExportReorgTxMatch exports the tombstones of the events of a matching
+// transaction dropped by a reorg.
+func (tomox *TomoX) ExportReorgTxMatch(tx *types.Transaction) {
+	if tomox.kafka == nil {
+		return
+	}
+	batch, err := DecodeTxMatchesBatch(tx.Data())
+	if err != nil {
+		log.Warn("Failed to decode reorged matching transaction", "txhash", tx.Hash(), "err", err)
+		return
+	}
+	batch.TxHash = tx.Hash()
+	tomox.kafka.enqueue(kafkaRecords(0, common.Hash{}, batch, true))
+}
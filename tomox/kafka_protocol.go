@@ -0,0 +1,440 @@
+package tomox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Kafka protocol requests used by the exporter, the oldest versions supported
+// by the current brokers.
+const (
+	kafkaProduceKey      = 0
+	kafkaProduceVersion  = 3 // First version producing record batches
+	kafkaMetadataKey     = 3
+	kafkaMetadataVersion = 1
+
+	kafkaAcksAll      = -1 // Records acknowledged once replicated to all the in-sync replicas
+	kafkaTimeout      = 10 * time.Second
+	kafkaMaxFrameSize = 64 * 1024 * 1024
+)
+
+var (
+	errKafkaNoBroker    = errors.New("no kafka broker reachable")
+	errKafkaCorrelation = errors.New("kafka response for another request")
+	errKafkaShort       = errors.New("short kafka response")
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaMessage is a record produced to a partition of the topic. A nil value
+// is a tombstone, deleting the earlier records of the key from the compacted
+// topics.
+type kafkaMessage struct {
+	Key   []byte
+	Value []byte
+	Time  int64 // Milliseconds since the epoch
+}
+
+// kafkaClient produces records to the partitions of a topic, talking to the
+// partition leaders found through the metadata of the bootstrap brokers.
+type kafkaClient struct {
+	brokers []string
+	topic   string
+
+	leaders     []string            // Address of the leader of each partition, nil until loaded
+	conns       map[string]net.Conn // Connections to the brokers, by address
+	correlation int32
+}
+
+func newKafkaClient(brokers []string, topic string) *kafkaClient {
+	return &kafkaClient{
+		brokers: brokers,
+		topic:   topic,
+		conns:   make(map[string]net.Conn),
+	}
+}
+
+// partitions returns the number of partitions of the topic, loading the
+// metadata if needed.
+func (c *kafkaClient) partitions() (int, error) {
+	if c.leaders == nil {
+		if err := c.refresh(); err != nil {
+			return 0, err
+		}
+	}
+	return len(c.leaders), nil
+}
+
+// refresh loads the leaders of the partitions of the topic from the first
+// bootstrap broker answering.
+func (c *kafkaClient) refresh() error {
+	req := new(kafkaEncoder)
+	req.putInt32(1)
+	req.putString(c.topic)
+
+	err := errKafkaNoBroker
+	for _, broker := range c.brokers {
+		var resp []byte
+		if resp, err = c.roundTrip(broker, kafkaMetadataKey, kafkaMetadataVersion, req.Bytes()); err != nil {
+			continue
+		}
+		if c.leaders, err = c.decodeMetadata(resp); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// decodeMetadata returns the leader of each partition of the topic.
+func (c *kafkaClient) decodeMetadata(resp []byte) ([]string, error) {
+	dec := &kafkaDecoder{buf: resp}
+
+	brokers := make(map[int32]string)
+	for i := dec.int32(); i > 0 && dec.err == nil; i-- {
+		id, host, port := dec.int32(), dec.string(), dec.int32()
+		dec.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	dec.int32() // controller
+
+	var leaders []string
+	for i := dec.int32(); i > 0 && dec.err == nil; i-- {
+		code, topic := dec.int16(), dec.string()
+		dec.int8() // internal
+		if code != 0 && topic == c.topic {
+			return nil, fmt.Errorf("kafka metadata of %s failed with error code %d", topic, code)
+		}
+		for j := dec.int32(); j > 0 && dec.err == nil; j-- {
+			code, partition, leader := dec.int16(), dec.int32(), dec.int32()
+			dec.int32s() // replicas
+			dec.int32s() // in-sync replicas
+			if topic != c.topic {
+				continue
+			}
+			if code != 0 {
+				return nil, fmt.Errorf("kafka partition %d of %s unavailable with error code %d", partition, topic, code)
+			}
+			for int(partition) >= len(leaders) {
+				leaders = append(leaders, "")
+			}
+			leaders[partition] = brokers[leader]
+		}
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	for partition, leader := range leaders {
+		if leader == "" {
+			return nil, fmt.Errorf("kafka partition %d of %s has no leader", partition, c.topic)
+		}
+	}
+	if len(leaders) == 0 {
+		return nil, fmt.Errorf("kafka topic %s not found", c.topic)
+	}
+	return leaders, nil
+}
+
+// produce sends records to a partition of the topic, returning once they are
+// acknowledged by all the in-sync replicas. The metadata is reloaded on the
+// next call on failure, as the leader may have moved.
+func (c *kafkaClient) produce(partition int, msgs []*kafkaMessage) error {
+	if _, err := c.partitions(); err != nil {
+		return err
+	}
+	req := new(kafkaEncoder)
+	req.putInt16(-1) // transactional id
+	req.putInt16(kafkaAcksAll)
+	req.putInt32(int32(kafkaTimeout / time.Millisecond))
+	req.putInt32(1)
+	req.putString(c.topic)
+	req.putInt32(1)
+	req.putInt32(int32(partition))
+	req.putBytes(encodeRecordBatch(msgs))
+
+	resp, err := c.roundTrip(c.leaders[partition], kafkaProduceKey, kafkaProduceVersion, req.Bytes())
+	if err == nil {
+		err = decodeProduce(resp)
+	}
+	if err != nil {
+		c.leaders = nil
+	}
+	return err
+}
+
+// decodeProduce checks that a produce response acknowledges the records.
+func decodeProduce(resp []byte) error {
+	dec := &kafkaDecoder{buf: resp}
+	for i := dec.int32(); i > 0 && dec.err == nil; i-- {
+		dec.string() // topic
+		for j := dec.int32(); j > 0 && dec.err == nil; j-- {
+			partition, code := dec.int32(), dec.int16()
+			dec.int64() // base offset
+			dec.int64() // log append time
+			if code != 0 {
+				return fmt.Errorf("kafka produce to partition %d failed with error code %d", partition, code)
+			}
+		}
+	}
+	return dec.err
+}
+
+// roundTrip sends a request to a broker and returns the body of its response,
+// closing the connection on failure.
+func (c *kafkaClient) roundTrip(addr string, key, version int16, body []byte) ([]byte, error) {
+	conn, ok := c.conns[addr]
+	if !ok {
+		var err error
+		if conn, err = net.DialTimeout("tcp", addr, kafkaTimeout); err != nil {
+			return nil, err
+		}
+		c.conns[addr] = conn
+	}
+	c.correlation++
+
+	req := new(kafkaEncoder)
+	req.putInt32(0) // size, set below
+	req.putInt16(key)
+	req.putInt16(version)
+	req.putInt32(c.correlation)
+	req.putString("tomo")
+	req.Write(body)
+	frame := req.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+
+	resp, err := c.exchange(conn, frame)
+	if err != nil {
+		conn.Close()
+		delete(c.conns, addr)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchange writes a request frame and reads the response frame.
+func (c *kafkaClient) exchange(conn net.Conn, frame []byte) ([]byte, error) {
+	// The broker may wait for the replicas up to the request timeout
+	conn.SetDeadline(time.Now().Add(2 * kafkaTimeout))
+	if _, err := conn.Write(frame); err != nil {
+		return nil, err
+	}
+	var header [8]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size < 4 || size > kafkaMaxFrameSize {
+		return nil, fmt.Errorf("invalid kafka response size %d", size)
+	}
+	if int32(binary.BigEndian.Uint32(header[4:])) != c.correlation {
+		return nil, errKafkaCorrelation
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// close drops the connections to the brokers.
+func (c *kafkaClient) close() {
+	for addr, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, addr)
+	}
+}
+
+// encodeRecordBatch encodes records in the batch format of the brokers from
+// version 0.11 on, uncompressed and without idempotence.
+func encodeRecordBatch(msgs []*kafkaMessage) []byte {
+	first, max := msgs[0].Time, msgs[0].Time
+	for _, msg := range msgs {
+		if msg.Time > max {
+			max = msg.Time
+		}
+	}
+	records := new(kafkaEncoder)
+	for i, msg := range msgs {
+		record := new(kafkaEncoder)
+		record.putInt8(0) // attributes
+		record.putVarint(msg.Time - first)
+		record.putVarint(int64(i))
+		record.putVarintBytes(msg.Key)
+		record.putVarintBytes(msg.Value)
+		record.putVarint(0) // headers
+
+		records.putVarint(int64(record.Len()))
+		records.Write(record.Bytes())
+	}
+	// The checksum covers the batch from the attributes on
+	checked := new(kafkaEncoder)
+	checked.putInt16(0) // attributes
+	checked.putInt32(int32(len(msgs) - 1))
+	checked.putInt64(first)
+	checked.putInt64(max)
+	checked.putInt64(-1) // producer id
+	checked.putInt16(-1) // producer epoch
+	checked.putInt32(-1) // base sequence
+	checked.putInt32(int32(len(msgs)))
+	checked.Write(records.Bytes())
+
+	batch := new(kafkaEncoder)
+	batch.putInt64(0) // base offset
+	batch.putInt32(int32(4 + 1 + 4 + checked.Len()))
+	batch.putInt32(-1) // partition leader epoch
+	batch.putInt8(2)   // magic
+	batch.putInt32(int32(crc32.Checksum(checked.Bytes(), crc32c)))
+	batch.Write(checked.Bytes())
+	return batch.Bytes()
+}
+
+// kafkaEncoder writes the primitive types of the Kafka protocol.
+type kafkaEncoder struct {
+	bytes.Buffer
+}
+
+func (e *kafkaEncoder) putInt8(v int8) { e.WriteByte(byte(v)) }
+
+func (e *kafkaEncoder) putInt16(v int16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], uint16(v))
+	e.Write(buf[:])
+}
+
+func (e *kafkaEncoder) putInt32(v int32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(v))
+	e.Write(buf[:])
+}
+
+func (e *kafkaEncoder) putInt64(v int64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	e.Write(buf[:])
+}
+
+func (e *kafkaEncoder) putString(s string) {
+	e.putInt16(int16(len(s)))
+	e.WriteString(s)
+}
+
+func (e *kafkaEncoder) putBytes(b []byte) {
+	e.putInt32(int32(len(b)))
+	e.Write(b)
+}
+
+func (e *kafkaEncoder) putVarint(v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	e.Write(buf[:binary.PutVarint(buf[:], v)])
+}
+
+// putVarintBytes writes bytes prefixed by their varint length, -1 for nil.
+func (e *kafkaEncoder) putVarintBytes(b []byte) {
+	if b == nil {
+		e.putVarint(-1)
+		return
+	}
+	e.putVarint(int64(len(b)))
+	e.Write(b)
+}
+
+// kafkaDecoder reads the primitive types of the Kafka protocol, the first
+// error being kept and the following reads returning zero values.
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errKafkaShort
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32s() []int32 {
+	var list []int32
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		list = append(list, d.int32())
+	}
+	return list
+}
+
+// string reads a nullable string, empty if null.
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *kafkaDecoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+func (d *kafkaDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errKafkaShort
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// varintBytes reads bytes prefixed by their varint length, nil if -1.
+func (d *kafkaDecoder) varintBytes() []byte {
+	n := d.varint()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
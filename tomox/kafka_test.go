@@ -0,0 +1,255 @@
+package tomox
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// kafkaBroker is a single broker cluster serving a topic of two partitions,
+// rejecting the first produce requests.
+type kafkaBroker struct {
+	listener net.Listener
+	topic    string
+	failures int
+	produced chan *kafkaMessage
+}
+
+func newKafkaBroker(t *testing.T, topic string, failures int) *kafkaBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	b := &kafkaBroker{listener: listener, topic: topic, failures: failures, produced: make(chan *kafkaMessage, 16)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(t, conn)
+		}
+	}()
+	return b
+}
+
+func (b *kafkaBroker) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, frame); err != nil {
+			return
+		}
+		req := &kafkaDecoder{buf: frame}
+		key, version, correlation, client := req.int16(), req.int16(), req.int32(), req.string()
+		if client != "tomo" {
+			t.Errorf("client id mismatch: have %s, want tomo", client)
+		}
+		resp := new(kafkaEncoder)
+		resp.putInt32(0)
+		resp.putInt32(correlation)
+		switch {
+		case key == kafkaMetadataKey && version == kafkaMetadataVersion:
+			host, port, _ := net.SplitHostPort(b.listener.Addr().String())
+			number, _ := strconv.Atoi(port)
+			resp.putInt32(1)
+			resp.putInt32(7)
+			resp.putString(host)
+			resp.putInt32(int32(number))
+			resp.putInt16(-1)
+			resp.putInt32(7)
+			resp.putInt32(1)
+			resp.putInt16(0)
+			resp.putString(b.topic)
+			resp.putInt8(0)
+			resp.putInt32(2)
+			for partition := int32(0); partition < 2; partition++ {
+				resp.putInt16(0)
+				resp.putInt32(partition)
+				resp.putInt32(7)
+				resp.putInt32(1)
+				resp.putInt32(7)
+				resp.putInt32(1)
+				resp.putInt32(7)
+			}
+		case key == kafkaProduceKey && version == kafkaProduceVersion:
+			req.string()
+			if acks := req.int16(); acks != kafkaAcksAll {
+				t.Errorf("acks mismatch: have %d, want %d", acks, kafkaAcksAll)
+			}
+			req.int32()
+			req.int32()
+			topic := req.string()
+			req.int32()
+			partition := req.int32()
+			msgs := b.decodeRecordBatch(t, req.bytes())
+			if req.err != nil || topic != b.topic {
+				t.Errorf("invalid produce request to %s: %v", topic, req.err)
+			}
+			code := int16(0)
+			if b.failures > 0 {
+				b.failures--
+				code = 6 // Not leader for partition
+			}
+			resp.putInt32(1)
+			resp.putString(topic)
+			resp.putInt32(1)
+			resp.putInt32(partition)
+			resp.putInt16(code)
+			resp.putInt64(0)
+			resp.putInt64(-1)
+			resp.putInt32(0)
+			if code == 0 {
+				for _, msg := range msgs {
+					b.produced <- msg
+				}
+			}
+		default:
+			t.Errorf("unexpected request %d version %d", key, version)
+			return
+		}
+		frame = resp.Bytes()
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+		conn.Write(frame)
+	}
+}
+
+// decodeRecordBatch checks the layout and checksum of a record batch and
+// returns its records.
+func (b *kafkaBroker) decodeRecordBatch(t *testing.T, batch []byte) []*kafkaMessage {
+	dec := &kafkaDecoder{buf: batch}
+	dec.int64()
+	if length := dec.int32(); int(length) != len(dec.buf) {
+		t.Errorf("batch length mismatch: have %d, want %d", length, len(dec.buf))
+	}
+	dec.int32()
+	if magic := dec.int8(); magic != 2 {
+		t.Errorf("magic mismatch: have %d, want 2", magic)
+	}
+	if crc := uint32(dec.int32()); crc != crc32.Checksum(dec.buf, crc32c) {
+		t.Errorf("batch checksum mismatch")
+	}
+	dec.int16()
+	dec.int32()
+	first := dec.int64()
+	dec.int64()
+	dec.int64()
+	dec.int16()
+	dec.int32()
+
+	var msgs []*kafkaMessage
+	for i := dec.int32(); i > 0 && dec.err == nil; i-- {
+		dec.varint()
+		dec.int8()
+		msg := &kafkaMessage{Time: first + dec.varint()}
+		dec.varint()
+		msg.Key, msg.Value = dec.varintBytes(), dec.varintBytes()
+		dec.varint()
+		msgs = append(msgs, msg)
+	}
+	if dec.err != nil {
+		t.Errorf("failed to decode record batch: %v", dec.err)
+	}
+	return msgs
+}
+
+// Tests that the orders and trades of the matching transactions are produced
+// once the broker accepts them, and deleted by tombstones when reorged.
+func TestKafkaExport(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-kafka-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	broker := newKafkaBroker(t, "dex", 1)
+	defer broker.listener.Close()
+	tomox := New(&Config{DataDir: datadir, KafkaBrokers: []string{broker.listener.Addr().String()}, KafkaTopic: "dex"})
+
+	order := &tomox_state.OrderItem{Hash: common.Hash{0x01}, Status: OrderStatusNew}
+	cancel := &tomox_state.OrderItem{Hash: common.Hash{0x02}, Status: OrderStatusCancelled}
+	encode := func(order *tomox_state.OrderItem) []byte {
+		order.BaseToken, order.QuoteToken = common.Address{0xb7}, common.Address{0x01}
+		order.Quantity, order.Price, order.FilledAmount, order.Nonce = big.NewInt(1), big.NewInt(2), big.NewInt(0), big.NewInt(0)
+		order.Signature = &tomox_state.Signature{}
+		enc, err := EncodeBytesItem(order)
+		if err != nil {
+			t.Fatalf("failed to encode order: %v", err)
+		}
+		return enc
+	}
+	batch := TxMatchBatch{
+		Data: []TxDataMatch{
+			{Order: encode(order), Trades: []map[string]string{{TradeTakerOrderHash: order.Hash.Hex(), TradeMakerOrderHash: common.Hash{0x03}.Hex(), TradeQuantity: "1", TradePrice: "2"}}},
+			{Order: encode(cancel)},
+		},
+		Timestamp: 1500000000 * 1e9,
+	}
+	data, _ := json.Marshal(batch)
+	tx := types.NewTransaction(0, common.Address{}, nil, 0, nil, data)
+	batch.TxHash = tx.Hash()
+
+	// The records are stored before the exporter starts, and retried until the
+	// broker accepts them
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	tomox.ExportTxMatches(block, []TxMatchBatch{batch})
+	tomox.kafka.start()
+	defer tomox.kafka.stop()
+
+	expect := []string{KafkaEventOrder, KafkaEventTrade, KafkaEventCancel}
+	var keys [][]byte
+	for _, typ := range expect {
+		select {
+		case msg := <-broker.produced:
+			var event KafkaEvent
+			if err := json.Unmarshal(msg.Value, &event); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if event.Type != typ || event.BlockNumber != 10 || event.BlockHash != block.Hash() || event.TxHash != tx.Hash() || event.Time != 1500000000000 || msg.Time != event.Time {
+				t.Errorf("event mismatch: have %+v, want type %s", event, typ)
+			}
+			if typ == KafkaEventTrade && (event.Trade == nil || event.Trade.Price.Int64() != 2) {
+				t.Errorf("trade mismatch: %+v", event.Trade)
+			}
+			keys = append(keys, msg.Key)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%s event not produced", typ)
+		}
+	}
+	// Reorging the transaction produces the tombstones of its records
+	tomox.ExportReorgTxMatch(tx)
+	for i := range expect {
+		select {
+		case msg := <-broker.produced:
+			if msg.Value != nil || string(msg.Key) != string(keys[i]) {
+				t.Errorf("tombstone %d mismatch: key %s, value %x", i, msg.Key, msg.Value)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("tombstone %d not produced", i)
+		}
+	}
+	// The acknowledged records are removed from the outbox
+	time.Sleep(100 * time.Millisecond)
+	if head, tail := tomox.kafka.sequence(kafkaHeadKey), tomox.kafka.sequence(kafkaTailKey); head != 6 || tail != 6 {
+		t.Errorf("outbox sequences mismatch: head %d, tail %d", head, tail)
+	}
+	if has, _ := tomox.db.Has(kafkaOutboxKey(0)); has {
+		t.Error("acknowledged record left in the outbox")
+	}
+}
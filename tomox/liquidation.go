@@ -0,0 +1,51 @@
+package tomox
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+// ProcessLiquidations liquidates the open loans of the lending books which are
+// expired or undercollateralized, valuing the collaterals at the last prices
+// of the TomoX pairs in the given state.
+func (tomox *TomoX) ProcessLiquidations(ipcEndpoint string, config *params.TomoXLendingConfig, time uint64, statedb *state.StateDB, tomoxState *tomox_state.TomoXStateDB, lendingState *lendingstate.LendingStateDB) []*lendingstate.LendingTrade {
+	valuer := tomox.collateralValuer(ipcEndpoint, config, tomoxState)
+	return tomoxlending.ProcessLiquidations(time, config.LiquidationThreshold(), valuer, statedb, lendingState)
+}
+
+// collateralValuer values collaterals with the price source configured for the
+// loan tokens, falling back to the pair of the collateral token quoted in the
+// lending token, then to the inverse pair.
+func (tomox *TomoX) collateralValuer(ipcEndpoint string, config *params.TomoXLendingConfig, tomoxState *tomox_state.TomoXStateDB) tomoxlending.CollateralValuer {
+	return func(collateralToken, lendingToken common.Address, quantity *big.Int) *big.Int {
+		baseToken, quoteToken, ok := config.PriceSource(collateralToken, lendingToken)
+		if !ok {
+			baseToken, quoteToken = collateralToken, lendingToken
+			if price := tomoxState.GetPrice(GetOrderBookHash(baseToken, quoteToken)); price == nil || price.Sign() == 0 {
+				baseToken, quoteToken = lendingToken, collateralToken
+			}
+		}
+		price := tomoxState.GetPrice(GetOrderBookHash(baseToken, quoteToken))
+		if price == nil || price.Sign() == 0 {
+			return nil
+		}
+		// Prices are in quote token units for one base token
+		baseTokenDecimal, err := tomox.GetTokenDecimal(ipcEndpoint, baseToken)
+		if err != nil {
+			log.Debug("Failed to get token decimal", "token", baseToken.Hex(), "err", err)
+			return nil
+		}
+		value := new(big.Int)
+		if baseToken == collateralToken {
+			return value.Div(value.Mul(quantity, price), baseTokenDecimal)
+		}
+		return value.Div(value.Mul(quantity, baseTokenDecimal), price)
+	}
+}
@@ -14,6 +14,7 @@ const (
 	OrderStatusFilled        = "FILLED"
 	OrderStatusCancelled     = "CANCELLED"
 	OrderStatusRejected 	= "REJECTED"
+	OrderStatusAmended       = "AMENDED"
 )
 
 
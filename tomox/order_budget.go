@@ -0,0 +1,41 @@
+package tomox
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// OrderBudget limits the matching of the pending orders of a block, so that
+// blocks packed with orders still leave room for the regular transactions.
+type OrderBudget struct {
+	MaxOrders int       // Maximum number of orders matched, unlimited if zero
+	Deadline  time.Time // Time the matching stops at, unlimited if zero
+}
+
+// expired reports whether the time allotted to the matching is over.
+func (b OrderBudget) expired() bool {
+	return !b.Deadline.IsZero() && time.Now().After(b.Deadline)
+}
+
+// limitOrders keeps at most max of the pending orders, taking them round-robin
+// from the users in the given order, by nonce. Users with many pending orders
+// thus don't crowd out the others.
+func limitOrders(pending map[common.Address]types.OrderTransactions, senders []common.Address, max int) map[common.Address]types.OrderTransactions {
+	limited := make(map[common.Address]types.OrderTransactions)
+	for depth, count := 0, 0; count < max; depth++ {
+		taken := false
+		for _, sender := range senders {
+			if txs := pending[sender]; depth < len(txs) && count < max {
+				limited[sender] = txs[:depth+1]
+				count++
+				taken = true
+			}
+		}
+		if !taken {
+			break
+		}
+	}
+	return limited
+}
@@ -0,0 +1,50 @@
+package tomox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestLimitOrders(t *testing.T) {
+	// Users 0-2 with 3, 1 and 2 pending orders
+	senders := []common.Address{{0}, {1}, {2}}
+	pending := map[common.Address]types.OrderTransactions{
+		senders[0]: make(types.OrderTransactions, 3),
+		senders[1]: make(types.OrderTransactions, 1),
+		senders[2]: make(types.OrderTransactions, 2),
+	}
+	tests := []struct {
+		max  int
+		want []int
+	}{
+		{1, []int{1, 0, 0}},
+		{2, []int{1, 1, 0}},
+		{4, []int{2, 1, 1}},
+		{5, []int{2, 1, 2}},
+		{6, []int{3, 1, 2}},
+		{10, []int{3, 1, 2}},
+	}
+	for _, tt := range tests {
+		limited := limitOrders(pending, senders, tt.max)
+		for i, sender := range senders {
+			if have := len(limited[sender]); have != tt.want[i] {
+				t.Errorf("max %d: user %d orders mismatch: have %d, want %d", tt.max, i, have, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestOrderBudgetExpired(t *testing.T) {
+	if (OrderBudget{}).expired() {
+		t.Error("unlimited budget expired")
+	}
+	if (OrderBudget{Deadline: time.Now().Add(time.Hour)}).expired() {
+		t.Error("budget expired before its deadline")
+	}
+	if !(OrderBudget{Deadline: time.Now().Add(-time.Second)}).expired() {
+		t.Error("budget not expired after its deadline")
+	}
+}
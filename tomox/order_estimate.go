@@ -0,0 +1,79 @@
+package tomox
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// MatchFill is a trade an order would make against a maker order of the book.
+type MatchFill struct {
+	MakerOrderHash common.Hash    `json:"makerOrderHash"`
+	Maker          common.Address `json:"maker"`
+	Price          *big.Int       `json:"price"`
+	Quantity       *big.Int       `json:"quantity"`
+	TakerFee       *big.Int       `json:"takerFee,omitempty"`
+}
+
+// MatchEstimate is the outcome of matching an order against the order book of
+// its pair, estimated without applying the order.
+type MatchEstimate struct {
+	Fills        []MatchFill `json:"fills"`
+	Filled       *big.Int    `json:"filled"`             // Quantity of the order filled
+	Remaining    *big.Int    `json:"remaining"`          // Quantity of the order left unfilled
+	AveragePrice *big.Int    `json:"averagePrice"`       // Volume weighted price of the fills, nil if none
+	Rejected     string      `json:"rejected,omitempty"` // Reason the order would be rejected for, if any
+}
+
+// EstimateMatch dry-runs the matching of an order in the block with the given
+// number against copies of the given states, leaving them untouched. The nonce
+// of the order is set to the next one of its user, so that it's matched as if
+// sent right away.
+func (tomox *TomoX) EstimateMatch(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, number uint64, order *tomox_state.OrderItem) (*MatchEstimate, error) {
+	statedb, tomoXstatedb = statedb.Copy(), tomoXstatedb.Copy()
+
+	order.Nonce = new(big.Int).SetUint64(tomoXstatedb.GetNonce(order.UserAddress.Hash()))
+	trades, rejects, err := tomox.ApplyOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, number, GetOrderBookHash(order.BaseToken, order.QuoteToken), order)
+	if err != nil {
+		return nil, err
+	}
+	return newMatchEstimate(order, trades, rejects), nil
+}
+
+// newMatchEstimate summarizes the trades and rejects of a matched order.
+func newMatchEstimate(order *tomox_state.OrderItem, trades []map[string]string, rejects []*tomox_state.OrderItem) *MatchEstimate {
+	estimate := &MatchEstimate{
+		Fills:  make([]MatchFill, 0, len(trades)),
+		Filled: new(big.Int),
+	}
+	value := new(big.Int)
+	for _, trade := range trades {
+		fill := MatchFill{
+			MakerOrderHash: common.HexToHash(trade[TradeMakerOrderHash]),
+			Maker:          common.HexToAddress(trade[TradeMaker]),
+			Price:          ToBigInt(trade[TradePrice]),
+			Quantity:       ToBigInt(trade[TradeQuantity]),
+		}
+		if fee, ok := trade[TradeTakerFee]; ok {
+			fill.TakerFee = ToBigInt(fee)
+		}
+		estimate.Fills = append(estimate.Fills, fill)
+		estimate.Filled.Add(estimate.Filled, fill.Quantity)
+		value.Add(value, new(big.Int).Mul(fill.Price, fill.Quantity))
+	}
+	if estimate.Filled.Sign() > 0 {
+		estimate.AveragePrice = value.Div(value, estimate.Filled)
+	}
+	estimate.Remaining = new(big.Int).Sub(order.Quantity, estimate.Filled)
+	if estimate.Remaining.Sign() < 0 {
+		estimate.Remaining.SetUint64(0)
+	}
+	for _, reject := range rejects {
+		if reject == order {
+			estimate.Rejected = reject.RejectReason
+		}
+	}
+	return estimate
+}
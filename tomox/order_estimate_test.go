@@ -0,0 +1,82 @@
+package tomox
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestNewMatchEstimate(t *testing.T) {
+	order := &tomox_state.OrderItem{Quantity: big.NewInt(10)}
+	trades := []map[string]string{
+		{TradeMakerOrderHash: common.Hash{0x01}.Hex(), TradePrice: "100", TradeQuantity: "3", TradeTakerFee: "1"},
+		{TradeMakerOrderHash: common.Hash{0x02}.Hex(), TradePrice: "110", TradeQuantity: "2"},
+	}
+	estimate := newMatchEstimate(order, trades, nil)
+	if len(estimate.Fills) != 2 || estimate.Fills[1].MakerOrderHash != (common.Hash{0x02}) {
+		t.Fatalf("fills mismatch: have %+v", estimate.Fills)
+	}
+	if estimate.Fills[0].TakerFee.Int64() != 1 || estimate.Fills[1].TakerFee != nil {
+		t.Errorf("taker fees mismatch: have %v, %v, want 1, nil", estimate.Fills[0].TakerFee, estimate.Fills[1].TakerFee)
+	}
+	if estimate.Filled.Int64() != 5 || estimate.Remaining.Int64() != 5 {
+		t.Errorf("quantities mismatch: have %v filled, %v remaining, want 5, 5", estimate.Filled, estimate.Remaining)
+	}
+	// (100*3 + 110*2) / 5
+	if estimate.AveragePrice.Int64() != 104 {
+		t.Errorf("average price mismatch: have %v, want 104", estimate.AveragePrice)
+	}
+	if estimate.Rejected != "" {
+		t.Errorf("unrejected order reported rejected: %q", estimate.Rejected)
+	}
+	maker := &tomox_state.OrderItem{RejectReason: RejectUnfunded}
+	order.RejectReason = RejectSelfTrade
+	if estimate := newMatchEstimate(order, nil, []*tomox_state.OrderItem{maker, order}); estimate.Rejected != RejectSelfTrade || estimate.AveragePrice != nil {
+		t.Errorf("rejected order mismatch: have %q, price %v, want %q, nil", estimate.Rejected, estimate.AveragePrice, RejectSelfTrade)
+	}
+}
+
+func TestEstimateMatchLeavesState(t *testing.T) {
+	var (
+		user  = common.HexToAddress("0x0a")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	statedb.SetState(common.HexToAddress(common.TomoXPairHalt), vm.TomoXPairHaltSlot(base, quote), common.BigToHash(big.NewInt(1)))
+
+	tomox := &TomoX{}
+	orderBook := GetOrderBookHash(base, quote)
+	newOrder := func(orderType string) *tomox_state.OrderItem {
+		return &tomox_state.OrderItem{UserAddress: user, BaseToken: base, QuoteToken: quote, Side: Bid, Type: orderType, Price: big.NewInt(100), Quantity: big.NewInt(10)}
+	}
+	// A limit order rests on the halted pair, unfilled
+	estimate, err := tomox.EstimateMatch(common.Address{}, "", statedb, tomoxState, 1, newOrder(Limit))
+	if err != nil {
+		t.Fatalf("failed to estimate limit order: %v", err)
+	}
+	if len(estimate.Fills) != 0 || estimate.Remaining.Int64() != 10 || estimate.Rejected != "" {
+		t.Errorf("limit estimate mismatch: have %d fills, %v remaining, rejected %q", len(estimate.Fills), estimate.Remaining, estimate.Rejected)
+	}
+	if bid, _ := tomoxState.GetBestBidPrice(orderBook); bid.Sign() != 0 {
+		t.Errorf("estimated order rests in the book at %v", bid)
+	}
+	if nonce := tomoxState.GetNonce(user.Hash()); nonce != 0 {
+		t.Errorf("estimated order bumped the nonce to %d", nonce)
+	}
+	// A market order is rejected on the halted pair
+	estimate, err = tomox.EstimateMatch(common.Address{}, "", statedb, tomoxState, 1, newOrder(Market))
+	if err != nil {
+		t.Fatalf("failed to estimate market order: %v", err)
+	}
+	if estimate.Rejected != RejectPairHalted {
+		t.Errorf("market estimate rejection mismatch: have %q, want %q", estimate.Rejected, RejectPairHalted)
+	}
+}
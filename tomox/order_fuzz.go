@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package tomox
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/hashicorp/golang-lru"
+)
+
+// fuzzBalance is the balance of every token held by the users of the fuzzed
+// orders, and the deposit of their relayers.
+var fuzzBalance = new(big.Int).Mul(big.NewInt(1000000000), common.BasePrice)
+
+// fuzzFeeRate is the trading fee of the relayers of the fuzzed orders, 0.1%.
+var fuzzFeeRate = big.NewInt(1)
+
+// Fuzz implements a go-fuzz fuzzer method to test the matching engine. The
+// input is an RLP list of order transactions, matched in turn against empty
+// order books, which must never be left crossed. The matching corpus of
+// gen_fuzz_corpus.go seeds it.
+func Fuzz(data []byte) int {
+	var txs []*types.OrderTransaction
+	if err := rlp.DecodeBytes(data, &txs); err != nil {
+		return 0
+	}
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	tomox_state.UpgradeMatchingVersion(LatestMatchingVersion, tomoxState)
+
+	tokenDecimalCache, _ := lru.New(defaultCacheLimit)
+	tomox := &TomoX{tokenDecimalCache: tokenDecimalCache}
+	matched := 0
+	for _, tx := range txs {
+		order, err := NewOrderItem(tx)
+		if err != nil {
+			continue
+		}
+		// Skip the nonce checks, the signatures are not verified either
+		order.Nonce.SetUint64(tomoxState.GetNonce(order.UserAddress.Hash()))
+		fundFuzzOrder(tomox, statedb, order)
+
+		orderBook := GetOrderBookHash(order.BaseToken, order.QuoteToken)
+		trades, _, err := tomox.CommitOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, order)
+		if err != nil {
+			continue
+		}
+		matched += len(trades)
+
+		bid, _ := tomoxState.GetBestBidPrice(orderBook)
+		ask, _ := tomoxState.GetBestAskPrice(orderBook)
+		if bid.Sign() > 0 && ask.Sign() > 0 && bid.Cmp(ask) >= 0 {
+			panic("crossed order book")
+		}
+	}
+	if matched > 0 {
+		return 1
+	}
+	return 0
+}
+
+// fundFuzzOrder sets the decimals of the tokens of an order, and funds its user
+// and registers its relayer the first time they are seen.
+func fundFuzzOrder(tomox *TomoX, statedb *state.StateDB, order *tomox_state.OrderItem) {
+	for _, token := range []common.Address{order.BaseToken, order.QuoteToken} {
+		if _, ok := tomox.CachedTokenDecimal(token); !ok {
+			tomox.SetTokenDecimal(token, common.BasePrice)
+		}
+		if !statedb.Exist(token) {
+			statedb.CreateAccount(token)
+		}
+		if tomox_state.GetTokenBalance(order.UserAddress, token, statedb).Sign() == 0 {
+			tomox_state.SetTokenBalance(order.UserAddress, fuzzBalance, token, statedb)
+		}
+	}
+	if tomox_state.CheckRelayerFee(order.ExchangeAddress, common.RelayerFee, statedb) != nil {
+		loc := tomox_state.GetLocMappingAtKey(order.ExchangeAddress.Hash(), tomox_state.RelayerMappingSlot["RELAYER_LIST"])
+		registration := common.HexToAddress(common.RelayerRegistrationSMC)
+		deposit := common.BigToHash(new(big.Int).Add(loc, tomox_state.RelayerStructMappingSlot["_deposit"]))
+		statedb.SetState(registration, deposit, common.BigToHash(fuzzBalance))
+		fee := common.BigToHash(new(big.Int).Add(loc, tomox_state.RelayerStructMappingSlot["_fee"]))
+		statedb.SetState(registration, fee, common.BigToHash(fuzzFeeRate))
+		owner := common.BigToHash(new(big.Int).Add(loc, tomox_state.RelayerStructMappingSlot["_owner"]))
+		statedb.SetState(registration, owner, order.ExchangeAddress.Hash())
+	}
+}
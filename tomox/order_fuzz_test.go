@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// +build gofuzz
+
+package tomox
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the fuzzer matches crossing orders, and leaves the book untouched
+// by orders that don't cross.
+func TestFuzz(t *testing.T) {
+	order := func(user common.Address, nonce uint64, side string, price int64) *types.OrderTransaction {
+		tx := types.NewOrderTransaction(nonce, new(big.Int).Mul(big.NewInt(10), common.BasePrice), new(big.Int).Mul(big.NewInt(price), common.BasePrice), common.Address{1}, user, common.Address{2}, common.Address{3}, types.OrderStatusNew, side, "LO", "BTC/TOMO", common.Hash{}, 0)
+		tx.SetOrderHash(types.OrderTxSigner{}.Hash(tx))
+		return tx
+	}
+	maker, taker := common.Address{4}, common.Address{5}
+	tests := []struct {
+		name   string
+		orders []*types.OrderTransaction
+		want   int
+	}{
+		{"crossing", []*types.OrderTransaction{order(maker, 0, "SELL", 100), order(taker, 0, "BUY", 110)}, 1},
+		{"resting", []*types.OrderTransaction{order(maker, 0, "SELL", 110), order(taker, 0, "BUY", 100)}, 0},
+	}
+	for _, tt := range tests {
+		enc, _ := rlp.EncodeToBytes(tt.orders)
+		if res := Fuzz(enc); res != tt.want {
+			t.Errorf("%s: fuzz result mismatch: have %d, want %d", tt.name, res, tt.want)
+		}
+	}
+	if res := Fuzz([]byte{0xc1}); res != 0 {
+		t.Errorf("malformed input: fuzz result mismatch: have %d, want 0", res)
+	}
+}
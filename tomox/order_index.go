@@ -0,0 +1,97 @@
+package tomox
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// orderBookIndexPrefix prefixes the keys of the order books index, which maps
+// users to the order books they placed orders in.
+var orderBookIndexPrefix = []byte("ob-index-")
+
+func orderBookIndexKey(user common.Address) []byte {
+	return append(append([]byte{}, orderBookIndexPrefix...), user.Bytes()...)
+}
+
+// IndexOrderBooks adds the order books of the orders matched in a block to the
+// order books index of their users. The index is only ever extended, whether
+// the blocks end up canonical or not, so it lists a superset of the order
+// books a user has open orders in at any block.
+func (tomox *TomoX) IndexOrderBooks(block *types.Block) {
+	added := make(map[common.Address]map[common.Hash]struct{})
+	for _, tx := range block.Transactions() {
+		if !tx.IsMatchingTransaction() {
+			continue
+		}
+		batch, err := DecodeTxMatchesBatch(tx.Data())
+		if err != nil {
+			continue
+		}
+		for _, txMatch := range batch.Data {
+			order, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			if added[order.UserAddress] == nil {
+				added[order.UserAddress] = make(map[common.Hash]struct{})
+			}
+			added[order.UserAddress][GetOrderBookHash(order.BaseToken, order.QuoteToken)] = struct{}{}
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	tomox.orderBookIndexLock.Lock()
+	defer tomox.orderBookIndexLock.Unlock()
+
+	batch := tomox.db.NewBatch()
+	for user, orderBooks := range added {
+		known := tomox.UserOrderBooks(user)
+		for _, orderBook := range known {
+			delete(orderBooks, orderBook)
+		}
+		if len(orderBooks) == 0 {
+			continue
+		}
+		for orderBook := range orderBooks {
+			known = append(known, orderBook)
+		}
+		sort.Slice(known, func(i, j int) bool { return bytes.Compare(known[i][:], known[j][:]) < 0 })
+		enc, _ := rlp.EncodeToBytes(known)
+		batch.Put(orderBookIndexKey(user), enc)
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write order books index", "block", block.Number(), "err", err)
+	}
+}
+
+// UserOrderBooks returns the order books a user placed orders in, as indexed
+// by IndexOrderBooks.
+func (tomox *TomoX) UserOrderBooks(user common.Address) []common.Hash {
+	enc, err := tomox.db.Get(orderBookIndexKey(user))
+	if err != nil || len(enc) == 0 {
+		return nil
+	}
+	var orderBooks []common.Hash
+	if err := rlp.DecodeBytes(enc, &orderBooks); err != nil {
+		log.Error("Failed to decode order books index", "user", user, "err", err)
+		return nil
+	}
+	return orderBooks
+}
+
+// GetOpenOrders returns the orders of a user open in the given state, looking
+// them up in the order books the user is indexed in only.
+func (tomox *TomoX) GetOpenOrders(tomoxState *tomox_state.TomoXStateDB, user common.Address) []tomox_state.OrderItem {
+	orders := []tomox_state.OrderItem{}
+	for _, orderBook := range tomox.UserOrderBooks(user) {
+		orders = append(orders, tomoxState.GetUserOrders(orderBook, user)...)
+	}
+	return orders
+}
@@ -0,0 +1,70 @@
+package tomox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestOpenOrders(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-index-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+	tomox := New(&Config{DataDir: datadir})
+	defer tomox.db.Close()
+
+	var (
+		user, other      = common.Address{0x01}, common.Address{0x02}
+		tomo, btc, eth   = common.HexToAddress(common.TomoNativeAddress), common.Address{0xb7}, common.Address{0xe7}
+		btcBook, ethBook = GetOrderBookHash(btc, tomo), GetOrderBookHash(eth, tomo)
+	)
+	sig := &tomox_state.Signature{V: 27, R: common.Hash{0x01}, S: common.Hash{0x02}}
+	orders := []*tomox_state.OrderItem{
+		{OrderID: 1, UserAddress: user, BaseToken: btc, QuoteToken: tomo, Quantity: big.NewInt(1), Price: big.NewInt(10), Side: tomox_state.Ask, Signature: sig},
+		{OrderID: 2, UserAddress: other, BaseToken: btc, QuoteToken: tomo, Quantity: big.NewInt(1), Price: big.NewInt(11), Side: tomox_state.Ask, Signature: sig},
+		{OrderID: 1, UserAddress: user, BaseToken: eth, QuoteToken: tomo, Quantity: big.NewInt(1), Price: big.NewInt(5), Side: tomox_state.Bid, Signature: sig},
+	}
+	var batch TxMatchBatch
+	for _, order := range orders {
+		enc, _ := EncodeBytesItem(order)
+		batch.Data = append(batch.Data, TxDataMatch{Order: enc})
+	}
+	data, _ := EncodeTxMatchesBatch(batch)
+	tx := types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, data)
+	tomox.IndexOrderBooks(types.NewBlock(&types.Header{Number: common.Big1}, []*types.Transaction{tx}, nil, nil))
+
+	if books := tomox.UserOrderBooks(user); len(books) != 2 {
+		t.Fatalf("user order books mismatch: have %d, want 2", len(books))
+	}
+	if books := tomox.UserOrderBooks(other); len(books) != 1 || books[0] != btcBook {
+		t.Fatalf("other order books mismatch: have %x, want [%x]", books, btcBook)
+	}
+	// Only the orders still open in the state are returned
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	statedb.InsertOrderItem(btcBook, common.BigToHash(big.NewInt(1)), *orders[0])
+	statedb.InsertOrderItem(btcBook, common.BigToHash(big.NewInt(2)), *orders[1])
+	statedb.InsertOrderItem(ethBook, common.BigToHash(big.NewInt(1)), *orders[2])
+	root := statedb.IntermediateRoot()
+	statedb.Commit()
+	statedb, _ = tomox_state.New(root, statedb.Database())
+
+	if open := tomox.GetOpenOrders(statedb, user); len(open) != 2 {
+		t.Fatalf("open orders mismatch: have %d, want 2", len(open))
+	}
+	if err := statedb.CancelOrder(ethBook, orders[2]); err != nil {
+		t.Fatalf("failed to cancel order: %v", err)
+	}
+	open := tomox.GetOpenOrders(statedb, user)
+	if len(open) != 1 || open[0].BaseToken != btc {
+		t.Fatalf("open orders after cancel mismatch: have %v", open)
+	}
+}
@@ -17,9 +17,29 @@ import (
 var emptyAddress = common.StringToAddress("")
 var errQuantityTradeTooSmall = errors.New("Quantity trade too small")
 
-func (tomox *TomoX) CommitOrder(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, orderBook common.Hash, order *tomox_state.OrderItem) ([]map[string]string, []*tomox_state.OrderItem, error) {
+// Reasons for which the matching engine rejects an order
+const (
+	RejectInvalidPrice    = "invalid price"
+	RejectInvalidQuantity = "invalid quantity"
+	RejectPairSize        = "off the pair sizes"
+	RejectPairHalted      = "pair halted"
+	RejectSelfTrade       = "self trade prevented"
+	RejectTradeTooSmall   = "trade quantity too small"
+	RejectUnfunded        = "insufficient balance or relayer deposit"
+	RejectExpired         = "order expired"
+	RejectUnsupported     = "not supported by the matching rules"
+)
+
+// rejectOrder adds an order to the rejected orders, with the reason of its
+// rejection.
+func rejectOrder(rejects []*tomox_state.OrderItem, order *tomox_state.OrderItem, reason string) []*tomox_state.OrderItem {
+	order.RejectReason = reason
+	return append(rejects, order)
+}
+
+func (tomox *TomoX) CommitOrder(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, number uint64, orderBook common.Hash, order *tomox_state.OrderItem) ([]map[string]string, []*tomox_state.OrderItem, error) {
 	snap := tomoXstatedb.Snapshot()
-	trades, rejects, err := tomox.ApplyOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, orderBook, order)
+	trades, rejects, err := tomox.ApplyOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, number, orderBook, order)
 	if err != nil {
 		tomoXstatedb.RevertToSnapshot(snap)
 		return nil, nil, err
@@ -27,7 +47,8 @@ func (tomox *TomoX) CommitOrder(coinbase common.Address, ipcEndpoint string, sta
 	return trades, rejects, err
 }
 
-func (tomox *TomoX) ApplyOrder(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, orderBook common.Hash, order *tomox_state.OrderItem) ([]map[string]string, []*tomox_state.OrderItem, error) {
+// ApplyOrder matches an order in the block with the given number.
+func (tomox *TomoX) ApplyOrder(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, number uint64, orderBook common.Hash, order *tomox_state.OrderItem) ([]map[string]string, []*tomox_state.OrderItem, error) {
 	var (
 		rejects []*tomox_state.OrderItem
 		trades  []map[string]string
@@ -42,17 +63,23 @@ func (tomox *TomoX) ApplyOrder(coinbase common.Address, ipcEndpoint string, stat
 	}
 	if order.Price.Sign() == 0 || common.BigToHash(order.Price).Big().Cmp(order.Price) != 0 {
 		log.Debug("Reject order price invalid", "price", order.Price)
-		rejects = append(rejects, order)
+		rejects = rejectOrder(rejects, order, RejectInvalidPrice)
 		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
 		return trades, rejects, nil
 	}
 	if order.Quantity.Sign() == 0 || common.BigToHash(order.Quantity).Big().Cmp(order.Quantity) != 0 {
 		log.Debug("Reject order quantity invalid", "quantity", order.Quantity)
-		rejects = append(rejects, order)
+		rejects = rejectOrder(rejects, order, RejectInvalidQuantity)
 		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
 		return trades, rejects, nil
 	}
 
+	if !StateMatchingRules(tomoXstatedb).Supports(order) {
+		log.Debug("Reject order not supported by the matching rules", "status", order.Status, "expiry", order.ExpiryBlock, "stp", order.SelfTradePrevention, "version", tomoXstatedb.MatchingVersion())
+		rejects = rejectOrder(rejects, order, RejectUnsupported)
+		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
+		return trades, rejects, nil
+	}
 	if order.Status == OrderStatusCancelled {
 		err := tomoXstatedb.CancelOrder(orderBook, order)
 		if err != nil {
@@ -63,6 +90,40 @@ func (tomox *TomoX) ApplyOrder(coinbase common.Address, ipcEndpoint string, stat
 		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
 		return trades, rejects, nil
 	}
+	if tomox_state.IsOrderExpired(order.ExpiryBlock, number) {
+		log.Debug("Reject order expired", "expiry", order.ExpiryBlock, "number", number)
+		rejects = rejectOrder(rejects, order, RejectExpired)
+		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
+		return trades, rejects, nil
+	}
+	if err := tomox_state.VerifyPairSize(statedb, order.BaseToken, order.QuoteToken, order.Type, order.Price, order.Quantity); err != nil {
+		log.Debug("Reject order off the pair sizes", "price", order.Price, "quantity", order.Quantity, "err", err)
+		rejects = rejectOrder(rejects, order, RejectPairSize)
+		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
+		return trades, rejects, nil
+	}
+	if order.Status == OrderStatusAmended {
+		trades, rejects, err = tomox.amendOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, orderBook, order)
+		if err != nil {
+			log.Debug("Error when amend order", "order", order, "err", err)
+			return nil, nil, err
+		}
+		log.Debug("Exchange add user nonce:", "address", order.UserAddress, "status", order.Status, "nonce", nonce+1)
+		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
+		return trades, rejects, nil
+	}
+	if tomox_state.IsPairHalted(statedb, order.BaseToken, order.QuoteToken) {
+		// The matching of a halted pair is suspended, limit orders rest in the
+		// order book and market orders, which can't, are rejected
+		log.Debug("Pair halted, order not matched", "base", order.BaseToken.Hex(), "quote", order.QuoteToken.Hex(), "type", order.Type)
+		if order.Type == Market {
+			rejects = rejectOrder(rejects, order, RejectPairHalted)
+		} else {
+			restOrder(tomoXstatedb, orderBook, order)
+		}
+		tomoXstatedb.SetNonce(order.UserAddress.Hash(), nonce+1)
+		return trades, rejects, nil
+	}
 	orderType := order.Type
 	// if we do not use auto-increment orderid, we must set price slot to avoid conflict
 	if orderType == Market {
@@ -176,17 +237,64 @@ func (tomox *TomoX) processLimitOrder(coinbase common.Address, ipcEndpoint strin
 		}
 	}
 	if quantityToTrade.Cmp(zero) > 0 {
-		orderId := tomoXstatedb.GetNonce(orderBook)
-		order.OrderID = orderId + 1
 		order.Quantity = quantityToTrade
-		tomoXstatedb.SetNonce(orderBook, orderId+1)
-		orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
-		tomoXstatedb.InsertOrderItem(orderBook, orderIdHash, *order)
+		restOrder(tomoXstatedb, orderBook, order)
 		log.Debug("After matching, order (unmatched part) is now added to tree", "side", order.Side, "order", order)
 	}
 	return trades, rejects, nil
 }
 
+// amendOrder replaces the price and quantity of a resting order with the ones of
+// its amendment. An order only decreasing its quantity keeps its time priority,
+// otherwise it's taken off the order book and matched again as a new limit
+// order, resting under a new order id.
+func (tomox *TomoX) amendOrder(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, orderBook common.Hash, order *tomox_state.OrderItem) ([]map[string]string, []*tomox_state.OrderItem, error) {
+	orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
+	resting := tomoXstatedb.GetOrder(orderBook, orderIdHash)
+	if resting.Quantity == nil || resting.Quantity.Sign() == 0 || resting.Hash != order.Hash || resting.UserAddress != order.UserAddress || resting.Side != order.Side {
+		return nil, nil, ErrAmendedOrderNotFound
+	}
+	if resting.Price.Cmp(order.Price) == 0 && resting.Quantity.Cmp(order.Quantity) >= 0 {
+		if decrease := new(big.Int).Sub(resting.Quantity, order.Quantity); decrease.Sign() > 0 {
+			if err := tomoXstatedb.SubAmountOrderItem(orderBook, orderIdHash, resting.Price, decrease, resting.Side); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, nil
+	}
+	if err := tomoXstatedb.CancelOrder(orderBook, &resting); err != nil {
+		return nil, nil, err
+	}
+	order.Type = Limit
+	order.ExpiryBlock = resting.ExpiryBlock
+	if tomox_state.IsPairHalted(statedb, order.BaseToken, order.QuoteToken) {
+		restOrder(tomoXstatedb, orderBook, order)
+		return nil, nil, nil
+	}
+	return tomox.processLimitOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, orderBook, order)
+}
+
+// restOrder adds an order to the order book under the next order id, indexing
+// it to be pruned at its expiry block if it has one.
+func restOrder(tomoXstatedb *tomox_state.TomoXStateDB, orderBook common.Hash, order *tomox_state.OrderItem) {
+	orderId := tomoXstatedb.GetNonce(orderBook)
+	order.OrderID = orderId + 1
+	tomoXstatedb.SetNonce(orderBook, orderId+1)
+	orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
+	tomoXstatedb.InsertOrderItem(orderBook, orderIdHash, *order)
+	if order.ExpiryBlock != 0 {
+		tomoXstatedb.AddExpiringOrder(tomox_state.ExpiringOrder{
+			BaseToken:   order.BaseToken,
+			QuoteToken:  order.QuoteToken,
+			OrderBook:   orderBook,
+			OrderID:     order.OrderID,
+			Hash:        order.Hash,
+			UserAddress: order.UserAddress,
+			ExpiryBlock: order.ExpiryBlock,
+		})
+	}
+}
+
 // processOrderList : process the order list
 func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, side string, orderBook common.Hash, price *big.Int, quantityStillToTrade *big.Int, order *tomox_state.OrderItem) (*big.Int, []map[string]string, []*tomox_state.OrderItem, error) {
 	quantityToTrade := CloneBigInt(quantityStillToTrade)
@@ -206,6 +314,22 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 		if oldestOrder.Quantity == nil || oldestOrder.Quantity.Sign() == 0 && amount.Sign() == 0 {
 			break
 		}
+		// Self-trade prevention, as chosen by the taker
+		if mode := order.SelfTradePrevention; mode != "" && oldestOrder.UserAddress == order.UserAddress {
+			log.Debug("Prevent self trade", "user", order.UserAddress, "mode", mode, "taker", order.Hash, "maker", oldestOrder.Hash)
+			if mode == tomox_state.CancelOldest || mode == tomox_state.CancelBoth { // cancel maker
+				rejects = rejectOrder(rejects, &oldestOrder, RejectSelfTrade)
+				if err := tomoXstatedb.CancelOrder(orderBook, &oldestOrder); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			if mode == tomox_state.CancelNewest || mode == tomox_state.CancelBoth { // reject Taker
+				rejects = rejectOrder(rejects, order, RejectSelfTrade)
+				quantityToTrade = Zero()
+				break
+			}
+			continue
+		}
 		var (
 			tradedQuantity    *big.Int
 			maxTradedQuantity *big.Int
@@ -219,24 +343,24 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 		if oldestOrder.QuoteToken.String() != common.TomoNativeAddress {
 			quotePrice = tomoXstatedb.GetPrice(GetOrderBookHash(oldestOrder.QuoteToken, common.HexToAddress(common.TomoNativeAddress)))
 		}
-		tradedQuantity, rejectMaker, err := tomox.getTradeQuantity(quotePrice, coinbase, ipcEndpoint, statedb, order, &oldestOrder, maxTradedQuantity)
+		tradedQuantity, rejectMaker, settleBalance, err := tomox.getTradeQuantity(quotePrice, coinbase, ipcEndpoint, statedb, tomoXstatedb, order, &oldestOrder, maxTradedQuantity)
 		if err != nil && err == errQuantityTradeTooSmall {
 			if tradedQuantity.Cmp(maxTradedQuantity) == 0 {
 				if quantityToTrade.Cmp(amount) == 0 { // reject Taker & maker
-					rejects = append(rejects, order)
+					rejects = rejectOrder(rejects, order, RejectTradeTooSmall)
 					quantityToTrade = Zero()
-					rejects = append(rejects, &oldestOrder)
+					rejects = rejectOrder(rejects, &oldestOrder, RejectTradeTooSmall)
 					err = tomoXstatedb.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
 						return nil, nil, nil, err
 					}
 					break
 				} else if quantityToTrade.Cmp(amount) < 0 { // reject Taker
-					rejects = append(rejects, order)
+					rejects = rejectOrder(rejects, order, RejectTradeTooSmall)
 					quantityToTrade = Zero()
 					break
 				} else { // reject maker
-					rejects = append(rejects, &oldestOrder)
+					rejects = rejectOrder(rejects, &oldestOrder, RejectTradeTooSmall)
 					err = tomoXstatedb.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
 						return nil, nil, nil, err
@@ -245,14 +369,14 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 				}
 			} else {
 				if rejectMaker { // reject maker
-					rejects = append(rejects, &oldestOrder)
+					rejects = rejectOrder(rejects, &oldestOrder, RejectUnfunded)
 					err = tomoXstatedb.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
 						return nil, nil, nil, err
 					}
 					continue
 				} else { // reject Taker
-					rejects = append(rejects, order)
+					rejects = rejectOrder(rejects, order, RejectUnfunded)
 					quantityToTrade = Zero()
 					break
 				}
@@ -262,7 +386,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 		}
 		if tradedQuantity.Sign() == 0 && !rejectMaker {
 			log.Debug("Reject order Taker ", "tradedQuantity", tradedQuantity, "rejectMaker", rejectMaker)
-			rejects = append(rejects, order)
+			rejects = rejectOrder(rejects, order, RejectUnfunded)
 			quantityToTrade = Zero()
 			break
 		}
@@ -272,6 +396,7 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 			if oldestOrder.QuoteToken.String() == common.TomoNativeAddress {
 				tomoXstatedb.SetPrice(orderBook, price)
 			}
+			tomoXstatedb.AddTradeVolume(oldestOrder.BaseToken, oldestOrder.QuoteToken, price, tradedQuantity)
 			log.Debug("Update quantity for orderId", "orderId", orderId.Hex())
 			log.Debug("TRADE", "orderBook", orderBook, "Taker price", price, "maker price", order.Price, "Amount", tradedQuantity, "orderId", orderId, "side", side)
 
@@ -288,11 +413,15 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 			// Taker price is offer price
 			// tradedPrice is always actual price
 			transactionRecord[TradePrice] = oldestOrder.Price.String()
+			if settleBalance != nil {
+				transactionRecord[TradeTakerFee] = settleBalance.Taker.Fee.String()
+				transactionRecord[TradeMakerFee] = settleBalance.Maker.Fee.String()
+			}
 
 			trades = append(trades, transactionRecord)
 		}
 		if rejectMaker {
-			rejects = append(rejects, &oldestOrder)
+			rejects = rejectOrder(rejects, &oldestOrder, RejectUnfunded)
 			err := tomoXstatedb.CancelOrder(orderBook, &oldestOrder)
 			if err != nil {
 				return nil, nil, nil, err
@@ -302,14 +431,14 @@ func (tomox *TomoX) processOrderList(coinbase common.Address, ipcEndpoint string
 	return quantityToTrade, trades, rejects, nil
 }
 
-func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, takerOrder *tomox_state.OrderItem, makerOrder *tomox_state.OrderItem, quantityToTrade *big.Int) (*big.Int, bool, error) {
+func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, takerOrder *tomox_state.OrderItem, makerOrder *tomox_state.OrderItem, quantityToTrade *big.Int) (*big.Int, bool, *SettleBalance, error) {
 	baseTokenDecimal, err := tomox.GetTokenDecimal(ipcEndpoint, makerOrder.BaseToken)
 	if err != nil || baseTokenDecimal.Sign() == 0 {
-		return Zero(), false, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", makerOrder.BaseToken.String(), err)
+		return Zero(), false, nil, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", makerOrder.BaseToken.String(), err)
 	}
 	quoteTokenDecimal, err := tomox.GetTokenDecimal(ipcEndpoint, makerOrder.QuoteToken)
 	if err != nil || quoteTokenDecimal.Sign() == 0 {
-		return Zero(), false, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", makerOrder.QuoteToken.String(), err)
+		return Zero(), false, nil, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", makerOrder.QuoteToken.String(), err)
 	}
 	if makerOrder.QuoteToken.String() == common.TomoNativeAddress {
 		quotePrice = quoteTokenDecimal
@@ -317,20 +446,20 @@ func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Addres
 	if takerOrder.ExchangeAddress.String() == makerOrder.ExchangeAddress.String() {
 		if err := tomox_state.CheckRelayerFee(takerOrder.ExchangeAddress, new(big.Int).Mul(common.RelayerFee, big.NewInt(2)), statedb); err != nil {
 			log.Debug("Reject order Taker Exchnage = Maker Exchange , relayer not enough fee ", "err", err)
-			return Zero(), false, nil
+			return Zero(), false, nil, nil
 		}
 	} else {
 		if err := tomox_state.CheckRelayerFee(takerOrder.ExchangeAddress, common.RelayerFee, statedb); err != nil {
 			log.Debug("Reject order Taker , relayer not enough fee ", "err", err)
-			return Zero(), false, nil
+			return Zero(), false, nil, nil
 		}
 		if err := tomox_state.CheckRelayerFee(makerOrder.ExchangeAddress, common.RelayerFee, statedb); err != nil {
 			log.Debug("Reject order maker , relayer not enough fee ", "err", err)
-			return Zero(), true, nil
+			return Zero(), true, nil, nil
 		}
 	}
-	takerFeeRate := tomox_state.GetExRelayerFee(takerOrder.ExchangeAddress, statedb)
-	makerFeeRate := tomox_state.GetExRelayerFee(makerOrder.ExchangeAddress, statedb)
+	takerFeeRate := tomox_state.GetTradingFee(takerOrder.ExchangeAddress, tomoXstatedb, statedb)
+	makerFeeRate := tomox_state.GetTradingFee(makerOrder.ExchangeAddress, tomoXstatedb, statedb)
 	var takerBalance, makerBalance *big.Int
 	switch takerOrder.Side {
 	case Bid:
@@ -352,9 +481,9 @@ func (tomox *TomoX) getTradeQuantity(quotePrice *big.Int, coinbase common.Addres
 		if err == nil {
 			err = SetteBalance(coinbase, takerOrder, makerOrder, setteBalance, statedb)
 		}
-		return quantity, rejectMaker, err
+		return quantity, rejectMaker, setteBalance, err
 	}
-	return quantity, rejectMaker, nil
+	return quantity, rejectMaker, nil, nil
 }
 
 func GetTradeQuantity(takerSide string, takerFeeRate *big.Int, takerBalance *big.Int, makerPrice *big.Int, makerFeeRate *big.Int, makerBalance *big.Int, baseTokenDecimal *big.Int, quantityToTrade *big.Int) (*big.Int, bool) {
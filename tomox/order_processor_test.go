@@ -0,0 +1,311 @@
+package tomox
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestApplyOrderHaltedPair(t *testing.T) {
+	var (
+		maker = common.HexToAddress("0x0a")
+		taker = common.HexToAddress("0x0b")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	statedb.SetState(common.HexToAddress(common.TomoXPairHalt), vm.TomoXPairHaltSlot(base, quote), common.BigToHash(big.NewInt(1)))
+
+	tomox := &TomoX{}
+	orderBook := GetOrderBookHash(base, quote)
+	newOrder := func(user common.Address, side, orderType string, price int64) *tomox_state.OrderItem {
+		return &tomox_state.OrderItem{
+			UserAddress: user,
+			BaseToken:   base,
+			QuoteToken:  quote,
+			Side:        side,
+			Type:        orderType,
+			Price:       big.NewInt(price),
+			Quantity:    big.NewInt(10),
+			Nonce:       new(big.Int).SetUint64(tomoxState.GetNonce(user.Hash())),
+		}
+	}
+	// Crossing limit orders rest on both sides without trading
+	for _, order := range []*tomox_state.OrderItem{newOrder(maker, Ask, Limit, 100), newOrder(taker, Bid, Limit, 110)} {
+		trades, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, order)
+		if err != nil {
+			t.Fatalf("failed to apply %s order: %v", order.Side, err)
+		}
+		if len(trades) != 0 || len(rejects) != 0 {
+			t.Fatalf("%s order matched on a halted pair: %d trades, %d rejects", order.Side, len(trades), len(rejects))
+		}
+	}
+	if ask, _ := tomoxState.GetBestAskPrice(orderBook); ask.Int64() != 100 {
+		t.Errorf("best ask mismatch: have %v, want 100", ask)
+	}
+	if bid, _ := tomoxState.GetBestBidPrice(orderBook); bid.Int64() != 110 {
+		t.Errorf("best bid mismatch: have %v, want 110", bid)
+	}
+	// Market orders can't rest and are rejected
+	trades, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(taker, Bid, Market, 1))
+	if err != nil {
+		t.Fatalf("failed to apply market order: %v", err)
+	}
+	if len(trades) != 0 || len(rejects) != 1 {
+		t.Fatalf("market order on a halted pair: %d trades, %d rejects, want 0 and 1", len(trades), len(rejects))
+	}
+	if reason := rejects[0].RejectReason; reason != RejectPairHalted {
+		t.Errorf("reject reason mismatch: have %q, want %q", reason, RejectPairHalted)
+	}
+	if nonce := tomoxState.GetNonce(taker.Hash()); nonce != 2 {
+		t.Errorf("taker nonce mismatch: have %d, want 2", nonce)
+	}
+}
+
+func TestApplyOrderSelfTradePrevention(t *testing.T) {
+	var (
+		user  = common.HexToAddress("0x0a")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	tests := []struct {
+		mode             string
+		rejects          int
+		bestAsk, bestBid int64
+	}{
+		{tomox_state.CancelNewest, 1, 100, 0},
+		{tomox_state.CancelOldest, 1, 0, 110},
+		{tomox_state.CancelBoth, 2, 0, 0},
+	}
+	for _, tt := range tests {
+		db, _ := ethdb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+		tomox_state.UpgradeMatchingVersion(MatchingVersionSelfTrade, tomoxState)
+
+		tomox := &TomoX{}
+		orderBook := GetOrderBookHash(base, quote)
+		newOrder := func(side string, price int64, mode string) *tomox_state.OrderItem {
+			nonce := tomoxState.GetNonce(user.Hash())
+			return &tomox_state.OrderItem{
+				UserAddress:         user,
+				BaseToken:           base,
+				QuoteToken:          quote,
+				Side:                side,
+				Type:                Limit,
+				Price:               big.NewInt(price),
+				Quantity:            big.NewInt(10),
+				Nonce:               new(big.Int).SetUint64(nonce),
+				Hash:                common.BigToHash(new(big.Int).SetUint64(nonce + 1)),
+				SelfTradePrevention: mode,
+			}
+		}
+		if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(Ask, 100, "")); err != nil {
+			t.Fatalf("%s: failed to apply maker order: %v", tt.mode, err)
+		}
+		trades, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(Bid, 110, tt.mode))
+		if err != nil {
+			t.Fatalf("%s: failed to apply taker order: %v", tt.mode, err)
+		}
+		if len(trades) != 0 || len(rejects) != tt.rejects {
+			t.Errorf("%s: %d trades, %d rejects, want 0 and %d", tt.mode, len(trades), len(rejects), tt.rejects)
+		}
+		for _, reject := range rejects {
+			if reject.RejectReason != RejectSelfTrade {
+				t.Errorf("%s: reject reason mismatch: have %q, want %q", tt.mode, reject.RejectReason, RejectSelfTrade)
+			}
+		}
+		if ask, _ := tomoxState.GetBestAskPrice(orderBook); ask.Int64() != tt.bestAsk {
+			t.Errorf("%s: best ask mismatch: have %v, want %d", tt.mode, ask, tt.bestAsk)
+		}
+		if bid, _ := tomoxState.GetBestBidPrice(orderBook); bid.Int64() != tt.bestBid {
+			t.Errorf("%s: best bid mismatch: have %v, want %d", tt.mode, bid, tt.bestBid)
+		}
+	}
+}
+
+func TestApplyOrderPairSize(t *testing.T) {
+	var (
+		user  = common.HexToAddress("0x0a")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	tick, lot := vm.TomoXPairSizeSlots(base, quote)
+	statedb.SetState(common.HexToAddress(common.TomoXPairSize), tick, common.BigToHash(big.NewInt(5)))
+	statedb.SetState(common.HexToAddress(common.TomoXPairSize), lot, common.BigToHash(big.NewInt(100)))
+
+	tomox := &TomoX{}
+	orderBook := GetOrderBookHash(base, quote)
+	tests := []struct {
+		price, quantity int64
+		rejected        bool
+	}{
+		{price: 101, quantity: 100, rejected: true}, // off the tick
+		{price: 100, quantity: 150, rejected: true}, // off the lot
+		{price: 100, quantity: 50, rejected: true},  // below the lot
+		{price: 100, quantity: 200, rejected: false},
+	}
+	for i, tt := range tests {
+		order := &tomox_state.OrderItem{
+			UserAddress: user,
+			BaseToken:   base,
+			QuoteToken:  quote,
+			Side:        Ask,
+			Type:        Limit,
+			Price:       big.NewInt(tt.price),
+			Quantity:    big.NewInt(tt.quantity),
+			Nonce:       new(big.Int).SetUint64(tomoxState.GetNonce(user.Hash())),
+		}
+		_, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, order)
+		if err != nil {
+			t.Fatalf("test %d: failed to apply order: %v", i, err)
+		}
+		if rejected := len(rejects) == 1; rejected != tt.rejected {
+			t.Errorf("test %d: rejected mismatch: have %v, want %v", i, rejected, tt.rejected)
+		}
+	}
+	if nonce := tomoxState.GetNonce(user.Hash()); nonce != uint64(len(tests)) {
+		t.Errorf("user nonce mismatch: have %d, want %d", nonce, len(tests))
+	}
+	if ask, volume := tomoxState.GetBestAskPrice(orderBook); ask.Int64() != 100 || volume.Int64() != 200 {
+		t.Errorf("best ask mismatch: have %v/%v, want 100/200", ask, volume)
+	}
+}
+
+func TestApplyOrderAmend(t *testing.T) {
+	var (
+		user  = common.HexToAddress("0x0a")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	tomox_state.UpgradeMatchingVersion(MatchingVersionLifecycle, tomoxState)
+
+	tomox := &TomoX{}
+	orderBook := GetOrderBookHash(base, quote)
+	newOrder := func(status string, hash common.Hash, id uint64, price, quantity int64) *tomox_state.OrderItem {
+		return &tomox_state.OrderItem{
+			UserAddress: user,
+			BaseToken:   base,
+			QuoteToken:  quote,
+			Status:      status,
+			Side:        Ask,
+			Type:        Limit,
+			Price:       big.NewInt(price),
+			Quantity:    big.NewInt(quantity),
+			Nonce:       new(big.Int).SetUint64(tomoxState.GetNonce(user.Hash())),
+			Hash:        hash,
+			OrderID:     id,
+		}
+	}
+	for _, hash := range []common.Hash{{0x01}, {0x02}} {
+		if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusNew, hash, 0, 100, 10)); err != nil {
+			t.Fatalf("failed to apply order %x: %v", hash, err)
+		}
+	}
+	// Decreasing the quantity keeps the time priority
+	if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusAmended, common.Hash{0x01}, 1, 100, 4)); err != nil {
+		t.Fatalf("failed to amend quantity: %v", err)
+	}
+	if id, amount, _ := tomoxState.GetBestOrderIdAndAmount(orderBook, big.NewInt(100), Ask); id != common.BigToHash(big.NewInt(1)) || amount.Int64() != 4 {
+		t.Errorf("best order mismatch: have %x/%v, want 1/4", id, amount)
+	}
+	if ask, volume := tomoxState.GetBestAskPrice(orderBook); ask.Int64() != 100 || volume.Int64() != 14 {
+		t.Errorf("best ask mismatch: have %v/%v, want 100/14", ask, volume)
+	}
+	// Changing the price rests the order again under a new id
+	amend := newOrder(OrderStatusAmended, common.Hash{0x01}, 1, 105, 4)
+	if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, amend); err != nil {
+		t.Fatalf("failed to amend price: %v", err)
+	}
+	if amend.OrderID != 3 {
+		t.Errorf("amended order id mismatch: have %d, want 3", amend.OrderID)
+	}
+	if id, amount, _ := tomoxState.GetBestOrderIdAndAmount(orderBook, big.NewInt(100), Ask); id != common.BigToHash(big.NewInt(2)) || amount.Int64() != 10 {
+		t.Errorf("best order mismatch: have %x/%v, want 2/10", id, amount)
+	}
+	if volume := tomoxState.GetVolume(orderBook, big.NewInt(105), Ask); volume.Int64() != 4 {
+		t.Errorf("amended price volume mismatch: have %v, want 4", volume)
+	}
+	// Orders not resting under the given id and hash aren't amended
+	if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 1, orderBook, newOrder(OrderStatusAmended, common.Hash{0x01}, 1, 100, 1)); err != ErrAmendedOrderNotFound {
+		t.Errorf("amendment of a moved order: have %v, want %v", err, ErrAmendedOrderNotFound)
+	}
+	if nonce := tomoxState.GetNonce(user.Hash()); nonce != 4 {
+		t.Errorf("user nonce mismatch: have %d, want 4", nonce)
+	}
+}
+
+func TestApplyOrderExpiry(t *testing.T) {
+	var (
+		user  = common.HexToAddress("0x0a")
+		base  = common.HexToAddress("0x1001")
+		quote = common.HexToAddress("0x1002")
+	)
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox_state.NewDatabase(db))
+	tomox_state.UpgradeMatchingVersion(MatchingVersionLifecycle, tomoxState)
+
+	tomox := &TomoX{}
+	orderBook := GetOrderBookHash(base, quote)
+	newOrder := func(expiry uint64) *tomox_state.OrderItem {
+		nonce := tomoxState.GetNonce(user.Hash())
+		return &tomox_state.OrderItem{
+			UserAddress: user,
+			BaseToken:   base,
+			QuoteToken:  quote,
+			Side:        Bid,
+			Type:        Limit,
+			Price:       big.NewInt(100),
+			Quantity:    big.NewInt(10),
+			Nonce:       new(big.Int).SetUint64(nonce),
+			Hash:        common.BigToHash(new(big.Int).SetUint64(nonce + 1)),
+			ExpiryBlock: expiry,
+		}
+	}
+	expiring := newOrder(10)
+	expiring.Price = big.NewInt(90)
+	if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 10, orderBook, expiring); err != nil {
+		t.Fatalf("failed to apply order expiring at 10: %v", err)
+	}
+	if pruned := tomox_state.PruneExpiredOrders(10, tomoxState); len(pruned) != 1 {
+		t.Fatalf("pruned orders mismatch: have %d, want 1", len(pruned))
+	}
+	// Orders are rejected past their expiry block, whether the orders expiring
+	// at it were pruned already or not
+	for _, tt := range []struct{ expiry, number uint64 }{{10, 11}, {15, 20}} {
+		_, rejects, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, tt.number, orderBook, newOrder(tt.expiry))
+		if err != nil {
+			t.Fatalf("failed to apply order expiring at %d: %v", tt.expiry, err)
+		}
+		if len(rejects) != 1 || rejects[0].RejectReason != RejectExpired {
+			t.Fatalf("order expiring at %d not rejected at %d: %d rejects", tt.expiry, tt.number, len(rejects))
+		}
+	}
+	// Resting orders are indexed at their expiry, the ones without none
+	for _, expiry := range []uint64{11, 0} {
+		if _, _, err := tomox.ApplyOrder(common.Address{}, "", statedb, tomoxState, 11, orderBook, newOrder(expiry)); err != nil {
+			t.Fatalf("failed to apply order expiring at %d: %v", expiry, err)
+		}
+	}
+	if orders := tomoxState.GetRestingExpiringOrders(11, 100); len(orders) != 1 || orders[0].OrderID != 2 {
+		t.Fatalf("expiring orders mismatch: have %+v, want order 2", orders)
+	}
+	tomox_state.PruneExpiredOrders(11, tomoxState)
+	if bid, volume := tomoxState.GetBestBidPrice(orderBook); bid.Int64() != 100 || volume.Int64() != 10 {
+		t.Errorf("best bid mismatch: have %v/%v, want 100/10", bid, volume)
+	}
+}
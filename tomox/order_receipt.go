@@ -0,0 +1,90 @@
+package tomox
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// OrderReceipt is the execution result of an order by the matching engine.
+type OrderReceipt struct {
+	OrderHash    common.Hash         `json:"orderHash"`
+	UserAddress  common.Address      `json:"userAddress"`
+	Nonce        *big.Int            `json:"nonce"`
+	BlockHash    common.Hash         `json:"blockHash"`
+	BlockNumber  uint64              `json:"blockNumber"`
+	TxHash       common.Hash         `json:"transactionHash"` // Matching transaction carrying the order
+	Status       string              `json:"status"`
+	FilledAmount *big.Int            `json:"filledAmount"`
+	RejectReason string              `json:"rejectReason,omitempty"`
+	Trades       []map[string]string `json:"trades"`
+}
+
+// NewOrderReceipts returns the receipts of the orders matched by the matching
+// transactions of a block.
+func NewOrderReceipts(block *types.Block) []*OrderReceipt {
+	var receipts []*OrderReceipt
+	for _, tx := range block.Transactions() {
+		if !tx.IsMatchingTransaction() {
+			continue
+		}
+		batch, err := DecodeTxMatchesBatch(tx.Data())
+		if err != nil {
+			log.Debug("Skipping corrupted matching transaction", "hash", tx.Hash(), "err", err)
+			continue
+		}
+		for _, txMatch := range batch.Data {
+			order, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			receipt := newOrderReceipt(order, txMatch)
+			receipt.BlockHash = block.Hash()
+			receipt.BlockNumber = block.NumberU64()
+			receipt.TxHash = tx.Hash()
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts
+}
+
+// newOrderReceipt derives the status of an order from its matching result.
+func newOrderReceipt(order *tomox_state.OrderItem, txMatch TxDataMatch) *OrderReceipt {
+	receipt := &OrderReceipt{
+		OrderHash:    order.Hash,
+		UserAddress:  order.UserAddress,
+		Nonce:        order.Nonce,
+		FilledAmount: Zero(),
+		Trades:       txMatch.GetTrades(),
+	}
+	if receipt.Trades == nil {
+		receipt.Trades = []map[string]string{}
+	}
+	for _, trade := range receipt.Trades {
+		receipt.FilledAmount = Add(receipt.FilledAmount, ToBigInt(trade[TradeQuantity]))
+	}
+	for _, reject := range txMatch.GetRejectedOrders() {
+		if reject.Hash == order.Hash {
+			receipt.Status = OrderStatusRejected
+			receipt.RejectReason = reject.RejectReason
+			return receipt
+		}
+	}
+	switch {
+	case order.Status == OrderStatusCancelled:
+		receipt.Status = OrderStatusCancelled
+	case order.Quantity != nil && receipt.FilledAmount.Cmp(order.Quantity) >= 0:
+		receipt.Status = OrderStatusFilled
+	case receipt.FilledAmount.Sign() > 0:
+		receipt.Status = OrderStatusPartialFilled
+	case order.Type == Market:
+		// Market orders don't rest in the order book, unfilled they are dropped
+		receipt.Status = OrderStatusCancelled
+	default:
+		receipt.Status = OrderStatusOpen
+	}
+	return receipt
+}
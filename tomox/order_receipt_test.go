@@ -0,0 +1,85 @@
+package tomox
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestOrderReceipts(t *testing.T) {
+	sig := &tomox_state.Signature{V: 27, R: common.Hash{0x01}, S: common.Hash{0x02}}
+	newOrder := func(hash byte, orderType, status string) *tomox_state.OrderItem {
+		return &tomox_state.OrderItem{
+			Hash:        common.Hash{hash},
+			UserAddress: common.Address{hash},
+			Quantity:    big.NewInt(10),
+			Price:       big.NewInt(100),
+			Nonce:       big.NewInt(int64(hash)),
+			Side:        tomox_state.Bid,
+			Type:        orderType,
+			Status:      status,
+			Signature:   sig,
+		}
+	}
+	trade := func(quantity string) map[string]string {
+		return map[string]string{TradeQuantity: quantity}
+	}
+	tests := []struct {
+		order   *tomox_state.OrderItem
+		trades  []map[string]string
+		rejects []*tomox_state.OrderItem
+		status  string
+		filled  int64
+		reason  string
+	}{
+		{newOrder(1, Limit, OrderStatusNew), []map[string]string{trade("4"), trade("6")}, nil, OrderStatusFilled, 10, ""},
+		{newOrder(2, Limit, OrderStatusNew), []map[string]string{trade("4")}, nil, OrderStatusPartialFilled, 4, ""},
+		{newOrder(3, Limit, OrderStatusNew), nil, nil, OrderStatusOpen, 0, ""},
+		{newOrder(4, Market, OrderStatusNew), nil, nil, OrderStatusCancelled, 0, ""},
+		{newOrder(5, Limit, OrderStatusCancelled), nil, nil, OrderStatusCancelled, 0, ""},
+		{newOrder(6, Limit, OrderStatusNew), []map[string]string{trade("3")}, []*tomox_state.OrderItem{
+			{Hash: common.Hash{0xff}, RejectReason: RejectUnfunded},
+			{Hash: common.Hash{6}, RejectReason: RejectTradeTooSmall},
+		}, OrderStatusRejected, 3, RejectTradeTooSmall},
+	}
+	var batch TxMatchBatch
+	for _, tt := range tests {
+		enc, err := EncodeBytesItem(tt.order)
+		if err != nil {
+			t.Fatalf("failed to encode order: %v", err)
+		}
+		batch.Data = append(batch.Data, TxDataMatch{Order: enc, Trades: tt.trades, RejectedOders: tt.rejects})
+	}
+	data, _ := EncodeTxMatchesBatch(batch)
+	tx := types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, data)
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7)}, []*types.Transaction{tx}, nil, nil)
+
+	receipts := NewOrderReceipts(block)
+	if len(receipts) != len(tests) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), len(tests))
+	}
+	for i, tt := range tests {
+		receipt := receipts[i]
+		if receipt.OrderHash != tt.order.Hash || receipt.UserAddress != tt.order.UserAddress {
+			t.Errorf("order %d: receipt of order %x by %x", i, receipt.OrderHash, receipt.UserAddress)
+		}
+		if receipt.BlockHash != block.Hash() || receipt.BlockNumber != 7 || receipt.TxHash != tx.Hash() {
+			t.Errorf("order %d: receipt position mismatch: block %x #%d, tx %x", i, receipt.BlockHash, receipt.BlockNumber, receipt.TxHash)
+		}
+		if receipt.Status != tt.status {
+			t.Errorf("order %d: status mismatch: have %s, want %s", i, receipt.Status, tt.status)
+		}
+		if receipt.FilledAmount.Int64() != tt.filled {
+			t.Errorf("order %d: filled amount mismatch: have %v, want %d", i, receipt.FilledAmount, tt.filled)
+		}
+		if receipt.RejectReason != tt.reason {
+			t.Errorf("order %d: reject reason mismatch: have %q, want %q", i, receipt.RejectReason, tt.reason)
+		}
+		if len(receipt.Trades) != len(tt.trades) {
+			t.Errorf("order %d: trade count mismatch: have %d, want %d", i, len(receipt.Trades), len(tt.trades))
+		}
+	}
+}
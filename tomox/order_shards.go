@@ -0,0 +1,255 @@
+package tomox
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// minShardedOrders is the number of orders below which matching them
+// concurrently does not pay for copying the states.
+const minShardedOrders = 16
+
+// matchingThreads is the number of order shards matched concurrently.
+var matchingThreads = runtime.NumCPU()
+
+// orderShards splits n orders into at most matchingThreads shards which can be
+// matched independently: orders of a same pair or of a same user, whose nonce
+// orders them across pairs, always end up in the same shard. Shards list the
+// indexes of their orders in increasing order, and are sorted by their first
+// order. Nil is returned when the orders can't be split in two shards or
+// are too few to be worth it.
+func orderShards(n int, pair func(i int) common.Hash, user func(i int) common.Address) [][]int {
+	if n < minShardedOrders || matchingThreads < 2 {
+		return nil
+	}
+	// Link the orders sharing a pair or a user
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj {
+			// Keep the lowest index as root, for groups to be ordered by their first order
+			if ri < rj {
+				parent[rj] = ri
+			} else {
+				parent[ri] = rj
+			}
+		}
+	}
+	var (
+		pairs = make(map[common.Hash]int)
+		users = make(map[common.Address]int)
+	)
+	for i := 0; i < n; i++ {
+		if j, ok := pairs[pair(i)]; ok {
+			union(i, j)
+		} else {
+			pairs[pair(i)] = i
+		}
+		if j, ok := users[user(i)]; ok {
+			union(i, j)
+		} else {
+			users[user(i)] = i
+		}
+	}
+	var (
+		groups [][]int
+		index  = make(map[int]int)
+	)
+	for i := 0; i < n; i++ {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	if len(groups) < 2 {
+		return nil
+	}
+	// Spread the groups over the shards, each going to the least loaded one
+	shards := make([][]int, matchingThreads)
+	if len(groups) < len(shards) {
+		shards = shards[:len(groups)]
+	}
+	for _, group := range groups {
+		least := 0
+		for s := range shards {
+			if len(shards[s]) < len(shards[least]) {
+				least = s
+			}
+		}
+		shards[least] = append(shards[least], group...)
+	}
+	for _, shard := range shards {
+		sort.Ints(shard)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i][0] < shards[j][0] })
+	return shards
+}
+
+// shardStates holds the copies of the states a shard of orders is matched on.
+type shardStates struct {
+	statedb      *state.StateDB
+	tomoxStatedb *tomox_state.TomoXStateDB
+}
+
+// runShards runs the given function on copies of the states for every shard
+// concurrently, and merges the changes of the copies back into the states in
+// the order of the shards. Nothing is merged and false is returned if the
+// shards turn out to have accessed the same parts of the states, in which case
+// the function has to be run on the states in the original order of the orders.
+func runShards(statedb *state.StateDB, tomoxStatedb *tomox_state.TomoXStateDB, shards [][]int, run func(shard int, statedb *state.StateDB, tomoxStatedb *tomox_state.TomoXStateDB)) bool {
+	copies := make([]shardStates, len(shards))
+	for i := range copies {
+		copies[i] = shardStates{statedb.Copy(), tomoxStatedb.Copy()}
+		copies[i].statedb.TrackAccesses()
+		copies[i].tomoxStatedb.TrackAccesses()
+	}
+	var wg sync.WaitGroup
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			run(i, copies[i].statedb, copies[i].tomoxStatedb)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range copies {
+		for j := i + 1; j < len(copies); j++ {
+			if copies[i].statedb.ConflictsWith(copies[j].statedb) || copies[i].tomoxStatedb.ConflictsWith(copies[j].tomoxStatedb) {
+				log.Debug("Order shards conflict, matching them in turn", "shards", len(shards), "first", i, "second", j)
+				return false
+			}
+		}
+	}
+	for _, states := range copies {
+		statedb.MergeWrites(states.statedb)
+		tomoxStatedb.MergeWrites(states.tomoxStatedb)
+	}
+	return true
+}
+
+// ApplyOrders verifies and applies in turn the orders of a matching
+// transaction of the block with the given number on top of the given states, as
+// ApplyOrder does, and returns their trades in matching order. Orders of independent pairs are matched
+// concurrently, with the same outcome.
+func (tomox *TomoX) ApplyOrders(coinbase common.Address, ipcEndpoint string, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, number uint64, orders []*tomox_state.OrderItem) (types.Trades, error) {
+	results := make([][]map[string]string, len(orders))
+	apply := func(statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, i int) error {
+		order := orders[i]
+		log.Debug("process tx match", "order", order)
+		if err := order.VerifyOrder(statedb); err != nil {
+			return fmt.Errorf("invalid order . Error: %v", err)
+		}
+		trades, _, err := tomox.ApplyOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, number, GetOrderBookHash(order.BaseToken, order.QuoteToken), order)
+		results[i] = trades
+		return err
+	}
+	shards := orderShards(len(orders),
+		func(i int) common.Hash { return GetOrderBookHash(orders[i].BaseToken, orders[i].QuoteToken) },
+		func(i int) common.Address { return orders[i].UserAddress },
+	)
+	if shards != nil {
+		// Orders after the first failing one of a shard are not applied, the
+		// failing order of the whole batch is the first failing one of all
+		errs := make([]error, len(shards))
+		failed := make([]int, len(shards))
+		merged := runShards(statedb, tomoXstatedb, shards, func(shard int, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB) {
+			for _, i := range shards[shard] {
+				if err := apply(statedb, tomoXstatedb, i); err != nil {
+					errs[shard], failed[shard] = err, i
+					return
+				}
+			}
+		})
+		if merged {
+			first := -1
+			for shard, err := range errs {
+				if err != nil && (first < 0 || failed[shard] < failed[first]) {
+					first = shard
+				}
+			}
+			if first >= 0 {
+				return nil, errs[first]
+			}
+			return NewTrades(results...), nil
+		}
+	}
+	for i := range orders {
+		if err := apply(statedb, tomoXstatedb, i); err != nil {
+			return nil, err
+		}
+	}
+	return NewTrades(results...), nil
+}
+
+// ProcessOrderPending matches the pending orders on top of the given states,
+// within the given budget, returning the matching results to be included in the
+// block with the given number. Orders of users trading independent pairs are matched concurrently,
+// their results following each other shard by shard.
+func (tomox *TomoX) ProcessOrderPending(coinbase common.Address, ipcEndpoint string, pending map[common.Address]types.OrderTransactions, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, number uint64, budget OrderBudget) []TxDataMatch {
+	// Index the pending orders by user, in a deterministic order
+	var (
+		senders []common.Address
+		owners  []int
+		pairs   []common.Hash
+	)
+	for sender := range pending {
+		senders = append(senders, sender)
+	}
+	sort.Slice(senders, func(i, j int) bool { return bytes.Compare(senders[i][:], senders[j][:]) < 0 })
+	if budget.MaxOrders > 0 {
+		pending = limitOrders(pending, senders, budget.MaxOrders)
+	}
+	for s, sender := range senders {
+		for _, tx := range pending[sender] {
+			owners = append(owners, s)
+			pairs = append(pairs, GetOrderBookHash(tx.BaseToken(), tx.QuoteToken()))
+		}
+	}
+	shards := orderShards(len(owners),
+		func(i int) common.Hash { return pairs[i] },
+		func(i int) common.Address { return senders[owners[i]] },
+	)
+	if shards != nil {
+		results := make([][]TxDataMatch, len(shards))
+		merged := runShards(statedb, tomoXstatedb, shards, func(shard int, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB) {
+			subset := make(map[common.Address]types.OrderTransactions)
+			for _, i := range shards[shard] {
+				if sender := senders[owners[i]]; subset[sender] == nil {
+					subset[sender] = pending[sender]
+				}
+			}
+			results[shard] = tomox.processOrderPending(coinbase, ipcEndpoint, subset, statedb, tomoXstatedb, number, budget)
+		})
+		if merged {
+			txMatches := []TxDataMatch{}
+			for _, result := range results {
+				txMatches = append(txMatches, result...)
+			}
+			return txMatches
+		}
+	}
+	return tomox.processOrderPending(coinbase, ipcEndpoint, pending, statedb, tomoXstatedb, number, budget)
+}
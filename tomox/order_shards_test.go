@@ -0,0 +1,46 @@
+package tomox
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestOrderShards(t *testing.T) {
+	defer func(threads int) { matchingThreads = threads }(matchingThreads)
+	matchingThreads = 3
+
+	// Orders of pairs 0-4 by users 0-5: pairs 0 and 1 are linked by user 1,
+	// pairs 2 and 4 by user 3, pair 3 is on its own
+	orders := []struct{ pair, user byte }{
+		{0, 0}, {1, 1}, {2, 2}, {0, 1}, {3, 4}, {4, 3}, {2, 3}, {3, 5},
+		{0, 0}, {1, 1}, {2, 2}, {0, 1}, {3, 4}, {4, 3}, {2, 3}, {3, 5},
+	}
+	pair := func(i int) common.Hash { return common.Hash{orders[i].pair} }
+	user := func(i int) common.Address { return common.Address{orders[i].user} }
+
+	want := [][]int{
+		{0, 1, 3, 8, 9, 11},
+		{2, 5, 6, 10, 13, 14},
+		{4, 7, 12, 15},
+	}
+	if shards := orderShards(len(orders), pair, user); !reflect.DeepEqual(shards, want) {
+		t.Errorf("shards mismatch: have %v, want %v", shards, want)
+	}
+	// Too few orders, or a single group of them, are not sharded
+	if shards := orderShards(minShardedOrders-1, pair, user); shards != nil {
+		t.Errorf("too few orders sharded: %v", shards)
+	}
+	single := func(i int) common.Address { return common.Address{} }
+	if shards := orderShards(len(orders), pair, single); shards != nil {
+		t.Errorf("orders of a single user sharded: %v", shards)
+	}
+	// Groups are spread over the available threads
+	matchingThreads = 2
+	for _, shard := range orderShards(len(orders), pair, user) {
+		if len(shard) < 6 {
+			t.Errorf("unbalanced shard: %v", shard)
+		}
+	}
+}
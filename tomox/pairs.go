@@ -0,0 +1,118 @@
+package tomox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// pairListedPrefix prefixes the keys of the blocks the pairs were first seen
+// listed at, followed by the relayer, base and quote tokens.
+var pairListedPrefix = []byte("pair-listed-")
+
+func pairListedKey(relayer, baseToken, quoteToken common.Address) []byte {
+	key := append(append([]byte{}, pairListedPrefix...), relayer.Bytes()...)
+	return append(append(key, baseToken.Bytes()...), quoteToken.Bytes()...)
+}
+
+// Pair is a trading pair listed by a relayer in the relayer registration
+// contract.
+type Pair struct {
+	BaseToken   common.Address
+	QuoteToken  common.Address
+	Relayer     common.Address
+	ListedBlock uint64 // Block the pair was first seen listed at, zero if listed before the pairs were tracked
+}
+
+// OrderBook returns the hash of the order book of the pair.
+func (p Pair) OrderBook() common.Hash {
+	return GetOrderBookHash(p.BaseToken, p.QuoteToken)
+}
+
+// UpdatePairs refreshes the cached trading pairs from the relayer registration
+// contract in the state of a block, when the block calls the contract or the
+// pairs were never loaded. The block is recorded as the listing block of the
+// pairs seen for the first time.
+func (tomox *TomoX) UpdatePairs(block *types.Block, statedb *state.StateDB) {
+	tomox.pairsLock.Lock()
+	defer tomox.pairsLock.Unlock()
+
+	registration := common.HexToAddress(common.RelayerRegistrationSMC)
+	if tomox.pairs != nil {
+		called := false
+		for _, tx := range block.Transactions() {
+			if to := tx.To(); to != nil && *to == registration {
+				called = true
+				break
+			}
+		}
+		if !called {
+			return
+		}
+	}
+	var (
+		initial = tomox.pairs == nil
+		known   = make(map[Pair]struct{})
+		pairs   = []Pair{}
+		batch   = tomox.db.NewBatch()
+	)
+	for _, pair := range tomox.pairs {
+		known[pair] = struct{}{}
+	}
+	for _, relayer := range tomox_state.GetCoinbaseList(statedb) {
+		if !tomox_state.IsValidRelayer(statedb, relayer) {
+			continue
+		}
+		count := tomox_state.GetBaseTokenLength(relayer, statedb)
+		if quotes := tomox_state.GetQuoteTokenLength(relayer, statedb); quotes < count {
+			count = quotes
+		}
+		for i := uint64(0); i < count; i++ {
+			pair := Pair{
+				BaseToken:  tomox_state.GetBaseTokenAtIndex(relayer, statedb, i),
+				QuoteToken: tomox_state.GetQuoteTokenAtIndex(relayer, statedb, i),
+				Relayer:    relayer,
+			}
+			key := pairListedKey(relayer, pair.BaseToken, pair.QuoteToken)
+			if enc, err := tomox.db.Get(key); err == nil && len(enc) == 8 {
+				pair.ListedBlock = binary.BigEndian.Uint64(enc)
+			} else if _, ok := known[pair]; !ok && !initial {
+				// New pair, the ones found when first loading the pairs were
+				// listed at some unknown earlier block
+				pair.ListedBlock = block.NumberU64()
+				enc := make([]byte, 8)
+				binary.BigEndian.PutUint64(enc, pair.ListedBlock)
+				batch.Put(key, enc)
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write pair listing blocks", "block", block.Number(), "err", err)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if c := bytes.Compare(pairs[i].Relayer[:], pairs[j].Relayer[:]); c != 0 {
+			return c < 0
+		}
+		if c := bytes.Compare(pairs[i].BaseToken[:], pairs[j].BaseToken[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(pairs[i].QuoteToken[:], pairs[j].QuoteToken[:]) < 0
+	})
+	tomox.pairs = pairs
+}
+
+// GetPairs returns the trading pairs cached by UpdatePairs, nil if they were
+// never loaded.
+func (tomox *TomoX) GetPairs() []Pair {
+	tomox.pairsLock.RLock()
+	defer tomox.pairsLock.RUnlock()
+
+	return tomox.pairs
+}
@@ -0,0 +1,84 @@
+package tomox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// listPair registers a relayer in the relayer registration contract if needed
+// and appends a pair to its token lists.
+func listPair(statedb *state.StateDB, relayer, baseToken, quoteToken common.Address) {
+	registration := common.HexToAddress(common.RelayerRegistrationSMC)
+	loc := tomox_state.GetLocMappingAtKey(relayer.Hash(), tomox_state.RelayerMappingSlot["RELAYER_LIST"])
+	if !tomox_state.IsValidRelayer(statedb, relayer) {
+		countSlot := common.BigToHash(new(big.Int).SetUint64(tomox_state.RelayerMappingSlot["RelayerCount"]))
+		count := statedb.GetState(registration, countSlot).Big()
+		coinbaseLoc := tomox_state.GetLocMappingAtKey(common.BigToHash(count), tomox_state.RelayerMappingSlot["RELAYER_COINBASES"])
+		statedb.SetState(registration, common.BigToHash(coinbaseLoc), relayer.Hash())
+		statedb.SetState(registration, countSlot, common.BigToHash(new(big.Int).Add(count, common.Big1)))
+		statedb.SetState(registration, common.BigToHash(loc), common.BigToHash(big.NewInt(1)))
+	}
+	for slot, token := range map[string]common.Address{"_fromTokens": baseToken, "_toTokens": quoteToken} {
+		lengthLoc := common.BigToHash(new(big.Int).Add(loc, tomox_state.RelayerStructMappingSlot[slot]))
+		length := statedb.GetState(registration, lengthLoc).Big().Uint64()
+		statedb.SetState(registration, state.GetLocDynamicArrAtElement(lengthLoc, length, 1), token.Hash())
+		statedb.SetState(registration, lengthLoc, common.BigToHash(new(big.Int).SetUint64(length+1)))
+	}
+}
+
+func TestUpdatePairs(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-pairs-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+	tomox := New(&Config{DataDir: datadir})
+	defer tomox.db.Close()
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		relayer         = common.Address{0x0f}
+		tomo, btc, eth  = common.HexToAddress(common.TomoNativeAddress), common.Address{0xb7}, common.Address{0xe7}
+		registration    = common.HexToAddress(common.RelayerRegistrationSMC)
+		registrationTxs = []*types.Transaction{types.NewTransaction(0, registration, common.Big0, 0, common.Big0, nil)}
+	)
+	listPair(statedb, relayer, btc, tomo)
+
+	if pairs := tomox.GetPairs(); pairs != nil {
+		t.Fatalf("pairs loaded before any block: %v", pairs)
+	}
+	// Pairs found when first loading them have no known listing block
+	tomox.UpdatePairs(types.NewBlock(&types.Header{Number: big.NewInt(10)}, nil, nil, nil), statedb)
+	want := []Pair{{BaseToken: btc, QuoteToken: tomo, Relayer: relayer}}
+	if pairs := tomox.GetPairs(); len(pairs) != 1 || pairs[0] != want[0] {
+		t.Fatalf("initial pairs mismatch: have %v, want %v", pairs, want)
+	}
+	// Blocks not calling the registration contract don't refresh the pairs
+	listPair(statedb, relayer, eth, tomo)
+	tomox.UpdatePairs(types.NewBlock(&types.Header{Number: big.NewInt(11)}, nil, nil, nil), statedb)
+	if pairs := tomox.GetPairs(); len(pairs) != 1 {
+		t.Fatalf("pairs refreshed without registration call: %v", pairs)
+	}
+	tomox.UpdatePairs(types.NewBlock(&types.Header{Number: big.NewInt(12)}, registrationTxs, nil, nil), statedb)
+	want = append(want, Pair{BaseToken: eth, QuoteToken: tomo, Relayer: relayer, ListedBlock: 12})
+	pairs := tomox.GetPairs()
+	if len(pairs) != 2 || pairs[0] != want[0] || pairs[1] != want[1] {
+		t.Fatalf("refreshed pairs mismatch: have %v, want %v", pairs, want)
+	}
+	// Listing blocks survive a reload
+	tomox.pairs = nil
+	tomox.UpdatePairs(types.NewBlock(&types.Header{Number: big.NewInt(20)}, nil, nil, nil), statedb)
+	if pairs := tomox.GetPairs(); len(pairs) != 2 || pairs[1] != want[1] {
+		t.Fatalf("reloaded pairs mismatch: have %v, want %v", pairs, want)
+	}
+}
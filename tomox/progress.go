@@ -0,0 +1,92 @@
+package tomox
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// processedKey is the key of the last block the TomoX state was processed at.
+var processedKey = []byte("tomox-processed")
+
+// SyncProgress gives progress indications when the TomoX state is being
+// rebuilt from the imported blocks, e.g. after a fast sync.
+type SyncProgress struct {
+	ProcessedBlock uint64      // Last block the TomoX state was processed at
+	ProcessedRoot  common.Hash // TomoX state root at the last processed block
+	PendingPairs   uint64      // Listed pairs without order book in the processed state yet
+}
+
+// processedBlock is the RLP layout of the last processed block.
+type processedBlock struct {
+	Number uint64
+	Root   common.Hash
+}
+
+// MarkProcessed records a block as the last one the TomoX state was processed
+// at, with the root of its TomoX state.
+func (tomox *TomoX) MarkProcessed(block *types.Block, root common.Hash) {
+	tomox.progressLock.Lock()
+	defer tomox.progressLock.Unlock()
+
+	tomox.processed = &processedBlock{Number: block.NumberU64(), Root: root}
+	enc, _ := rlp.EncodeToBytes(tomox.processed)
+	if err := tomox.db.Put(processedKey, enc); err != nil {
+		log.Error("Failed to store processed TomoX block", "block", block.Number(), "err", err)
+	}
+}
+
+// lastProcessed returns the last processed block, loading it from the
+// database after a restart. It returns nil if no block was ever processed.
+func (tomox *TomoX) lastProcessed() *processedBlock {
+	tomox.progressLock.Lock()
+	defer tomox.progressLock.Unlock()
+
+	if tomox.processed == nil {
+		enc, err := tomox.db.Get(processedKey)
+		if err != nil || len(enc) == 0 {
+			return nil
+		}
+		processed := new(processedBlock)
+		if err := rlp.DecodeBytes(enc, processed); err != nil {
+			log.Error("Failed to decode processed TomoX block", "err", err)
+			return nil
+		}
+		tomox.processed = processed
+	}
+	return tomox.processed
+}
+
+// Progress returns how far the TomoX state was rebuilt: the last block it was
+// processed at, and the number of listed pairs whose order books are not yet
+// present in the state of that block. All the pairs are pending if the state
+// is not available.
+func (tomox *TomoX) Progress() SyncProgress {
+	var (
+		progress   SyncProgress
+		orderBooks = make(map[common.Hash]struct{})
+	)
+	for _, pair := range tomox.GetPairs() {
+		orderBooks[pair.OrderBook()] = struct{}{}
+	}
+	processed := tomox.lastProcessed()
+	if processed == nil {
+		progress.PendingPairs = uint64(len(orderBooks))
+		return progress
+	}
+	progress.ProcessedBlock, progress.ProcessedRoot = processed.Number, processed.Root
+
+	tomoxState, err := tomox_state.New(processed.Root, tomox.StateCache)
+	if err != nil {
+		progress.PendingPairs = uint64(len(orderBooks))
+		return progress
+	}
+	for orderBook := range orderBooks {
+		if !tomoxState.Exist(orderBook) {
+			progress.PendingPairs++
+		}
+	}
+	return progress
+}
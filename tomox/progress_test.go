@@ -0,0 +1,58 @@
+package tomox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestProgress(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-progress-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+	tomox := New(&Config{DataDir: datadir})
+
+	var (
+		tomo, btc, eth = common.HexToAddress(common.TomoNativeAddress), common.Address{0xb7}, common.Address{0xe7}
+		btcPair        = Pair{BaseToken: btc, QuoteToken: tomo, Relayer: common.Address{0x0f}}
+		ethPair        = Pair{BaseToken: eth, QuoteToken: tomo, Relayer: common.Address{0x0f}}
+	)
+	tomox.pairs = []Pair{btcPair, ethPair, {BaseToken: btc, QuoteToken: tomo, Relayer: common.Address{0x0e}}}
+
+	if progress := tomox.Progress(); progress.ProcessedBlock != 0 || progress.PendingPairs != 2 {
+		t.Fatalf("progress mismatch before any block: %+v", progress)
+	}
+	// Only the pairs with an order book in the processed state are rebuilt
+	tomoxState, _ := tomox_state.New(common.Hash{}, tomox.StateCache)
+	order := tomox_state.OrderItem{OrderID: 1, Quantity: big.NewInt(1), Price: big.NewInt(1), Side: tomox_state.Ask, Hash: common.Hash{0x01}}
+	tomoxState.InsertOrderItem(btcPair.OrderBook(), common.BigToHash(big.NewInt(1)), order)
+	root, err := tomoxState.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit TomoX state: %v", err)
+	}
+	if err := tomox.StateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush TomoX state: %v", err)
+	}
+	tomox.MarkProcessed(types.NewBlock(&types.Header{Number: big.NewInt(42)}, nil, nil, nil), root)
+
+	want := SyncProgress{ProcessedBlock: 42, ProcessedRoot: root, PendingPairs: 1}
+	if progress := tomox.Progress(); progress != want {
+		t.Fatalf("progress mismatch: have %+v, want %+v", progress, want)
+	}
+	tomox.db.Close()
+
+	// The processed block is kept across restarts
+	tomox = New(&Config{DataDir: datadir})
+	defer tomox.db.Close()
+	tomox.pairs = []Pair{btcPair, ethPair}
+	if progress := tomox.Progress(); progress != want {
+		t.Fatalf("reloaded progress mismatch: have %+v, want %+v", progress, want)
+	}
+}
@@ -113,9 +113,11 @@ func (tomox *TomoX) SettleBalance(
 	return result, nil
 }
 
+// GetTokenDecimal returns the decimal of a token, as the power of ten of its
+// decimals, querying its contract over IPC only the first time.
 func (tomox *TomoX) GetTokenDecimal(ipcEndpoint string,tokenAddr common.Address) (*big.Int, error) {
-	if tokenDecimal, ok := tomox.tokenDecimalCache.Get(tokenAddr); ok {
-		return tokenDecimal.(*big.Int), nil
+	if tokenDecimal, ok := tomox.CachedTokenDecimal(tokenAddr); ok {
+		return tokenDecimal, nil
 	}
 	if tokenAddr.String() == common.TomoNativeAddress {
 		tomox.tokenDecimalCache.Add(tokenAddr, common.BasePrice)
@@ -136,6 +138,12 @@ func (tomox *TomoX) GetTokenDecimal(ipcEndpoint string,tokenAddr common.Address)
 		return nil, err
 	}
 	tokenDecimal := new(big.Int).SetUint64(0).Exp(big.NewInt(10), big.NewInt(int64(decimal)), nil)
-	tomox.tokenDecimalCache.Add(tokenAddr, tokenDecimal)
+	tomox.storeTokenDecimal(tokenAddr, tokenDecimal)
 	return tokenDecimal, nil
 }
+
+// SetTokenDecimal caches the decimal of a token, so that matching does not have
+// to query the token contract over IPC.
+func (tomox *TomoX) SetTokenDecimal(tokenAddr common.Address, tokenDecimal *big.Int) {
+	tomox.storeTokenDecimal(tokenAddr, tokenDecimal)
+}
@@ -0,0 +1,81 @@
+package tomox
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// tokenDecimalPrefix prefixes the keys of the token decimals stored in the
+// TomoX database, followed by the token address.
+var tokenDecimalPrefix = []byte("token-decimal-")
+
+func tokenDecimalKey(token common.Address) []byte {
+	return append(append([]byte{}, tokenDecimalPrefix...), token.Bytes()...)
+}
+
+// CachedTokenDecimal returns the decimal of a token, as the power of ten of its
+// decimals, if it was already queried from its contract. The decimals don't
+// change once a token is deployed, so they are kept in the TomoX database
+// across restarts.
+func (tomox *TomoX) CachedTokenDecimal(token common.Address) (*big.Int, bool) {
+	if tokenDecimal, ok := tomox.tokenDecimalCache.Get(token); ok {
+		return tokenDecimal.(*big.Int), true
+	}
+	if tomox.db == nil {
+		return nil, false
+	}
+	enc, err := tomox.db.Get(tokenDecimalKey(token))
+	if err != nil || len(enc) == 0 {
+		return nil, false
+	}
+	tokenDecimal := new(big.Int).SetBytes(enc)
+	tomox.tokenDecimalCache.Add(token, tokenDecimal)
+	return tokenDecimal, true
+}
+
+// storeTokenDecimal caches the decimal of a token and stores it in the TomoX
+// database.
+func (tomox *TomoX) storeTokenDecimal(token common.Address, tokenDecimal *big.Int) {
+	tomox.tokenDecimalCache.Add(token, tokenDecimal)
+	if tomox.db == nil {
+		return
+	}
+	if err := tomox.db.Put(tokenDecimalKey(token), tokenDecimal.Bytes()); err != nil {
+		log.Error("Failed to store token decimal", "token", token, "err", err)
+	}
+}
+
+// TokenDecimals returns the number of decimals of a token decimal, the power
+// of ten it is.
+func TokenDecimals(tokenDecimal *big.Int) uint8 {
+	return uint8(len(tokenDecimal.String()) - 1)
+}
+
+// formatUnits formats an amount of the smallest units of a token as a decimal
+// number of whole tokens, without trailing zeros.
+func formatUnits(amount *big.Int, tokenDecimal *big.Int) string {
+	if amount == nil || tokenDecimal == nil || tokenDecimal.Sign() <= 0 {
+		return ""
+	}
+	decimals := int(TokenDecimals(tokenDecimal))
+	formatted := new(big.Rat).SetFrac(amount, tokenDecimal).FloatString(decimals)
+	if decimals > 0 {
+		formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+	}
+	return formatted
+}
+
+// NormalizePrice formats the price of a pair, in smallest units of the quote
+// token per whole base token, as a number of whole quote tokens.
+func NormalizePrice(price *big.Int, quoteTokenDecimal *big.Int) string {
+	return formatUnits(price, quoteTokenDecimal)
+}
+
+// NormalizeQuantity formats a quantity of smallest units of the base token of
+// a pair as a number of whole base tokens.
+func NormalizeQuantity(quantity *big.Int, baseTokenDecimal *big.Int) string {
+	return formatUnits(quantity, baseTokenDecimal)
+}
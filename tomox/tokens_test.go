@@ -0,0 +1,57 @@
+package tomox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNormalize(t *testing.T) {
+	sixDecimals := big.NewInt(1000000)
+	tests := []struct {
+		amount, tokenDecimal *big.Int
+		want                 string
+	}{
+		{big.NewInt(1500000), sixDecimals, "1.5"},
+		{big.NewInt(2000000), sixDecimals, "2"},
+		{big.NewInt(1), sixDecimals, "0.000001"},
+		{big.NewInt(0), sixDecimals, "0"},
+		{new(big.Int).Mul(big.NewInt(123), common.BasePrice), common.BasePrice, "123"},
+		{big.NewInt(42), big.NewInt(1), "42"},
+		{big.NewInt(42), nil, ""},
+	}
+	for i, tt := range tests {
+		if have := NormalizePrice(tt.amount, tt.tokenDecimal); have != tt.want {
+			t.Errorf("test %d: normalized amount mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+	if decimals := TokenDecimals(common.BasePrice); decimals != 18 {
+		t.Errorf("decimals mismatch: have %d, want 18", decimals)
+	}
+}
+
+func TestCachedTokenDecimal(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-tokens-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	token, decimal := common.Address{0x01}, big.NewInt(1000000)
+	tomox := New(&Config{DataDir: datadir})
+	if _, ok := tomox.CachedTokenDecimal(token); ok {
+		t.Fatalf("unknown token decimal cached")
+	}
+	tomox.SetTokenDecimal(token, decimal)
+	tomox.db.Close()
+
+	// The decimals are kept across restarts
+	tomox = New(&Config{DataDir: datadir})
+	defer tomox.db.Close()
+	if cached, ok := tomox.CachedTokenDecimal(token); !ok || cached.Cmp(decimal) != 0 {
+		t.Fatalf("stored token decimal mismatch: have %v, want %v", cached, decimal)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 	"math/big"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -29,6 +32,8 @@ const (
 var (
 	ErrNonceTooHigh = errors.New("nonce too high")
 	ErrNonceTooLow  = errors.New("nonce too low")
+
+	ErrAmendedOrderNotFound = errors.New("amended order not found in the order book")
 )
 
 type Config struct {
@@ -37,6 +42,18 @@ type Config struct {
 	DBName         string `toml:",omitempty"`
 	ConnectionUrl  string `toml:",omitempty"`
 	ReplicaSetName string `toml:",omitempty"`
+
+	TrieCache   int           // Megabytes of memory for the TomoX tries, half of it caching clean nodes
+	TrieTimeout time.Duration // Processing time after which the TomoX tries in memory are flushed
+
+	Discovery bool // Whether to advertise and look for the other TomoX nodes over discovery v5
+
+	KafkaBrokers []string `toml:",omitempty"` // Brokers the orders and trades are exported to, disabled if empty
+	KafkaTopic   string   `toml:",omitempty"` // Topic the orders and trades are exported to
+
+	HistoryServe  bool     `toml:",omitempty"` // Whether to serve the order history of the MongoDB to the other nodes
+	HistoryPeers  []string `toml:",omitempty"` // Archive nodes (enode URLs) to snapshot the order history from, disabled if empty
+	HistoryBlocks uint64   `toml:",omitempty"` // Number of recent blocks to snapshot the order history of, all if zero
 }
 
 type TxDataMatch struct {
@@ -53,7 +70,10 @@ type TxMatchBatch struct {
 
 // DefaultConfig represents (shocker!) the default configuration.
 var DefaultConfig = Config{
-	DataDir: "",
+	DataDir:     "",
+	TrieCache:   128,
+	TrieTimeout: 5 * time.Minute,
+	KafkaTopic:  "tomox",
 }
 
 type TomoX struct {
@@ -63,23 +83,64 @@ type TomoX struct {
 	Triegc     *prque.Prque         // Priority queue mapping block numbers to tries to gc
 	StateCache tomox_state.Database // State database to reuse between imports (contains state cache)    *tomox_state.TomoXStateDB
 
+	trieDirtyLimit common.StorageSize // Memory allowance of the TomoX tries not yet flushed, zero if unbounded
+	trieTimeLimit  time.Duration      // Processing time allowance of the TomoX tries not yet flushed, zero if unbounded
+
 	orderNonce map[common.Address]*big.Int
 
+	lending *tomoxlending.Lending // Lending books, matched along the spot order books
+
 	sdkNode           bool
 	settings          syncmap.Map // holds configuration settings that can be dynamically changed
 	tokenDecimalCache *lru.Cache
 	orderCache        *lru.Cache
+
+	orderBookIndexLock sync.Mutex // Lock serialising the updates of the order books index
+	feeIndexLock       sync.Mutex // Lock serialising the updates of the fee index
+	volumeIndexLock    sync.Mutex // Lock serialising the updates of the volume index
+
+	pairs     []Pair       // Trading pairs listed in the relayer registration contract, nil until loaded
+	pairsLock sync.RWMutex // Lock protecting the trading pairs
+
+	processed    *processedBlock // Last block the TomoX state was processed at, nil until loaded
+	progressLock sync.Mutex      // Lock protecting the last processed block
+
+	discovery      bool                         // Whether to find the other TomoX nodes over discovery v5
+	discovered     map[discover.NodeID]struct{} // TomoX nodes found through the discovery topic
+	discoveredLock sync.RWMutex                 // Lock protecting the discovered nodes
+
+	kafka *kafkaExporter // Exporter of the orders and trades to Kafka, if configured
+
+	history *historySync // Order history served to or snapshotted from the other nodes, if configured
+
+	quit           chan struct{}
 }
 
 func (tomox *TomoX) Protocols() []p2p.Protocol {
+	if tomox.history != nil {
+		return []p2p.Protocol{tomox.historyProtocol()}
+	}
 	return []p2p.Protocol{}
 }
 
 func (tomox *TomoX) Start(server *p2p.Server) error {
+	if tomox.discovery {
+		tomox.startDiscovery(server)
+	}
+	if tomox.kafka != nil {
+		tomox.kafka.start()
+	}
+	if tomox.history != nil {
+		tomox.startHistory(server)
+	}
 	return nil
 }
 
 func (tomox *TomoX) Stop() error {
+	close(tomox.quit)
+	if tomox.kafka != nil {
+		tomox.kafka.stop()
+	}
 	return nil
 }
 
@@ -107,6 +168,9 @@ func New(cfg *Config) *TomoX {
 		Triegc:            prque.New(),
 		tokenDecimalCache: tokenDecimalCache,
 		orderCache:        orderCache,
+		discovery:         cfg.Discovery,
+		discovered:        make(map[discover.NodeID]struct{}),
+		quit:              make(chan struct{}),
 	}
 
 	// default DBEngine: levelDB
@@ -118,12 +182,49 @@ func New(cfg *Config) *TomoX {
 		tomoX.sdkNode = true
 	}
 
-	tomoX.StateCache = tomox_state.NewDatabase(tomoX.db)
+	tomoX.StateCache = tomox_state.NewDatabaseWithCache(tomoX.db, cfg.TrieCache/2)
+	tomoX.trieDirtyLimit = common.StorageSize(cfg.TrieCache-cfg.TrieCache/2) * 1024 * 1024
+	tomoX.trieTimeLimit = cfg.TrieTimeout
+	tomoX.lending = tomoxlending.New(tomoX.db)
 	tomoX.settings.Store(overflowIdx, false)
 
+	if len(cfg.KafkaBrokers) > 0 {
+		topic := cfg.KafkaTopic
+		if topic == "" {
+			topic = DefaultConfig.KafkaTopic
+		}
+		tomoX.kafka = newKafkaExporter(tomoX.db, cfg.KafkaBrokers, topic)
+	}
+	if cfg.HistoryServe || len(cfg.HistoryPeers) > 0 {
+		if tomoX.sdkNode {
+			tomoX.history = newHistorySync(cfg)
+		} else {
+			log.Warn("Order history sync requires the mongodb engine")
+		}
+	}
 	return tomoX
 }
 
+// TriesOverLimit reports whether the TomoX tries kept in memory exceed their
+// memory allowance, or have been for longer than their processing time one,
+// given the time spent processing blocks since the tries were last flushed.
+// Critical is set when an allowance is exceeded twice, in which case the tries
+// should be flushed right away.
+func (tomox *TomoX) TriesOverLimit(proctime time.Duration) (over bool, critical bool) {
+	if tomox.StateCache == nil {
+		return false, false
+	}
+	if tomox.trieDirtyLimit > 0 {
+		size := tomox.StateCache.TrieDB().Size()
+		over, critical = size > tomox.trieDirtyLimit, size >= 2*tomox.trieDirtyLimit
+	}
+	if tomox.trieTimeLimit > 0 {
+		over = over || proctime > tomox.trieTimeLimit
+		critical = critical || proctime >= 2*tomox.trieTimeLimit
+	}
+	return over, critical
+}
+
 // Overflow returns an indication if the message queue is full.
 func (tomox *TomoX) Overflow() bool {
 	val, _ := tomox.settings.Load(overflowIdx)
@@ -142,6 +243,11 @@ func (tomox *TomoX) GetMongoDB() OrderDao {
 	return tomox.mongodb
 }
 
+// GetLending returns the lending service of TomoX.
+func (tomox *TomoX) GetLending() *tomoxlending.Lending {
+	return tomox.lending
+}
+
 // APIs returns the RPC descriptors the TomoX implementation offers
 func (tomox *TomoX) APIs() []rpc.API {
 	return []rpc.API{
@@ -159,7 +265,41 @@ func (tomox *TomoX) Version() uint64 {
 	return ProtocolVersion
 }
 
-func (tomox *TomoX) ProcessOrderPending(coinbase common.Address, ipcEndpoint string, pending map[common.Address]types.OrderTransactions, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB) []TxDataMatch {
+// NewOrderItem converts a signed order transaction into the order item
+// processed by the matching engine.
+func NewOrderItem(tx *types.OrderTransaction) (*tomox_state.OrderItem, error) {
+	V, R, S := tx.Signature()
+	n, err := strconv.ParseInt(V.String(), 10, 8)
+	if err != nil {
+		return nil, err
+	}
+	return &tomox_state.OrderItem{
+		Nonce:           big.NewInt(int64(tx.Nonce())),
+		Quantity:        tx.Quantity(),
+		Price:           tx.Price(),
+		ExchangeAddress: tx.ExchangeAddress(),
+		UserAddress:     tx.UserAddress(),
+		BaseToken:       tx.BaseToken(),
+		QuoteToken:      tx.QuoteToken(),
+		Status:          tx.Status(),
+		Side:            tx.Side(),
+		Type:            tx.Type(),
+		Hash:            tx.OrderHash(),
+		OrderID:         tx.OrderID(),
+		Signature: &tomox_state.Signature{
+			V: byte(n),
+			R: common.BigToHash(R),
+			S: common.BigToHash(S),
+		},
+		PairName:            tx.PairName(),
+		SelfTradePrevention: tx.SelfTradePrevention(),
+		ExpiryBlock:         tx.ExpiryBlock(),
+	}, nil
+}
+
+// processOrderPending matches the pending orders in turn, by nonce of their
+// users, until the deadline of the budget.
+func (tomox *TomoX) processOrderPending(coinbase common.Address, ipcEndpoint string, pending map[common.Address]types.OrderTransactions, statedb *state.StateDB, tomoXstatedb *tomox_state.TomoXStateDB, number uint64, budget OrderBudget) []TxDataMatch {
 	txMatches := []TxDataMatch{}
 	txs := types.NewOrderTransactionByNonce(types.OrderTxSigner{}, pending)
 	for {
@@ -167,36 +307,18 @@ func (tomox *TomoX) ProcessOrderPending(coinbase common.Address, ipcEndpoint str
 		if tx == nil {
 			break
 		}
+		if budget.expired() {
+			log.Debug("Order matching budget exhausted", "matched", len(txMatches))
+			break
+		}
 		log.Debug("ProcessOrderPending start", "len", len(pending))
 		log.Debug("Get pending orders to process", "address", tx.UserAddress(), "nonce", tx.Nonce())
-		V, R, S := tx.Signature()
-
-		bigstr := V.String()
-		n, e := strconv.ParseInt(bigstr, 10, 8)
-		if e != nil {
+		order, err := NewOrderItem(tx)
+		if err != nil {
+			log.Debug("Skipping order with invalid signature", "hash", tx.Hash(), "err", err)
+			txs.Shift()
 			continue
 		}
-
-		order := &tomox_state.OrderItem{
-			Nonce:           big.NewInt(int64(tx.Nonce())),
-			Quantity:        tx.Quantity(),
-			Price:           tx.Price(),
-			ExchangeAddress: tx.ExchangeAddress(),
-			UserAddress:     tx.UserAddress(),
-			BaseToken:       tx.BaseToken(),
-			QuoteToken:      tx.QuoteToken(),
-			Status:          tx.Status(),
-			Side:            tx.Side(),
-			Type:            tx.Type(),
-			Hash:            tx.OrderHash(),
-			OrderID:         tx.OrderID(),
-			Signature: &tomox_state.Signature{
-				V: byte(n),
-				R: common.BigToHash(R),
-				S: common.BigToHash(S),
-			},
-			PairName: tx.PairName(),
-		}
 		cancel := false
 		if order.Status == OrderStatusCancelled {
 			cancel = true
@@ -211,7 +333,7 @@ func (tomox *TomoX) ProcessOrderPending(coinbase common.Address, ipcEndpoint str
 			order.Status = OrderStatusCancelled
 		}
 
-		trades, rejects, err := tomox.CommitOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, GetOrderBookHash(order.BaseToken, order.QuoteToken), order)
+		trades, rejects, err := tomox.CommitOrder(coinbase, ipcEndpoint, statedb, tomoXstatedb, number, GetOrderBookHash(order.BaseToken, order.QuoteToken), order)
 
 		log.Debug("List reject order", "rejects", len(rejects))
 		for _, reject := range rejects {
@@ -266,7 +388,7 @@ func (tomox *TomoX) ProcessOrderPending(coinbase common.Address, ipcEndpoint str
 // 2. txMatchData.Trades: includes information of matched orders.
 // 		a. PutObject them to `trades` collection
 // 		b. Update status of regrading orders to sdktypes.OrderStatusFilled
-func (tomox *TomoX) SyncDataToSDKNode(txDataMatch TxDataMatch, txHash common.Hash, txMatchTime time.Time, statedb *state.StateDB) error {
+func (tomox *TomoX) SyncDataToSDKNode(txDataMatch TxDataMatch, txHash common.Hash, txMatchTime time.Time, statedb *state.StateDB, tomoxStatedb *tomox_state.TomoXStateDB) error {
 	var (
 		// originTakerOrder: order get from db, nil if it doesn't exist
 		// takerOrderInTx: order decoded from txdata
@@ -300,6 +422,16 @@ func (tomox *TomoX) SyncDataToSDKNode(txDataMatch TxDataMatch, txHash common.Has
 		updatedTakerOrder = takerOrderInTx
 	}
 
+	if takerOrderInTx.Status == OrderStatusAmended && originTakerOrder != nil {
+		// The amended quantity is the one left open, on top of the filled one
+		filled := Zero()
+		if updatedTakerOrder.FilledAmount != nil {
+			filled = updatedTakerOrder.FilledAmount
+		}
+		updatedTakerOrder.Price = takerOrderInTx.Price
+		updatedTakerOrder.Quantity = Add(filled, takerOrderInTx.Quantity)
+		updatedTakerOrder.OrderID = takerOrderInTx.OrderID
+	}
 	if takerOrderInTx.Status != OrderStatusCancelled {
 		updatedTakerOrder.Status = OrderStatusOpen
 	} else {
@@ -346,11 +478,11 @@ func (tomox *TomoX) SyncDataToSDKNode(txDataMatch TxDataMatch, txHash common.Has
 		// feeAmount: all fees are calculated in quoteToken
 		quoteTokenQuantity := big.NewInt(0).Mul(quantity, price)
 		quoteTokenQuantity = big.NewInt(0).Div(quoteTokenQuantity, common.BasePrice)
-		takerFee := big.NewInt(0).Mul(quoteTokenQuantity, tomox_state.GetExRelayerFee(updatedTakerOrder.ExchangeAddress, statedb))
+		takerFee := big.NewInt(0).Mul(quoteTokenQuantity, tomox_state.GetTradingFee(updatedTakerOrder.ExchangeAddress, tomoxStatedb, statedb))
 		takerFee = big.NewInt(0).Div(takerFee, common.TomoXBaseFee)
 		tradeRecord.TakeFee = takerFee
 
-		makerFee := big.NewInt(0).Mul(quoteTokenQuantity, tomox_state.GetExRelayerFee(common.HexToAddress(trade[TradeMakerExchange]), statedb))
+		makerFee := big.NewInt(0).Mul(quoteTokenQuantity, tomox_state.GetTradingFee(common.HexToAddress(trade[TradeMakerExchange]), tomoxStatedb, statedb))
 		makerFee = big.NewInt(0).Div(makerFee, common.TomoXBaseFee)
 		tradeRecord.MakeFee = makerFee
 		if tradeRecord.CreatedAt.IsZero() {
@@ -0,0 +1,85 @@
+package tomox_state
+
+import "github.com/ethereum/go-ethereum/common"
+
+// stateAccesses records the keys of the tomox trie read and written since the
+// tracking was started on a state. Exchange objects, relayer fees, price
+// histories and expiry indexes all live in the trie under distinct keys.
+type stateAccesses struct {
+	reads  map[common.Hash]struct{}
+	writes map[common.Hash]struct{}
+}
+
+func (self *TomoXStateDB) readKey(key common.Hash) {
+	if self.accesses != nil {
+		self.accesses.reads[key] = struct{}{}
+	}
+}
+
+func (self *TomoXStateDB) writeKey(key common.Hash) {
+	if self.accesses != nil {
+		self.accesses.writes[key] = struct{}{}
+	}
+}
+
+// TrackAccesses starts recording the keys the state is read and written at,
+// dropping the ones recorded so far. It is meant for the copies of a state
+// changed concurrently, to check that their changes are independent with
+// ConflictsWith and to apply them with MergeWrites.
+func (self *TomoXStateDB) TrackAccesses() {
+	self.accesses = &stateAccesses{
+		reads:  make(map[common.Hash]struct{}),
+		writes: make(map[common.Hash]struct{}),
+	}
+}
+
+// ConflictsWith reports whether a key written in one of the tracked states was
+// read or written in the other one.
+func (self *TomoXStateDB) ConflictsWith(other *TomoXStateDB) bool {
+	if self.accesses == nil || other.accesses == nil {
+		return true
+	}
+	return self.accesses.overwrites(other.accesses) || other.accesses.overwrites(self.accesses)
+}
+
+func (a *stateAccesses) overwrites(b *stateAccesses) bool {
+	for key := range a.writes {
+		if _, ok := b.reads[key]; ok {
+			return true
+		}
+		if _, ok := b.writes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeWrites applies to the state the writes recorded in a tracked copy of
+// it, which overwrite the exchange objects, relayer fees, price histories and
+// expiry indexes of the state. The merge is journalled like any other change of the state.
+func (self *TomoXStateDB) MergeWrites(src *TomoXStateDB) {
+	if src.accesses == nil {
+		return
+	}
+	for key := range src.accesses.writes {
+		if obj, ok := src.stateExhangeObjects[key]; ok {
+			self.journal = append(self.journal, exchangeObjectChange{hash: key, prev: self.stateExhangeObjects[key]})
+			self.setStateExchangeObject(obj.deepCopy(self, self.MarkStateExchangeObjectDirty))
+			continue
+		}
+		if fee, ok := src.relayerFees[key]; ok {
+			self.journal = append(self.journal, relayerFeeChange{key: key, prev: copyFee(self.getRelayerFee(key))})
+			self.setRelayerFee(key, copyFee(fee))
+			continue
+		}
+		if history, ok := src.priceHistories[key]; ok {
+			self.journal = append(self.journal, priceHistoryChange{key: key, prev: self.getPriceHistory(key).copy()})
+			self.setPriceHistory(key, history.copy())
+			continue
+		}
+		if index, ok := src.expiryIndexes[key]; ok {
+			self.journal = append(self.journal, expiryIndexChange{key: key, prev: self.getExpiryIndex(key).copy()})
+			self.setExpiryIndex(key, index.copy())
+		}
+	}
+}
@@ -14,6 +14,11 @@ var (
 	Market    = "MO"
 	Limit     = "LO"
 	Cancel    = "CANCELLED"
+
+	// self-trade prevention modes
+	CancelNewest = "CANCEL_NEWEST"
+	CancelOldest = "CANCEL_OLDEST"
+	CancelBoth   = "CANCEL_BOTH"
 )
 
 var EmptyHash = common.Hash{}
@@ -42,11 +47,23 @@ var (
 	ErrOrderBookHashNotMatch = errors.New("verify order: orderbook hash not match")
 	ErrOrderTreeHashNotMatch = errors.New("verify order: ordertree hash not match")
 
+	ErrInvalidSelfTradePrevention = errors.New("verify order: invalid self-trade prevention")
+	ErrInvalidTickSize            = errors.New("verify order: price is not a multiple of the tick size")
+	ErrInvalidLotSize             = errors.New("verify order: quantity is not a multiple of the lot size")
+
 	// supported order types
 	MatchingOrderType = map[string]bool{
 		Market: true,
 		Limit:  true,
 	}
+
+	// supported self-trade prevention modes, none by default
+	SelfTradePreventionMode = map[string]bool{
+		"":           true,
+		CancelNewest: true,
+		CancelOldest: true,
+		CancelBoth:   true,
+	}
 )
 
 // exchangeObject is the Ethereum consensus representation of exchanges.
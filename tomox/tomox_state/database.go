@@ -85,6 +85,17 @@ func NewDatabase(db ethdb.Database) Database {
 	}
 }
 
+// NewDatabaseWithCache creates a backing store for state like NewDatabase does,
+// additionally caching up to cache megabytes of the trie nodes read from or
+// flushed to the low level storage layer.
+func NewDatabaseWithCache(db ethdb.Database, cache int) Database {
+	csc, _ := lru.New(codeSizeCacheSize)
+	return &cachingDB{
+		db:            trie.NewDatabaseWithCache(db, cache),
+		codeSizeCache: csc,
+	}
+}
+
 type cachingDB struct {
 	db            *trie.Database
 	mu            sync.Mutex
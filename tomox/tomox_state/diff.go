@@ -0,0 +1,127 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tomox_state
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// PriceLevelChange is a price level of a side of an order book which differs
+// between two states.
+type PriceLevelChange struct {
+	Side   string   `json:"side"`
+	Price  *big.Int `json:"price"`
+	Volume *big.Int `json:"volume"` // Volume at the later state, zero for the removed levels
+}
+
+// OrderBookDiff lists the price levels of an order book added, removed and
+// changed between two states, sorted by side and price. Applied to the book at
+// the first state, it yields the book at the second one.
+type OrderBookDiff struct {
+	Added   []PriceLevelChange `json:"added"`
+	Removed []PriceLevelChange `json:"removed"`
+	Changed []PriceLevelChange `json:"changed"`
+}
+
+// DiffOrderBook compares the ask and bid trees of an order book in two states,
+// walking only the trie nodes which differ between them. The comparison is
+// abandoned with the context error once ctx is done.
+func DiffOrderBook(ctx context.Context, from, to *TomoXStateDB, orderBook common.Hash) (*OrderBookDiff, error) {
+	diff := &OrderBookDiff{Added: []PriceLevelChange{}, Removed: []PriceLevelChange{}, Changed: []PriceLevelChange{}}
+	for _, side := range []string{Ask, Bid} {
+		fromTree, toTree := from.orderTree(orderBook, side), to.orderTree(orderBook, side)
+
+		// Levels of the later state missing or different in the earlier one
+		diffIt, _ := trie.NewDifferenceIterator(fromTree.NodeIterator(nil), toTree.NodeIterator(nil))
+		it := trie.NewIterator(diffIt)
+		for it.Next() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			var data orderList
+			if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+				return nil, err
+			}
+			priceKey := to.trie.GetKey(it.Key)
+			change := PriceLevelChange{Side: side, Price: new(big.Int).SetBytes(priceKey), Volume: data.Volume}
+			prev, err := fromTree.TryGet(priceKey)
+			if err != nil {
+				return nil, err
+			}
+			if len(prev) == 0 {
+				diff.Added = append(diff.Added, change)
+			} else {
+				diff.Changed = append(diff.Changed, change)
+			}
+		}
+		if it.Err != nil {
+			return nil, it.Err
+		}
+		// Levels of the earlier state missing in the later one
+		diffIt, _ = trie.NewDifferenceIterator(toTree.NodeIterator(nil), fromTree.NodeIterator(nil))
+		it = trie.NewIterator(diffIt)
+		for it.Next() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			priceKey := from.trie.GetKey(it.Key)
+			next, err := toTree.TryGet(priceKey)
+			if err != nil {
+				return nil, err
+			}
+			if len(next) == 0 {
+				diff.Removed = append(diff.Removed, PriceLevelChange{Side: side, Price: new(big.Int).SetBytes(priceKey), Volume: new(big.Int)})
+			}
+		}
+		if it.Err != nil {
+			return nil, it.Err
+		}
+	}
+	for _, changes := range [][]PriceLevelChange{diff.Added, diff.Removed, diff.Changed} {
+		sortPriceLevels(changes)
+	}
+	return diff, nil
+}
+
+// orderTree returns the ask or bid tree of an order book, an empty one if the
+// order book doesn't exist.
+func (self *TomoXStateDB) orderTree(orderBook common.Hash, side string) Trie {
+	exchangeObject := self.getStateExchangeObject(orderBook)
+	if exchangeObject == nil {
+		tr, _ := self.db.OpenStorageTrie(orderBook, EmptyHash)
+		return tr
+	}
+	if side == Ask {
+		return exchangeObject.getAsksTrie(self.db)
+	}
+	return exchangeObject.getBidsTrie(self.db)
+}
+
+func sortPriceLevels(changes []PriceLevelChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Side != changes[j].Side {
+			return changes[i].Side < changes[j].Side
+		}
+		return changes[i].Price.Cmp(changes[j].Price) < 0
+	})
+}
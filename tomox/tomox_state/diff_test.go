@@ -0,0 +1,103 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tomox_state
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestDiffOrderBook(t *testing.T) {
+	orderBook := common.StringToHash("BTC/TOMO")
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+
+	commit := func(statedb *TomoXStateDB) *TomoXStateDB {
+		root := statedb.IntermediateRoot()
+		statedb.Commit()
+		committed, err := New(root, stateCache)
+		if err != nil {
+			t.Fatalf("failed to open state %x: %v", root, err)
+		}
+		return committed
+	}
+	order := func(id uint64, side string, price, quantity int64) OrderItem {
+		return OrderItem{OrderID: id, Side: side, Price: big.NewInt(price), Quantity: big.NewInt(quantity), Hash: common.BigToHash(new(big.Int).SetUint64(id)), Signature: &Signature{}}
+	}
+	insert := func(statedb *TomoXStateDB, items ...OrderItem) {
+		for _, item := range items {
+			statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(item.OrderID)), item)
+		}
+	}
+	empty, _ := New(common.Hash{}, stateCache)
+	statedb, _ := New(common.Hash{}, stateCache)
+	insert(statedb, order(1, Ask, 10, 1), order(2, Ask, 11, 2), order(3, Bid, 8, 3), order(4, Bid, 7, 4))
+	from := commit(statedb)
+
+	// Add an ask level and an order to a bid level, remove an ask level
+	statedb, _ = New(from.IntermediateRoot(), stateCache)
+	insert(statedb, order(5, Ask, 12, 5), order(6, Bid, 8, 6))
+	cancelled := order(2, Ask, 11, 2)
+	if err := statedb.CancelOrder(orderBook, &cancelled); err != nil {
+		t.Fatalf("failed to cancel order: %v", err)
+	}
+	to := commit(statedb)
+
+	diff, err := DiffOrderBook(context.Background(), from, to, orderBook)
+	if err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+	checkChanges(t, "added", diff.Added, "SELL 12 5")
+	checkChanges(t, "removed", diff.Removed, "SELL 11 0")
+	checkChanges(t, "changed", diff.Changed, "BUY 8 9")
+
+	// A new book is all added, and identical states have no diff
+	if diff, err = DiffOrderBook(context.Background(), empty, from, orderBook); err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+	checkChanges(t, "added from empty", diff.Added, "BUY 7 4", "BUY 8 3", "SELL 10 1", "SELL 11 2")
+	if diff, err = DiffOrderBook(context.Background(), to, to, orderBook); err != nil {
+		t.Fatalf("failed to diff: %v", err)
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+		t.Errorf("diff of identical states: have %+v", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DiffOrderBook(ctx, from, to, orderBook); err != context.Canceled {
+		t.Errorf("error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+func checkChanges(t *testing.T, kind string, have []PriceLevelChange, want ...string) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Errorf("%s: change count mismatch: have %d, want %d", kind, len(have), len(want))
+		return
+	}
+	for i, change := range have {
+		if s := fmt.Sprintf("%s %v %v", change.Side, change.Price, change.Volume); s != want[i] {
+			t.Errorf("%s %d: change mismatch: have %s, want %s", kind, i, s, want[i])
+		}
+	}
+}
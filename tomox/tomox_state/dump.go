@@ -17,65 +17,140 @@
 package tomox_state
 
 import (
+	"context"
 	"fmt"
 	"github.com/ethereum/go-ethereum/rlp"
+	"io"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
+// DumpOrderTree is the content of a side of an order book, the order lists by
+// decimal price. It encodes to RLP as the list of its order lists sorted by
+// price, the form used by the export tools.
+type DumpOrderTree map[string]DumpOrderList
+
+// DumpOrderList is the content of an order list, the quantities of its orders
+// by decimal order ID.
 type DumpOrderList struct {
 	Volume *big.Int
-	Orders map[*big.Int]*big.Int
+	Orders map[string]*big.Int
+
+	// Price and volume in whole tokens, filled in by the APIs knowing the
+	// decimals of the tokens
+	NormalizedPrice  string `json:",omitempty"`
+	NormalizedVolume string `json:",omitempty"`
 }
 
-func (self *TomoXStateDB) DumpAskTrie(orderBook common.Hash) (map[*big.Int]DumpOrderList, error) {
+// DumpAskTrie returns the ask side of an order book, the orders at each price.
+// The iteration is abandoned with the context error once ctx is done.
+func (self *TomoXStateDB) DumpAskTrie(ctx context.Context, orderBook common.Hash) (DumpOrderTree, error) {
 	exhangeObject := self.getStateExchangeObject(orderBook)
 	if exhangeObject == nil {
 		return nil, fmt.Errorf("Order book not found orderBook : %v ", orderBook.Hex())
 	}
-	result := map[*big.Int]DumpOrderList{}
-	it := trie.NewIterator(exhangeObject.getAsksTrie(self.db).NodeIterator(nil))
-	for it.Next() {
-		priceByte := self.trie.GetKey(it.Key)
-		price := new(big.Int).SetBytes(priceByte)
-		var data orderList
-		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
-			return nil, fmt.Errorf("Fail when decode order iist orderBook : %v ,price :%v ", orderBook.Hex(), price)
-		}
-		orderList := newStateOrderList(self, Ask, orderBook, common.BytesToHash(priceByte), data, nil)
-		dumpOrderList := DumpOrderList{Volume: data.Volume, Orders: map[*big.Int]*big.Int{}}
-		orderListIt := trie.NewIterator(orderList.getTrie(self.db).NodeIterator(nil))
-		for orderListIt.Next() {
-			dumpOrderList.Orders[new(big.Int).SetBytes(self.trie.GetKey(orderListIt.Key))] = new(big.Int).SetBytes(orderListIt.Value)
-		}
-		result[price] = dumpOrderList
-	}
-	return result, nil
+	return self.dumpSide(ctx, Ask, orderBook, exhangeObject.getAsksTrie(self.db))
 }
 
-func (self *TomoXStateDB) DumpBidTrie(orderBook common.Hash) (map[*big.Int]DumpOrderList, error) {
+// DumpBidTrie returns the bid side of an order book, the orders at each price.
+// The iteration is abandoned with the context error once ctx is done.
+func (self *TomoXStateDB) DumpBidTrie(ctx context.Context, orderBook common.Hash) (DumpOrderTree, error) {
 	exhangeObject := self.getStateExchangeObject(orderBook)
 	if exhangeObject == nil {
 		return nil, fmt.Errorf("Order book not found orderBook : %v ", orderBook.Hex())
 	}
-	result := map[*big.Int]DumpOrderList{}
-	it := trie.NewIterator(exhangeObject.getBidsTrie(self.db).NodeIterator(nil))
+	return self.dumpSide(ctx, Bid, orderBook, exhangeObject.getBidsTrie(self.db))
+}
+
+func (self *TomoXStateDB) dumpSide(ctx context.Context, side string, orderBook common.Hash, sideTrie Trie) (DumpOrderTree, error) {
+	result := DumpOrderTree{}
+	it := trie.NewIterator(sideTrie.NodeIterator(nil))
 	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		priceByte := self.trie.GetKey(it.Key)
 		price := new(big.Int).SetBytes(priceByte)
 		var data orderList
 		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
 			return nil, fmt.Errorf("Fail when decode order iist orderBook : %v ,price :%v ", orderBook.Hex(), price)
 		}
-		orderList := newStateOrderList(self, Bid, orderBook, common.BytesToHash(priceByte), data, nil)
-		dumpOrderList := DumpOrderList{Volume: data.Volume, Orders: map[*big.Int]*big.Int{}}
+		orderList := newStateOrderList(self, side, orderBook, common.BytesToHash(priceByte), data, nil)
+		dumpOrderList := DumpOrderList{Volume: data.Volume, Orders: map[string]*big.Int{}}
 		orderListIt := trie.NewIterator(orderList.getTrie(self.db).NodeIterator(nil))
 		for orderListIt.Next() {
-			dumpOrderList.Orders[new(big.Int).SetBytes(self.trie.GetKey(orderListIt.Key))] = new(big.Int).SetBytes(orderListIt.Value)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			orderID := new(big.Int).SetBytes(self.trie.GetKey(orderListIt.Key))
+			dumpOrderList.Orders[orderID.String()] = new(big.Int).SetBytes(orderListIt.Value)
 		}
-		result[price] = dumpOrderList
+		result[price.String()] = dumpOrderList
 	}
 	return result, nil
 }
+
+// rlpOrderList is the RLP encoding of an order list of a dump, its orders
+// sorted by ID.
+type rlpOrderList struct {
+	Price  *big.Int
+	Volume *big.Int
+	Orders []rlpOrder
+}
+
+type rlpOrder struct {
+	ID       *big.Int
+	Quantity *big.Int
+}
+
+// EncodeRLP implements rlp.Encoder, encoding the order lists sorted by price.
+func (t DumpOrderTree) EncodeRLP(w io.Writer) error {
+	lists := make([]rlpOrderList, 0, len(t))
+	for price, list := range t {
+		enc := rlpOrderList{Volume: list.Volume, Orders: make([]rlpOrder, 0, len(list.Orders))}
+		var err error
+		if enc.Price, err = parseDumpKey(price); err != nil {
+			return err
+		}
+		for id, quantity := range list.Orders {
+			orderID, err := parseDumpKey(id)
+			if err != nil {
+				return err
+			}
+			enc.Orders = append(enc.Orders, rlpOrder{ID: orderID, Quantity: quantity})
+		}
+		sort.Slice(enc.Orders, func(i, j int) bool { return enc.Orders[i].ID.Cmp(enc.Orders[j].ID) < 0 })
+		lists = append(lists, enc)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Price.Cmp(lists[j].Price) < 0 })
+	return rlp.Encode(w, lists)
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (t *DumpOrderTree) DecodeRLP(s *rlp.Stream) error {
+	var lists []rlpOrderList
+	if err := s.Decode(&lists); err != nil {
+		return err
+	}
+	*t = make(DumpOrderTree, len(lists))
+	for _, enc := range lists {
+		list := DumpOrderList{Volume: enc.Volume, Orders: make(map[string]*big.Int, len(enc.Orders))}
+		for _, order := range enc.Orders {
+			list.Orders[order.ID.String()] = order.Quantity
+		}
+		(*t)[enc.Price.String()] = list
+	}
+	return nil
+}
+
+// parseDumpKey parses a decimal price or order ID keying a dump.
+func parseDumpKey(key string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(key, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid dump key %q", key)
+	}
+	return n, nil
+}
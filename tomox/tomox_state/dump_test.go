@@ -0,0 +1,121 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tomox_state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the order book dumps list the orders at each price, and stop once
+// the deadline of their context is exceeded.
+func TestDumpTrieDeadline(t *testing.T) {
+	orderBook := common.StringToHash("BTC/TOMO")
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+	for i := 1; i <= 10; i++ {
+		order := OrderItem{OrderID: uint64(i), Quantity: big.NewInt(int64(i)), Price: big.NewInt(int64(i%5 + 1)), Side: Ask, Signature: &Signature{}}
+		if i%2 == 0 {
+			order.Side = Bid
+		}
+		statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i))), order)
+	}
+	root := statedb.IntermediateRoot()
+	statedb.Commit()
+	statedb, err := New(root, stateCache)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+
+	dumps := map[string]func(context.Context, common.Hash) (DumpOrderTree, error){
+		Ask: statedb.DumpAskTrie,
+		Bid: statedb.DumpBidTrie,
+	}
+	// An order of each side: its price, ID and quantity
+	samples := map[string][3]string{Ask: {"2", "1", "1"}, Bid: {"3", "2", "2"}}
+	for side, dump := range dumps {
+		result, err := dump(context.Background(), orderBook)
+		if err != nil {
+			t.Fatalf("%s: failed to dump: %v", side, err)
+		}
+		orders := 0
+		for _, list := range result {
+			orders += len(list.Orders)
+		}
+		if orders != 5 {
+			t.Errorf("%s: order count mismatch: have %d, want 5", side, orders)
+		}
+		sample := samples[side]
+		if quantity := result[sample[0]].Orders[sample[1]]; quantity == nil || quantity.String() != sample[2] {
+			t.Errorf("%s: quantity of order %s at %s mismatch: have %v, want %s", side, sample[1], sample[0], quantity, sample[2])
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		if _, err := dump(ctx, orderBook); err != context.DeadlineExceeded {
+			t.Errorf("%s: error mismatch: have %v, want %v", side, err, context.DeadlineExceeded)
+		}
+		cancel()
+	}
+}
+
+func TestDumpOrderTreeEncoding(t *testing.T) {
+	tree := DumpOrderTree{
+		"20": {Volume: big.NewInt(7), Orders: map[string]*big.Int{"3": big.NewInt(4), "12": big.NewInt(3)}},
+		"9":  {Volume: big.NewInt(1), Orders: map[string]*big.Int{"5": big.NewInt(1)}},
+	}
+	// JSON is keyed by the decimal prices and order IDs
+	blob, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("failed to encode JSON: %v", err)
+	}
+	want := `{"20":{"Volume":7,"Orders":{"12":3,"3":4}},"9":{"Volume":1,"Orders":{"5":1}}}`
+	if string(blob) != want {
+		t.Errorf("JSON mismatch:\nhave %s\nwant %s", blob, want)
+	}
+	// RLP lists the order lists and orders sorted numerically
+	enc, err := rlp.EncodeToBytes(tree)
+	if err != nil {
+		t.Fatalf("failed to encode RLP: %v", err)
+	}
+	sorted, _ := rlp.EncodeToBytes([]rlpOrderList{
+		{Price: big.NewInt(9), Volume: big.NewInt(1), Orders: []rlpOrder{{big.NewInt(5), big.NewInt(1)}}},
+		{Price: big.NewInt(20), Volume: big.NewInt(7), Orders: []rlpOrder{{big.NewInt(3), big.NewInt(4)}, {big.NewInt(12), big.NewInt(3)}}},
+	})
+	if !bytes.Equal(enc, sorted) {
+		t.Errorf("RLP mismatch:\nhave %x\nwant %x", enc, sorted)
+	}
+	var dec DumpOrderTree
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatalf("failed to decode RLP: %v", err)
+	}
+	if !reflect.DeepEqual(dec, tree) {
+		t.Errorf("RLP round trip mismatch: have %v, want %v", dec, tree)
+	}
+	if _, err := rlp.EncodeToBytes(DumpOrderTree{"0x10": {Volume: big.NewInt(1)}}); err == nil {
+		t.Error("encoded a tree with an invalid price")
+	}
+}
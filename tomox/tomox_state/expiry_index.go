@@ -0,0 +1,227 @@
+package tomox_state
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// expiryIndexPrefix + block number -> hashed key of the orders expiring at a block in the tomox trie
+	expiryIndexPrefix = []byte("tomox-expiry-index")
+
+	// expiryPrunedKey is the hashed key of the last block the expired orders were
+	// pruned at, kept as a nonce of the tomox state. It only records the cleanup
+	// of the indexes, the expiry of an order depends on the block matching it.
+	expiryPrunedKey = crypto.Keccak256Hash([]byte("tomox-expiry-pruned"))
+
+	// expiryScheduleKey is the hashed key of the index listing the blocks with
+	// orders indexed to expire at, not pruned yet
+	expiryScheduleKey = crypto.Keccak256Hash([]byte("tomox-expiry-schedule"))
+)
+
+// ExpiringOrder locates an order resting in an order book until its expiry.
+type ExpiringOrder struct {
+	BaseToken   common.Address `json:"baseToken"`
+	QuoteToken  common.Address `json:"quoteToken"`
+	OrderBook   common.Hash    `json:"orderBook"`
+	OrderID     uint64         `json:"orderID"`
+	Hash        common.Hash    `json:"hash"`
+	UserAddress common.Address `json:"userAddress"`
+	ExpiryBlock uint64         `json:"expiryBlock"`
+}
+
+// ExpiryIndex holds the orders expiring at a block, in the order they rested.
+// The index of the expiry schedule holds instead the blocks with orders indexed
+// to expire at, in ascending order.
+type ExpiryIndex struct {
+	Orders []ExpiringOrder
+	Blocks []uint64 `rlp:"tail"`
+}
+
+func expiryIndexKey(number uint64) common.Hash {
+	return crypto.Keccak256Hash(expiryIndexPrefix, common.BigToHash(new(big.Int).SetUint64(number)).Bytes())
+}
+
+func (index *ExpiryIndex) copy() *ExpiryIndex {
+	if index == nil {
+		return nil
+	}
+	return &ExpiryIndex{Orders: append([]ExpiringOrder{}, index.Orders...), Blocks: append([]uint64{}, index.Blocks...)}
+}
+
+// GetExpiringOrders returns the orders indexed to expire at the given block,
+// including the ones which left the order book since.
+func (self *TomoXStateDB) GetExpiringOrders(number uint64) []ExpiringOrder {
+	if index := self.getExpiryIndex(expiryIndexKey(number)); index != nil {
+		return index.copy().Orders
+	}
+	return nil
+}
+
+func (self *TomoXStateDB) getExpiryIndex(key common.Hash) *ExpiryIndex {
+	self.readKey(key)
+	if index, ok := self.expiryIndexes[key]; ok {
+		return index
+	}
+	enc, err := self.trie.TryGet(key[:])
+	if len(enc) == 0 {
+		self.setError(err)
+		return nil
+	}
+	index := new(ExpiryIndex)
+	if err := rlp.DecodeBytes(enc, index); err != nil {
+		log.Error("Failed to decode expiry index", "key", key.Hex(), "err", err)
+		return nil
+	}
+	self.expiryIndexes[key] = index
+	return index
+}
+
+func (self *TomoXStateDB) setExpiryIndex(key common.Hash, index *ExpiryIndex) {
+	self.writeKey(key)
+	self.expiryIndexes[key] = index
+	self.expiryIndexesDirty[key] = struct{}{}
+}
+
+// AddExpiringOrder indexes an order resting in an order book to be pruned at
+// its expiry block.
+func (self *TomoXStateDB) AddExpiringOrder(order ExpiringOrder) {
+	key := expiryIndexKey(order.ExpiryBlock)
+	prev := self.getExpiryIndex(key)
+	self.journal = append(self.journal, expiryIndexChange{key: key, prev: prev.copy()})
+
+	index := prev.copy()
+	if index == nil {
+		index = new(ExpiryIndex)
+	}
+	index.Orders = append(index.Orders, order)
+	self.setExpiryIndex(key, index)
+
+	if prev == nil {
+		self.scheduleExpiry(order.ExpiryBlock)
+	}
+}
+
+// scheduleExpiry adds a block to the expiry schedule, keeping it sorted.
+func (self *TomoXStateDB) scheduleExpiry(number uint64) {
+	prev := self.getExpiryIndex(expiryScheduleKey)
+	schedule := prev.copy()
+	if schedule == nil {
+		schedule = new(ExpiryIndex)
+	}
+	i := sort.Search(len(schedule.Blocks), func(i int) bool { return schedule.Blocks[i] >= number })
+	if i < len(schedule.Blocks) && schedule.Blocks[i] == number {
+		return
+	}
+	schedule.Blocks = append(schedule.Blocks, 0)
+	copy(schedule.Blocks[i+1:], schedule.Blocks[i:])
+	schedule.Blocks[i] = number
+
+	self.journal = append(self.journal, expiryIndexChange{key: expiryScheduleKey, prev: prev.copy()})
+	self.setExpiryIndex(expiryScheduleKey, schedule)
+}
+
+// GetRestingExpiringOrders returns the orders expiring at the blocks between
+// the given ones, included, which still rest in their order books.
+func (self *TomoXStateDB) GetRestingExpiringOrders(from, to uint64) []ExpiringOrder {
+	var orders []ExpiringOrder
+	for n := from; n <= to && n != 0; n++ {
+		index := self.getExpiryIndex(expiryIndexKey(n))
+		if index == nil {
+			continue
+		}
+		for _, expiring := range index.Orders {
+			if _, ok := self.restingOrder(expiring); ok {
+				orders = append(orders, expiring)
+			}
+		}
+	}
+	return orders
+}
+
+// restingOrder returns an indexed order if it still rests in its order book,
+// filled, cancelled or amended orders having left it since.
+func (self *TomoXStateDB) restingOrder(expiring ExpiringOrder) (OrderItem, bool) {
+	orderIdHash := common.BigToHash(new(big.Int).SetUint64(expiring.OrderID))
+	order := self.GetOrder(expiring.OrderBook, orderIdHash)
+	if order.Quantity == nil || order.Quantity.Sign() == 0 || order.Hash != expiring.Hash || order.ExpiryBlock != expiring.ExpiryBlock {
+		return order, false
+	}
+	return order, true
+}
+
+// IsOrderExpired reports whether an order expiring at the given block can no
+// longer be matched in the block with the given number, orders good till
+// cancelled never expiring. Orders are matched up to their expiry block
+// included, pruned at the end of it.
+func IsOrderExpired(expiryBlock, number uint64) bool {
+	return expiryBlock != 0 && expiryBlock < number
+}
+
+// updateExpiryIndexes writes the modified expiry indexes to the trie.
+func (self *TomoXStateDB) updateExpiryIndexes() {
+	for key := range self.expiryIndexesDirty {
+		index := self.expiryIndexes[key]
+		if index == nil {
+			self.setError(self.trie.TryDelete(key[:]))
+			continue
+		}
+		data, err := rlp.EncodeToBytes(index)
+		if err != nil {
+			panic(err)
+		}
+		self.setError(self.trie.TryUpdate(key[:], data))
+	}
+	self.expiryIndexesDirty = make(map[common.Hash]struct{})
+}
+
+// PruneExpiredOrders cancels the orders expiring at the blocks up to the given
+// one, which still rest in their order books, and drops their indexes. Only
+// the indexes of the scheduled blocks are visited, so pruning costs the number
+// of expired orders rather than a scan of the order books, and the state is
+// left untouched until an order expiring at the block range is indexed. It
+// returns the orders cancelled.
+func PruneExpiredOrders(number uint64, tomoxStatedb *TomoXStateDB) []OrderItem {
+	schedule := tomoxStatedb.getExpiryIndex(expiryScheduleKey)
+	if schedule == nil || len(schedule.Blocks) == 0 || schedule.Blocks[0] > number {
+		return nil
+	}
+	due := sort.Search(len(schedule.Blocks), func(i int) bool { return schedule.Blocks[i] > number })
+
+	var cancelled []OrderItem
+	for _, n := range schedule.Blocks[:due] {
+		key := expiryIndexKey(n)
+		index := tomoxStatedb.getExpiryIndex(key)
+		if index == nil {
+			continue
+		}
+		for _, expiring := range index.Orders {
+			order, ok := tomoxStatedb.restingOrder(expiring)
+			if !ok {
+				continue
+			}
+			if err := tomoxStatedb.CancelOrder(expiring.OrderBook, &order); err != nil {
+				log.Error("Failed to prune expired order", "orderBook", expiring.OrderBook.Hex(), "orderID", expiring.OrderID, "err", err)
+				continue
+			}
+			cancelled = append(cancelled, order)
+		}
+		tomoxStatedb.journal = append(tomoxStatedb.journal, expiryIndexChange{key: key, prev: index.copy()})
+		tomoxStatedb.setExpiryIndex(key, nil)
+	}
+	// Drop the pruned blocks from the schedule, and the schedule once empty
+	tomoxStatedb.journal = append(tomoxStatedb.journal, expiryIndexChange{key: expiryScheduleKey, prev: schedule.copy()})
+	if due == len(schedule.Blocks) {
+		tomoxStatedb.setExpiryIndex(expiryScheduleKey, nil)
+	} else {
+		tomoxStatedb.setExpiryIndex(expiryScheduleKey, &ExpiryIndex{Blocks: append([]uint64{}, schedule.Blocks[due:]...)})
+	}
+	tomoxStatedb.SetNonce(expiryPrunedKey, number)
+	log.Debug("Pruned expired orders", "number", number, "blocks", due, "cancelled", len(cancelled))
+	return cancelled
+}
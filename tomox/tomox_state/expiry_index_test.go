@@ -0,0 +1,87 @@
+package tomox_state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestPruneExpiredOrders(t *testing.T) {
+	orderBook := common.StringToHash("BTC/TOMO")
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+
+	// Pruning leaves the state untouched until orders are indexed
+	root := statedb.IntermediateRoot()
+	if cancelled := PruneExpiredOrders(5, statedb); len(cancelled) != 0 || statedb.IntermediateRoot() != root {
+		t.Fatalf("pruning without expiring orders: %d orders cancelled, root changed %v", len(cancelled), statedb.IntermediateRoot() != root)
+	}
+	rest := func(id uint64, expiry uint64) OrderItem {
+		order := OrderItem{OrderID: id, Quantity: big.NewInt(10), Price: big.NewInt(100), Side: Ask, Hash: common.BigToHash(new(big.Int).SetUint64(id)), ExpiryBlock: expiry, Signature: &Signature{V: 1}}
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(id)), order)
+		statedb.AddExpiringOrder(ExpiringOrder{OrderBook: orderBook, OrderID: id, Hash: order.Hash, ExpiryBlock: expiry})
+		return order
+	}
+	rest(1, 7)
+	cancelled := rest(2, 7)
+	rest(3, 9)
+	rest(4, 3)
+	if err := statedb.CancelOrder(orderBook, &cancelled); err != nil {
+		t.Fatalf("failed to cancel order: %v", err)
+	}
+	if orders := statedb.GetRestingExpiringOrders(6, 8); len(orders) != 1 || orders[0].OrderID != 1 {
+		t.Fatalf("resting expiring orders mismatch: have %+v, want order 1", orders)
+	}
+	if IsOrderExpired(3, 3) || IsOrderExpired(7, 7) || IsOrderExpired(0, 100) {
+		t.Errorf("expiries mismatch: have %v/%v/%v for blocks 3/7/0, want false/false/false", IsOrderExpired(3, 3), IsOrderExpired(7, 7), IsOrderExpired(0, 100))
+	}
+	statedb.IntermediateRoot()
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	statedb, _ = New(root, stateCache)
+
+	// Pruning is reverted along the state
+	snapshot := statedb.Snapshot()
+	PruneExpiredOrders(8, statedb)
+	statedb.RevertToSnapshot(snapshot)
+	if orders := statedb.GetRestingExpiringOrders(3, 7); len(orders) != 2 {
+		t.Fatalf("reverted pruning mismatch: have %d orders, want 2", len(orders))
+	}
+	// The orders expiring up to the block are cancelled, from the first one
+	// indexed on, the others rest
+	cancelledOrders := PruneExpiredOrders(8, statedb)
+	if len(cancelledOrders) != 2 || cancelledOrders[0].OrderID != 4 || cancelledOrders[1].OrderID != 1 {
+		t.Fatalf("pruned orders mismatch: have %+v, want orders 4 and 1", cancelledOrders)
+	}
+	if ask, volume := statedb.GetBestAskPrice(orderBook); ask.Int64() != 100 || volume.Int64() != 10 {
+		t.Errorf("best ask mismatch: have %v/%v, want 100/10", ask, volume)
+	}
+	if orders := statedb.GetExpiringOrders(3); orders != nil {
+		t.Errorf("pruned index kept: %+v", orders)
+	}
+	if orders := statedb.GetExpiringOrders(7); orders != nil {
+		t.Errorf("pruned index kept: %+v", orders)
+	}
+	if orders := statedb.GetRestingExpiringOrders(9, 9); len(orders) != 1 || orders[0].OrderID != 3 {
+		t.Errorf("unexpired orders mismatch: have %+v, want order 3", orders)
+	}
+	if !IsOrderExpired(7, 8) || IsOrderExpired(9, 8) {
+		t.Errorf("expiries mismatch in block 8: have %v/%v for blocks 7/9, want true/false", IsOrderExpired(7, 8), IsOrderExpired(9, 8))
+	}
+	// Pruning before the next expiry leaves the state untouched
+	root = statedb.IntermediateRoot()
+	if cancelled := PruneExpiredOrders(8, statedb); len(cancelled) != 0 || statedb.IntermediateRoot() != root {
+		t.Errorf("pruning before the next expiry: %d orders cancelled, root changed %v", len(cancelled), statedb.IntermediateRoot() != root)
+	}
+	if cancelled := PruneExpiredOrders(10, statedb); len(cancelled) != 1 || cancelled[0].OrderID != 3 {
+		t.Errorf("pruned orders mismatch: have %+v, want order 3", cancelled)
+	}
+	if orders := statedb.GetExpiringOrders(9); orders != nil {
+		t.Errorf("pruned index kept: %+v", orders)
+	}
+}
@@ -53,6 +53,22 @@ type (
 		hash common.Hash
 		prev *big.Int
 	}
+	relayerFeeChange struct {
+		key  common.Hash
+		prev *big.Int
+	}
+	priceHistoryChange struct {
+		key  common.Hash
+		prev *PriceHistory
+	}
+	expiryIndexChange struct {
+		key  common.Hash
+		prev *ExpiryIndex
+	}
+	exchangeObjectChange struct {
+		hash common.Hash
+		prev *stateExchanges
+	}
 )
 
 func (ch insertOrder) undo(s *TomoXStateDB) {
@@ -85,3 +101,19 @@ func (ch nonceChange) undo(s *TomoXStateDB) {
 func (ch priceChange) undo(s *TomoXStateDB) {
 	s.SetPrice(ch.hash, ch.prev)
 }
+func (ch relayerFeeChange) undo(s *TomoXStateDB) {
+	s.setRelayerFee(ch.key, ch.prev)
+}
+func (ch priceHistoryChange) undo(s *TomoXStateDB) {
+	s.setPriceHistory(ch.key, ch.prev)
+}
+func (ch expiryIndexChange) undo(s *TomoXStateDB) {
+	s.setExpiryIndex(ch.key, ch.prev)
+}
+func (ch exchangeObjectChange) undo(s *TomoXStateDB) {
+	if ch.prev == nil {
+		delete(s.stateExhangeObjects, ch.hash)
+		return
+	}
+	s.stateExhangeObjects[ch.hash] = ch.prev
+}
@@ -0,0 +1,30 @@
+package tomox_state
+
+import (
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// matchingVersionKey is the hashed key of the version of the matching rules
+// the orders are applied with, kept as a nonce of the tomox state
+var matchingVersionKey = crypto.Keccak256Hash([]byte("tomox-matching-version"))
+
+// MatchingVersion returns the version of the matching rules of the state, 0
+// until a fork upgrades them.
+func (self *TomoXStateDB) MatchingVersion() uint64 {
+	return self.GetNonce(matchingVersionKey)
+}
+
+// UpgradeMatchingVersion upgrades the matching rules of the state to the given
+// version, before the orders of a block are applied. The rules are never
+// downgraded, and left untouched until the first fork so that the states of
+// the blocks before it keep their roots. It reports whether they changed.
+func UpgradeMatchingVersion(version uint64, tomoxStatedb *TomoXStateDB) bool {
+	current := tomoxStatedb.MatchingVersion()
+	if version <= current {
+		return false
+	}
+	tomoxStatedb.SetNonce(matchingVersionKey, version)
+	log.Info("Upgraded the TomoX matching rules", "from", current, "to", version)
+	return true
+}
@@ -40,6 +40,13 @@ type OrderItem struct {
 	PrevOrder []byte `json:"-"`
 	OrderList []byte `json:"-"`
 	Key       string `json:"key"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty" rlp:"optional"`
+	ExpiryBlock         uint64 `json:"expiryBlock,omitempty" rlp:"optional"` // Last block the order can be matched in, zero if good till cancelled
+
+	// RejectReason tells why the matching engine rejected the order, it is
+	// reported in the matching results only and not kept in the state
+	RejectReason string `json:"rejectReason,omitempty" rlp:"-"`
 }
 
 // Signature struct
@@ -78,6 +85,9 @@ type OrderItemBSON struct {
 	PrevOrder       string           `json:"prevOrder,omitempty" bson:"prevOrder"`
 	OrderList       string           `json:"orderList,omitempty" bson:"orderList"`
 	Key             string           `json:"key" bson:"key"`
+
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty" bson:"selfTradePrevention,omitempty"`
+	ExpiryBlock         uint64 `json:"expiryBlock,omitempty" bson:"expiryBlock,omitempty"`
 }
 
 func (o *OrderItem) GetBSON() (interface{}, error) {
@@ -99,6 +109,9 @@ func (o *OrderItem) GetBSON() (interface{}, error) {
 		UpdatedAt:       o.UpdatedAt,
 		OrderID:         strconv.FormatUint(o.OrderID, 10),
 		Key:             o.Key,
+
+		SelfTradePrevention: o.SelfTradePrevention,
+		ExpiryBlock:         o.ExpiryBlock,
 	}
 
 	if o.FilledAmount != nil {
@@ -140,6 +153,9 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 		UpdatedAt       time.Time        `json:"updatedAt" bson:"updatedAt"`
 		OrderID         string           `json:"orderID" bson:"orderID"`
 		Key             string           `json:"key" bson:"key"`
+
+		SelfTradePrevention string `json:"selfTradePrevention" bson:"selfTradePrevention"`
+		ExpiryBlock         uint64 `json:"expiryBlock" bson:"expiryBlock"`
 	})
 
 	err := raw.Unmarshal(decoded)
@@ -188,6 +204,8 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 	}
 	o.OrderID = uint64(orderID)
 	o.Key = decoded.Key
+	o.SelfTradePrevention = decoded.SelfTradePrevention
+	o.ExpiryBlock = decoded.ExpiryBlock
 
 	return nil
 }
@@ -209,6 +227,11 @@ func (o *OrderItem) VerifyOrder(state *state.StateDB) error {
 	if err := VerifyPair(state, o.ExchangeAddress, o.BaseToken, o.QuoteToken); err != nil {
 		return err
 	}
+	if o.Status != Cancel {
+		if err := VerifyPairSize(state, o.BaseToken, o.QuoteToken, o.Type, o.Price, o.Quantity); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -228,6 +251,9 @@ func (o *OrderItem) VerifyBasicOrderInfo() error {
 	if err := o.verifyOrderType(); err != nil {
 		return err
 	}
+	if err := o.verifySelfTradePrevention(); err != nil {
+		return err
+	}
 	if err := o.verifySignature(); err != nil {
 		return err
 	}
@@ -242,6 +268,9 @@ func (o *OrderItem) verifyRelayer(state *state.StateDB) error {
 	return nil
 }
 
+// orderHashExtensionVersion prefixes the optional fields of an order hash.
+const orderHashExtensionVersion = 1
+
 // following: https://github.com/tomochain/tomox-sdk/blob/master/types/order.go#L125
 func (o *OrderItem) ComputeHash() common.Hash {
 	sha := sha3.NewKeccak256()
@@ -257,6 +286,13 @@ func (o *OrderItem) ComputeHash() common.Hash {
 	sha.Write([]byte(o.Status))
 	sha.Write([]byte(o.Type))
 	sha.Write(common.BigToHash(o.Nonce).Bytes())
+	// Mirrors the optional fields of types.OrderTxSigner.OrderCreateHash
+	if o.SelfTradePrevention != "" || o.ExpiryBlock != 0 {
+		sha.Write([]byte{orderHashExtensionVersion})
+		sha.Write(common.BigToHash(big.NewInt(int64(len(o.SelfTradePrevention)))).Bytes())
+		sha.Write([]byte(o.SelfTradePrevention))
+		sha.Write(common.BigToHash(new(big.Int).SetUint64(o.ExpiryBlock)).Bytes())
+	}
 	return common.BytesToHash(sha.Sum(nil))
 }
 
@@ -310,6 +346,15 @@ func (o *OrderItem) verifyOrderType() error {
 	return nil
 }
 
+// verify self-trade prevention mode
+func (o *OrderItem) verifySelfTradePrevention() error {
+	if _, ok := SelfTradePreventionMode[o.SelfTradePrevention]; !ok {
+		log.Debug("Invalid self-trade prevention", "mode", o.SelfTradePrevention)
+		return ErrInvalidSelfTradePrevention
+	}
+	return nil
+}
+
 //verify order side
 func (o *OrderItem) verifyOrderSide() error {
 
@@ -397,17 +442,9 @@ func (s *Signature) MarshalSignature() ([]byte, error) {
 
 // Verify returns the address that corresponds to the given signature and signed message
 func (s *Signature) Verify(hash common.Hash) (common.Address, error) {
-
-	hashBytes := hash.Bytes()
 	sigBytes, err := s.MarshalSignature()
 	if err != nil {
 		return common.Address{}, err
 	}
-
-	pubKey, err := crypto.SigToPub(hashBytes, sigBytes)
-	if err != nil {
-		return common.Address{}, err
-	}
-	address := crypto.PubkeyToAddress(*pubKey)
-	return address, nil
+	return RecoverSigner(hash, sigBytes)
 }
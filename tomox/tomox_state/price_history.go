@@ -0,0 +1,203 @@
+package tomox_state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// priceHistoryPrefix + base token + quote token -> hashed key of the price history of a pair in the tomox trie
+var priceHistoryPrefix = []byte("tomox-price-history")
+
+// priceOracleKey is the hashed key of the activation flag of the price oracle,
+// kept as a nonce of the tomox state
+var priceOracleKey = crypto.Keccak256Hash([]byte("tomox-price-oracle"))
+
+// PricePoint sums the trades of a pair matched in a block.
+type PricePoint struct {
+	Number      uint64   // Block number, zero for the block being processed
+	Volume      *big.Int // Traded base token quantity
+	QuoteVolume *big.Int // Sum of the traded quantities weighted by their prices
+}
+
+// PriceHistory holds the trades of a pair matched in the last blocks, in block
+// order.
+type PriceHistory struct {
+	BaseToken  common.Address
+	QuoteToken common.Address
+	Points     []PricePoint
+}
+
+func priceHistoryKey(baseToken, quoteToken common.Address) common.Hash {
+	return crypto.Keccak256Hash(priceHistoryPrefix, baseToken[:], quoteToken[:])
+}
+
+func (history *PriceHistory) copy() *PriceHistory {
+	if history == nil {
+		return nil
+	}
+	cpy := &PriceHistory{BaseToken: history.BaseToken, QuoteToken: history.QuoteToken, Points: make([]PricePoint, len(history.Points))}
+	for i, point := range history.Points {
+		cpy.Points[i] = PricePoint{Number: point.Number, Volume: new(big.Int).Set(point.Volume), QuoteVolume: new(big.Int).Set(point.QuoteVolume)}
+	}
+	return cpy
+}
+
+// AveragePrice returns the volume weighted average price of the trades matched
+// since the given block, along with their volume. The price is nil if there is
+// no such trade.
+func (history *PriceHistory) AveragePrice(since uint64) (*big.Int, *big.Int) {
+	volume, quoteVolume := new(big.Int), new(big.Int)
+	if history != nil {
+		for _, point := range history.Points {
+			if point.Number >= since {
+				volume.Add(volume, point.Volume)
+				quoteVolume.Add(quoteVolume, point.QuoteVolume)
+			}
+		}
+	}
+	if volume.Sign() == 0 {
+		return nil, volume
+	}
+	return quoteVolume.Div(quoteVolume, volume), volume
+}
+
+// GetPriceHistory returns the trades of a pair matched in the last blocks, nil
+// if the pair was never traded.
+func (self *TomoXStateDB) GetPriceHistory(baseToken, quoteToken common.Address) *PriceHistory {
+	return self.getPriceHistory(priceHistoryKey(baseToken, quoteToken)).copy()
+}
+
+func (self *TomoXStateDB) getPriceHistory(key common.Hash) *PriceHistory {
+	self.readKey(key)
+	if history, ok := self.priceHistories[key]; ok {
+		return history
+	}
+	enc, err := self.trie.TryGet(key[:])
+	if len(enc) == 0 {
+		self.setError(err)
+		return nil
+	}
+	history := new(PriceHistory)
+	if err := rlp.DecodeBytes(enc, history); err != nil {
+		log.Error("Failed to decode price history", "key", key.Hex(), "err", err)
+		return nil
+	}
+	self.priceHistories[key] = history
+	return history
+}
+
+func (self *TomoXStateDB) setPriceHistory(key common.Hash, history *PriceHistory) {
+	self.writeKey(key)
+	self.priceHistories[key] = history
+	self.priceHistoriesDirty[key] = struct{}{}
+}
+
+// PriceOracleActive reports whether the trades are recorded for the price
+// oracle, which is left untouched until its fork so that the states of the
+// blocks before it keep their roots.
+func (self *TomoXStateDB) PriceOracleActive() bool {
+	return self.GetNonce(priceOracleKey) != 0
+}
+
+// ActivatePriceOracle starts recording the trades for the price oracle, before
+// the orders of the first block of its fork are applied.
+func ActivatePriceOracle(tomoxStatedb *TomoXStateDB) {
+	if !tomoxStatedb.PriceOracleActive() {
+		tomoxStatedb.SetNonce(priceOracleKey, 1)
+		log.Info("Activated the TomoX price oracle")
+	}
+}
+
+// AddTradeVolume records a trade of a pair in the block being processed, once
+// the price oracle is active.
+func (self *TomoXStateDB) AddTradeVolume(baseToken, quoteToken common.Address, price, quantity *big.Int) {
+	if !self.PriceOracleActive() {
+		return
+	}
+	key := priceHistoryKey(baseToken, quoteToken)
+	prev := self.getPriceHistory(key)
+	self.journal = append(self.journal, priceHistoryChange{key: key, prev: prev.copy()})
+
+	history := prev.copy()
+	if history == nil {
+		history = &PriceHistory{BaseToken: baseToken, QuoteToken: quoteToken}
+	}
+	if n := len(history.Points); n == 0 || history.Points[n-1].Number != 0 {
+		history.Points = append(history.Points, PricePoint{Volume: new(big.Int), QuoteVolume: new(big.Int)})
+	}
+	point := &history.Points[len(history.Points)-1]
+	point.Volume.Add(point.Volume, quantity)
+	point.QuoteVolume.Add(point.QuoteVolume, new(big.Int).Mul(price, quantity))
+	self.setPriceHistory(key, history)
+}
+
+// updatePriceHistories writes the modified price histories to the trie.
+func (self *TomoXStateDB) updatePriceHistories() {
+	for key := range self.priceHistoriesDirty {
+		history := self.priceHistories[key]
+		if history == nil {
+			self.setError(self.trie.TryDelete(key[:]))
+			continue
+		}
+		data, err := rlp.EncodeToBytes(history)
+		if err != nil {
+			panic(err)
+		}
+		self.setError(self.trie.TryUpdate(key[:], data))
+	}
+	self.priceHistoriesDirty = make(map[common.Hash]struct{})
+}
+
+// UpdatePriceOracle closes the trades of the pairs traded in the block with
+// the given number, dropping the ones older than the price window, and
+// publishes the new average prices of these pairs in the storage of the price
+// oracle account, where smart contracts read them. Nothing is written until the
+// price oracle is active.
+func UpdatePriceOracle(number uint64, tomoxStatedb *TomoXStateDB, statedb *state.StateDB) {
+	if !tomoxStatedb.PriceOracleActive() {
+		return
+	}
+	keys := make([]common.Hash, 0, len(tomoxStatedb.priceHistories))
+	for key, history := range tomoxStatedb.priceHistories {
+		if history != nil && len(history.Points) > 0 && history.Points[len(history.Points)-1].Number == 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+	oracle := common.HexToAddress(common.TomoXPriceOracle)
+	for _, key := range keys {
+		prev := tomoxStatedb.priceHistories[key]
+		tomoxStatedb.journal = append(tomoxStatedb.journal, priceHistoryChange{key: key, prev: prev.copy()})
+
+		history := prev.copy()
+		history.Points[len(history.Points)-1].Number = number
+		since := uint64(0)
+		if number >= common.TomoXPriceWindow {
+			since = number - common.TomoXPriceWindow + 1
+		}
+		for len(history.Points) > 0 && history.Points[0].Number < since {
+			history.Points = history.Points[1:]
+		}
+		tomoxStatedb.setPriceHistory(key, history)
+
+		price, _ := history.AveragePrice(since)
+		priceSlot, numberSlot := vm.TomoXPriceSlots(history.BaseToken, history.QuoteToken)
+		if statedb.GetNonce(oracle) == 0 {
+			// Keep the oracle account from being deleted as an empty account
+			statedb.SetNonce(oracle, 1)
+		}
+		statedb.SetState(oracle, priceSlot, common.BigToHash(price))
+		statedb.SetState(oracle, numberSlot, common.BigToHash(new(big.Int).SetUint64(number)))
+		log.Debug("Update price oracle", "base", history.BaseToken.Hex(), "quote", history.QuoteToken.Hex(), "number", number, "price", price)
+	}
+}
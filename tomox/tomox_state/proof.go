@@ -0,0 +1,156 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tomox_state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ProofList collects the trie nodes of a merkle proof.
+type ProofList [][]byte
+
+// Put implements ethdb.Putter, appending the node.
+func (l *ProofList) Put(key []byte, value []byte) error {
+	*l = append(*l, common.CopyBytes(value))
+	return nil
+}
+
+// EntryProof proves an entry of an order book against a TomoX state root,
+// either an order of its order index or a price level of one of its sides.
+// The exchange proof proves the order book object under the order book hash
+// in the state trie, the entry proof the entry under the root of the order
+// book trie holding it. Missing entries are proven by proofs of absence.
+type EntryProof struct {
+	OrderBook     common.Hash
+	Exchange      []byte // RLP encoding of the order book object, nil if missing
+	ExchangeProof ProofList
+	Side          string      // Side of the price level, empty for orders
+	Key           common.Hash // Order ID or price
+	Value         []byte      // RLP encoding of the order or price level, nil if missing
+	Proof         ProofList
+}
+
+// ProveOrder returns the proof of an order of the order index of an order book.
+func (self *TomoXStateDB) ProveOrder(orderBook common.Hash, orderId common.Hash) (*EntryProof, error) {
+	return self.proveEntry(orderBook, "", orderId)
+}
+
+// ProvePriceLevel returns the proof of a price level of a side of an order book.
+func (self *TomoXStateDB) ProvePriceLevel(orderBook common.Hash, side string, price common.Hash) (*EntryProof, error) {
+	if side != Ask && side != Bid {
+		return nil, fmt.Errorf("invalid side %q", side)
+	}
+	return self.proveEntry(orderBook, side, price)
+}
+
+func (self *TomoXStateDB) proveEntry(orderBook common.Hash, side string, key common.Hash) (*EntryProof, error) {
+	proof := &EntryProof{OrderBook: orderBook, Side: side, Key: key}
+	if err := self.trie.Prove(orderBook[:], 0, &proof.ExchangeProof); err != nil {
+		return nil, err
+	}
+	exchange, err := self.trie.TryGet(orderBook[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(exchange) == 0 {
+		return proof, nil
+	}
+	proof.Exchange = exchange
+
+	exchangeObject := self.getStateExchangeObject(orderBook)
+	if exchangeObject == nil {
+		return nil, fmt.Errorf("undecodable order book %s", orderBook.Hex())
+	}
+	tr, err := self.db.OpenStorageTrie(orderBook, entryRoot(exchangeObject.data, side))
+	if err != nil {
+		return nil, err
+	}
+	if err := tr.Prove(key[:], 0, &proof.Proof); err != nil {
+		return nil, err
+	}
+	if proof.Value, err = tr.TryGet(key[:]); err != nil {
+		return nil, err
+	}
+	if len(proof.Value) == 0 {
+		proof.Value = nil
+	}
+	return proof, nil
+}
+
+// entryRoot returns the root of the trie of an order book holding the orders
+// or the price levels of a side.
+func entryRoot(data exchangeObject, side string) common.Hash {
+	switch side {
+	case Ask:
+		return data.AskRoot
+	case Bid:
+		return data.BidRoot
+	}
+	return data.OrderRoot
+}
+
+// Verify checks the proof against a TomoX state root, and that the order book
+// object and the entry are the proven values.
+func (p *EntryProof) Verify(root common.Hash) error {
+	exchange, err := verifyProof(root, p.OrderBook[:], p.ExchangeProof)
+	if err != nil {
+		return fmt.Errorf("invalid order book proof: %v", err)
+	}
+	if !bytes.Equal(exchange, p.Exchange) {
+		return errors.New("order book object mismatch")
+	}
+	if exchange == nil {
+		if p.Value != nil {
+			return errors.New("entry of a missing order book")
+		}
+		return nil
+	}
+	var data exchangeObject
+	if err := rlp.DecodeBytes(exchange, &data); err != nil {
+		return fmt.Errorf("invalid order book object: %v", err)
+	}
+	value, err := verifyProof(entryRoot(data, p.Side), p.Key[:], p.Proof)
+	if err != nil {
+		return fmt.Errorf("invalid entry proof: %v", err)
+	}
+	if !bytes.Equal(value, p.Value) {
+		return errors.New("entry mismatch")
+	}
+	return nil
+}
+
+// verifyProof returns the value of a key proven under a trie root, nil if the
+// key is proven missing.
+func verifyProof(root common.Hash, key []byte, proof ProofList) ([]byte, error) {
+	if root == EmptyRoot || root == EmptyHash {
+		return nil, nil
+	}
+	db, _ := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	value, err, _ := trie.VerifyProof(root, key, db)
+	return value, err
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tomox_state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestEntryProof(t *testing.T) {
+	orderBook := common.StringToHash("BTC/TOMO")
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+	for i := uint64(1); i <= 20; i++ {
+		order := OrderItem{OrderID: i, Side: Ask, Price: new(big.Int).SetUint64(100 + i%4), Quantity: new(big.Int).SetUint64(i), Signature: &Signature{}}
+		if i%2 == 0 {
+			order.Side, order.Price = Bid, new(big.Int).SetUint64(50+i%4)
+		}
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(i)), order)
+	}
+	root := statedb.IntermediateRoot()
+	statedb.Commit()
+	statedb, err := New(root, stateCache)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+
+	// An existing order and price level
+	proof, err := statedb.ProveOrder(orderBook, common.BigToHash(big.NewInt(7)))
+	if err != nil {
+		t.Fatalf("failed to prove order: %v", err)
+	}
+	if err := proof.Verify(root); err != nil {
+		t.Fatalf("order proof rejected: %v", err)
+	}
+	var order OrderItem
+	if err := rlp.DecodeBytes(proof.Value, &order); err != nil || order.OrderID != 7 || order.Quantity.Uint64() != 7 {
+		t.Errorf("proven order mismatch: have %+v, %v", order, err)
+	}
+	proof, err = statedb.ProvePriceLevel(orderBook, Bid, common.BigToHash(big.NewInt(52)))
+	if err != nil {
+		t.Fatalf("failed to prove price level: %v", err)
+	}
+	if err := proof.Verify(root); err != nil {
+		t.Fatalf("price level proof rejected: %v", err)
+	}
+	var level orderList
+	if err := rlp.DecodeBytes(proof.Value, &level); err != nil || level.Volume.Uint64() != 2+6+10+14+18 {
+		t.Errorf("proven price level mismatch: have %+v, %v", level, err)
+	}
+	// Tampered values are rejected
+	proof.Value = common.CopyBytes(proof.Value)
+	proof.Value[len(proof.Value)-1]++
+	if err := proof.Verify(root); err == nil {
+		t.Error("tampered price level accepted")
+	}
+	if err := proof.Verify(common.HexToHash("0x01")); err == nil {
+		t.Error("proof accepted against another root")
+	}
+
+	// Missing entries and order books are proven absent
+	for _, key := range []common.Hash{common.BigToHash(big.NewInt(99)), common.BigToHash(big.NewInt(1000))} {
+		proof, err := statedb.ProveOrder(orderBook, key)
+		if err != nil {
+			t.Fatalf("failed to prove missing order: %v", err)
+		}
+		if proof.Value != nil || proof.Verify(root) != nil {
+			t.Errorf("missing order %v: value %x, verification %v", key.Big(), proof.Value, proof.Verify(root))
+		}
+	}
+	proof, err = statedb.ProvePriceLevel(common.StringToHash("ETH/TOMO"), Ask, common.BigToHash(big.NewInt(1)))
+	if err != nil {
+		t.Fatalf("failed to prove missing order book: %v", err)
+	}
+	if proof.Exchange != nil || proof.Verify(root) != nil {
+		t.Errorf("missing order book: object %x, verification %v", proof.Exchange, proof.Verify(root))
+	}
+	if _, err := statedb.ProvePriceLevel(orderBook, "", common.Hash{}); err == nil {
+		t.Error("proved a price level of no side")
+	}
+}
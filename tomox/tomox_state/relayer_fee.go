@@ -0,0 +1,109 @@
+package tomox_state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// relayerFeePrefix + relayer address -> hashed key of the relayer fee in the tomox trie
+var relayerFeePrefix = []byte("tomox-relayer-fee")
+
+func relayerFeeKey(relayer common.Address) common.Hash {
+	return crypto.Keccak256Hash(relayerFeePrefix, relayer[:])
+}
+
+func copyFee(fee *big.Int) *big.Int {
+	if fee == nil {
+		return nil
+	}
+	return new(big.Int).Set(fee)
+}
+
+// GetRelayerFee returns the trade fee rate of a relayer cached in the tomox
+// state, nil if it is not cached. The rate is expressed in 1/TomoXBaseFee of
+// the traded quote token quantity and charged to both makers and takers.
+func (self *TomoXStateDB) GetRelayerFee(relayer common.Address) *big.Int {
+	return copyFee(self.getRelayerFee(relayerFeeKey(relayer)))
+}
+
+func (self *TomoXStateDB) getRelayerFee(key common.Hash) *big.Int {
+	self.readKey(key)
+	if fee, ok := self.relayerFees[key]; ok {
+		return fee
+	}
+	enc, err := self.trie.TryGet(key[:])
+	if len(enc) == 0 {
+		self.setError(err)
+		return nil
+	}
+	fee := new(big.Int)
+	if err := rlp.DecodeBytes(enc, fee); err != nil {
+		log.Error("Failed to decode relayer fee", "key", key.Hex(), "err", err)
+		return nil
+	}
+	self.relayerFees[key] = fee
+	return fee
+}
+
+// SetRelayerFee caches the trade fee rate of a relayer in the tomox state.
+func (self *TomoXStateDB) SetRelayerFee(relayer common.Address, fee *big.Int) {
+	key := relayerFeeKey(relayer)
+	self.journal = append(self.journal, relayerFeeChange{
+		key:  key,
+		prev: self.GetRelayerFee(relayer),
+	})
+	self.setRelayerFee(key, copyFee(fee))
+}
+
+func (self *TomoXStateDB) setRelayerFee(key common.Hash, fee *big.Int) {
+	self.writeKey(key)
+	self.relayerFees[key] = fee
+	self.relayerFeesDirty[key] = struct{}{}
+}
+
+// updateRelayerFees writes the modified relayer fees to the trie.
+func (self *TomoXStateDB) updateRelayerFees() {
+	for key := range self.relayerFeesDirty {
+		fee := self.relayerFees[key]
+		if fee == nil {
+			self.setError(self.trie.TryDelete(key[:]))
+			continue
+		}
+		data, err := rlp.EncodeToBytes(fee)
+		if err != nil {
+			panic(err)
+		}
+		self.setError(self.trie.TryUpdate(key[:], data))
+	}
+	self.relayerFeesDirty = make(map[common.Hash]struct{})
+}
+
+// RefreshRelayerFees caches the trade fee rates of all the registered relayers,
+// as read from the registration contract. It is run at each epoch checkpoint
+// from the relayer fee fork on, so that rates updated by relayers take effect
+// at the next epoch.
+func RefreshRelayerFees(tomoxStatedb *TomoXStateDB, statedb *state.StateDB) {
+	for _, relayer := range getCoinbaseList(statedb) {
+		fee := GetExRelayerFee(relayer, statedb)
+		if cached := tomoxStatedb.GetRelayerFee(relayer); cached != nil && cached.Cmp(fee) == 0 {
+			continue
+		}
+		log.Debug("Refresh relayer fee", "relayer", relayer.Hex(), "fee", fee)
+		tomoxStatedb.SetRelayerFee(relayer, fee)
+	}
+}
+
+// GetTradingFee returns the fee rate to apply to the trades of a relayer, the
+// one cached at the last epoch checkpoint if any. The rate of a relayer not
+// cached yet is read from the registration contract.
+func GetTradingFee(relayer common.Address, tomoxStatedb *TomoXStateDB, statedb *state.StateDB) *big.Int {
+	if fee := tomoxStatedb.GetRelayerFee(relayer); fee != nil {
+		return fee
+	}
+	return GetExRelayerFee(relayer, statedb)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/pkg/errors"
@@ -41,6 +42,11 @@ func getCoinbaseList(statedb *state.StateDB) []common.Address {
 	return listCoinBase
 }
 
+// GetCoinbaseList returns the coinbases of all the registered relayers.
+func GetCoinbaseList(statedb *state.StateDB) []common.Address {
+	return getCoinbaseList(statedb)
+}
+
 //GetCoinbaseFeeList get add coinbase fee
 func GetCoinbaseFeeList(statedb *state.StateDB) map[common.Address]*big.Int {
 	log.Info("GetCoinbaseFeeList start...")
@@ -70,6 +76,34 @@ func GetRelayerOwner(relayer common.Address, statedb *state.StateDB) common.Addr
 	return common.BytesToAddress(statedb.GetState(common.HexToAddress(common.RelayerRegistrationSMC), locHash).Bytes())
 }
 
+// IsPairHalted returns whether the matching of a pair was halted by the owner
+// of the relayer registration contract.
+func IsPairHalted(statedb *state.StateDB, baseToken, quoteToken common.Address) bool {
+	slot := vm.TomoXPairHaltSlot(baseToken, quoteToken)
+	return statedb.GetState(common.HexToAddress(common.TomoXPairHalt), slot) != (common.Hash{})
+}
+
+// GetPairSize returns the tick size and the lot size of a pair, zero when they
+// are not set.
+func GetPairSize(statedb *state.StateDB, baseToken, quoteToken common.Address) (*big.Int, *big.Int) {
+	account := common.HexToAddress(common.TomoXPairSize)
+	tick, lot := vm.TomoXPairSizeSlots(baseToken, quoteToken)
+	return statedb.GetState(account, tick).Big(), statedb.GetState(account, lot).Big()
+}
+
+// VerifyPairSize checks that the price of a limit order is a multiple of the
+// tick size of its pair and that its quantity is a multiple of the lot size.
+func VerifyPairSize(statedb *state.StateDB, baseToken, quoteToken common.Address, orderType string, price, quantity *big.Int) error {
+	tickSize, lotSize := GetPairSize(statedb, baseToken, quoteToken)
+	if orderType == Limit && tickSize.Sign() > 0 && (price == nil || new(big.Int).Mod(price, tickSize).Sign() != 0) {
+		return ErrInvalidTickSize
+	}
+	if lotSize.Sign() > 0 && (quantity == nil || quantity.Sign() <= 0 || new(big.Int).Mod(quantity, lotSize).Sign() != 0) {
+		return ErrInvalidLotSize
+	}
+	return nil
+}
+
 func GetBaseTokenLength(relayer common.Address, statedb *state.StateDB) uint64 {
 	slot := RelayerMappingSlot["RELAYER_LIST"]
 	locBig := GetLocMappingAtKey(relayer.Hash(), slot)
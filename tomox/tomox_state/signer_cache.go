@@ -0,0 +1,33 @@
+package tomox_state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Number of recovered order signers to keep, enough for the orders of the
+// blocks of a sync batch.
+const signerCacheSize = 65536
+
+// signerCache maps the hash of a signed message and its signature to the
+// address recovered from them, so that orders recovered ahead of the block
+// processing are not recovered again when applied.
+var signerCache, _ = lru.New(signerCacheSize)
+
+// RecoverSigner returns the address of the key which produced the signature
+// of the given hash, looking it up in the cache of recovered signers first.
+// The signature is in the [R || S || V] format with V being 0 or 1.
+func RecoverSigner(hash common.Hash, sig []byte) (common.Address, error) {
+	key := crypto.Keccak256Hash(hash.Bytes(), sig)
+	if addr, ok := signerCache.Get(key); ok {
+		return addr.(common.Address), nil
+	}
+	pub, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	addr := crypto.PubkeyToAddress(*pub)
+	signerCache.Add(key, addr)
+	return addr, nil
+}
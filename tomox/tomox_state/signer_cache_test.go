@@ -0,0 +1,38 @@
+package tomox_state
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestRecoverSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	hash := crypto.Keccak256Hash([]byte("order"))
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	cacheKey := crypto.Keccak256Hash(hash.Bytes(), sig)
+	if signerCache.Contains(cacheKey) {
+		t.Fatalf("signer cached before recovery")
+	}
+	for i := 0; i < 2; i++ {
+		signer, err := RecoverSigner(hash, sig)
+		if err != nil {
+			t.Fatalf("recovery %d failed: %v", i, err)
+		}
+		if signer != addr {
+			t.Errorf("recovery %d: signer mismatch: have %x, want %x", i, signer, addr)
+		}
+		if !signerCache.Contains(cacheKey) {
+			t.Errorf("recovery %d: signer not cached", i)
+		}
+	}
+	// A signature of another message must not hit the cache
+	if signer, err := RecoverSigner(common.Hash{1}, sig); err == nil && signer == addr {
+		t.Errorf("signature of another message recovered to the signer")
+	}
+}
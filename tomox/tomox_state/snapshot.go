@@ -0,0 +1,107 @@
+package tomox_state
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Levels of the tries making up the TomoX state.
+const (
+	exchangeLevel  = iota // Main trie, holding the exchange objects of the order books
+	orderTreeLevel        // Ask and bid trees of an order book, holding the order lists by price
+	leafLevel             // Order lists and order items, holding plain values
+)
+
+// TrieWalker walks the nodes of TomoX state tries, skipping the subtries it
+// already walked.
+type TrieWalker struct {
+	db   Database
+	seen map[common.Hash]struct{}
+}
+
+// NewTrieWalker creates a walker of the tries stored in the given database.
+func NewTrieWalker(db Database) *TrieWalker {
+	return &TrieWalker{db: db, seen: make(map[common.Hash]struct{})}
+}
+
+// Walk calls onNode with the encoding of every node of the trie with the given
+// root not walked before, and onPreimage with the preimages of the keys of its
+// leaves. With nested set, the order book tries hanging from the exchange
+// objects are walked too, as in the TomoX state trie.
+func (w *TrieWalker) Walk(root common.Hash, nested bool, onNode func(blob []byte) error, onPreimage func(key []byte) error) error {
+	level := leafLevel
+	if nested {
+		level = exchangeLevel
+	}
+	return w.walk(root, level, onNode, onPreimage)
+}
+
+func (w *TrieWalker) walk(root common.Hash, level int, onNode func(blob []byte) error, onPreimage func(key []byte) error) error {
+	if root == EmptyRoot || root == EmptyHash {
+		return nil
+	}
+	if _, ok := w.seen[root]; ok {
+		return nil
+	}
+	tr, err := w.db.OpenStorageTrie(EmptyHash, root)
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+		if hash := it.Hash(); hash != EmptyHash {
+			// Identical subtries were walked along with their leaves
+			if _, ok := w.seen[hash]; ok {
+				descend = false
+				continue
+			}
+			w.seen[hash] = struct{}{}
+			blob, err := w.db.TrieDB().Node(hash)
+			if err != nil {
+				return err
+			}
+			if err := onNode(blob); err != nil {
+				return err
+			}
+		}
+		if !it.Leaf() {
+			continue
+		}
+		if key := tr.GetKey(it.LeafKey()); key != nil {
+			if err := onPreimage(key); err != nil {
+				return err
+			}
+		}
+		for _, child := range subtries(it.LeafBlob(), level) {
+			if err := w.walk(child.root, child.level, onNode, onPreimage); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
+
+type subtrie struct {
+	root  common.Hash
+	level int
+}
+
+// subtries returns the tries hanging from a leaf of a trie at the given level.
+// Leaves which are not exchange objects or order lists, such as the price
+// histories, have none.
+func subtries(leaf []byte, level int) []subtrie {
+	switch level {
+	case exchangeLevel:
+		var exchange exchangeObject
+		if err := rlp.DecodeBytes(leaf, &exchange); err == nil {
+			return []subtrie{{exchange.AskRoot, orderTreeLevel}, {exchange.BidRoot, orderTreeLevel}, {exchange.OrderRoot, leafLevel}}
+		}
+	case orderTreeLevel:
+		var list orderList
+		if err := rlp.DecodeBytes(leaf, &list); err == nil {
+			return []subtrie{{list.Root, leafLevel}}
+		}
+	}
+	return nil
+}
@@ -17,6 +17,7 @@
 package tomox_state
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
@@ -48,6 +49,11 @@ type stateExchanges struct {
 	bidsTrie   Trie // storage trie, which becomes non-nil on first access
 	ordersTrie Trie // storage trie, which becomes non-nil on first access
 
+	// Best price caches, nil until looked up in the tries and again once
+	// the cached price level is removed from them.
+	bestAsk *common.Hash
+	bestBid *common.Hash
+
 	stateAskObjects      map[common.Hash]*stateOrderList
 	stateAskObjectsDirty map[common.Hash]struct{}
 
@@ -117,42 +123,115 @@ func (c *stateExchanges) getOrdersTrie(db Database) Trie {
 	return c.ordersTrie
 }
 
+// getBestPriceAsksTrie returns the lowest price of the asks trie, looking it up
+// in the trie only when it's not cached.
 func (c *stateExchanges) getBestPriceAsksTrie(db Database) common.Hash {
+	if c.bestAsk == nil {
+		best, ok := c.findBestPriceAsksTrie(db)
+		if !ok {
+			return EmptyHash
+		}
+		c.bestAsk = &best
+	}
+	return *c.bestAsk
+}
+
+// findBestPriceAsksTrie descends the asks trie to its lowest price, reporting
+// whether it could be read.
+func (c *stateExchanges) findBestPriceAsksTrie(db Database) (common.Hash, bool) {
 	trie := c.getAsksTrie(db)
 	encKey, encValue, err := trie.TryGetBestLeftKeyAndValue()
 	if err != nil {
 		log.Error("Failed find best price ask trie ", "orderbook", c.hash.Hex())
-		return EmptyHash
+		return EmptyHash, false
 	}
 	if len(encKey) == 0 || len(encValue) == 0 {
 		log.Debug("Not found get best ask trie", "encKey", encKey, "encValue", encValue)
-		return EmptyHash
+		return EmptyHash, true
 	}
 	var data orderList
 	if err := rlp.DecodeBytes(encValue, &data); err != nil {
 		log.Error("Failed to decode state get best ask trie", "err", err)
-		return EmptyHash
+		return EmptyHash, false
 	}
-	return common.BytesToHash(encKey)
+	return common.BytesToHash(encKey), true
 }
 
+// getBestBidsTrie returns the highest price of the bids trie, looking it up in
+// the trie only when it's not cached.
 func (c *stateExchanges) getBestBidsTrie(db Database) common.Hash {
+	if c.bestBid == nil {
+		best, ok := c.findBestBidsTrie(db)
+		if !ok {
+			return EmptyHash
+		}
+		c.bestBid = &best
+	}
+	return *c.bestBid
+}
+
+// findBestBidsTrie descends the bids trie to its highest price, reporting
+// whether it could be read.
+func (c *stateExchanges) findBestBidsTrie(db Database) (common.Hash, bool) {
 	trie := c.getBidsTrie(db)
 	encKey, encValue, err := trie.TryGetBestRightKeyAndValue()
 	if err != nil {
 		log.Error("Failed find best price bid trie ", "orderbook", c.hash.Hex())
-		return EmptyHash
+		return EmptyHash, false
 	}
 	if len(encKey) == 0 || len(encValue) == 0 {
 		log.Debug("Not found get best bid trie", "encKey", encKey, "encValue", encValue)
-		return EmptyHash
+		return EmptyHash, true
 	}
 	var data orderList
 	if err := rlp.DecodeBytes(encValue, &data); err != nil {
 		log.Error("Failed to decode state get best bid trie", "err", err)
-		return EmptyHash
+		return EmptyHash, false
+	}
+	return common.BytesToHash(encKey), true
+}
+
+// askPriceInserted updates the cached best ask after a price level was written
+// to the asks trie.
+func (c *stateExchanges) askPriceInserted(price common.Hash) {
+	if c.bestAsk == nil {
+		return
+	}
+	if common.EmptyHash(*c.bestAsk) {
+		// Can't tell an empty trie from a zero price, look the price up again
+		c.bestAsk = nil
+	} else if bytes.Compare(price[:], c.bestAsk[:]) < 0 {
+		c.bestAsk = &price
+	}
+}
+
+// bidPriceInserted updates the cached best bid after a price level was written
+// to the bids trie.
+func (c *stateExchanges) bidPriceInserted(price common.Hash) {
+	if c.bestBid == nil {
+		return
+	}
+	if common.EmptyHash(*c.bestBid) {
+		c.bestBid = nil
+	} else if bytes.Compare(price[:], c.bestBid[:]) > 0 {
+		c.bestBid = &price
+	}
+}
+
+// askPriceDeleted drops the cached best ask if it's the price level removed from
+// the asks trie.
+func (c *stateExchanges) askPriceDeleted(price common.Hash) {
+	if c.bestAsk != nil && *c.bestAsk == price {
+		c.bestAsk = nil
+	}
+}
+
+// bidPriceDeleted drops the cached best bid if it's the price level removed from
+// the bids trie.
+func (c *stateExchanges) bidPriceDeleted(price common.Hash) {
+	if c.bestBid != nil && *c.bestBid == price {
+		c.bestBid = nil
 	}
-	return common.BytesToHash(encKey)
 }
 
 // updateAskTrie writes cached storage modifications into the object's storage trie.
@@ -163,12 +242,14 @@ func (self *stateExchanges) updateAsksTrie(db Database) Trie {
 			delete(self.stateAskObjectsDirty, price)
 			if (orderList.empty()) {
 				self.setError(tr.TryDelete(price[:]))
+				self.askPriceDeleted(price)
 				continue
 			}
 			orderList.updateRoot(db)
 			// Encoding []byte cannot fail, ok to ignore the error.
 			v, _ := rlp.EncodeToBytes(orderList)
 			self.setError(tr.TryUpdate(price[:], v))
+			self.askPriceInserted(price)
 		}
 	}
 
@@ -229,12 +310,14 @@ func (self *stateExchanges) updateBidsTrie(db Database) Trie {
 			delete(self.stateBidObjectsDirty, price)
 			if (orderList.empty()) {
 				self.setError(tr.TryDelete(price[:]))
+				self.bidPriceDeleted(price)
 				continue
 			}
 			orderList.updateRoot(db)
 			// Encoding []byte cannot fail, ok to ignore the error.
 			v, _ := rlp.EncodeToBytes(orderList)
 			self.setError(tr.TryUpdate(price[:], v))
+			self.bidPriceInserted(price)
 		}
 	}
 	return tr
@@ -279,6 +362,7 @@ func (self *stateExchanges) deepCopy(db *TomoXStateDB, onDirty func(hash common.
 	if self.ordersTrie != nil {
 		stateExchanges.ordersTrie = db.db.CopyTrie(self.ordersTrie)
 	}
+	stateExchanges.bestAsk, stateExchanges.bestBid = self.bestAsk, self.bestBid
 	for price, bidObject := range self.stateBidObjects {
 		stateExchanges.stateBidObjects[price] = bidObject.deepCopy(db, self.MarkStateBidObjectDirty)
 	}
@@ -336,11 +420,13 @@ func (self *stateExchanges) Price() *big.Int {
 // updateStateExchangeObject writes the given object to the trie.
 func (self *stateExchanges) removeStateOrderListAskObject(db Database, stateOrderList *stateOrderList) {
 	self.setError(self.asksTrie.TryDelete(stateOrderList.price[:]))
+	self.askPriceDeleted(stateOrderList.price)
 }
 
 // updateStateExchangeObject writes the given object to the trie.
 func (self *stateExchanges) removeStateOrderListBidObject(db Database, stateOrderList *stateOrderList) {
 	self.setError(self.bidsTrie.TryDelete(stateOrderList.price[:]))
+	self.bidPriceDeleted(stateOrderList.price)
 }
 
 // Retrieve a state object given my the address. Returns nil if not found.
@@ -388,6 +474,7 @@ func (self *stateExchanges) createStateOrderListAskObject(db Database, price com
 		panic(fmt.Errorf("can't encode order list object at %x: %v", price[:], err))
 	}
 	self.setError(self.asksTrie.TryUpdate(price[:], data))
+	self.askPriceInserted(price)
 	if self.onDirty != nil {
 		self.onDirty(self.Hash())
 		self.onDirty = nil
@@ -440,6 +527,7 @@ func (self *stateExchanges) createStateBidOrderListObject(db Database, price com
 		panic(fmt.Errorf("can't encode order list object at %x: %v", price[:], err))
 	}
 	self.setError(self.bidsTrie.TryUpdate(price[:], data))
+	self.bidPriceInserted(price)
 	if self.onDirty != nil {
 		self.onDirty(self.Hash())
 		self.onDirty = nil
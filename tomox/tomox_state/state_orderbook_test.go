@@ -0,0 +1,103 @@
+package tomox_state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// newBestPriceState returns a state holding an order book with n ask and bid
+// price levels, of one order each.
+func newBestPriceState(n int) (*TomoXStateDB, common.Hash, []OrderItem) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := New(common.Hash{}, NewDatabase(db))
+	orderBook := common.StringToHash("BTC/TOMO")
+
+	var orders []OrderItem
+	for i := 0; i < n; i++ {
+		orders = append(orders,
+			OrderItem{OrderID: uint64(2*i + 1), Quantity: big.NewInt(1), Price: big.NewInt(int64(1000 + i)), Side: Ask, Hash: common.Hash{byte(i), 0x01}},
+			OrderItem{OrderID: uint64(2*i + 2), Quantity: big.NewInt(1), Price: big.NewInt(int64(1000 - i)), Side: Bid, Hash: common.Hash{byte(i), 0x02}},
+		)
+	}
+	for _, order := range orders {
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(order.OrderID)), order)
+	}
+	return statedb, orderBook, orders
+}
+
+func TestBestPriceCache(t *testing.T) {
+	statedb, orderBook, orders := newBestPriceState(10)
+
+	check := func(stage string) {
+		exchange := statedb.getStateExchangeObject(orderBook)
+		wantAsk, _ := exchange.findBestPriceAsksTrie(statedb.db)
+		wantBid, _ := exchange.findBestBidsTrie(statedb.db)
+		if ask, _ := statedb.GetBestAskPrice(orderBook); common.BigToHash(ask) != wantAsk {
+			t.Errorf("%s: best ask mismatch: have %v, want %v", stage, ask, wantAsk.Big())
+		}
+		if bid, _ := statedb.GetBestBidPrice(orderBook); common.BigToHash(bid) != wantBid {
+			t.Errorf("%s: best bid mismatch: have %v, want %v", stage, bid, wantBid.Big())
+		}
+	}
+	check("inserted")
+
+	// Better prices replace the cached ones, worse ones don't
+	better := []OrderItem{
+		{OrderID: 100, Quantity: big.NewInt(1), Price: big.NewInt(999), Side: Ask, Hash: common.Hash{0xa1}},
+		{OrderID: 101, Quantity: big.NewInt(1), Price: big.NewInt(1001), Side: Bid, Hash: common.Hash{0xa2}},
+		{OrderID: 102, Quantity: big.NewInt(1), Price: big.NewInt(2000), Side: Ask, Hash: common.Hash{0xa3}},
+		{OrderID: 103, Quantity: big.NewInt(1), Price: big.NewInt(1), Side: Bid, Hash: common.Hash{0xa4}},
+	}
+	snapshot := statedb.Snapshot()
+	for _, order := range better {
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(order.OrderID)), order)
+		check("better inserted")
+	}
+	statedb.RevertToSnapshot(snapshot)
+	check("reverted")
+
+	// Cancelling the best orders removes their price levels
+	for _, order := range orders[:4] {
+		order := order
+		if err := statedb.CancelOrder(orderBook, &order); err != nil {
+			t.Fatalf("failed to cancel order %d: %v", order.OrderID, err)
+		}
+		check("cancelled")
+	}
+	statedb.IntermediateRoot()
+	check("hashed")
+
+	// Copies keep the cached prices of their own tries
+	copied := statedb.Copy()
+	for _, order := range orders[4:6] {
+		order := order
+		copied.CancelOrder(orderBook, &order)
+	}
+	check("copy changed")
+	statedb = copied
+	check("copy")
+}
+
+func BenchmarkBestAskPrice(b *testing.B) {
+	statedb, orderBook, _ := newBestPriceState(1000)
+	statedb.IntermediateRoot()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		statedb.GetBestAskPrice(orderBook)
+	}
+}
+
+func BenchmarkBestAskPriceTrie(b *testing.B) {
+	statedb, orderBook, _ := newBestPriceState(1000)
+	statedb.IntermediateRoot()
+	exchange := statedb.getStateExchangeObject(orderBook)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		exchange.findBestPriceAsksTrie(statedb.db)
+	}
+}
@@ -47,6 +47,18 @@ type TomoXStateDB struct {
 	stateExhangeObjects      map[common.Hash]*stateExchanges
 	stateExhangeObjectsDirty map[common.Hash]struct{}
 
+	// Trading fees of the relayers, nil values are deleted on commit.
+	relayerFees      map[common.Hash]*big.Int
+	relayerFeesDirty map[common.Hash]struct{}
+
+	// Trades of the pairs in the last blocks, nil values are deleted on commit.
+	priceHistories      map[common.Hash]*PriceHistory
+	priceHistoriesDirty map[common.Hash]struct{}
+
+	// Orders expiring at the next blocks, nil values are deleted on commit.
+	expiryIndexes      map[common.Hash]*ExpiryIndex
+	expiryIndexesDirty map[common.Hash]struct{}
+
 	// DB error.
 	// State objects are used by the consensus core and VM which are
 	// unable to deal with database-level errors. Any error that occurs
@@ -60,6 +72,9 @@ type TomoXStateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Keys of the trie accessed, when tracked
+	accesses *stateAccesses
+
 	lock sync.Mutex
 }
 
@@ -74,6 +89,12 @@ func New(root common.Hash, db Database) (*TomoXStateDB, error) {
 		trie:                     tr,
 		stateExhangeObjects:      make(map[common.Hash]*stateExchanges),
 		stateExhangeObjectsDirty: make(map[common.Hash]struct{}),
+		relayerFees:              make(map[common.Hash]*big.Int),
+		relayerFeesDirty:         make(map[common.Hash]struct{}),
+		priceHistories:           make(map[common.Hash]*PriceHistory),
+		priceHistoriesDirty:      make(map[common.Hash]struct{}),
+		expiryIndexes:            make(map[common.Hash]*ExpiryIndex),
+		expiryIndexesDirty:       make(map[common.Hash]struct{}),
 	}, nil
 }
 
@@ -186,6 +207,41 @@ func (self *TomoXStateDB) GetOrder(orderBook common.Hash, orderId common.Hash) O
 	}
 	return stateOrderItem.data
 }
+// GetUserOrders returns the orders of a user still open in an order book,
+// sorted by their id.
+func (self *TomoXStateDB) GetUserOrders(orderBook common.Hash, user common.Address) []OrderItem {
+	stateObject := self.getStateExchangeObject(orderBook)
+	if stateObject == nil {
+		return nil
+	}
+	var orders []OrderItem
+	// Prefer 'live' orders, then the ones of the trie not loaded yet
+	for _, obj := range stateObject.stateOrderObjects {
+		if !obj.empty() && obj.data.UserAddress == user {
+			orders = append(orders, obj.data)
+		}
+	}
+	it := trie.NewIterator(stateObject.getOrdersTrie(self.db).NodeIterator(nil))
+	for it.Next() {
+		var data OrderItem
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			log.Error("Failed to decode state order object", "orderBook", orderBook, "err", err)
+			continue
+		}
+		if data.UserAddress != user {
+			continue
+		}
+		if _, ok := stateObject.stateOrderObjects[common.BigToHash(new(big.Int).SetUint64(data.OrderID))]; ok {
+			continue
+		}
+		if data.Quantity != nil && data.Quantity.Sign() > 0 {
+			orders = append(orders, data)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].OrderID < orders[j].OrderID })
+	return orders
+}
+
 func (self *TomoXStateDB) SubAmountOrderItem(orderBook common.Hash, orderId common.Hash, price *big.Int, amount *big.Int, side string) error {
 	priceHash := common.BigToHash(price)
 	stateObject := self.GetOrNewStateExchangeObject(orderBook)
@@ -378,6 +434,7 @@ func (self *TomoXStateDB) updateStateExchangeObject(stateObject *stateExchanges)
 
 // Retrieve a state object given my the address. Returns nil if not found.
 func (self *TomoXStateDB) getStateExchangeObject(addr common.Hash) (stateObject *stateExchanges) {
+	self.readKey(addr)
 	// Prefer 'live' objects.
 	if obj := self.stateExhangeObjects[addr]; obj != nil {
 		return obj
@@ -400,6 +457,7 @@ func (self *TomoXStateDB) getStateExchangeObject(addr common.Hash) (stateObject
 }
 
 func (self *TomoXStateDB) setStateExchangeObject(object *stateExchanges) {
+	self.writeKey(object.Hash())
 	self.stateExhangeObjects[object.Hash()] = object
 	self.stateExhangeObjectsDirty[object.Hash()] = struct{}{}
 }
@@ -416,6 +474,7 @@ func (self *TomoXStateDB) GetOrNewStateExchangeObject(addr common.Hash) *stateEx
 // MarkStateAskObjectDirty adds the specified object to the dirty map to avoid costly
 // state object cache iteration to find a handful of modified ones.
 func (self *TomoXStateDB) MarkStateExchangeObjectDirty(addr common.Hash) {
+	self.writeKey(addr)
 	self.stateExhangeObjectsDirty[addr] = struct{}{}
 }
 
@@ -440,6 +499,12 @@ func (self *TomoXStateDB) Copy() *TomoXStateDB {
 		trie:                     self.db.CopyTrie(self.trie),
 		stateExhangeObjects:      make(map[common.Hash]*stateExchanges, len(self.stateExhangeObjectsDirty)),
 		stateExhangeObjectsDirty: make(map[common.Hash]struct{}, len(self.stateExhangeObjectsDirty)),
+		relayerFees:              make(map[common.Hash]*big.Int, len(self.relayerFees)),
+		relayerFeesDirty:         make(map[common.Hash]struct{}, len(self.relayerFeesDirty)),
+		priceHistories:           make(map[common.Hash]*PriceHistory, len(self.priceHistories)),
+		priceHistoriesDirty:      make(map[common.Hash]struct{}, len(self.priceHistoriesDirty)),
+		expiryIndexes:            make(map[common.Hash]*ExpiryIndex, len(self.expiryIndexes)),
+		expiryIndexesDirty:       make(map[common.Hash]struct{}, len(self.expiryIndexesDirty)),
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range self.stateExhangeObjectsDirty {
@@ -448,6 +513,24 @@ func (self *TomoXStateDB) Copy() *TomoXStateDB {
 	for addr, exchangeObject := range self.stateExhangeObjects {
 		state.stateExhangeObjects[addr] = exchangeObject.deepCopy(state, state.MarkStateExchangeObjectDirty)
 	}
+	for key, fee := range self.relayerFees {
+		state.relayerFees[key] = copyFee(fee)
+	}
+	for key := range self.relayerFeesDirty {
+		state.relayerFeesDirty[key] = struct{}{}
+	}
+	for key, history := range self.priceHistories {
+		state.priceHistories[key] = history.copy()
+	}
+	for key := range self.priceHistoriesDirty {
+		state.priceHistoriesDirty[key] = struct{}{}
+	}
+	for key, index := range self.expiryIndexes {
+		state.expiryIndexes[key] = index.copy()
+	}
+	for key := range self.expiryIndexesDirty {
+		state.expiryIndexesDirty[key] = struct{}{}
+	}
 
 	return state
 }
@@ -501,6 +584,9 @@ func (s *TomoXStateDB) Finalise() {
 			//delete(s.stateExhangeObjectsDirty, addr)
 		}
 	}
+	s.updateRelayerFees()
+	s.updatePriceHistories()
+	s.updateExpiryIndexes()
 	s.clearJournalAndRefund()
 }
 
@@ -533,6 +619,9 @@ func (s *TomoXStateDB) Commit() (root common.Hash, err error) {
 			delete(s.stateExhangeObjectsDirty, addr)
 		}
 	}
+	s.updateRelayerFees()
+	s.updatePriceHistories()
+	s.updateExpiryIndexes()
 	// Write trie changes.
 	root, err = s.trie.Commit(func(leaf []byte, parent common.Hash) error {
 		var exchange exchangeObject
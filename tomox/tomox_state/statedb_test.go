@@ -20,6 +20,9 @@ import (
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"math/big"
 	"testing"
@@ -228,3 +231,241 @@ func TestRevertStates(t *testing.T) {
 	}
 	db.Close()
 }
+
+func TestRelayerFeeStates(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+
+	relayer, other := common.HexToAddress("0x1"), common.HexToAddress("0x2")
+	statedb.SetRelayerFee(relayer, big.NewInt(1))
+
+	// Cached fees are reverted along with the other changes
+	snapshot := statedb.Snapshot()
+	statedb.SetRelayerFee(other, big.NewInt(3))
+	statedb.SetRelayerFee(relayer, big.NewInt(5))
+	statedb.RevertToSnapshot(snapshot)
+	if fee := statedb.GetRelayerFee(other); fee != nil {
+		t.Fatalf("reverted relayer fee still cached: %v", fee)
+	}
+	root := statedb.IntermediateRoot()
+	if root == EmptyRoot {
+		t.Fatalf("relayer fee not written to the trie")
+	}
+	if _, err := statedb.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	// Cached fees are part of the committed state
+	statedb, err := New(root, stateCache)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	fee := statedb.GetRelayerFee(relayer)
+	if fee == nil || fee.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("relayer fee mismatch: have %v, want 1", fee)
+	}
+	if fee := statedb.GetRelayerFee(other); fee != nil {
+		t.Fatalf("unknown relayer fee cached: %v", fee)
+	}
+}
+
+func TestTradingFee(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := New(common.Hash{}, NewDatabase(db))
+	contractState, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	// Register a relayer with a trade fee in the registration contract
+	relayer := common.HexToAddress("0x1")
+	contract := common.HexToAddress(common.RelayerRegistrationSMC)
+	setFee := func(fee int64) {
+		loc := GetLocMappingAtKey(relayer.Hash(), RelayerMappingSlot["RELAYER_LIST"])
+		loc.Add(loc, RelayerStructMappingSlot["_fee"])
+		contractState.SetState(contract, common.BigToHash(loc), common.BigToHash(big.NewInt(fee)))
+	}
+	contractState.SetState(contract, common.BigToHash(new(big.Int).SetUint64(RelayerMappingSlot["RelayerCount"])), common.BigToHash(big.NewInt(1)))
+	contractState.SetState(contract, common.BigToHash(GetLocMappingAtKey(common.BigToHash(big.NewInt(0)), RelayerMappingSlot["RELAYER_COINBASES"])), relayer.Hash())
+	setFee(10)
+
+	// The rate of a relayer not cached is read without touching the state
+	if fee := GetTradingFee(relayer, statedb, contractState); fee.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("uncached fee mismatch: have %v, want 10", fee)
+	}
+	if root := statedb.IntermediateRoot(); root != EmptyRoot {
+		t.Fatalf("uncached fee written to the trie")
+	}
+	// Cached rates apply until the next refresh
+	RefreshRelayerFees(statedb, contractState)
+	setFee(20)
+	if fee := GetTradingFee(relayer, statedb, contractState); fee.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("cached fee mismatch: have %v, want 10", fee)
+	}
+	RefreshRelayerFees(statedb, contractState)
+	if fee := GetTradingFee(relayer, statedb, contractState); fee.Cmp(big.NewInt(20)) != 0 {
+		t.Fatalf("refreshed fee mismatch: have %v, want 20", fee)
+	}
+}
+
+func TestPriceOracle(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	baseToken, quoteToken := common.HexToAddress("0x1"), common.HexToAddress("0x2")
+	oracle := common.HexToAddress(common.TomoXPriceOracle)
+	priceSlot, numberSlot := vm.TomoXPriceSlots(baseToken, quoteToken)
+
+	// Nothing is recorded nor published before the price oracle fork
+	tomoxState, _ := New(common.Hash{}, stateCache)
+	tomoxState.AddTradeVolume(baseToken, quoteToken, big.NewInt(100), big.NewInt(1))
+	UpdatePriceOracle(9, tomoxState, statedb)
+	if tomoxState.IntermediateRoot() != EmptyRoot || statedb.GetNonce(oracle) != 0 {
+		t.Fatalf("price oracle updated before its fork")
+	}
+	// Trades of a block are averaged by volume and published at the end of it
+	ActivatePriceOracle(tomoxState)
+	tomoxState.AddTradeVolume(baseToken, quoteToken, big.NewInt(100), big.NewInt(1))
+	tomoxState.AddTradeVolume(baseToken, quoteToken, big.NewInt(200), big.NewInt(3))
+	snapshot := tomoxState.Snapshot()
+	tomoxState.AddTradeVolume(baseToken, quoteToken, big.NewInt(1000), big.NewInt(100))
+	tomoxState.RevertToSnapshot(snapshot)
+	UpdatePriceOracle(10, tomoxState, statedb)
+
+	if price := statedb.GetState(oracle, priceSlot).Big(); price.Cmp(big.NewInt(175)) != 0 {
+		t.Fatalf("published price mismatch: have %v, want 175", price)
+	}
+	if number := statedb.GetState(oracle, numberSlot).Big(); number.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("published block mismatch: have %v, want 10", number)
+	}
+	root, err := tomoxState.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	// Trades older than the price window are dropped
+	tomoxState, _ = New(root, stateCache)
+	tomoxState.AddTradeVolume(baseToken, quoteToken, big.NewInt(300), big.NewInt(1))
+	UpdatePriceOracle(10+common.TomoXPriceWindow-1, tomoxState, statedb)
+	if price := statedb.GetState(oracle, priceSlot).Big(); price.Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("published price mismatch: have %v, want 200", price)
+	}
+	tomoxState.AddTradeVolume(baseToken, quoteToken, big.NewInt(400), big.NewInt(1))
+	UpdatePriceOracle(10+common.TomoXPriceWindow, tomoxState, statedb)
+	if price := statedb.GetState(oracle, priceSlot).Big(); price.Cmp(big.NewInt(350)) != 0 {
+		t.Fatalf("published price mismatch: have %v, want 350", price)
+	}
+	history := tomoxState.GetPriceHistory(baseToken, quoteToken)
+	if len(history.Points) != 2 || history.Points[0].Number != 10+common.TomoXPriceWindow-1 {
+		t.Fatalf("price history mismatch: %+v", history.Points)
+	}
+	if price, volume := history.AveragePrice(10 + common.TomoXPriceWindow); price.Cmp(big.NewInt(400)) != 0 || volume.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("average price mismatch: have %v for %v, want 400 for 1", price, volume)
+	}
+}
+
+// Tests that the nodes walked from a TomoX state are enough to open it in
+// another database, and that they are walked once.
+func TestTrieWalker(t *testing.T) {
+	orderBook := common.StringToHash("BTC/TOMO")
+	db, _ := ethdb.NewMemDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+	for i := 1; i <= 10; i++ {
+		side := Ask
+		if i%2 == 0 {
+			side = Bid
+		}
+		item := OrderItem{OrderID: uint64(i), Quantity: big.NewInt(int64(i)), Price: big.NewInt(int64(100 + i%3)), Side: side, Signature: &Signature{V: 1, R: common.HexToHash("1111"), S: common.HexToHash("2222")}}
+		statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i))), item)
+	}
+	statedb.SetPrice(orderBook, big.NewInt(100))
+	statedb.AddTradeVolume(common.HexToAddress("0x1"), common.HexToAddress("0x2"), big.NewInt(100), big.NewInt(5))
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+
+	copyDb, _ := ethdb.NewMemDatabase()
+	walker := NewTrieWalker(stateCache)
+	nodes := 0
+	onNode := func(blob []byte) error {
+		nodes++
+		return copyDb.Put(crypto.Keccak256(blob), blob)
+	}
+	onPreimage := func([]byte) error { return nil }
+	if err := walker.Walk(root, true, onNode, onPreimage); err != nil {
+		t.Fatalf("failed to walk state: %v", err)
+	}
+	if nodes == 0 {
+		t.Fatalf("no node walked")
+	}
+	copied, err := New(root, NewDatabase(copyDb))
+	if err != nil {
+		t.Fatalf("failed to open copied state: %v", err)
+	}
+	if price := copied.GetPrice(orderBook); price.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("copied price mismatch: have %v, want 100", price)
+	}
+	complete := NewTrieWalker(NewDatabase(copyDb))
+	if err := complete.Walk(root, true, func([]byte) error { return nil }, onPreimage); err != nil {
+		t.Errorf("copied state incomplete: %v", err)
+	}
+	// Walking the same state again yields no node
+	if err := walker.Walk(root, true, onNode, onPreimage); err != nil {
+		t.Fatalf("failed to walk state again: %v", err)
+	}
+	if have := nodes; have != len(copyDb.Keys()) {
+		t.Errorf("nodes walked twice: have %d, want %d", have, len(copyDb.Keys()))
+	}
+}
+
+func TestMergeWrites(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	books := []common.Hash{common.StringToHash("BTC/TOMO"), common.StringToHash("ETH/TOMO")}
+	users := []common.Hash{common.StringToHash("alice"), common.StringToHash("bob")}
+	apply := func(statedb *TomoXStateDB, i int) {
+		for id := uint64(1); id <= 3; id++ {
+			order := OrderItem{OrderID: id, Quantity: big.NewInt(int64(id)), Price: big.NewInt(int64(10 * id)), Side: Ask, Signature: &Signature{V: 1}}
+			statedb.InsertOrderItem(books[i], common.BigToHash(new(big.Int).SetUint64(id)), order)
+		}
+		statedb.SetNonce(users[i], 3)
+		statedb.AddTradeVolume(common.Address{byte(i)}, common.Address{0xff}, big.NewInt(10), big.NewInt(1))
+	}
+	base, _ := New(common.Hash{}, NewDatabase(db))
+	base.SetNonce(users[0], 1)
+	base.IntermediateRoot()
+
+	serial := base.Copy()
+	apply(serial, 0)
+	apply(serial, 1)
+
+	first, second := base.Copy(), base.Copy()
+	first.TrackAccesses()
+	second.TrackAccesses()
+	apply(first, 0)
+	apply(second, 1)
+	if first.ConflictsWith(second) {
+		t.Fatalf("independent books reported as conflicting")
+	}
+	merged := base.Copy()
+	merged.MergeWrites(first)
+	merged.MergeWrites(second)
+	if have, want := merged.IntermediateRoot(), serial.IntermediateRoot(); have != want {
+		t.Fatalf("merged root mismatch: have %x, want %x", have, want)
+	}
+	// The merge is reverted along with the changes following a snapshot
+	reverted := base.Copy()
+	snap := reverted.Snapshot()
+	reverted.MergeWrites(first)
+	reverted.RevertToSnapshot(snap)
+	if have, want := reverted.IntermediateRoot(), base.IntermediateRoot(); have != want {
+		t.Errorf("reverted root mismatch: have %x, want %x", have, want)
+	}
+	// Books touched by both copies conflict
+	other := base.Copy()
+	other.TrackAccesses()
+	apply(other, 0)
+	if !first.ConflictsWith(other) {
+		t.Errorf("shared book not reported as conflicting")
+	}
+}
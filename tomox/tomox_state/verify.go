@@ -0,0 +1,192 @@
+package tomox_state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// OrderBookReport is the outcome of the integrity checks of an order book.
+type OrderBookReport struct {
+	OrderBook common.Hash `json:"orderBook"`
+	Nonce     uint64      `json:"nonce"`
+	AskLevels int         `json:"askLevels"`
+	BidLevels int         `json:"bidLevels"`
+	AskVolume *big.Int    `json:"askVolume"`
+	BidVolume *big.Int    `json:"bidVolume"`
+	BestAsk   *big.Int    `json:"bestAsk"`
+	BestBid   *big.Int    `json:"bestBid"`
+	Orders    int         `json:"orders"` // Resting orders of the order index
+	Issues    []string    `json:"issues,omitempty"`
+}
+
+// Valid reports whether the order book passed all the checks.
+func (r *OrderBookReport) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *OrderBookReport) issuef(format string, args ...interface{}) {
+	r.Issues = append(r.Issues, fmt.Sprintf(format, args...))
+}
+
+// VerifyOrderBooks checks the integrity of all the order books of the state,
+// skipping the entries without any price level nor order, like the nonces of
+// the users. The reports are sorted by order book hash.
+func (self *TomoXStateDB) VerifyOrderBooks() ([]*OrderBookReport, error) {
+	var reports []*OrderBookReport
+	it := trie.NewIterator(self.trie.NodeIterator(nil))
+	for it.Next() {
+		var data exchangeObject
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			// Relayer fees and price histories share the trie
+			continue
+		}
+		report, err := self.VerifyOrderBook(common.BytesToHash(it.Key))
+		if err != nil {
+			return reports, err
+		}
+		if report.AskLevels+report.BidLevels+report.Orders == 0 && report.Valid() {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports, it.Err
+}
+
+// VerifyOrderBook checks the integrity of an order book: the volume of each
+// price level must equal the sum of its resting orders, every resting order
+// must match its entry of the order index and the other way round, the cached
+// best prices must be the ends of the price levels and the best bid must be
+// lower than the best ask. Inconsistencies are collected in the report, the
+// error is only set when the tries can't be read.
+func (self *TomoXStateDB) VerifyOrderBook(orderBook common.Hash) (*OrderBookReport, error) {
+	exchange := self.getStateExchangeObject(orderBook)
+	if exchange == nil {
+		return nil, fmt.Errorf("order book not found: %s", orderBook.Hex())
+	}
+	report := &OrderBookReport{
+		OrderBook: orderBook,
+		Nonce:     exchange.Nonce(),
+		AskVolume: new(big.Int),
+		BidVolume: new(big.Int),
+		BestAsk:   new(big.Int),
+		BestBid:   new(big.Int),
+	}
+	// Load the order index, checked against the price levels below
+	orders := make(map[common.Hash]OrderItem)
+	it := trie.NewIterator(exchange.getOrdersTrie(self.db).NodeIterator(nil))
+	for it.Next() {
+		orderId := common.BytesToHash(it.Key)
+		var order OrderItem
+		if err := rlp.DecodeBytes(it.Value, &order); err != nil {
+			report.issuef("undecodable order %v: %v", orderId.Big(), err)
+			continue
+		}
+		if order.OrderID > report.Nonce {
+			report.issuef("order %d above the order book nonce %d", order.OrderID, report.Nonce)
+		}
+		orders[orderId] = order
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	report.Orders = len(orders)
+
+	// Walk the price levels of both sides
+	resting := make(map[common.Hash]struct{})
+	sides := []struct {
+		side   string
+		trie   Trie
+		levels *int
+		volume *big.Int
+		best   *big.Int
+	}{
+		{Ask, exchange.getAsksTrie(self.db), &report.AskLevels, report.AskVolume, report.BestAsk},
+		{Bid, exchange.getBidsTrie(self.db), &report.BidLevels, report.BidVolume, report.BestBid},
+	}
+	for _, s := range sides {
+		var lowest, highest *big.Int
+		it := trie.NewIterator(s.trie.NodeIterator(nil))
+		for it.Next() {
+			price := new(big.Int).SetBytes(it.Key)
+			var data orderList
+			if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+				return nil, fmt.Errorf("order book %s: invalid %s level %v: %v", orderBook.Hex(), s.side, price, err)
+			}
+			if lowest == nil || price.Cmp(lowest) < 0 {
+				lowest = price
+			}
+			if highest == nil || price.Cmp(highest) > 0 {
+				highest = price
+			}
+			*s.levels++
+
+			list := newStateOrderList(self, s.side, orderBook, common.BytesToHash(it.Key), data, nil)
+			sum, count := new(big.Int), 0
+			orderIt := trie.NewIterator(list.getTrie(self.db).NodeIterator(nil))
+			for orderIt.Next() {
+				orderId := common.BytesToHash(orderIt.Key)
+				amount := new(big.Int).SetBytes(orderIt.Value)
+				sum.Add(sum, amount)
+				count++
+
+				if _, ok := resting[orderId]; ok {
+					report.issuef("order %v resting at several levels", orderId.Big())
+				}
+				resting[orderId] = struct{}{}
+
+				order, ok := orders[orderId]
+				switch {
+				case !ok:
+					report.issuef("%s order %v at price %v missing from the order index", s.side, orderId.Big(), price)
+				case order.Side != s.side:
+					report.issuef("%s order %v resting on the %s side", order.Side, orderId.Big(), s.side)
+				case order.Price == nil || order.Price.Cmp(price) != 0:
+					report.issuef("%s order %v of price %v resting at price %v", s.side, orderId.Big(), order.Price, price)
+				case order.Quantity == nil || order.Quantity.Cmp(amount) != 0:
+					report.issuef("%s order %v of quantity %v resting with amount %v", s.side, orderId.Big(), order.Quantity, amount)
+				}
+			}
+			if orderIt.Err != nil {
+				return nil, orderIt.Err
+			}
+			if count == 0 {
+				report.issuef("empty %s level at price %v", s.side, price)
+			}
+			if data.Volume == nil || data.Volume.Cmp(sum) != 0 {
+				report.issuef("%s level at price %v has volume %v, resting orders sum to %v", s.side, price, data.Volume, sum)
+			}
+			s.volume.Add(s.volume, sum)
+		}
+		if it.Err != nil {
+			return nil, it.Err
+		}
+		// Check the best price served to the matching engine
+		var best *big.Int
+		if s.side == Ask {
+			best, _ = self.GetBestAskPrice(orderBook)
+			if lowest != nil && best.Cmp(lowest) != 0 {
+				report.issuef("best ask %v, lowest ask level %v", best, lowest)
+			}
+		} else {
+			best, _ = self.GetBestBidPrice(orderBook)
+			if highest != nil && best.Cmp(highest) != 0 {
+				report.issuef("best bid %v, highest bid level %v", best, highest)
+			}
+		}
+		s.best.Set(best)
+	}
+	if report.BestAsk.Sign() > 0 && report.BestBid.Sign() > 0 && report.BestBid.Cmp(report.BestAsk) >= 0 {
+		report.issuef("crossed order book: best bid %v, best ask %v", report.BestBid, report.BestAsk)
+	}
+	// Every order of the index must rest at a price level
+	for orderId, order := range orders {
+		if _, ok := resting[orderId]; !ok {
+			report.issuef("%s order %d of the order index not resting at any level", order.Side, order.OrderID)
+		}
+	}
+	return report, self.Error()
+}
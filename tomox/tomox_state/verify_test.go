@@ -0,0 +1,76 @@
+package tomox_state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// commitAndReopen commits a state and opens it again from its root.
+func commitAndReopen(t *testing.T, statedb *TomoXStateDB) *TomoXStateDB {
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	reopened, err := New(root, statedb.db)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	return reopened
+}
+
+func TestVerifyOrderBooks(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := New(common.Hash{}, NewDatabase(db))
+	orderBook := common.StringToHash("BTC/TOMO")
+
+	// Five ask levels from 1001 and five bid levels down from 1000
+	sig := &Signature{V: 27, R: common.HexToHash("0x01"), S: common.HexToHash("0x02")}
+	for i := 0; i < 5; i++ {
+		ask := OrderItem{OrderID: uint64(2*i + 1), Quantity: big.NewInt(1), Price: big.NewInt(int64(1001 + i)), Side: Ask, Signature: sig}
+		bid := OrderItem{OrderID: uint64(2*i + 2), Quantity: big.NewInt(1), Price: big.NewInt(int64(1000 - i)), Side: Bid, Signature: sig}
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(ask.OrderID)), ask)
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(bid.OrderID)), bid)
+	}
+	statedb.SetNonce(orderBook, 10)
+	statedb.SetNonce(common.HexToHash("0x01"), 3) // nonce of a user, not an order book
+	statedb = commitAndReopen(t, statedb)
+
+	reports, err := statedb.VerifyOrderBooks()
+	if err != nil {
+		t.Fatalf("failed to verify order books: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("report count mismatch: have %d, want 1", len(reports))
+	}
+	report := reports[0]
+	if !report.Valid() {
+		t.Fatalf("valid order book reported with issues: %v", report.Issues)
+	}
+	if report.OrderBook != orderBook || report.AskLevels != 5 || report.BidLevels != 5 || report.Orders != 10 {
+		t.Errorf("report mismatch: %+v", report)
+	}
+	if report.AskVolume.Int64() != 5 || report.BidVolume.Int64() != 5 {
+		t.Errorf("volume mismatch: have %v/%v, want 5/5", report.AskVolume, report.BidVolume)
+	}
+	if report.BestAsk.Int64() != 1001 || report.BestBid.Int64() != 1000 {
+		t.Errorf("best prices mismatch: have %v/%v, want 1001/1000", report.BestAsk, report.BestBid)
+	}
+	// Corrupt the volume of a level and cross the book
+	exchange := statedb.getStateExchangeObject(orderBook)
+	exchange.getStateOrderListAskObject(statedb.db, common.BigToHash(big.NewInt(1001))).AddVolume(big.NewInt(1))
+	crossing := OrderItem{OrderID: 11, Quantity: big.NewInt(1), Price: big.NewInt(1002), Side: Bid, Signature: sig}
+	statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(11)), crossing)
+	statedb = commitAndReopen(t, statedb)
+
+	report, err = statedb.VerifyOrderBook(orderBook)
+	if err != nil {
+		t.Fatalf("failed to verify order book: %v", err)
+	}
+	// Volume mismatch, order above the nonce and crossed book
+	if len(report.Issues) != 3 {
+		t.Fatalf("issue count mismatch: have %d, want 3: %v", len(report.Issues), report.Issues)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
 	"github.com/globalsign/mgo/bson"
 )
@@ -23,6 +24,8 @@ const (
 	TradeBaseToken      = "bToken"
 	TradeQuoteToken     = "qToken"
 	TradePrice          = "tradedPrice"
+	TradeTakerFee       = "takerFee"
+	TradeMakerFee       = "makerFee"
 )
 
 type Trade struct {
@@ -137,3 +140,36 @@ func (t *Trade) ComputeHash() common.Hash {
 	sha.Write(t.TakerOrderHash.Bytes())
 	return common.BytesToHash(sha.Sum(nil))
 }
+
+// NewTrades converts the trade records of matched orders into the trades of a
+// block, in the given order. The timestamps of the records, which depend on the
+// node matching the orders, are left out.
+func NewTrades(records ...[]map[string]string) types.Trades {
+	trades := types.Trades{}
+	for _, list := range records {
+		for _, record := range list {
+			trades = append(trades, &types.Trade{
+				TakerOrderHash: common.HexToHash(record[TradeTakerOrderHash]),
+				MakerOrderHash: common.HexToHash(record[TradeMakerOrderHash]),
+				Maker:          common.HexToAddress(record[TradeMaker]),
+				MakerExchange:  common.HexToAddress(record[TradeMakerExchange]),
+				BaseToken:      common.HexToAddress(record[TradeBaseToken]),
+				QuoteToken:     common.HexToAddress(record[TradeQuoteToken]),
+				Price:          ToBigInt(record[TradePrice]),
+				Quantity:       ToBigInt(record[TradeQuantity]),
+				TakerFee:       ToBigInt(record[TradeTakerFee]),
+				MakerFee:       ToBigInt(record[TradeMakerFee]),
+			})
+		}
+	}
+	return trades
+}
+
+// MatchedTrades returns the trades of the orders matched for a block.
+func MatchedTrades(txMatches []TxDataMatch) types.Trades {
+	records := make([][]map[string]string, len(txMatches))
+	for i, txMatch := range txMatches {
+		records[i] = txMatch.GetTrades()
+	}
+	return NewTrades(records...)
+}
@@ -0,0 +1,160 @@
+package tomox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// volumeIndexPrefix prefixes the keys of the volume index, which records the
+// volume traded by each address on a pair in each epoch.
+var volumeIndexPrefix = []byte("volume-index-")
+
+func volumeIndexKey(baseToken, quoteToken common.Address, epoch uint64) []byte {
+	key := append(append([]byte{}, volumeIndexPrefix...), baseToken.Bytes()...)
+	key = append(key, quoteToken.Bytes()...)
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, epoch)
+	return append(key, enc...)
+}
+
+// TraderVolume is the volume an address traded on a pair, in base token.
+type TraderVolume struct {
+	Address     common.Address `json:"address"`
+	Volume      *big.Int       `json:"volume"`      // Volume traded on both sides
+	MakerVolume *big.Int       `json:"makerVolume"` // Volume of the resting orders filled
+	TakerVolume *big.Int       `json:"takerVolume"` // Volume of the incoming orders filled
+	Trades      uint64         `json:"trades"`      // Trades of the address, counted once per side
+}
+
+// volumeIndexEntry are the volumes traded on a pair in a block.
+type volumeIndexEntry struct {
+	Number  uint64
+	Hash    common.Hash
+	Traders []*TraderVolume
+}
+
+// addTraderVolume adds the volume traded by an address to a list of volumes.
+func addTraderVolume(volumes []*TraderVolume, address common.Address, makerVolume, takerVolume *big.Int, trades uint64) []*TraderVolume {
+	for _, total := range volumes {
+		if total.Address == address {
+			total.Volume.Add(total.Volume, makerVolume).Add(total.Volume, takerVolume)
+			total.MakerVolume.Add(total.MakerVolume, makerVolume)
+			total.TakerVolume.Add(total.TakerVolume, takerVolume)
+			total.Trades += trades
+			return volumes
+		}
+	}
+	return append(volumes, &TraderVolume{
+		Address:     address,
+		Volume:      new(big.Int).Add(makerVolume, takerVolume),
+		MakerVolume: new(big.Int).Set(makerVolume),
+		TakerVolume: new(big.Int).Set(takerVolume),
+		Trades:      trades,
+	})
+}
+
+// IndexTradedVolumes adds the volumes of the trades of a block to the volume
+// index of their pairs, under the given epoch. Like the fee index, volumes are
+// recorded per block so that indexing a block again replaces them and the
+// blocks dropped by a reorg can be told apart when summing them.
+func (tomox *TomoX) IndexTradedVolumes(block *types.Block, epoch uint64) {
+	type pair struct{ base, quote common.Address }
+	entries := make(map[pair]*volumeIndexEntry)
+	for _, tx := range block.Transactions() {
+		if !tx.IsMatchingTransaction() {
+			continue
+		}
+		batch, err := DecodeTxMatchesBatch(tx.Data())
+		if err != nil {
+			continue
+		}
+		for _, txMatch := range batch.Data {
+			order, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			key := pair{order.BaseToken, order.QuoteToken}
+			for _, trade := range txMatch.GetTrades() {
+				quantity := ToBigInt(trade[TradeQuantity])
+				if quantity.Sign() <= 0 {
+					continue
+				}
+				entry := entries[key]
+				if entry == nil {
+					entry = &volumeIndexEntry{Number: block.NumberU64(), Hash: block.Hash()}
+					entries[key] = entry
+				}
+				entry.Traders = addTraderVolume(entry.Traders, order.UserAddress, Zero(), quantity, 1)
+				entry.Traders = addTraderVolume(entry.Traders, common.HexToAddress(trade[TradeMaker]), quantity, Zero(), 1)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	tomox.volumeIndexLock.Lock()
+	defer tomox.volumeIndexLock.Unlock()
+
+	batch := tomox.db.NewBatch()
+	for pair, added := range entries {
+		indexed := tomox.volumeIndexEntries(pair.base, pair.quote, epoch)
+		for i, entry := range indexed {
+			if entry.Hash == added.Hash {
+				indexed = append(indexed[:i], indexed[i+1:]...)
+				break
+			}
+		}
+		enc, _ := rlp.EncodeToBytes(append(indexed, added))
+		batch.Put(volumeIndexKey(pair.base, pair.quote, epoch), enc)
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write volume index", "block", block.Number(), "err", err)
+	}
+}
+
+// volumeIndexEntries returns the volumes traded on a pair indexed in an epoch,
+// by block.
+func (tomox *TomoX) volumeIndexEntries(baseToken, quoteToken common.Address, epoch uint64) []*volumeIndexEntry {
+	enc, err := tomox.db.Get(volumeIndexKey(baseToken, quoteToken, epoch))
+	if err != nil || len(enc) == 0 {
+		return nil
+	}
+	var entries []*volumeIndexEntry
+	if err := rlp.DecodeBytes(enc, &entries); err != nil {
+		log.Error("Failed to decode volume index", "baseToken", baseToken, "quoteToken", quoteToken, "epoch", epoch, "err", err)
+		return nil
+	}
+	return entries
+}
+
+// TopTraders sums the volumes traded on a pair indexed in an epoch by
+// IndexTradedVolumes, over the blocks for which canonical returns true, and
+// returns the addresses with the largest volumes first, at most limit of them.
+func (tomox *TomoX) TopTraders(baseToken, quoteToken common.Address, epoch uint64, limit int, canonical func(number uint64, hash common.Hash) bool) []*TraderVolume {
+	traders := []*TraderVolume{}
+	for _, entry := range tomox.volumeIndexEntries(baseToken, quoteToken, epoch) {
+		if !canonical(entry.Number, entry.Hash) {
+			continue
+		}
+		for _, volume := range entry.Traders {
+			traders = addTraderVolume(traders, volume.Address, volume.MakerVolume, volume.TakerVolume, volume.Trades)
+		}
+	}
+	sort.Slice(traders, func(i, j int) bool {
+		if cmp := traders[i].Volume.Cmp(traders[j].Volume); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(traders[i].Address[:], traders[j].Address[:]) < 0
+	})
+	if limit >= 0 && len(traders) > limit {
+		traders = traders[:limit]
+	}
+	return traders
+}
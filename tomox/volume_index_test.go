@@ -0,0 +1,77 @@
+package tomox
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+func TestTopTraders(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "tomox-volumes-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+	tomox := New(&Config{DataDir: datadir})
+	defer tomox.db.Close()
+
+	var (
+		taker, alice, bob = common.Address{0x01}, common.Address{0x02}, common.Address{0x03}
+		tomo, btc, eth    = common.HexToAddress(common.TomoNativeAddress), common.Address{0xb7}, common.Address{0xe7}
+	)
+	sig := &tomox_state.Signature{V: 27, R: common.Hash{0x01}, S: common.Hash{0x02}}
+	encode := func(base common.Address) []byte {
+		order := &tomox_state.OrderItem{UserAddress: taker, BaseToken: base, QuoteToken: tomo, Quantity: big.NewInt(10), Price: big.NewInt(10), Side: tomox_state.Bid, Signature: sig}
+		enc, _ := EncodeBytesItem(order)
+		return enc
+	}
+	trade := func(maker common.Address, quantity string) map[string]string {
+		return map[string]string{TradeMaker: maker.Hex(), TradeQuantity: quantity, TradePrice: "10"}
+	}
+	newBlock := func(number int64, extra byte, base common.Address, trades ...map[string]string) *types.Block {
+		data, _ := EncodeTxMatchesBatch(TxMatchBatch{Data: []TxDataMatch{{Order: encode(base), Trades: trades}}})
+		tx := types.NewTransaction(0, common.HexToAddress(common.TomoXAddr), common.Big0, 0, common.Big0, data)
+		return types.NewBlock(&types.Header{Number: big.NewInt(number), Extra: []byte{extra}}, []*types.Transaction{tx}, nil, nil)
+	}
+	first := newBlock(1, 0, btc, trade(alice, "3"), trade(bob, "2"))
+	second := newBlock(2, 0, btc, trade(bob, "4"))
+	side := newBlock(2, 1, btc, trade(alice, "100"))
+	other := newBlock(3, 0, eth, trade(alice, "50"))
+	for _, block := range []*types.Block{first, first, second, side, other} {
+		tomox.IndexTradedVolumes(block, 0)
+	}
+	canonical := func(number uint64, hash common.Hash) bool {
+		return hash == first.Hash() || hash == second.Hash() || hash == other.Hash()
+	}
+	// Indexing a block again doesn't count it twice, side blocks and other pairs are skipped
+	traders := tomox.TopTraders(btc, tomo, 0, 10, canonical)
+	if len(traders) != 3 {
+		t.Fatalf("traders mismatch: have %d, want 3", len(traders))
+	}
+	want := []struct {
+		address             common.Address
+		maker, taker, total int64
+		trades              uint64
+	}{
+		{taker, 0, 9, 9, 3},
+		{bob, 6, 0, 6, 2},
+		{alice, 3, 0, 3, 1},
+	}
+	for i, w := range want {
+		have := traders[i]
+		if have.Address != w.address || have.MakerVolume.Int64() != w.maker || have.TakerVolume.Int64() != w.taker || have.Volume.Int64() != w.total || have.Trades != w.trades {
+			t.Errorf("trader %d mismatch: have %x %v/%v/%v in %d trades, want %x %d/%d/%d in %d", i, have.Address, have.MakerVolume, have.TakerVolume, have.Volume, have.Trades, w.address, w.maker, w.taker, w.total, w.trades)
+		}
+	}
+	if traders := tomox.TopTraders(btc, tomo, 0, 1, canonical); len(traders) != 1 || traders[0].Address != taker {
+		t.Errorf("limited traders mismatch: %+v", traders)
+	}
+	if traders := tomox.TopTraders(btc, tomo, 1, 10, canonical); len(traders) != 0 {
+		t.Errorf("volumes indexed in another epoch: %+v", traders)
+	}
+}
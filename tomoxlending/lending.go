@@ -0,0 +1,346 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tomoxlending implements the TomoX lending protocol: users invest or
+// borrow tokens for a term at an interest rate, through interest rate order
+// books matched by the masternodes next to the TomoX spot order books.
+//
+// Lending orders are signed by the users and submitted to the masternodes
+// through the RPC API. The orders processed in a block are carried, along
+// with the resulting lending state root, by a lending transaction sent to
+// common.TomoXLendingAddr, which also holds the funds of the resting orders
+// and the collateral of the open loans in escrow.
+package tomoxlending
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+var (
+	ErrInvalidRelayer    = errors.New("verify lending order: invalid relayer")
+	ErrUnknownOrder      = errors.New("lending order not found")
+	ErrUnknownTrade      = errors.New("lending trade not found")
+	ErrLendingRootFailed = errors.New("invalid lending state root")
+)
+
+// escrow holds the funds of the resting orders and the collateral of the
+// open loans.
+var escrow = common.HexToAddress(common.TomoXLendingAddr)
+
+// Lending holds the lending state database and the pending lending orders.
+type Lending struct {
+	StateCache tomox_state.Database // Lending state database to reuse between imports
+	pool       *LendingPool
+}
+
+// New creates the lending service, storing its state in the given database.
+func New(db ethdb.Database) *Lending {
+	return &Lending{
+		StateCache: tomox_state.NewDatabase(db),
+		pool:       NewLendingPool(),
+	}
+}
+
+// Pool returns the pool of the pending lending orders.
+func (l *Lending) Pool() *LendingPool {
+	return l.pool
+}
+
+// GetLendingState returns the lending state after the given block.
+func (l *Lending) GetLendingState(block *types.Block) (*lendingstate.LendingStateDB, error) {
+	return lendingstate.New(GetLendingStateRoot(block), l.StateCache)
+}
+
+// CommitState writes the lending state of a block to the database.
+func (l *Lending) CommitState(lendingState *lendingstate.LendingStateDB) error {
+	root, err := lendingState.Commit()
+	if err != nil {
+		return err
+	}
+	return l.StateCache.TrieDB().Commit(root, false)
+}
+
+// GetLendingTransaction returns the lending transaction of a block, nil if the
+// block has none.
+func GetLendingTransaction(block *types.Block) *types.Transaction {
+	for _, tx := range block.Transactions() {
+		if tx.IsLendingTransaction() {
+			return tx
+		}
+	}
+	return nil
+}
+
+// GetLendingStateRoot returns the lending state root after the given block.
+func GetLendingStateRoot(block *types.Block) common.Hash {
+	if tx := GetLendingTransaction(block); tx != nil {
+		if batch, err := DecodeLendingBatch(tx.Data()); err == nil {
+			return batch.Root
+		}
+	}
+	return lendingstate.EmptyRoot
+}
+
+// ProcessLendingPending processes the pending lending orders on top of the
+// given states for a block mined at the given time, dropping the orders which
+// fail. It returns the batch to carry in the lending transaction of the block.
+func (l *Lending) ProcessLendingPending(time uint64, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) *LendingBatch {
+	batch := &LendingBatch{}
+	for _, order := range l.pool.Pending(lendingState) {
+		switch err := ApplyLendingOrder(time, order, statedb, lendingState); err {
+		case nil:
+			batch.Orders = append(batch.Orders, order)
+		case ErrNonceTooHigh:
+			// A previous order of the user failed, wait for it to be replaced
+			log.Debug("Skipping lending order with high nonce", "user", order.UserAddress, "nonce", order.Nonce)
+		default:
+			log.Debug("Lending order failed", "hash", order.Hash, "user", order.UserAddress, "nonce", order.Nonce, "err", err)
+			l.pool.Remove(order)
+		}
+	}
+	batch.Root = lendingState.IntermediateRoot()
+	return batch
+}
+
+// ApplyLendingBatch verifies the lending transaction of a block, applying its
+// orders on top of the given states.
+func ApplyLendingBatch(time uint64, batch *LendingBatch, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) error {
+	for _, order := range batch.Orders {
+		if err := ApplyLendingOrder(time, order, statedb, lendingState); err != nil {
+			return fmt.Errorf("lending order %x: %v", order.Hash, err)
+		}
+	}
+	if root := lendingState.IntermediateRoot(); root != batch.Root {
+		return fmt.Errorf("%v: have %x, want %x", ErrLendingRootFailed, root, batch.Root)
+	}
+	return nil
+}
+
+// ApplyLendingOrder processes a lending order at the given time. The states
+// are left unchanged if the order fails.
+func ApplyLendingOrder(time uint64, order *LendingOrder, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) error {
+	user, err := order.Sender()
+	if err != nil {
+		return err
+	}
+	if user != order.UserAddress {
+		return ErrInvalidSignature
+	}
+	nonce := lendingState.GetNonce(user)
+	if order.Nonce < nonce {
+		return ErrNonceTooLow
+	}
+	if order.Nonce > nonce {
+		return ErrNonceTooHigh
+	}
+	snap, lendingSnap := statedb.Snapshot(), lendingState.Snapshot()
+	switch order.Type {
+	case Limit:
+		err = processLimitOrder(time, order, statedb, lendingState)
+	case Cancel:
+		err = processCancelOrder(order, statedb, lendingState)
+	case Repay:
+		err = processRepayOrder(time, order, statedb, lendingState)
+	default:
+		err = ErrInvalidType
+	}
+	if err != nil {
+		statedb.RevertToSnapshot(snap)
+		lendingState.RevertToSnapshot(lendingSnap)
+		return err
+	}
+	lendingState.SetNonce(user, nonce+1)
+	return nil
+}
+
+// processLimitOrder locks the funds of a new order and matches it against the
+// opposite side of its lending book, resting the remaining quantity.
+func processLimitOrder(time uint64, order *LendingOrder, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) error {
+	if order.Term == 0 {
+		return lendingstate.ErrInvalidTerm
+	}
+	if order.Interest == nil || order.Interest.Sign() <= 0 {
+		return lendingstate.ErrInvalidInterest
+	}
+	if order.Quantity == nil || order.Quantity.Sign() <= 0 {
+		return lendingstate.ErrInvalidQuantity
+	}
+	if !tomox_state.IsValidRelayer(statedb, order.Relayer) {
+		return ErrInvalidRelayer
+	}
+	item := &lendingstate.LendingItem{
+		Hash:        order.Hash,
+		Relayer:     order.Relayer,
+		UserAddress: order.UserAddress,
+		Side:        order.Side,
+		Interest:    new(big.Int).Set(order.Interest),
+		Quantity:    new(big.Int).Set(order.Quantity),
+	}
+	switch order.Side {
+	case lendingstate.Investing:
+		if err := transfer(order.UserAddress, escrow, order.Quantity, order.LendingToken, statedb); err != nil {
+			return err
+		}
+	case lendingstate.Borrowing:
+		if order.Collateral == nil || order.Collateral.Sign() <= 0 || order.CollateralToken == order.LendingToken {
+			return lendingstate.ErrInvalidCollateral
+		}
+		if err := transfer(order.UserAddress, escrow, order.Collateral, order.CollateralToken, statedb); err != nil {
+			return err
+		}
+		item.CollateralToken = order.CollateralToken
+		item.Collateral = new(big.Int).Set(order.Collateral)
+	default:
+		return lendingstate.ErrInvalidSide
+	}
+	book := lendingState.GetOrNewLendingBook(order.LendingToken, order.Term)
+	bookHash := lendingstate.GetLendingBookHash(order.LendingToken, order.Term)
+
+	opposite := lendingstate.Borrowing
+	if item.Side == lendingstate.Borrowing {
+		opposite = lendingstate.Investing
+	}
+	for _, resting := range lendingState.GetLendingItems(bookHash, opposite) {
+		if item.Quantity.Sign() == 0 {
+			break
+		}
+		// Investors lend at their rate or above, borrowers borrow at their rate or below
+		if cmp := resting.Interest.Cmp(item.Interest); (item.Side == lendingstate.Investing && cmp < 0) || (item.Side == lendingstate.Borrowing && cmp > 0) {
+			break
+		}
+		investing, borrowing := item, resting
+		if item.Side == lendingstate.Borrowing {
+			investing, borrowing = resting, item
+		}
+		amount := item.Quantity
+		if resting.Quantity.Cmp(amount) < 0 {
+			amount = resting.Quantity
+		}
+		amount = new(big.Int).Set(amount)
+
+		// The collateral of the borrower is pledged pro rata of the borrowed amount
+		collateral := new(big.Int).Set(borrowing.Collateral)
+		if amount.Cmp(borrowing.Quantity) < 0 {
+			collateral.Mul(collateral, amount).Div(collateral, borrowing.Quantity)
+		}
+		if err := transfer(escrow, borrowing.UserAddress, amount, book.LendingToken, statedb); err != nil {
+			return err
+		}
+		lendingState.InsertLendingTrade(book, &lendingstate.LendingTrade{
+			Investor:         investing.UserAddress,
+			Borrower:         borrowing.UserAddress,
+			LendingToken:     book.LendingToken,
+			CollateralToken:  borrowing.CollateralToken,
+			Term:             book.Term,
+			Interest:         new(big.Int).Set(resting.Interest),
+			Amount:           amount,
+			CollateralAmount: collateral,
+			CreatedAt:        time,
+			ExpiresAt:        time + book.Term,
+		})
+		log.Debug("Lending trade", "lendingToken", book.LendingToken, "term", book.Term, "investor", investing.UserAddress, "borrower", borrowing.UserAddress, "amount", amount, "interest", resting.Interest)
+
+		borrowing.Collateral.Sub(borrowing.Collateral, collateral)
+		item.Quantity.Sub(item.Quantity, amount)
+		resting.Quantity.Sub(resting.Quantity, amount)
+		if resting.Quantity.Sign() == 0 {
+			lendingState.RemoveLendingItem(book, resting)
+		} else {
+			lendingState.UpdateLendingItem(book, resting)
+		}
+	}
+	if item.Quantity.Sign() > 0 {
+		lendingState.InsertLendingItem(book, item)
+	}
+	return nil
+}
+
+// processCancelOrder removes a resting item of the user from its lending book,
+// refunding its remaining funds.
+func processCancelOrder(order *LendingOrder, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) error {
+	book := lendingState.GetLendingBook(lendingstate.GetLendingBookHash(order.LendingToken, order.Term))
+	if book == nil {
+		return ErrUnknownOrder
+	}
+	item := lendingState.GetLendingItem(lendingstate.GetLendingBookHash(order.LendingToken, order.Term), order.LendingID)
+	if item == nil || item.UserAddress != order.UserAddress {
+		return ErrUnknownOrder
+	}
+	var err error
+	if item.Side == lendingstate.Investing {
+		err = transfer(escrow, item.UserAddress, item.Quantity, book.LendingToken, statedb)
+	} else {
+		err = transfer(escrow, item.UserAddress, item.Collateral, item.CollateralToken, statedb)
+	}
+	if err != nil {
+		return err
+	}
+	lendingState.RemoveLendingItem(book, item)
+	return nil
+}
+
+// processRepayOrder closes a loan of the user: the borrower pays back the
+// amount and the interest due to the investor and gets back the collateral.
+func processRepayOrder(time uint64, order *LendingOrder, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) error {
+	bookHash := lendingstate.GetLendingBookHash(order.LendingToken, order.Term)
+	book := lendingState.GetLendingBook(bookHash)
+	if book == nil {
+		return ErrUnknownTrade
+	}
+	trade := lendingState.GetLendingTrade(bookHash, order.LendingID)
+	if trade == nil || trade.Status != lendingstate.TradeStatusOpen || trade.Borrower != order.UserAddress {
+		return ErrUnknownTrade
+	}
+	due := new(big.Int).Add(trade.Amount, trade.InterestDue())
+	if err := transfer(trade.Borrower, trade.Investor, due, trade.LendingToken, statedb); err != nil {
+		return err
+	}
+	if err := transfer(escrow, trade.Borrower, trade.CollateralAmount, trade.CollateralToken, statedb); err != nil {
+		return err
+	}
+	trade.Status, trade.ClosedAt = lendingstate.TradeStatusRepaid, time
+	lendingState.CloseLendingTrade(book, trade)
+	return nil
+}
+
+// transfer moves an amount of token between two accounts.
+func transfer(from, to common.Address, amount *big.Int, token common.Address, statedb *state.StateDB) error {
+	if amount.Sign() == 0 {
+		return nil
+	}
+	balance := tomox_state.GetTokenBalance(from, token, statedb)
+	if balance.Cmp(amount) < 0 {
+		return fmt.Errorf("insufficient balance of token %s for %s: have %v, want %v", token.Hex(), from.Hex(), balance, amount)
+	}
+	if token.String() == common.TomoNativeAddress {
+		statedb.SubBalance(from, amount)
+		statedb.AddBalance(to, amount)
+		return nil
+	}
+	if err := tomox_state.SetTokenBalance(from, new(big.Int).Sub(balance, amount), token, statedb); err != nil {
+		return err
+	}
+	return tomox_state.SetTokenBalance(to, new(big.Int).Add(tomox_state.GetTokenBalance(to, token, statedb), amount), token, statedb)
+}
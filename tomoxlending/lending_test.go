@@ -0,0 +1,152 @@
+package tomoxlending
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+var (
+	testRelayer = common.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e")
+	testToken   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testNative  = common.HexToAddress(common.TomoNativeAddress)
+	testTerm    = uint64(30 * 24 * 60 * 60)
+)
+
+func newTestStates(t *testing.T) (*state.StateDB, *lendingstate.LendingStateDB) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lendingState, err := lendingstate.New(lendingstate.EmptyRoot, tomox_state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Register the relayer and deploy the lending token
+	loc := tomox_state.GetLocMappingAtKey(testRelayer.Hash(), tomox_state.RelayerMappingSlot["RELAYER_LIST"])
+	statedb.SetState(common.HexToAddress(common.RelayerRegistrationSMC), common.BigToHash(loc), common.BigToHash(big.NewInt(1)))
+	statedb.SetNonce(testToken, 1)
+	return statedb, lendingState
+}
+
+func signTestOrder(t *testing.T, order *LendingOrder, key *ecdsa.PrivateKey) *LendingOrder {
+	order.Relayer = testRelayer
+	order.UserAddress = crypto.PubkeyToAddress(key.PublicKey)
+	order.LendingToken = testToken
+	order.Term = testTerm
+	if err := SignLendingOrder(order, key); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestLendBorrowRepay(t *testing.T) {
+	statedb, lendingState := newTestStates(t)
+	investorKey, _ := crypto.GenerateKey()
+	borrowerKey, _ := crypto.GenerateKey()
+	investor, borrower := crypto.PubkeyToAddress(investorKey.PublicKey), crypto.PubkeyToAddress(borrowerKey.PublicKey)
+
+	tomox_state.SetTokenBalance(investor, big.NewInt(1000), testToken, statedb)
+	tomox_state.SetTokenBalance(borrower, big.NewInt(10), testToken, statedb)
+	statedb.SetBalance(borrower, big.NewInt(500))
+
+	balance := func(addr, token common.Address) int64 {
+		return tomox_state.GetTokenBalance(addr, token, statedb).Int64()
+	}
+	apply := func(order *LendingOrder) {
+		if err := ApplyLendingOrder(1000, order, statedb, lendingState); err != nil {
+			t.Fatalf("failed to apply %s %s order: %v", order.Type, order.Side, err)
+		}
+	}
+	// An investing order rests in the book, its funds in escrow
+	apply(signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Investing, Interest: big.NewInt(10000000), Quantity: big.NewInt(1000)}, investorKey))
+	if have := balance(escrow, testToken); have != 1000 {
+		t.Fatalf("escrow balance mismatch: have %d, want 1000", have)
+	}
+	// A borrowing order at a higher rate matches it at the investor's rate
+	apply(signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Borrowing, Interest: big.NewInt(12000000), Quantity: big.NewInt(400), CollateralToken: testNative, Collateral: big.NewInt(200)}, borrowerKey))
+
+	bookHash := lendingstate.GetLendingBookHash(testToken, testTerm)
+	trade := lendingState.GetLendingTrade(bookHash, 1)
+	if trade == nil {
+		t.Fatal("lending trade not found")
+	}
+	if trade.Amount.Int64() != 400 || trade.CollateralAmount.Int64() != 200 || trade.Interest.Int64() != 10000000 || trade.ExpiresAt != 1000+testTerm {
+		t.Errorf("lending trade mismatch: %+v", trade)
+	}
+	if have := balance(borrower, testToken); have != 410 {
+		t.Errorf("borrower balance mismatch: have %d, want 410", have)
+	}
+	if items := lendingState.GetLendingItems(bookHash, lendingstate.Investing); len(items) != 1 || items[0].Quantity.Int64() != 600 {
+		t.Errorf("remaining investing items mismatch: %v", items)
+	}
+	if items := lendingState.GetLendingItems(bookHash, lendingstate.Borrowing); len(items) != 0 {
+		t.Errorf("borrowing items left: %v", items)
+	}
+	// Orders must follow the lending nonce of the user
+	if err := ApplyLendingOrder(1000, signTestOrder(t, &LendingOrder{Type: Repay, LendingID: 1, Nonce: 2}, borrowerKey), statedb, lendingState); err != ErrNonceTooHigh {
+		t.Errorf("nonce error mismatch: have %v, want %v", err, ErrNonceTooHigh)
+	}
+	// Repaying pays the interest to the investor and releases the collateral
+	apply(signTestOrder(t, &LendingOrder{Type: Repay, LendingID: 1, Nonce: 1}, borrowerKey))
+	interest := trade.InterestDue().Int64()
+	if have, want := balance(investor, testToken), 400+interest; have != want {
+		t.Errorf("investor balance mismatch: have %d, want %d", have, want)
+	}
+	if have := statedb.GetBalance(borrower).Int64(); have != 500 {
+		t.Errorf("borrower collateral not released: have %d, want 500", have)
+	}
+	if trade := lendingState.GetLendingTrade(bookHash, 1); trade.Status != lendingstate.TradeStatusRepaid {
+		t.Errorf("lending trade status mismatch: have %s, want %s", trade.Status, lendingstate.TradeStatusRepaid)
+	}
+	if book := lendingState.GetLendingBook(bookHash); len(book.Trades) != 0 {
+		t.Errorf("repaid lending trade left open")
+	}
+	// Cancelling refunds the remaining quantity
+	apply(signTestOrder(t, &LendingOrder{Type: Cancel, LendingID: 1, Nonce: 1}, investorKey))
+	if have, want := balance(investor, testToken), 1000+interest; have != want {
+		t.Errorf("investor balance mismatch: have %d, want %d", have, want)
+	}
+	if have := balance(escrow, testToken); have != 0 {
+		t.Errorf("escrow balance mismatch: have %d, want 0", have)
+	}
+}
+
+// Tests that the lending batch of a mined block is verified on top of the
+// same states.
+func TestApplyLendingBatch(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	user := crypto.PubkeyToAddress(key.PublicKey)
+
+	db, _ := ethdb.NewMemDatabase()
+	lending := New(db)
+	statedb, lendingState := newTestStates(t)
+	tomox_state.SetTokenBalance(user, big.NewInt(100), testToken, statedb)
+	verifyStatedb, verifyLendingState := statedb.Copy(), lendingState.Copy()
+
+	order := signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Investing, Interest: big.NewInt(5000000), Quantity: big.NewInt(100)}, key)
+	if err := lending.Pool().Add(order, lendingState); err != nil {
+		t.Fatal(err)
+	}
+	batch := lending.ProcessLendingPending(1000, statedb, lendingState)
+	if len(batch.Orders) != 1 {
+		t.Fatalf("processed orders mismatch: have %d, want 1", len(batch.Orders))
+	}
+	if err := ApplyLendingBatch(1000, batch, verifyStatedb, verifyLendingState); err != nil {
+		t.Fatalf("failed to verify lending batch: %v", err)
+	}
+	if pending := lending.Pool().Pending(lendingState); len(pending) != 0 {
+		t.Errorf("processed orders left in the pool: %d", len(pending))
+	}
+	if err := ApplyLendingBatch(1000, &LendingBatch{Root: common.Hash{1}}, verifyStatedb, verifyLendingState); err == nil {
+		t.Errorf("invalid lending root accepted")
+	}
+}
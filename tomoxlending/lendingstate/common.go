@@ -0,0 +1,117 @@
+package lendingstate
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+var (
+	EmptyRoot = tomox_state.EmptyRoot
+
+	Investing = "INVEST"
+	Borrowing = "BORROW"
+
+	TradeStatusOpen       = "OPEN"
+	TradeStatusRepaid     = "REPAID"
+	TradeStatusLiquidated = "LIQUIDATED"
+
+	// BaseInterest is the yearly interest rate of 100%: interest rates are
+	// expressed in 1/BaseInterest per year, e.g. 10% is 10^7.
+	BaseInterest = big.NewInt(100000000)
+
+	// YearSeconds is the duration of a year used to pro-rate the interests.
+	YearSeconds = uint64(365 * 24 * 60 * 60)
+)
+
+var (
+	ErrInvalidInterest   = errors.New("verify lending order: invalid interest")
+	ErrInvalidQuantity   = errors.New("verify lending order: invalid quantity")
+	ErrInvalidCollateral = errors.New("verify lending order: invalid collateral")
+	ErrInvalidTerm       = errors.New("verify lending order: invalid term")
+	ErrInvalidSide       = errors.New("verify lending order: invalid side")
+)
+
+// LendingItem is an order resting in a lending book. Investing items offer
+// Quantity of the lending token at the given interest rate, borrowing items
+// ask for it and pledge Collateral of the collateral token for the whole
+// remaining quantity.
+type LendingItem struct {
+	ID              uint64         `json:"id"`
+	Hash            common.Hash    `json:"hash"`
+	Relayer         common.Address `json:"relayer"`
+	UserAddress     common.Address `json:"userAddress"`
+	Side            string         `json:"side"`
+	Interest        *big.Int       `json:"interest"`
+	Quantity        *big.Int       `json:"quantity"`
+	CollateralToken common.Address `json:"collateralToken"`
+	Collateral      *big.Int       `json:"collateral"`
+}
+
+// LendingTrade is a loan, created when a borrowing item matches an investing
+// item. The collateral is held in escrow until the loan is repaid, or seized
+// by the investor when the loan is liquidated.
+type LendingTrade struct {
+	ID               uint64         `json:"id"`
+	Investor         common.Address `json:"investor"`
+	Borrower         common.Address `json:"borrower"`
+	LendingToken     common.Address `json:"lendingToken"`
+	CollateralToken  common.Address `json:"collateralToken"`
+	Term             uint64         `json:"term"`
+	Interest         *big.Int       `json:"interest"`
+	Amount           *big.Int       `json:"amount"`
+	CollateralAmount *big.Int       `json:"collateralAmount"`
+	CreatedAt        uint64         `json:"createdAt"`
+	ExpiresAt        uint64         `json:"expiresAt"`
+	Status           string         `json:"status"`
+	ClosedAt         uint64         `json:"closedAt,omitempty"`
+	SeizedCollateral *big.Int       `json:"seizedCollateral,omitempty"` // Collateral seized by the investor on liquidation
+}
+
+// LendingBook is the interest rate order book of a lending token for a term.
+// Investing items are sorted by ascending interest, borrowing items by
+// descending interest, both in time priority for a same interest.
+type LendingBook struct {
+	LendingToken common.Address
+	Term         uint64
+	NextItemID   uint64
+	NextTradeID  uint64
+	Investing    []uint64
+	Borrowing    []uint64
+	Trades       []uint64
+}
+
+// GetLendingBookHash returns the hash identifying the lending book of a
+// lending token for a term.
+func GetLendingBookHash(lendingToken common.Address, term uint64) common.Hash {
+	return crypto.Keccak256Hash(lendingToken.Bytes(), encodeUint64(term))
+}
+
+// InterestDue returns the interest due at the end of the term of the loan.
+func (trade *LendingTrade) InterestDue() *big.Int {
+	interest := new(big.Int).Mul(trade.Amount, trade.Interest)
+	interest.Mul(interest, new(big.Int).SetUint64(trade.Term))
+	return interest.Div(interest, new(big.Int).Mul(BaseInterest, new(big.Int).SetUint64(YearSeconds)))
+}
+
+// better reports whether item a has the priority over item b in their side
+// of a lending book.
+func better(a, b *LendingItem) bool {
+	if cmp := a.Interest.Cmp(b.Interest); cmp != 0 {
+		if a.Side == Investing {
+			return cmp < 0
+		}
+		return cmp > 0
+	}
+	return a.ID < b.ID
+}
+
+func encodeUint64(n uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, n)
+	return enc
+}
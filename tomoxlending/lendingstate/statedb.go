@@ -0,0 +1,381 @@
+// Copyright (c) 2018 Tomochain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package lendingstate provides the state of the TomoX lending books, stored
+// in its own trie next to the TomoX trading state.
+package lendingstate
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// Prefixes of the hashed keys of the objects in the lending trie.
+var (
+	bookPrefix  = []byte("lending-book")
+	itemPrefix  = []byte("lending-item")
+	tradePrefix = []byte("lending-trade")
+	noncePrefix = []byte("lending-nonce")
+	booksKey    = crypto.Keccak256Hash([]byte("lending-books"))
+)
+
+type revision struct {
+	id           int
+	journalIndex int
+}
+
+// objectChange is a journal entry restoring the previous encoding of an
+// object, nil if it did not exist.
+type objectChange struct {
+	key  common.Hash
+	prev []byte
+}
+
+// LendingStateDB holds the lending books, their resting items and the open
+// loans. Objects are stored RLP encoded under hashed keys in a single trie,
+// and cached in memory until the state is finalised.
+type LendingStateDB struct {
+	db   tomox_state.Database
+	trie tomox_state.Trie
+
+	// Encoded objects modified or read since the last commit, nil values are
+	// deleted from the trie.
+	objects      map[common.Hash][]byte
+	objectsDirty map[common.Hash]struct{}
+
+	// DB error, returned by Commit.
+	dbErr error
+
+	// Journal of state modifications, the backbone of Snapshot and
+	// RevertToSnapshot.
+	journal        []objectChange
+	validRevisions []revision
+	nextRevisionId int
+
+	lock sync.Mutex
+}
+
+// New creates a new lending state from a given trie.
+func New(root common.Hash, db tomox_state.Database) (*LendingStateDB, error) {
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return &LendingStateDB{
+		db:           db,
+		trie:         tr,
+		objects:      make(map[common.Hash][]byte),
+		objectsDirty: make(map[common.Hash]struct{}),
+	}, nil
+}
+
+// setError remembers the first non-nil error it is called with.
+func (self *LendingStateDB) setError(err error) {
+	if self.dbErr == nil {
+		self.dbErr = err
+	}
+}
+
+func (self *LendingStateDB) Error() error {
+	return self.dbErr
+}
+
+// Database retrieves the low level database supporting the lending state.
+func (self *LendingStateDB) Database() tomox_state.Database {
+	return self.db
+}
+
+func (self *LendingStateDB) getObject(key common.Hash) []byte {
+	if enc, ok := self.objects[key]; ok {
+		return enc
+	}
+	enc, err := self.trie.TryGet(key[:])
+	if err != nil {
+		self.setError(err)
+		return nil
+	}
+	if len(enc) == 0 {
+		enc = nil
+	}
+	self.objects[key] = enc
+	return enc
+}
+
+func (self *LendingStateDB) setObject(key common.Hash, enc []byte) {
+	self.journal = append(self.journal, objectChange{key: key, prev: self.getObject(key)})
+	self.objects[key] = enc
+	self.objectsDirty[key] = struct{}{}
+}
+
+func (self *LendingStateDB) decodeObject(key common.Hash, obj interface{}) bool {
+	enc := self.getObject(key)
+	if enc == nil {
+		return false
+	}
+	if err := rlp.DecodeBytes(enc, obj); err != nil {
+		log.Error("Failed to decode lending object", "key", key.Hex(), "err", err)
+		return false
+	}
+	return true
+}
+
+func (self *LendingStateDB) encodeObject(key common.Hash, obj interface{}) {
+	enc, err := rlp.EncodeToBytes(obj)
+	if err != nil {
+		panic(err)
+	}
+	self.setObject(key, enc)
+}
+
+// GetNonce returns the nonce of the next lending order of a user.
+func (self *LendingStateDB) GetNonce(user common.Address) uint64 {
+	var nonce uint64
+	self.decodeObject(crypto.Keccak256Hash(noncePrefix, user[:]), &nonce)
+	return nonce
+}
+
+func (self *LendingStateDB) SetNonce(user common.Address, nonce uint64) {
+	self.encodeObject(crypto.Keccak256Hash(noncePrefix, user[:]), nonce)
+}
+
+// GetLendingBooks returns the hashes of the lending books created so far.
+func (self *LendingStateDB) GetLendingBooks() []common.Hash {
+	var books []common.Hash
+	self.decodeObject(booksKey, &books)
+	return books
+}
+
+// GetLendingBook returns a lending book, nil if it does not exist.
+func (self *LendingStateDB) GetLendingBook(bookHash common.Hash) *LendingBook {
+	book := new(LendingBook)
+	if !self.decodeObject(crypto.Keccak256Hash(bookPrefix, bookHash[:]), book) {
+		return nil
+	}
+	return book
+}
+
+// GetOrNewLendingBook returns the lending book of a lending token for a term,
+// creating it if needed.
+func (self *LendingStateDB) GetOrNewLendingBook(lendingToken common.Address, term uint64) *LendingBook {
+	bookHash := GetLendingBookHash(lendingToken, term)
+	if book := self.GetLendingBook(bookHash); book != nil {
+		return book
+	}
+	book := &LendingBook{LendingToken: lendingToken, Term: term}
+	self.SetLendingBook(book)
+	self.encodeObject(booksKey, append(self.GetLendingBooks(), bookHash))
+	return book
+}
+
+func (self *LendingStateDB) SetLendingBook(book *LendingBook) {
+	bookHash := GetLendingBookHash(book.LendingToken, book.Term)
+	self.encodeObject(crypto.Keccak256Hash(bookPrefix, bookHash[:]), book)
+}
+
+func itemKey(bookHash common.Hash, id uint64) common.Hash {
+	return crypto.Keccak256Hash(itemPrefix, bookHash[:], encodeUint64(id))
+}
+
+// GetLendingItem returns an item resting in a lending book, nil if it does not
+// exist.
+func (self *LendingStateDB) GetLendingItem(bookHash common.Hash, id uint64) *LendingItem {
+	item := new(LendingItem)
+	if !self.decodeObject(itemKey(bookHash, id), item) {
+		return nil
+	}
+	return item
+}
+
+// GetLendingItems returns the items resting in a side of a lending book, in
+// priority order.
+func (self *LendingStateDB) GetLendingItems(bookHash common.Hash, side string) []*LendingItem {
+	book := self.GetLendingBook(bookHash)
+	if book == nil {
+		return nil
+	}
+	ids := book.Investing
+	if side == Borrowing {
+		ids = book.Borrowing
+	}
+	items := make([]*LendingItem, 0, len(ids))
+	for _, id := range ids {
+		if item := self.GetLendingItem(bookHash, id); item != nil {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// InsertLendingItem rests a new item in its side of a lending book, assigning
+// its identifier.
+func (self *LendingStateDB) InsertLendingItem(book *LendingBook, item *LendingItem) {
+	bookHash := GetLendingBookHash(book.LendingToken, book.Term)
+	book.NextItemID++
+	item.ID = book.NextItemID
+
+	ids := &book.Investing
+	if item.Side == Borrowing {
+		ids = &book.Borrowing
+	}
+	idx := sort.Search(len(*ids), func(i int) bool {
+		return better(item, self.GetLendingItem(bookHash, (*ids)[i]))
+	})
+	*ids = append(*ids, 0)
+	copy((*ids)[idx+1:], (*ids)[idx:])
+	(*ids)[idx] = item.ID
+
+	self.encodeObject(itemKey(bookHash, item.ID), item)
+	self.SetLendingBook(book)
+}
+
+// UpdateLendingItem stores the remaining quantity of a partially filled item.
+func (self *LendingStateDB) UpdateLendingItem(book *LendingBook, item *LendingItem) {
+	self.encodeObject(itemKey(GetLendingBookHash(book.LendingToken, book.Term), item.ID), item)
+}
+
+// RemoveLendingItem removes a filled or cancelled item from its lending book.
+func (self *LendingStateDB) RemoveLendingItem(book *LendingBook, item *LendingItem) {
+	ids := &book.Investing
+	if item.Side == Borrowing {
+		ids = &book.Borrowing
+	}
+	*ids = removeID(*ids, item.ID)
+	self.setObject(itemKey(GetLendingBookHash(book.LendingToken, book.Term), item.ID), nil)
+	self.SetLendingBook(book)
+}
+
+func tradeKey(bookHash common.Hash, id uint64) common.Hash {
+	return crypto.Keccak256Hash(tradePrefix, bookHash[:], encodeUint64(id))
+}
+
+// GetLendingTrade returns a loan of a lending book, nil if it does not exist.
+func (self *LendingStateDB) GetLendingTrade(bookHash common.Hash, id uint64) *LendingTrade {
+	trade := new(LendingTrade)
+	if !self.decodeObject(tradeKey(bookHash, id), trade) {
+		return nil
+	}
+	return trade
+}
+
+// InsertLendingTrade records a new open loan, assigning its identifier.
+func (self *LendingStateDB) InsertLendingTrade(book *LendingBook, trade *LendingTrade) {
+	book.NextTradeID++
+	trade.ID = book.NextTradeID
+	trade.Status = TradeStatusOpen
+	book.Trades = append(book.Trades, trade.ID)
+
+	self.encodeObject(tradeKey(GetLendingBookHash(book.LendingToken, book.Term), trade.ID), trade)
+	self.SetLendingBook(book)
+}
+
+// CloseLendingTrade removes a repaid or liquidated loan from the open loans of
+// its lending book, keeping its final state.
+func (self *LendingStateDB) CloseLendingTrade(book *LendingBook, trade *LendingTrade) {
+	book.Trades = removeID(book.Trades, trade.ID)
+	self.encodeObject(tradeKey(GetLendingBookHash(book.LendingToken, book.Term), trade.ID), trade)
+	self.SetLendingBook(book)
+}
+
+func removeID(ids []uint64, id uint64) []uint64 {
+	for i := range ids {
+		if ids[i] == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// Copy creates a deep, independent copy of the state.
+// Snapshots of the copied state cannot be applied to the copy.
+func (self *LendingStateDB) Copy() *LendingStateDB {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	state := &LendingStateDB{
+		db:           self.db,
+		trie:         self.db.CopyTrie(self.trie),
+		objects:      make(map[common.Hash][]byte, len(self.objects)),
+		objectsDirty: make(map[common.Hash]struct{}, len(self.objectsDirty)),
+	}
+	for key, enc := range self.objects {
+		state.objects[key] = common.CopyBytes(enc)
+	}
+	for key := range self.objectsDirty {
+		state.objectsDirty[key] = struct{}{}
+	}
+	return state
+}
+
+// Snapshot returns an identifier for the current revision of the state.
+func (self *LendingStateDB) Snapshot() int {
+	id := self.nextRevisionId
+	self.nextRevisionId++
+	self.validRevisions = append(self.validRevisions, revision{id, len(self.journal)})
+	return id
+}
+
+// RevertToSnapshot reverts all state changes made since the given revision.
+func (self *LendingStateDB) RevertToSnapshot(revid int) {
+	idx := sort.Search(len(self.validRevisions), func(i int) bool {
+		return self.validRevisions[i].id >= revid
+	})
+	if idx == len(self.validRevisions) || self.validRevisions[idx].id != revid {
+		panic(fmt.Errorf("revision id %v cannot be reverted", revid))
+	}
+	snapshot := self.validRevisions[idx].journalIndex
+
+	// Replay the journal to undo changes.
+	for i := len(self.journal) - 1; i >= snapshot; i-- {
+		self.objects[self.journal[i].key] = self.journal[i].prev
+	}
+	self.journal = self.journal[:snapshot]
+	self.validRevisions = self.validRevisions[:idx]
+}
+
+// Finalise writes the modified objects to the trie and clears the journal.
+func (self *LendingStateDB) Finalise() {
+	for key := range self.objectsDirty {
+		if enc := self.objects[key]; enc == nil {
+			self.setError(self.trie.TryDelete(key[:]))
+		} else {
+			self.setError(self.trie.TryUpdate(key[:], enc))
+		}
+	}
+	self.objectsDirty = make(map[common.Hash]struct{})
+	self.journal = nil
+	self.validRevisions = self.validRevisions[:0]
+}
+
+// IntermediateRoot computes the current root hash of the lending trie.
+func (self *LendingStateDB) IntermediateRoot() common.Hash {
+	self.Finalise()
+	return self.trie.Hash()
+}
+
+// Commit writes the state to the underlying in-memory trie database.
+func (self *LendingStateDB) Commit() (common.Hash, error) {
+	self.Finalise()
+	if self.dbErr != nil {
+		return common.Hash{}, self.dbErr
+	}
+	return self.trie.Commit(nil)
+}
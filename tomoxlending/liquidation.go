@@ -0,0 +1,72 @@
+package tomoxlending
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+// CollateralValuer returns the value of a quantity of collateral token in
+// lending token, nil if no price is known.
+type CollateralValuer func(collateralToken, lendingToken common.Address, quantity *big.Int) *big.Int
+
+// ProcessLiquidations scans the open loans of all lending books at the given
+// time and liquidates the expired ones, and the ones whose collateral is worth
+// less than rate percent of their debt. The investor of a liquidated loan
+// seizes the collateral worth its debt, the remainder goes back to the
+// borrower. The scan only depends on the given states, so that every node
+// liquidates the same loans of a block.
+func ProcessLiquidations(time uint64, rate uint64, value CollateralValuer, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) []*lendingstate.LendingTrade {
+	var liquidated []*lendingstate.LendingTrade
+	for _, bookHash := range lendingState.GetLendingBooks() {
+		book := lendingState.GetLendingBook(bookHash)
+		if book == nil {
+			continue
+		}
+		for _, id := range append([]uint64{}, book.Trades...) {
+			trade := lendingState.GetLendingTrade(bookHash, id)
+			if trade == nil || trade.Status != lendingstate.TradeStatusOpen {
+				continue
+			}
+			debt := new(big.Int).Add(trade.Amount, trade.InterestDue())
+			worth := value(trade.CollateralToken, trade.LendingToken, trade.CollateralAmount)
+
+			expired := time >= trade.ExpiresAt
+			undercollateralized := worth != nil && new(big.Int).Mul(worth, big.NewInt(100)).Cmp(new(big.Int).Mul(debt, new(big.Int).SetUint64(rate))) < 0
+			if !expired && !undercollateralized {
+				continue
+			}
+			if err := liquidate(time, book, trade, debt, worth, statedb, lendingState); err != nil {
+				log.Error("Failed to liquidate lending trade", "book", bookHash.Hex(), "id", trade.ID, "err", err)
+				continue
+			}
+			log.Debug("Liquidated lending trade", "book", bookHash.Hex(), "id", trade.ID, "expired", expired, "debt", debt, "collateral", worth)
+			liquidated = append(liquidated, trade)
+		}
+	}
+	return liquidated
+}
+
+// liquidate closes a loan, splitting its collateral between the investor and
+// the borrower. Without a price the investor seizes the whole collateral.
+func liquidate(time uint64, book *lendingstate.LendingBook, trade *lendingstate.LendingTrade, debt, worth *big.Int, statedb *state.StateDB, lendingState *lendingstate.LendingStateDB) error {
+	seized := trade.CollateralAmount
+	if worth != nil && worth.Cmp(debt) > 0 {
+		seized = new(big.Int).Div(new(big.Int).Mul(trade.CollateralAmount, debt), worth)
+	}
+	snap := statedb.Snapshot()
+	if err := transfer(escrow, trade.Investor, seized, trade.CollateralToken, statedb); err != nil {
+		return err
+	}
+	if err := transfer(escrow, trade.Borrower, new(big.Int).Sub(trade.CollateralAmount, seized), trade.CollateralToken, statedb); err != nil {
+		statedb.RevertToSnapshot(snap)
+		return err
+	}
+	trade.Status, trade.ClosedAt = lendingstate.TradeStatusLiquidated, time
+	trade.SeizedCollateral = new(big.Int).Set(seized)
+	lendingState.CloseLendingTrade(book, trade)
+	return nil
+}
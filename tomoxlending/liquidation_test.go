@@ -0,0 +1,108 @@
+package tomoxlending
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+// fixedPrice values collaterals at a fixed number of lending token units per
+// collateral unit.
+func fixedPrice(price int64) CollateralValuer {
+	return func(collateralToken, lendingToken common.Address, quantity *big.Int) *big.Int {
+		return new(big.Int).Mul(quantity, big.NewInt(price))
+	}
+}
+
+func TestProcessLiquidations(t *testing.T) {
+	statedb, lendingState := newTestStates(t)
+	investorKey, _ := crypto.GenerateKey()
+	borrowerKey, _ := crypto.GenerateKey()
+	investor, borrower := crypto.PubkeyToAddress(investorKey.PublicKey), crypto.PubkeyToAddress(borrowerKey.PublicKey)
+
+	tomox_state.SetTokenBalance(investor, big.NewInt(1000), testToken, statedb)
+	statedb.SetBalance(borrower, big.NewInt(400))
+
+	// Open two loans of 400 tokens, each backed by 200 TOMO
+	for i, order := range []*LendingOrder{
+		signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Investing, Interest: big.NewInt(10000000), Quantity: big.NewInt(1000)}, investorKey),
+		signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Borrowing, Interest: big.NewInt(10000000), Quantity: big.NewInt(400), CollateralToken: testNative, Collateral: big.NewInt(200)}, borrowerKey),
+		signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Borrowing, Interest: big.NewInt(10000000), Quantity: big.NewInt(400), CollateralToken: testNative, Collateral: big.NewInt(200), Nonce: 1}, borrowerKey),
+	} {
+		if err := ApplyLendingOrder(1000, order, statedb, lendingState); err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+	bookHash := lendingstate.GetLendingBookHash(testToken, testTerm)
+
+	// Well collateralized loans and loans without price are left open
+	if liquidated := ProcessLiquidations(2000, 110, fixedPrice(3), statedb, lendingState); len(liquidated) != 0 {
+		t.Fatalf("liquidated %d healthy trades", len(liquidated))
+	}
+	if liquidated := ProcessLiquidations(2000, 110, func(common.Address, common.Address, *big.Int) *big.Int { return nil }, statedb, lendingState); len(liquidated) != 0 {
+		t.Fatalf("liquidated %d trades without price", len(liquidated))
+	}
+	// Below the liquidation rate, the investor seizes the whole collateral
+	liquidated := ProcessLiquidations(2000, 110, fixedPrice(2), statedb, lendingState)
+	if len(liquidated) != 2 {
+		t.Fatalf("liquidated trades mismatch: have %d, want 2", len(liquidated))
+	}
+	for _, trade := range liquidated {
+		if trade.Status != lendingstate.TradeStatusLiquidated || trade.ClosedAt != 2000 || trade.SeizedCollateral.Int64() != 200 {
+			t.Errorf("liquidated trade mismatch: %+v", trade)
+		}
+		if stored := lendingState.GetLendingTrade(bookHash, trade.ID); stored.Status != lendingstate.TradeStatusLiquidated {
+			t.Errorf("lending trade %d status mismatch: have %s", trade.ID, stored.Status)
+		}
+	}
+	if have := statedb.GetBalance(investor).Int64(); have != 400 {
+		t.Errorf("investor collateral mismatch: have %d, want 400", have)
+	}
+	if book := lendingState.GetLendingBook(bookHash); len(book.Trades) != 0 {
+		t.Errorf("liquidated trades left open: %v", book.Trades)
+	}
+}
+
+// Tests that expired loans are liquidated whatever their collateral value, the
+// collateral in excess of the debt going back to the borrower.
+func TestProcessLiquidationsExpired(t *testing.T) {
+	statedb, lendingState := newTestStates(t)
+	investorKey, _ := crypto.GenerateKey()
+	borrowerKey, _ := crypto.GenerateKey()
+	investor, borrower := crypto.PubkeyToAddress(investorKey.PublicKey), crypto.PubkeyToAddress(borrowerKey.PublicKey)
+
+	tomox_state.SetTokenBalance(investor, big.NewInt(400), testToken, statedb)
+	statedb.SetBalance(borrower, big.NewInt(200))
+	for i, order := range []*LendingOrder{
+		signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Investing, Interest: big.NewInt(10000000), Quantity: big.NewInt(400)}, investorKey),
+		signTestOrder(t, &LendingOrder{Type: Limit, Side: lendingstate.Borrowing, Interest: big.NewInt(10000000), Quantity: big.NewInt(400), CollateralToken: testNative, Collateral: big.NewInt(200)}, borrowerKey),
+	} {
+		if err := ApplyLendingOrder(1000, order, statedb, lendingState); err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+	}
+	bookHash := lendingstate.GetLendingBookHash(testToken, testTerm)
+	trade := lendingState.GetLendingTrade(bookHash, 1)
+	if liquidated := ProcessLiquidations(trade.ExpiresAt-1, 110, fixedPrice(4), statedb, lendingState); len(liquidated) != 0 {
+		t.Fatalf("liquidated %d trades before expiry", len(liquidated))
+	}
+	if liquidated := ProcessLiquidations(trade.ExpiresAt, 110, fixedPrice(4), statedb, lendingState); len(liquidated) != 1 {
+		t.Fatalf("liquidated trades mismatch: have %d, want 1", len(liquidated))
+	}
+	// The collateral is worth 800, the investor seizes the part worth the debt
+	debt := new(big.Int).Add(trade.Amount, trade.InterestDue()).Int64()
+	seized := 200 * debt / 800
+	if have := statedb.GetBalance(investor).Int64(); have != seized {
+		t.Errorf("investor collateral mismatch: have %d, want %d", have, seized)
+	}
+	if have := statedb.GetBalance(borrower).Int64(); have != 200-seized {
+		t.Errorf("borrower collateral mismatch: have %d, want %d", have, 200-seized)
+	}
+	if have := statedb.GetBalance(escrow).Int64(); have != 0 {
+		t.Errorf("escrow balance mismatch: have %d, want 0", have)
+	}
+}
@@ -0,0 +1,129 @@
+package tomoxlending
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tomox/tomox_state"
+)
+
+// Types of the lending orders.
+const (
+	Limit  = "LO"     // Rests an investing or borrowing item in a lending book
+	Cancel = "CANCEL" // Cancels a resting item, refunding its remaining funds
+	Repay  = "REPAY"  // Repays an open loan, releasing its collateral
+)
+
+var (
+	ErrInvalidSignature = errors.New("verify lending order: invalid signature")
+	ErrWrongHash        = errors.New("verify lending order: wrong hash")
+	ErrInvalidType      = errors.New("verify lending order: unsupported order type")
+)
+
+// LendingOrder is a lending order signed by a user, submitted through the RPC
+// API and carried in the lending transaction of the block it is processed in.
+type LendingOrder struct {
+	Relayer         common.Address
+	UserAddress     common.Address
+	LendingToken    common.Address
+	CollateralToken common.Address
+	Term            uint64   // Duration of the loan, in seconds
+	Interest        *big.Int // Yearly interest rate, in 1/lendingstate.BaseInterest
+	Quantity        *big.Int // Quantity of lending token to invest or borrow
+	Collateral      *big.Int // Collateral pledged by a borrower for the whole quantity
+	Side            string
+	Type            string
+	LendingID       uint64 // Item to cancel or loan to repay
+	Nonce           uint64
+	Hash            common.Hash
+	Signature       []byte // [R || S || V] signature of the hash, as a signed message
+}
+
+// ComputeHash returns the hash of the content of the order, which is signed by
+// the user.
+func (order *LendingOrder) ComputeHash() common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{
+		order.Relayer,
+		order.UserAddress,
+		order.LendingToken,
+		order.CollateralToken,
+		order.Term,
+		bigOrZero(order.Interest),
+		bigOrZero(order.Quantity),
+		bigOrZero(order.Collateral),
+		order.Side,
+		order.Type,
+		order.LendingID,
+		order.Nonce,
+	})
+	return crypto.Keccak256Hash(enc)
+}
+
+// signHash returns the hash of the order hash signed as a message.
+func signHash(hash common.Hash) []byte {
+	return crypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash.Bytes())
+}
+
+// Sender verifies the hash and the signature of the order, and returns the
+// address of its signer.
+func (order *LendingOrder) Sender() (common.Address, error) {
+	if order.ComputeHash() != order.Hash {
+		return common.Address{}, ErrWrongHash
+	}
+	if len(order.Signature) != 65 {
+		return common.Address{}, ErrInvalidSignature
+	}
+	sig := common.CopyBytes(order.Signature)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	user, err := tomox_state.RecoverSigner(common.BytesToHash(signHash(order.Hash)), sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return user, nil
+}
+
+// SignLendingOrder fills in the hash of the order and signs it with the given
+// key.
+func SignLendingOrder(order *LendingOrder, prv *ecdsa.PrivateKey) error {
+	order.Hash = order.ComputeHash()
+	sig, err := crypto.Sign(signHash(order.Hash), prv)
+	if err != nil {
+		return err
+	}
+	order.Signature = sig
+	return nil
+}
+
+// LendingBatch is the content of the lending transaction of a block: the
+// lending orders processed in the block and the resulting lending state root.
+type LendingBatch struct {
+	Orders []*LendingOrder
+	Root   common.Hash
+}
+
+// EncodeLendingBatch encodes a lending batch as lending transaction data.
+func EncodeLendingBatch(batch *LendingBatch) ([]byte, error) {
+	return rlp.EncodeToBytes(batch)
+}
+
+// DecodeLendingBatch decodes the data of a lending transaction.
+func DecodeLendingBatch(data []byte) (*LendingBatch, error) {
+	batch := new(LendingBatch)
+	if err := rlp.DecodeBytes(data, batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func bigOrZero(n *big.Int) *big.Int {
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
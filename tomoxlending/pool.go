@@ -0,0 +1,125 @@
+package tomoxlending
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/tomoxlending/lendingstate"
+)
+
+// LimitThresholdNonceInQueue is the maximum number of pending lending orders
+// of a user.
+const LimitThresholdNonceInQueue = 100
+
+var (
+	ErrNonceTooLow  = errors.New("lending order nonce too low")
+	ErrNonceTooHigh = errors.New("lending order nonce too high")
+)
+
+// LendingPool holds the lending orders submitted to this node until they are
+// processed in a mined block.
+type LendingPool struct {
+	orders map[common.Address]map[uint64]*LendingOrder // Pending orders of each user by nonce
+	mu     sync.RWMutex
+}
+
+func NewLendingPool() *LendingPool {
+	return &LendingPool{
+		orders: make(map[common.Address]map[uint64]*LendingOrder),
+	}
+}
+
+// Add verifies the signature of a lending order and queues it, replacing any
+// pending order of the user with the same nonce. Orders with a nonce already
+// processed in the given state are rejected.
+func (pool *LendingPool) Add(order *LendingOrder, lendingState *lendingstate.LendingStateDB) error {
+	user, err := order.Sender()
+	if err != nil {
+		return err
+	}
+	if user != order.UserAddress {
+		return ErrInvalidSignature
+	}
+	nonce := lendingState.GetNonce(user)
+	if order.Nonce < nonce {
+		return ErrNonceTooLow
+	}
+	if order.Nonce >= nonce+LimitThresholdNonceInQueue {
+		return ErrNonceTooHigh
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.orders[user] == nil {
+		pool.orders[user] = make(map[uint64]*LendingOrder)
+	}
+	pool.orders[user][order.Nonce] = order
+	return nil
+}
+
+// Pending returns the orders which can be processed on top of the given state:
+// the orders of each user with consecutive nonces starting at the user's
+// lending nonce. Orders already processed are dropped from the pool.
+func (pool *LendingPool) Pending(lendingState *lendingstate.LendingStateDB) []*LendingOrder {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	users := make([]common.Address, 0, len(pool.orders))
+	for user, orders := range pool.orders {
+		nonce := lendingState.GetNonce(user)
+		for n := range orders {
+			if n < nonce {
+				delete(orders, n)
+			}
+		}
+		if len(orders) == 0 {
+			delete(pool.orders, user)
+			continue
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return bytes.Compare(users[i][:], users[j][:]) < 0
+	})
+	var pending []*LendingOrder
+	for _, user := range users {
+		for nonce := lendingState.GetNonce(user); ; nonce++ {
+			order, ok := pool.orders[user][nonce]
+			if !ok {
+				break
+			}
+			pending = append(pending, order)
+		}
+	}
+	return pending
+}
+
+// Remove drops an order which cannot be processed from the pool.
+func (pool *LendingPool) Remove(order *LendingOrder) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if orders := pool.orders[order.UserAddress]; orders != nil && orders[order.Nonce] == order {
+		delete(orders, order.Nonce)
+	}
+}
+
+// Content returns the pending orders of each user.
+func (pool *LendingPool) Content() map[common.Address][]*LendingOrder {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	content := make(map[common.Address][]*LendingOrder, len(pool.orders))
+	for user, orders := range pool.orders {
+		for _, order := range orders {
+			content[user] = append(content[user], order)
+		}
+		sort.Slice(content[user], func(i, j int) bool {
+			return content[user][i].Nonce < content[user][j].Nonce
+		})
+	}
+	return content
+}
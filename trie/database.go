@@ -17,12 +17,14 @@
 package trie
 
 import (
+	"math"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/hashicorp/golang-lru/simplelru"
 )
 
 // secureKeyPrefix is the database key prefix used to store trie node preimages.
@@ -57,6 +59,11 @@ type Database struct {
 	nodesSize     common.StorageSize // Storage size of the nodes cache
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	cleans      *simplelru.LRU     // Clean nodes read from or flushed to disk, nil if disabled
+	cleansSize  common.StorageSize // Storage size of the clean nodes cache
+	cleansLimit common.StorageSize // Storage size the clean nodes cache is trimmed to
+	cleansLock  sync.Mutex         // Mutex protecting the clean nodes cache, which is changed on reads
+
 	Lock sync.RWMutex
 }
 
@@ -80,6 +87,21 @@ func NewDatabase(diskdb ethdb.Database) *Database {
 	}
 }
 
+// NewDatabaseWithCache creates a new trie database which additionally keeps up
+// to cache megabytes of clean nodes in memory, sparing the disk reads of the
+// nodes recently read or flushed.
+func NewDatabaseWithCache(diskdb ethdb.Database, cache int) *Database {
+	db := NewDatabase(diskdb)
+	if cache > 0 {
+		// The size limit is enforced by hand, the count one is never reached
+		db.cleans, _ = simplelru.NewLRU(math.MaxInt32, func(key, value interface{}) {
+			db.cleansSize -= common.StorageSize(common.HashLength + len(value.([]byte)))
+		})
+		db.cleansLimit = common.StorageSize(cache * 1024 * 1024)
+	}
+	return db
+}
+
 // DiskDB retrieves the persistent storage backing the trie database.
 func (db *Database) DiskDB() DatabaseReader {
 	return db.diskdb
@@ -129,8 +151,48 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	if node != nil {
 		return node.blob, nil
 	}
+	if blob := db.cleanNode(hash); blob != nil {
+		return blob, nil
+	}
 	// Content unavailable in memory, attempt to retrieve from disk
-	return db.diskdb.Get(hash[:])
+	enc, err := db.diskdb.Get(hash[:])
+	if err == nil && len(enc) > 0 {
+		db.cacheCleanNode(hash, enc)
+	}
+	return enc, err
+}
+
+// cleanNode retrieves a node from the clean nodes cache, nil if it's not there.
+func (db *Database) cleanNode(hash common.Hash) []byte {
+	if db.cleans == nil {
+		return nil
+	}
+	db.cleansLock.Lock()
+	defer db.cleansLock.Unlock()
+
+	if blob, ok := db.cleans.Get(hash); ok {
+		return blob.([]byte)
+	}
+	return nil
+}
+
+// cacheCleanNode adds a node present on disk to the clean nodes cache, evicting
+// the least recently used ones beyond the size limit of the cache.
+func (db *Database) cacheCleanNode(hash common.Hash, blob []byte) {
+	if db.cleans == nil {
+		return
+	}
+	db.cleansLock.Lock()
+	defer db.cleansLock.Unlock()
+
+	if db.cleans.Contains(hash) {
+		return
+	}
+	db.cleans.Add(hash, blob)
+	db.cleansSize += common.StorageSize(common.HashLength + len(blob))
+	for db.cleansSize > db.cleansLimit {
+		db.cleans.RemoveOldest()
+	}
 }
 
 // preimage retrieves a cached trie node pre-image from memory. If it cannot be
@@ -340,6 +402,7 @@ func (db *Database) uncache(hash common.Hash) {
 	for child := range node.children {
 		db.uncache(child)
 	}
+	db.cacheCleanNode(hash, node.blob)
 	delete(db.nodes, hash)
 	db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
 }
@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that the nodes flushed to disk are served from the clean cache, which
+// stays within its size limit.
+func TestCleanCache(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	triedb := NewDatabaseWithCache(diskdb, 1)
+
+	trie, _ := New(common.Hash{}, triedb)
+	for i := 0; i < 100; i++ {
+		trie.Update([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	root, _ := trie.Commit(nil)
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	// Drop the nodes from disk, they must still be readable from the cache
+	for _, key := range diskdb.Keys() {
+		diskdb.Delete(key)
+	}
+	trie, err := New(root, triedb)
+	if err != nil {
+		t.Fatalf("failed to open committed trie: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if value := trie.Get([]byte(fmt.Sprintf("key-%d", i))); string(value) != fmt.Sprintf("value-%d", i) {
+			t.Fatalf("value %d mismatch: have %q", i, value)
+		}
+	}
+	// Shrink the cache and check the least recently used nodes are evicted
+	triedb.cleansLimit = 256
+	triedb.cacheCleanNode(common.Hash{0x01}, make([]byte, 64))
+	if triedb.cleansSize > triedb.cleansLimit {
+		t.Errorf("clean cache over its limit: have %v, limit %v", triedb.cleansSize, triedb.cleansLimit)
+	}
+	if !triedb.cleans.Contains(common.Hash{0x01}) {
+		t.Errorf("most recent node evicted")
+	}
+}